@@ -0,0 +1,213 @@
+// Package alias keeps authorization working while applications rename
+// object IDs. During the rename window the client resolves old IDs to new
+// ones and falls back to the old tuples, while a Rewriter moves the tuples
+// over in the background:
+//
+//	aliases := alias.NewMap(map[string]string{"project:legacy-42": "project:7f3c"})
+//	az := authz.New(backend, authz.WithAliases(aliases))
+//	rw := &alias.Rewriter{Backend: backend, Aliases: aliases}
+//	go rw.Run(ctx, time.Minute)
+//
+// Once an old ID has no tuples left it is marked done: checks stop falling
+// back to it, but it keeps resolving to the new ID for callers that still
+// send it.
+package alias
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+)
+
+// Map is an in-memory authz.Aliases. It is safe for concurrent use.
+type Map struct {
+	mu      sync.RWMutex
+	current map[string]string   // old → new
+	pending map[string][]string // new → old IDs not yet rewritten
+}
+
+// NewMap returns a map of old object IDs to new ones, all pending rewrite.
+func NewMap(renames map[string]string) *Map {
+	m := &Map{current: map[string]string{}, pending: map[string][]string{}}
+	for old, cur := range renames {
+		m.Add(old, cur)
+	}
+	return m
+}
+
+// Add records that old was renamed to cur. Chained renames resolve to the
+// last ID.
+func (m *Map) Add(old, cur string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.current == nil {
+		m.current, m.pending = map[string]string{}, map[string][]string{}
+	}
+	for o, c := range m.current {
+		if c == old {
+			m.current[o] = cur
+		}
+	}
+	m.current[old] = cur
+	m.pending[cur] = append(m.pending[cur], old)
+	// Old IDs pending under an intermediate name move to the final one.
+	m.pending[cur] = append(m.pending[cur], m.pending[old]...)
+	delete(m.pending, old)
+}
+
+// Current implements authz.Aliases.
+func (m *Map) Current(object string) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if cur, ok := m.current[object]; ok {
+		return cur
+	}
+	return object
+}
+
+// Previous implements authz.Aliases.
+func (m *Map) Previous(object string) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]string(nil), m.pending[object]...)
+}
+
+// Pending returns the old IDs whose tuples are not yet rewritten, sorted.
+func (m *Map) Pending() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []string
+	for _, olds := range m.pending {
+		out = append(out, olds...)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Done marks old as rewritten.
+func (m *Map) Done(old string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cur, ok := m.current[old]
+	if !ok {
+		return
+	}
+	olds := m.pending[cur]
+	for i, o := range olds {
+		if o == old {
+			olds = append(olds[:i], olds[i+1:]...)
+			break
+		}
+	}
+	if len(olds) == 0 {
+		delete(m.pending, cur)
+	} else {
+		m.pending[cur] = olds
+	}
+}
+
+// Rewriter moves tuples from old object IDs to their current ones, both
+// where the old ID is the object and where it is the user or userset.
+type Rewriter struct {
+	Backend authz.Backend
+	Aliases *Map
+	// Writer applies the rewrite; default Backend.
+	Writer authz.TupleWriter
+	Logger *slog.Logger
+}
+
+// Rewrite moves every tuple that mentions a pending old ID and marks the
+// IDs done. Each tuple is written under its new ID and deleted under its
+// old one in the same transaction, so no access is lost in between. It
+// returns the number of tuples moved.
+func (r *Rewriter) Rewrite(ctx context.Context) (int, error) {
+	pending := r.Aliases.Pending()
+	if len(pending) == 0 {
+		return 0, nil
+	}
+	isOld := map[string]bool{}
+	for _, o := range pending {
+		isOld[o] = true
+	}
+	// An old ID can appear as the user of tuples on any type, so read the
+	// whole store rather than filter per object.
+	all, err := authz.ReadAll(ctx, r.Backend, authz.Tuple{})
+	if err != nil {
+		return 0, fmt.Errorf("alias: read: %w", err)
+	}
+	stored := make(map[authz.Tuple]bool, len(all))
+	for _, t := range all {
+		stored[t.Key()] = true
+	}
+	w := r.Writer
+	if w == nil {
+		w = r.Backend
+	}
+	moved := 0
+	var writes, deletes []authz.Tuple
+	flush := func() error {
+		if len(writes)+len(deletes) == 0 {
+			return nil
+		}
+		if err := w.Write(ctx, writes, deletes); err != nil {
+			return fmt.Errorf("alias: rewrite: %w", err)
+		}
+		moved += len(deletes)
+		writes, deletes = nil, nil
+		return nil
+	}
+	for _, t := range all {
+		userObj, rel, isUserset := strings.Cut(t.User, "#")
+		if !isOld[t.Object] && !isOld[userObj] {
+			continue
+		}
+		renamed := authz.Tuple{User: r.Aliases.Current(userObj), Relation: t.Relation, Object: r.Aliases.Current(t.Object), Condition: t.Condition}
+		if isUserset {
+			renamed.User += "#" + rel
+		}
+		if !stored[renamed.Key()] {
+			stored[renamed.Key()] = true
+			writes = append(writes, renamed)
+		}
+		deletes = append(deletes, t)
+		if len(writes)+len(deletes) >= authz.MaxWriteTuples-1 {
+			if err := flush(); err != nil {
+				return moved, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return moved, err
+	}
+	for _, o := range pending {
+		r.Aliases.Done(o)
+	}
+	return moved, nil
+}
+
+// Run rewrites every interval until ctx is done.
+func (r *Rewriter) Run(ctx context.Context, interval time.Duration) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		n, err := r.Rewrite(ctx)
+		if r.Logger != nil {
+			if err != nil {
+				r.Logger.WarnContext(ctx, "alias rewrite failed", "moved", n, "error", err)
+			} else if n > 0 {
+				r.Logger.InfoContext(ctx, "aliased tuples rewritten", "moved", n)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
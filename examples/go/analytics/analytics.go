@@ -0,0 +1,181 @@
+// Package analytics ships tuple snapshots and sampled decision logs to
+// analytics storage, as Parquet files or streamed into BigQuery, for access
+// analytics and anomaly detection pipelines.
+//
+//	rec := &analytics.Recorder{Dest: &analytics.ParquetDir{Dir: "/var/log/fga"}, Sample: 0.1}
+//	az := authz.New(backend, authz.WithDecisionObserver(rec))
+//	go rec.Run(ctx, time.Minute)
+//
+//	err := analytics.WriteTuples(ctx, f, backend, time.Now())
+//
+// Decisions and tuples share one flat layout per kind, see DecisionColumns
+// and TupleColumns, so tables loaded from files and from the streaming API
+// can be queried alike.
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/eval"
+	"github.com/bogdanticu88/openfga-examples/parquet"
+)
+
+// TupleColumns is the layout of tuple snapshots.
+var TupleColumns = []parquet.Column{
+	{Name: "user", Type: parquet.String},
+	{Name: "relation", Type: parquet.String},
+	{Name: "object", Type: parquet.String},
+	{Name: "snapshot_at", Type: parquet.Timestamp},
+}
+
+// DecisionColumns is the layout of decision logs. error is empty for
+// checks that succeeded and duration_us is the check latency in
+// microseconds.
+var DecisionColumns = []parquet.Column{
+	{Name: "time", Type: parquet.Timestamp},
+	{Name: "user", Type: parquet.String},
+	{Name: "relation", Type: parquet.String},
+	{Name: "object", Type: parquet.String},
+	{Name: "allowed", Type: parquet.Bool},
+	{Name: "impersonator", Type: parquet.String},
+	{Name: "error", Type: parquet.String},
+	{Name: "duration_us", Type: parquet.Int64},
+}
+
+// Destination stores batches of decisions.
+type Destination interface {
+	WriteDecisions(ctx context.Context, ds []authz.Decision) error
+}
+
+// DefaultBuffer is the number of decisions a Recorder holds between
+// flushes when Recorder.Buffer is zero.
+const DefaultBuffer = 10000
+
+// Recorder is an authz.DecisionObserver that samples decisions into a
+// buffer and writes them to Dest on Flush. Denials and failed checks are
+// always kept, so the rarer, more telling decisions survive sampling.
+type Recorder struct {
+	Dest Destination
+	// Sample is the fraction of allowed decisions kept, in (0, 1]; zero
+	// keeps every decision.
+	Sample float64
+	// Buffer caps the decisions held between flushes; default
+	// DefaultBuffer. Decisions beyond it are dropped and counted.
+	Buffer int
+	Logger *slog.Logger
+
+	mu      sync.Mutex
+	pending []authz.Decision
+	dropped int
+}
+
+// ObserveDecision implements authz.DecisionObserver.
+func (r *Recorder) ObserveDecision(_ context.Context, d authz.Decision) {
+	if d.Allowed && r.Sample > 0 && r.Sample < 1 && rand.Float64() >= r.Sample {
+		return
+	}
+	limit := r.Buffer
+	if limit <= 0 {
+		limit = DefaultBuffer
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.pending) >= limit {
+		r.dropped++
+		return
+	}
+	r.pending = append(r.pending, d)
+}
+
+// Dropped returns the number of decisions lost to a full buffer.
+func (r *Recorder) Dropped() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.dropped
+}
+
+// Flush writes the buffered decisions to Dest. On failure they are lost,
+// since retrying would let one bad destination exhaust the buffer.
+func (r *Recorder) Flush(ctx context.Context) (int, error) {
+	r.mu.Lock()
+	batch := r.pending
+	r.pending = nil
+	r.mu.Unlock()
+	if len(batch) == 0 {
+		return 0, nil
+	}
+	if err := r.Dest.WriteDecisions(ctx, batch); err != nil {
+		return 0, fmt.Errorf("analytics: write %d decisions: %w", len(batch), err)
+	}
+	return len(batch), nil
+}
+
+// Run flushes every interval until ctx is done, then once more.
+func (r *Recorder) Run(ctx context.Context, interval time.Duration) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			r.flush(context.WithoutCancel(ctx))
+			return ctx.Err()
+		case <-t.C:
+			r.flush(ctx)
+		}
+	}
+}
+
+func (r *Recorder) flush(ctx context.Context) {
+	n, err := r.Flush(ctx)
+	if r.Logger == nil {
+		return
+	}
+	if err != nil {
+		r.Logger.WarnContext(ctx, "decision log flush failed", "error", err)
+	} else if n > 0 {
+		r.Logger.DebugContext(ctx, "decision log flushed", "count", n)
+	}
+}
+
+// WriteTuples writes every tuple in the store to w as a Parquet file with
+// TupleColumns, stamped with at.
+func WriteTuples(ctx context.Context, w io.Writer, b authz.Backend, at time.Time) error {
+	all, err := authz.ReadAll(ctx, b, authz.Tuple{})
+	if err != nil {
+		return fmt.Errorf("analytics: read: %w", err)
+	}
+	eval.SortTuples(all)
+	pw := parquet.NewWriter(w, TupleColumns...)
+	for _, t := range all {
+		if err := pw.Write(t.User, t.Relation, t.Object, at); err != nil {
+			return err
+		}
+	}
+	return pw.Close()
+}
+
+// WriteDecisions writes ds to w as a Parquet file with DecisionColumns.
+func WriteDecisions(w io.Writer, ds []authz.Decision) error {
+	pw := parquet.NewWriter(w, DecisionColumns...)
+	for _, d := range ds {
+		if err := pw.Write(decisionRow(d)...); err != nil {
+			return err
+		}
+	}
+	return pw.Close()
+}
+
+func decisionRow(d authz.Decision) []interface{} {
+	msg := ""
+	if d.Err != nil {
+		msg = d.Err.Error()
+	}
+	return []interface{}{d.Time, d.User, d.Relation, d.Object, d.Allowed, d.Impersonator, msg, d.Duration.Microseconds()}
+}
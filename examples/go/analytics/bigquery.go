@@ -0,0 +1,165 @@
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/parquet"
+)
+
+// DefaultBigQueryBatch is the number of rows per insertAll request when
+// BigQuery.Batch is zero, the size BigQuery recommends.
+const DefaultBigQueryBatch = 500
+
+// BigQuery streams rows into a table through the tabledata.insertAll API.
+// The table must exist with the columns of DecisionColumns or TupleColumns;
+// see Schema.
+type BigQuery struct {
+	Project string
+	Dataset string
+	Table   string
+	// Client must authenticate requests, e.g. an oauth2 client from
+	// golang.org/x/oauth2/google.
+	Client *http.Client
+	// Endpoint defaults to "https://bigquery.googleapis.com".
+	Endpoint string
+	// Batch is the rows per request; default DefaultBigQueryBatch.
+	Batch int
+}
+
+// WriteDecisions implements Destination.
+func (q *BigQuery) WriteDecisions(ctx context.Context, ds []authz.Decision) error {
+	rows := make([]map[string]interface{}, len(ds))
+	for i, d := range ds {
+		rows[i] = record(DecisionColumns, decisionRow(d))
+	}
+	return q.insert(ctx, rows)
+}
+
+// InsertTuples streams a snapshot of every tuple in the store, stamped
+// with at.
+func (q *BigQuery) InsertTuples(ctx context.Context, b authz.Backend, at time.Time) error {
+	all, err := authz.ReadAll(ctx, b, authz.Tuple{})
+	if err != nil {
+		return fmt.Errorf("analytics: read: %w", err)
+	}
+	rows := make([]map[string]interface{}, len(all))
+	for i, t := range all {
+		rows[i] = record(TupleColumns, []interface{}{t.User, t.Relation, t.Object, at})
+	}
+	return q.insert(ctx, rows)
+}
+
+// Schema returns the BigQuery table schema for columns, in the form taken
+// by "bq mk --schema" and the tables.insert API.
+func Schema(columns []parquet.Column) []map[string]string {
+	types := map[parquet.Type]string{
+		parquet.String:    "STRING",
+		parquet.Int64:     "INT64",
+		parquet.Bool:      "BOOL",
+		parquet.Timestamp: "TIMESTAMP",
+	}
+	out := make([]map[string]string, len(columns))
+	for i, c := range columns {
+		out[i] = map[string]string{"name": c.Name, "type": types[c.Type], "mode": "REQUIRED"}
+	}
+	return out
+}
+
+func record(columns []parquet.Column, values []interface{}) map[string]interface{} {
+	rec := make(map[string]interface{}, len(columns))
+	for i, c := range columns {
+		v := values[i]
+		if t, ok := v.(time.Time); ok {
+			v = t.UTC().Format(time.RFC3339Nano)
+		}
+		rec[c.Name] = v
+	}
+	return rec
+}
+
+type insertRow struct {
+	JSON map[string]interface{} `json:"json"`
+}
+
+type insertResponse struct {
+	InsertErrors []struct {
+		Index  int `json:"index"`
+		Errors []struct {
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	} `json:"insertErrors"`
+}
+
+func (q *BigQuery) insert(ctx context.Context, rows []map[string]interface{}) error {
+	batch := q.Batch
+	if batch <= 0 {
+		batch = DefaultBigQueryBatch
+	}
+	for start := 0; start < len(rows); start += batch {
+		end := min(start+batch, len(rows))
+		if err := q.post(ctx, rows[start:end]); err != nil {
+			return fmt.Errorf("analytics: bigquery rows %d-%d: %w", start, end-1, err)
+		}
+	}
+	return nil
+}
+
+func (q *BigQuery) post(ctx context.Context, rows []map[string]interface{}) error {
+	body := struct {
+		Rows []insertRow `json:"rows"`
+	}{Rows: make([]insertRow, len(rows))}
+	for i, r := range rows {
+		body.Rows[i] = insertRow{JSON: r}
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	endpoint := q.Endpoint
+	if endpoint == "" {
+		endpoint = "https://bigquery.googleapis.com"
+	}
+	u := fmt.Sprintf("%s/bigquery/v2/projects/%s/datasets/%s/tables/%s/insertAll", strings.TrimSuffix(endpoint, "/"),
+		url.PathEscape(q.Project), url.PathEscape(q.Dataset), url.PathEscape(q.Table))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	hc := q.Client
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(msg[:min(len(msg), 512)]))
+	}
+	var ir insertResponse
+	if err := json.Unmarshal(msg, &ir); err != nil {
+		return err
+	}
+	if len(ir.InsertErrors) > 0 {
+		e := ir.InsertErrors[0]
+		reason := "unknown"
+		if len(e.Errors) > 0 {
+			reason = e.Errors[0].Reason + ": " + e.Errors[0].Message
+		}
+		return fmt.Errorf("%d rows rejected, first at %d: %s", len(ir.InsertErrors), e.Index, reason)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+)
+
+// ParquetDir is a Destination writing one Parquet file per batch to Dir,
+// named <Prefix>-<UTC time>.parquet so a directory sorts by time and can be
+// loaded with a wildcard, e.g. "bq load ... 'gs://bucket/decisions-*'".
+type ParquetDir struct {
+	Dir string
+	// Prefix defaults to "decisions".
+	Prefix string
+	// Now defaults to time.Now.
+	Now func() time.Time
+}
+
+// WriteDecisions implements Destination. The file is written under a
+// temporary name and renamed, so loaders never see a partial file.
+func (p *ParquetDir) WriteDecisions(_ context.Context, ds []authz.Decision) error {
+	now := time.Now
+	if p.Now != nil {
+		now = p.Now
+	}
+	prefix := p.Prefix
+	if prefix == "" {
+		prefix = "decisions"
+	}
+	name := filepath.Join(p.Dir, fmt.Sprintf("%s-%s.parquet", prefix, now().UTC().Format("20060102T150405.000000000Z")))
+	f, err := os.CreateTemp(p.Dir, "."+prefix+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := WriteDecisions(f, ds); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
@@ -0,0 +1,219 @@
+// Package anomaly watches the decision stream for patterns worth a human
+// look — a burst of denials for one user, a service checking an admin
+// relation for the first time — and alerts through notification sinks.
+//
+//	a := &anomaly.Analyzer{
+//		Rules: []anomaly.Rule{
+//			&anomaly.DenySpike{Threshold: 20, Window: time.Minute},
+//			&anomaly.FirstPrivileged{Relations: []string{"admin"}, UserTypes: []string{"service"}},
+//		},
+//		Sinks: []notifications.Sink{&notifications.Slack{WebhookURL: url}},
+//	}
+//	az := authz.New(backend, authz.WithDecisionObserver(a))
+//	go a.Run(ctx)
+//
+// Rules run on the request path and keep their state in memory, so each
+// process sees only its own decisions; run one analyzer per replica or feed
+// a central one from the decision log.
+package anomaly
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/notifications"
+)
+
+// Event kinds raised by the rules in this package.
+const (
+	DenySpikeDetected    notifications.Kind = "deny_spike"
+	FirstPrivilegedCheck notifications.Kind = "first_privileged_check"
+)
+
+// Rule inspects one decision and returns the alerts it raises. Rules are
+// called concurrently and must be quick.
+type Rule interface {
+	Inspect(d authz.Decision) []notifications.Event
+}
+
+// RuleFunc adapts a function to Rule.
+type RuleFunc func(d authz.Decision) []notifications.Event
+
+// Inspect calls f.
+func (f RuleFunc) Inspect(d authz.Decision) []notifications.Event { return f(d) }
+
+// DefaultQueue is the number of undelivered alerts an Analyzer holds when
+// Analyzer.Queue is zero.
+const DefaultQueue = 100
+
+// Analyzer is an authz.DecisionObserver that applies Rules to every
+// decision and queues their alerts for Run to deliver.
+type Analyzer struct {
+	Rules []Rule
+	Sinks []notifications.Sink
+	// Queue caps undelivered alerts; default DefaultQueue. Alerts beyond it
+	// are dropped and counted rather than slowing checks down.
+	Queue  int
+	Logger *slog.Logger
+
+	once    sync.Once
+	events  chan notifications.Event
+	mu      sync.Mutex
+	dropped int
+}
+
+func (a *Analyzer) init() {
+	a.once.Do(func() {
+		n := a.Queue
+		if n <= 0 {
+			n = DefaultQueue
+		}
+		a.events = make(chan notifications.Event, n)
+	})
+}
+
+// ObserveDecision implements authz.DecisionObserver.
+func (a *Analyzer) ObserveDecision(_ context.Context, d authz.Decision) {
+	a.init()
+	for _, r := range a.Rules {
+		for _, ev := range r.Inspect(d) {
+			select {
+			case a.events <- ev:
+			default:
+				a.mu.Lock()
+				a.dropped++
+				a.mu.Unlock()
+			}
+		}
+	}
+}
+
+// Dropped returns the number of alerts lost to a full queue.
+func (a *Analyzer) Dropped() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.dropped
+}
+
+// Run delivers queued alerts to every sink until ctx is done. A failing
+// sink does not stop the others, and its failure is logged.
+func (a *Analyzer) Run(ctx context.Context) error {
+	a.init()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ev := <-a.events:
+			var errs []error
+			for _, s := range a.Sinks {
+				if err := s.Send(ctx, ev); err != nil {
+					errs = append(errs, fmt.Errorf("anomaly: %s: %w", ev.Kind, err))
+				}
+			}
+			if err := errors.Join(errs...); err != nil && a.Logger != nil {
+				a.Logger.WarnContext(ctx, "anomaly alert failed", "kind", string(ev.Kind), "error", err)
+			}
+		}
+	}
+}
+
+// DenySpike alerts when one user is denied Threshold times within Window,
+// then stays quiet for that user for Cooldown.
+type DenySpike struct {
+	Threshold int
+	Window    time.Duration
+	// Cooldown defaults to Window.
+	Cooldown time.Duration
+
+	mu      sync.Mutex
+	denials map[string][]time.Time
+	quiet   map[string]time.Time
+}
+
+// Inspect implements Rule.
+func (r *DenySpike) Inspect(d authz.Decision) []notifications.Event {
+	if d.Allowed || d.Err != nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.denials == nil {
+		r.denials, r.quiet = map[string][]time.Time{}, map[string]time.Time{}
+	}
+	cutoff := d.Time.Add(-r.Window)
+	recent := r.denials[d.User]
+	i := 0
+	for i < len(recent) && !recent[i].After(cutoff) {
+		i++
+	}
+	recent = append(recent[i:], d.Time)
+	r.denials[d.User] = recent
+	if len(recent) < r.Threshold || d.Time.Before(r.quiet[d.User]) {
+		return nil
+	}
+	cooldown := r.Cooldown
+	if cooldown == 0 {
+		cooldown = r.Window
+	}
+	r.quiet[d.User] = d.Time.Add(cooldown)
+	return []notifications.Event{{
+		Kind:  DenySpikeDetected,
+		Time:  d.Time,
+		Tuple: &authz.Tuple{User: d.User, Relation: d.Relation, Object: d.Object},
+		Text:  fmt.Sprintf("%s was denied %d times in %s, most recently %s on %s", d.User, len(recent), r.Window, d.Relation, d.Object),
+	}}
+}
+
+// FirstPrivileged alerts the first time a user checks a privileged
+// relation, allowed or not: a service probing admin rights it never needed
+// before is often a compromised credential or a new code path.
+type FirstPrivileged struct {
+	// Relations are matched by name ("admin") or qualified by type
+	// ("organization#owner").
+	Relations []string
+	// UserTypes limits the rule to users of these types, e.g. "service";
+	// empty means every user.
+	UserTypes []string
+
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+// Inspect implements Rule.
+func (r *FirstPrivileged) Inspect(d authz.Decision) []notifications.Event {
+	typ, _, _ := strings.Cut(d.Object, ":")
+	if !slices.Contains(r.Relations, d.Relation) && !slices.Contains(r.Relations, typ+"#"+d.Relation) {
+		return nil
+	}
+	userType, _, _ := strings.Cut(d.User, ":")
+	if len(r.UserTypes) > 0 && !slices.Contains(r.UserTypes, userType) {
+		return nil
+	}
+	key := d.User + " " + typ + "#" + d.Relation
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.seen == nil {
+		r.seen = map[string]bool{}
+	}
+	if r.seen[key] {
+		return nil
+	}
+	r.seen[key] = true
+	outcome := "denied"
+	if d.Allowed {
+		outcome = "allowed"
+	}
+	return []notifications.Event{{
+		Kind:  FirstPrivilegedCheck,
+		Time:  d.Time,
+		Tuple: &authz.Tuple{User: d.User, Relation: d.Relation, Object: d.Object},
+		Text:  fmt.Sprintf("%s checked %s on %s for the first time (%s)", d.User, d.Relation, d.Object, outcome),
+	}}
+}
@@ -0,0 +1,280 @@
+// Package approval adds a four-eyes mode for sensitive relations. Writes
+// touching them are not sent; Gate stages them as a Request, and a second
+// principal commits the request with Approve or discards it with Reject.
+//
+//	gate := &approval.Gate{
+//		Relations:  []string{"admin", "organization#owner"},
+//		Store:      &approval.MemoryStore{},
+//		CanApprove: approval.RequireRelation(backend, "admin", "organization:acme"),
+//	}
+//	az := authz.New(backend, authz.WithModelValidation(0), authz.WithWriteGuard(gate))
+//	gate.Client = az
+//
+// Install the gate after the other guards so invalid writes fail before
+// they are staged; the others run again when the request is committed.
+package approval
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+)
+
+var (
+	// ErrPending is wrapped by the error Write returns for a staged write.
+	ErrPending = errors.New("approval: write staged for approval")
+	// ErrSelfApproval is returned when the requester, or the admin who
+	// impersonated them, approves their own request.
+	ErrSelfApproval = errors.New("approval: requester may not approve")
+	// ErrNotFound is returned for an unknown request.
+	ErrNotFound = errors.New("approval: no such request")
+	// ErrNoApprovalCheck is returned by Approve when the gate has no
+	// CanApprove; without one nobody is entitled to approve.
+	ErrNoApprovalCheck = errors.New("approval: no approval check configured")
+	// ErrNotApprover wraps the refusals of CanApprove.
+	ErrNotApprover = errors.New("approval: not allowed to approve")
+	// ErrImpersonated is returned by Approve and Reject for a context in
+	// which an admin impersonates the user; the decision must be the
+	// admin's own.
+	ErrImpersonated = errors.New("approval: impersonated users may not decide requests")
+)
+
+// PendingError is returned by Write when the gate staged the mutation.
+type PendingError struct {
+	ID string
+}
+
+func (e *PendingError) Error() string { return fmt.Sprintf("%v: request %s", ErrPending, e.ID) }
+
+func (e *PendingError) Unwrap() error { return ErrPending }
+
+// Request is a staged mutation.
+type Request struct {
+	ID        string `json:"id"`
+	Requester string `json:"requester"`
+	// Impersonator is the admin who staged the request as Requester, if
+	// any; they may not approve it either.
+	Impersonator string        `json:"impersonator,omitempty"`
+	Writes       []authz.Tuple `json:"writes,omitempty"`
+	Deletes      []authz.Tuple `json:"deletes,omitempty"`
+	CreatedAt    time.Time     `json:"created_at"`
+}
+
+// Store persists staged requests.
+type Store interface {
+	Put(ctx context.Context, r Request) error
+	// Get returns the request with id, or an error wrapping ErrNotFound.
+	Get(ctx context.Context, id string) (Request, error)
+	Delete(ctx context.Context, id string) error
+	List(ctx context.Context) ([]Request, error)
+}
+
+// MemoryStore is a Store for tests and single-process use.
+type MemoryStore struct {
+	mu sync.Mutex
+	m  map[string]Request
+}
+
+func (s *MemoryStore) Put(_ context.Context, r Request) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.m == nil {
+		s.m = map[string]Request{}
+	}
+	s.m[r.ID] = r
+	return nil
+}
+
+func (s *MemoryStore) Get(_ context.Context, id string) (Request, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.m[id]
+	if !ok {
+		return Request{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return r, nil
+}
+
+func (s *MemoryStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.m, id)
+	return nil
+}
+
+func (s *MemoryStore) List(context.Context) ([]Request, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Request, 0, len(s.m))
+	for _, r := range s.m {
+		out = append(out, r)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+// Gate is an authz.WriteGuard staging writes and deletes of sensitive
+// relations. Skip it with authz.BypassWriteGuards.
+type Gate struct {
+	// Relations lists the sensitive relations, either by name ("admin")
+	// or qualified by type ("organization#owner").
+	Relations []string
+	Store     Store
+	// Client commits approved requests; it is normally the client the gate
+	// is installed on.
+	Client *authz.Client
+	// CanApprove decides whether approver may commit r, e.g. by requiring
+	// an admin relation, see RequireRelation; a non-nil error refuses.
+	// Approve refuses every request while it is nil.
+	CanApprove func(ctx context.Context, approver string, r Request) error
+	// Now defaults to time.Now.
+	Now func() time.Time
+}
+
+type approvedKey struct{}
+
+// GuardWrite implements authz.WriteGuard. A mutation touching any sensitive
+// relation is staged whole, attributed to the user in ctx, and rejected with
+// a *PendingError.
+func (g *Gate) GuardWrite(ctx context.Context, writes, deletes []authz.Tuple) error {
+	if ctx.Value(approvedKey{}) != nil || !g.sensitive(writes, deletes) {
+		return nil
+	}
+	requester, ok := authz.UserFromContext(ctx)
+	if !ok {
+		return fmt.Errorf("approval: %w", authz.ErrNoUser)
+	}
+	r, err := g.Submit(ctx, requester, writes, deletes)
+	if err != nil {
+		return err
+	}
+	return &PendingError{ID: r.ID}
+}
+
+// Submit stages writes and deletes as a request by requester whether or
+// not they touch a sensitive relation, for changes proposed by tools
+// rather than written by users, such as package leastpriv's
+// recommendations.
+func (g *Gate) Submit(ctx context.Context, requester string, writes, deletes []authz.Tuple) (Request, error) {
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return Request{}, fmt.Errorf("approval: %w", err)
+	}
+	r := Request{ID: hex.EncodeToString(id[:]), Requester: requester, Writes: writes, Deletes: deletes, CreatedAt: g.now()}
+	r.Impersonator, _ = authz.Impersonator(ctx)
+	if err := g.Store.Put(ctx, r); err != nil {
+		return Request{}, fmt.Errorf("approval: stage: %w", err)
+	}
+	return r, nil
+}
+
+func (g *Gate) sensitive(writes, deletes []authz.Tuple) bool {
+	for _, t := range append(append([]authz.Tuple(nil), writes...), deletes...) {
+		typ, _, _ := strings.Cut(t.Object, ":")
+		if slices.Contains(g.Relations, t.Relation) || slices.Contains(g.Relations, typ+"#"+t.Relation) {
+			return true
+		}
+	}
+	return false
+}
+
+// Approve commits request id on behalf of the user in ctx, who must not be
+// impersonated, must not be its requester or the admin who impersonated
+// the requester, and must pass CanApprove. The request is removed once the
+// write succeeds.
+func (g *Gate) Approve(ctx context.Context, id string) error {
+	approver, r, err := g.decider(ctx, id)
+	if err != nil {
+		return err
+	}
+	if approver == r.Requester || approver == r.Impersonator {
+		return fmt.Errorf("%w: %s", ErrSelfApproval, id)
+	}
+	if err := g.approver(ctx, approver, r); err != nil {
+		return err
+	}
+	ctx = context.WithValue(ctx, approvedKey{}, id)
+	if err := g.Client.Write(ctx, r.Writes, r.Deletes); err != nil {
+		return err
+	}
+	return g.Store.Delete(ctx, id)
+}
+
+// RequireRelation returns a CanApprove admitting approvers with relation
+// on object, checked on b at approval time.
+func RequireRelation(b authz.Backend, relation, object string) func(ctx context.Context, approver string, r Request) error {
+	return func(ctx context.Context, approver string, _ Request) error {
+		allowed, err := b.Check(ctx, authz.CheckRequest{User: approver, Relation: relation, Object: object})
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return fmt.Errorf("needs %s on %s", relation, object)
+		}
+		return nil
+	}
+}
+
+// Reject discards request id on behalf of the user in ctx: its requester
+// withdrawing it, or an approver passing the same checks as for Approve.
+func (g *Gate) Reject(ctx context.Context, id string) error {
+	user, r, err := g.decider(ctx, id)
+	if err != nil {
+		return err
+	}
+	if user != r.Requester {
+		if err := g.approver(ctx, user, r); err != nil {
+			return err
+		}
+	}
+	return g.Store.Delete(ctx, id)
+}
+
+// decider returns the user deciding request id through ctx, refusing
+// impersonated contexts, and the request.
+func (g *Gate) decider(ctx context.Context, id string) (string, Request, error) {
+	user, ok := authz.UserFromContext(ctx)
+	if !ok {
+		return "", Request{}, fmt.Errorf("approval: %w", authz.ErrNoUser)
+	}
+	if admin, ok := authz.Impersonator(ctx); ok {
+		return "", Request{}, fmt.Errorf("%w: %s as %s", ErrImpersonated, admin, user)
+	}
+	r, err := g.Store.Get(ctx, id)
+	if err != nil {
+		return "", Request{}, err
+	}
+	return user, r, nil
+}
+
+// approver runs CanApprove for user on r.
+func (g *Gate) approver(ctx context.Context, user string, r Request) error {
+	if g.CanApprove == nil {
+		return ErrNoApprovalCheck
+	}
+	if err := g.CanApprove(ctx, user, r); err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrNotApprover, user, err)
+	}
+	return nil
+}
+
+// Pending returns the staged requests, oldest first.
+func (g *Gate) Pending(ctx context.Context) ([]Request, error) {
+	return g.Store.List(ctx)
+}
+
+func (g *Gate) now() time.Time {
+	if g.Now != nil {
+		return g.Now()
+	}
+	return time.Now()
+}
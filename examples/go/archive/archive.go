@@ -0,0 +1,239 @@
+// Package archive copies the store's changes feed to object storage, which
+// keeps the history the server may prune for audits and package history.
+//
+//	a := &archive.Archiver{
+//		Backend: backend,
+//		Bucket:  &archive.S3{Bucket: "audit", Region: "eu-west-1", AccessKeyID: id, SecretAccessKey: secret},
+//		Prefix:  "fga/" + storeID,
+//		Cursor:  events.FileCursor("/var/lib/fga/archive.cursor"),
+//	}
+//	go a.Run(ctx, time.Minute)
+//
+// Changes are written as JSON lines, one authz.Change per line, partitioned
+// by the hour they happened in:
+//
+//	fga/<store>/date=2026-10-14/hour=09/20261014T091502.123456789Z-3f2a9c1e.jsonl
+//	fga/<store>/date=2026-10-14/hour=09/20261014T091502.123456789Z-3f2a9c1e.jsonl.sha256
+//
+// which query engines read as a partitioned table. Each object has a
+// sidecar in sha256sum format, and S3 also verifies the checksum on upload.
+// A downloaded copy is checked with VerifyDir and read with history.Glob.
+package archive
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/events"
+)
+
+// Bucket stores objects by key.
+type Bucket interface {
+	// Put stores data under key, replacing any object there. sum is the
+	// SHA-256 of data, for stores that verify uploads.
+	Put(ctx context.Context, key string, data []byte, sum [sha256.Size]byte) error
+}
+
+// Dir is a Bucket in a local directory, e.g. a mounted volume.
+type Dir string
+
+// Put implements Bucket, writing the file atomically.
+func (d Dir) Put(_ context.Context, key string, data []byte, _ [sha256.Size]byte) error {
+	p := filepath.Join(string(d), filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".archive-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+// Archiver copies the changes feed to Bucket. Delivery is at-least-once:
+// the cursor is saved after each page is stored, and a page stored again
+// after a crash may overlap the previous one; history.Files drops the
+// repeats.
+type Archiver struct {
+	Backend authz.Backend
+	Bucket  Bucket
+	// Prefix is prepended to every key, without a trailing slash.
+	Prefix string
+	// Cursor defaults to an in-memory position starting at the beginning.
+	Cursor events.Cursor
+	Logger *slog.Logger
+
+	token string
+}
+
+// Sync archives every change since the saved cursor and returns how many
+// were archived.
+func (a *Archiver) Sync(ctx context.Context) (int, error) {
+	token := a.token
+	if a.Cursor != nil {
+		t, err := a.Cursor.Load(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("archive: load cursor: %w", err)
+		}
+		token = t
+	}
+	archived := 0
+	for {
+		page, next, err := a.Backend.ReadChanges(ctx, "", token)
+		if err != nil {
+			return archived, fmt.Errorf("archive: read changes: %w", err)
+		}
+		if err := a.store(ctx, page); err != nil {
+			return archived, err
+		}
+		archived += len(page)
+		if next != "" && next != token {
+			if err := a.save(ctx, next); err != nil {
+				return archived, err
+			}
+		}
+		if len(page) == 0 || next == "" || next == token {
+			return archived, nil
+		}
+		token = next
+	}
+}
+
+// store writes a page as one object per hour partition it spans.
+func (a *Archiver) store(ctx context.Context, page []authz.Change) error {
+	for start := 0; start < len(page); {
+		hour := page[start].Timestamp.UTC().Truncate(time.Hour)
+		end := start + 1
+		for end < len(page) && page[end].Timestamp.UTC().Truncate(time.Hour).Equal(hour) {
+			end++
+		}
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		for _, c := range page[start:end] {
+			if err := enc.Encode(c); err != nil {
+				return err
+			}
+		}
+		data := buf.Bytes()
+		sum := sha256.Sum256(data)
+		digest := hex.EncodeToString(sum[:])
+		key := Key(a.Prefix, page[start].Timestamp, digest)
+		if err := a.Bucket.Put(ctx, key, data, sum); err != nil {
+			return fmt.Errorf("archive: put %s: %w", key, err)
+		}
+		side := []byte(digest + "  " + path.Base(key) + "\n")
+		if err := a.Bucket.Put(ctx, key+".sha256", side, sha256.Sum256(side)); err != nil {
+			return fmt.Errorf("archive: put %s.sha256: %w", key, err)
+		}
+		start = end
+	}
+	return nil
+}
+
+// Key returns the object key for changes starting at t whose content has
+// the hex SHA-256 digest. Equal pages map to equal keys, so storing a page
+// twice leaves one object.
+func Key(prefix string, t time.Time, digest string) string {
+	t = t.UTC()
+	k := fmt.Sprintf("date=%s/hour=%02d/%s-%s.jsonl", t.Format("2006-01-02"), t.Hour(), t.Format("20060102T150405.000000000Z"), digest[:8])
+	if prefix != "" {
+		k = prefix + "/" + k
+	}
+	return k
+}
+
+// Run syncs every interval until ctx is done.
+func (a *Archiver) Run(ctx context.Context, interval time.Duration) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		n, err := a.Sync(ctx)
+		if a.Logger != nil {
+			if err != nil {
+				a.Logger.WarnContext(ctx, "change archive sync failed", "archived", n, "error", err)
+			} else if n > 0 {
+				a.Logger.DebugContext(ctx, "changes archived", "count", n)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+func (a *Archiver) save(ctx context.Context, token string) error {
+	a.token = token
+	if a.Cursor == nil {
+		return nil
+	}
+	if err := a.Cursor.Save(ctx, token); err != nil {
+		return fmt.Errorf("archive: save cursor: %w", err)
+	}
+	return nil
+}
+
+// ErrCorrupt is wrapped by VerifyDir's errors for objects that do not match
+// their checksum.
+var ErrCorrupt = errors.New("archive: checksum mismatch")
+
+// VerifyDir checks every .jsonl file under dir, a Dir bucket or a
+// downloaded copy of one, against its sidecar and returns the number of
+// files verified. Every bad or unpaired file is reported in the joined
+// error.
+func VerifyDir(dir string) (int, error) {
+	var errs []error
+	n := 0
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(p, ".jsonl") {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		side, err := os.ReadFile(p + ".sha256")
+		if err != nil {
+			errs = append(errs, fmt.Errorf("archive: %s: %w", p, err))
+			return nil
+		}
+		want, _, _ := strings.Cut(string(side), " ")
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != want {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrCorrupt, p))
+			return nil
+		}
+		n++
+		return nil
+	})
+	if err != nil {
+		return n, fmt.Errorf("archive: %w", err)
+	}
+	return n, errors.Join(errs...)
+}
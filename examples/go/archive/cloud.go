@@ -0,0 +1,189 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// GCS is a Google Cloud Storage bucket written through the JSON API. The
+// MD5 the service reports for each upload is compared with the data's.
+type GCS struct {
+	Bucket string
+	// Client must authenticate requests, e.g. an oauth2 client from
+	// golang.org/x/oauth2/google with the devstorage.read_write scope.
+	Client *http.Client
+	// Endpoint defaults to https://storage.googleapis.com.
+	Endpoint string
+}
+
+// Put implements Bucket.
+func (g *GCS) Put(ctx context.Context, key string, data []byte, _ [sha256.Size]byte) error {
+	endpoint := g.Endpoint
+	if endpoint == "" {
+		endpoint = "https://storage.googleapis.com"
+	}
+	u := fmt.Sprintf("%s/upload/storage/v1/b/%s/o?uploadType=media&name=%s", strings.TrimSuffix(endpoint, "/"),
+		url.PathEscape(g.Bucket), url.QueryEscape(key))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	hc := g.Client
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("gcs: %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	var obj struct {
+		MD5Hash string `json:"md5Hash"`
+	}
+	if err := json.Unmarshal(body, &obj); err != nil {
+		return fmt.Errorf("gcs: %w", err)
+	}
+	sum := md5.Sum(data)
+	if obj.MD5Hash != base64.StdEncoding.EncodeToString(sum[:]) {
+		return fmt.Errorf("%w: gcs stored md5 %s", ErrCorrupt, obj.MD5Hash)
+	}
+	return nil
+}
+
+// S3 is an Amazon S3 bucket, or one of a compatible service, written with
+// Signature Version 4. Uploads carry their SHA-256 checksum, which the
+// service verifies before storing the object.
+type S3 struct {
+	Bucket string
+	Region string
+	// Endpoint defaults to https://s3.<Region>.amazonaws.com; set it for
+	// compatible services. Requests use path-style addressing.
+	Endpoint        string
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is set for temporary credentials.
+	SessionToken string
+	Client       *http.Client
+	// Now defaults to time.Now.
+	Now func() time.Time
+}
+
+// Put implements Bucket.
+func (s *S3) Put(ctx context.Context, key string, data []byte, sum [sha256.Size]byte) error {
+	endpoint := s.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + s.Region + ".amazonaws.com"
+	}
+	u, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil {
+		return err
+	}
+	u.Path += "/" + s.Bucket + "/" + key
+	u.RawPath = uriEncode(u.Path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.Header.Set("x-amz-checksum-sha256", base64.StdEncoding.EncodeToString(sum[:]))
+	s.sign(req, hex.EncodeToString(sum[:]))
+	hc := s.Client
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return fmt.Errorf("s3: %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+// sign adds a Signature Version 4 Authorization header to req, whose
+// payload has the hex SHA-256 payloadHash.
+func (s *S3) sign(req *http.Request, payloadHash string) {
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+	t := now().UTC()
+	stamp, day := t.Format("20060102T150405Z"), t.Format("20060102")
+	req.Header.Set("x-amz-date", stamp)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+	if s.SessionToken != "" {
+		req.Header.Set("x-amz-security-token", s.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonHeaders strings.Builder
+	for _, k := range names {
+		canonHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signed := strings.Join(names, ";")
+	canon := strings.Join([]string{req.Method, req.URL.EscapedPath(), req.URL.RawQuery, canonHeaders.String(), signed, payloadHash}, "\n")
+
+	scope := day + "/" + s.Region + "/s3/aws4_request"
+	hashed := sha256.Sum256([]byte(canon))
+	toSign := "AWS4-HMAC-SHA256\n" + stamp + "\n" + scope + "\n" + hex.EncodeToString(hashed[:])
+	k := hmacSHA256([]byte("AWS4"+s.SecretAccessKey), day)
+	for _, part := range []string{s.Region, "s3", "aws4_request"} {
+		k = hmacSHA256(k, part)
+	}
+	sig := hex.EncodeToString(hmacSHA256(k, toSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", s.AccessKeyID, scope, signed, sig))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// uriEncode escapes everything but unreserved characters and slashes, as
+// Signature Version 4 requires of S3 object keys.
+func uriEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', strings.IndexByte("-._~/", c) >= 0:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,88 @@
+package authz
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Aliases maps renamed object IDs to their current ID during a rename
+// window, while the tuples are rewritten; see package alias.
+type Aliases interface {
+	// Current returns the ID object was renamed to, or object itself.
+	Current(object string) string
+	// Previous returns the old IDs of object whose tuples may not have
+	// been rewritten yet.
+	Previous(object string) []string
+}
+
+// WithAliases resolves every user and object passed to the client's
+// helpers through a: old IDs are replaced by current ones, a denied check
+// is retried on the old IDs still pending rewrite, and deletes also remove
+// tuples still stored under an old ID.
+func WithAliases(a Aliases) Option {
+	return func(c *Client) { c.aliases = a }
+}
+
+// currentUser resolves the object part of a user or userset.
+func (c *Client) currentUser(user string) string {
+	obj, rel, isUserset := strings.Cut(user, "#")
+	obj = c.aliases.Current(obj)
+	if isUserset {
+		return obj + "#" + rel
+	}
+	return obj
+}
+
+func (c *Client) currentObject(o Object) Object {
+	if c.aliases == nil {
+		return o
+	}
+	cur, err := ParseObject(c.aliases.Current(o.String()))
+	if err != nil {
+		return o
+	}
+	return cur
+}
+
+// checkPrevious retries a denied check on object's pending old IDs.
+func (c *Client) checkPrevious(ctx context.Context, user, relation, object string) (bool, error) {
+	if c.aliases == nil {
+		return false, nil
+	}
+	for _, old := range c.aliases.Previous(object) {
+		allowed, err := c.backend.Check(ctx, CheckRequest{User: user, Relation: relation, Object: old})
+		if err != nil || allowed {
+			return allowed, err
+		}
+	}
+	return false, nil
+}
+
+// aliasDeletes expands deletes of objects with pending old IDs to the
+// variants that are actually stored, since the server rejects deleting a
+// tuple that does not exist.
+func (c *Client) aliasDeletes(ctx context.Context, deletes []Tuple) ([]Tuple, error) {
+	if c.aliases == nil {
+		return deletes, nil
+	}
+	var out []Tuple
+	for _, t := range deletes {
+		previous := c.aliases.Previous(t.Object)
+		if len(previous) == 0 {
+			out = append(out, t)
+			continue
+		}
+		for _, object := range append([]string{t.Object}, previous...) {
+			variant := Tuple{User: t.User, Relation: t.Relation, Object: object}
+			existing, _, err := c.backend.Read(ctx, variant, "")
+			if err != nil {
+				return nil, fmt.Errorf("authz: read %s: %w", variant, err)
+			}
+			if len(existing) > 0 {
+				out = append(out, variant)
+			}
+		}
+	}
+	return out, nil
+}
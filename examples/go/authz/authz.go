@@ -0,0 +1,224 @@
+// Package authz wraps an OpenFGA client with the enforcement helpers
+// application code calls on every request.
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/ids"
+	"github.com/bogdanticu88/openfga-examples/model"
+	"github.com/bogdanticu88/openfga-examples/policy"
+)
+
+var (
+	// ErrForbidden is returned by Require when the check is denied.
+	ErrForbidden = errors.New("authz: forbidden")
+	// ErrNoUser is returned when a helper needs the caller but the context
+	// carries none; see WithUser.
+	ErrNoUser = errors.New("authz: no user in context")
+	// ErrNoPolicy is returned by Require when the client has no policy map.
+	ErrNoPolicy = errors.New("authz: no policy map configured")
+)
+
+// CheckRequest is a single authorization question.
+type CheckRequest struct {
+	User     string
+	Relation string
+	Object   string
+}
+
+// Backend is the subset of the OpenFGA API the helpers depend on. FromSDK
+// adapts the official client; tests can substitute any implementation.
+type Backend interface {
+	Check(ctx context.Context, req CheckRequest) (bool, error)
+	// Write applies writes and deletes in a single transaction.
+	Write(ctx context.Context, writes, deletes []Tuple) error
+	// Read returns one page of tuples matching filter and the token for the
+	// next page, which is empty after the last page. See ReadAll.
+	Read(ctx context.Context, filter Tuple, continuationToken string) ([]Tuple, string, error)
+	// ReadModel returns the authorization model with id, or the active
+	// model when id is empty.
+	ReadModel(ctx context.Context, id string) (*model.Model, error)
+	// GetStore describes the configured store.
+	GetStore(ctx context.Context) (Store, error)
+	// ReadChanges returns one page of the changes feed, oldest first,
+	// optionally limited to objectType. Unlike Read, the returned token is
+	// kept at the end of the feed so it can be polled for new changes.
+	ReadChanges(ctx context.Context, objectType, continuationToken string) ([]Change, string, error)
+	// ListUsers returns the users with relation on object. Each filter is a
+	// user type ("user") or a userset type ("group#member"); results are
+	// users, wildcards ("user:*") or usersets ("group:eng#member").
+	ListUsers(ctx context.Context, object, relation string, userFilters []string) ([]string, error)
+}
+
+// Store describes an OpenFGA store.
+type Store struct {
+	ID        string
+	Name      string
+	CreatedAt time.Time
+}
+
+// Client exposes the enforcement helpers on top of a Backend.
+type Client struct {
+	backend   Backend
+	policies  policy.Map
+	guards    []WriteGuard
+	observers []DecisionObserver
+	ids       *ids.Policy
+	log       *slog.Logger
+	selfTest  []Assertion
+	aliases   Aliases
+	lister    Lister
+	contexts  *contextPipeline
+
+	impersonation *impersonationCheck
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithPolicy sets the operation table used by Require.
+func WithPolicy(m policy.Map) Option {
+	return func(c *Client) { c.policies = m }
+}
+
+// WithIDPolicy normalizes every user and object passed to the client's
+// helpers through p; invalid identifiers fail before reaching the server.
+// The backend is wrapped with NormalizeIDs, so Client.Backend() applies p
+// too.
+func WithIDPolicy(p *ids.Policy) Option {
+	return func(c *Client) { c.ids = p }
+}
+
+// WithLogger sets the logger for client calls. Checks log at debug level and
+// failures at warn; see package logging for per-subsystem loggers.
+func WithLogger(l *slog.Logger) Option {
+	return func(c *Client) { c.log = l }
+}
+
+// New returns a Client that talks to backend.
+func New(backend Backend, opts ...Option) *Client {
+	c := &Client{backend: backend, log: slog.New(discardHandler{})}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.ids != nil {
+		c.backend = NormalizeIDs(c.backend, c.ids)
+	}
+	return c
+}
+
+// Check reports whether user has relation on object.
+func (c *Client) Check(ctx context.Context, user, relation, object string) (bool, error) {
+	user, object, err := c.normalize(user, object)
+	if err != nil {
+		return false, err
+	}
+	req := CheckRequest{User: user, Relation: relation, Object: object}
+	if c.contexts != nil {
+		if ctx, err = c.contexts.apply(ctx, req); err != nil {
+			return false, err
+		}
+	}
+	start := time.Now()
+	allowed, err := c.backend.Check(ctx, req)
+	if err == nil && !allowed {
+		allowed, err = c.checkPrevious(ctx, user, relation, object)
+	}
+	c.observe(ctx, Decision{User: user, Relation: relation, Object: object, Allowed: allowed && err == nil, Err: err,
+		Time: start, Duration: time.Since(start)})
+	if err != nil {
+		c.log.WarnContext(ctx, "check failed", auditArgs(ctx, "user", user, "relation", relation, "object", object, "error", err)...)
+		return false, fmt.Errorf("authz: check %s#%s@%s: %w", object, relation, user, err)
+	}
+	c.log.DebugContext(ctx, "check", auditArgs(ctx, "user", user, "relation", relation, "object", object,
+		"allowed", allowed, "duration", time.Since(start))...)
+	return allowed, nil
+}
+
+// Require resolves operation through the policy map and checks that the user
+// carried by ctx holds the required relation on the object with objectID.
+// It returns nil when allowed and an error wrapping ErrForbidden when denied.
+func (c *Client) Require(ctx context.Context, operation, objectID string) error {
+	if c.policies == nil {
+		return ErrNoPolicy
+	}
+	rule, err := c.policies.Lookup(operation)
+	if err != nil {
+		return err
+	}
+	user, ok := UserFromContext(ctx)
+	if !ok {
+		return ErrNoUser
+	}
+	object := rule.Object(objectID)
+	allowed, err := c.Check(ctx, user, rule.Relation, object)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return fmt.Errorf("%w: %s may not %s %s", ErrForbidden, user, operation, object)
+	}
+	return nil
+}
+
+// normalize applies the client's ID policy and aliases, if any, to a user
+// and object.
+func (c *Client) normalize(user, object string) (string, string, error) {
+	if c.ids != nil {
+		var err error
+		if user, object, err = normalizeIDs(c.ids, user, object); err != nil {
+			return "", "", err
+		}
+	}
+	if c.aliases != nil {
+		user, object = c.currentUser(user), c.aliases.Current(object)
+	}
+	return user, object, nil
+}
+
+func (c *Client) normalizeTuples(ts []Tuple) ([]Tuple, error) {
+	if c.ids == nil && c.aliases == nil || len(ts) == 0 {
+		return ts, nil
+	}
+	out := make([]Tuple, len(ts))
+	for i, t := range ts {
+		user, object, err := c.normalize(t.User, t.Object)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = Tuple{User: user, Relation: t.Relation, Object: object, Condition: t.Condition}
+	}
+	return out, nil
+}
+
+// Policies returns the configured operation table.
+func (c *Client) Policies() policy.Map {
+	return c.policies
+}
+
+type userKey struct{}
+
+// WithUser returns a context carrying the calling user, e.g. "user:alice".
+// Middleware typically sets it once per request.
+func WithUser(ctx context.Context, user string) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// UserFromContext returns the user stored by WithUser.
+func UserFromContext(ctx context.Context) (string, bool) {
+	user, ok := ctx.Value(userKey{}).(string)
+	return user, ok && user != ""
+}
+
+// discardHandler is the default log handler; slog.DiscardHandler needs Go 1.24.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
@@ -0,0 +1,221 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// BatchConcurrency is the number of calls CheckMany runs at once.
+const BatchConcurrency = 8
+
+// ItemResult is the outcome of one item of a batch.
+type ItemResult struct {
+	// Index is the item's position in the batch as submitted.
+	Index int
+	Tuple Tuple
+	// Op is the mutation for write batches, empty for checks.
+	Op Operation
+	// Allowed is the answer for checks.
+	Allowed bool
+	Err     error
+	// Retryable reports whether the failure looks transient; see Retryable.
+	Retryable bool
+}
+
+// BatchResult is the per-item outcome of a batch operation such as
+// CheckMany, WriteMany or WriteBatched. Batches do not fail as a whole: every item
+// reports its own status, and Retry resubmits the ones that failed.
+type BatchResult struct {
+	Items []ItemResult
+	run   func(ctx context.Context, items []ItemResult) []ItemResult
+}
+
+// Failed returns the items that failed.
+func (r *BatchResult) Failed() []ItemResult {
+	var out []ItemResult
+	for _, it := range r.Items {
+		if it.Err != nil {
+			out = append(out, it)
+		}
+	}
+	return out
+}
+
+// Err returns nil when every item succeeded and a *PartialError with the
+// item errors otherwise.
+func (r *BatchResult) Err() error {
+	failed := r.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+	errs := make([]error, len(failed))
+	for i, it := range failed {
+		errs[i] = fmt.Errorf("%s: %w", it.Tuple, it.Err)
+	}
+	return &PartialError{Op: "authz: batch", Failed: len(failed), Total: len(r.Items), Errs: errs}
+}
+
+// RetryScope selects the failed items Retry resubmits.
+type RetryScope int
+
+const (
+	// RetryTransient resubmits the failures that look transient; see
+	// ItemResult.Retryable.
+	RetryTransient RetryScope = iota
+	// RetryAllFailed resubmits every failure, e.g. after fixing the model
+	// that rejected them.
+	RetryAllFailed
+)
+
+// Retry resubmits the failed items scope selects and returns the batch with
+// their new outcomes. Items that succeeded, and failures outside scope, are
+// carried over as they were.
+func (r *BatchResult) Retry(ctx context.Context, scope RetryScope) *BatchResult {
+	var again []ItemResult
+	for _, it := range r.Items {
+		if it.Err != nil && (it.Retryable || scope == RetryAllFailed) {
+			again = append(again, it)
+		}
+	}
+	out := &BatchResult{Items: append([]ItemResult(nil), r.Items...), run: r.run}
+	if len(again) == 0 {
+		return out
+	}
+	for _, it := range r.run(ctx, again) {
+		out.Items[it.Index] = it
+	}
+	return out
+}
+
+// Append adds o's items to r, renumbered to follow r's. o must come from
+// the same operation on the same backend, e.g. successive chunks of one
+// import, since Retry resubmits every item the way r's were.
+func (r *BatchResult) Append(o *BatchResult) {
+	for _, it := range o.Items {
+		it.Index = len(r.Items)
+		r.Items = append(r.Items, it)
+	}
+	if r.run == nil {
+		r.run = o.run
+	}
+}
+
+// Retryable reports whether err looks like the server being down or
+// overloaded, so the same request may succeed later: network failures,
+// timeouts, 429 and 5xx responses, and responses that never arrived
+// (status 0).
+func Retryable(err error) bool {
+	var sc interface{ ResponseStatusCode() int }
+	if errors.As(err, &sc) {
+		code := sc.ResponseStatusCode()
+		return code == http.StatusTooManyRequests || code >= 500 || code == 0
+	}
+	var ne net.Error
+	return errors.As(err, &ne) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// Duplicate reports whether err is the server refusing a write of a tuple
+// that already exists or a delete of one that does not, which is how a
+// replayed mutation that was already applied fails.
+func Duplicate(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "tuple which already exists") || strings.Contains(msg, "tuple which does not exist") ||
+		strings.Contains(msg, "tuple already exists") || strings.Contains(msg, "tuple does not exist")
+}
+
+func (it ItemResult) fail(err error) ItemResult {
+	it.Err, it.Retryable, it.Allowed = err, err != nil && Retryable(err), false
+	return it
+}
+
+// CheckMany runs the checks concurrently, splitting ctx's deadline across
+// them, and reports each answer or error.
+func (c *Client) CheckMany(ctx context.Context, reqs []CheckRequest) *BatchResult {
+	items := make([]ItemResult, len(reqs))
+	for i, req := range reqs {
+		items[i] = ItemResult{Index: i, Tuple: Tuple{User: req.User, Relation: req.Relation, Object: req.Object}}
+	}
+	r := &BatchResult{Items: items, run: c.checkItems}
+	r.Items = c.checkItems(ctx, items)
+	return r
+}
+
+func (c *Client) checkItems(ctx context.Context, items []ItemResult) []ItemResult {
+	out := make([]ItemResult, len(items))
+	budget := NewBudget(ctx, len(items), BatchConcurrency)
+	sem := make(chan struct{}, BatchConcurrency)
+	var wg sync.WaitGroup
+	for i, it := range items {
+		wg.Add(1)
+		go func(i int, it ItemResult) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				out[i] = it.fail(ctx.Err())
+				return
+			}
+			cctx, cancel := budget.Next()
+			defer cancel()
+			allowed, err := c.Check(cctx, it.Tuple.User, it.Tuple.Relation, it.Tuple.Object)
+			out[i] = it.fail(err)
+			out[i].Allowed = allowed && err == nil
+		}(i, it)
+	}
+	wg.Wait()
+	return out
+}
+
+// WriteMany applies writes and deletes in batches of MaxWriteTuples through
+// w. A batch the server refuses is retried item by item, so one bad tuple
+// only fails itself; unlike Write, the mutation is not atomic.
+func WriteMany(ctx context.Context, w TupleWriter, writes, deletes []Tuple) *BatchResult {
+	items := make([]ItemResult, 0, len(writes)+len(deletes))
+	for _, t := range writes {
+		items = append(items, ItemResult{Index: len(items), Tuple: t, Op: OpWrite})
+	}
+	for _, t := range deletes {
+		items = append(items, ItemResult{Index: len(items), Tuple: t, Op: OpDelete})
+	}
+	run := func(ctx context.Context, items []ItemResult) []ItemResult { return writeItems(ctx, w, items) }
+	return &BatchResult{Items: run(ctx, items), run: run}
+}
+
+func writeItems(ctx context.Context, w TupleWriter, items []ItemResult) []ItemResult {
+	out := make([]ItemResult, 0, len(items))
+	for len(items) > 0 {
+		batch := items[:min(len(items), MaxWriteTuples)]
+		items = items[len(batch):]
+		if err := writeItemBatch(ctx, w, batch); err == nil || len(batch) == 1 {
+			for _, it := range batch {
+				out = append(out, it.fail(err))
+			}
+			continue
+		}
+		for _, it := range batch {
+			out = append(out, it.fail(writeItemBatch(ctx, w, []ItemResult{it})))
+		}
+	}
+	return out
+}
+
+func writeItemBatch(ctx context.Context, w TupleWriter, items []ItemResult) error {
+	var writes, deletes []Tuple
+	for _, it := range items {
+		if it.Op == OpDelete {
+			deletes = append(deletes, it.Tuple)
+		} else {
+			writes = append(writes, it.Tuple)
+		}
+	}
+	return w.Write(ctx, writes, deletes)
+}
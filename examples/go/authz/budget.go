@@ -0,0 +1,107 @@
+package authz
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// PartialError is returned by composite helpers when some of their
+// sub-calls failed, alongside the results of the ones that succeeded.
+// errors.Is sees through it to the individual failures, e.g.
+// context.DeadlineExceeded.
+type PartialError struct {
+	Op     string
+	Failed int
+	Total  int
+	Errs   []error
+}
+
+func (e *PartialError) Error() string {
+	msg := fmt.Sprintf("%s: %d of %d calls failed", e.Op, e.Failed, e.Total)
+	if len(e.Errs) > 0 {
+		msg += ", first: " + e.Errs[0].Error()
+	}
+	return msg
+}
+
+func (e *PartialError) Unwrap() []error { return e.Errs }
+
+// Budget divides the time left before a context's deadline among the
+// sub-calls of a composite operation, so one slow call cannot use it all
+// and starve the rest.
+type Budget struct {
+	ctx      context.Context
+	parallel int
+
+	mu   sync.Mutex
+	left int
+}
+
+// NewBudget returns a budget for calls sub-calls of which up to parallel
+// run at once.
+func NewBudget(ctx context.Context, calls, parallel int) *Budget {
+	return &Budget{ctx: ctx, parallel: max(parallel, 1), left: max(calls, 1)}
+}
+
+// Next returns the context for the next sub-call. Its deadline is an even
+// share of the time left over the waves of calls still to run, so time a
+// fast call leaves unused goes to the ones after it and the last wave gets
+// everything that remains. Without a deadline on the parent, Next only
+// adds cancellation.
+func (b *Budget) Next() (context.Context, context.CancelFunc) {
+	b.mu.Lock()
+	left := b.left
+	if b.left > 1 {
+		b.left--
+	}
+	b.mu.Unlock()
+	deadline, ok := b.ctx.Deadline()
+	if !ok {
+		return context.WithCancel(b.ctx)
+	}
+	waves := (left + b.parallel - 1) / b.parallel
+	return context.WithTimeout(b.ctx, time.Until(deadline)/time.Duration(waves))
+}
+
+// ListRelations returns the relations of object's type that user holds on
+// object, in model order. Each relation is checked with a share of ctx's
+// deadline; when some checks fail, the relations confirmed so far are
+// returned with a *PartialError.
+func (c *Client) ListRelations(ctx context.Context, user string, object Object) ([]string, error) {
+	m, err := c.backend.ReadModel(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("authz: read model: %w", err)
+	}
+	t := m.Type(object.Type)
+	if t == nil {
+		return nil, fmt.Errorf("authz: type %s is not in the model", object.Type)
+	}
+	budget := NewBudget(ctx, len(t.Relations), 1)
+	var held []string
+	var errs []error
+	answered := 0
+	for _, r := range t.Relations {
+		if err := ctx.Err(); err != nil {
+			// Out of time altogether; the rest would fail the same way.
+			errs = append(errs, err)
+			break
+		}
+		cctx, cancel := budget.Next()
+		allowed, err := c.Check(cctx, user, r.Name, object.String())
+		cancel()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		answered++
+		if allowed {
+			held = append(held, r.Name)
+		}
+	}
+	if len(errs) > 0 {
+		return held, &PartialError{Op: "authz: list relations", Failed: len(t.Relations) - answered, Total: len(t.Relations), Errs: errs}
+	}
+	return held, nil
+}
@@ -0,0 +1,108 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// The interfaces below each cover one capability of Client so that code can
+// depend on just what it uses and tests can substitute a small double; see
+// the mocks in package fgatest. TupleWriter covers writes.
+
+// Checker answers authorization questions.
+type Checker interface {
+	Check(ctx context.Context, user, relation, object string) (bool, error)
+}
+
+// Lister lists the objects of a type on which a user has a relation.
+type Lister interface {
+	ListObjects(ctx context.Context, user, relation, objectType string) ([]string, error)
+}
+
+// Expander expands a relation on an object into the users holding it. Each
+// filter is a user type ("user") or a userset type ("group#member").
+type Expander interface {
+	ListUsers(ctx context.Context, object, relation string, userFilters []string) ([]string, error)
+}
+
+// StoreAdmin describes the store and its authorization models.
+type StoreAdmin interface {
+	GetStore(ctx context.Context) (Store, error)
+	ReadModel(ctx context.Context, id string) (*model.Model, error)
+}
+
+var (
+	_ Checker     = (*Client)(nil)
+	_ TupleWriter = (*Client)(nil)
+	_ Lister      = (*Client)(nil)
+	_ Expander    = (*Client)(nil)
+	_ StoreAdmin  = (*Client)(nil)
+)
+
+// ErrNoLister is returned by ListObjects when neither the backend nor
+// WithLister provides ListObjects.
+var ErrNoLister = errors.New("authz: no lister configured")
+
+// WithLister sets the Lister used by ListObjects, for backends that do not
+// implement it themselves; effective.SDKLister covers the official client.
+func WithLister(l Lister) Option {
+	return func(c *Client) { c.lister = l }
+}
+
+// ListObjects returns the objects of objectType on which user has relation.
+func (c *Client) ListObjects(ctx context.Context, user, relation, objectType string) ([]string, error) {
+	l := c.lister
+	if l == nil {
+		var ok bool
+		if l, ok = c.backend.(Lister); !ok {
+			return nil, ErrNoLister
+		}
+	}
+	if c.ids != nil {
+		var err error
+		if user, err = c.ids.User(user); err != nil {
+			return nil, fmt.Errorf("authz: %w", err)
+		}
+	}
+	if c.aliases != nil {
+		user = c.currentUser(user)
+	}
+	objects, err := l.ListObjects(ctx, user, relation, objectType)
+	if err != nil {
+		return nil, fmt.Errorf("authz: list objects %s#%s@%s: %w", objectType, relation, user, err)
+	}
+	return objects, nil
+}
+
+// ListUsers returns the users with relation on object; see
+// Backend.ListUsers.
+func (c *Client) ListUsers(ctx context.Context, object, relation string, userFilters []string) ([]string, error) {
+	if c.ids != nil {
+		var err error
+		if object, err = c.ids.Object(object); err != nil {
+			return nil, fmt.Errorf("authz: %w", err)
+		}
+	}
+	if c.aliases != nil {
+		object = c.aliases.Current(object)
+	}
+	users, err := c.backend.ListUsers(ctx, object, relation, userFilters)
+	if err != nil {
+		return nil, fmt.Errorf("authz: list users %s#%s: %w", object, relation, err)
+	}
+	return users, nil
+}
+
+// GetStore describes the store the client talks to.
+func (c *Client) GetStore(ctx context.Context) (Store, error) {
+	return c.backend.GetStore(ctx)
+}
+
+// ReadModel returns the authorization model with id, or the active model
+// when id is empty.
+func (c *Client) ReadModel(ctx context.Context, id string) (*model.Model, error) {
+	return c.backend.ReadModel(ctx, id)
+}
@@ -0,0 +1,42 @@
+package authz
+
+import (
+	"context"
+	"time"
+)
+
+// Operation is the kind of tuple change.
+type Operation string
+
+const (
+	OpWrite  Operation = "write"
+	OpDelete Operation = "delete"
+)
+
+// Change is one entry of the store's changes feed.
+type Change struct {
+	Tuple     Tuple     `json:"tuple"`
+	Operation Operation `json:"operation"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// ReadAllChanges reads the changes feed from continuationToken to its
+// current end and returns the changes with the token to resume from.
+func ReadAllChanges(ctx context.Context, b Backend, objectType, continuationToken string) ([]Change, string, error) {
+	var all []Change
+	token := continuationToken
+	for {
+		page, next, err := b.ReadChanges(ctx, objectType, token)
+		if err != nil {
+			return all, token, err
+		}
+		all = append(all, page...)
+		if len(page) == 0 || next == "" || next == token {
+			if next != "" {
+				token = next
+			}
+			return all, token, nil
+		}
+		token = next
+	}
+}
@@ -0,0 +1,135 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// ErrNotFlushed is returned by Pending.Result before the collector it was
+// queued on has been flushed.
+var ErrNotFlushed = errors.New("authz: check not flushed")
+
+// Collector queues checks made while handling one request and resolves
+// them together on Flush, so a page that checks each row it renders waits
+// for the checks concurrently rather than one after another:
+//
+//	col := authz.CollectorFromContext(r.Context())
+//	for i, doc := range docs {
+//		rows[i].CanEdit = col.Queue(user, "editor", doc.Object())
+//	}
+//	if err := col.Flush(r.Context()); err != nil { ... }
+//	tmpl.Execute(w, rows) // {{if .CanEdit.Allowed}}
+//
+// Identical checks are queued once, which is the only way a Collector
+// reduces the number of calls: Flush uses CheckMany, which still makes one
+// Check per distinct question, BatchConcurrency at a time. Checks queued
+// after a flush wait for the next one.
+type Collector struct {
+	client *Client
+
+	mu      sync.Mutex
+	pending map[CheckRequest]*Pending
+	queued  []*Pending
+}
+
+// Pending is the future answer to a queued check.
+type Pending struct {
+	Request CheckRequest
+
+	mu      sync.Mutex
+	done    bool
+	allowed bool
+	err     error
+}
+
+// Result returns the answer, or ErrNotFlushed before the flush.
+func (p *Pending) Result() (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.done {
+		return false, ErrNotFlushed
+	}
+	return p.allowed, p.err
+}
+
+// Allowed reports whether the check was flushed and allowed. It fails
+// closed, for templates that cannot handle errors.
+func (p *Pending) Allowed() bool {
+	allowed, err := p.Result()
+	return allowed && err == nil
+}
+
+// Collector returns an empty collector over c.
+func (c *Client) Collector() *Collector {
+	return &Collector{client: c, pending: map[CheckRequest]*Pending{}}
+}
+
+// Queue adds a check to the next flush.
+func (col *Collector) Queue(user, relation, object string) *Pending {
+	req := CheckRequest{User: user, Relation: relation, Object: object}
+	col.mu.Lock()
+	defer col.mu.Unlock()
+	if p, ok := col.pending[req]; ok {
+		return p
+	}
+	p := &Pending{Request: req}
+	col.pending[req] = p
+	col.queued = append(col.queued, p)
+	return p
+}
+
+// QueueRef is Queue with typed references.
+func (col *Collector) QueueRef(user User, relation string, object Object) *Pending {
+	return col.Queue(user.String(), relation, object.String())
+}
+
+// Flush runs the queued checks through CheckMany and resolves them. The
+// error is a *PartialError when some checks failed; their Pendings carry
+// the individual errors.
+func (col *Collector) Flush(ctx context.Context) error {
+	col.mu.Lock()
+	queued := col.queued
+	col.queued = nil
+	col.mu.Unlock()
+	if len(queued) == 0 {
+		return nil
+	}
+	reqs := make([]CheckRequest, len(queued))
+	for i, p := range queued {
+		reqs[i] = p.Request
+	}
+	res := col.client.CheckMany(ctx, reqs)
+	for _, it := range res.Items {
+		p := queued[it.Index]
+		p.mu.Lock()
+		p.done, p.allowed, p.err = true, it.Allowed, it.Err
+		p.mu.Unlock()
+	}
+	return res.Err()
+}
+
+type collectorKey struct{}
+
+// WithCollector returns a context carrying col; see CollectorFromContext.
+func WithCollector(ctx context.Context, col *Collector) context.Context {
+	return context.WithValue(ctx, collectorKey{}, col)
+}
+
+// CollectorFromContext returns the collector stored by WithCollector or
+// CollectChecks, or nil.
+func CollectorFromContext(ctx context.Context) *Collector {
+	col, _ := ctx.Value(collectorKey{}).(*Collector)
+	return col
+}
+
+// CollectChecks is middleware giving every request its own Collector over
+// c, so handler code anywhere can queue checks for the request.
+func CollectChecks(c *Client) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(WithCollector(r.Context(), c.Collector())))
+		})
+	}
+}
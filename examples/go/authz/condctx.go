@@ -0,0 +1,295 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/http"
+	"net/netip"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// ErrConditionContext is wrapped by errors for condition context values
+// that do not match the parameter types the model declares.
+var ErrConditionContext = errors.New("authz: invalid condition context")
+
+type conditionContextKey struct{}
+
+// WithConditionContext returns a context whose checks send values as the
+// condition evaluation context, on top of what context providers supply.
+func WithConditionContext(ctx context.Context, values map[string]any) context.Context {
+	merged := maps.Clone(ConditionContextFrom(ctx))
+	if merged == nil {
+		merged = map[string]any{}
+	}
+	maps.Copy(merged, values)
+	return context.WithValue(ctx, conditionContextKey{}, merged)
+}
+
+// ConditionContextFrom returns the condition context set on ctx. Backends
+// from FromSDK send it with every check.
+func ConditionContextFrom(ctx context.Context) map[string]any {
+	v, _ := ctx.Value(conditionContextKey{}).(map[string]any)
+	return v
+}
+
+// ContextProvider adds condition parameters for a check, e.g. the client's
+// IP or the tenant's plan, to values.
+type ContextProvider interface {
+	Provide(ctx context.Context, req CheckRequest, values map[string]any) error
+}
+
+// ContextProviderFunc adapts a function to ContextProvider.
+type ContextProviderFunc func(ctx context.Context, req CheckRequest, values map[string]any) error
+
+// Provide calls f.
+func (f ContextProviderFunc) Provide(ctx context.Context, req CheckRequest, values map[string]any) error {
+	return f(ctx, req, values)
+}
+
+// WithContextProviders assembles the condition context of every Check from
+// providers, in order, with values from WithConditionContext taking
+// precedence. Only relations the model can satisfy through a condition get
+// a context, it holds only the parameters those conditions declare, and
+// each value is checked against the declared type. The model is cached for
+// a minute.
+//
+//	az := authz.New(backend, authz.WithContextProviders(
+//		authz.CurrentTime("current_time"),
+//		authz.Attributes(),
+//		authz.ContextProviderFunc(func(ctx context.Context, _ authz.CheckRequest, v map[string]any) error {
+//			v["plan"] = tenantPlan(ctx)
+//			return nil
+//		}),
+//	))
+func WithContextProviders(providers ...ContextProvider) Option {
+	return func(c *Client) {
+		c.contexts = &contextPipeline{providers: providers, models: NewModelValidator(c.backend, time.Minute)}
+	}
+}
+
+type contextPipeline struct {
+	providers []ContextProvider
+	models    *ModelValidator
+}
+
+// apply returns ctx carrying the validated condition context for req.
+func (p *contextPipeline) apply(ctx context.Context, req CheckRequest) (context.Context, error) {
+	m, err := p.models.Model(ctx)
+	if err != nil {
+		return ctx, err
+	}
+	typ, _, _ := strings.Cut(req.Object, ":")
+	params := conditionParams(m, typ, req.Relation)
+	if len(params) == 0 {
+		return ctx, nil
+	}
+	values := map[string]any{}
+	for _, pr := range p.providers {
+		if err := pr.Provide(ctx, req, values); err != nil {
+			return ctx, fmt.Errorf("authz: condition context: %w", err)
+		}
+	}
+	maps.Copy(values, ConditionContextFrom(ctx))
+	out := make(map[string]any, len(values))
+	for name, v := range values {
+		typ, ok := params[name]
+		if !ok {
+			continue
+		}
+		nv, err := conditionValue(typ, v)
+		if err != nil {
+			return ctx, fmt.Errorf("%w: %s: %v", ErrConditionContext, name, err)
+		}
+		out[name] = nv
+	}
+	return context.WithValue(ctx, conditionContextKey{}, out), nil
+}
+
+// conditionParams returns the parameters, with their types, of every
+// condition that can take part in resolving typ#relation.
+func conditionParams(m *model.Model, typ, relation string) map[string]string {
+	type node struct{ typ, rel string }
+	params := map[string]string{}
+	seen := map[node]bool{}
+	stack := []node{{typ, relation}}
+	addDirect := func(refs []model.TypeRef) {
+		for _, ref := range refs {
+			if c := m.Condition(ref.Condition); c != nil {
+				for _, p := range c.Params {
+					params[p.Name] = p.Type
+				}
+			}
+			if ref.Relation != "" {
+				stack = append(stack, node{ref.Type, ref.Relation})
+			}
+		}
+	}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[n] {
+			continue
+		}
+		seen[n] = true
+		rel := m.Relation(n.typ, n.rel)
+		if rel == nil {
+			continue
+		}
+		model.Walk(rel.Rewrite, func(rw model.Rewrite) {
+			switch rw := rw.(type) {
+			case *model.Direct:
+				addDirect(rw.Types)
+			case *model.Computed:
+				stack = append(stack, node{n.typ, rw.Relation})
+			case *model.TupleToUserset:
+				if ts := m.Relation(n.typ, rw.Tupleset); ts != nil {
+					refs := ts.DirectTypes()
+					addDirect(refs)
+					for _, ref := range refs {
+						stack = append(stack, node{ref.Type, rw.Computed})
+					}
+				}
+			}
+		})
+	}
+	return params
+}
+
+// conditionValue checks v against a condition parameter type and returns
+// it in the form the server expects: times as RFC 3339 strings and
+// durations in Go syntax.
+func conditionValue(typ string, v any) (any, error) {
+	switch t := v.(type) {
+	case time.Time:
+		v = t.UTC().Format(time.RFC3339Nano)
+	case time.Duration:
+		v = t.String()
+	case netip.Addr:
+		v = t.String()
+	case net.IP:
+		v = t.String()
+	}
+	rv := reflect.ValueOf(v)
+	kind := reflect.Invalid
+	if rv.IsValid() {
+		kind = rv.Kind()
+	}
+	isInt := reflect.Int <= kind && kind <= reflect.Int64
+	isUint := reflect.Uint <= kind && kind <= reflect.Uintptr
+	isFloat := kind == reflect.Float32 || kind == reflect.Float64
+	wrong := fmt.Errorf("%T is not a %s", v, typ)
+	switch base, _, _ := strings.Cut(typ, "<"); base {
+	case "any":
+		return v, nil
+	case "string":
+		if kind == reflect.String {
+			return v, nil
+		}
+	case "bool":
+		if kind == reflect.Bool {
+			return v, nil
+		}
+	case "int":
+		if isInt || isUint || isFloat && rv.Float() == float64(int64(rv.Float())) {
+			return v, nil
+		}
+	case "uint":
+		if isUint || isInt && rv.Int() >= 0 || isFloat && rv.Float() >= 0 && rv.Float() == float64(uint64(rv.Float())) {
+			return v, nil
+		}
+	case "double":
+		if isFloat || isInt || isUint {
+			return v, nil
+		}
+	case "timestamp":
+		if kind == reflect.String {
+			if _, err := time.Parse(time.RFC3339Nano, rv.String()); err != nil {
+				return nil, err
+			}
+			return v, nil
+		}
+	case "duration":
+		if kind == reflect.String {
+			if _, err := time.ParseDuration(rv.String()); err != nil {
+				return nil, err
+			}
+			return v, nil
+		}
+	case "ipaddress":
+		if kind == reflect.String {
+			if _, err := netip.ParseAddr(rv.String()); err != nil {
+				return nil, err
+			}
+			return v, nil
+		}
+	case "list":
+		if kind == reflect.Slice || kind == reflect.Array {
+			return v, nil
+		}
+	case "map":
+		if kind == reflect.Map && rv.Type().Key().Kind() == reflect.String {
+			return v, nil
+		}
+	default:
+		return nil, fmt.Errorf("unknown parameter type %s", typ)
+	}
+	return nil, wrong
+}
+
+// CurrentTime provides the time of the check as param, a timestamp.
+func CurrentTime(param string) ContextProvider {
+	return ContextProviderFunc(func(_ context.Context, _ CheckRequest, values map[string]any) error {
+		values[param] = time.Now()
+		return nil
+	})
+}
+
+type attributesKey struct{}
+
+// WithAttributes returns a context carrying request attributes, such as
+// the ones HTTPAttributes extracts, for the Attributes provider.
+func WithAttributes(ctx context.Context, attrs map[string]any) context.Context {
+	merged := maps.Clone(AttributesFrom(ctx))
+	if merged == nil {
+		merged = map[string]any{}
+	}
+	maps.Copy(merged, attrs)
+	return context.WithValue(ctx, attributesKey{}, merged)
+}
+
+// AttributesFrom returns the attributes set by WithAttributes.
+func AttributesFrom(ctx context.Context) map[string]any {
+	v, _ := ctx.Value(attributesKey{}).(map[string]any)
+	return v
+}
+
+// Attributes provides the request attributes on the context; parameters
+// no condition declares are dropped before the check is sent.
+func Attributes() ContextProvider {
+	return ContextProviderFunc(func(ctx context.Context, _ CheckRequest, values map[string]any) error {
+		maps.Copy(values, AttributesFrom(ctx))
+		return nil
+	})
+}
+
+// HTTPAttributes returns the attributes of r usable by conditions:
+// "ip", the peer address, and "user_agent". Behind a proxy, replace "ip"
+// with the client address the proxy reports.
+func HTTPAttributes(r *http.Request) map[string]any {
+	attrs := map[string]any{"user_agent": r.UserAgent()}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if ip, err := netip.ParseAddr(host); err == nil {
+		attrs["ip"] = ip.Unmap().String()
+	}
+	return attrs
+}
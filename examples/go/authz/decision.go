@@ -0,0 +1,48 @@
+package authz
+
+import (
+	"context"
+	"time"
+)
+
+// Decision is the outcome of one Client.Check, as passed to decision
+// observers.
+type Decision struct {
+	User         string
+	Relation     string
+	Object       string
+	Allowed      bool
+	Impersonator string
+	// Err is set when the check failed; Allowed is then false.
+	Err      error
+	Time     time.Time
+	Duration time.Duration
+}
+
+// DecisionObserver receives every decision made by Client.Check, on the
+// request path: implementations must return quickly and not block.
+type DecisionObserver interface {
+	ObserveDecision(ctx context.Context, d Decision)
+}
+
+// DecisionObserverFunc adapts a function to DecisionObserver.
+type DecisionObserverFunc func(ctx context.Context, d Decision)
+
+// ObserveDecision calls f.
+func (f DecisionObserverFunc) ObserveDecision(ctx context.Context, d Decision) { f(ctx, d) }
+
+// WithDecisionObserver adds an observer of every check, e.g. a decision log
+// or an anomaly detector. Observers run in the order they were configured.
+func WithDecisionObserver(o DecisionObserver) Option {
+	return func(c *Client) { c.observers = append(c.observers, o) }
+}
+
+func (c *Client) observe(ctx context.Context, d Decision) {
+	if len(c.observers) == 0 {
+		return
+	}
+	d.Impersonator, _ = Impersonator(ctx)
+	for _, o := range c.observers {
+		o.ObserveDecision(ctx, d)
+	}
+}
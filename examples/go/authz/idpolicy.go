@@ -0,0 +1,111 @@
+package authz
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bogdanticu88/openfga-examples/ids"
+)
+
+// NormalizeIDs returns a Backend that applies p to every user and object it
+// is passed: checks, writes, read filters and list queries. A Client built
+// WithIDPolicy wraps its backend with it, so code reading or writing through
+// Client.Backend() sees the same identifiers as the client's helpers.
+func NormalizeIDs(b Backend, p *ids.Policy) Backend {
+	return &idBackend{Backend: b, ids: p}
+}
+
+type idBackend struct {
+	Backend
+	ids *ids.Policy
+}
+
+func (b *idBackend) Check(ctx context.Context, req CheckRequest) (bool, error) {
+	user, object, err := normalizeIDs(b.ids, req.User, req.Object)
+	if err != nil {
+		return false, err
+	}
+	req.User, req.Object = user, object
+	return b.Backend.Check(ctx, req)
+}
+
+func (b *idBackend) Write(ctx context.Context, writes, deletes []Tuple) error {
+	writes, err := normalizeTupleIDs(b.ids, writes)
+	if err != nil {
+		return err
+	}
+	if deletes, err = normalizeTupleIDs(b.ids, deletes); err != nil {
+		return err
+	}
+	return b.Backend.Write(ctx, writes, deletes)
+}
+
+// Read normalizes the parts of filter that are set. An object of the form
+// "type:" matches every object of type and is passed unchanged.
+func (b *idBackend) Read(ctx context.Context, filter Tuple, continuationToken string) ([]Tuple, string, error) {
+	if filter.User != "" {
+		user, err := b.ids.User(filter.User)
+		if err != nil {
+			return nil, "", fmt.Errorf("authz: %w", err)
+		}
+		filter.User = user
+	}
+	if filter.Object != "" && !strings.HasSuffix(filter.Object, ":") {
+		object, err := b.ids.Object(filter.Object)
+		if err != nil {
+			return nil, "", fmt.Errorf("authz: %w", err)
+		}
+		filter.Object = object
+	}
+	return b.Backend.Read(ctx, filter, continuationToken)
+}
+
+func (b *idBackend) ListUsers(ctx context.Context, object, relation string, userFilters []string) ([]string, error) {
+	object, err := b.ids.Object(object)
+	if err != nil {
+		return nil, fmt.Errorf("authz: %w", err)
+	}
+	return b.Backend.ListUsers(ctx, object, relation, userFilters)
+}
+
+// ListObjects implements Lister when the wrapped backend does.
+func (b *idBackend) ListObjects(ctx context.Context, user, relation, objectType string) ([]string, error) {
+	l, ok := b.Backend.(Lister)
+	if !ok {
+		return nil, ErrNoLister
+	}
+	user, err := b.ids.User(user)
+	if err != nil {
+		return nil, fmt.Errorf("authz: %w", err)
+	}
+	return l.ListObjects(ctx, user, relation, objectType)
+}
+
+// normalizeIDs applies p to a user and object.
+func normalizeIDs(p *ids.Policy, user, object string) (string, string, error) {
+	user, err := p.User(user)
+	if err != nil {
+		return "", "", fmt.Errorf("authz: %w", err)
+	}
+	if object, err = p.Object(object); err != nil {
+		return "", "", fmt.Errorf("authz: %w", err)
+	}
+	return user, object, nil
+}
+
+func normalizeTupleIDs(p *ids.Policy, ts []Tuple) ([]Tuple, error) {
+	if len(ts) == 0 {
+		return ts, nil
+	}
+	out := make([]Tuple, len(ts))
+	for i, t := range ts {
+		user, object, err := normalizeIDs(p, t.User, t.Object)
+		if err != nil {
+			return nil, err
+		}
+		t.User, t.Object = user, object
+		out[i] = t
+	}
+	return out, nil
+}
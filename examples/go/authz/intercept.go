@@ -0,0 +1,263 @@
+package authz
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// Operation names used in Call.Op.
+const (
+	OpCheck       = "check"
+	OpWriteTuples = "write"
+	OpRead        = "read"
+	OpReadModel   = "read_model"
+	OpGetStore    = "get_store"
+	OpReadChanges = "read_changes"
+	OpListUsers   = "list_users"
+)
+
+// Call is one backend operation as seen by interceptors. Only the fields
+// of its Op are set; interceptors may modify them before calling next.
+type Call struct {
+	Op string
+	// Check is the question for OpCheck.
+	Check CheckRequest
+	// Writes and Deletes are the mutation for OpWriteTuples.
+	Writes, Deletes []Tuple
+	// Filter and Token page OpRead; Token and ObjectType page
+	// OpReadChanges.
+	Filter     Tuple
+	Token      string
+	ObjectType string
+	// ModelID is the model asked for by OpReadModel.
+	ModelID string
+	// Object, Relation and UserFilters are the query for OpListUsers.
+	Object      string
+	Relation    string
+	UserFilters []string
+}
+
+// Result holds the outputs of a Call; only the fields of its Op are set.
+type Result struct {
+	Allowed bool
+	Tuples  []Tuple
+	Changes []Change
+	Token   string
+	Model   *model.Model
+	Store   Store
+	Users   []string
+}
+
+// Invoker performs a call.
+type Invoker func(ctx context.Context, call *Call) (*Result, error)
+
+// Interceptor wraps every backend operation: it sees the call before it is
+// made and the result or error after, and decides whether and how to call
+// next. Logging, metrics, caching, retries and request rewriting are all
+// interceptors.
+type Interceptor func(ctx context.Context, call *Call, next Invoker) (*Result, error)
+
+// Intercept returns b with interceptors around every operation. The first
+// interceptor is the outermost.
+func Intercept(b Backend, interceptors ...Interceptor) Backend {
+	if len(interceptors) == 0 {
+		return b
+	}
+	invoke := Invoker(func(ctx context.Context, c *Call) (*Result, error) { return dispatch(ctx, b, c) })
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		ic, next := interceptors[i], invoke
+		invoke = func(ctx context.Context, c *Call) (*Result, error) { return ic(ctx, c, next) }
+	}
+	return &interceptedBackend{invoke: invoke}
+}
+
+// WithInterceptors wraps the client's backend with Intercept.
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(c *Client) { c.backend = Intercept(c.backend, interceptors...) }
+}
+
+func dispatch(ctx context.Context, b Backend, c *Call) (*Result, error) {
+	r := &Result{}
+	var err error
+	switch c.Op {
+	case OpCheck:
+		r.Allowed, err = b.Check(ctx, c.Check)
+	case OpWriteTuples:
+		err = b.Write(ctx, c.Writes, c.Deletes)
+	case OpRead:
+		r.Tuples, r.Token, err = b.Read(ctx, c.Filter, c.Token)
+	case OpReadModel:
+		r.Model, err = b.ReadModel(ctx, c.ModelID)
+	case OpGetStore:
+		r.Store, err = b.GetStore(ctx)
+	case OpReadChanges:
+		r.Changes, r.Token, err = b.ReadChanges(ctx, c.ObjectType, c.Token)
+	case OpListUsers:
+		r.Users, err = b.ListUsers(ctx, c.Object, c.Relation, c.UserFilters)
+	}
+	return r, err
+}
+
+type interceptedBackend struct {
+	invoke Invoker
+}
+
+// call invokes the chain, substituting an empty Result for nil.
+func (b *interceptedBackend) call(ctx context.Context, c *Call) (*Result, error) {
+	r, err := b.invoke(ctx, c)
+	if r == nil {
+		r = &Result{}
+	}
+	return r, err
+}
+
+func (b *interceptedBackend) Check(ctx context.Context, req CheckRequest) (bool, error) {
+	r, err := b.call(ctx, &Call{Op: OpCheck, Check: req})
+	return r.Allowed, err
+}
+
+func (b *interceptedBackend) Write(ctx context.Context, writes, deletes []Tuple) error {
+	_, err := b.call(ctx, &Call{Op: OpWriteTuples, Writes: writes, Deletes: deletes})
+	return err
+}
+
+func (b *interceptedBackend) Read(ctx context.Context, filter Tuple, continuationToken string) ([]Tuple, string, error) {
+	r, err := b.call(ctx, &Call{Op: OpRead, Filter: filter, Token: continuationToken})
+	return r.Tuples, r.Token, err
+}
+
+func (b *interceptedBackend) ReadModel(ctx context.Context, id string) (*model.Model, error) {
+	r, err := b.call(ctx, &Call{Op: OpReadModel, ModelID: id})
+	return r.Model, err
+}
+
+func (b *interceptedBackend) GetStore(ctx context.Context) (Store, error) {
+	r, err := b.call(ctx, &Call{Op: OpGetStore})
+	return r.Store, err
+}
+
+func (b *interceptedBackend) ReadChanges(ctx context.Context, objectType, continuationToken string) ([]Change, string, error) {
+	r, err := b.call(ctx, &Call{Op: OpReadChanges, ObjectType: objectType, Token: continuationToken})
+	return r.Changes, r.Token, err
+}
+
+func (b *interceptedBackend) ListUsers(ctx context.Context, object, relation string, userFilters []string) ([]string, error) {
+	r, err := b.call(ctx, &Call{Op: OpListUsers, Object: object, Relation: relation, UserFilters: userFilters})
+	return r.Users, err
+}
+
+// RetryInterceptor retries operations failing with a Retryable error up to
+// attempts times in total, waiting backoff, then twice as long, between
+// tries. Writes are retried too: a write either applies entirely or not at
+// all, but one that timed out after applying fails on retry with the
+// server's duplicate-tuple error.
+func RetryInterceptor(attempts int, backoff time.Duration) Interceptor {
+	return func(ctx context.Context, c *Call, next Invoker) (*Result, error) {
+		wait := backoff
+		for i := 1; ; i++ {
+			r, err := next(ctx, c)
+			if err == nil || i >= attempts || !Retryable(err) {
+				return r, err
+			}
+			select {
+			case <-ctx.Done():
+				return r, err
+			case <-time.After(wait):
+			}
+			wait *= 2
+		}
+	}
+}
+
+// CacheInterceptor caches allowed and denied check answers for ttl. Any
+// write clears the cache, and calls under HigherConsistency, a pinned model
+// or a condition context bypass it, since their answers can differ between
+// calls for the same question. An answer is only stored if no write
+// completed while it was being computed, since it may predate the write.
+// Expired answers are swept out at most once per ttl, when a new answer is
+// stored.
+func CacheInterceptor(ttl time.Duration) Interceptor {
+	type entry struct {
+		allowed bool
+		at      time.Time
+	}
+	var mu sync.Mutex
+	cache := map[CheckRequest]entry{}
+	swept := time.Now()
+	var gen uint64 // bumped by every write
+	return func(ctx context.Context, c *Call, next Invoker) (*Result, error) {
+		switch c.Op {
+		case OpWriteTuples:
+			r, err := next(ctx, c)
+			mu.Lock()
+			cache = map[CheckRequest]entry{}
+			gen++
+			mu.Unlock()
+			return r, err
+		case OpCheck:
+			if o := RequestOptionsFrom(ctx); o.Consistency == HigherConsistency || o.ModelID != "" {
+				return next(ctx, c)
+			}
+			if len(ConditionContextFrom(ctx)) > 0 {
+				return next(ctx, c)
+			}
+			mu.Lock()
+			e, ok := cache[c.Check]
+			started := gen
+			mu.Unlock()
+			if ok && time.Since(e.at) < ttl {
+				return &Result{Allowed: e.allowed}, nil
+			}
+			r, err := next(ctx, c)
+			if err == nil {
+				now := time.Now()
+				mu.Lock()
+				defer mu.Unlock()
+				if gen != started {
+					return r, err
+				}
+				if now.Sub(swept) >= ttl {
+					for k, e := range cache {
+						if now.Sub(e.at) >= ttl {
+							delete(cache, k)
+						}
+					}
+					swept = now
+				}
+				cache[c.Check] = entry{allowed: r.Allowed, at: now}
+			}
+			return r, err
+		}
+		return next(ctx, c)
+	}
+}
+
+// AuditInterceptor logs every check and write with its outcome and
+// duration, at info level, and failures of any operation at warn.
+func AuditInterceptor(l *slog.Logger) Interceptor {
+	return func(ctx context.Context, c *Call, next Invoker) (*Result, error) {
+		start := time.Now()
+		r, err := next(ctx, c)
+		args := []interface{}{"op", c.Op, "duration", time.Since(start)}
+		switch c.Op {
+		case OpCheck:
+			args = append(args, "user", c.Check.User, "relation", c.Check.Relation, "object", c.Check.Object)
+			if err == nil {
+				args = append(args, "allowed", r.Allowed)
+			}
+		case OpWriteTuples:
+			args = append(args, "writes", len(c.Writes), "deletes", len(c.Deletes))
+		}
+		switch {
+		case err != nil:
+			l.WarnContext(ctx, "authz call failed", auditArgs(ctx, append(args, "error", err)...)...)
+		case c.Op == OpCheck || c.Op == OpWriteTuples:
+			l.InfoContext(ctx, "authz call", auditArgs(ctx, args...)...)
+		}
+		return r, err
+	}
+}
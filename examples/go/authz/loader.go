@@ -0,0 +1,165 @@
+package authz
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// LoaderConfig tunes a Loader.
+type LoaderConfig struct {
+	// Wait is how long a batch collects checks after its first; default
+	// 2ms.
+	Wait time.Duration
+	// MaxBatch sends a batch early once it holds this many checks; default
+	// MaxWriteTuples.
+	MaxBatch int
+}
+
+// Loader batches and caches checks in the style of a GraphQL DataLoader:
+// resolvers call Load independently, checks arriving within Wait of each
+// other are run together through CheckMany, and each answer is kept for
+// the rest of the request, so a question asked by many resolvers costs one
+// call. Distinct questions still cost one Check each. Failed checks are
+// not cached. A Loader belongs to one request; see LoadChecks.
+type Loader struct {
+	client *Client
+	ctx    context.Context
+	cfg    LoaderConfig
+
+	mu    sync.Mutex
+	cache map[CheckRequest]*loaderEntry
+	batch []*loaderEntry
+	timer *time.Timer
+}
+
+type loaderEntry struct {
+	req     CheckRequest
+	done    chan struct{}
+	allowed bool
+	err     error
+}
+
+// Loader returns a Loader whose batches run under ctx, normally the
+// request's context.
+func (c *Client) Loader(ctx context.Context, cfg LoaderConfig) *Loader {
+	if cfg.Wait <= 0 {
+		cfg.Wait = 2 * time.Millisecond
+	}
+	if cfg.MaxBatch <= 0 {
+		cfg.MaxBatch = MaxWriteTuples
+	}
+	return &Loader{client: c, ctx: ctx, cfg: cfg, cache: map[CheckRequest]*loaderEntry{}}
+}
+
+// Load reports whether user has relation on object, joining the current
+// batch or reusing an earlier answer.
+func (l *Loader) Load(ctx context.Context, user, relation, object string) (bool, error) {
+	req := CheckRequest{User: user, Relation: relation, Object: object}
+	l.mu.Lock()
+	e, ok := l.cache[req]
+	if !ok {
+		e = &loaderEntry{req: req, done: make(chan struct{})}
+		l.cache[req] = e
+		l.batch = append(l.batch, e)
+		switch {
+		case len(l.batch) >= l.cfg.MaxBatch:
+			batch := l.take()
+			go l.dispatch(batch)
+		case l.timer == nil:
+			l.timer = time.AfterFunc(l.cfg.Wait, func() {
+				l.mu.Lock()
+				batch := l.take()
+				l.mu.Unlock()
+				l.dispatch(batch)
+			})
+		}
+	}
+	l.mu.Unlock()
+	select {
+	case <-e.done:
+		return e.allowed, e.err
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+// LoadRef is Load with typed references.
+func (l *Loader) LoadRef(ctx context.Context, user User, relation string, object Object) (bool, error) {
+	return l.Load(ctx, user.String(), relation, object.String())
+}
+
+// Prime caches an answer known from elsewhere, e.g. the owner of a freshly
+// created object. It does not override an answer already loaded.
+func (l *Loader) Prime(user, relation, object string, allowed bool) {
+	req := CheckRequest{User: user, Relation: relation, Object: object}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.cache[req]; ok {
+		return
+	}
+	e := &loaderEntry{req: req, done: make(chan struct{}), allowed: allowed}
+	close(e.done)
+	l.cache[req] = e
+}
+
+// take removes the current batch; l.mu must be held.
+func (l *Loader) take() []*loaderEntry {
+	batch := l.batch
+	l.batch = nil
+	if l.timer != nil {
+		l.timer.Stop()
+		l.timer = nil
+	}
+	return batch
+}
+
+func (l *Loader) dispatch(batch []*loaderEntry) {
+	if len(batch) == 0 {
+		return
+	}
+	reqs := make([]CheckRequest, len(batch))
+	for i, e := range batch {
+		reqs[i] = e.req
+	}
+	res := l.client.CheckMany(l.ctx, reqs)
+	l.mu.Lock()
+	for _, it := range res.Items {
+		e := batch[it.Index]
+		e.allowed, e.err = it.Allowed, it.Err
+		if it.Err != nil {
+			delete(l.cache, e.req)
+		}
+	}
+	l.mu.Unlock()
+	for _, e := range batch {
+		close(e.done)
+	}
+}
+
+type loaderKey struct{}
+
+// WithLoader returns a context carrying l; see LoaderFromContext.
+func WithLoader(ctx context.Context, l *Loader) context.Context {
+	return context.WithValue(ctx, loaderKey{}, l)
+}
+
+// LoaderFromContext returns the loader stored by WithLoader or
+// LoadChecks, or nil.
+func LoaderFromContext(ctx context.Context) *Loader {
+	l, _ := ctx.Value(loaderKey{}).(*Loader)
+	return l
+}
+
+// LoadChecks is net/http middleware giving every request its own Loader
+// over c. It knows nothing about GraphQL: wrap the GraphQL server's
+// http.Handler with it, and resolvers find the Loader with
+// LoaderFromContext on the request context they are given.
+func LoadChecks(c *Client, cfg LoaderConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(WithLoader(r.Context(), c.Loader(r.Context(), cfg))))
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package authz
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ListObjectsLimit is the server's default cap on ListObjects results. A
+// result of this size may be truncated.
+const ListObjectsLimit = 1000
+
+// Pagination strategies reported in Page.Strategy.
+const (
+	// FGAFirst lists the user's objects once and keeps the application
+	// rows among them; used when the user can reach few objects.
+	FGAFirst = "fga-first"
+	// DBFirst checks each application row; used when the user can reach
+	// too many objects to list, or listing is unavailable.
+	DBFirst = "db-first"
+)
+
+// PageQuery describes one page of application rows the user may see.
+type PageQuery[T any] struct {
+	User, Relation, ObjectType string
+	// Cursor is the Next of the previous page, empty for the first.
+	Cursor string
+	// Limit is the page size.
+	Limit int
+	// Fetch returns up to limit rows starting at the application's cursor,
+	// in a stable order, and the cursor of the following rows, empty after
+	// the last.
+	Fetch func(ctx context.Context, cursor string, limit int) ([]T, string, error)
+	// Object returns a row's object; default ObjectOf, for registered
+	// types.
+	Object func(T) string
+	// MaxFetches bounds the Fetch calls per page, zero for no bound. When
+	// reached the page is short and Next resumes the scan.
+	MaxFetches int
+}
+
+// Page is one page of authorized rows.
+type Page[T any] struct {
+	Items []T
+	// Next continues after the last item, empty when there are no more
+	// rows.
+	Next     string
+	Strategy string
+}
+
+// pageCursor is the position after the last returned row: the
+// application's cursor for its page and how many rows of it were used.
+type pageCursor struct {
+	Cursor string `json:"c,omitempty"`
+	Skip   int    `json:"s,omitempty"`
+}
+
+// ListAuthorizedPage returns exactly q.Limit rows from q.Fetch on which the
+// user has q.Relation, fewer only on the last page or at q.MaxFetches. It
+// asks for the user's objects first: when they are fewer than
+// ListObjectsLimit rows are matched against them (FGAFirst); otherwise, or
+// if the client has no Lister, each fetched row is checked (DBFirst). Rows
+// past the page's last item are not checked, and Next resumes inside the
+// application's page, so nothing is skipped or fetched twice for nothing.
+func ListAuthorizedPage[T any](ctx context.Context, c *Client, q PageQuery[T]) (*Page[T], error) {
+	if q.Limit <= 0 {
+		return nil, errors.New("authz: page limit must be positive")
+	}
+	object := q.Object
+	if object == nil {
+		object = func(v T) string {
+			o, err := ObjectOf(v)
+			if err != nil {
+				return ""
+			}
+			return o.String()
+		}
+	}
+	var pos pageCursor
+	if q.Cursor != "" {
+		data, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+		if err == nil {
+			err = json.Unmarshal(data, &pos)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("authz: invalid page cursor: %w", err)
+		}
+	}
+
+	page := &Page[T]{Strategy: DBFirst}
+	var reachable map[string]bool
+	objects, err := c.ListObjects(ctx, q.User, q.Relation, q.ObjectType)
+	switch {
+	case errors.Is(err, ErrNoLister):
+	case err != nil:
+		return nil, err
+	case len(objects) < ListObjectsLimit:
+		page.Strategy = FGAFirst
+		reachable = make(map[string]bool, len(objects))
+		for _, o := range objects {
+			reachable[o] = true
+		}
+	}
+
+	for fetches := 0; q.MaxFetches == 0 || fetches < q.MaxFetches; fetches++ {
+		rows, next, err := q.Fetch(ctx, pos.Cursor, q.Limit)
+		if err != nil {
+			return nil, fmt.Errorf("authz: fetch page: %w", err)
+		}
+		rows = rows[min(pos.Skip, len(rows)):]
+		allowed, err := authorizedRows(ctx, c, q.User, q.Relation, rows, object, reachable, q.Limit-len(page.Items))
+		if err != nil {
+			return nil, err
+		}
+		for i, ok := range allowed {
+			if !ok {
+				continue
+			}
+			page.Items = append(page.Items, rows[i])
+			if len(page.Items) < q.Limit {
+				continue
+			}
+			switch {
+			case i+1 < len(rows):
+				page.Next = encodePageCursor(pageCursor{Cursor: pos.Cursor, Skip: pos.Skip + i + 1})
+			case next != "":
+				page.Next = encodePageCursor(pageCursor{Cursor: next})
+			}
+			return page, nil
+		}
+		if next == "" {
+			return page, nil
+		}
+		pos = pageCursor{Cursor: next}
+	}
+	page.Next = encodePageCursor(pos)
+	return page, nil
+}
+
+// authorizedRows reports which rows the user may see. With reachable it
+// matches against the set; otherwise it checks rows in chunks sized by
+// need, the number of items still missing from the page, so rows well past
+// the page's end are left unchecked. The result may be shorter than rows.
+// A failed check fails the page rather than hiding the row.
+func authorizedRows[T any](ctx context.Context, c *Client, user, relation string, rows []T, object func(T) string, reachable map[string]bool, need int) ([]bool, error) {
+	objects := make([]string, len(rows))
+	for i, r := range rows {
+		if objects[i] = object(r); objects[i] == "" {
+			return nil, fmt.Errorf("authz: no object for row %d of %T", i, r)
+		}
+	}
+	out := make([]bool, 0, len(rows))
+	if reachable != nil {
+		for _, o := range objects {
+			out = append(out, reachable[o])
+		}
+		return out, nil
+	}
+	found := 0
+	for len(out) < len(rows) && found < need {
+		chunk := objects[len(out):min(len(rows), len(out)+max(need-found, BatchConcurrency))]
+		reqs := make([]CheckRequest, len(chunk))
+		for i, o := range chunk {
+			reqs[i] = CheckRequest{User: user, Relation: relation, Object: o}
+		}
+		res := c.CheckMany(ctx, reqs)
+		if err := res.Err(); err != nil {
+			return nil, err
+		}
+		for _, it := range res.Items {
+			out = append(out, it.Allowed)
+			if it.Allowed {
+				found++
+			}
+		}
+	}
+	return out, nil
+}
+
+func encodePageCursor(p pageCursor) string {
+	data, _ := json.Marshal(p)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
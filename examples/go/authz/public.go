@@ -0,0 +1,101 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrNotPublic is returned by MakePublic for a relation whose type
+// restrictions do not allow a wildcard.
+var ErrNotPublic = errors.New("authz: relation does not allow public access")
+
+// MakePublic grants relation on object to everyone by writing a wildcard
+// tuple, e.g. document:readme#viewer@user:*. The wildcard types come from
+// the model; with userTypes the grant is limited to those types. Types
+// already public are left alone.
+func (c *Client) MakePublic(ctx context.Context, object Object, relation string, userTypes ...string) error {
+	object = c.currentObject(object)
+	types, err := c.wildcardTypes(ctx, object, relation, userTypes)
+	if err != nil {
+		return err
+	}
+	var writes []Tuple
+	for _, typ := range types {
+		t := NewTuple(Wildcard(typ), relation, object)
+		existing, _, err := c.backend.Read(ctx, t, "")
+		if err != nil {
+			return fmt.Errorf("authz: make public: %w", err)
+		}
+		if len(existing) == 0 {
+			writes = append(writes, t)
+		}
+	}
+	if len(writes) == 0 {
+		return nil
+	}
+	return c.Write(ctx, writes, nil)
+}
+
+// MakePrivate removes every wildcard grant of relation on object. Grants to
+// specific users are kept.
+func (c *Client) MakePrivate(ctx context.Context, object Object, relation string) error {
+	public, err := c.PublicTo(ctx, object, relation)
+	if err != nil {
+		return err
+	}
+	var deletes []Tuple
+	for _, typ := range public {
+		deletes = append(deletes, NewTuple(Wildcard(typ), relation, object))
+	}
+	if len(deletes) == 0 {
+		return nil
+	}
+	return c.Write(ctx, nil, deletes)
+}
+
+// PublicTo returns the user types relation on object is directly granted
+// to as a whole. It reports wildcard tuples only; public access inherited
+// through other relations is a Check question.
+func (c *Client) PublicTo(ctx context.Context, object Object, relation string) ([]string, error) {
+	object = c.currentObject(object)
+	tuples, err := ReadAll(ctx, c.backend, Tuple{Relation: relation, Object: object.String()})
+	if err != nil {
+		return nil, fmt.Errorf("authz: read %s#%s: %w", object, relation, err)
+	}
+	var types []string
+	for _, t := range tuples {
+		if u, err := t.UserRef(); err == nil && u.IsWildcard() {
+			types = append(types, u.Type)
+		}
+	}
+	return types, nil
+}
+
+func (c *Client) wildcardTypes(ctx context.Context, object Object, relation string, want []string) ([]string, error) {
+	m, err := c.backend.ReadModel(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("authz: read model: %w", err)
+	}
+	rel := m.Relation(object.Type, relation)
+	if rel == nil {
+		return nil, fmt.Errorf("authz: %s#%s is not in the model", object.Type, relation)
+	}
+	allowed := rel.WildcardTypes()
+	if len(want) == 0 {
+		if len(allowed) == 0 {
+			return nil, fmt.Errorf("%w: %s#%s", ErrNotPublic, object.Type, relation)
+		}
+		return allowed, nil
+	}
+	for _, typ := range want {
+		ok := false
+		for _, a := range allowed {
+			ok = ok || a == typ
+		}
+		if !ok {
+			return nil, fmt.Errorf("%w: %s#%s to %s:*", ErrNotPublic, object.Type, relation, typ)
+		}
+	}
+	return want, nil
+}
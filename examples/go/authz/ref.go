@@ -0,0 +1,247 @@
+package authz
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bogdanticu88/openfga-examples/ids"
+)
+
+// strictIDs validates the lexical form of parsed references.
+var strictIDs = &ids.Policy{}
+
+// Object is a typed reference such as project:api.
+type Object struct {
+	Type string
+	ID   string
+}
+
+// NewObject returns the object of typ with id, e.g. NewObject("project", "api").
+func NewObject(typ, id string) Object {
+	return Object{Type: typ, ID: id}
+}
+
+func (o Object) String() string { return o.Type + ":" + o.ID }
+
+// Userset returns the userset of o's members holding relation, e.g.
+// group:eng#member.
+func (o Object) Userset(relation string) Userset {
+	return Userset{Object: o, Relation: relation}
+}
+
+// ParseObject parses "type:id".
+func ParseObject(s string) (Object, error) {
+	typ, id, ok := strings.Cut(s, ":")
+	if !ok {
+		return Object{}, fmt.Errorf("authz: object %q must have the form type:id", s)
+	}
+	if _, err := strictIDs.ID(typ, id); err != nil {
+		return Object{}, fmt.Errorf("authz: object %q: %w", s, err)
+	}
+	return Object{Type: typ, ID: id}, nil
+}
+
+// MustParseObject is ParseObject for constants; it panics on error.
+func MustParseObject(s string) Object {
+	o, err := ParseObject(s)
+	if err != nil {
+		panic(err)
+	}
+	return o
+}
+
+// Userset is a relation on an object used as a user, e.g. group:eng#member.
+type Userset struct {
+	Object   Object
+	Relation string
+}
+
+func (u Userset) String() string { return u.Object.String() + "#" + u.Relation }
+
+// User returns u in the form accepted wherever a User is expected.
+func (u Userset) User() User {
+	return User{Type: u.Object.Type, ID: u.Object.ID, Relation: u.Relation}
+}
+
+// ParseUserset parses "type:id#relation".
+func ParseUserset(s string) (Userset, error) {
+	obj, rel, ok := strings.Cut(s, "#")
+	if !ok {
+		return Userset{}, fmt.Errorf("authz: userset %q must have the form type:id#relation", s)
+	}
+	o, err := ParseObject(obj)
+	if err != nil {
+		return Userset{}, err
+	}
+	if err := ids.ValidateRelation(rel); err != nil {
+		return Userset{}, fmt.Errorf("authz: userset %q: %w", s, err)
+	}
+	return Userset{Object: o, Relation: rel}, nil
+}
+
+// User is the user side of a tuple: a concrete object (user:alice), a
+// wildcard (user:*) or a userset (group:eng#member).
+type User struct {
+	Type     string
+	ID       string
+	Relation string
+}
+
+// Wildcard returns the public user of typ, e.g. user:*.
+func Wildcard(typ string) User { return User{Type: typ, ID: "*"} }
+
+// IsWildcard reports whether u is typ:*.
+func (u User) IsWildcard() bool { return u.ID == "*" }
+
+// IsUserset reports whether u carries a relation.
+func (u User) IsUserset() bool { return u.Relation != "" }
+
+// Object returns u's object part, e.g. group:eng for group:eng#member.
+func (u User) Object() Object { return Object{Type: u.Type, ID: u.ID} }
+
+func (u User) String() string {
+	s := u.Type + ":" + u.ID
+	if u.Relation != "" {
+		s += "#" + u.Relation
+	}
+	return s
+}
+
+// ParseUser parses any of the user forms.
+func ParseUser(s string) (User, error) {
+	if strings.Contains(s, "#") {
+		us, err := ParseUserset(s)
+		if err != nil {
+			return User{}, err
+		}
+		return us.User(), nil
+	}
+	typ, id, ok := strings.Cut(s, ":")
+	if !ok {
+		return User{}, fmt.Errorf("authz: user %q must have the form type:id", s)
+	}
+	if id == "*" {
+		if err := ids.ValidateType(typ); err != nil {
+			return User{}, fmt.Errorf("authz: user %q: %w", s, err)
+		}
+		return Wildcard(typ), nil
+	}
+	o, err := ParseObject(s)
+	if err != nil {
+		return User{}, err
+	}
+	return User{Type: o.Type, ID: o.ID}, nil
+}
+
+// MustParseUser is ParseUser for constants; it panics on error.
+func MustParseUser(s string) User {
+	u, err := ParseUser(s)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+// NewTuple builds a tuple from typed references.
+func NewTuple(user User, relation string, object Object) Tuple {
+	return Tuple{User: user.String(), Relation: relation, Object: object.String()}
+}
+
+// UserRef parses t's user.
+func (t Tuple) UserRef() (User, error) { return ParseUser(t.User) }
+
+// ObjectRef parses t's object.
+func (t Tuple) ObjectRef() (Object, error) { return ParseObject(t.Object) }
+
+// CheckRef is Check with typed references.
+func (c *Client) CheckRef(ctx context.Context, user User, relation string, object Object) (bool, error) {
+	return c.Check(ctx, user.String(), relation, object.String())
+}
+
+// WriteRef writes the tuple user relation object.
+func (c *Client) WriteRef(ctx context.Context, user User, relation string, object Object) error {
+	return c.Write(ctx, []Tuple{NewTuple(user, relation, object)}, nil)
+}
+
+// DeleteRef deletes the tuple user relation object.
+func (c *Client) DeleteRef(ctx context.Context, user User, relation string, object Object) error {
+	return c.Write(ctx, nil, []Tuple{NewTuple(user, relation, object)})
+}
+
+// RequireRef is Require with a typed object, which must be of the type the
+// operation's rule names.
+func (c *Client) RequireRef(ctx context.Context, operation string, object Object) error {
+	if c.policies != nil {
+		if rule, err := c.policies.Lookup(operation); err == nil && rule.ObjectType != object.Type {
+			return fmt.Errorf("authz: %s applies to %s objects, not %s", operation, rule.ObjectType, object)
+		}
+	}
+	return c.Require(ctx, operation, object.ID)
+}
+
+// ListObjectsRef is ListObjects with typed references.
+func (c *Client) ListObjectsRef(ctx context.Context, user User, relation, objectType string) ([]Object, error) {
+	ss, err := c.ListObjects(ctx, user.String(), relation, objectType)
+	if err != nil {
+		return nil, err
+	}
+	objects := make([]Object, 0, len(ss))
+	for _, s := range ss {
+		o, err := ParseObject(s)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, o)
+	}
+	return objects, nil
+}
+
+// UserFilter restricts ListUsers to users of Type or, with Relation, to
+// usersets of Type with that relation.
+type UserFilter struct {
+	Type     string
+	Relation string
+}
+
+func (f UserFilter) String() string {
+	if f.Relation == "" {
+		return f.Type
+	}
+	return f.Type + "#" + f.Relation
+}
+
+// ParseUserFilter parses "type" or "type#relation".
+func ParseUserFilter(s string) (UserFilter, error) {
+	typ, rel, ok := strings.Cut(s, "#")
+	if err := ids.ValidateType(typ); err != nil {
+		return UserFilter{}, fmt.Errorf("authz: user filter %q: %w", s, err)
+	}
+	if ok {
+		if err := ids.ValidateRelation(rel); err != nil {
+			return UserFilter{}, fmt.Errorf("authz: user filter %q: %w", s, err)
+		}
+	}
+	return UserFilter{Type: typ, Relation: rel}, nil
+}
+
+// ListUsersRef is ListUsers with typed references.
+func (c *Client) ListUsersRef(ctx context.Context, object Object, relation string, userFilters []UserFilter) ([]User, error) {
+	filters := make([]string, len(userFilters))
+	for i, f := range userFilters {
+		filters[i] = f.String()
+	}
+	ss, err := c.ListUsers(ctx, object.String(), relation, filters)
+	if err != nil {
+		return nil, err
+	}
+	users := make([]User, 0, len(ss))
+	for _, s := range ss {
+		u, err := ParseUser(s)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, nil
+}
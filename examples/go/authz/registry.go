@@ -0,0 +1,142 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/bogdanticu88/openfga-examples/ids"
+)
+
+var (
+	// ErrNotRegistered is returned for values whose Go type was not passed
+	// to Register.
+	ErrNotRegistered = errors.New("authz: type not registered")
+	// ErrNoChecker is returned by the package-level Check when the context
+	// carries no Checker and no default is set; see WithChecker.
+	ErrNoChecker = errors.New("authz: no checker in context")
+)
+
+// resource is a registered Go type: its FGA object type, how to get the
+// object ID from a value and, for RegisterTagged, the relations it links.
+type resource struct {
+	objectType string
+	id         func(v any) string
+	links      []link
+}
+
+var registry = struct {
+	sync.RWMutex
+	types map[reflect.Type]resource
+}{types: map[reflect.Type]resource{}}
+
+// Register maps the Go type T to objectType, taking object IDs from id:
+//
+//	authz.Register[Project]("project", func(p Project) string { return p.Slug })
+//	allowed, err := authz.Check(ctx, user, "viewer", project)
+//
+// Values of *T resolve through T. Register is meant for package init and
+// panics on an invalid type name or when T is already registered.
+func Register[T any](objectType string, id func(T) string) {
+	if err := ids.ValidateType(objectType); err != nil {
+		panic(fmt.Sprintf("authz: register %s: %v", typeOf[T](), err))
+	}
+	register(typeOf[T](), resource{objectType: objectType, id: func(v any) string { return id(v.(T)) }})
+}
+
+func register(t reflect.Type, r resource) {
+	registry.Lock()
+	defer registry.Unlock()
+	if prev, ok := registry.types[t]; ok {
+		panic(fmt.Sprintf("authz: %s already registered as %s", t, prev.objectType))
+	}
+	registry.types[t] = r
+}
+
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// lookup returns the registration for v, dereferencing pointers.
+func lookup(v any) (resource, any, error) {
+	rv := reflect.ValueOf(v)
+	for {
+		registry.RLock()
+		r, ok := registry.types[rv.Type()]
+		registry.RUnlock()
+		if ok {
+			return r, rv.Interface(), nil
+		}
+		if rv.Kind() != reflect.Pointer || rv.IsNil() {
+			return resource{}, nil, fmt.Errorf("%w: %s", ErrNotRegistered, reflect.TypeOf(v))
+		}
+		rv = rv.Elem()
+	}
+}
+
+// ObjectOf returns the object for a value of a registered type.
+func ObjectOf[T any](v T) (Object, error) {
+	r, v2, err := lookup(v)
+	if err != nil {
+		return Object{}, err
+	}
+	return Object{Type: r.objectType, ID: r.id(v2)}, nil
+}
+
+// ObjectType returns the object type T was registered with.
+func ObjectType[T any]() (string, error) {
+	registry.RLock()
+	defer registry.RUnlock()
+	r, ok := registry.types[typeOf[T]()]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrNotRegistered, typeOf[T]())
+	}
+	return r.objectType, nil
+}
+
+type checkerKey struct{}
+
+var defaultChecker struct {
+	sync.RWMutex
+	c Checker
+}
+
+// WithChecker returns a context whose package-level Check calls go to c.
+func WithChecker(ctx context.Context, c Checker) context.Context {
+	return context.WithValue(ctx, checkerKey{}, c)
+}
+
+// SetDefault sets the Checker used by the package-level Check when the
+// context carries none, typically the application's single *Client.
+func SetDefault(c Checker) {
+	defaultChecker.Lock()
+	defaultChecker.c = c
+	defaultChecker.Unlock()
+}
+
+// CheckerFromContext returns the Checker set by WithChecker, or the
+// default.
+func CheckerFromContext(ctx context.Context) (Checker, bool) {
+	if c, ok := ctx.Value(checkerKey{}).(Checker); ok {
+		return c, true
+	}
+	defaultChecker.RLock()
+	defer defaultChecker.RUnlock()
+	return defaultChecker.c, defaultChecker.c != nil
+}
+
+// Check reports whether user has relation on resource, a value of a type
+// passed to Register, using the Checker on ctx.
+func Check[T any](ctx context.Context, user, relation string, resource T) (bool, error) {
+	c, ok := CheckerFromContext(ctx)
+	if !ok {
+		return false, ErrNoChecker
+	}
+	o, err := ObjectOf(resource)
+	if err != nil {
+		return false, err
+	}
+	return c.Check(ctx, user, relation, o.String())
+}
@@ -0,0 +1,71 @@
+package authz
+
+import (
+	"context"
+	"time"
+)
+
+// Consistency is the server's latency/freshness trade-off for reads.
+type Consistency string
+
+const (
+	// ConsistencyDefault leaves the choice to the server.
+	ConsistencyDefault Consistency = ""
+	// MinimizeLatency allows answers from caches.
+	MinimizeLatency Consistency = "MINIMIZE_LATENCY"
+	// HigherConsistency skips caches so recent writes are seen, e.g. to
+	// check a grant just made.
+	HigherConsistency Consistency = "HIGHER_CONSISTENCY"
+)
+
+// RequestOptions are per-request overrides carried by a context, so
+// middleware can set them once for everything a request does. Backends
+// from FromSDK honor every field; caches in this module bypass themselves
+// for HigherConsistency.
+type RequestOptions struct {
+	Consistency Consistency
+	// ModelID pins the authorization model instead of the configured one.
+	ModelID string
+	// Timeout bounds each call made under the context.
+	Timeout time.Duration
+}
+
+type requestOptionsKey struct{}
+
+// WithConsistency returns a context whose calls use consistency c.
+func WithConsistency(ctx context.Context, c Consistency) context.Context {
+	o := RequestOptionsFrom(ctx)
+	o.Consistency = c
+	return context.WithValue(ctx, requestOptionsKey{}, o)
+}
+
+// WithModelID returns a context whose calls use the model with id.
+func WithModelID(ctx context.Context, id string) context.Context {
+	o := RequestOptionsFrom(ctx)
+	o.ModelID = id
+	return context.WithValue(ctx, requestOptionsKey{}, o)
+}
+
+// WithTimeout returns a context whose calls are each bounded by d. Unlike
+// context.WithTimeout, the limit applies per call, not to the request as
+// a whole.
+func WithTimeout(ctx context.Context, d time.Duration) context.Context {
+	o := RequestOptionsFrom(ctx)
+	o.Timeout = d
+	return context.WithValue(ctx, requestOptionsKey{}, o)
+}
+
+// RequestOptionsFrom returns the options set on ctx.
+func RequestOptionsFrom(ctx context.Context) RequestOptions {
+	o, _ := ctx.Value(requestOptionsKey{}).(RequestOptions)
+	return o
+}
+
+// CallContext applies the per-call timeout from ctx's options, if any.
+// Backends call it around each request.
+func CallContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if d := RequestOptionsFrom(ctx).Timeout; d > 0 {
+		return context.WithTimeout(ctx, d)
+	}
+	return ctx, func() {}
+}
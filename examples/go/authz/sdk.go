@@ -0,0 +1,238 @@
+package authz
+
+import (
+	"context"
+	"fmt"
+
+	openfga "github.com/openfga/go-sdk"
+	"github.com/openfga/go-sdk/client"
+
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// sdkBackend adapts *client.OpenFgaClient to Backend. Store and model IDs
+// come from the client's configuration; ReadModel without an ID falls back to
+// the latest model when none is configured.
+type sdkBackend struct {
+	fga *client.OpenFgaClient
+}
+
+// FromSDK returns a Backend backed by the official OpenFGA Go client.
+func FromSDK(fga *client.OpenFgaClient) Backend {
+	return &sdkBackend{fga: fga}
+}
+
+func (b *sdkBackend) Check(ctx context.Context, req CheckRequest) (bool, error) {
+	ctx, cancel := CallContext(ctx)
+	defer cancel()
+	o := RequestOptionsFrom(ctx)
+	body := client.ClientCheckRequest{
+		User:     req.User,
+		Relation: req.Relation,
+		Object:   req.Object,
+	}
+	if cc := ConditionContextFrom(ctx); len(cc) > 0 {
+		m := map[string]interface{}(cc)
+		body.Context = &m
+	}
+	resp, err := b.fga.Check(ctx).Body(body).Options(client.ClientCheckOptions{AuthorizationModelId: sdkModelID(o), Consistency: sdkConsistency(o)}).Execute()
+	if err != nil {
+		return false, err
+	}
+	return resp.GetAllowed(), nil
+}
+
+func (b *sdkBackend) Write(ctx context.Context, writes, deletes []Tuple) error {
+	ctx, cancel := CallContext(ctx)
+	defer cancel()
+	body := client.ClientWriteRequest{}
+	for _, t := range writes {
+		key := client.ClientTupleKey{User: t.User, Relation: t.Relation, Object: t.Object}
+		if t.Condition.Name != "" {
+			values, err := t.Condition.Values()
+			if err != nil {
+				return err
+			}
+			key.Condition = &openfga.RelationshipCondition{Name: t.Condition.Name}
+			if values != nil {
+				key.Condition.Context = &values
+			}
+		}
+		body.Writes = append(body.Writes, key)
+	}
+	for _, t := range deletes {
+		body.Deletes = append(body.Deletes, client.ClientTupleKeyWithoutCondition{User: t.User, Relation: t.Relation, Object: t.Object})
+	}
+	_, err := b.fga.Write(ctx).Body(body).Options(client.ClientWriteOptions{AuthorizationModelId: sdkModelID(RequestOptionsFrom(ctx))}).Execute()
+	return err
+}
+
+func (b *sdkBackend) Read(ctx context.Context, filter Tuple, continuationToken string) ([]Tuple, string, error) {
+	ctx, cancel := CallContext(ctx)
+	defer cancel()
+	body := client.ClientReadRequest{}
+	if filter.User != "" {
+		body.User = &filter.User
+	}
+	if filter.Relation != "" {
+		body.Relation = &filter.Relation
+	}
+	if filter.Object != "" {
+		body.Object = &filter.Object
+	}
+	opts := client.ClientReadOptions{Consistency: sdkConsistency(RequestOptionsFrom(ctx))}
+	if continuationToken != "" {
+		opts.ContinuationToken = &continuationToken
+	}
+	resp, err := b.fga.Read(ctx).Body(body).Options(opts).Execute()
+	if err != nil {
+		return nil, "", err
+	}
+	tuples := make([]Tuple, 0, len(resp.Tuples))
+	for _, t := range resp.Tuples {
+		tuple, err := tupleFromSDK(t.Key)
+		if err != nil {
+			return nil, "", err
+		}
+		tuples = append(tuples, tuple)
+	}
+	return tuples, resp.ContinuationToken, nil
+}
+
+func tupleFromSDK(k openfga.TupleKey) (Tuple, error) {
+	t := Tuple{User: k.User, Relation: k.Relation, Object: k.Object}
+	if c := k.Condition; c != nil {
+		var values map[string]any
+		if c.Context != nil {
+			values = *c.Context
+		}
+		var err error
+		if t.Condition, err = NewTupleCondition(c.Name, values); err != nil {
+			return Tuple{}, err
+		}
+	}
+	return t, nil
+}
+
+func (b *sdkBackend) ReadModel(ctx context.Context, id string) (*model.Model, error) {
+	ctx, cancel := CallContext(ctx)
+	defer cancel()
+	if id == "" {
+		id = RequestOptionsFrom(ctx).ModelID
+	}
+	if id == "" {
+		id, _ = b.fga.GetAuthorizationModelId()
+	}
+	if id == "" {
+		resp, err := b.fga.ReadLatestAuthorizationModel(ctx).Execute()
+		if err != nil {
+			return nil, err
+		}
+		if resp.AuthorizationModel == nil {
+			return nil, fmt.Errorf("store has no authorization model")
+		}
+		return model.FromSDK(resp.AuthorizationModel)
+	}
+	resp, err := b.fga.ReadAuthorizationModel(ctx).Options(client.ClientReadAuthorizationModelOptions{AuthorizationModelId: &id}).Execute()
+	if err != nil {
+		return nil, err
+	}
+	if resp.AuthorizationModel == nil {
+		return nil, fmt.Errorf("authorization model %s not found", id)
+	}
+	return model.FromSDK(resp.AuthorizationModel)
+}
+
+func (b *sdkBackend) GetStore(ctx context.Context) (Store, error) {
+	ctx, cancel := CallContext(ctx)
+	defer cancel()
+	resp, err := b.fga.GetStore(ctx).Execute()
+	if err != nil {
+		return Store{}, err
+	}
+	return Store{ID: resp.Id, Name: resp.Name, CreatedAt: resp.CreatedAt}, nil
+}
+
+func (b *sdkBackend) ReadChanges(ctx context.Context, objectType, continuationToken string) ([]Change, string, error) {
+	ctx, cancel := CallContext(ctx)
+	defer cancel()
+	opts := client.ClientReadChangesOptions{}
+	if continuationToken != "" {
+		opts.ContinuationToken = &continuationToken
+	}
+	resp, err := b.fga.ReadChanges(ctx).Body(client.ClientReadChangesRequest{Type: objectType}).Options(opts).Execute()
+	if err != nil {
+		return nil, "", err
+	}
+	changes := make([]Change, 0, len(resp.Changes))
+	for _, c := range resp.Changes {
+		op := OpWrite
+		if c.Operation == openfga.TUPLEOPERATION_DELETE {
+			op = OpDelete
+		}
+		t, err := tupleFromSDK(c.TupleKey)
+		if err != nil {
+			return nil, "", err
+		}
+		changes = append(changes, Change{
+			Tuple:     t,
+			Operation: op,
+			Timestamp: c.Timestamp,
+		})
+	}
+	return changes, resp.GetContinuationToken(), nil
+}
+
+func (b *sdkBackend) ListUsers(ctx context.Context, object, relation string, userFilters []string) ([]string, error) {
+	ctx, cancel := CallContext(ctx)
+	defer cancel()
+	obj, err := ParseObject(object)
+	if err != nil {
+		return nil, err
+	}
+	body := client.ClientListUsersRequest{Object: openfga.FgaObject{Type: obj.Type, Id: obj.ID}, Relation: relation}
+	for _, s := range userFilters {
+		f, err := ParseUserFilter(s)
+		if err != nil {
+			return nil, err
+		}
+		filter := openfga.UserTypeFilter{Type: f.Type}
+		if f.Relation != "" {
+			filter.Relation = &f.Relation
+		}
+		body.UserFilters = append(body.UserFilters, filter)
+	}
+	o := RequestOptionsFrom(ctx)
+	resp, err := b.fga.ListUsers(ctx).Body(body).Options(client.ClientListUsersOptions{AuthorizationModelId: sdkModelID(o), Consistency: sdkConsistency(o)}).Execute()
+	if err != nil {
+		return nil, err
+	}
+	users := make([]string, 0, len(resp.Users))
+	for _, u := range resp.Users {
+		switch {
+		case u.Object != nil:
+			users = append(users, User{Type: u.Object.Type, ID: u.Object.Id}.String())
+		case u.Userset != nil:
+			users = append(users, User{Type: u.Userset.Type, ID: u.Userset.Id, Relation: u.Userset.Relation}.String())
+		case u.Wildcard != nil:
+			users = append(users, Wildcard(u.Wildcard.Type).String())
+		}
+	}
+	return users, nil
+}
+
+// sdkModelID returns the model override in o, nil to use the client's.
+func sdkModelID(o RequestOptions) *string {
+	if o.ModelID == "" {
+		return nil
+	}
+	return &o.ModelID
+}
+
+func sdkConsistency(o RequestOptions) *openfga.ConsistencyPreference {
+	if o.Consistency == ConsistencyDefault {
+		return nil
+	}
+	c := openfga.ConsistencyPreference(o.Consistency)
+	return &c
+}
@@ -0,0 +1,73 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrSelfTest is wrapped by the error Open returns when a smoke assertion
+// fails.
+var ErrSelfTest = errors.New("authz: startup self-test failed")
+
+// Assertion is a check with a known answer, e.g. a seed admin who must be
+// allowed or a canary user who must be denied.
+type Assertion struct {
+	User     string
+	Relation string
+	Object   string
+	Allowed  bool
+}
+
+func (a Assertion) String() string {
+	return fmt.Sprintf("%s#%s@%s is %v", a.Object, a.Relation, a.User, a.Allowed)
+}
+
+// WithSelfTest sets the assertions Open and SelfTest verify. They should
+// only involve tuples that exist in every healthy environment.
+func WithSelfTest(assertions ...Assertion) Option {
+	return func(c *Client) { c.selfTest = append(c.selfTest, assertions...) }
+}
+
+// SelfTestError lists the assertions that did not hold.
+type SelfTestError struct {
+	Failures []string
+}
+
+func (e *SelfTestError) Error() string {
+	return ErrSelfTest.Error() + ": " + strings.Join(e.Failures, "; ")
+}
+
+func (e *SelfTestError) Unwrap() error { return ErrSelfTest }
+
+// Open is New followed by SelfTest: it refuses to return a client whose
+// backend does not answer the configured assertions as expected, catching a
+// service pointed at the wrong store or an empty one.
+func Open(ctx context.Context, backend Backend, opts ...Option) (*Client, error) {
+	c := New(backend, opts...)
+	if err := c.SelfTest(ctx); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// SelfTest runs every configured assertion and reports all that fail,
+// including those whose check returned an error.
+func (c *Client) SelfTest(ctx context.Context) error {
+	var failures []string
+	for _, a := range c.selfTest {
+		allowed, err := c.Check(ctx, a.User, a.Relation, a.Object)
+		switch {
+		case err != nil:
+			failures = append(failures, fmt.Sprintf("%s: %v", a, err))
+		case allowed != a.Allowed:
+			failures = append(failures, fmt.Sprintf("%s: got %v", a, allowed))
+		}
+	}
+	if len(failures) > 0 {
+		c.log.ErrorContext(ctx, "startup self-test failed", "failures", len(failures))
+		return &SelfTestError{Failures: failures}
+	}
+	return nil
+}
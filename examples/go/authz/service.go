@@ -0,0 +1,163 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrStarted is returned by Service.Start and Service.Add once the service
+// is running.
+var ErrStarted = errors.New("authz: service already started")
+
+// Task is a background component owned by a Service, such as a cache
+// invalidator, a changes consumer, a sweeper or a queue flusher.
+type Task struct {
+	Name string
+	// Run does the work until ctx is done. If it returns early with an
+	// error, the service logs it and runs it again after a backoff.
+	Run func(ctx context.Context) error
+	// Drain, when set, runs during Stop after Run has returned, e.g. to
+	// flush a queue. It gets the context passed to Stop.
+	Drain func(ctx context.Context) error
+}
+
+// Every adapts the Run(ctx, interval) methods of this module's pollers to
+// Task.Run.
+func Every(run func(context.Context, time.Duration) error, interval time.Duration) func(context.Context) error {
+	return func(ctx context.Context) error { return run(ctx, interval) }
+}
+
+// Service starts background tasks together and stops them gracefully:
+//
+//	svc := &authz.Service{Logger: logger}
+//	svc.Add(authz.Task{Name: "write queue", Run: authz.Every(queue.Run, time.Second),
+//		Drain: func(ctx context.Context) error { _, err := queue.Flush(ctx); return err }})
+//	svc.Add(authz.Task{Name: "anomalies", Run: analyzer.Run})
+//	return svc.Run(ctx, 30*time.Second) // until SIGTERM or SIGINT
+type Service struct {
+	// Logger receives task failures; nil disables logging.
+	Logger *slog.Logger
+
+	mu      sync.Mutex
+	tasks   []Task
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	started bool
+}
+
+// Add registers a task; it must be called before Start.
+func (s *Service) Add(t Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return ErrStarted
+	}
+	s.tasks = append(s.tasks, t)
+	return nil
+}
+
+// Start runs every task in its own goroutine until Stop. Tasks do not see
+// ctx's cancellation as a stop signal: only Stop ends them, so deadlines
+// on the start-up context do not leak into long-lived work.
+func (s *Service) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return ErrStarted
+	}
+	s.started = true
+	ctx, s.cancel = context.WithCancel(context.WithoutCancel(ctx))
+	for _, t := range s.tasks {
+		s.wg.Add(1)
+		go s.run(ctx, t)
+	}
+	return nil
+}
+
+func (s *Service) run(ctx context.Context, t Task) {
+	defer s.wg.Done()
+	backoff := time.Second
+	for {
+		start := time.Now()
+		err := t.Run(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(start) > time.Minute {
+			backoff = time.Second
+		}
+		s.logf(ctx, slog.LevelWarn, "task stopped, restarting", "task", t.Name, "error", err, "backoff", backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, time.Minute)
+	}
+}
+
+// Stop cancels every task, waits for them to return and then drains them
+// in the order they were added. It gives up waiting when ctx is done and
+// returns the drain errors joined.
+func (s *Service) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.started || s.cancel == nil {
+		s.mu.Unlock()
+		return nil
+	}
+	s.cancel()
+	s.cancel = nil
+	tasks := s.tasks
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return fmt.Errorf("authz: stop: %w", ctx.Err())
+	}
+	var errs []error
+	for _, t := range tasks {
+		if t.Drain == nil {
+			continue
+		}
+		if err := t.Drain(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", t.Name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("authz: stop: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// Run starts the service, waits for ctx to be done or for SIGTERM or
+// SIGINT, and stops it, allowing up to grace for tasks to finish.
+func (s *Service) Run(ctx context.Context, grace time.Duration) error {
+	if err := s.Start(ctx); err != nil {
+		return err
+	}
+	sig, stop := signal.NotifyContext(ctx, syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	<-sig.Done()
+	stopCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), grace)
+	defer cancel()
+	return s.Stop(stopCtx)
+}
+
+func (s *Service) logf(ctx context.Context, level slog.Level, msg string, args ...interface{}) {
+	if s.Logger != nil {
+		s.Logger.Log(ctx, level, msg, args...)
+	}
+}
@@ -0,0 +1,50 @@
+package authz
+
+import (
+	"context"
+
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// Split returns a Backend that sends Check, Read, ReadModel, GetStore and
+// ListUsers to reads, such as a read replica or a caching proxy, and Write
+// and ReadChanges to writes. The changes feed stays on the primary so its
+// tokens are never handed to a server that did not issue them.
+//
+// Reads may lag writes by the replica's delay; code that checks right
+// after granting should call the write side directly.
+func Split(reads, writes Backend) Backend {
+	return &splitBackend{reads: reads, writes: writes}
+}
+
+type splitBackend struct {
+	reads, writes Backend
+}
+
+func (s *splitBackend) Check(ctx context.Context, req CheckRequest) (bool, error) {
+	return s.reads.Check(ctx, req)
+}
+
+func (s *splitBackend) Write(ctx context.Context, writes, deletes []Tuple) error {
+	return s.writes.Write(ctx, writes, deletes)
+}
+
+func (s *splitBackend) Read(ctx context.Context, filter Tuple, continuationToken string) ([]Tuple, string, error) {
+	return s.reads.Read(ctx, filter, continuationToken)
+}
+
+func (s *splitBackend) ReadModel(ctx context.Context, id string) (*model.Model, error) {
+	return s.reads.ReadModel(ctx, id)
+}
+
+func (s *splitBackend) GetStore(ctx context.Context) (Store, error) {
+	return s.reads.GetStore(ctx)
+}
+
+func (s *splitBackend) ReadChanges(ctx context.Context, objectType, continuationToken string) ([]Change, string, error) {
+	return s.writes.ReadChanges(ctx, objectType, continuationToken)
+}
+
+func (s *splitBackend) ListUsers(ctx context.Context, object, relation string, userFilters []string) ([]string, error) {
+	return s.reads.ListUsers(ctx, object, relation, userFilters)
+}
@@ -0,0 +1,233 @@
+package authz
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/bogdanticu88/openfga-examples/ids"
+)
+
+// link is one relation declared in an fga struct tag: the tuple
+// object#relation@userType:<field>[#userRelation] for each value of field.
+type link struct {
+	relation     string
+	field        []int
+	userType     string
+	userRelation string
+}
+
+// RegisterTagged registers the struct type T from its fga tag, which may be
+// on any field; a blank field keeps it out of the way:
+//
+//	type Project struct {
+//		_       struct{} `fga:"type=project,id=ID,parent=OrgID->organization"`
+//		ID      string
+//		OrgID   string
+//		Editors []string `fga:"editor=Editors->user"`
+//	}
+//
+// type and id name the object type and the field holding the ID. Every
+// other key is a relation on the object whose users come from the named
+// field, a string, integer or slice of either: "parent=OrgID->organization"
+// links project:<ID>#parent@organization:<OrgID>, and "->team#member"
+// makes the users usersets. Zero fields link nothing. Tags on several
+// fields are merged. Registered types work with Check and ObjectOf, and
+// EntityTuples and CreateEntity derive their tuples.
+//
+// Like Register, RegisterTagged panics on a malformed tag.
+func RegisterTagged[T any]() {
+	t := typeOf[T]()
+	r, err := parseTags(t)
+	if err != nil {
+		panic(fmt.Sprintf("authz: register %s: %v", t, err))
+	}
+	register(t, r)
+}
+
+func parseTags(t reflect.Type) (resource, error) {
+	if t.Kind() != reflect.Struct {
+		return resource{}, fmt.Errorf("fga tags need a struct, not %s", t.Kind())
+	}
+	var r resource
+	var idField []int
+	for i := 0; i < t.NumField(); i++ {
+		tag, ok := t.Field(i).Tag.Lookup("fga")
+		if !ok {
+			continue
+		}
+		for _, kv := range strings.Split(tag, ",") {
+			key, value, ok := strings.Cut(strings.TrimSpace(kv), "=")
+			if !ok {
+				return resource{}, fmt.Errorf("tag entry %q must have the form key=value", kv)
+			}
+			switch key {
+			case "type":
+				if err := ids.ValidateType(value); err != nil {
+					return resource{}, err
+				}
+				r.objectType = value
+			case "id":
+				f, err := tagField(t, value)
+				if err != nil {
+					return resource{}, err
+				}
+				if f.Type.Kind() == reflect.Slice {
+					return resource{}, fmt.Errorf("id field %s must not be a slice", value)
+				}
+				idField = f.Index
+			default:
+				if err := ids.ValidateRelation(key); err != nil {
+					return resource{}, err
+				}
+				name, user, ok := strings.Cut(value, "->")
+				if !ok {
+					return resource{}, fmt.Errorf("relation %s: %q must have the form Field->type", key, value)
+				}
+				f, err := tagField(t, name)
+				if err != nil {
+					return resource{}, err
+				}
+				userType, userRel, _ := strings.Cut(user, "#")
+				if err := ids.ValidateType(userType); err != nil {
+					return resource{}, fmt.Errorf("relation %s: %w", key, err)
+				}
+				r.links = append(r.links, link{relation: key, field: f.Index, userType: userType, userRelation: userRel})
+			}
+		}
+	}
+	if r.objectType == "" || idField == nil {
+		return resource{}, fmt.Errorf("fga tags must set type and id")
+	}
+	r.id = func(v any) string { return fmt.Sprint(reflect.ValueOf(v).FieldByIndex(idField).Interface()) }
+	return r, nil
+}
+
+func tagField(t reflect.Type, name string) (reflect.StructField, error) {
+	f, ok := t.FieldByName(name)
+	if !ok || !f.IsExported() {
+		return f, fmt.Errorf("no exported field %s", name)
+	}
+	kind := f.Type.Kind()
+	if kind == reflect.Slice {
+		kind = f.Type.Elem().Kind()
+	}
+	if kind != reflect.String && (kind < reflect.Int || kind > reflect.Uint64) {
+		return f, fmt.Errorf("field %s: %s cannot hold an identifier", name, f.Type)
+	}
+	return f, nil
+}
+
+// fieldValues renders a string, integer or slice field as identifiers,
+// skipping zero values.
+func fieldValues(v reflect.Value) []string {
+	if v.Kind() == reflect.Slice {
+		var out []string
+		for i := 0; i < v.Len(); i++ {
+			out = append(out, fieldValues(v.Index(i))...)
+		}
+		return out
+	}
+	if v.IsZero() {
+		return nil
+	}
+	return []string{fmt.Sprint(v.Interface())}
+}
+
+// EntityTuples returns the tuples the fga tags of v's type declare, in
+// declaration order. Types registered with Register have none.
+func EntityTuples[T any](v T) ([]Tuple, error) {
+	r, v2, err := lookup(v)
+	if err != nil {
+		return nil, err
+	}
+	id := r.id(v2)
+	if id == "" {
+		return nil, fmt.Errorf("authz: %s has no id", r.objectType)
+	}
+	object := Object{Type: r.objectType, ID: id}.String()
+	rv := reflect.ValueOf(v2)
+	var out []Tuple
+	for _, l := range r.links {
+		for _, u := range fieldValues(rv.FieldByIndex(l.field)) {
+			user := l.userType + ":" + u
+			if l.userRelation != "" {
+				user += "#" + l.userRelation
+			}
+			out = append(out, Tuple{User: user, Relation: l.relation, Object: object})
+		}
+	}
+	return out, nil
+}
+
+// CreateEntity writes the tuples of a newly created entity, in one
+// transaction unless there are more than MaxWriteTuples.
+func CreateEntity[T any](ctx context.Context, w TupleWriter, v T) error {
+	ts, err := EntityTuples(v)
+	if err != nil {
+		return err
+	}
+	return WriteBatched(ctx, w, ts, nil).Err()
+}
+
+// EntityChanges returns the tuples to write and delete when an entity
+// changes from old to new, e.g. a project moved to another organization
+// loses its old parent tuple and gains the new one.
+func EntityChanges[T any](old, new T) (writes, deletes []Tuple, err error) {
+	before, err := EntityTuples(old)
+	if err != nil {
+		return nil, nil, err
+	}
+	after, err := EntityTuples(new)
+	if err != nil {
+		return nil, nil, err
+	}
+	had := make(map[Tuple]bool, len(before))
+	for _, t := range before {
+		had[t] = true
+	}
+	for _, t := range after {
+		if had[t] {
+			delete(had, t)
+			continue
+		}
+		writes = append(writes, t)
+	}
+	for _, t := range before {
+		if had[t] {
+			deletes = append(deletes, t)
+		}
+	}
+	return writes, deletes, nil
+}
+
+// UpdateEntity rewrites the tuples of an entity that changed from old to
+// new in a single transaction, so checks never see a project with both or
+// neither of its organizations. Call it from the application's update hook
+// with the stored and the updated value; unchanged entities make no call.
+// The server rejects the transaction if a deleted tuple was never written.
+func UpdateEntity[T any](ctx context.Context, w TupleWriter, old, new T) error {
+	writes, deletes, err := EntityChanges(old, new)
+	if err != nil {
+		return err
+	}
+	if len(writes) == 0 && len(deletes) == 0 {
+		return nil
+	}
+	if len(writes)+len(deletes) > MaxWriteTuples {
+		return fmt.Errorf("authz: update entity: %d tuple changes exceed one transaction", len(writes)+len(deletes))
+	}
+	return w.Write(ctx, writes, deletes)
+}
+
+// DeleteEntity deletes the tuples of a deleted entity. Tuples other code
+// wrote on the object are left alone; see tuples.DeleteWhere to remove
+// everything.
+func DeleteEntity[T any](ctx context.Context, w TupleWriter, v T) error {
+	ts, err := EntityTuples(v)
+	if err != nil {
+		return err
+	}
+	return WriteBatched(ctx, w, nil, ts).Err()
+}
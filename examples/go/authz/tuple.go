@@ -0,0 +1,192 @@
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Tuple is a relationship: User has Relation on Object, optionally only
+// while Condition holds.
+type Tuple struct {
+	User      string
+	Relation  string
+	Object    string
+	Condition TupleCondition
+}
+
+// TupleCondition makes a tuple hold only for the requests on which the
+// named model condition evaluates to true; the server evaluates it. Context
+// is the condition's stored context as a JSON object, merged with the
+// context of each check. The zero value is no condition.
+type TupleCondition struct {
+	Name    string
+	Context string
+}
+
+// NewTupleCondition returns the condition name with the stored context
+// values, e.g. {"expires_at": "2024-05-01T12:00:00Z"}.
+func NewTupleCondition(name string, values map[string]any) (TupleCondition, error) {
+	c := TupleCondition{Name: name}
+	if len(values) > 0 {
+		raw, err := json.Marshal(values)
+		if err != nil {
+			return TupleCondition{}, fmt.Errorf("authz: condition %s: %w", name, err)
+		}
+		c.Context = string(raw)
+	}
+	return c, nil
+}
+
+// Values decodes the stored context; it is nil without one.
+func (c TupleCondition) Values() (map[string]any, error) {
+	if c.Context == "" {
+		return nil, nil
+	}
+	var values map[string]any
+	if err := json.Unmarshal([]byte(c.Context), &values); err != nil {
+		return nil, fmt.Errorf("authz: condition %s: %w", c.Name, err)
+	}
+	return values, nil
+}
+
+// Key returns t without its condition: the server stores at most one tuple
+// per key, and deletes and read filters match on the key alone.
+func (t Tuple) Key() Tuple {
+	return Tuple{User: t.User, Relation: t.Relation, Object: t.Object}
+}
+
+type tupleJSON struct {
+	User      string              `json:"user"`
+	Relation  string              `json:"relation"`
+	Object    string              `json:"object"`
+	Condition *tupleConditionJSON `json:"condition,omitempty"`
+}
+
+type tupleConditionJSON struct {
+	Name    string          `json:"name"`
+	Context json.RawMessage `json:"context,omitempty"`
+}
+
+// MarshalJSON encodes t as {"user", "relation", "object"} with a
+// "condition" object when it has one.
+func (t Tuple) MarshalJSON() ([]byte, error) {
+	out := tupleJSON{User: t.User, Relation: t.Relation, Object: t.Object}
+	if t.Condition.Name != "" {
+		out.Condition = &tupleConditionJSON{Name: t.Condition.Name}
+		if t.Condition.Context != "" {
+			out.Condition.Context = json.RawMessage(t.Condition.Context)
+		}
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes the form MarshalJSON writes.
+func (t *Tuple) UnmarshalJSON(data []byte) error {
+	var in tupleJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	*t = Tuple{User: in.User, Relation: in.Relation, Object: in.Object}
+	if in.Condition != nil {
+		t.Condition = TupleCondition{Name: in.Condition.Name, Context: string(in.Condition.Context)}
+	}
+	return nil
+}
+
+// String renders t in the object#relation@user form used by relations.txt,
+// followed by " with <condition>" for a conditioned tuple.
+func (t Tuple) String() string {
+	s := t.Object + "#" + t.Relation + "@" + t.User
+	if t.Condition.Name != "" {
+		s += " with " + t.Condition.Name
+	}
+	return s
+}
+
+// ParseTuple parses the object#relation@user form produced by String. A
+// " with <condition>" suffix sets the condition name but no stored context.
+func ParseTuple(s string) (Tuple, error) {
+	s = strings.TrimSpace(s)
+	var cond string
+	if i := strings.Index(s, " with "); i >= 0 {
+		s, cond = strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+len(" with "):])
+		if cond == "" {
+			return Tuple{}, fmt.Errorf("authz: tuple %q: missing condition name", s)
+		}
+	}
+	at := strings.Index(s, "@")
+	if at < 0 {
+		return Tuple{}, fmt.Errorf("authz: tuple %q: missing @user", s)
+	}
+	objRel, user := s[:at], s[at+1:]
+	hash := strings.LastIndex(objRel, "#")
+	if hash < 0 {
+		return Tuple{}, fmt.Errorf("authz: tuple %q: missing #relation", s)
+	}
+	t := Tuple{User: user, Relation: objRel[hash+1:], Object: objRel[:hash]}
+	if t.User == "" || t.Relation == "" || t.Object == "" {
+		return Tuple{}, fmt.Errorf("authz: tuple %q: empty field", s)
+	}
+	t.Condition.Name = cond
+	return t, nil
+}
+
+// Matches reports whether t satisfies a Read filter: empty filter fields
+// match anything, and an Object of the form "type:" matches every object of
+// that type.
+func (filter Tuple) Matches(t Tuple) bool {
+	if filter.User != "" && filter.User != t.User {
+		return false
+	}
+	if filter.Relation != "" && filter.Relation != t.Relation {
+		return false
+	}
+	switch {
+	case filter.Object == "":
+	case strings.HasSuffix(filter.Object, ":"):
+		if !strings.HasPrefix(t.Object, filter.Object) {
+			return false
+		}
+	case filter.Object != t.Object:
+		return false
+	}
+	return true
+}
+
+// ReadType returns every tuple on objects of objectType, or every tuple
+// when objectType is empty. The server filters by object type only
+// together with a user, so this pages through the whole store and filters
+// here; where an object or a user is known, Read with it instead.
+func ReadType(ctx context.Context, b Backend, objectType string) ([]Tuple, error) {
+	all, err := ReadAll(ctx, b, Tuple{})
+	if err != nil || objectType == "" {
+		return all, err
+	}
+	filter := Tuple{Object: objectType + ":"}
+	out := all[:0]
+	for _, t := range all {
+		if filter.Matches(t) {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+// ReadAll pages through every tuple matching filter.
+func ReadAll(ctx context.Context, b Backend, filter Tuple) ([]Tuple, error) {
+	var all []Tuple
+	token := ""
+	for {
+		page, next, err := b.Read(ctx, filter, token)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+		if next == "" || next == token {
+			return all, nil
+		}
+		token = next
+	}
+}
@@ -0,0 +1,77 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// ModelValidator is a WriteGuard that checks every written tuple against the
+// store's active model before it is sent, so malformed tuples fail with a
+// precise message instead of an opaque 400. The model is fetched on first
+// use and cached for TTL.
+type ModelValidator struct {
+	backend Backend
+	ttl     time.Duration
+
+	mu      sync.Mutex
+	model   *model.Model
+	fetched time.Time
+}
+
+// NewModelValidator returns a validator reading the model from backend. A
+// zero ttl caches the model until Invalidate is called.
+func NewModelValidator(backend Backend, ttl time.Duration) *ModelValidator {
+	return &ModelValidator{backend: backend, ttl: ttl}
+}
+
+// WithModelValidation validates writes against the active model, cached for
+// ttl.
+func WithModelValidation(ttl time.Duration) Option {
+	return func(c *Client) { c.guards = append(c.guards, NewModelValidator(c.backend, ttl)) }
+}
+
+// Model returns the cached model, fetching it when absent or expired.
+func (v *ModelValidator) Model(ctx context.Context) (*model.Model, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.model != nil && (v.ttl == 0 || time.Since(v.fetched) < v.ttl) {
+		return v.model, nil
+	}
+	m, err := v.backend.ReadModel(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("authz: load model for validation: %w", err)
+	}
+	v.model, v.fetched = m, time.Now()
+	return m, nil
+}
+
+// Invalidate drops the cached model, e.g. after deploying a new one.
+func (v *ModelValidator) Invalidate() {
+	v.mu.Lock()
+	v.model = nil
+	v.mu.Unlock()
+}
+
+// GuardWrite implements WriteGuard. Every invalid write is reported; the
+// errors wrap model.ErrInvalidTuple.
+func (v *ModelValidator) GuardWrite(ctx context.Context, writes, deletes []Tuple) error {
+	if len(writes) == 0 {
+		return nil
+	}
+	m, err := v.Model(ctx)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, t := range writes {
+		if err := m.ValidateTuple(t.User, t.Relation, t.Object, t.Condition.Name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,115 @@
+package authz
+
+import (
+	"context"
+	"fmt"
+)
+
+// WriteGuard inspects a mutation before it is sent and returns an error to
+// reject it. Guards run in the order they were configured.
+type WriteGuard interface {
+	GuardWrite(ctx context.Context, writes, deletes []Tuple) error
+}
+
+// WriteGuardFunc adapts a function to WriteGuard.
+type WriteGuardFunc func(ctx context.Context, writes, deletes []Tuple) error
+
+// GuardWrite calls f.
+func (f WriteGuardFunc) GuardWrite(ctx context.Context, writes, deletes []Tuple) error {
+	return f(ctx, writes, deletes)
+}
+
+// WithWriteGuard adds a guard consulted by Write.
+func WithWriteGuard(g WriteGuard) Option {
+	return func(c *Client) { c.guards = append(c.guards, g) }
+}
+
+type bypassKey struct{}
+
+// BypassWriteGuards returns a context under which Write skips every guard.
+// It is intended for migrations and repair jobs that must pass through
+// intermediate states the guards would reject.
+func BypassWriteGuards(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassKey{}, true)
+}
+
+func guardsBypassed(ctx context.Context) bool {
+	v, _ := ctx.Value(bypassKey{}).(bool)
+	return v
+}
+
+// Write runs the configured guards and then applies writes and deletes in a
+// single transaction.
+func (c *Client) Write(ctx context.Context, writes, deletes []Tuple) error {
+	writes, err := c.normalizeTuples(writes)
+	if err != nil {
+		return err
+	}
+	deletes, err = c.normalizeTuples(deletes)
+	if err != nil {
+		return err
+	}
+	if deletes, err = c.aliasDeletes(ctx, deletes); err != nil {
+		return err
+	}
+	if !guardsBypassed(ctx) {
+		for _, g := range c.guards {
+			if err := g.GuardWrite(ctx, writes, deletes); err != nil {
+				c.log.InfoContext(ctx, "write rejected by guard", auditArgs(ctx, "error", err)...)
+				return err
+			}
+		}
+	}
+	if err := c.backend.Write(ctx, writes, deletes); err != nil {
+		c.log.WarnContext(ctx, "write failed", auditArgs(ctx, "writes", len(writes), "deletes", len(deletes), "error", err)...)
+		return fmt.Errorf("authz: write: %w", err)
+	}
+	c.log.DebugContext(ctx, "write", auditArgs(ctx, "writes", len(writes), "deletes", len(deletes))...)
+	return nil
+}
+
+// Backend returns the backend the client talks to.
+func (c *Client) Backend() Backend {
+	return c.backend
+}
+
+// MaxWriteTuples is the server's limit on writes plus deletes per Write.
+const MaxWriteTuples = 100
+
+// TupleWriter is the write side shared by Backend and Client.
+type TupleWriter interface {
+	Write(ctx context.Context, writes, deletes []Tuple) error
+}
+
+// WriteBatched splits a mutation into Write calls of at most MaxWriteTuples
+// tuples, writes before deletes, and reports each tuple's outcome. The
+// mutation as a whole is not atomic: batches before a failing one stay
+// applied, and the tuples of the failing batch and of every later one fail
+// with its error. Unlike WriteMany, nothing is written after a failure, so
+// later batches never overtake an earlier one; use BatchResult.Err for a
+// single error.
+func WriteBatched(ctx context.Context, b TupleWriter, writes, deletes []Tuple) *BatchResult {
+	items := make([]ItemResult, 0, len(writes)+len(deletes))
+	for _, t := range writes {
+		items = append(items, ItemResult{Index: len(items), Tuple: t, Op: OpWrite})
+	}
+	for _, t := range deletes {
+		items = append(items, ItemResult{Index: len(items), Tuple: t, Op: OpDelete})
+	}
+	run := func(ctx context.Context, items []ItemResult) []ItemResult {
+		out := make([]ItemResult, 0, len(items))
+		var err error
+		for len(items) > 0 {
+			batch := items[:min(len(items), MaxWriteTuples)]
+			items = items[len(batch):]
+			if err == nil {
+				err = writeItemBatch(ctx, b, batch)
+			}
+			for _, it := range batch {
+				out = append(out, it.fail(err))
+			}
+		}
+		return out
+	}
+	return &BatchResult{Items: run(ctx, items), run: run}
+}
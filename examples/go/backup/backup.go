@@ -0,0 +1,442 @@
+// Package backup takes disaster-recovery snapshots of a store and restores
+// them, optionally to a point in time, into a fresh store.
+//
+// A snapshot is the active model and every tuple; between snapshots the
+// changes feed is appended to a change log, so restoring replays the log on
+// top of the latest snapshot before the requested time:
+//
+//	bk := &backup.Backup{Backend: authz.FromSDK(fga), Dir: "/var/backups/fga", SnapshotEvery: 24 * time.Hour}
+//	go bk.Run(ctx, time.Minute)
+//
+//	res, err := backup.Restore(ctx, "/var/backups/fga", at, authz.FromSDK(fresh), promote.SDKModelWriter{Client: fresh})
+//
+// The directory holds snapshot-<time>.json files, each followed by the
+// changes-<time>.jsonl log of the changes read after it, and the feed
+// cursor.
+package backup
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/eval"
+	"github.com/bogdanticu88/openfga-examples/events"
+	"github.com/bogdanticu88/openfga-examples/model"
+	"github.com/bogdanticu88/openfga-examples/promote"
+)
+
+var (
+	// ErrNoSnapshot is returned when the directory has no snapshot to sync
+	// after or restore from.
+	ErrNoSnapshot = errors.New("backup: no snapshot")
+	// ErrNotEmpty is returned by Restore when the target store has tuples.
+	ErrNotEmpty = errors.New("backup: target store is not empty")
+)
+
+// stamp names files so they sort in time order.
+const stamp = "20060102T150405.000000000Z"
+
+// Snapshot is the content of a snapshot file.
+type Snapshot struct {
+	// TakenAt is when the tuples had been read. Changes made while reading
+	// may or may not be included; they are also in the following change
+	// log, and replaying them is idempotent.
+	TakenAt time.Time     `json:"taken_at"`
+	Store   string        `json:"store"`
+	ModelID string        `json:"model_id"`
+	Model   string        `json:"model"`
+	Tuples  []authz.Tuple `json:"tuples"`
+}
+
+// Backup writes snapshots and change logs of Backend to Dir.
+type Backup struct {
+	Backend authz.Backend
+	Dir     string
+	// Cursor keeps the changes feed position; default a file in Dir.
+	Cursor events.Cursor
+	// SnapshotEvery is how often Run takes a snapshot; zero takes one only
+	// when Dir has none.
+	SnapshotEvery time.Duration
+	// Keep is the number of snapshots kept, with their change logs; zero
+	// keeps all. Restoring to a time before the oldest kept snapshot fails.
+	Keep int
+	// Now defaults to time.Now.
+	Now func() time.Time
+	// Logger receives scheduling events; nil disables logging.
+	Logger *slog.Logger
+}
+
+// Snapshot drains the changes feed into the current change log, then writes
+// a new snapshot and starts its change log.
+func (bk *Backup) Snapshot(ctx context.Context) (*Snapshot, error) {
+	_, err := bk.Sync(ctx)
+	if errors.Is(err, ErrNoSnapshot) {
+		// The first snapshot holds everything before it; only move the
+		// cursor to the end of the feed.
+		err = bk.skipChanges(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	m, err := bk.Backend.ReadModel(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("backup: read model: %w", err)
+	}
+	st, err := bk.Backend.GetStore(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("backup: get store: %w", err)
+	}
+	all, err := authz.ReadAll(ctx, bk.Backend, authz.Tuple{})
+	if err != nil {
+		return nil, fmt.Errorf("backup: read tuples: %w", err)
+	}
+	eval.SortTuples(all)
+	s := &Snapshot{TakenAt: bk.now().UTC(), Store: st.ID, ModelID: m.ID, Model: m.String(), Tuples: all}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	name := s.TakenAt.Format(stamp)
+	if err := writeFile(filepath.Join(bk.Dir, "snapshot-"+name+".json"), data); err != nil {
+		return nil, fmt.Errorf("backup: %w", err)
+	}
+	if err := writeFile(filepath.Join(bk.Dir, "changes-"+name+".jsonl"), nil); err != nil {
+		return nil, fmt.Errorf("backup: %w", err)
+	}
+	bk.logf(ctx, slog.LevelInfo, "snapshot taken", "tuples", len(all), "model_id", m.ID)
+	return s, bk.prune()
+}
+
+// Sync appends the changes since the last call to the latest snapshot's
+// change log and returns how many there were.
+func (bk *Backup) Sync(ctx context.Context) (int, error) {
+	snaps, err := list(bk.Dir)
+	if err != nil {
+		return 0, err
+	}
+	if len(snaps) == 0 {
+		return 0, ErrNoSnapshot
+	}
+	cur := bk.cursor()
+	token, err := cur.Load(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("backup: load cursor: %w", err)
+	}
+	changes, next, err := authz.ReadAllChanges(ctx, bk.Backend, "", token)
+	if len(changes) > 0 {
+		if werr := appendChanges(filepath.Join(bk.Dir, "changes-"+snaps[len(snaps)-1]+".jsonl"), changes); werr != nil {
+			return 0, fmt.Errorf("backup: %w", werr)
+		}
+	}
+	// Save what was appended even when the read stopped early, so the
+	// next call does not append it again.
+	if next != token {
+		if serr := cur.Save(ctx, next); serr != nil {
+			return len(changes), fmt.Errorf("backup: save cursor: %w", serr)
+		}
+	}
+	if err != nil {
+		return len(changes), fmt.Errorf("backup: read changes: %w", err)
+	}
+	return len(changes), nil
+}
+
+func (bk *Backup) skipChanges(ctx context.Context) error {
+	cur := bk.cursor()
+	token, err := cur.Load(ctx)
+	if err != nil {
+		return fmt.Errorf("backup: load cursor: %w", err)
+	}
+	_, next, err := authz.ReadAllChanges(ctx, bk.Backend, "", token)
+	if err != nil {
+		return fmt.Errorf("backup: read changes: %w", err)
+	}
+	if err := cur.Save(ctx, next); err != nil {
+		return fmt.Errorf("backup: save cursor: %w", err)
+	}
+	return nil
+}
+
+// Run syncs the change log every interval until ctx is done, taking a
+// snapshot first when Dir has none and then every SnapshotEvery. Failures
+// are logged and retried at the next interval.
+func (bk *Backup) Run(ctx context.Context, interval time.Duration) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		bk.tick(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+func (bk *Backup) tick(ctx context.Context) {
+	snaps, err := list(bk.Dir)
+	if err != nil {
+		bk.logf(ctx, slog.LevelWarn, "backup failed", "error", err)
+		return
+	}
+	due := len(snaps) == 0
+	if !due && bk.SnapshotEvery > 0 {
+		last, _ := time.Parse(stamp, snaps[len(snaps)-1])
+		due = bk.now().Sub(last) >= bk.SnapshotEvery
+	}
+	if due {
+		if _, err := bk.Snapshot(ctx); err != nil {
+			bk.logf(ctx, slog.LevelWarn, "snapshot failed", "error", err)
+		}
+		return
+	}
+	if n, err := bk.Sync(ctx); err != nil {
+		bk.logf(ctx, slog.LevelWarn, "change log sync failed", "error", err)
+	} else if n > 0 {
+		bk.logf(ctx, slog.LevelDebug, "change log synced", "changes", n)
+	}
+}
+
+// prune removes the snapshots and change logs beyond Keep.
+func (bk *Backup) prune() error {
+	if bk.Keep <= 0 {
+		return nil
+	}
+	snaps, err := list(bk.Dir)
+	if err != nil {
+		return err
+	}
+	for _, name := range snaps[:max(0, len(snaps)-bk.Keep)] {
+		for _, f := range []string{"snapshot-" + name + ".json", "changes-" + name + ".jsonl"} {
+			if err := os.Remove(filepath.Join(bk.Dir, f)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("backup: prune: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// RestoreResult describes a restore.
+type RestoreResult struct {
+	// Snapshot is the snapshot restored from, without its tuples.
+	Snapshot Snapshot
+	// Replayed is the number of change log entries applied on top of it.
+	Replayed int
+	Tuples   int
+	ModelID  string
+}
+
+// State is the store as it was at a point in time, rebuilt from a backup
+// directory by Load.
+type State struct {
+	// Snapshot is the snapshot the state was rebuilt from, without its
+	// tuples.
+	Snapshot Snapshot
+	// Model is the snapshot's model.
+	Model *model.Model
+	// Tuples are the tuples in effect, sorted with eval.SortTuples.
+	Tuples []authz.Tuple
+	// Replayed is the number of change log entries applied on top of the
+	// snapshot.
+	Replayed int
+}
+
+// Load rebuilds the state at time at from dir: the tuples of the latest
+// snapshot taken at or before at with every logged change up to at
+// applied. The zero time loads the latest state in dir.
+func Load(dir string, at time.Time) (*State, error) {
+	snaps, err := list(dir)
+	if err != nil {
+		return nil, err
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+	from := -1
+	for i, name := range snaps {
+		if t, _ := time.Parse(stamp, name); !t.After(at) {
+			from = i
+		}
+	}
+	if from < 0 {
+		return nil, fmt.Errorf("%w at or before %s", ErrNoSnapshot, at.UTC().Format(time.RFC3339))
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "snapshot-"+snaps[from]+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("backup: %w", err)
+	}
+	var s Snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("backup: snapshot %s: %w", snaps[from], err)
+	}
+	m, err := model.Parse("snapshot-"+snaps[from]+".json", []byte(s.Model))
+	if err != nil {
+		return nil, fmt.Errorf("backup: snapshot model: %w", err)
+	}
+	// Keyed like the server's tuples: deletes in the changes feed carry no
+	// condition, and a rewrite replaces the condition.
+	state := map[authz.Tuple]authz.Tuple{}
+	for _, t := range s.Tuples {
+		state[t.Key()] = t
+	}
+	st := &State{Model: m}
+	// Changes made while a later snapshot was being read are logged after
+	// it, so every later log is scanned, not only this snapshot's.
+	for _, name := range snaps[from:] {
+		n, err := replay(filepath.Join(dir, "changes-"+name+".jsonl"), at, state)
+		if err != nil {
+			return nil, fmt.Errorf("backup: %w", err)
+		}
+		st.Replayed += n
+	}
+	st.Tuples = make([]authz.Tuple, 0, len(state))
+	for _, t := range state {
+		st.Tuples = append(st.Tuples, t)
+	}
+	eval.SortTuples(st.Tuples)
+	s.Tuples = nil
+	st.Snapshot = s
+	return st, nil
+}
+
+// Restore rebuilds the store as it was at time at in target, which must be
+// empty: it writes the snapshot's model, then the tuples Load returns for
+// at. The zero time restores the latest state in dir.
+func Restore(ctx context.Context, dir string, at time.Time, target authz.Backend, models promote.ModelWriter) (*RestoreResult, error) {
+	st, err := Load(dir, at)
+	if err != nil {
+		return nil, err
+	}
+	existing, _, err := target.Read(ctx, authz.Tuple{}, "")
+	if err != nil {
+		return nil, fmt.Errorf("backup: read target: %w", err)
+	}
+	if len(existing) > 0 {
+		return nil, ErrNotEmpty
+	}
+	res := &RestoreResult{Snapshot: st.Snapshot, Replayed: st.Replayed, Tuples: len(st.Tuples)}
+	if res.ModelID, err = models.WriteModel(ctx, st.Model); err != nil {
+		return nil, fmt.Errorf("backup: write model: %w", err)
+	}
+	if err := authz.WriteBatched(ctx, target, st.Tuples, nil).Err(); err != nil {
+		return nil, fmt.Errorf("backup: write tuples: %w", err)
+	}
+	return res, nil
+}
+
+// replay applies the logged changes made at or before at to state.
+func replay(path string, at time.Time, state map[authz.Tuple]authz.Tuple) (int, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	n := 0
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 1<<20)
+	for line := 1; sc.Scan(); line++ {
+		var c authz.Change
+		if err := json.Unmarshal(sc.Bytes(), &c); err != nil {
+			return n, fmt.Errorf("%s:%d: %w", filepath.Base(path), line, err)
+		}
+		if c.Timestamp.After(at) {
+			continue
+		}
+		if c.Operation == authz.OpWrite {
+			state[c.Tuple.Key()] = c.Tuple
+		} else {
+			delete(state, c.Tuple.Key())
+		}
+		n++
+	}
+	return n, sc.Err()
+}
+
+// list returns the snapshot names in dir, oldest first.
+func list(dir string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "snapshot-*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("backup: %w", err)
+	}
+	names := make([]string, len(matches))
+	for i, m := range matches {
+		names[i] = strings.TrimSuffix(strings.TrimPrefix(filepath.Base(m), "snapshot-"), ".json")
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func appendChanges(path string, changes []authz.Change) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, c := range changes {
+		if err := enc.Encode(c); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeFile writes data to path atomically.
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".backup-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (bk *Backup) cursor() events.Cursor {
+	if bk.Cursor != nil {
+		return bk.Cursor
+	}
+	return events.FileCursor(filepath.Join(bk.Dir, "cursor"))
+}
+
+func (bk *Backup) now() time.Time {
+	if bk.Now != nil {
+		return bk.Now()
+	}
+	return time.Now()
+}
+
+func (bk *Backup) logf(ctx context.Context, level slog.Level, msg string, args ...interface{}) {
+	if bk.Logger != nil {
+		bk.Logger.Log(ctx, level, msg, args...)
+	}
+}
@@ -0,0 +1,66 @@
+package backup
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+)
+
+func TestLoadReplaysConditionedRewrite(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	name := base.Format(stamp)
+	snap := Snapshot{TakenAt: base, Model: "model\n  schema 1.1\n\ntype user\n\ntype system\n  relations\n    define break_glass: [user with not_expired]\n\ncondition not_expired(current_time: timestamp, expires_at: timestamp) {\n  current_time < expires_at\n}\n"}
+	data, err := json.Marshal(snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "snapshot-"+name+".json"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	grant := func(expires string) authz.Tuple {
+		c, err := authz.NewTupleCondition("not_expired", map[string]any{"expires_at": expires})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return authz.Tuple{User: "user:alice", Relation: "break_glass", Object: "system:global", Condition: c}
+	}
+	first, moved := grant("2024-05-01T14:00:00Z"), grant("2024-05-01T18:00:00Z")
+	changes := []authz.Change{
+		{Tuple: first, Operation: authz.OpWrite, Timestamp: base.Add(time.Minute)},
+		// The changes feed reports deletes without the condition.
+		{Tuple: first.Key(), Operation: authz.OpDelete, Timestamp: base.Add(2 * time.Minute)},
+		{Tuple: moved, Operation: authz.OpWrite, Timestamp: base.Add(2 * time.Minute)},
+	}
+	if err := appendChanges(filepath.Join(dir, "changes-"+name+".jsonl"), changes); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		at   time.Time
+		want []authz.Tuple
+	}{
+		{base, nil},
+		{base.Add(time.Minute), []authz.Tuple{first}},
+		{base.Add(2 * time.Minute), []authz.Tuple{moved}},
+	}
+	for _, tt := range tests {
+		st, err := Load(dir, tt.at)
+		if err != nil {
+			t.Fatalf("Load(%s): %v", tt.at, err)
+		}
+		if len(st.Tuples) != len(tt.want) {
+			t.Fatalf("Load(%s) = %v, want %v", tt.at, st.Tuples, tt.want)
+		}
+		for i := range tt.want {
+			if st.Tuples[i] != tt.want[i] {
+				t.Errorf("Load(%s) = %v, want %v", tt.at, st.Tuples, tt.want)
+			}
+		}
+	}
+}
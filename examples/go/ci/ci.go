@@ -0,0 +1,429 @@
+// Package ci is a policy-as-code gate for authorization model changes. A
+// bundle is a directory laid out like those under models/:
+//
+//	model.fga       the proposed model (required, unless fga.mod is present)
+//	fga.mod         a manifest of model fragments, see package modules
+//	relations.txt   a snapshot of tuples, one object#relation@user per line
+//	assertions.txt  checks with known answers: object#relation@user true|false
+//	baseline.fga    the currently deployed model
+//	fga.owners      type ownership, see modules.Ownership
+//
+// Validate parses and lints the model, checks that every snapshot tuple is
+// still writable, runs the assertions, and — given a baseline — reports
+// removed relations, replays the snapshot under both models to list every
+// decision the change flips, and flags changes to types the author does
+// not own. Results are machine-readable JSON or SARIF.
+package ci
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/eval"
+	"github.com/bogdanticu88/openfga-examples/lint"
+	"github.com/bogdanticu88/openfga-examples/model"
+	"github.com/bogdanticu88/openfga-examples/modeldiff"
+	"github.com/bogdanticu88/openfga-examples/modules"
+	"github.com/bogdanticu88/openfga-examples/sarif"
+)
+
+// Bundle file names.
+const (
+	ModelFile      = "model.fga"
+	ManifestFile   = modules.ManifestFile
+	TuplesFile     = "relations.txt"
+	AssertionsFile = "assertions.txt"
+	BaselineFile   = "baseline.fga"
+	OwnersFile     = modules.OwnersFile
+)
+
+// Stages, reported as Finding.Stage and as the SARIF rule ID prefix.
+const (
+	StageParse      = "parse"
+	StageModules    = "modules"
+	StageValidate   = "validate"
+	StageLint       = "lint"
+	StageTuples     = "tuples"
+	StageAssertions = "assertions"
+	StageCompat     = "compat"
+	StageShadow     = "shadow"
+	StageOwnership  = "ownership"
+)
+
+// Finding is one problem in a bundle. Level is a SARIF level.
+type Finding struct {
+	Stage   string `json:"stage"`
+	Rule    string `json:"rule"`
+	Level   string `json:"level"`
+	Message string `json:"message"`
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Col     int    `json:"col,omitempty"`
+}
+
+func (f Finding) String() string {
+	loc := f.File
+	if f.Line > 0 {
+		loc = fmt.Sprintf("%s:%d:%d", f.File, f.Line, f.Col)
+	}
+	return fmt.Sprintf("%s: %s: %s [%s]", loc, f.Level, f.Message, f.Rule)
+}
+
+// Result is the outcome of Validate.
+type Result struct {
+	Bundle string `json:"bundle"`
+	// OK is false when any finding is at or above Config.FailOn.
+	OK           bool      `json:"ok"`
+	Tuples       int       `json:"tuples"`
+	Assertions   int       `json:"assertions"`
+	ShadowChecks int       `json:"shadow_checks"`
+	Findings     []Finding `json:"findings"`
+}
+
+// WriteJSON writes r as indented JSON.
+func (r *Result) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// SARIF converts r to a SARIF log for code review annotations.
+func (r *Result) SARIF() *sarif.Log {
+	seen := map[string]bool{}
+	var rules []sarif.Rule
+	for _, f := range r.Findings {
+		if !seen[f.Rule] {
+			seen[f.Rule] = true
+			rules = append(rules, sarif.Rule{ID: f.Rule, ShortDescription: sarif.Message{Text: f.Stage}})
+		}
+	}
+	log := sarif.New("fga-ci", rules)
+	for _, f := range r.Findings {
+		res := sarif.Result{RuleID: f.Rule, Level: f.Level, Message: sarif.Message{Text: f.Message}}
+		if f.File != "" {
+			res.Locations = sarif.At(filepath.ToSlash(f.File), f.Line, f.Col)
+		}
+		log.Add(res)
+	}
+	return log
+}
+
+// Config tunes Validate. The zero value is usable.
+type Config struct {
+	Lint lint.Config
+	// FailOn is the lowest level that fails the gate; default
+	// sarif.LevelError.
+	FailOn string
+	// Baseline overrides the bundle's baseline.fga.
+	Baseline string
+	// MaxShadowChecks bounds the replay; default 10000. Hitting the bound is
+	// reported as a note.
+	MaxShadowChecks int
+	// Owners overrides the bundle's fga.owners.
+	Owners string
+	// Author lists the identities of the change's author, e.g. their
+	// handle and teams as used in the ownership file. Changes to types
+	// they do not own are errors; without an author, every change with
+	// owners is a note naming the reviewers it needs.
+	Author []string
+}
+
+// Validate runs every stage on the bundle at path with the default Config.
+func Validate(ctx context.Context, bundlePath string) (*Result, error) {
+	return Config{}.Validate(ctx, bundlePath)
+}
+
+// Validate runs every stage on the bundle at path. The error is for bundles
+// that cannot be read at all; problems in their content are findings.
+func (c Config) Validate(ctx context.Context, bundlePath string) (*Result, error) {
+	v := &validator{cfg: c, dir: bundlePath, res: &Result{Bundle: bundlePath}}
+	if err := v.run(ctx); err != nil {
+		return nil, err
+	}
+	v.res.OK = true
+	for _, f := range v.res.Findings {
+		if rank(f.Level) >= rank(c.failOn()) {
+			v.res.OK = false
+		}
+	}
+	return v.res, nil
+}
+
+func (c Config) failOn() string {
+	if c.FailOn == "" {
+		return sarif.LevelError
+	}
+	return c.FailOn
+}
+
+func rank(level string) int {
+	switch level {
+	case sarif.LevelError:
+		return 2
+	case sarif.LevelWarning:
+		return 1
+	}
+	return 0
+}
+
+type validator struct {
+	cfg Config
+	dir string
+	// modelPath is model.fga, or fga.mod when the bundle has one.
+	modelPath string
+	res       *Result
+	model     *model.Model
+	tuples    []authz.Tuple
+}
+
+func (v *validator) add(f Finding) { v.res.Findings = append(v.res.Findings, f) }
+
+func (v *validator) path(name string) string { return filepath.Join(v.dir, name) }
+
+// file returns the file pos is in: a fragment for modular models.
+func (v *validator) file(pos model.Pos) string {
+	if pos.File != "" {
+		return pos.File
+	}
+	return v.modelPath
+}
+
+func (v *validator) run(ctx context.Context) error {
+	v.modelPath = v.path(ModelFile)
+	if _, err := os.Stat(v.path(ManifestFile)); err == nil {
+		v.modelPath = v.path(ManifestFile)
+	}
+	m, err := modules.ParseFile(v.modelPath)
+	if err != nil {
+		var se *model.SyntaxError
+		var conflicts []*modules.Conflict
+		for _, e := range unjoin(err) {
+			var c *modules.Conflict
+			if errors.As(e, &c) {
+				conflicts = append(conflicts, c)
+			}
+		}
+		switch {
+		case errors.As(err, &se):
+			v.add(Finding{Stage: StageParse, Rule: "parse/syntax", Level: sarif.LevelError, Message: se.Msg, File: v.file(se.Pos), Line: se.Pos.Line, Col: se.Pos.Col})
+		case len(conflicts) > 0:
+			for _, c := range conflicts {
+				msg := c.Msg
+				if c.Other != (model.Pos{}) {
+					msg += ", first defined at " + c.Other.String()
+				}
+				v.add(Finding{Stage: StageModules, Rule: "modules/conflict", Level: sarif.LevelError, Message: msg, File: v.file(c.Pos), Line: c.Pos.Line, Col: c.Pos.Col})
+			}
+		default:
+			return fmt.Errorf("ci: %w", err)
+		}
+		return nil
+	}
+	v.model = m
+	if err := m.Validate(); err != nil {
+		for _, e := range unjoin(err) {
+			f := Finding{Stage: StageValidate, Rule: "validate/model", Level: sarif.LevelError, Message: e.Error(), File: v.modelPath}
+			var ve *model.ValidationError
+			if errors.As(e, &ve) {
+				f.Message, f.File, f.Line, f.Col = ve.Msg, v.file(ve.Pos), ve.Pos.Line, ve.Pos.Col
+			}
+			v.add(f)
+		}
+		return nil
+	}
+	for _, lf := range lint.Lint(m, v.cfg.Lint) {
+		v.add(Finding{Stage: StageLint, Rule: "lint/" + lf.Rule, Level: lf.Severity.Level(), Message: lf.Message, File: v.file(lf.Pos), Line: lf.Pos.Line, Col: lf.Pos.Col})
+	}
+	if err := v.checkTuples(); err != nil {
+		return err
+	}
+	if err := v.checkAssertions(ctx); err != nil {
+		return err
+	}
+	return v.checkBaseline(ctx)
+}
+
+func unjoin(err error) []error {
+	if j, ok := err.(interface{ Unwrap() []error }); ok {
+		return j.Unwrap()
+	}
+	return []error{err}
+}
+
+// eachLine calls fn with every non-blank, non-comment line of the bundle
+// file name and its line number. A missing file is not an error.
+func (v *validator) eachLine(name string, fn func(line int, text string)) (bool, error) {
+	f, err := os.Open(v.path(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("ci: %w", err)
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		text := strings.TrimSpace(sc.Text())
+		if text != "" && !strings.HasPrefix(text, "#") {
+			fn(n, text)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return true, fmt.Errorf("ci: %s: %w", name, err)
+	}
+	return true, nil
+}
+
+// checkTuples requires every snapshot tuple to be writable under the new
+// model, so deploying it does not strand stored data.
+func (v *validator) checkTuples() error {
+	file := v.path(TuplesFile)
+	_, err := v.eachLine(TuplesFile, func(line int, text string) {
+		t, err := authz.ParseTuple(text)
+		if err != nil {
+			v.add(Finding{Stage: StageTuples, Rule: "tuples/syntax", Level: sarif.LevelError, Message: err.Error(), File: file, Line: line, Col: 1})
+			return
+		}
+		v.tuples = append(v.tuples, t)
+		if err := v.model.ValidateTuple(t.User, t.Relation, t.Object, t.Condition.Name); err != nil {
+			v.add(Finding{Stage: StageTuples, Rule: "tuples/invalid", Level: sarif.LevelError, Message: err.Error(), File: file, Line: line, Col: 1})
+		}
+	})
+	v.res.Tuples = len(v.tuples)
+	return err
+}
+
+func (v *validator) checkAssertions(ctx context.Context) error {
+	file := v.path(AssertionsFile)
+	e := eval.New(v.model, eval.NewTupleStore(v.tuples...))
+	_, err := v.eachLine(AssertionsFile, func(line int, text string) {
+		fail := func(rule, format string, args ...interface{}) {
+			v.add(Finding{Stage: StageAssertions, Rule: rule, Level: sarif.LevelError, Message: fmt.Sprintf(format, args...), File: file, Line: line, Col: 1})
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 || (fields[1] != "true" && fields[1] != "false") {
+			fail("assertions/syntax", "expected \"object#relation@user true|false\", got %q", text)
+			return
+		}
+		t, err := authz.ParseTuple(fields[0])
+		if err != nil {
+			fail("assertions/syntax", "%v", err)
+			return
+		}
+		v.res.Assertions++
+		want := fields[1] == "true"
+		got, err := e.Check(ctx, authz.CheckRequest{User: t.User, Relation: t.Relation, Object: t.Object})
+		switch {
+		case err != nil:
+			fail("assertions/error", "%s: %v", t, err)
+		case got != want:
+			fail("assertions/failed", "%s: expected %v, got %v", t, want, got)
+		}
+	})
+	return err
+}
+
+func (v *validator) checkBaseline(ctx context.Context) error {
+	path := v.cfg.Baseline
+	if path == "" {
+		path = v.path(BaselineFile)
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+	}
+	base, err := modules.ParseFile(path)
+	if err != nil {
+		return fmt.Errorf("ci: baseline: %w", err)
+	}
+	if err := v.checkOwnership(base); err != nil {
+		return err
+	}
+	for _, bt := range base.Types {
+		nt := v.model.Type(bt.Name)
+		if nt == nil {
+			v.add(Finding{Stage: StageCompat, Rule: "compat/type-removed", Level: sarif.LevelWarning,
+				Message: fmt.Sprintf("type %s was removed; applications checking it will fail", bt.Name), File: v.modelPath})
+			continue
+		}
+		for _, br := range bt.Relations {
+			if nt.Relation(br.Name) == nil {
+				v.add(Finding{Stage: StageCompat, Rule: "compat/relation-removed", Level: sarif.LevelWarning,
+					Message: fmt.Sprintf("relation %s#%s was removed; applications checking it will fail", bt.Name, br.Name), File: v.file(nt.Pos), Line: nt.Pos.Line, Col: nt.Pos.Col})
+			}
+		}
+	}
+	return v.shadow(ctx, base)
+}
+
+// checkOwnership flags the changes from base that the author does not
+// own, when the bundle has an ownership file.
+func (v *validator) checkOwnership(base *model.Model) error {
+	path := v.cfg.Owners
+	if path == "" {
+		path = v.path(OwnersFile)
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+	}
+	o, err := modules.ReadOwnership(path)
+	if err != nil {
+		return fmt.Errorf("ci: owners: %w", err)
+	}
+	changes := modules.Diff(base, v.model)
+	if len(v.cfg.Author) == 0 {
+		for _, c := range changes {
+			if owners := o.Owners(c.Type, c.Relation); len(owners) > 0 {
+				v.add(Finding{Stage: StageOwnership, Rule: "ownership/review", Level: sarif.LevelNote,
+					Message: fmt.Sprintf("%s: needs review from %s", c, strings.Join(owners, ", ")), File: v.file(c.Pos), Line: c.Pos.Line, Col: c.Pos.Col})
+			}
+		}
+		return nil
+	}
+	// Removals point into the baseline.
+	for _, vi := range o.Check(changes, v.cfg.Author) {
+		v.add(Finding{Stage: StageOwnership, Rule: "ownership/outside", Level: sarif.LevelError,
+			Message: fmt.Sprintf("%s, owned by %s", vi.Change, strings.Join(vi.Owners, ", ")), File: v.file(vi.Pos), Line: vi.Pos.Line, Col: vi.Pos.Col})
+	}
+	return nil
+}
+
+// shadow replays the snapshot under both models: every concrete user of the
+// snapshot against every relation both models define on every object.
+func (v *validator) shadow(ctx context.Context, base *model.Model) error {
+	limit := v.cfg.MaxShadowChecks
+	if limit <= 0 {
+		limit = 10000
+	}
+	queries := modeldiff.Queries(base, v.model, v.tuples)
+	if len(queries) > limit {
+		queries = queries[:limit]
+		defer v.add(Finding{Stage: StageShadow, Rule: "shadow/truncated", Level: sarif.LevelNote,
+			Message: fmt.Sprintf("shadow evaluation stopped after %d checks", limit), File: v.modelPath})
+	}
+	rep, err := modeldiff.Behavioral(ctx, base, v.model, v.tuples, queries)
+	if err != nil {
+		return fmt.Errorf("ci: shadow: %w", err)
+	}
+	v.res.ShadowChecks = rep.Queries
+	for _, c := range rep.Changes {
+		q := c.Query
+		typ, _, _ := strings.Cut(q.Object, ":")
+		pos := v.model.Relation(typ, q.Relation).Pos
+		if err := errors.Join(c.BeforeErr, c.AfterErr); err != nil {
+			v.add(Finding{Stage: StageShadow, Rule: "shadow/error", Level: sarif.LevelWarning,
+				Message: fmt.Sprintf("%s#%s@%s: %v", q.Object, q.Relation, q.User, err), File: v.file(pos), Line: pos.Line, Col: pos.Col})
+			continue
+		}
+		v.add(Finding{Stage: StageShadow, Rule: "shadow/changed", Level: sarif.LevelWarning,
+			Message: fmt.Sprintf("%s#%s@%s changes from %v to %v", q.Object, q.Relation, q.User, c.Before, c.After), File: v.file(pos), Line: pos.Line, Col: pos.Col})
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+// Command authzvet reports HTTP handlers and gRPC methods that never call an
+// authorization helper.
+//
+//	authzvet ./...
+//	authzvet -funcs example.com/app/authz.Client.Require,example.com/app/web.Authorize ./internal/api
+//	go vet -vettool=$(which authzvet) ./...
+//
+// It exits with a non-zero status when findings are reported, so it can run
+// in CI next to go vet. Suppress a finding with an "//authz:ignore <reason>"
+// line in the function's doc comment.
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"github.com/bogdanticu88/openfga-examples/enforcecheck"
+)
+
+func main() {
+	singlechecker.Main(enforcecheck.Analyzer)
+}
@@ -0,0 +1,99 @@
+// Command fgaci validates model bundles in CI: lint, snapshot tuples,
+// assertions, and compatibility, shadow evaluation and ownership checks
+// against a baseline.
+//
+//	fgaci models/saas
+//	fgaci -format sarif -fail-on warning models/* > fga.sarif
+//	fgaci -baseline main/fga.mod -author @bob,@tracker models/modular
+//
+// It exits with status 1 when any bundle fails the gate.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/bogdanticu88/openfga-examples/ci"
+	"github.com/bogdanticu88/openfga-examples/lint"
+)
+
+func main() {
+	format := flag.String("format", "text", "output format: text, json or sarif")
+	failOn := flag.String("fail-on", "error", "lowest level that fails the gate: note, warning or error")
+	baseline := flag.String("baseline", "", "deployed model to compare against, instead of each bundle's baseline.fga")
+	public := flag.String("public", "", "comma-separated type#relation pairs that are meant to be public")
+	disable := flag.String("disable", "", "comma-separated lint rule IDs to skip")
+	owners := flag.String("owners", "", "ownership file, instead of each bundle's fga.owners")
+	author := flag.String("author", "", "comma-separated handles and teams of the change's author, checked against the ownership file")
+	flag.Parse()
+
+	cfg := ci.Config{
+		Lint:     lint.Config{Public: split(*public), Disable: split(*disable)},
+		FailOn:   *failOn,
+		Baseline: *baseline,
+		Owners:   *owners,
+		Author:   split(*author),
+	}
+	ok := true
+	var results []*ci.Result
+	for _, dir := range flag.Args() {
+		res, err := cfg.Validate(context.Background(), dir)
+		if err != nil {
+			log.Fatalf("fgaci: %v", err)
+		}
+		ok = ok && res.OK
+		results = append(results, res)
+	}
+
+	switch *format {
+	case "json":
+		all := &ci.Result{Bundle: strings.Join(flag.Args(), ","), OK: ok}
+		if len(results) == 1 {
+			all = results[0]
+		} else {
+			for _, r := range results {
+				all.Tuples += r.Tuples
+				all.Assertions += r.Assertions
+				all.ShadowChecks += r.ShadowChecks
+				all.Findings = append(all.Findings, r.Findings...)
+			}
+		}
+		if err := all.WriteJSON(os.Stdout); err != nil {
+			log.Fatalf("fgaci: %v", err)
+		}
+	case "sarif":
+		merged := &ci.Result{}
+		for _, r := range results {
+			merged.Findings = append(merged.Findings, r.Findings...)
+		}
+		if err := merged.SARIF().Write(os.Stdout); err != nil {
+			log.Fatalf("fgaci: %v", err)
+		}
+	default:
+		for _, r := range results {
+			for _, f := range r.Findings {
+				fmt.Fprintln(os.Stderr, f)
+			}
+			status := "ok"
+			if !r.OK {
+				status = "FAIL"
+			}
+			fmt.Printf("%s %s: %d tuples, %d assertions, %d shadow checks, %d findings\n",
+				status, r.Bundle, r.Tuples, r.Assertions, r.ShadowChecks, len(r.Findings))
+		}
+	}
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+func split(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
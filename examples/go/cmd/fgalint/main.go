@@ -0,0 +1,62 @@
+// Command fgalint reports questionable constructs in .fga model files.
+//
+//	fgalint models/saas/model.fga
+//	fgalint -public document#viewer,repo#reader -disable public-relation models/*/model.fga
+//	fgalint -format sarif models/*/model.fga > fgalint.sarif
+//
+// It exits with status 1 when a warning or error is reported.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/bogdanticu88/openfga-examples/lint"
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+func main() {
+	public := flag.String("public", "", "comma-separated type#relation pairs that are meant to be public")
+	disable := flag.String("disable", "", "comma-separated rule IDs to skip")
+	rules := flag.Bool("rules", false, "list the rules and exit")
+	format := flag.String("format", "text", "output format: text or sarif")
+	flag.Parse()
+
+	if *rules {
+		for _, r := range lint.Rules {
+			fmt.Printf("%-20s %s\n", r.ID, r.Description)
+		}
+		return
+	}
+	cfg := lint.Config{Public: split(*public), Disable: split(*disable)}
+	var findings []lint.Finding
+	for _, path := range flag.Args() {
+		m, err := model.ParseFile(path)
+		if err != nil {
+			log.Fatalf("fgalint: %v", err)
+		}
+		findings = append(findings, lint.Lint(m, cfg)...)
+	}
+	if *format == "sarif" {
+		if err := lint.SARIF(findings).Write(os.Stdout); err != nil {
+			log.Fatalf("fgalint: %v", err)
+		}
+	} else {
+		for _, f := range findings {
+			fmt.Fprintln(os.Stderr, f)
+		}
+	}
+	if lint.Max(findings) >= lint.Warning {
+		os.Exit(1)
+	}
+}
+
+func split(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
@@ -0,0 +1,634 @@
+// Command fgamodel is a toolbox for working with authorization models.
+//
+//	fgamodel repl -model models/saas/model.fga -tuples relations.txt
+//	fgamodel repl -api-url http://localhost:8080 -store-id 01H...
+//	fgamodel dash -api-url http://localhost:8080 -store-id 01H...
+//	fgamodel playground -addr localhost:3001 -model models/saas/model.fga
+//	fgamodel scenario models/saas/*.scenario
+//	fgamodel diff -tuples relations.txt old.fga new.fga
+//	fgamodel reach -model models/rbac/model.fga document#viewer
+//	fgamodel stats -api-url http://localhost:8080 -store-id 01H... -top 20
+//	fgamodel suggest -api-url http://localhost:8080 -store-id 01H... user:alice viewer document:roadmap
+//	fgamodel build -o model.fga models/tracker/fga.mod
+//	fgamodel usage -type document -grants -api-url http://localhost:8080 -store-id 01H... decisions.jsonl
+//	fgamodel init -o authz
+//	fgamodel infer -o authz examples.txt
+//
+// repl starts an interactive shell; type help for its commands. dash shows
+// the store dashboard as a terminal UI, refreshed every -interval, with a
+// prompt that runs checks. playground serves a web UI for editing the model,
+// writing tuples and running checks; without -model or -api-url it starts
+// from an empty model. Against a model file, or an fga.mod manifest of model
+// fragments, the commands run on the embedded evaluator, seeded from an
+// optional relations.txt with one object#relation@user per line. scenario
+// runs scenario scripts, see package scenario, and exits 1 if any fails.
+// diff evaluates checks under two model versions, see package modeldiff, and
+// exits 1 if any answer changes; the queries default to every user, relation
+// and object the tuples mention. reach lists the user types that could ever
+// obtain a relation and the paths that lead there, see package reach.
+// suggest lists the smallest grants that would give a user a relation,
+// narrowest first, and writes the one chosen with -apply, see package
+// suggest. stats prints tuple counts and growth, or Prometheus gauges with
+// -prometheus, see package stats. build assembles the fragments an fga.mod
+// lists into one model, see package modules, or expands the macros of a .fga
+// file, see package macro, and prints it or writes it to -o. usage reports
+// checks per object and relation, with a daily heatmap, from JSON decision
+// logs, and with -grants the stored grants nobody exercised, see package
+// decisions. init asks about resources, roles, hierarchy, group sharing and
+// public access and writes a model, seed tuples, a scenario testing them, a
+// store file for the OpenFGA CLI, a constants package and the answers as
+// scaffold.yaml, see package scaffold. infer proposes a model and tuples
+// from example decisions such as "alice can edit project api because she's
+// admin of organization acme", checks every example against them and, with
+// -o, writes model.fga and relations.txt; it is experimental, see package
+// infer.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/openfga/go-sdk/client"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/dashboard"
+	"github.com/bogdanticu88/openfga-examples/decisions"
+	"github.com/bogdanticu88/openfga-examples/eval"
+	"github.com/bogdanticu88/openfga-examples/infer"
+	"github.com/bogdanticu88/openfga-examples/model"
+	"github.com/bogdanticu88/openfga-examples/modeldiff"
+	"github.com/bogdanticu88/openfga-examples/modules"
+	"github.com/bogdanticu88/openfga-examples/playground"
+	"github.com/bogdanticu88/openfga-examples/reach"
+	"github.com/bogdanticu88/openfga-examples/repl"
+	"github.com/bogdanticu88/openfga-examples/scaffold"
+	"github.com/bogdanticu88/openfga-examples/scenario"
+	"github.com/bogdanticu88/openfga-examples/stats"
+	"github.com/bogdanticu88/openfga-examples/suggest"
+)
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "repl":
+		runREPL(os.Args[2:])
+	case "dash":
+		runDash(os.Args[2:])
+	case "playground":
+		runPlayground(os.Args[2:])
+	case "scenario":
+		runScenarios(os.Args[2:])
+	case "diff":
+		runDiff(os.Args[2:])
+	case "reach":
+		runReach(os.Args[2:])
+	case "stats":
+		runStats(os.Args[2:])
+	case "suggest":
+		runSuggest(os.Args[2:])
+	case "usage":
+		runUsage(os.Args[2:])
+	case "build":
+		runBuild(os.Args[2:])
+	case "init":
+		runInit(os.Args[2:])
+	case "infer":
+		runInfer(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: fgamodel repl|dash|playground|scenario|diff|reach|suggest|stats|usage|build|init|infer [flags]")
+	os.Exit(2)
+}
+
+// target holds the flags selecting what a subcommand runs against.
+type target struct {
+	model, tuples            string
+	apiURL, storeID, modelID string
+	readAPIURL               string
+}
+
+func targetFlags(fs *flag.FlagSet) *target {
+	t := &target{}
+	fs.StringVar(&t.model, "model", "", "model .fga file, or fga.mod manifest, to evaluate locally")
+	fs.StringVar(&t.tuples, "tuples", "", "relations.txt to seed the local store")
+	fs.StringVar(&t.apiURL, "api-url", "", "OpenFGA API URL, instead of -model")
+	fs.StringVar(&t.readAPIURL, "read-api-url", "", "OpenFGA API URL for reads, e.g. a replica or caching proxy; default -api-url")
+	fs.StringVar(&t.storeID, "store-id", "", "store ID on the server")
+	fs.StringVar(&t.modelID, "model-id", "", "authorization model ID on the server; default the latest")
+	return t
+}
+
+func runREPL(args []string) {
+	fs := flag.NewFlagSet("repl", flag.ExitOnError)
+	t := targetFlags(fs)
+	fs.Parse(args)
+
+	ctx := context.Background()
+	b, _, err := t.backend()
+	if err != nil {
+		log.Fatalf("fgamodel: %v", err)
+	}
+	s, err := repl.New(ctx, b, os.Stdout)
+	if err != nil {
+		log.Fatalf("fgamodel: %v", err)
+	}
+
+	lines := newLineReader(s.Complete)
+	defer lines.Close()
+	for {
+		line, err := lines.ReadLine("> ", s.History())
+		if err != nil {
+			return
+		}
+		if err := s.Exec(ctx, line); errors.Is(err, repl.ErrQuit) {
+			return
+		} else if err != nil {
+			fmt.Fprintln(os.Stdout, "error:", err)
+		}
+	}
+}
+
+func runDash(args []string) {
+	fs := flag.NewFlagSet("dash", flag.ExitOnError)
+	t := targetFlags(fs)
+	recent := fs.Int("recent", dashboard.DefaultRecent, "number of recent changes shown")
+	interval := fs.Duration("interval", dashboard.DefaultInterval, "time between refreshes")
+	fs.Parse(args)
+
+	ctx := context.Background()
+	b, fga, err := t.backend()
+	if err != nil {
+		log.Fatalf("fgamodel: %v", err)
+	}
+	d := &dashboard.Dashboard{Backend: b, Recent: *recent}
+	if fga != nil {
+		d.Inventory = dashboard.SDKInventory{Client: fga}
+	}
+	ui := dashboard.NewUI(ctx, d)
+	ui.Interval = *interval
+	if _, err := tea.NewProgram(ui, tea.WithAltScreen(), tea.WithContext(ctx)).Run(); err != nil {
+		log.Fatalf("fgamodel: %v", err)
+	}
+}
+
+func runScenarios(args []string) {
+	fs := flag.NewFlagSet("scenario", flag.ExitOnError)
+	quiet := fs.Bool("q", false, "print only failures")
+	fs.Parse(args)
+
+	failed := false
+	for _, path := range fs.Args() {
+		s, err := scenario.ParseFile(path)
+		if err != nil {
+			log.Fatalf("fgamodel: %v", err)
+		}
+		rep, err := scenario.Run(context.Background(), s)
+		if err != nil && !errors.Is(err, scenario.ErrFailed) {
+			log.Fatalf("fgamodel: %v", err)
+		}
+		if rep.Failed > 0 {
+			failed = true
+		}
+		if !*quiet || rep.Failed > 0 {
+			rep.WriteText(os.Stdout)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func runDiff(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	tuplesPath := fs.String("tuples", "", "relations.txt holding the tuples to evaluate against")
+	queriesPath := fs.String("queries", "", "file of checks, one object#relation@user per line")
+	fs.Parse(args)
+	if fs.NArg() != 2 || *tuplesPath == "" {
+		fmt.Fprintln(os.Stderr, "usage: fgamodel diff -tuples relations.txt [-queries file] old.fga new.fga")
+		os.Exit(2)
+	}
+	a, err := modules.ParseFile(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("fgamodel: %v", err)
+	}
+	b, err := modules.ParseFile(fs.Arg(1))
+	if err != nil {
+		log.Fatalf("fgamodel: %v", err)
+	}
+	tuples, err := readTuples(*tuplesPath)
+	if err != nil {
+		log.Fatalf("fgamodel: %v", err)
+	}
+	queries := modeldiff.Queries(a, b, tuples)
+	if *queriesPath != "" {
+		ts, err := readTuples(*queriesPath)
+		if err != nil {
+			log.Fatalf("fgamodel: %v", err)
+		}
+		queries = queries[:0]
+		for _, t := range ts {
+			queries = append(queries, authz.CheckRequest{User: t.User, Relation: t.Relation, Object: t.Object})
+		}
+	}
+	rep, err := modeldiff.Behavioral(context.Background(), a, b, tuples, queries)
+	if err != nil {
+		log.Fatalf("fgamodel: %v", err)
+	}
+	rep.WriteText(os.Stdout)
+	if len(rep.Changes) > 0 {
+		os.Exit(1)
+	}
+}
+
+func runReach(args []string) {
+	fs := flag.NewFlagSet("reach", flag.ExitOnError)
+	modelPath := fs.String("model", "", "model .fga file")
+	maxPaths := fs.Int("max-paths", reach.DefaultMaxPaths, "maximum number of paths per relation")
+	usersets := fs.Bool("usersets", false, "report userset assignments as grantees of their own")
+	fs.Parse(args)
+	if *modelPath == "" || fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: fgamodel reach -model model.fga type#relation...")
+		os.Exit(2)
+	}
+	m, err := modules.ParseFile(*modelPath)
+	if err != nil {
+		log.Fatalf("fgamodel: %v", err)
+	}
+	for _, arg := range fs.Args() {
+		typ, rel, ok := strings.Cut(arg, "#")
+		if !ok {
+			log.Fatalf("fgamodel: %q is not type#relation", arg)
+		}
+		res, err := reach.Who(m, typ, rel, reach.Options{MaxPaths: *maxPaths, KeepUsersets: *usersets})
+		if err != nil {
+			log.Fatalf("fgamodel: %v", err)
+		}
+		res.WriteText(os.Stdout)
+	}
+}
+
+func runSuggest(args []string) {
+	fs := flag.NewFlagSet("suggest", flag.ExitOnError)
+	t := targetFlags(fs)
+	apply := fs.Int("apply", 0, "write the suggestion with this number")
+	fs.Parse(args)
+	if fs.NArg() != 3 {
+		fmt.Fprintln(os.Stderr, "usage: fgamodel suggest [-apply n] <user> <relation> <object>")
+		os.Exit(2)
+	}
+	b, _, err := t.backend()
+	if err != nil {
+		log.Fatalf("fgamodel: %v", err)
+	}
+	ctx := context.Background()
+	e := &suggest.Engine{Backend: b}
+	ss, err := e.Suggest(ctx, fs.Arg(0), fs.Arg(1), fs.Arg(2))
+	if err != nil {
+		log.Fatalf("fgamodel: %v", err)
+	}
+	if len(ss) == 0 {
+		fmt.Printf("%s already has %s on %s, or no grant can give it\n", fs.Arg(0), fs.Arg(1), fs.Arg(2))
+		return
+	}
+	for i, s := range ss {
+		fmt.Printf("%d. [%s] %s\n", i+1, s.Scope, s)
+		for _, t := range s.Tuples {
+			fmt.Printf("     %s\n", t)
+		}
+	}
+	if *apply == 0 {
+		return
+	}
+	if *apply < 1 || *apply > len(ss) {
+		log.Fatalf("fgamodel: no suggestion %d", *apply)
+	}
+	if err := e.Apply(ctx, ss[*apply-1]); err != nil {
+		log.Fatalf("fgamodel: %v", err)
+	}
+	fmt.Printf("applied %d\n", *apply)
+}
+
+func runStats(args []string) {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	t := targetFlags(fs)
+	top := fs.Int("top", stats.DefaultTop, "number of largest objects to list")
+	bucket := fs.Duration("bucket", stats.DefaultBucket, "length of each growth period")
+	window := fs.Duration("window", stats.DefaultWindow, "how far back to report growth")
+	prom := fs.Bool("prometheus", false, "print Prometheus gauges instead of tables")
+	fs.Parse(args)
+	b, _, err := t.backend()
+	if err != nil {
+		log.Fatalf("fgamodel: %v", err)
+	}
+	s, err := stats.Collect(context.Background(), b, stats.Options{Top: *top, Bucket: *bucket, Window: *window})
+	if err != nil {
+		log.Fatalf("fgamodel: %v", err)
+	}
+	if *prom {
+		err = s.WritePrometheus(os.Stdout)
+	} else {
+		err = s.WriteText(os.Stdout)
+	}
+	if err != nil {
+		log.Fatalf("fgamodel: %v", err)
+	}
+}
+
+func runUsage(args []string) {
+	fs := flag.NewFlagSet("usage", flag.ExitOnError)
+	t := targetFlags(fs)
+	objectType := fs.String("type", "", "only report objects of this type")
+	top := fs.Int("top", 20, "number of most checked objects to list; 0 lists all")
+	since := fs.Duration("since", 0, "only count decisions this recent; default all")
+	grants := fs.Bool("grants", false, "also list the target's grants by checks, unexercised first")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: fgamodel usage [flags] <decisions.jsonl>...")
+		os.Exit(2)
+	}
+	agg := &decisions.Aggregator{}
+	if *since > 0 {
+		agg.Since = time.Now().Add(-*since)
+	}
+	for _, path := range fs.Args() {
+		f, err := os.Open(path)
+		if err != nil {
+			log.Fatalf("fgamodel: %v", err)
+		}
+		_, err = agg.ReadLog(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("fgamodel: %s: %v", path, err)
+		}
+	}
+	r := agg.Report(decisions.Options{ObjectType: *objectType, Top: *top})
+	var err error
+	if *asJSON {
+		err = json.NewEncoder(os.Stdout).Encode(r)
+	} else {
+		err = r.WriteText(os.Stdout)
+	}
+	if err != nil {
+		log.Fatalf("fgamodel: %v", err)
+	}
+	if !*grants {
+		return
+	}
+	b, _, err := t.backend()
+	if err != nil {
+		log.Fatalf("fgamodel: %v", err)
+	}
+	gs, err := agg.Grants(context.Background(), b, *objectType)
+	if err != nil {
+		log.Fatalf("fgamodel: %v", err)
+	}
+	fmt.Println()
+	if err := decisions.WriteGrantsText(os.Stdout, gs); err != nil {
+		log.Fatalf("fgamodel: %v", err)
+	}
+}
+
+func runBuild(args []string) {
+	fs := flag.NewFlagSet("build", flag.ExitOnError)
+	out := fs.String("o", "", "write the model to this file instead of stdout")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: fgamodel build [-o model.fga] fga.mod|model.fga")
+		os.Exit(2)
+	}
+	m, err := modules.Load(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("fgamodel: %v", err)
+	}
+	if *out == "" {
+		fmt.Print(m)
+		return
+	}
+	if err := os.WriteFile(*out, []byte(m.String()), 0o644); err != nil {
+		log.Fatalf("fgamodel: %v", err)
+	}
+}
+
+func runInit(args []string) {
+	fs := flag.NewFlagSet("init", flag.ExitOnError)
+	out := fs.String("o", ".", "directory to write the files to")
+	force := fs.Bool("f", false, "overwrite existing files")
+	fs.Parse(args)
+
+	lines := newLineReader(nil)
+	defer lines.Close()
+	prompt := scaffold.PrompterFunc(func(question, def string) (string, error) {
+		return lines.ReadLine(fmt.Sprintf("%s [%s]: ", question, def), nil)
+	})
+	spec, err := scaffold.Interview(prompt, os.Stdout)
+	if err != nil {
+		log.Fatalf("fgamodel: %v", err)
+	}
+	res, err := scaffold.Generate(spec)
+	if err != nil {
+		log.Fatalf("fgamodel: %v", err)
+	}
+	files := res.Files()
+	files["scaffold.yaml"] = spec.Marshal()
+	if !*force {
+		for name := range files {
+			if _, err := os.Stat(filepath.Join(*out, name)); err == nil {
+				log.Fatalf("fgamodel: %s already exists; use -f to overwrite", filepath.Join(*out, name))
+			}
+		}
+	}
+	for name, data := range files {
+		path := filepath.Join(*out, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			log.Fatalf("fgamodel: %v", err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			log.Fatalf("fgamodel: %v", err)
+		}
+	}
+	fmt.Printf("\nWrote %s. Next:\n", *out)
+	fmt.Printf("  fgamodel scenario %s\n", filepath.Join(*out, "model.scenario"))
+	fmt.Printf("  fgamodel repl -model %s -tuples %s\n", filepath.Join(*out, "model.fga"), filepath.Join(*out, "relations.txt"))
+	fmt.Printf("  fga store import --file %s\n", filepath.Join(*out, "store.fga.yaml"))
+}
+
+func runInfer(args []string) {
+	fs := flag.NewFlagSet("infer", flag.ExitOnError)
+	out := fs.String("o", "", "write model.fga and relations.txt to this directory")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: fgamodel infer [-o dir] examples.txt")
+		os.Exit(2)
+	}
+	examples, err := infer.ReadExamples(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("fgamodel: %v", err)
+	}
+	p, err := infer.Propose(context.Background(), examples)
+	if err != nil {
+		log.Fatalf("fgamodel: %v", err)
+	}
+	if err := p.WriteText(os.Stdout); err != nil {
+		log.Fatalf("fgamodel: %v", err)
+	}
+	if *out != "" {
+		var tuples strings.Builder
+		tuples.WriteString("# Tuples inferred from " + filepath.Base(fs.Arg(0)) + "\n# Format: object#relation@user\n\n")
+		for _, t := range p.Tuples {
+			tuples.WriteString(t.String() + "\n")
+		}
+		if err := os.MkdirAll(*out, 0o755); err != nil {
+			log.Fatalf("fgamodel: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(*out, "model.fga"), []byte(p.Model.String()), 0o644); err != nil {
+			log.Fatalf("fgamodel: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(*out, "relations.txt"), []byte(tuples.String()), 0o644); err != nil {
+			log.Fatalf("fgamodel: %v", err)
+		}
+	}
+	if failed := p.Failed(); len(failed) > 0 {
+		log.Fatalf("fgamodel: %d of %d examples do not hold", len(failed), len(p.Outcomes))
+	}
+}
+
+// backend returns the backend t selects and, for a server, the client.
+func (t *target) backend() (authz.Backend, *client.OpenFgaClient, error) {
+	if t.apiURL != "" {
+		fga, err := client.NewSdkClient(&client.ClientConfiguration{
+			ApiUrl:               t.apiURL,
+			StoreId:              t.storeID,
+			AuthorizationModelId: t.modelID,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		if t.readAPIURL == "" {
+			return authz.FromSDK(fga), fga, nil
+		}
+		reads, err := client.NewSdkClient(&client.ClientConfiguration{
+			ApiUrl:               t.readAPIURL,
+			StoreId:              t.storeID,
+			AuthorizationModelId: t.modelID,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		return authz.Split(authz.FromSDK(reads), authz.FromSDK(fga)), fga, nil
+	}
+	m, tuples, err := t.local()
+	if err != nil {
+		return nil, nil, err
+	}
+	return eval.New(m, eval.NewTupleStore(tuples...)), nil, nil
+}
+
+// local reads the model and seed tuples for the embedded evaluator.
+func (t *target) local() (*model.Model, []authz.Tuple, error) {
+	if t.model == "" {
+		return nil, nil, errors.New("one of -model or -api-url is required")
+	}
+	m, err := modules.ParseFile(t.model)
+	if err != nil {
+		return nil, nil, err
+	}
+	if t.tuples == "" {
+		return m, nil, nil
+	}
+	tuples, err := readTuples(t.tuples)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, tuple := range tuples {
+		if err := m.ValidateTuple(tuple.User, tuple.Relation, tuple.Object, tuple.Condition.Name); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", t.tuples, err)
+		}
+	}
+	return m, tuples, nil
+}
+
+// readTuples reads a file of object#relation@user lines, skipping blank
+// lines and comments.
+func readTuples(path string) ([]authz.Tuple, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var tuples []authz.Tuple
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		t, err := authz.ParseTuple(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		tuples = append(tuples, t)
+	}
+	return tuples, sc.Err()
+}
+
+func runPlayground(args []string) {
+	fs := flag.NewFlagSet("playground", flag.ExitOnError)
+	t := targetFlags(fs)
+	addr := fs.String("addr", "localhost:3001", "listen address")
+	fs.Parse(args)
+
+	var cfg playground.Config
+	var err error
+	if t.apiURL != "" {
+		cfg.Backend, _, err = t.backend()
+	} else if t.model != "" {
+		cfg.Model, cfg.Tuples, err = t.local()
+	}
+	if err != nil {
+		log.Fatalf("fgamodel: %v", err)
+	}
+	log.Printf("playground on http://%s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, playground.New(cfg)))
+}
+
+// lineReader reads command lines. On a Linux terminal it edits lines in raw
+// mode with history and, given a completion function, tab completion;
+// elsewhere it reads plain lines.
+type lineReader interface {
+	ReadLine(prompt string, history []string) (string, error)
+	Close() error
+}
+
+type plainReader struct{ sc *bufio.Scanner }
+
+func (r *plainReader) ReadLine(prompt string, _ []string) (string, error) {
+	fmt.Print(prompt)
+	if !r.sc.Scan() {
+		if err := r.sc.Err(); err != nil {
+			return "", err
+		}
+		return "", errors.New("EOF")
+	}
+	return r.sc.Text(), nil
+}
+
+func (r *plainReader) Close() error { return nil }
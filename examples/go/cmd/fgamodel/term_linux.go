@@ -0,0 +1,135 @@
+//go:build linux
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+func newLineReader(complete func(string) []string) lineReader {
+	fd := int(os.Stdin.Fd())
+	var saved syscall.Termios
+	if ioctl(fd, syscall.TCGETS, &saved) != nil {
+		return &plainReader{sc: bufio.NewScanner(os.Stdin)}
+	}
+	raw := saved
+	raw.Lflag &^= syscall.ICANON | syscall.ECHO
+	raw.Cc[syscall.VMIN], raw.Cc[syscall.VTIME] = 1, 0
+	if ioctl(fd, syscall.TCSETS, &raw) != nil {
+		return &plainReader{sc: bufio.NewScanner(os.Stdin)}
+	}
+	return &rawReader{fd: fd, saved: saved, in: bufio.NewReader(os.Stdin), complete: complete}
+}
+
+func ioctl(fd int, req uintptr, t *syscall.Termios) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), req, uintptr(unsafe.Pointer(t))); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+type rawReader struct {
+	fd       int
+	saved    syscall.Termios
+	in       *bufio.Reader
+	complete func(string) []string
+}
+
+func (r *rawReader) Close() error {
+	return ioctl(r.fd, syscall.TCSETS, &r.saved)
+}
+
+// ReadLine supports printable input, backspace, tab completion, up/down
+// through history, Ctrl-C to clear the line and Ctrl-D on an empty line to
+// quit.
+func (r *rawReader) ReadLine(prompt string, history []string) (string, error) {
+	var line []rune
+	hist := len(history)
+	redraw := func() { fmt.Printf("\r\x1b[K%s%s", prompt, string(line)) }
+	redraw()
+	for {
+		c, _, err := r.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		switch c {
+		case '\r', '\n':
+			fmt.Print("\r\n")
+			return string(line), nil
+		case 4: // Ctrl-D
+			if len(line) == 0 {
+				fmt.Print("\r\n")
+				return "", errors.New("EOF")
+			}
+		case 3: // Ctrl-C
+			line = line[:0]
+			fmt.Print("^C\r\n")
+			redraw()
+		case 127, 8:
+			if len(line) > 0 {
+				line = line[:len(line)-1]
+				redraw()
+			}
+		case '\t':
+			if r.complete != nil {
+				line = r.tab(line)
+				redraw()
+			}
+		case 0x1b:
+			if b, _ := r.in.ReadByte(); b != '[' {
+				continue
+			}
+			switch b, _ := r.in.ReadByte(); b {
+			case 'A':
+				if hist > 0 {
+					hist--
+					line = []rune(history[hist])
+				}
+			case 'B':
+				if hist < len(history)-1 {
+					hist++
+					line = []rune(history[hist])
+				} else {
+					hist, line = len(history), line[:0]
+				}
+			}
+			redraw()
+		default:
+			if c >= ' ' {
+				line = append(line, c)
+				fmt.Print(string(c))
+			}
+		}
+	}
+}
+
+// tab completes the last word to the candidates' common prefix, listing
+// them when there is more than one.
+func (r *rawReader) tab(line []rune) []rune {
+	s := string(line)
+	cands := r.complete(s)
+	if len(cands) == 0 {
+		return line
+	}
+	start := strings.LastIndexAny(s, " \t") + 1
+	common := cands[0]
+	for _, c := range cands[1:] {
+		for !strings.HasPrefix(c, common) {
+			common = common[:len(common)-1]
+		}
+	}
+	if len(cands) > 1 {
+		fmt.Printf("\r\n%s\r\n", strings.Join(cands, "  "))
+	}
+	s = s[:start] + common
+	if len(cands) == 1 && !strings.HasSuffix(common, ":") {
+		s += " "
+	}
+	return []rune(s)
+}
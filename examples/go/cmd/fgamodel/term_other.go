@@ -0,0 +1,12 @@
+//go:build !linux
+
+package main
+
+import (
+	"bufio"
+	"os"
+)
+
+func newLineReader(func(string) []string) lineReader {
+	return &plainReader{sc: bufio.NewScanner(os.Stdin)}
+}
@@ -0,0 +1,57 @@
+// Command modelconv converts authorization models between the DSL and API
+// JSON, reporting anything the conversion loses.
+//
+//	modelconv dsl2json models/saas/model.fga > model.json
+//	modelconv json2dsl model.json > model.fga
+//	modelconv -strict json2dsl model.json     # exit 1 if anything is lost
+//
+// Losses are printed to stderr.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/bogdanticu88/openfga-examples/modelconv"
+)
+
+func main() {
+	strict := flag.Bool("strict", false, "exit with status 1 when the conversion loses anything")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: modelconv [-strict] dsl2json|json2dsl file")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	cmd, path := flag.Arg(0), flag.Arg(1)
+	src, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatalf("modelconv: %v", err)
+	}
+	var out []byte
+	var losses []modelconv.Loss
+	switch cmd {
+	case "dsl2json":
+		out, losses, err = modelconv.ConvertDSLToJSON(path, src)
+	case "json2dsl":
+		out, losses, err = modelconv.ConvertJSONToDSL(src)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatalf("modelconv: %v", err)
+	}
+	os.Stdout.Write(out)
+	for _, l := range losses {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", path, l)
+	}
+	if *strict && len(losses) > 0 {
+		os.Exit(1)
+	}
+}
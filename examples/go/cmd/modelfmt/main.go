@@ -0,0 +1,62 @@
+// Command modelfmt formats .fga model files, and API JSON models, in
+// canonical style.
+//
+//	modelfmt models/saas/model.fga        # print the formatted file
+//	modelfmt -w models/*/model.fga        # rewrite files in place
+//	modelfmt -check models/*/model.fga    # list unformatted files, exit 1 if any
+//
+// Files ending in .json are treated as API JSON models.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/bogdanticu88/openfga-examples/modelfmt"
+)
+
+func main() {
+	write := flag.Bool("w", false, "write the result back to the file")
+	check := flag.Bool("check", false, "list files whose formatting differs and exit 1 if any")
+	keepOrder := flag.Bool("keep-order", false, "normalize spacing only; keep types and relations in source order")
+	flag.Parse()
+
+	unformatted := false
+	for _, path := range flag.Args() {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatalf("modelfmt: %v", err)
+		}
+		var out []byte
+		if strings.HasSuffix(path, ".json") {
+			out, err = modelfmt.JSON(src)
+		} else {
+			out, err = modelfmt.Format(path, src, modelfmt.Options{KeepOrder: *keepOrder})
+		}
+		if err != nil {
+			log.Fatalf("modelfmt: %v", err)
+		}
+		switch {
+		case *check:
+			if !bytes.Equal(src, out) {
+				fmt.Println(path)
+				unformatted = true
+			}
+		case *write:
+			if !bytes.Equal(src, out) {
+				if err := os.WriteFile(path, out, 0o644); err != nil {
+					log.Fatalf("modelfmt: %v", err)
+				}
+			}
+		default:
+			os.Stdout.Write(out)
+		}
+	}
+	if unformatted {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,54 @@
+// Command modelschema generates a protobuf file, a JSON Schema or a Go
+// constants package describing the object types and relations of a model.
+//
+//	modelschema -format proto -package acme.authz.v1 models/saas/model.fga > authz.proto
+//	modelschema -format jsonschema models/saas/model.fga > tuple.schema.json
+//	modelschema -format go -package authzmodel models/saas/model.fga > authzmodel/authzmodel.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/bogdanticu88/openfga-examples/model"
+	"github.com/bogdanticu88/openfga-examples/modelschema"
+)
+
+func main() {
+	format := flag.String("format", "proto", "output format: proto, jsonschema or go")
+	pkg := flag.String("package", "", "protobuf package, default authz.v1, or Go package, default authzmodel")
+	id := flag.String("id", "", "JSON Schema $id")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: modelschema [flags] model.fga")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+	m, err := model.ParseFile(flag.Arg(0))
+	if err != nil {
+		log.Fatalf("modelschema: %v", err)
+	}
+	var out []byte
+	switch *format {
+	case "proto":
+		if *pkg == "" {
+			*pkg = "authz.v1"
+		}
+		out, err = modelschema.Protobuf(m, *pkg)
+	case "go":
+		if *pkg == "" {
+			*pkg = "authzmodel"
+		}
+		out, err = modelschema.Go(m, *pkg)
+	case "jsonschema":
+		out, err = modelschema.JSONSchema(m, *id)
+	default:
+		log.Fatalf("modelschema: unknown format %q", *format)
+	}
+	if err != nil {
+		log.Fatalf("modelschema: %v", err)
+	}
+	os.Stdout.Write(out)
+}
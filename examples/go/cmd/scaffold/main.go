@@ -0,0 +1,59 @@
+// Command scaffold generates an authorization model, seed tuples for a demo
+// organization, a scenario checking them, a store file for the OpenFGA CLI
+// and a Go constants package from a description of resources and their
+// roles; see package scaffold for the file format. fgamodel init writes the
+// description by asking questions.
+//
+//	scaffold -o authz resources.yaml     # writes authz/model.fga, authz/relations.txt, ...
+//	scaffold resources.yaml              # prints the model
+//
+// The result runs as is on the embedded evaluator:
+//
+//	fgamodel scenario authz/model.scenario
+//	fgamodel repl -model authz/model.fga -tuples authz/relations.txt
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/bogdanticu88/openfga-examples/scaffold"
+)
+
+func main() {
+	out := flag.String("o", "", "write the files to this directory instead of printing the model")
+	force := flag.Bool("f", false, "overwrite existing files")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: scaffold [-o dir] [-f] resources.yaml")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+	spec, err := scaffold.ReadSpec(flag.Arg(0))
+	if err != nil {
+		log.Fatalf("scaffold: %v", err)
+	}
+	res, err := scaffold.Generate(spec)
+	if err != nil {
+		log.Fatalf("scaffold: %v", err)
+	}
+	if *out == "" {
+		fmt.Print(res.Model)
+		return
+	}
+	if err := res.WriteDir(*out, *force); err != nil {
+		log.Fatalf("scaffold: %v", err)
+	}
+	names := make([]string, 0, len(res.Files()))
+	for name := range res.Files() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Println(filepath.Join(*out, name))
+	}
+}
@@ -0,0 +1,173 @@
+// Package compaction finds tuples a store can do without: direct grants
+// that group membership, a stronger relation or parent inheritance already
+// imply. Deleting them shrinks the store and makes access reviews honest,
+// since a revoked group membership then actually revokes access.
+//
+//	plan, err := compaction.Analyze(ctx, backend, compaction.Options{})
+//	for _, t := range plan.Redundant {
+//		fmt.Println(t)
+//	}
+//	err = plan.Apply(ctx, backend, az)
+//
+// A tuple is redundant when its user still holds its relation on its
+// object once it is gone. Candidates are removed one at a time on the
+// embedded evaluator, each judged with the earlier removals applied, so two
+// grants that only back each other up are never both listed. Tuples on
+// tupleset relations (parent links) are never candidates: they carry
+// inheritance for every object below, not access for one user.
+package compaction
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/eval"
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// ErrUnsafe is returned by Verify and Apply when a check the plan relies
+// on does not hold.
+var ErrUnsafe = errors.New("compaction: plan is not safe")
+
+// Options tunes Analyze.
+type Options struct {
+	// Model defaults to the store's active model.
+	Model *model.Model
+	// ObjectType limits candidates to objects of one type; empty means all.
+	ObjectType string
+	// Keep exempts tuples from compaction, e.g. grants an audit requires to
+	// stay explicit.
+	Keep func(authz.Tuple) bool
+}
+
+// Plan lists the tuples safe to delete.
+type Plan struct {
+	Redundant []authz.Tuple
+	// Examined is the number of candidates evaluated.
+	Examined int
+}
+
+// Analyze reads every tuple from b and returns the redundant ones, in
+// SortTuples order. Grants to individual users are considered first, then
+// grants to usersets, then memberships (relations other types reference as
+// usersets, such as group#member), so compaction keeps the structure and
+// drops the one-off grants.
+func Analyze(ctx context.Context, b authz.Backend, opts Options) (*Plan, error) {
+	m := opts.Model
+	if m == nil {
+		var err error
+		if m, err = b.ReadModel(ctx, ""); err != nil {
+			return nil, fmt.Errorf("compaction: read model: %w", err)
+		}
+	}
+	all, err := authz.ReadAll(ctx, b, authz.Tuple{})
+	if err != nil {
+		return nil, fmt.Errorf("compaction: read: %w", err)
+	}
+	tuplesets, members := structural(m)
+	var direct, usersets, memberships []authz.Tuple
+	for _, t := range all {
+		typ, _, _ := strings.Cut(t.Object, ":")
+		switch {
+		case opts.ObjectType != "" && typ != opts.ObjectType,
+			tuplesets[typ+"#"+t.Relation],
+			strings.HasSuffix(t.User, ":*"),
+			opts.Keep != nil && opts.Keep(t):
+		case members[typ+"#"+t.Relation]:
+			memberships = append(memberships, t)
+		case strings.Contains(t.User, "#"):
+			usersets = append(usersets, t)
+		default:
+			direct = append(direct, t)
+		}
+	}
+	eval.SortTuples(direct)
+	eval.SortTuples(usersets)
+	eval.SortTuples(memberships)
+
+	store := eval.NewTupleStore(all...)
+	e := eval.New(m, store)
+	plan := &Plan{}
+	for _, t := range append(append(direct, usersets...), memberships...) {
+		plan.Examined++
+		if err := store.Write(nil, []authz.Tuple{t}); err != nil {
+			return nil, fmt.Errorf("compaction: %w", err)
+		}
+		still, err := e.Check(ctx, authz.CheckRequest{User: t.User, Relation: t.Relation, Object: t.Object})
+		if err == nil && still {
+			plan.Redundant = append(plan.Redundant, t)
+			continue
+		}
+		if err := store.Write([]authz.Tuple{t}, nil); err != nil {
+			return nil, fmt.Errorf("compaction: %w", err)
+		}
+	}
+	eval.SortTuples(plan.Redundant)
+	return plan, nil
+}
+
+// Verify checks, against b, that every user in the plan holds the
+// relation its redundant tuple grants. Run it before Apply to catch a
+// store that changed since Analyze, and after to confirm nothing was lost.
+func (p *Plan) Verify(ctx context.Context, b authz.Backend) error {
+	var failed []string
+	for _, t := range p.Redundant {
+		ok, err := b.Check(ctx, authz.CheckRequest{User: t.User, Relation: t.Relation, Object: t.Object})
+		if err != nil {
+			return fmt.Errorf("compaction: verify %s: %w", t, err)
+		}
+		if !ok {
+			failed = append(failed, t.String())
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%w: %d checks fail: %s", ErrUnsafe, len(failed), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// Apply verifies the plan, deletes its tuples through w and verifies
+// again. If access was lost the deleted tuples are written back and the
+// error wraps ErrUnsafe. w is typically the authz.Client, so write guards
+// see the deletes; checks go to b.
+func (p *Plan) Apply(ctx context.Context, b authz.Backend, w authz.TupleWriter) error {
+	if err := p.Verify(ctx, b); err != nil {
+		return err
+	}
+	if err := authz.WriteBatched(ctx, w, nil, p.Redundant).Err(); err != nil {
+		return fmt.Errorf("compaction: delete: %w", err)
+	}
+	if err := p.Verify(ctx, b); err != nil {
+		if rerr := authz.WriteBatched(ctx, w, p.Redundant, nil).Err(); rerr != nil {
+			return fmt.Errorf("%w; restoring the deleted tuples failed: %v", err, rerr)
+		}
+		return fmt.Errorf("%w; the deleted tuples were restored", err)
+	}
+	return nil
+}
+
+// structural returns the "type#relation" keys used as the tupleset of a
+// "from" rewrite and those referenced as usersets in type restrictions.
+func structural(m *model.Model) (tuplesets, members map[string]bool) {
+	tuplesets, members = map[string]bool{}, map[string]bool{}
+	for _, t := range m.Types {
+		for _, r := range t.Relations {
+			model.Walk(r.Rewrite, func(rw model.Rewrite) {
+				switch n := rw.(type) {
+				case *model.TupleToUserset:
+					tuplesets[t.Name+"#"+n.Tupleset] = true
+				case *model.Direct:
+					for _, ref := range n.Types {
+						if ref.Relation != "" {
+							members[ref.Type+"#"+ref.Relation] = true
+						}
+					}
+				}
+			})
+		}
+	}
+	return tuplesets, members
+}
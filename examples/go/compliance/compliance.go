@@ -0,0 +1,359 @@
+// Package compliance assembles audit evidence for SOC 2 and ISO 27001
+// reviews from the other packages' data: who holds each privileged
+// relation, how those grants changed during the period, and how far the
+// access review has got.
+//
+//	g := &compliance.Generator{
+//		Backend:    backend,
+//		Privileged: []compliance.Relation{{ObjectType: "organization", Relation: "admin"}},
+//		History:    history.Files(archived),
+//		Reviewer:   reviewer,
+//		Dir:        "/srv/evidence",
+//	}
+//	go g.Run(ctx, 30*24*time.Hour)
+//
+// Each package is a directory of the same report rendered as HTML, PDF and
+// one CSV file per table, named after the period's end.
+package compliance
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/history"
+	"github.com/bogdanticu88/openfga-examples/review"
+)
+
+// Relation names a privileged relation, e.g. organization#admin.
+type Relation struct {
+	ObjectType string
+	Relation   string
+}
+
+func (r Relation) String() string { return r.ObjectType + "#" + r.Relation }
+
+// Holder is a user holding a privileged relation on an object, directly or
+// through a group.
+type Holder struct {
+	Object   string
+	Relation string
+	User     string
+}
+
+// Report is one evidence package.
+type Report struct {
+	GeneratedAt time.Time
+	From, To    time.Time
+	Store       authz.Store
+	Privileged  []Relation
+	Holders     []Holder
+	// Changes are the grants and revocations of privileged relations
+	// between From and To, oldest first.
+	Changes []authz.Change
+	// Review is the access review at GeneratedAt, nil without a Reviewer.
+	Review *review.Report
+}
+
+// ReviewCompletion returns the fraction of reviewed grants that are up to
+// date, 1 when there is nothing to review, or -1 without a review.
+func (r *Report) ReviewCompletion() float64 {
+	if r.Review == nil {
+		return -1
+	}
+	if r.Review.Covered == 0 {
+		return 1
+	}
+	due := len(r.Review.Findings) + len(r.Review.Unknown)
+	return float64(r.Review.Covered-due) / float64(r.Review.Covered)
+}
+
+// Generator builds Reports.
+type Generator struct {
+	Backend    authz.Backend
+	Privileged []Relation
+	// UserTypes are the user types listed as holders; default "user".
+	UserTypes []string
+	// History supplies the period's changes; default the server's feed,
+	// which may have been pruned; see package archive.
+	History history.Source
+	// Reviewer, if set, supplies the access review status.
+	Reviewer *review.Reviewer
+	// Dir receives the packages written by Run.
+	Dir string
+	// Now defaults to time.Now.
+	Now func() time.Time
+	// Logger receives scheduling events; nil disables logging.
+	Logger *slog.Logger
+}
+
+// Generate builds the report for the period [from, to).
+func (g *Generator) Generate(ctx context.Context, from, to time.Time) (*Report, error) {
+	rep := &Report{GeneratedAt: g.now(), From: from, To: to, Privileged: g.Privileged}
+	store, err := g.Backend.GetStore(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("compliance: get store: %w", err)
+	}
+	rep.Store = store
+
+	all, err := authz.ReadType(ctx, g.Backend, "")
+	if err != nil {
+		return nil, fmt.Errorf("compliance: read tuples: %w", err)
+	}
+	userTypes := g.UserTypes
+	if len(userTypes) == 0 {
+		userTypes = []string{"user"}
+	}
+	for _, p := range g.Privileged {
+		objects := map[string]bool{}
+		for _, t := range all {
+			if strings.HasPrefix(t.Object, p.ObjectType+":") {
+				objects[t.Object] = true
+			}
+		}
+		for _, obj := range sortedKeys(objects) {
+			users, err := g.Backend.ListUsers(ctx, obj, p.Relation, userTypes)
+			if err != nil {
+				return nil, fmt.Errorf("compliance: list users %s#%s: %w", obj, p.Relation, err)
+			}
+			sort.Strings(users)
+			for _, u := range users {
+				rep.Holders = append(rep.Holders, Holder{Object: obj, Relation: p.Relation, User: u})
+			}
+		}
+	}
+
+	src := g.History
+	if src == nil {
+		src = history.Feed{Backend: g.Backend}
+	}
+	types := map[string]bool{}
+	for _, p := range g.Privileged {
+		types[p.ObjectType] = true
+	}
+	for _, typ := range sortedKeys(types) {
+		changes, err := src.Changes(ctx, typ)
+		if err != nil {
+			return nil, fmt.Errorf("compliance: changes: %w", err)
+		}
+		for _, c := range changes {
+			if c.Timestamp.Before(from) || !c.Timestamp.Before(to) || !g.privileged(c.Tuple) {
+				continue
+			}
+			rep.Changes = append(rep.Changes, c)
+		}
+	}
+	sort.SliceStable(rep.Changes, func(i, j int) bool { return rep.Changes[i].Timestamp.Before(rep.Changes[j].Timestamp) })
+
+	if g.Reviewer != nil {
+		rv, err := g.Reviewer.Review(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("compliance: %w", err)
+		}
+		rep.Review = &rv
+	}
+	return rep, nil
+}
+
+func (g *Generator) privileged(t authz.Tuple) bool {
+	for _, p := range g.Privileged {
+		if t.Relation == p.Relation && strings.HasPrefix(t.Object, p.ObjectType+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// Write renders r into dir as report.html, report.pdf, holders.csv,
+// changes.csv and, with a review, review.csv.
+func Write(dir string, r *Report) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("compliance: %w", err)
+	}
+	type file struct {
+		name  string
+		write func(io.Writer) error
+	}
+	files := []file{
+		{"report.html", r.WriteHTML},
+		{"report.pdf", r.WritePDF},
+		{"holders.csv", r.WriteHoldersCSV},
+		{"changes.csv", r.WriteChangesCSV},
+	}
+	if r.Review != nil {
+		files = append(files, file{"review.csv", r.WriteReviewCSV})
+	}
+	for _, f := range files {
+		out, err := os.Create(filepath.Join(dir, f.name))
+		if err != nil {
+			return fmt.Errorf("compliance: %w", err)
+		}
+		err = f.write(out)
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("compliance: %s: %w", f.name, err)
+		}
+	}
+	return nil
+}
+
+// Run writes a package for each period into Dir/<end date> until ctx is
+// done; the first covers the period before the call. Failures are logged
+// and the period is retried at the next tick.
+func (g *Generator) Run(ctx context.Context, period time.Duration) error {
+	t := time.NewTicker(period)
+	defer t.Stop()
+	from := g.now().Add(-period)
+	for {
+		to := g.now()
+		rep, err := g.Generate(ctx, from, to)
+		if err == nil {
+			err = Write(filepath.Join(g.Dir, to.UTC().Format("2006-01-02")), rep)
+		}
+		if err != nil {
+			g.logf(ctx, slog.LevelWarn, "compliance report failed", "error", err)
+		} else {
+			g.logf(ctx, slog.LevelInfo, "compliance report written", "holders", len(rep.Holders), "changes", len(rep.Changes))
+			from = to
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// WriteHoldersCSV writes object, relation, user rows.
+func (r *Report) WriteHoldersCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"object", "relation", "user"})
+	for _, h := range r.Holders {
+		cw.Write([]string{h.Object, h.Relation, h.User})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteChangesCSV writes time, operation, user, relation, object rows.
+func (r *Report) WriteChangesCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"time", "operation", "user", "relation", "object"})
+	for _, c := range r.Changes {
+		cw.Write([]string{c.Timestamp.UTC().Format(time.RFC3339), string(c.Operation), c.Tuple.User, c.Tuple.Relation, c.Tuple.Object})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteReviewCSV writes the grants awaiting review with their status.
+func (r *Report) WriteReviewCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"user", "relation", "object", "status", "granted_at", "granted_by"})
+	if r.Review != nil {
+		for _, f := range r.Review.Findings {
+			g := f.Grant
+			cw.Write([]string{g.Tuple.User, g.Tuple.Relation, g.Tuple.Object, "overdue", g.WrittenAt.UTC().Format(time.RFC3339), g.WrittenBy})
+		}
+		for _, t := range r.Review.Unknown {
+			cw.Write([]string{t.User, t.Relation, t.Object, "unknown", "", ""})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+var reportHTML = template.Must(template.New("report").Funcs(template.FuncMap{
+	"date":    func(t time.Time) string { return t.UTC().Format(time.RFC3339) },
+	"percent": func(f float64) string { return fmt.Sprintf("%.1f%%", 100*f) },
+}).Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Access evidence {{.Store.Name}} {{date .To}}</title>
+<style>body{font-family:sans-serif}table{border-collapse:collapse}td,th{border:1px solid #ccc;padding:2px 6px;text-align:left}</style>
+</head><body>
+<h1>Access evidence: {{.Store.Name}}</h1>
+<p>Store {{.Store.ID}}, period {{date .From}} to {{date .To}}, generated {{date .GeneratedAt}}.</p>
+<p>Privileged relations:{{range .Privileged}} {{.}}{{end}}</p>
+<h2>Privileged access ({{len .Holders}})</h2>
+<table><tr><th>Object</th><th>Relation</th><th>User</th></tr>
+{{range .Holders}}<tr><td>{{.Object}}</td><td>{{.Relation}}</td><td>{{.User}}</td></tr>
+{{end}}</table>
+<h2>Changes in the period ({{len .Changes}})</h2>
+<table><tr><th>Time</th><th>Operation</th><th>User</th><th>Relation</th><th>Object</th></tr>
+{{range .Changes}}<tr><td>{{date .Timestamp}}</td><td>{{.Operation}}</td><td>{{.Tuple.User}}</td><td>{{.Tuple.Relation}}</td><td>{{.Tuple.Object}}</td></tr>
+{{end}}</table>
+{{with .Review}}<h2>Access review</h2>
+<p>{{percent $.ReviewCompletion}} of {{.Covered}} grants reviewed; {{len .Findings}} overdue, {{len .Unknown}} of unknown age.</p>
+<table><tr><th>Grant</th><th>Status</th><th>Granted</th><th>By</th></tr>
+{{range .Findings}}<tr><td>{{.Grant.Tuple}}</td><td>overdue</td><td>{{date .Grant.WrittenAt}}</td><td>{{.Grant.WrittenBy}}</td></tr>
+{{end}}{{range .Unknown}}<tr><td>{{.}}</td><td>unknown</td><td></td><td></td></tr>
+{{end}}</table>{{end}}
+</body></html>
+`))
+
+// WriteHTML renders r as a standalone HTML page.
+func (r *Report) WriteHTML(w io.Writer) error {
+	return reportHTML.Execute(w, r)
+}
+
+// lines renders r as plain text lines, the content of the PDF.
+func (r *Report) lines() []string {
+	date := func(t time.Time) string { return t.UTC().Format(time.RFC3339) }
+	out := []string{
+		"Access evidence: " + r.Store.Name,
+		fmt.Sprintf("Store %s, period %s to %s, generated %s", r.Store.ID, date(r.From), date(r.To), date(r.GeneratedAt)),
+		"",
+		fmt.Sprintf("Privileged access (%d)", len(r.Holders)),
+	}
+	for _, h := range r.Holders {
+		out = append(out, fmt.Sprintf("  %s#%s  %s", h.Object, h.Relation, h.User))
+	}
+	out = append(out, "", fmt.Sprintf("Changes in the period (%d)", len(r.Changes)))
+	for _, c := range r.Changes {
+		out = append(out, fmt.Sprintf("  %s  %-6s %s", date(c.Timestamp), c.Operation, c.Tuple))
+	}
+	if r.Review != nil {
+		out = append(out, "", "Access review",
+			fmt.Sprintf("  %.1f%% of %d grants reviewed; %d overdue, %d of unknown age",
+				100*r.ReviewCompletion(), r.Review.Covered, len(r.Review.Findings), len(r.Review.Unknown)))
+		for _, f := range r.Review.Findings {
+			out = append(out, fmt.Sprintf("  overdue  %s granted %s %s", f.Grant.Tuple, date(f.Grant.WrittenAt), f.Grant.WrittenBy))
+		}
+		for _, t := range r.Review.Unknown {
+			out = append(out, "  unknown  "+t.String())
+		}
+	}
+	return out
+}
+
+func (g *Generator) now() time.Time {
+	if g.Now != nil {
+		return g.Now()
+	}
+	return time.Now()
+}
+
+func (g *Generator) logf(ctx context.Context, level slog.Level, msg string, args ...interface{}) {
+	if g.Logger != nil {
+		g.Logger.Log(ctx, level, msg, args...)
+	}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
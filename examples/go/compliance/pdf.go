@@ -0,0 +1,98 @@
+package compliance
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// pdfLinesPerPage and pdfLineWidth fit 10pt Courier, 6pt per character,
+// with 12pt leading on an A4 page with 40pt margins.
+const (
+	pdfLinesPerPage = 64
+	pdfLineWidth    = 85
+)
+
+// WritePDF renders r as a plain-text PDF in Courier, which every viewer
+// has built in, so no font needs embedding.
+func (r *Report) WritePDF(w io.Writer) error {
+	return writePDF(w, r.lines())
+}
+
+// writePDF lays lines out on A4 pages, wrapping long ones. Characters
+// outside Latin-1 print as '?'.
+func writePDF(w io.Writer, text []string) error {
+	var lines []string
+	for _, l := range text {
+		r := []rune(l)
+		for len(r) > pdfLineWidth {
+			lines = append(lines, string(r[:pdfLineWidth]))
+			r = append([]rune("    "), r[pdfLineWidth:]...)
+		}
+		lines = append(lines, string(r))
+	}
+	var pages [][]string
+	for len(lines) > 0 {
+		n := min(len(lines), pdfLinesPerPage)
+		pages = append(pages, lines[:n])
+		lines = lines[n:]
+	}
+	if len(pages) == 0 {
+		pages = [][]string{nil}
+	}
+
+	// Objects: 1 catalog, 2 page tree, 3 font, then a page and its content
+	// stream per page.
+	var buf bytes.Buffer
+	offsets := []int{0}
+	obj := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets)-1, body)
+	}
+	buf.WriteString("%PDF-1.4\n")
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	for i, page := range pages {
+		var content strings.Builder
+		content.WriteString("BT /F1 10 Tf 12 TL 40 802 Td\n")
+		for _, l := range page {
+			content.WriteString("(" + pdfString(l) + ") '\n")
+		}
+		content.WriteString("ET")
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", 5+2*i))
+		obj(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()))
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets))
+	for _, off := range offsets[1:] {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets), xref)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// pdfString escapes s for a PDF literal string.
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r > 0xff:
+			b.WriteByte('?')
+		case r > 0x7e:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
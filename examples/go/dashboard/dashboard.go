@@ -0,0 +1,310 @@
+// Package dashboard collects an operational view of a store — the stores on
+// the server, model versions, tuple counts per type and the latest changes
+// — and renders it for a terminal. UI shows it as a bubbletea terminal UI
+// that refreshes on a timer above a check playground; cmd/fgamodel dash
+// runs it.
+//
+// Counts are taken with one full read when the dashboard starts and kept
+// current from the changes feed afterwards, so refreshing a large store
+// stays cheap.
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openfga/go-sdk/client"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// DefaultRecent is the number of changes shown when Dashboard.Recent is
+// zero.
+const DefaultRecent = 10
+
+// ModelVersion is one authorization model written to the store.
+type ModelVersion struct {
+	ID    string
+	Types int
+}
+
+// Inventory lists what the Backend interface cannot: every store on the
+// server and every model version of the configured store, newest first.
+type Inventory interface {
+	ListStores(ctx context.Context) ([]authz.Store, error)
+	ListModels(ctx context.Context) ([]ModelVersion, error)
+}
+
+// Snapshot is one refresh of the dashboard. A panel whose data could not be
+// read is left empty and its error recorded in Errors.
+type Snapshot struct {
+	TakenAt time.Time
+	Store   authz.Store
+	Stores  []authz.Store
+	Models  []ModelVersion
+	Model   *model.Model
+	// Counts is the number of tuples per object type.
+	Counts map[string]int
+	Recent []authz.Change
+	Errors []string
+}
+
+// Tuples is the total of Counts.
+func (s *Snapshot) Tuples() int {
+	n := 0
+	for _, c := range s.Counts {
+		n += c
+	}
+	return n
+}
+
+// Dashboard collects snapshots of one store. It is not safe for concurrent
+// use.
+type Dashboard struct {
+	Backend authz.Backend
+	// Inventory fills the stores and model versions panels; nil hides them.
+	Inventory Inventory
+	// Recent is the number of changes kept; default DefaultRecent.
+	Recent int
+	// Now defaults to time.Now.
+	Now func() time.Time
+
+	started bool
+	token   string
+	counts  map[string]int
+	recent  []authz.Change
+}
+
+// Collect takes a snapshot.
+func (d *Dashboard) Collect(ctx context.Context) *Snapshot {
+	now := time.Now
+	if d.Now != nil {
+		now = d.Now
+	}
+	s := &Snapshot{TakenAt: now()}
+	fail := func(panel string, err error) { s.Errors = append(s.Errors, fmt.Sprintf("%s: %v", panel, err)) }
+
+	var err error
+	if s.Store, err = d.Backend.GetStore(ctx); err != nil {
+		fail("store", err)
+	}
+	if s.Model, err = d.Backend.ReadModel(ctx, ""); err != nil {
+		fail("model", err)
+	}
+	if d.Inventory != nil {
+		if s.Stores, err = d.Inventory.ListStores(ctx); err != nil {
+			fail("stores", err)
+		}
+		if s.Models, err = d.Inventory.ListModels(ctx); err != nil {
+			fail("models", err)
+		}
+	}
+	if err := d.refresh(ctx); err != nil {
+		fail("tuples", err)
+	}
+	s.Counts = make(map[string]int, len(d.counts))
+	for k, v := range d.counts {
+		s.Counts[k] = v
+	}
+	s.Recent = append([]authz.Change(nil), d.recent...)
+	return s
+}
+
+// refresh brings counts and recent changes up to date. The first call
+// finds the end of the feed before reading the store, so no change is
+// counted twice or missed except for writes racing the initial read.
+func (d *Dashboard) refresh(ctx context.Context) error {
+	keep := d.Recent
+	if keep == 0 {
+		keep = DefaultRecent
+	}
+	changes, token, err := authz.ReadAllChanges(ctx, d.Backend, "", d.token)
+	if err != nil {
+		return err
+	}
+	d.token = token
+	if !d.started {
+		all, err := authz.ReadAll(ctx, d.Backend, authz.Tuple{})
+		if err != nil {
+			return err
+		}
+		d.counts = map[string]int{}
+		for _, t := range all {
+			d.counts[objectType(t.Object)]++
+		}
+		d.started = true
+	} else {
+		for _, c := range changes {
+			if c.Operation == authz.OpDelete {
+				d.counts[objectType(c.Tuple.Object)]--
+			} else {
+				d.counts[objectType(c.Tuple.Object)]++
+			}
+		}
+	}
+	d.recent = append(d.recent, changes...)
+	if len(d.recent) > keep {
+		d.recent = d.recent[len(d.recent)-keep:]
+	}
+	return nil
+}
+
+func objectType(object string) string {
+	typ, _, _ := strings.Cut(object, ":")
+	return typ
+}
+
+// SDKInventory is an Inventory over the official client.
+type SDKInventory struct {
+	Client *client.OpenFgaClient
+}
+
+// ListStores implements Inventory.
+func (i SDKInventory) ListStores(ctx context.Context) ([]authz.Store, error) {
+	var stores []authz.Store
+	opts := client.ClientListStoresOptions{}
+	for {
+		resp, err := i.Client.ListStores(ctx).Options(opts).Execute()
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range resp.Stores {
+			stores = append(stores, authz.Store{ID: s.Id, Name: s.Name, CreatedAt: s.CreatedAt})
+		}
+		if resp.ContinuationToken == "" {
+			return stores, nil
+		}
+		token := resp.ContinuationToken
+		opts.ContinuationToken = &token
+	}
+}
+
+// ListModels implements Inventory.
+func (i SDKInventory) ListModels(ctx context.Context) ([]ModelVersion, error) {
+	var models []ModelVersion
+	opts := client.ClientReadAuthorizationModelsOptions{}
+	for {
+		resp, err := i.Client.ReadAuthorizationModels(ctx).Options(opts).Execute()
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range resp.AuthorizationModels {
+			models = append(models, ModelVersion{ID: m.Id, Types: len(m.TypeDefinitions)})
+		}
+		if resp.GetContinuationToken() == "" {
+			return models, nil
+		}
+		token := resp.GetContinuationToken()
+		opts.ContinuationToken = &token
+	}
+}
+
+const (
+	bold    = "\x1b[1m"
+	dim     = "\x1b[2m"
+	reverse = "\x1b[7m"
+	red     = "\x1b[31m"
+	green   = "\x1b[32m"
+	reset   = "\x1b[0m"
+)
+
+// Render writes s as ANSI text fitted to width columns.
+func Render(w io.Writer, s *Snapshot, width int) error {
+	var b strings.Builder
+	title := fmt.Sprintf(" %s (%s)  %d tuples  %s ", s.Store.Name, s.Store.ID, s.Tuples(), s.TakenAt.Format("15:04:05"))
+	fmt.Fprintf(&b, "%s%s%s%s\n", reverse, bold, pad(title, width), reset)
+
+	if s.Model != nil {
+		id := s.Model.ID
+		if id == "" {
+			id = "(embedded)"
+		}
+		section(&b, "Model")
+		fmt.Fprintf(&b, "  %s  schema %s  %d types  %d conditions\n", id, s.Model.SchemaVersion, len(s.Model.Types), len(s.Model.Conditions))
+	}
+	if len(s.Stores) > 0 {
+		section(&b, "Stores")
+		for _, st := range s.Stores {
+			marker := " "
+			if st.ID == s.Store.ID {
+				marker = "*"
+			}
+			fmt.Fprintf(&b, " %s %-26s %s  %s%s%s\n", marker, st.ID, st.Name, dim, st.CreatedAt.Format(time.DateOnly), reset)
+		}
+	}
+	if len(s.Models) > 0 {
+		section(&b, "Model versions")
+		for i, m := range s.Models {
+			marker := " "
+			if s.Model != nil && m.ID == s.Model.ID {
+				marker = "*"
+			}
+			if i == 5 {
+				fmt.Fprintf(&b, "   %s… %d older%s\n", dim, len(s.Models)-i, reset)
+				break
+			}
+			fmt.Fprintf(&b, " %s %s  %d types\n", marker, m.ID, m.Types)
+		}
+	}
+
+	section(&b, "Tuples by type")
+	types := make([]string, 0, len(s.Counts))
+	most := 0
+	for t, n := range s.Counts {
+		types = append(types, t)
+		most = max(most, n)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		return s.Counts[types[i]] > s.Counts[types[j]] || s.Counts[types[i]] == s.Counts[types[j]] && types[i] < types[j]
+	})
+	barWidth := max(width-36, 10)
+	for _, t := range types {
+		n := s.Counts[t]
+		bar := 0
+		if most > 0 {
+			bar = max(n*barWidth/most, 1)
+		}
+		fmt.Fprintf(&b, "  %-20s %8d %s\n", t, n, strings.Repeat("█", bar))
+	}
+
+	section(&b, "Recent changes")
+	if len(s.Recent) == 0 {
+		fmt.Fprintf(&b, "  %s(none)%s\n", dim, reset)
+	}
+	for i := len(s.Recent) - 1; i >= 0; i-- {
+		c := s.Recent[i]
+		color, sign := green, "+"
+		if c.Operation == authz.OpDelete {
+			color, sign = red, "-"
+		}
+		line := fmt.Sprintf("%s %s %s", c.Timestamp.Format("01-02 15:04:05"), sign, c.Tuple)
+		fmt.Fprintf(&b, "  %s%s%s\n", color, truncate(line, width-2), reset)
+	}
+	for _, e := range s.Errors {
+		fmt.Fprintf(&b, "%s! %s%s\n", red, truncate(e, width-2), reset)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func section(b *strings.Builder, name string) {
+	fmt.Fprintf(b, "\n%s%s%s\n", bold, name, reset)
+}
+
+func pad(s string, width int) string {
+	s = truncate(s, width)
+	return s + strings.Repeat(" ", max(width-len([]rune(s)), 0))
+}
+
+func truncate(s string, width int) string {
+	r := []rune(s)
+	if width > 1 && len(r) > width {
+		return string(r[:width-1]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,169 @@
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+)
+
+// DefaultInterval is the refresh interval used when UI.Interval is zero.
+const DefaultInterval = 5 * time.Second
+
+// UI is a bubbletea model showing the dashboard's panels above a check
+// playground. It refreshes every Interval, and on enter at an empty prompt;
+// a line of the form "<user> <relation> <object>" runs a check against the
+// dashboard's backend. Up and down recall earlier checks, esc quits.
+//
+//	ui := dashboard.NewUI(ctx, d)
+//	_, err := tea.NewProgram(ui, tea.WithAltScreen()).Run()
+type UI struct {
+	// Interval is the time between refreshes; default DefaultInterval.
+	Interval time.Duration
+
+	ctx        context.Context
+	dash       *Dashboard
+	width      int
+	snap       *Snapshot
+	collecting bool
+	input      []rune
+	history    []string
+	recall     int
+	result     string
+}
+
+// NewUI returns a UI over d. ctx bounds every collection and check.
+func NewUI(ctx context.Context, d *Dashboard) *UI {
+	return &UI{ctx: ctx, dash: d, width: 80}
+}
+
+type snapshotMsg struct{ snap *Snapshot }
+
+type tickMsg struct{}
+
+type checkMsg struct {
+	line    string
+	allowed bool
+	err     error
+}
+
+// Init implements tea.Model by taking the first snapshot.
+func (u *UI) Init() tea.Cmd {
+	return u.collect()
+}
+
+// collect takes a snapshot off the UI goroutine. At most one is in flight,
+// since a Dashboard is not safe for concurrent use.
+func (u *UI) collect() tea.Cmd {
+	if u.collecting {
+		return nil
+	}
+	u.collecting = true
+	return func() tea.Msg { return snapshotMsg{u.dash.Collect(u.ctx)} }
+}
+
+func (u *UI) check(line string, fields []string) tea.Cmd {
+	return func() tea.Msg {
+		allowed, err := u.dash.Backend.Check(u.ctx, authz.CheckRequest{User: fields[0], Relation: fields[1], Object: fields[2]})
+		return checkMsg{line: line, allowed: allowed, err: err}
+	}
+}
+
+// Update implements tea.Model.
+func (u *UI) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		u.width = msg.Width
+	case snapshotMsg:
+		u.snap, u.collecting = msg.snap, false
+		interval := u.Interval
+		if interval <= 0 {
+			interval = DefaultInterval
+		}
+		return u, tea.Tick(interval, func(time.Time) tea.Msg { return tickMsg{} })
+	case tickMsg:
+		return u, u.collect()
+	case checkMsg:
+		switch {
+		case msg.err != nil:
+			u.result = red + "error: " + msg.err.Error() + reset
+		case msg.allowed:
+			u.result = green + msg.line + ": allowed" + reset
+		default:
+			u.result = red + msg.line + ": denied" + reset
+		}
+	case tea.KeyMsg:
+		return u, u.key(msg)
+	}
+	return u, nil
+}
+
+func (u *UI) key(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyCtrlC, tea.KeyEsc:
+		return tea.Quit
+	case tea.KeyEnter:
+		line := strings.TrimSpace(string(u.input))
+		u.input, u.recall = nil, len(u.history)
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+			u.result = ""
+			return u.collect()
+		case line == "q" || line == "quit":
+			return tea.Quit
+		case len(fields) != 3:
+			u.result = "usage: <user> <relation> <object>"
+			return nil
+		}
+		u.history = append(u.history, line)
+		u.recall = len(u.history)
+		u.result = dim + line + ": checking…" + reset
+		return u.check(line, fields)
+	case tea.KeyBackspace:
+		if len(u.input) > 0 {
+			u.input = u.input[:len(u.input)-1]
+		}
+	case tea.KeyCtrlU:
+		u.input = nil
+	case tea.KeyUp:
+		if u.recall > 0 {
+			u.recall--
+			u.input = []rune(u.history[u.recall])
+		}
+	case tea.KeyDown:
+		if u.recall < len(u.history) {
+			u.recall++
+			u.input = nil
+			if u.recall < len(u.history) {
+				u.input = []rune(u.history[u.recall])
+			}
+		}
+	case tea.KeySpace:
+		u.input = append(u.input, ' ')
+	case tea.KeyRunes:
+		u.input = append(u.input, msg.Runes...)
+	}
+	return nil
+}
+
+// View implements tea.Model.
+func (u *UI) View() string {
+	var b strings.Builder
+	if u.snap == nil {
+		fmt.Fprintf(&b, "%sLoading…%s\n", dim, reset)
+	} else {
+		Render(&b, u.snap, u.width)
+	}
+	section(&b, "Check")
+	fmt.Fprintf(&b, "  %s<user> <relation> <object>, enter on an empty line refreshes, esc quits%s\n", dim, reset)
+	if u.result != "" {
+		fmt.Fprintf(&b, "  %s\n", u.result)
+	}
+	fmt.Fprintf(&b, "check> %s%s %s", string(u.input), reverse, reset)
+	return b.String()
+}
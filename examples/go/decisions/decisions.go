@@ -0,0 +1,431 @@
+// Package decisions aggregates decisions into per-object and per-relation
+// usage statistics — checks per day, unique users, deny ratio and a daily
+// heatmap — and lists grants nobody exercises, to find over-privileged or
+// unused access:
+//
+//	agg := &decisions.Aggregator{}
+//	az := authz.New(backend, authz.WithDecisionObserver(agg))
+//	http.Handle("/usage", decisions.Handler(agg))
+//
+//	// Or offline, from JSON decision logs:
+//	n, err := agg.ReadLog(f)
+//	r := agg.Report(decisions.Options{ObjectType: "document", Top: 20})
+//	r.WriteText(os.Stdout)
+//
+// cmd/fgamodel usage prints the report for log files. Counts are kept in
+// memory per object, relation and user, so feed an aggregator a bounded
+// window rather than a process's whole life.
+package decisions
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+)
+
+const day = 24 * time.Hour
+
+// Aggregator counts decisions. It is an authz.DecisionObserver; the zero
+// value is ready to use and safe for concurrent use.
+type Aggregator struct {
+	// Since and Until, when set, bound the decisions counted; ReadLog over
+	// a long log uses them to select a window.
+	Since, Until time.Time
+
+	mu     sync.Mutex
+	counts map[key]*counter
+}
+
+type key struct{ object, relation string }
+
+type counter struct {
+	checks, denied, errors int
+	users                  map[string]int
+	allowed                map[string]int
+	days                   map[time.Time]int
+	first, last            time.Time
+}
+
+// ObserveDecision implements authz.DecisionObserver.
+func (a *Aggregator) ObserveDecision(_ context.Context, d authz.Decision) {
+	a.Add(d)
+}
+
+// Add counts d.
+func (a *Aggregator) Add(d authz.Decision) {
+	if !a.Since.IsZero() && d.Time.Before(a.Since) || !a.Until.IsZero() && !d.Time.Before(a.Until) {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.counts == nil {
+		a.counts = map[key]*counter{}
+	}
+	k := key{d.Object, d.Relation}
+	c := a.counts[k]
+	if c == nil {
+		c = &counter{users: map[string]int{}, allowed: map[string]int{}, days: map[time.Time]int{}, first: d.Time, last: d.Time}
+		a.counts[k] = c
+	}
+	c.checks++
+	switch {
+	case d.Err != nil:
+		c.errors++
+	case !d.Allowed:
+		c.denied++
+	default:
+		c.allowed[d.User]++
+	}
+	c.users[d.User]++
+	c.days[d.Time.UTC().Truncate(day)]++
+	if d.Time.Before(c.first) {
+		c.first = d.Time
+	}
+	if d.Time.After(c.last) {
+		c.last = d.Time
+	}
+}
+
+// ReadLog adds the checks recorded in a JSON log, one record per line as
+// written by slog.NewJSONHandler: the "check" and "check failed" records of
+// authz.Client and the "authz call" records of authz.AuditInterceptor.
+// Other records are skipped. It returns the number of decisions added.
+func (a *Aggregator) ReadLog(r io.Reader) (int, error) {
+	n := 0
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	for line := 1; sc.Scan(); line++ {
+		var rec struct {
+			Time     time.Time `json:"time"`
+			Msg      string    `json:"msg"`
+			Op       string    `json:"op"`
+			User     string    `json:"user"`
+			Relation string    `json:"relation"`
+			Object   string    `json:"object"`
+			Allowed  *bool     `json:"allowed"`
+			Error    string    `json:"error"`
+		}
+		if len(strings.TrimSpace(sc.Text())) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			return n, fmt.Errorf("decisions: line %d: %w", line, err)
+		}
+		switch rec.Msg {
+		case "check", "check failed":
+		case "authz call", "authz call failed":
+			if rec.Op != authz.OpCheck {
+				continue
+			}
+		default:
+			continue
+		}
+		if rec.Object == "" || rec.Relation == "" || rec.Allowed == nil && rec.Error == "" {
+			continue
+		}
+		d := authz.Decision{User: rec.User, Relation: rec.Relation, Object: rec.Object, Time: rec.Time}
+		if rec.Error != "" {
+			d.Err = errors.New(rec.Error)
+		} else {
+			d.Allowed = *rec.Allowed
+		}
+		a.Add(d)
+		n++
+	}
+	if err := sc.Err(); err != nil {
+		return n, fmt.Errorf("decisions: %w", err)
+	}
+	return n, nil
+}
+
+// Options tunes Report.
+type Options struct {
+	// ObjectType limits the report to objects of one type.
+	ObjectType string
+	// Top limits Objects to the most checked entries; zero keeps all.
+	Top int
+}
+
+// Stats is the usage of one object and relation, or, in
+// Report.Relations, of one relation over every object of a type; Object
+// is then the type.
+type Stats struct {
+	Object   string    `json:"object"`
+	Relation string    `json:"relation"`
+	Checks   int       `json:"checks"`
+	Denied   int       `json:"denied"`
+	Errors   int       `json:"errors"`
+	Users    int       `json:"users"`
+	First    time.Time `json:"first"`
+	Last     time.Time `json:"last"`
+	// ChecksPerDay averages Checks over the report's days.
+	ChecksPerDay float64 `json:"checks_per_day"`
+	// DenyRatio is Denied over the checks that did not fail.
+	DenyRatio float64 `json:"deny_ratio"`
+}
+
+// Row is one line of the heatmap: the checks of a type and relation per
+// day of Report.Days.
+type Row struct {
+	Type     string `json:"type"`
+	Relation string `json:"relation"`
+	Checks   []int  `json:"checks"`
+}
+
+// Report is a summary of an aggregator.
+type Report struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	// Days are the UTC days between From and To, the columns of Heatmap.
+	Days []time.Time `json:"days"`
+	// Objects is sorted by checks, most first.
+	Objects []Stats `json:"objects"`
+	// Relations is sorted by type and relation.
+	Relations []Stats `json:"relations"`
+	Heatmap   []Row   `json:"heatmap"`
+}
+
+// Report summarizes the decisions counted so far.
+func (a *Aggregator) Report(opts Options) *Report {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	r := &Report{}
+	type rel struct{ typ, relation string }
+	relations := map[rel]*counter{}
+	for k, c := range a.counts {
+		typ, _, _ := strings.Cut(k.object, ":")
+		if opts.ObjectType != "" && typ != opts.ObjectType {
+			continue
+		}
+		if r.From.IsZero() || c.first.Before(r.From) {
+			r.From = c.first
+		}
+		if c.last.After(r.To) {
+			r.To = c.last
+		}
+		r.Objects = append(r.Objects, stats(k.object, k.relation, c))
+		rc := relations[rel{typ, k.relation}]
+		if rc == nil {
+			rc = &counter{users: map[string]int{}, allowed: map[string]int{}, days: map[time.Time]int{}, first: c.first, last: c.last}
+			relations[rel{typ, k.relation}] = rc
+		}
+		rc.merge(c)
+	}
+	if len(r.Objects) == 0 {
+		return r
+	}
+	for d := r.From.UTC().Truncate(day); !d.After(r.To); d = d.Add(day) {
+		r.Days = append(r.Days, d)
+	}
+	for k, c := range relations {
+		r.Relations = append(r.Relations, stats(k.typ, k.relation, c))
+		row := Row{Type: k.typ, Relation: k.relation, Checks: make([]int, len(r.Days))}
+		for i, d := range r.Days {
+			row.Checks[i] = c.days[d]
+		}
+		r.Heatmap = append(r.Heatmap, row)
+	}
+	days := float64(len(r.Days))
+	for _, ss := range [][]Stats{r.Objects, r.Relations} {
+		for i := range ss {
+			ss[i].ChecksPerDay = float64(ss[i].Checks) / days
+		}
+	}
+	sort.Slice(r.Objects, func(i, j int) bool {
+		a, b := r.Objects[i], r.Objects[j]
+		if a.Checks != b.Checks {
+			return a.Checks > b.Checks
+		}
+		if a.Object != b.Object {
+			return a.Object < b.Object
+		}
+		return a.Relation < b.Relation
+	})
+	if opts.Top > 0 && len(r.Objects) > opts.Top {
+		r.Objects = r.Objects[:opts.Top]
+	}
+	sort.Slice(r.Relations, func(i, j int) bool {
+		a, b := r.Relations[i], r.Relations[j]
+		if a.Object != b.Object {
+			return a.Object < b.Object
+		}
+		return a.Relation < b.Relation
+	})
+	sort.Slice(r.Heatmap, func(i, j int) bool {
+		a, b := r.Heatmap[i], r.Heatmap[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Relation < b.Relation
+	})
+	return r
+}
+
+func (c *counter) merge(o *counter) {
+	c.checks += o.checks
+	c.denied += o.denied
+	c.errors += o.errors
+	for u, n := range o.users {
+		c.users[u] += n
+	}
+	for u, n := range o.allowed {
+		c.allowed[u] += n
+	}
+	for d, n := range o.days {
+		c.days[d] += n
+	}
+	if o.first.Before(c.first) {
+		c.first = o.first
+	}
+	if o.last.After(c.last) {
+		c.last = o.last
+	}
+}
+
+func stats(object, relation string, c *counter) Stats {
+	s := Stats{Object: object, Relation: relation, Checks: c.checks, Denied: c.denied, Errors: c.errors,
+		Users: len(c.users), First: c.first, Last: c.last}
+	if ok := c.checks - c.errors; ok > 0 {
+		s.DenyRatio = float64(c.denied) / float64(ok)
+	}
+	return s
+}
+
+// Grant is a stored tuple with the checks its user made on its object,
+// under any relation, while the aggregator was counting.
+type Grant struct {
+	Tuple  authz.Tuple `json:"tuple"`
+	Checks int         `json:"checks"`
+}
+
+// Grants reads the tuples on objects of objectType, or every tuple when it
+// is empty, and returns those granted to a single user with their check
+// counts, unexercised grants first. A grant with no checks over a
+// representative window is a candidate for removal; usersets and
+// wildcards are left out, since their members' checks cannot be told
+// apart from other grants'.
+func (a *Aggregator) Grants(ctx context.Context, b authz.Backend, objectType string) ([]Grant, error) {
+	all, err := authz.ReadType(ctx, b, objectType)
+	if err != nil {
+		return nil, fmt.Errorf("decisions: read: %w", err)
+	}
+	a.mu.Lock()
+	checks := map[[2]string]int{}
+	for k, c := range a.counts {
+		for u, n := range c.users {
+			checks[[2]string{u, k.object}] += n
+		}
+	}
+	a.mu.Unlock()
+	var gs []Grant
+	for _, t := range all {
+		if strings.Contains(t.User, "#") || strings.HasSuffix(t.User, ":*") {
+			continue
+		}
+		gs = append(gs, Grant{Tuple: t, Checks: checks[[2]string{t.User, t.Object}]})
+	}
+	sort.Slice(gs, func(i, j int) bool {
+		a, b := gs[i], gs[j]
+		if a.Checks != b.Checks {
+			return a.Checks < b.Checks
+		}
+		return a.Tuple.String() < b.Tuple.String()
+	})
+	return gs, nil
+}
+
+// Allowed returns the checks that were allowed, keyed by the checked
+// user, relation and object, with their counts: what users actually
+// exercised, as package leastpriv correlates with what they were granted.
+func (a *Aggregator) Allowed() map[authz.Tuple]int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := map[authz.Tuple]int{}
+	for k, c := range a.counts {
+		for u, n := range c.allowed {
+			out[authz.Tuple{User: u, Relation: k.relation, Object: k.object}] = n
+		}
+	}
+	return out
+}
+
+// heat shades a heatmap cell by its share of the row's busiest day.
+var heat = []string{" ", "░", "▒", "▓", "█"}
+
+// WriteText writes r as aligned tables followed by the heatmap, one
+// column per day.
+func (r *Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	if len(r.Days) == 0 {
+		fmt.Fprintln(tw, "no decisions")
+		return tw.Flush()
+	}
+	fmt.Fprintf(tw, "%s to %s, %d days\n\n", r.From.UTC().Format(time.RFC3339), r.To.UTC().Format(time.RFC3339), len(r.Days))
+	table := func(title string, ss []Stats) {
+		fmt.Fprintf(tw, "%s\tRELATION\tCHECKS\tPER DAY\tUSERS\tDENY\tERRORS\tLAST\n", title)
+		for _, s := range ss {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%.1f\t%d\t%.0f%%\t%d\t%s\n", s.Object, s.Relation, s.Checks, s.ChecksPerDay,
+				s.Users, 100*s.DenyRatio, s.Errors, s.Last.UTC().Format(time.RFC3339))
+		}
+	}
+	table("TYPE", r.Relations)
+	fmt.Fprintln(tw)
+	table("OBJECT", r.Objects)
+	fmt.Fprintf(tw, "\nHEATMAP\tRELATION\t%s .. %s\n", r.Days[0].Format("2006-01-02"), r.Days[len(r.Days)-1].Format("2006-01-02"))
+	for _, row := range r.Heatmap {
+		peak := 0
+		for _, n := range row.Checks {
+			peak = max(peak, n)
+		}
+		var cells strings.Builder
+		for _, n := range row.Checks {
+			i := 0
+			if n > 0 {
+				i = (n*(len(heat)-1) + peak - 1) / peak
+			}
+			cells.WriteString(heat[i])
+		}
+		fmt.Fprintf(tw, "%s\t%s\t|%s|\n", row.Type, row.Relation, cells.String())
+	}
+	return tw.Flush()
+}
+
+// WriteGrantsText writes grants as an aligned table.
+func WriteGrantsText(w io.Writer, gs []Grant) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "USER\tRELATION\tOBJECT\tCHECKS\n")
+	for _, g := range gs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\n", g.Tuple.User, g.Tuple.Relation, g.Tuple.Object, g.Checks)
+	}
+	return tw.Flush()
+}
+
+// Handler serves a's report as JSON. The query parameters type and top
+// set Options.
+func Handler(a *Aggregator) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		opts := Options{ObjectType: r.URL.Query().Get("type")}
+		if v := r.URL.Query().Get("top"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				http.Error(w, "decisions: invalid top", http.StatusBadRequest)
+				return
+			}
+			opts.Top = n
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(a.Report(opts))
+	})
+}
@@ -0,0 +1,217 @@
+// Package drift detects when two environments that should match, such as
+// staging and prod, no longer do: a different active model, or differences
+// in selected tuple subsets like role definitions and system grants.
+//
+//	c := &drift.Checker{
+//		Reference: promote.Env{Name: "staging", Backend: authz.FromSDK(stg)},
+//		Target:    promote.Env{Name: "prod", Backend: authz.FromSDK(prd)},
+//		Seeds:     []tuples.Filter{{ObjectType: "role"}},
+//		Notifier:  notifyOps,
+//	}
+//	http.Handle("/metrics/drift", c)
+//	go c.Run(ctx, 15*time.Minute)
+//
+// The comparison is the one promote.Preview makes, so a drift report is
+// also what promote.Promote with Prune would change.
+package drift
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/promote"
+	"github.com/bogdanticu88/openfga-examples/tuples"
+)
+
+// Report is the result of one comparison.
+type Report struct {
+	CheckedAt time.Time
+	Reference string
+	Target    string
+	// ModelDiff is a line diff of the target's active model to the
+	// reference's, empty when they match.
+	ModelDiff string
+	// Missing are selected tuples the reference has and the target lacks;
+	// Extra the reverse.
+	Missing []authz.Tuple
+	Extra   []authz.Tuple
+}
+
+// Empty reports whether the environments match.
+func (r *Report) Empty() bool {
+	return r.ModelDiff == "" && len(r.Missing) == 0 && len(r.Extra) == 0
+}
+
+// WriteText writes r for a log, an email or a ticket.
+func (r *Report) WriteText(w io.Writer) error {
+	if r.Empty() {
+		_, err := fmt.Fprintf(w, "%s matches %s at %s\n", r.Target, r.Reference, r.CheckedAt.UTC().Format(time.RFC3339))
+		return err
+	}
+	fmt.Fprintf(w, "%s has drifted from %s at %s\n", r.Target, r.Reference, r.CheckedAt.UTC().Format(time.RFC3339))
+	if r.ModelDiff != "" {
+		fmt.Fprintf(w, "\nmodel (-%s +%s):\n%s\n", r.Target, r.Reference, r.ModelDiff)
+	}
+	for _, t := range r.Missing {
+		fmt.Fprintf(w, "missing: %s\n", t)
+	}
+	for _, t := range r.Extra {
+		fmt.Fprintf(w, "extra: %s\n", t)
+	}
+	return nil
+}
+
+// Notifier delivers drift reports, e.g. to chat or a ticket queue.
+type Notifier interface {
+	Notify(ctx context.Context, r *Report) error
+}
+
+// NotifierFunc adapts a function to Notifier.
+type NotifierFunc func(ctx context.Context, r *Report) error
+
+// Notify calls f.
+func (f NotifierFunc) Notify(ctx context.Context, r *Report) error { return f(ctx, r) }
+
+// Checker compares Target against Reference. It serves the latest report
+// from Run as Prometheus gauges.
+type Checker struct {
+	Reference promote.Env
+	Target    promote.Env
+	// Seeds select the tuples compared; none compares the models only.
+	Seeds []tuples.Filter
+	// Notifier receives every report from Run that differs from the
+	// previous one, including the one reporting drift resolved; nil only
+	// logs.
+	Notifier Notifier
+	// Now defaults to time.Now.
+	Now func() time.Time
+	// Logger receives scheduling events; nil disables logging.
+	Logger *slog.Logger
+
+	mu   sync.Mutex
+	last *Report
+}
+
+// Check compares the environments once.
+func (c *Checker) Check(ctx context.Context) (*Report, error) {
+	p, err := promote.Preview(ctx, c.Reference, c.Target, promote.Options{Seeds: c.Seeds, Prune: true})
+	if err != nil {
+		return nil, fmt.Errorf("drift: %w", err)
+	}
+	return &Report{
+		CheckedAt: c.now(),
+		Reference: c.Reference.Name,
+		Target:    c.Target.Name,
+		ModelDiff: p.ModelDiff,
+		Missing:   p.Writes,
+		Extra:     p.Deletes,
+	}, nil
+}
+
+// Run checks every interval until ctx is done. Failures are logged and
+// retried at the next interval; the gauges keep the last good report.
+func (c *Checker) Run(ctx context.Context, interval time.Duration) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		c.tick(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+func (c *Checker) tick(ctx context.Context) {
+	r, err := c.Check(ctx)
+	if err != nil {
+		c.logf(ctx, slog.LevelWarn, "drift check failed", "error", err)
+		return
+	}
+	c.mu.Lock()
+	prev := c.last
+	c.last = r
+	c.mu.Unlock()
+	if !r.Empty() {
+		c.logf(ctx, slog.LevelInfo, "drift detected", "model", r.ModelDiff != "", "missing", len(r.Missing), "extra", len(r.Extra))
+	}
+	if c.Notifier == nil || same(prev, r) || prev == nil && r.Empty() {
+		return
+	}
+	if err := c.Notifier.Notify(ctx, r); err != nil {
+		c.logf(ctx, slog.LevelWarn, "drift notification failed", "error", err)
+	}
+}
+
+// Last returns the latest report from Run, or nil before the first.
+func (c *Checker) Last() *Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.last
+}
+
+// WritePrometheus writes r as the gauges fga_drift{reference,target,kind}
+// with kind model (0 or 1), missing and extra, and
+// fga_drift_checked_timestamp_seconds.
+func (r *Report) WritePrometheus(w io.Writer) error {
+	var b strings.Builder
+	envs := fmt.Sprintf("reference=%s,target=%s", label(r.Reference), label(r.Target))
+	model := 0
+	if r.ModelDiff != "" {
+		model = 1
+	}
+	fmt.Fprintf(&b, "# HELP fga_drift Differences between the target environment and its reference.\n# TYPE fga_drift gauge\n")
+	fmt.Fprintf(&b, "fga_drift{%s,kind=\"model\"} %d\n", envs, model)
+	fmt.Fprintf(&b, "fga_drift{%s,kind=\"missing\"} %d\n", envs, len(r.Missing))
+	fmt.Fprintf(&b, "fga_drift{%s,kind=\"extra\"} %d\n", envs, len(r.Extra))
+	fmt.Fprintf(&b, "# HELP fga_drift_checked_timestamp_seconds When the environments were last compared.\n# TYPE fga_drift_checked_timestamp_seconds gauge\n")
+	fmt.Fprintf(&b, "fga_drift_checked_timestamp_seconds{%s} %d\n", envs, r.CheckedAt.Unix())
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ServeHTTP serves the latest report in the Prometheus text format, or 503
+// before Run has completed a check.
+func (c *Checker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	last := c.Last()
+	if last == nil {
+		http.Error(w, "drift: no check yet", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	_ = last.WritePrometheus(w)
+}
+
+// same reports whether a and b describe the same drift.
+func same(a, b *Report) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.ModelDiff == b.ModelDiff && slices.Equal(a.Missing, b.Missing) && slices.Equal(a.Extra, b.Extra)
+}
+
+func (c *Checker) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+func (c *Checker) logf(ctx context.Context, level slog.Level, msg string, args ...interface{}) {
+	if c.Logger != nil {
+		c.Logger.Log(ctx, level, msg, args...)
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func label(v string) string { return `"` + labelEscaper.Replace(v) + `"` }
@@ -0,0 +1,257 @@
+// Package effective computes everything a user can do in one call, for
+// "what can alice access?" screens and access reviews:
+//
+//	r := &effective.Resolver{Lister: effective.SDKLister{Client: fga}, Model: m, CacheTTL: time.Minute}
+//	sum, err := r.Permissions(ctx, "user:alice", "project", "document")
+//	for _, object := range sum.ObjectIDs() {
+//		fmt.Println(object, sum.Objects[object]) // document:roadmap [editor viewer]
+//	}
+//
+// A summary is assembled from one ListObjects call per type and relation,
+// run concurrently, each with a share of the context's deadline so one
+// slow call cannot starve the rest. A failing call does not lose the
+// summary: it is recorded in Summary.Failures, the remaining relations are
+// still reported and the error is an *authz.PartialError, so callers can
+// show what is known and flag the gaps.
+package effective
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	openfga "github.com/openfga/go-sdk"
+	"github.com/openfga/go-sdk/client"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// DefaultConcurrency is the number of ListObjects calls in flight when
+// Resolver.Concurrency is zero.
+const DefaultConcurrency = 8
+
+// Lister lists the objects of a type on which a user has a relation.
+// *eval.Evaluator and *authz.Client implement it.
+type Lister = authz.Lister
+
+// SDKLister is a Lister over the official client.
+type SDKLister struct {
+	Client *client.OpenFgaClient
+}
+
+// ListObjects implements Lister, honoring the authz.RequestOptions on ctx.
+func (l SDKLister) ListObjects(ctx context.Context, user, relation, objectType string) ([]string, error) {
+	ctx, cancel := authz.CallContext(ctx)
+	defer cancel()
+	o := authz.RequestOptionsFrom(ctx)
+	opts := client.ClientListObjectsOptions{}
+	if o.ModelID != "" {
+		opts.AuthorizationModelId = &o.ModelID
+	}
+	if o.Consistency != authz.ConsistencyDefault {
+		c := openfga.ConsistencyPreference(o.Consistency)
+		opts.Consistency = &c
+	}
+	resp, err := l.Client.ListObjects(ctx).Body(client.ClientListObjectsRequest{
+		User:     user,
+		Relation: relation,
+		Type:     objectType,
+	}).Options(opts).Execute()
+	if err != nil {
+		return nil, err
+	}
+	return resp.GetObjects(), nil
+}
+
+// Failure is a type and relation whose objects could not be listed.
+type Failure struct {
+	Type     string
+	Relation string
+	Err      error
+}
+
+func (f Failure) Error() string {
+	return fmt.Sprintf("%s#%s: %v", f.Type, f.Relation, f.Err)
+}
+
+// Summary is a user's effective permissions.
+type Summary struct {
+	User string
+	// Objects maps each object the user can reach to its relations, sorted.
+	Objects map[string][]string
+	// Failures lists the queries that failed; their relations are missing
+	// from Objects.
+	Failures []Failure
+}
+
+// Complete reports whether every query succeeded.
+func (s *Summary) Complete() bool { return len(s.Failures) == 0 }
+
+// Has reports whether the summary grants relation on object.
+func (s *Summary) Has(object, relation string) bool {
+	return slices.Contains(s.Objects[object], relation)
+}
+
+// ObjectIDs returns the objects in Objects, sorted.
+func (s *Summary) ObjectIDs() []string {
+	ids := make([]string, 0, len(s.Objects))
+	for id := range s.Objects {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Resolver computes summaries. It is safe for concurrent use once
+// configured.
+type Resolver struct {
+	Lister Lister
+	// Model supplies the relations of each type.
+	Model *model.Model
+	// Concurrency bounds the calls in flight; default DefaultConcurrency.
+	Concurrency int
+	// CacheTTL keeps successful ListObjects results per user, type and
+	// relation; zero disables the cache.
+	CacheTTL time.Duration
+	// Now defaults to time.Now.
+	Now func() time.Time
+
+	mu    sync.Mutex
+	cache map[query]cached
+}
+
+type query struct {
+	user, objectType, relation string
+}
+
+type cached struct {
+	objects []string
+	at      time.Time
+}
+
+// Permissions lists what user can do on objects of objectTypes, or of
+// every type in the model when none are given. When some queries fail,
+// the summary of the others is returned with an *authz.PartialError; see
+// Summary.Failures.
+func (r *Resolver) Permissions(ctx context.Context, user string, objectTypes ...string) (*Summary, error) {
+	if len(objectTypes) == 0 {
+		for _, t := range r.Model.Types {
+			objectTypes = append(objectTypes, t.Name)
+		}
+	}
+	var queries []query
+	for _, typ := range objectTypes {
+		t := r.Model.Type(typ)
+		if t == nil {
+			return nil, fmt.Errorf("effective: unknown type %q", typ)
+		}
+		for _, rel := range t.Relations {
+			queries = append(queries, query{user, typ, rel.Name})
+		}
+	}
+
+	limit := r.Concurrency
+	if limit <= 0 {
+		limit = DefaultConcurrency
+	}
+	budget := authz.NewBudget(ctx, len(queries), limit)
+	results := make([][]string, len(queries))
+	errs := make([]error, len(queries))
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i, q := range queries {
+		wg.Add(1)
+		go func(i int, q query) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+			qctx, cancel := budget.Next()
+			defer cancel()
+			results[i], errs[i] = r.list(qctx, q)
+		}(i, q)
+	}
+	wg.Wait()
+
+	sum := &Summary{User: user, Objects: map[string][]string{}}
+	for i, q := range queries {
+		if errs[i] != nil {
+			sum.Failures = append(sum.Failures, Failure{Type: q.objectType, Relation: q.relation, Err: errs[i]})
+			continue
+		}
+		for _, object := range results[i] {
+			sum.Objects[object] = append(sum.Objects[object], q.relation)
+		}
+	}
+	for _, rels := range sum.Objects {
+		sort.Strings(rels)
+	}
+	if len(sum.Failures) == 0 {
+		return sum, nil
+	}
+	failed := make([]error, len(sum.Failures))
+	for i, f := range sum.Failures {
+		failed[i] = f
+	}
+	if len(sum.Failures) == len(queries) {
+		return sum, fmt.Errorf("effective: every query failed: %w", errors.Join(failed...))
+	}
+	return sum, &authz.PartialError{Op: "effective: permissions", Failed: len(failed), Total: len(queries), Errs: failed}
+}
+
+// Invalidate drops the cached results for user, or for everyone when user
+// is empty, e.g. after a write that changes their access.
+func (r *Resolver) Invalidate(user string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if user == "" {
+		r.cache = nil
+		return
+	}
+	for q := range r.cache {
+		if q.user == user {
+			delete(r.cache, q)
+		}
+	}
+}
+
+func (r *Resolver) list(ctx context.Context, q query) ([]string, error) {
+	now := time.Now
+	if r.Now != nil {
+		now = r.Now
+	}
+	// A pinned model or a fresh read asks a different question than the
+	// cached answers.
+	o := authz.RequestOptionsFrom(ctx)
+	cache := r.CacheTTL > 0 && o.ModelID == "" && o.Consistency != authz.HigherConsistency
+	if cache {
+		r.mu.Lock()
+		c, ok := r.cache[q]
+		r.mu.Unlock()
+		if ok && now().Sub(c.at) < r.CacheTTL {
+			return c.objects, nil
+		}
+	}
+	objects, err := r.Lister.ListObjects(ctx, q.user, q.relation, q.objectType)
+	if err != nil {
+		return nil, err
+	}
+	if cache {
+		r.mu.Lock()
+		if r.cache == nil {
+			r.cache = map[query]cached{}
+		}
+		r.cache[q] = cached{objects: objects, at: now()}
+		r.mu.Unlock()
+	}
+	return objects, nil
+}
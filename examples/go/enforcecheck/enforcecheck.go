@@ -0,0 +1,280 @@
+// Package enforcecheck finds HTTP handlers and gRPC methods that never call
+// an enforcement helper such as authz.Client.Require or Check.
+//
+// Analyzer is a go/analysis pass, so calls are resolved with type
+// information: a method that merely shares a name with an enforcement
+// function does not count. A handler is considered enforced when its body
+// calls an enforcement function directly, or calls a function that
+// (transitively) does, whether declared in the same package or in one it
+// imports. Handlers are functions with the http.HandlerFunc signature,
+// including function literals passed to a HandleFunc method, and exported
+// methods of a type named *Server that take a context and return an error.
+// Handlers that are intentionally public are suppressed with a directive in
+// their doc comment, or on the line above the HandleFunc call:
+//
+//	//authz:ignore health endpoint, no resource involved
+//	func healthz(w http.ResponseWriter, r *http.Request) { ... }
+//
+// cmd/authzvet runs the analyzer standalone or as a go vet tool.
+package enforcecheck
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// Directive suppresses a finding when present in a function's doc comment.
+const Directive = "//authz:ignore"
+
+const authzPath = "github.com/bogdanticu88/openfga-examples/authz"
+
+// DefaultFuncs are the functions treated as enforcement, each written as
+// import path, receiver type name for methods, and function name joined by
+// dots.
+var DefaultFuncs = []string{authzPath + ".Client.Require", authzPath + ".Client.Check"}
+
+// Kind classifies a flagged function.
+type Kind string
+
+const (
+	HTTPHandler Kind = "http handler"
+	GRPCMethod  Kind = "grpc method"
+)
+
+// Analyzer reports handlers missing an enforcement call. Its -funcs flag
+// replaces DefaultFuncs with a comma-separated list in the same form.
+var Analyzer = &analysis.Analyzer{
+	Name:      "enforcecheck",
+	Doc:       "report HTTP handlers and gRPC methods that never call an authorization helper",
+	Run:       run,
+	FactTypes: []analysis.Fact{new(enforces)},
+}
+
+var funcs string
+
+func init() {
+	Analyzer.Flags.StringVar(&funcs, "funcs", strings.Join(DefaultFuncs, ","), "comma-separated enforcement functions, as importpath.Func or importpath.Type.Method")
+}
+
+// enforces marks a function that calls an enforcement function, directly or
+// through others, so packages importing it can rely on it.
+type enforces struct{}
+
+func (*enforces) AFact()         {}
+func (*enforces) String() string { return "enforces" }
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	targets := map[string]bool{}
+	for _, f := range strings.Split(funcs, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			targets[f] = true
+		}
+	}
+	enforcers := map[*types.Func]bool{}
+	enforcing := func(fn *types.Func) bool {
+		if enforcers[fn] || targets[qualifiedName(fn)] {
+			return true
+		}
+		return fn.Pkg() != nil && fn.Pkg() != pass.Pkg && pass.ImportObjectFact(fn, new(enforces))
+	}
+	enforced := func(body ast.Node) bool {
+		for _, fn := range callees(pass.TypesInfo, body) {
+			if enforcing(fn) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Collect local functions and the functions each one calls.
+	calls := map[*types.Func][]*types.Func{}
+	var decls []*ast.FuncDecl
+	for _, f := range pass.Files {
+		for _, d := range f.Decls {
+			fn, ok := d.(*ast.FuncDecl)
+			if !ok || fn.Body == nil {
+				continue
+			}
+			obj, ok := pass.TypesInfo.Defs[fn.Name].(*types.Func)
+			if !ok {
+				continue
+			}
+			decls = append(decls, fn)
+			calls[obj] = callees(pass.TypesInfo, fn.Body)
+		}
+	}
+
+	// Propagate enforcement through local helpers until nothing changes.
+	for changed := true; changed; {
+		changed = false
+		for fn, cs := range calls {
+			if enforcers[fn] {
+				continue
+			}
+			for _, c := range cs {
+				if enforcing(c) {
+					enforcers[fn] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	for fn := range enforcers {
+		pass.ExportObjectFact(fn, new(enforces))
+	}
+
+	for _, fn := range decls {
+		obj := pass.TypesInfo.Defs[fn.Name].(*types.Func)
+		kind, ok := classify(obj)
+		if !ok || suppressed(fn.Doc) || enforcers[obj] {
+			continue
+		}
+		pass.Reportf(fn.Name.Pos(), "%s %s does not call an authorization helper", kind, funcName(obj))
+	}
+
+	for _, f := range pass.Files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			if fn, ok := typeutil.Callee(pass.TypesInfo, call).(*types.Func); !ok || fn.Name() != "HandleFunc" {
+				return true
+			}
+			for _, arg := range call.Args {
+				lit, ok := arg.(*ast.FuncLit)
+				if !ok || !isHandler(pass.TypesInfo.TypeOf(lit)) || enforced(lit.Body) || directiveAbove(pass, f, call) {
+					continue
+				}
+				pass.Reportf(lit.Pos(), "%s %s does not call an authorization helper", HTTPHandler, route(call))
+			}
+			return true
+		})
+	}
+	return nil, nil
+}
+
+// callees returns the functions and methods statically called in node.
+func callees(info *types.Info, node ast.Node) []*types.Func {
+	var fns []*types.Func
+	ast.Inspect(node, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			if fn, ok := typeutil.Callee(info, call).(*types.Func); ok {
+				fns = append(fns, fn.Origin())
+			}
+		}
+		return true
+	})
+	return fns
+}
+
+// qualifiedName returns fn in the form DefaultFuncs uses.
+func qualifiedName(fn *types.Func) string {
+	if fn.Pkg() == nil {
+		return fn.Name()
+	}
+	if t := recvNamed(fn); t != nil {
+		return fn.Pkg().Path() + "." + t.Obj().Name() + "." + fn.Name()
+	}
+	return fn.Pkg().Path() + "." + fn.Name()
+}
+
+func recvNamed(fn *types.Func) *types.Named {
+	recv := fn.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return nil
+	}
+	t := recv.Type()
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	n, _ := t.(*types.Named)
+	return n
+}
+
+func classify(fn *types.Func) (Kind, bool) {
+	sig := fn.Type().(*types.Signature)
+	if isHandler(sig) {
+		return HTTPHandler, true
+	}
+	recv := recvNamed(fn)
+	if recv == nil || !strings.HasSuffix(recv.Obj().Name(), "Server") || !fn.Exported() {
+		return "", false
+	}
+	params, results := sig.Params(), sig.Results()
+	if params.Len() != 2 || !isNamed(params.At(0).Type(), "context", "Context") {
+		return "", false
+	}
+	if results.Len() != 2 || !types.Identical(results.At(1).Type(), types.Universe.Lookup("error").Type()) {
+		return "", false
+	}
+	return GRPCMethod, true
+}
+
+// isHandler reports whether t is the signature of an http.HandlerFunc.
+func isHandler(t types.Type) bool {
+	sig, ok := t.(*types.Signature)
+	if !ok || sig.Params().Len() != 2 || sig.Results().Len() != 0 {
+		return false
+	}
+	req, ok := sig.Params().At(1).Type().(*types.Pointer)
+	return ok && isNamed(sig.Params().At(0).Type(), "net/http", "ResponseWriter") && isNamed(req.Elem(), "net/http", "Request")
+}
+
+func isNamed(t types.Type, pkg, name string) bool {
+	n, ok := t.(*types.Named)
+	return ok && n.Obj().Pkg() != nil && n.Obj().Pkg().Path() == pkg && n.Obj().Name() == name
+}
+
+func suppressed(doc *ast.CommentGroup) bool {
+	if doc == nil {
+		return false
+	}
+	for _, c := range doc.List {
+		if strings.HasPrefix(c.Text, Directive) {
+			return true
+		}
+	}
+	return false
+}
+
+// directiveAbove reports whether the line of call, or the line above it,
+// holds a Directive.
+func directiveAbove(pass *analysis.Pass, f *ast.File, call *ast.CallExpr) bool {
+	line := pass.Fset.Position(call.Pos()).Line
+	for _, cg := range f.Comments {
+		for _, c := range cg.List {
+			if l := pass.Fset.Position(c.Pos()).Line; (l == line || l == line-1) && strings.HasPrefix(c.Text, Directive) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func funcName(fn *types.Func) string {
+	if t := recvNamed(fn); t != nil {
+		return t.Obj().Name() + "." + fn.Name()
+	}
+	return fn.Name()
+}
+
+// route names a function literal handler by the pattern it is registered
+// for, when that is a constant.
+func route(call *ast.CallExpr) string {
+	if len(call.Args) > 0 {
+		if lit, ok := call.Args[0].(*ast.BasicLit); ok {
+			if p, err := strconv.Unquote(lit.Value); err == nil {
+				return fmt.Sprintf("for %q", p)
+			}
+		}
+	}
+	return "func literal"
+}
@@ -0,0 +1,13 @@
+package enforcecheck_test
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+
+	"github.com/bogdanticu88/openfga-examples/enforcecheck"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), enforcecheck.Analyzer, "handlers")
+}
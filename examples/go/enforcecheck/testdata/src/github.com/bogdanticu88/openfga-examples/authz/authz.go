@@ -0,0 +1,11 @@
+package authz
+
+import "context"
+
+type Client struct{}
+
+func (c *Client) Check(ctx context.Context, user, relation, object string) (bool, error) {
+	return false, nil
+}
+
+func (c *Client) Require(ctx context.Context, user, relation, object string) error { return nil }
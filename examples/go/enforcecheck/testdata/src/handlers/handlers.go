@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"helpers"
+)
+
+var az *authz.Client
+
+func getDocument(w http.ResponseWriter, r *http.Request) { // want getDocument:"enforces"
+	if err := az.Require(r.Context(), "user:anne", "viewer", "document:1"); err != nil {
+		http.Error(w, "forbidden", http.StatusForbidden)
+	}
+}
+
+func listDocuments(w http.ResponseWriter, r *http.Request) { // want `http handler listDocuments does not call an authorization helper`
+}
+
+// load calls the enforcement helper, so handlers calling it are enforced.
+func load(r *http.Request) bool { // want load:"enforces"
+	ok, _ := az.Check(r.Context(), "user:anne", "viewer", "document:1")
+	return ok
+}
+
+func viaHelper(w http.ResponseWriter, r *http.Request) { // want viaHelper:"enforces"
+	_ = load(r)
+}
+
+func viaOtherPackage(w http.ResponseWriter, r *http.Request) { // want viaOtherPackage:"enforces"
+	_ = helpers.Authorize(r, "viewer", "document:1")
+}
+
+//authz:ignore health endpoint, no resource involved
+func healthz(w http.ResponseWriter, r *http.Request) {}
+
+type validator struct{}
+
+func (validator) Check(r *http.Request) error { return nil }
+
+// Require shares its name with the enforcement method but is unrelated.
+func Require(r *http.Request) {}
+
+func unrelatedCheck(w http.ResponseWriter, r *http.Request) { // want `http handler unrelatedCheck does not call an authorization helper`
+	_ = validator{}.Check(r)
+	Require(r)
+}
+
+func routes(mux *http.ServeMux) { // want routes:"enforces"
+	mux.HandleFunc("/docs/", func(w http.ResponseWriter, r *http.Request) {
+		_ = helpers.Authorize(r, "viewer", "document:1")
+	})
+	mux.HandleFunc("/admin", func(w http.ResponseWriter, r *http.Request) { // want `http handler for "/admin" does not call an authorization helper`
+	})
+	//authz:ignore static assets are public
+	mux.HandleFunc("/static/", func(w http.ResponseWriter, r *http.Request) {})
+}
+
+type DocumentServer struct{}
+
+func (s *DocumentServer) GetDocument(ctx context.Context, id string) (string, error) { // want GetDocument:"enforces"
+	if err := az.Require(ctx, "user:anne", "viewer", "document:"+id); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+func (s *DocumentServer) DeleteDocument(ctx context.Context, id string) (string, error) { // want `grpc method DocumentServer.DeleteDocument does not call an authorization helper`
+	return id, nil
+}
+
+func (s *DocumentServer) close(ctx context.Context, id string) (string, error) { return id, nil }
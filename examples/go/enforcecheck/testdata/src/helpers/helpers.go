@@ -0,0 +1,14 @@
+package helpers
+
+import (
+	"net/http"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+)
+
+var Authz *authz.Client
+
+// Authorize requires relation on object for the request's user.
+func Authorize(r *http.Request, relation, object string) error {
+	return Authz.Require(r.Context(), r.Header.Get("X-User"), relation, object)
+}
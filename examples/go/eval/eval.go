@@ -0,0 +1,324 @@
+// Package eval is an embedded, in-process evaluator for authorization
+// models. It answers Check and ListObjects from an in-memory TupleStore and
+// implements authz.Backend, so it can stand in for a server in tests and
+// offline tooling.
+//
+// Conditions are not interpreted: tuples are matched against type
+// restrictions by type, relation and wildcard only.
+package eval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// DefaultMaxDepth matches the server's default resolution depth.
+const DefaultMaxDepth = 25
+
+var (
+	// ErrUnknownRelation is returned for a relation the model does not define.
+	ErrUnknownRelation = errors.New("eval: unknown relation")
+	// ErrDepthExceeded is returned when resolution nests deeper than MaxDepth.
+	ErrDepthExceeded = errors.New("eval: resolution depth exceeded")
+)
+
+// Evaluator resolves checks against a model and a tuple store.
+type Evaluator struct {
+	model *model.Model
+	store *TupleStore
+	// MaxDepth bounds nested resolution; zero means DefaultMaxDepth.
+	MaxDepth int
+}
+
+// New returns an evaluator over m and store. A nil store starts empty.
+func New(m *model.Model, store *TupleStore) *Evaluator {
+	if store == nil {
+		store = NewTupleStore()
+	}
+	return &Evaluator{model: m, store: store}
+}
+
+// Model returns the evaluator's model.
+func (e *Evaluator) Model() *model.Model { return e.model }
+
+// Store returns the evaluator's tuple store.
+func (e *Evaluator) Store() *TupleStore { return e.store }
+
+// Write implements authz.Backend.
+func (e *Evaluator) Write(ctx context.Context, writes, deletes []authz.Tuple) error {
+	return e.store.Write(writes, deletes)
+}
+
+// ReadModel implements authz.Backend. The evaluator holds a single model,
+// which is returned for any id.
+func (e *Evaluator) ReadModel(ctx context.Context, id string) (*model.Model, error) {
+	return e.model, nil
+}
+
+// GetStore implements authz.Backend with a fixed description of the
+// in-process store.
+func (e *Evaluator) GetStore(ctx context.Context) (authz.Store, error) {
+	return authz.Store{ID: "embedded", Name: "embedded"}, nil
+}
+
+// ReadPageSize is the number of tuples Read returns per page.
+const ReadPageSize = 100
+
+// Read implements authz.Backend with the server's filter semantics. The
+// continuation token is the offset of the next page.
+func (e *Evaluator) Read(ctx context.Context, filter authz.Tuple, continuationToken string) ([]authz.Tuple, string, error) {
+	offset := 0
+	if continuationToken != "" {
+		if _, err := fmt.Sscanf(continuationToken, "%d", &offset); err != nil {
+			return nil, "", fmt.Errorf("eval: invalid continuation token %q", continuationToken)
+		}
+	}
+	var matched []authz.Tuple
+	for _, t := range e.store.Tuples() {
+		if filter.Matches(t) {
+			matched = append(matched, t)
+		}
+	}
+	if offset >= len(matched) {
+		return nil, "", nil
+	}
+	end := min(offset+ReadPageSize, len(matched))
+	next := ""
+	if end < len(matched) {
+		next = fmt.Sprint(end)
+	}
+	return matched[offset:end], next, nil
+}
+
+// ReadChanges implements authz.Backend over the store's change log. The
+// continuation token is the offset of the next change; at the end of the
+// log it stays put so callers can poll.
+func (e *Evaluator) ReadChanges(ctx context.Context, objectType, continuationToken string) ([]authz.Change, string, error) {
+	offset := 0
+	if continuationToken != "" {
+		if _, err := fmt.Sscanf(continuationToken, "%d", &offset); err != nil {
+			return nil, "", fmt.Errorf("eval: invalid continuation token %q", continuationToken)
+		}
+	}
+	log := e.store.Changes()
+	if offset > len(log) {
+		offset = len(log)
+	}
+	var page []authz.Change
+	i := offset
+	for ; i < len(log) && len(page) < ReadPageSize; i++ {
+		if objectType == "" || strings.HasPrefix(log[i].Tuple.Object, objectType+":") {
+			page = append(page, log[i])
+		}
+	}
+	return page, fmt.Sprint(i), nil
+}
+
+// Check implements authz.Backend.
+func (e *Evaluator) Check(ctx context.Context, req authz.CheckRequest) (bool, error) {
+	r := &resolver{ctx: ctx, e: e, visiting: map[string]bool{}, maxDepth: e.MaxDepth}
+	if r.maxDepth == 0 {
+		r.maxDepth = DefaultMaxDepth
+	}
+	return r.check(req.User, req.Relation, req.Object, 0)
+}
+
+// ListObjects returns every object of objectType on which user has
+// relation. Candidates are the objects of that type present in the store.
+func (e *Evaluator) ListObjects(ctx context.Context, user, relation, objectType string) ([]string, error) {
+	if e.model.Relation(objectType, relation) == nil {
+		return nil, fmt.Errorf("%w: %s#%s", ErrUnknownRelation, objectType, relation)
+	}
+	var out []string
+	for _, obj := range e.store.Objects(objectType) {
+		ok, err := e.Check(ctx, authz.CheckRequest{User: user, Relation: relation, Object: obj})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out = append(out, obj)
+		}
+	}
+	return out, nil
+}
+
+// ListUsers implements authz.Backend. Candidates are the objects of each
+// filter type present in the store, plus the type's wildcard; usersets are
+// tried for filters of the form "type#relation".
+func (e *Evaluator) ListUsers(ctx context.Context, object, relation string, userFilters []string) ([]string, error) {
+	typ, _ := splitObject(object)
+	if e.model.Relation(typ, relation) == nil {
+		return nil, fmt.Errorf("%w: %s#%s", ErrUnknownRelation, typ, relation)
+	}
+	var out []string
+	for _, f := range userFilters {
+		userType, userRel, isUserset := strings.Cut(f, "#")
+		candidates := e.store.Objects(userType)
+		if isUserset {
+			for i, c := range candidates {
+				candidates[i] = c + "#" + userRel
+			}
+		} else {
+			candidates = append(candidates, userType+":*")
+		}
+		for _, u := range candidates {
+			ok, err := e.Check(ctx, authz.CheckRequest{User: u, Relation: relation, Object: object})
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				out = append(out, u)
+			}
+		}
+	}
+	return out, nil
+}
+
+type resolver struct {
+	ctx      context.Context
+	e        *Evaluator
+	visiting map[string]bool
+	maxDepth int
+}
+
+func (r *resolver) check(user, relation, object string, depth int) (bool, error) {
+	if err := r.ctx.Err(); err != nil {
+		return false, err
+	}
+	typ, _ := splitObject(object)
+	rel := r.e.model.Relation(typ, relation)
+	if rel == nil {
+		return false, fmt.Errorf("%w: %s#%s", ErrUnknownRelation, typ, relation)
+	}
+	if depth > r.maxDepth {
+		return false, fmt.Errorf("%w: %s#%s@%s", ErrDepthExceeded, object, relation, user)
+	}
+	key := object + "#" + relation + "@" + user
+	if r.visiting[key] {
+		// A cycle contributes nothing beyond what the other branches find.
+		return false, nil
+	}
+	r.visiting[key] = true
+	defer delete(r.visiting, key)
+	return r.rewrite(rel.Rewrite, user, relation, object, depth)
+}
+
+func (r *resolver) rewrite(rw model.Rewrite, user, relation, object string, depth int) (bool, error) {
+	switch n := rw.(type) {
+	case *model.Direct:
+		return r.direct(n, user, relation, object, depth)
+	case *model.Computed:
+		return r.check(user, n.Relation, object, depth+1)
+	case *model.TupleToUserset:
+		var firstErr error
+		for _, parent := range r.e.store.Users(object, n.Tupleset) {
+			if strings.Contains(parent, "#") || strings.HasSuffix(parent, ":*") {
+				continue
+			}
+			ptype, _ := splitObject(parent)
+			if r.e.model.Relation(ptype, n.Computed) == nil {
+				continue
+			}
+			ok, err := r.check(user, n.Computed, parent, depth+1)
+			if ok {
+				return true, nil
+			}
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		return false, firstErr
+	case *model.Union:
+		var firstErr error
+		for _, c := range n.Children {
+			ok, err := r.rewrite(c, user, relation, object, depth)
+			if ok {
+				return true, nil
+			}
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		return false, firstErr
+	case *model.Intersection:
+		for _, c := range n.Children {
+			ok, err := r.rewrite(c, user, relation, object, depth)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	case *model.Difference:
+		ok, err := r.rewrite(n.Base, user, relation, object, depth)
+		if err != nil || !ok {
+			return false, err
+		}
+		sub, err := r.rewrite(n.Subtract, user, relation, object, depth)
+		if err != nil {
+			return false, err
+		}
+		return !sub, nil
+	}
+	return false, fmt.Errorf("eval: unsupported rewrite %T", rw)
+}
+
+func (r *resolver) direct(d *model.Direct, user, relation, object string, depth int) (bool, error) {
+	userType, _ := splitObject(user)
+	_, userRel := splitUserset(user)
+	var firstErr error
+	for _, u := range r.e.store.Users(object, relation) {
+		ref := refOf(u)
+		if !allows(d, ref) {
+			continue
+		}
+		switch {
+		case u == user:
+			return true, nil
+		case ref.Wildcard:
+			if ref.Type == userType && userRel == "" {
+				return true, nil
+			}
+		case ref.Relation != "":
+			obj, rel := splitUserset(u)
+			ok, err := r.check(user, rel, obj, depth+1)
+			if ok {
+				return true, nil
+			}
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return false, firstErr
+}
+
+func allows(d *model.Direct, ref model.TypeRef) bool {
+	for _, t := range d.Types {
+		if t.Type == ref.Type && t.Relation == ref.Relation && t.Wildcard == ref.Wildcard {
+			return true
+		}
+	}
+	return false
+}
+
+// refOf describes the shape of a tuple user as a type restriction.
+func refOf(user string) model.TypeRef {
+	obj, rel := splitUserset(user)
+	typ, id := splitObject(obj)
+	return model.TypeRef{Type: typ, Relation: rel, Wildcard: id == "*"}
+}
+
+func splitObject(object string) (typ, id string) {
+	typ, id, _ = strings.Cut(object, ":")
+	return typ, id
+}
+
+func splitUserset(user string) (object, relation string) {
+	object, relation, _ = strings.Cut(user, "#")
+	return object, relation
+}
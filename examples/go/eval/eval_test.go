@@ -0,0 +1,171 @@
+package eval_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/eval"
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+var testModel = model.MustParse(`model
+  schema 1.1
+
+type user
+
+type group
+  relations
+    define member: [user, group#member]
+
+type folder
+  relations
+    define owner: [user]
+    define viewer: [user, group#member] or owner
+
+type document
+  relations
+    define parent: [folder]
+    define owner: [user]
+    define editor: [user] or owner
+    define blocked: [user]
+    define public: [user:*]
+    define viewer: [user, group#member] or editor or viewer from parent or public but not blocked
+    define approver: [user]
+    define can_publish: editor and approver
+    define loop: loop
+`)
+
+func tuple(user, relation, object string) authz.Tuple {
+	return authz.Tuple{User: user, Relation: relation, Object: object}
+}
+
+func newEvaluator() *eval.Evaluator {
+	return eval.New(testModel, eval.NewTupleStore(
+		tuple("user:anne", "member", "group:eng"),
+		tuple("group:eng#member", "member", "group:staff"),
+		tuple("user:beth", "owner", "folder:plans"),
+		tuple("group:staff#member", "viewer", "folder:plans"),
+		tuple("folder:plans", "parent", "document:roadmap"),
+		tuple("user:carl", "owner", "document:roadmap"),
+		tuple("user:carl", "approver", "document:roadmap"),
+		tuple("user:dana", "editor", "document:roadmap"),
+		tuple("user:anne", "blocked", "document:roadmap"),
+		tuple("user:*", "public", "document:faq"),
+		tuple("user:erin", "blocked", "document:faq"),
+	))
+}
+
+func TestCheck(t *testing.T) {
+	ev := newEvaluator()
+	tests := []struct {
+		name     string
+		user     string
+		relation string
+		object   string
+		want     bool
+	}{
+		{"direct", "user:carl", "owner", "document:roadmap", true},
+		{"no tuple", "user:dana", "owner", "document:roadmap", false},
+		{"computed", "user:carl", "editor", "document:roadmap", true},
+		{"nested userset", "user:anne", "member", "group:staff", true},
+		{"userset as user", "group:eng#member", "member", "group:staff", true},
+		{"tuple to userset", "user:beth", "viewer", "document:roadmap", true},
+		{"tuple to userset through group", "user:fred", "viewer", "document:roadmap", false},
+		{"exclusion", "user:anne", "viewer", "document:roadmap", false},
+		{"wildcard", "user:zoe", "viewer", "document:faq", true},
+		{"wildcard with exclusion", "user:erin", "viewer", "document:faq", false},
+		{"wildcard is not a userset", "group:eng#member", "public", "document:faq", false},
+		{"intersection", "user:carl", "can_publish", "document:roadmap", true},
+		{"intersection missing one side", "user:dana", "can_publish", "document:roadmap", false},
+		{"cycle", "user:carl", "loop", "document:roadmap", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ev.Check(context.Background(), authz.CheckRequest{User: tt.user, Relation: tt.relation, Object: tt.object})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Check(%s, %s, %s) = %v, want %v", tt.user, tt.relation, tt.object, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckErrors(t *testing.T) {
+	ev := newEvaluator()
+	_, err := ev.Check(context.Background(), authz.CheckRequest{User: "user:anne", Relation: "admin", Object: "document:roadmap"})
+	if !errors.Is(err, eval.ErrUnknownRelation) {
+		t.Errorf("unknown relation: error = %v, want ErrUnknownRelation", err)
+	}
+
+	ev.MaxDepth = 1
+	_, err = ev.Check(context.Background(), authz.CheckRequest{User: "user:beth", Relation: "viewer", Object: "document:roadmap"})
+	if !errors.Is(err, eval.ErrDepthExceeded) {
+		t.Errorf("MaxDepth 1: error = %v, want ErrDepthExceeded", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ev.MaxDepth = 0
+	if _, err := ev.Check(ctx, authz.CheckRequest{User: "user:carl", Relation: "owner", Object: "document:roadmap"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled context: error = %v, want context.Canceled", err)
+	}
+}
+
+func TestListObjects(t *testing.T) {
+	ev := newEvaluator()
+	tests := []struct {
+		user     string
+		relation string
+		typ      string
+		want     []string
+	}{
+		{"user:beth", "viewer", "document", []string{"document:faq", "document:roadmap"}},
+		{"user:anne", "viewer", "document", []string{"document:faq"}},
+		{"user:erin", "viewer", "document", nil},
+		{"user:anne", "viewer", "folder", []string{"folder:plans"}},
+	}
+	for _, tt := range tests {
+		got, err := ev.ListObjects(context.Background(), tt.user, tt.relation, tt.typ)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ListObjects(%s, %s, %s) = %v, want %v", tt.user, tt.relation, tt.typ, got, tt.want)
+		}
+	}
+	if _, err := ev.ListObjects(context.Background(), "user:anne", "admin", "document"); !errors.Is(err, eval.ErrUnknownRelation) {
+		t.Errorf("unknown relation: error = %v, want ErrUnknownRelation", err)
+	}
+}
+
+func TestListUsers(t *testing.T) {
+	ev := newEvaluator()
+	tests := []struct {
+		object   string
+		relation string
+		filters  []string
+		want     []string
+	}{
+		{"document:roadmap", "editor", []string{"user"}, []string{"user:carl", "user:dana"}},
+		// Every known user the wildcard covers, except the blocked one.
+		{"document:faq", "viewer", []string{"user"}, []string{"user:*", "user:anne", "user:beth", "user:carl", "user:dana"}},
+		{"group:staff", "member", []string{"group#member"}, []string{"group:eng#member"}},
+	}
+	for _, tt := range tests {
+		got, err := ev.ListUsers(context.Background(), tt.object, tt.relation, tt.filters)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ListUsers(%s, %s, %v) = %v, want %v", tt.object, tt.relation, tt.filters, got, tt.want)
+		}
+	}
+}
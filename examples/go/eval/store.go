@@ -0,0 +1,170 @@
+package eval
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+)
+
+var (
+	// ErrTupleExists mirrors the server rejecting a write of an existing tuple.
+	ErrTupleExists = errors.New("eval: tuple already exists")
+	// ErrTupleNotFound mirrors the server rejecting a delete of a missing tuple.
+	ErrTupleNotFound = errors.New("eval: tuple does not exist")
+)
+
+// TupleStore is an in-memory, indexed tuple set. It is safe for concurrent
+// use. Like the server it holds one tuple per key (see authz.Tuple.Key);
+// conditions are kept with their tuples but not evaluated.
+type TupleStore struct {
+	mu     sync.RWMutex
+	tuples map[authz.Tuple]authz.Tuple    // key → tuple with its condition
+	users  map[string]map[string]struct{} // object#relation → users
+	log    []authz.Change
+}
+
+// NewTupleStore returns a store holding tuples. Duplicates are ignored.
+func NewTupleStore(tuples ...authz.Tuple) *TupleStore {
+	s := &TupleStore{tuples: map[authz.Tuple]authz.Tuple{}, users: map[string]map[string]struct{}{}}
+	for _, t := range tuples {
+		s.add(t)
+	}
+	return s
+}
+
+// Write applies deletes and then writes atomically, failing as the server
+// does when a written tuple exists or a deleted one does not.
+func (s *TupleStore) Write(writes, deletes []authz.Tuple) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pending := map[authz.Tuple]bool{}
+	for _, t := range deletes {
+		if _, ok := s.tuples[t.Key()]; !ok || pending[t.Key()] {
+			return fmt.Errorf("%w: %s", ErrTupleNotFound, t)
+		}
+		pending[t.Key()] = true
+	}
+	for _, t := range writes {
+		if _, ok := s.tuples[t.Key()]; ok && !pending[t.Key()] {
+			return fmt.Errorf("%w: %s", ErrTupleExists, t)
+		}
+	}
+	now := time.Now().UTC()
+	for _, t := range deletes {
+		s.remove(t)
+		s.log = append(s.log, authz.Change{Tuple: t, Operation: authz.OpDelete, Timestamp: now})
+	}
+	for _, t := range writes {
+		s.add(t)
+		s.log = append(s.log, authz.Change{Tuple: t, Operation: authz.OpWrite, Timestamp: now})
+	}
+	return nil
+}
+
+// Changes returns the changes applied through Write, oldest first. Tuples
+// passed to NewTupleStore are initial state and not part of the log.
+func (s *TupleStore) Changes() []authz.Change {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]authz.Change(nil), s.log...)
+}
+
+func (s *TupleStore) add(t authz.Tuple) {
+	s.tuples[t.Key()] = t
+	key := t.Object + "#" + t.Relation
+	if s.users[key] == nil {
+		s.users[key] = map[string]struct{}{}
+	}
+	s.users[key][t.User] = struct{}{}
+}
+
+func (s *TupleStore) remove(t authz.Tuple) {
+	delete(s.tuples, t.Key())
+	key := t.Object + "#" + t.Relation
+	delete(s.users[key], t.User)
+	if len(s.users[key]) == 0 {
+		delete(s.users, key)
+	}
+}
+
+// Has reports whether a tuple with t's key is stored.
+func (s *TupleStore) Has(t authz.Tuple) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.tuples[t.Key()]
+	return ok
+}
+
+// Users returns the users directly related to object by relation, sorted.
+func (s *TupleStore) Users(object, relation string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	set := s.users[object+"#"+relation]
+	users := make([]string, 0, len(set))
+	for u := range set {
+		users = append(users, u)
+	}
+	sort.Strings(users)
+	return users
+}
+
+// Tuples returns every stored tuple sorted by object, relation and user.
+func (s *TupleStore) Tuples() []authz.Tuple {
+	s.mu.RLock()
+	out := make([]authz.Tuple, 0, len(s.tuples))
+	for _, t := range s.tuples {
+		out = append(out, t)
+	}
+	s.mu.RUnlock()
+	SortTuples(out)
+	return out
+}
+
+// Objects returns every object of objectType that appears in a tuple,
+// either as the object or as (part of) the user, sorted.
+func (s *TupleStore) Objects(objectType string) []string {
+	prefix := objectType + ":"
+	set := map[string]struct{}{}
+	s.mu.RLock()
+	for t := range s.tuples {
+		if strings.HasPrefix(t.Object, prefix) {
+			set[t.Object] = struct{}{}
+		}
+		if obj, _ := splitUserset(t.User); strings.HasPrefix(obj, prefix) && !strings.HasSuffix(obj, ":*") {
+			set[obj] = struct{}{}
+		}
+	}
+	s.mu.RUnlock()
+	out := make([]string, 0, len(set))
+	for o := range set {
+		out = append(out, o)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Len returns the number of stored tuples.
+func (s *TupleStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.tuples)
+}
+
+// SortTuples orders tuples by object, relation and user.
+func SortTuples(ts []authz.Tuple) {
+	sort.Slice(ts, func(i, j int) bool {
+		a, b := ts[i], ts[j]
+		if a.Object != b.Object {
+			return a.Object < b.Object
+		}
+		if a.Relation != b.Relation {
+			return a.Relation < b.Relation
+		}
+		return a.User < b.User
+	})
+}
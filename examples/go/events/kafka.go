@@ -0,0 +1,189 @@
+package events
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/writequeue"
+)
+
+// Message is a broker record.
+type Message struct {
+	Topic     string
+	Partition int
+	Offset    int64
+	Key       []byte
+	Value     []byte
+}
+
+// Reader is the consuming side of a broker client. Its shape matches the
+// FetchMessage/CommitMessages pair of common Kafka libraries, so a thin
+// adapter is all that is needed.
+type Reader interface {
+	FetchMessage(ctx context.Context) (Message, error)
+	CommitMessages(ctx context.Context, msgs ...Message) error
+}
+
+// Deduper remembers which event IDs were already applied.
+type Deduper interface {
+	Seen(ctx context.Context, id string) (bool, error)
+	Mark(ctx context.Context, id string) error
+}
+
+// MemoryDeduper keeps the most recent Size event IDs in memory. It covers
+// redeliveries after a rebalance; use a persistent Deduper for exactly-once
+// across restarts.
+type MemoryDeduper struct {
+	Size int
+
+	mu    sync.Mutex
+	order *list.List
+	ids   map[string]*list.Element
+}
+
+func (d *MemoryDeduper) Seen(_ context.Context, id string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, ok := d.ids[id]
+	return ok, nil
+}
+
+func (d *MemoryDeduper) Mark(_ context.Context, id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.ids == nil {
+		d.ids = make(map[string]*list.Element)
+		d.order = list.New()
+	}
+	if _, ok := d.ids[id]; ok {
+		return nil
+	}
+	d.ids[id] = d.order.PushBack(id)
+	size := d.Size
+	if size <= 0 {
+		size = 10000
+	}
+	for d.order.Len() > size {
+		oldest := d.order.Front()
+		d.order.Remove(oldest)
+		delete(d.ids, oldest.Value.(string))
+	}
+	return nil
+}
+
+// Consumer applies mutation events from a Reader to a backend. Each message
+// is committed only after it was applied, skipped as a duplicate, or handed
+// to OnFailed, so a crash leads to redelivery rather than loss.
+type Consumer struct {
+	Reader  Reader
+	Backend authz.Backend
+	// Decoder defaults to JSONDecoder{}.
+	Decoder Decoder
+	// Dedup defaults to a MemoryDeduper.
+	Dedup Deduper
+	// Retries is how many times a retryable failure is retried before the
+	// message counts as failed; default 5. Backoff doubles from
+	// InitialBackoff (default 200ms) up to 30s.
+	Retries        int
+	InitialBackoff time.Duration
+	// IsRetryable defaults to writequeue.Unavailable.
+	IsRetryable func(error) bool
+	// OnFailed receives messages that could not be decoded or applied, e.g.
+	// to forward them to a dead-letter topic. When nil, Run stops and
+	// returns the error without committing.
+	OnFailed func(Message, error)
+	// Logger receives per-message events; nil disables logging.
+	Logger *slog.Logger
+}
+
+// Run consumes until ctx is done or a message fails without OnFailed.
+func (c *Consumer) Run(ctx context.Context) error {
+	if c.Dedup == nil {
+		c.Dedup = &MemoryDeduper{}
+	}
+	for {
+		msg, err := c.Reader.FetchMessage(ctx)
+		if err != nil {
+			return err
+		}
+		if err := c.Handle(ctx, msg); err != nil {
+			if c.OnFailed == nil {
+				return err
+			}
+			c.logf(ctx, slog.LevelWarn, "mutation failed", "topic", msg.Topic, "offset", msg.Offset, "error", err)
+			c.OnFailed(msg, err)
+		}
+		if err := c.Reader.CommitMessages(ctx, msg); err != nil {
+			return fmt.Errorf("events: commit offset %d: %w", msg.Offset, err)
+		}
+	}
+}
+
+// Handle decodes and applies a single message, retrying retryable errors.
+func (c *Consumer) Handle(ctx context.Context, msg Message) error {
+	dec := c.Decoder
+	if dec == nil {
+		dec = JSONDecoder{}
+	}
+	m, err := dec.Decode(msg)
+	if err != nil {
+		return err
+	}
+	if c.Dedup != nil {
+		seen, err := c.Dedup.Seen(ctx, m.ID)
+		if err != nil {
+			return fmt.Errorf("events: dedup: %w", err)
+		}
+		if seen {
+			c.logf(ctx, slog.LevelDebug, "duplicate mutation skipped", "id", m.ID)
+			return nil
+		}
+	}
+	if err := c.apply(ctx, m); err != nil {
+		return fmt.Errorf("events: apply %s: %w", m.ID, err)
+	}
+	c.logf(ctx, slog.LevelDebug, "mutation applied", "id", m.ID, "writes", len(m.Writes), "deletes", len(m.Deletes))
+	if c.Dedup != nil {
+		return c.Dedup.Mark(ctx, m.ID)
+	}
+	return nil
+}
+
+func (c *Consumer) apply(ctx context.Context, m Mutation) error {
+	retries := c.Retries
+	if retries <= 0 {
+		retries = 5
+	}
+	backoff := c.InitialBackoff
+	if backoff <= 0 {
+		backoff = 200 * time.Millisecond
+	}
+	retryable := c.IsRetryable
+	if retryable == nil {
+		retryable = writequeue.Unavailable
+	}
+	for attempt := 0; ; attempt++ {
+		err := c.Backend.Write(ctx, m.Writes, m.Deletes)
+		if err == nil || attempt >= retries || !retryable(err) {
+			return err
+		}
+		c.logf(ctx, slog.LevelDebug, "retrying mutation", "id", m.ID, "attempt", attempt+1, "error", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, 30*time.Second)
+	}
+}
+
+func (c *Consumer) logf(ctx context.Context, level slog.Level, msg string, args ...interface{}) {
+	if c.Logger != nil {
+		c.Logger.Log(ctx, level, msg, args...)
+	}
+}
@@ -0,0 +1,60 @@
+// Package events connects tuple mutations to message brokers. Consumers read
+// mutation events and apply them to OpenFGA; publishers emit change events
+// for other services. Broker clients are supplied by the caller through
+// small interfaces, so the package carries no broker dependency.
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+)
+
+// ErrNoID is returned by a decoder when an event has no ID to deduplicate on.
+var ErrNoID = errors.New("events: mutation has no id")
+
+// Mutation is one event asking for tuples to be written and deleted
+// atomically.
+type Mutation struct {
+	ID      string        `json:"id"`
+	Writes  []authz.Tuple `json:"writes,omitempty"`
+	Deletes []authz.Tuple `json:"deletes,omitempty"`
+	Time    time.Time     `json:"time,omitempty"`
+}
+
+// Decoder turns a message payload into a Mutation. Supply one for Avro,
+// Protobuf or a custom JSON layout; JSONDecoder handles the default schema.
+type Decoder interface {
+	Decode(msg Message) (Mutation, error)
+}
+
+// DecoderFunc adapts a function to Decoder.
+type DecoderFunc func(Message) (Mutation, error)
+
+func (f DecoderFunc) Decode(msg Message) (Mutation, error) { return f(msg) }
+
+// JSONDecoder decodes the Mutation JSON schema:
+//
+//	{"id":"evt-1","writes":[{"user":"user:alice","relation":"viewer","object":"doc:1"}]}
+//
+// When the payload has no id, the message key is used if UseKeyAsID is set.
+type JSONDecoder struct {
+	UseKeyAsID bool
+}
+
+func (d JSONDecoder) Decode(msg Message) (Mutation, error) {
+	var m Mutation
+	if err := json.Unmarshal(msg.Value, &m); err != nil {
+		return Mutation{}, fmt.Errorf("events: decode: %w", err)
+	}
+	if m.ID == "" && d.UseKeyAsID {
+		m.ID = string(msg.Key)
+	}
+	if m.ID == "" {
+		return Mutation{}, ErrNoID
+	}
+	return m, nil
+}
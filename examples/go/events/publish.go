@@ -0,0 +1,216 @@
+package events
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+)
+
+// SchemaVersion is the version of the ChangeEvent schema. Fields are only
+// ever added; a breaking change bumps the version.
+const SchemaVersion = "1"
+
+// Change event types.
+const (
+	TypeTupleWritten = "fga.tuple.written"
+	TypeTupleDeleted = "fga.tuple.deleted"
+)
+
+// ChangeEvent is the published form of one authz.Change. The ID is derived
+// from the change itself, so republishing after a crash yields the same ID
+// and consumers can deduplicate.
+type ChangeEvent struct {
+	SchemaVersion string    `json:"schema_version"`
+	ID            string    `json:"id"`
+	Type          string    `json:"type"`
+	StoreID       string    `json:"store_id,omitempty"`
+	User          string    `json:"user"`
+	Relation      string    `json:"relation"`
+	Object        string    `json:"object"`
+	ObjectType    string    `json:"object_type"`
+	Time          time.Time `json:"time"`
+}
+
+// NewChangeEvent normalizes c into a ChangeEvent.
+func NewChangeEvent(storeID string, c authz.Change) ChangeEvent {
+	typ := TypeTupleWritten
+	if c.Operation == authz.OpDelete {
+		typ = TypeTupleDeleted
+	}
+	objectType, _, _ := strings.Cut(c.Tuple.Object, ":")
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%s|%d", storeID, c.Operation, c.Tuple, c.Timestamp.UnixNano())))
+	return ChangeEvent{
+		SchemaVersion: SchemaVersion,
+		ID:            hex.EncodeToString(sum[:16]),
+		Type:          typ,
+		StoreID:       storeID,
+		User:          c.Tuple.User,
+		Relation:      c.Tuple.Relation,
+		Object:        c.Tuple.Object,
+		ObjectType:    objectType,
+		Time:          c.Timestamp.UTC(),
+	}
+}
+
+// Publisher is the producing side of a broker client: a NATS subject or a
+// Kafka topic, with a key used for partitioning.
+type Publisher interface {
+	Publish(ctx context.Context, subject string, key, data []byte) error
+}
+
+// PublisherFunc adapts a function to Publisher.
+type PublisherFunc func(ctx context.Context, subject string, key, data []byte) error
+
+func (f PublisherFunc) Publish(ctx context.Context, subject string, key, data []byte) error {
+	return f(ctx, subject, key, data)
+}
+
+// Cursor persists the changes-feed position between runs.
+type Cursor interface {
+	Load(ctx context.Context) (string, error)
+	Save(ctx context.Context, token string) error
+}
+
+// FileCursor stores the continuation token in a file, replaced atomically.
+type FileCursor string
+
+func (f FileCursor) Load(context.Context) (string, error) {
+	b, err := os.ReadFile(string(f))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	return strings.TrimSpace(string(b)), err
+}
+
+func (f FileCursor) Save(_ context.Context, token string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(string(f)), ".cursor-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(token); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), string(f))
+}
+
+// Feed publishes the backend's changes feed. Delivery is at-least-once: the
+// cursor is saved after each page is published.
+type Feed struct {
+	Backend   authz.Backend
+	Publisher Publisher
+	// ObjectType limits the feed to one type; empty means all types.
+	ObjectType string
+	// Cursor defaults to an in-memory position starting at the beginning.
+	Cursor Cursor
+	// Subject picks the subject or topic for an event. The default is
+	// "fga.changes.<object_type>". Events are keyed by object so a
+	// partitioned broker keeps per-object order.
+	Subject func(ChangeEvent) string
+	Logger  *slog.Logger
+
+	token   string
+	storeID string
+}
+
+// Poll publishes every change since the saved cursor and returns how many
+// were published.
+func (f *Feed) Poll(ctx context.Context) (int, error) {
+	token := f.token
+	if f.Cursor != nil {
+		t, err := f.Cursor.Load(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("events: load cursor: %w", err)
+		}
+		token = t
+	}
+	if f.storeID == "" {
+		if s, err := f.Backend.GetStore(ctx); err == nil {
+			f.storeID = s.ID
+		}
+	}
+	published := 0
+	for {
+		page, next, err := f.Backend.ReadChanges(ctx, f.ObjectType, token)
+		if err != nil {
+			return published, fmt.Errorf("events: read changes: %w", err)
+		}
+		for _, c := range page {
+			ev := NewChangeEvent(f.storeID, c)
+			data, err := json.Marshal(ev)
+			if err != nil {
+				return published, err
+			}
+			if err := f.Publisher.Publish(ctx, f.subject(ev), []byte(ev.Object), data); err != nil {
+				return published, fmt.Errorf("events: publish %s: %w", ev.ID, err)
+			}
+			published++
+		}
+		if next != "" && next != token {
+			if err := f.save(ctx, next); err != nil {
+				return published, err
+			}
+		}
+		if len(page) == 0 || next == "" || next == token {
+			return published, nil
+		}
+		token = next
+	}
+}
+
+// Run polls every interval until ctx is done.
+func (f *Feed) Run(ctx context.Context, interval time.Duration) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		n, err := f.Poll(ctx)
+		if f.Logger != nil {
+			if err != nil {
+				f.Logger.WarnContext(ctx, "change feed poll failed", "published", n, "error", err)
+			} else if n > 0 {
+				f.Logger.DebugContext(ctx, "changes published", "count", n)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+func (f *Feed) save(ctx context.Context, token string) error {
+	f.token = token
+	if f.Cursor == nil {
+		return nil
+	}
+	if err := f.Cursor.Save(ctx, token); err != nil {
+		return fmt.Errorf("events: save cursor: %w", err)
+	}
+	return nil
+}
+
+func (f *Feed) subject(ev ChangeEvent) string {
+	if f.Subject != nil {
+		return f.Subject(ev)
+	}
+	return "fga.changes." + ev.ObjectType
+}
@@ -0,0 +1,211 @@
+package fgatest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// Mode selects whether a Cassette talks to a real server or serves a golden
+// file.
+type Mode int
+
+const (
+	// Replay serves recorded responses and fails on unknown requests.
+	Replay Mode = iota
+	// Record forwards requests to the real transport and captures them.
+	Record
+)
+
+// RecordEnv is the environment variable ModeFromEnv consults.
+const RecordEnv = "FGATEST_RECORD"
+
+// ModeFromEnv returns Record when FGATEST_RECORD is set to a non-empty value
+// other than "0", and Replay otherwise.
+func ModeFromEnv() Mode {
+	if v := os.Getenv(RecordEnv); v != "" && v != "0" {
+		return Record
+	}
+	return Replay
+}
+
+// Interaction is one captured request/response pair.
+type Interaction struct {
+	Method       string          `json:"method"`
+	Path         string          `json:"path"`
+	Query        string          `json:"query,omitempty"`
+	RequestBody  json.RawMessage `json:"request_body,omitempty"`
+	Status       int             `json:"status"`
+	ResponseBody json.RawMessage `json:"response_body,omitempty"`
+}
+
+// Cassette is an http.RoundTripper that records OpenFGA API traffic to a
+// golden file or replays it, so model-dependent tests run offline. Install it
+// through client.ClientConfiguration.HTTPClient:
+//
+//	cas, err := fgatest.OpenCassette("testdata/check.json", fgatest.ModeFromEnv(), nil)
+//	...
+//	defer cas.Close()
+//	fga, err := client.NewSdkClient(&client.ClientConfiguration{
+//		ApiUrl:     "http://localhost:8080",
+//		StoreId:    storeID,
+//		HTTPClient: cas.HTTPClient(),
+//	})
+//
+// Requests match on method, path, query string with its parameters sorted,
+// and canonical JSON body. Identical requests
+// are answered in the order they were recorded.
+type Cassette struct {
+	mode Mode
+	path string
+	next http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// ErrNoInteraction is returned in Replay mode for a request that was never
+// recorded, or whose recordings have all been consumed.
+var ErrNoInteraction = errors.New("fgatest: no recorded interaction")
+
+// OpenCassette loads path in Replay mode, or starts an empty recording in
+// Record mode. next is the transport used while recording and defaults to
+// http.DefaultTransport.
+func OpenCassette(path string, mode Mode, next http.RoundTripper) (*Cassette, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	c := &Cassette{mode: mode, path: path, next: next}
+	if mode == Record {
+		return c, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("fgatest: open cassette: %w", err)
+	}
+	if err := json.Unmarshal(data, &c.interactions); err != nil {
+		return nil, fmt.Errorf("fgatest: decode cassette %s: %w", path, err)
+	}
+	c.used = make([]bool, len(c.interactions))
+	return c, nil
+}
+
+// HTTPClient returns an *http.Client using the cassette as its transport.
+func (c *Cassette) HTTPClient() *http.Client {
+	return &http.Client{Transport: c}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	if c.mode == Record {
+		return c.record(req, body)
+	}
+	return c.replay(req, body)
+}
+
+func (c *Cassette) record(req *http.Request, body []byte) (*http.Response, error) {
+	resp, err := c.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.interactions = append(c.interactions, Interaction{
+		Method:       req.Method,
+		Path:         req.URL.Path,
+		Query:        req.URL.Query().Encode(),
+		RequestBody:  canonicalJSON(body),
+		Status:       resp.StatusCode,
+		ResponseBody: canonicalJSON(respBody),
+	})
+	c.mu.Unlock()
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	return resp, nil
+}
+
+func (c *Cassette) replay(req *http.Request, body []byte) (*http.Response, error) {
+	want := string(canonicalJSON(body))
+	query := req.URL.Query().Encode()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, in := range c.interactions {
+		if c.used[i] || in.Method != req.Method || in.Path != req.URL.Path || in.Query != query || string(in.RequestBody) != want {
+			continue
+		}
+		c.used[i] = true
+		return &http.Response{
+			Status:     fmt.Sprintf("%d %s", in.Status, http.StatusText(in.Status)),
+			StatusCode: in.Status,
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(bytes.NewReader(in.ResponseBody)),
+			Request:    req,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: %s %s %s", ErrNoInteraction, req.Method, req.URL.RequestURI(), want)
+}
+
+// Interactions returns a copy of the recorded or loaded interactions.
+func (c *Cassette) Interactions() []Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Interaction(nil), c.interactions...)
+}
+
+// Close writes the golden file when recording. It is a no-op in Replay mode.
+func (c *Cassette) Close() error {
+	if c.mode != Record {
+		return nil
+	}
+	c.mu.Lock()
+	data, err := json.MarshalIndent(c.interactions, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, append(data, '\n'), 0o644)
+}
+
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// canonicalJSON re-encodes body with sorted keys so semantically equal
+// payloads compare equal. Non-JSON bodies are returned as a JSON string.
+func canonicalJSON(body []byte) json.RawMessage {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		quoted, _ := json.Marshal(string(body))
+		return quoted
+	}
+	out, _ := json.Marshal(v)
+	return out
+}
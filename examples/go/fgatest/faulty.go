@@ -0,0 +1,193 @@
+// Package fgatest provides test doubles and fixtures for code built on
+// package authz.
+package fgatest
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// StatusError is an injected HTTP failure. It exposes ResponseStatusCode like
+// the SDK's API errors so retry and fail-open logic can treat both alike.
+type StatusError struct {
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("fgatest: injected %d %s", e.Code, http.StatusText(e.Code))
+}
+
+// ResponseStatusCode returns the injected HTTP status.
+func (e *StatusError) ResponseStatusCode() int {
+	return e.Code
+}
+
+// Faults sets the probability, between 0 and 1, of each injected failure.
+// Rates are evaluated in the order the fields are declared and at most one
+// failure is injected per call.
+type Faults struct {
+	// Latency is added before the call with probability LatencyRate.
+	Latency     time.Duration
+	LatencyRate float64
+	// TimeoutRate makes the call block until the context is done, or until
+	// Timeout elapses, and then fail with context.DeadlineExceeded. Without
+	// a Timeout or a context deadline, the call blocks for
+	// DefaultInjectedTimeout.
+	TimeoutRate float64
+	Timeout     time.Duration
+	// RateLimitRate fails the call with a 429.
+	RateLimitRate float64
+	// ServerErrorRate fails the call with a 500, 502 or 503.
+	ServerErrorRate float64
+}
+
+// DefaultInjectedTimeout is how long an injected timeout blocks when
+// neither Faults.Timeout nor the context bounds it.
+const DefaultInjectedTimeout = 100 * time.Millisecond
+
+// FaultyClient decorates an authz.Backend with randomized, seeded failures
+// so tests can exercise retry and fail-open/fail-closed paths repeatably.
+type FaultyClient struct {
+	authz.Backend
+
+	mu     sync.Mutex
+	faults Faults
+	rng    *rand.Rand
+	calls  int
+	failed int
+}
+
+// NewFaultyClient wraps next. The same seed always yields the same sequence
+// of faults for the same sequence of calls.
+func NewFaultyClient(next authz.Backend, faults Faults, seed int64) *FaultyClient {
+	return &FaultyClient{Backend: next, faults: faults, rng: rand.New(rand.NewSource(seed))}
+}
+
+// SetFaults replaces the fault rates, e.g. to simulate recovery mid-test.
+func (f *FaultyClient) SetFaults(faults Faults) {
+	f.mu.Lock()
+	f.faults = faults
+	f.mu.Unlock()
+}
+
+// Stats returns the number of calls seen and how many had a failure injected.
+func (f *FaultyClient) Stats() (calls, failed int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls, f.failed
+}
+
+func (f *FaultyClient) Check(ctx context.Context, req authz.CheckRequest) (bool, error) {
+	if err := f.inject(ctx); err != nil {
+		return false, err
+	}
+	return f.Backend.Check(ctx, req)
+}
+
+func (f *FaultyClient) Write(ctx context.Context, writes, deletes []authz.Tuple) error {
+	if err := f.inject(ctx); err != nil {
+		return err
+	}
+	return f.Backend.Write(ctx, writes, deletes)
+}
+
+func (f *FaultyClient) Read(ctx context.Context, filter authz.Tuple, continuationToken string) ([]authz.Tuple, string, error) {
+	if err := f.inject(ctx); err != nil {
+		return nil, "", err
+	}
+	return f.Backend.Read(ctx, filter, continuationToken)
+}
+
+func (f *FaultyClient) ReadModel(ctx context.Context, id string) (*model.Model, error) {
+	if err := f.inject(ctx); err != nil {
+		return nil, err
+	}
+	return f.Backend.ReadModel(ctx, id)
+}
+
+func (f *FaultyClient) GetStore(ctx context.Context) (authz.Store, error) {
+	if err := f.inject(ctx); err != nil {
+		return authz.Store{}, err
+	}
+	return f.Backend.GetStore(ctx)
+}
+
+func (f *FaultyClient) ReadChanges(ctx context.Context, objectType, continuationToken string) ([]authz.Change, string, error) {
+	if err := f.inject(ctx); err != nil {
+		return nil, "", err
+	}
+	return f.Backend.ReadChanges(ctx, objectType, continuationToken)
+}
+
+func (f *FaultyClient) ListUsers(ctx context.Context, object, relation string, userFilters []string) ([]string, error) {
+	if err := f.inject(ctx); err != nil {
+		return nil, err
+	}
+	return f.Backend.ListUsers(ctx, object, relation, userFilters)
+}
+
+// inject draws the faults for one call, sleeping for added latency, and
+// returns the injected error if any.
+func (f *FaultyClient) inject(ctx context.Context) error {
+	f.mu.Lock()
+	faults := f.faults
+	f.calls++
+	delay := time.Duration(0)
+	if f.rng.Float64() < faults.LatencyRate {
+		delay = faults.Latency
+	}
+	var timeout bool
+	var err error
+	switch r := f.rng.Float64(); {
+	case r < faults.TimeoutRate:
+		timeout = true
+	case r < faults.TimeoutRate+faults.RateLimitRate:
+		err = &StatusError{Code: http.StatusTooManyRequests}
+	case r < faults.TimeoutRate+faults.RateLimitRate+faults.ServerErrorRate:
+		codes := []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable}
+		err = &StatusError{Code: codes[f.rng.Intn(len(codes))]}
+	}
+	if timeout || err != nil {
+		f.failed++
+	}
+	f.mu.Unlock()
+
+	if delay > 0 {
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+	if timeout {
+		d := faults.Timeout
+		if _, ok := ctx.Deadline(); !ok && d <= 0 {
+			d = DefaultInjectedTimeout
+		}
+		if d <= 0 {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		if err := sleep(ctx, d); err != nil {
+			return err
+		}
+		return fmt.Errorf("fgatest: injected timeout: %w", context.DeadlineExceeded)
+	}
+	return err
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
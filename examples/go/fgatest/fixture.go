@@ -0,0 +1,224 @@
+package fgatest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/eval"
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// Fixture builds the tuples of a test scenario against a model with a
+// fluent API. Each object method makes that object current; grant methods
+// give a user a relation on the current object:
+//
+//	f := fgatest.NewFixture(m).
+//		Org("acme").Admin("alice").
+//		Team("eng").Member("bob").
+//		Project("api").Owner("alice")
+//
+// A new object is linked to its parent automatically: the most recent
+// object whose type the new object's type has a direct relation to, so
+// Project("api") above writes project:api#team@team:eng, and Team("eng")
+// writes team:eng#organization@organization:acme. Bare user IDs get the
+// "user" type.
+//
+// Tuples are validated against the model as they are added. Failures are
+// collected and reported by Err, Tuples and Evaluator, so a chain need not
+// be checked link by link.
+type Fixture struct {
+	model   *model.Model
+	tuples  []authz.Tuple
+	seen    map[authz.Tuple]bool
+	objects []string // in creation order
+	users   []string
+	current string
+	err     error
+}
+
+// NewFixture returns an empty fixture for m.
+func NewFixture(m *model.Model) *Fixture {
+	return &Fixture{model: m, seen: map[authz.Tuple]bool{}}
+}
+
+// Object makes typ:id current, creating and linking it on first use.
+func (f *Fixture) Object(typ, id string) *Fixture {
+	object := typ + ":" + id
+	if f.model.Type(typ) == nil {
+		f.fail(fmt.Errorf("fgatest: fixture: unknown type %q", typ))
+		return f
+	}
+	if !slices.Contains(f.objects, object) {
+		if parent, rel := f.parentOf(typ); parent != "" {
+			f.add(authz.Tuple{User: parent, Relation: rel, Object: object})
+		}
+		f.objects = append(f.objects, object)
+	}
+	f.current = object
+	return f
+}
+
+// Org makes organization:id current.
+func (f *Fixture) Org(id string) *Fixture { return f.Object("organization", id) }
+
+// Team makes team:id current.
+func (f *Fixture) Team(id string) *Fixture { return f.Object("team", id) }
+
+// Project makes project:id current.
+func (f *Fixture) Project(id string) *Fixture { return f.Object("project", id) }
+
+// Resource makes resource:id current.
+func (f *Fixture) Resource(id string) *Fixture { return f.Object("resource", id) }
+
+// Document makes document:id current.
+func (f *Fixture) Document(id string) *Fixture { return f.Object("document", id) }
+
+// Grant gives user relation on the current object. user is a bare ID, a
+// typed user ("service:ci"), a wildcard ("user:*") or a userset
+// ("team:eng#member").
+func (f *Fixture) Grant(relation, user string) *Fixture {
+	if f.current == "" {
+		f.fail(fmt.Errorf("fgatest: fixture: %s %s before any object", relation, user))
+		return f
+	}
+	if !strings.Contains(user, ":") {
+		user = "user:" + user
+	}
+	f.add(authz.Tuple{User: user, Relation: relation, Object: f.current})
+	if !strings.Contains(user, "#") && !strings.HasSuffix(user, ":*") && !slices.Contains(f.users, user) {
+		f.users = append(f.users, user)
+	}
+	return f
+}
+
+// Owner grants owner on the current object.
+func (f *Fixture) Owner(user string) *Fixture { return f.Grant("owner", user) }
+
+// Admin grants admin on the current object.
+func (f *Fixture) Admin(user string) *Fixture { return f.Grant("admin", user) }
+
+// Member grants member on the current object.
+func (f *Fixture) Member(user string) *Fixture { return f.Grant("member", user) }
+
+// Editor grants editor on the current object.
+func (f *Fixture) Editor(user string) *Fixture { return f.Grant("editor", user) }
+
+// Viewer grants viewer on the current object.
+func (f *Fixture) Viewer(user string) *Fixture { return f.Grant("viewer", user) }
+
+// Link relates the current object to another object, for parents the
+// automatic linking does not pick: Project("api").Link("team", "team:ops").
+func (f *Fixture) Link(relation, object string) *Fixture {
+	if f.current == "" {
+		f.fail(fmt.Errorf("fgatest: fixture: link %s before any object", relation))
+		return f
+	}
+	f.add(authz.Tuple{User: object, Relation: relation, Object: f.current})
+	return f
+}
+
+// Err returns the errors the fixture recorded, joined.
+func (f *Fixture) Err() error { return f.err }
+
+// Tuples returns the tuples in the order they were added.
+func (f *Fixture) Tuples() ([]authz.Tuple, error) {
+	return append([]authz.Tuple(nil), f.tuples...), f.err
+}
+
+// Objects returns the objects created, in order.
+func (f *Fixture) Objects() []string { return append([]string(nil), f.objects...) }
+
+// Users returns the users granted a relation directly, in order.
+func (f *Fixture) Users() []string { return append([]string(nil), f.users...) }
+
+// Evaluator returns an embedded evaluator holding the fixture's tuples.
+func (f *Fixture) Evaluator() (*eval.Evaluator, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return eval.New(f.model, eval.NewTupleStore(f.tuples...)), nil
+}
+
+// Permission is one relation a user holds on an object.
+type Permission struct {
+	User     string
+	Relation string
+	Object   string
+}
+
+// Permissions evaluates every relation of every fixture object for every
+// fixture user and returns those that are allowed, sorted by user, object
+// and relation: the expected-permission table of the scenario.
+func (f *Fixture) Permissions(ctx context.Context) ([]Permission, error) {
+	e, err := f.Evaluator()
+	if err != nil {
+		return nil, err
+	}
+	var out []Permission
+	for _, user := range f.users {
+		for _, object := range f.objects {
+			typ, _, _ := strings.Cut(object, ":")
+			for _, r := range f.model.Type(typ).Relations {
+				ok, err := e.Check(ctx, authz.CheckRequest{User: user, Relation: r.Name, Object: object})
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					out = append(out, Permission{User: user, Relation: r.Name, Object: object})
+				}
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.User != b.User {
+			return a.User < b.User
+		}
+		if a.Object != b.Object {
+			return a.Object < b.Object
+		}
+		return a.Relation < b.Relation
+	})
+	return out, nil
+}
+
+// parentOf returns the most recent object the new object of typ should be
+// linked to, and the relation to link it with.
+func (f *Fixture) parentOf(typ string) (string, string) {
+	t := f.model.Type(typ)
+	for i := len(f.objects) - 1; i >= 0; i-- {
+		parentType, _, _ := strings.Cut(f.objects[i], ":")
+		if parentType == typ {
+			continue
+		}
+		for _, r := range t.Relations {
+			for _, ref := range r.DirectTypes() {
+				if ref.Type == parentType && ref.Relation == "" && !ref.Wildcard && ref.Condition == "" {
+					return f.objects[i], r.Name
+				}
+			}
+		}
+	}
+	return "", ""
+}
+
+func (f *Fixture) add(t authz.Tuple) {
+	if f.seen[t] {
+		return
+	}
+	if err := f.model.ValidateTuple(t.User, t.Relation, t.Object, t.Condition.Name); err != nil {
+		f.fail(fmt.Errorf("fgatest: fixture: %w", err))
+		return
+	}
+	f.seen[t] = true
+	f.tuples = append(f.tuples, t)
+}
+
+func (f *Fixture) fail(err error) {
+	f.err = errors.Join(f.err, err)
+}
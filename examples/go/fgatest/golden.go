@@ -0,0 +1,118 @@
+package fgatest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"text/tabwriter"
+
+	"github.com/bogdanticu88/openfga-examples/textdiff"
+)
+
+// UpdateEnv is the environment variable that makes AssertMatrix rewrite
+// golden files instead of comparing against them.
+const UpdateEnv = "FGATEST_UPDATE"
+
+// Matrix renders the full permission matrix of the fixture: one block per
+// object, one row per user and one column per relation of the object's
+// type, with "x" where the user holds the relation:
+//
+//	project:api         editor  owner  team  viewer
+//	user:alice          x       x      .     x
+//	user:bob            .       .      .     x
+//
+// Rows and columns are sorted, so the output is stable and diffs line by
+// line when effective access changes.
+func (f *Fixture) Matrix(ctx context.Context) ([]byte, error) {
+	perms, err := f.Permissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	held := make(map[Permission]bool, len(perms))
+	for _, p := range perms {
+		held[p] = true
+	}
+	users, objects := f.Users(), f.Objects()
+	sort.Strings(users)
+	sort.Strings(objects)
+
+	var b bytes.Buffer
+	for i, object := range objects {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		typ, _, _ := strings.Cut(object, ":")
+		var relations []string
+		for _, r := range f.model.Type(typ).Relations {
+			relations = append(relations, r.Name)
+		}
+		sort.Strings(relations)
+		tw := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(tw, "%s\t%s\n", object, strings.Join(relations, "\t"))
+		for _, user := range users {
+			cells := make([]string, len(relations))
+			for j, rel := range relations {
+				cells[j] = "."
+				if held[Permission{User: user, Relation: rel, Object: object}] {
+					cells[j] = "x"
+				}
+			}
+			fmt.Fprintf(tw, "%s\t%s\n", user, strings.Join(cells, "\t"))
+		}
+		tw.Flush()
+	}
+	// tabwriter pads the last column; trim it so golden files carry no
+	// trailing spaces.
+	lines := strings.Split(b.String(), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " ")
+	}
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// AssertMatrix compares the fixture's permission matrix with the golden
+// file at path and fails tb with a line diff when they differ. With
+// FGATEST_UPDATE set to a non-empty value other than "0", it writes the
+// file instead; review and commit the result like any other change.
+//
+//	func TestAccess(t *testing.T) {
+//		f := fgatest.NewFixture(m).Org("acme").Admin("alice").Project("api").Owner("bob")
+//		fgatest.AssertMatrix(t, "testdata/access.golden", f)
+//	}
+func AssertMatrix(tb testing.TB, path string, f *Fixture) {
+	tb.Helper()
+	got, err := f.Matrix(context.Background())
+	if err != nil {
+		tb.Fatalf("fgatest: matrix: %v", err)
+	}
+	if v := os.Getenv(UpdateEnv); v != "" && v != "0" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			tb.Fatalf("fgatest: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			tb.Fatalf("fgatest: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		tb.Fatalf("fgatest: golden file %s does not exist; run with %s=1 to create it", path, UpdateEnv)
+	}
+	if err != nil {
+		tb.Fatalf("fgatest: %v", err)
+	}
+	if !bytes.Equal(want, got) {
+		tb.Errorf("fgatest: permission matrix differs from %s (-want +got):\n%s\nrun with %s=1 to accept the change", path, Diff(want, got), UpdateEnv)
+	}
+}
+
+// Diff returns a line diff of a and b; see textdiff.Lines.
+func Diff(a, b []byte) string {
+	return textdiff.Lines(a, b)
+}
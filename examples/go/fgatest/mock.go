@@ -0,0 +1,200 @@
+package fgatest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// The mocks below implement the capability interfaces of package authz.
+// Each calls its Func field, or returns zero values when it is nil, and
+// records the arguments of every call:
+//
+//	m := &fgatest.MockChecker{CheckFunc: func(ctx context.Context, user, relation, object string) (bool, error) {
+//		return user == "user:alice", nil
+//	}}
+//	handler := NewHandler(m)
+//	...
+//	if len(m.CheckCalls()) != 1 { ... }
+
+// CheckCall records one MockChecker.Check call.
+type CheckCall struct {
+	User, Relation, Object string
+}
+
+// MockChecker is an authz.Checker.
+type MockChecker struct {
+	CheckFunc func(ctx context.Context, user, relation, object string) (bool, error)
+
+	mu    sync.Mutex
+	calls []CheckCall
+}
+
+// Check implements authz.Checker.
+func (m *MockChecker) Check(ctx context.Context, user, relation, object string) (bool, error) {
+	m.mu.Lock()
+	m.calls = append(m.calls, CheckCall{User: user, Relation: relation, Object: object})
+	m.mu.Unlock()
+	if m.CheckFunc == nil {
+		return false, nil
+	}
+	return m.CheckFunc(ctx, user, relation, object)
+}
+
+// CheckCalls returns the recorded calls in order.
+func (m *MockChecker) CheckCalls() []CheckCall {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]CheckCall(nil), m.calls...)
+}
+
+// WriteCall records one MockTupleWriter.Write call.
+type WriteCall struct {
+	Writes, Deletes []authz.Tuple
+}
+
+// MockTupleWriter is an authz.TupleWriter.
+type MockTupleWriter struct {
+	WriteFunc func(ctx context.Context, writes, deletes []authz.Tuple) error
+
+	mu    sync.Mutex
+	calls []WriteCall
+}
+
+// Write implements authz.TupleWriter.
+func (m *MockTupleWriter) Write(ctx context.Context, writes, deletes []authz.Tuple) error {
+	m.mu.Lock()
+	m.calls = append(m.calls, WriteCall{Writes: writes, Deletes: deletes})
+	m.mu.Unlock()
+	if m.WriteFunc == nil {
+		return nil
+	}
+	return m.WriteFunc(ctx, writes, deletes)
+}
+
+// WriteCalls returns the recorded calls in order.
+func (m *MockTupleWriter) WriteCalls() []WriteCall {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]WriteCall(nil), m.calls...)
+}
+
+// ListObjectsCall records one MockLister.ListObjects call.
+type ListObjectsCall struct {
+	User, Relation, ObjectType string
+}
+
+// MockLister is an authz.Lister.
+type MockLister struct {
+	ListObjectsFunc func(ctx context.Context, user, relation, objectType string) ([]string, error)
+
+	mu    sync.Mutex
+	calls []ListObjectsCall
+}
+
+// ListObjects implements authz.Lister.
+func (m *MockLister) ListObjects(ctx context.Context, user, relation, objectType string) ([]string, error) {
+	m.mu.Lock()
+	m.calls = append(m.calls, ListObjectsCall{User: user, Relation: relation, ObjectType: objectType})
+	m.mu.Unlock()
+	if m.ListObjectsFunc == nil {
+		return nil, nil
+	}
+	return m.ListObjectsFunc(ctx, user, relation, objectType)
+}
+
+// ListObjectsCalls returns the recorded calls in order.
+func (m *MockLister) ListObjectsCalls() []ListObjectsCall {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]ListObjectsCall(nil), m.calls...)
+}
+
+// ListUsersCall records one MockExpander.ListUsers call.
+type ListUsersCall struct {
+	Object, Relation string
+	UserFilters      []string
+}
+
+// MockExpander is an authz.Expander.
+type MockExpander struct {
+	ListUsersFunc func(ctx context.Context, object, relation string, userFilters []string) ([]string, error)
+
+	mu    sync.Mutex
+	calls []ListUsersCall
+}
+
+// ListUsers implements authz.Expander.
+func (m *MockExpander) ListUsers(ctx context.Context, object, relation string, userFilters []string) ([]string, error) {
+	m.mu.Lock()
+	m.calls = append(m.calls, ListUsersCall{Object: object, Relation: relation, UserFilters: userFilters})
+	m.mu.Unlock()
+	if m.ListUsersFunc == nil {
+		return nil, nil
+	}
+	return m.ListUsersFunc(ctx, object, relation, userFilters)
+}
+
+// ListUsersCalls returns the recorded calls in order.
+func (m *MockExpander) ListUsersCalls() []ListUsersCall {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]ListUsersCall(nil), m.calls...)
+}
+
+// MockStoreAdmin is an authz.StoreAdmin. ReadModelCalls records the model
+// IDs asked for.
+type MockStoreAdmin struct {
+	GetStoreFunc  func(ctx context.Context) (authz.Store, error)
+	ReadModelFunc func(ctx context.Context, id string) (*model.Model, error)
+
+	mu        sync.Mutex
+	getStores int
+	readModel []string
+}
+
+// GetStore implements authz.StoreAdmin.
+func (m *MockStoreAdmin) GetStore(ctx context.Context) (authz.Store, error) {
+	m.mu.Lock()
+	m.getStores++
+	m.mu.Unlock()
+	if m.GetStoreFunc == nil {
+		return authz.Store{}, nil
+	}
+	return m.GetStoreFunc(ctx)
+}
+
+// ReadModel implements authz.StoreAdmin.
+func (m *MockStoreAdmin) ReadModel(ctx context.Context, id string) (*model.Model, error) {
+	m.mu.Lock()
+	m.readModel = append(m.readModel, id)
+	m.mu.Unlock()
+	if m.ReadModelFunc == nil {
+		return nil, nil
+	}
+	return m.ReadModelFunc(ctx, id)
+}
+
+// GetStoreCalls returns the number of GetStore calls.
+func (m *MockStoreAdmin) GetStoreCalls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.getStores
+}
+
+// ReadModelCalls returns the IDs passed to ReadModel in order.
+func (m *MockStoreAdmin) ReadModelCalls() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.readModel...)
+}
+
+var (
+	_ authz.Checker     = (*MockChecker)(nil)
+	_ authz.TupleWriter = (*MockTupleWriter)(nil)
+	_ authz.Lister      = (*MockLister)(nil)
+	_ authz.Expander    = (*MockExpander)(nil)
+	_ authz.StoreAdmin  = (*MockStoreAdmin)(nil)
+)
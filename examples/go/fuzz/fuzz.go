@@ -0,0 +1,123 @@
+// Package fuzz generates random tuples and check queries that are valid
+// under a model and cross-validates the embedded evaluator against a live
+// server, reporting every query on which they disagree.
+package fuzz
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/eval"
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// Config controls a fuzzing run. Zero values select the defaults noted.
+type Config struct {
+	Seed       int64
+	Iterations int // default 100
+	Tuples     int // tuples per iteration, default 20
+	Queries    int // queries per iteration, default 50
+	IDsPerType int // default 3
+
+	// Live, when set, is compared against the embedded evaluator. It must
+	// point at an empty store whose active model is the fuzzed model; each
+	// iteration writes its tuples and deletes them again afterwards.
+	Live authz.Backend
+}
+
+func (c *Config) defaults() {
+	if c.Iterations <= 0 {
+		c.Iterations = 100
+	}
+	if c.Tuples <= 0 {
+		c.Tuples = 20
+	}
+	if c.Queries <= 0 {
+		c.Queries = 50
+	}
+}
+
+// Discrepancy is a query on which the two evaluators disagree, together
+// with the tuples that were present.
+type Discrepancy struct {
+	Iteration   int
+	Tuples      []authz.Tuple
+	Query       authz.CheckRequest
+	Embedded    bool
+	EmbeddedErr error
+	Live        bool
+	LiveErr     error
+}
+
+func (d Discrepancy) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "iteration %d: check %s#%s@%s: embedded=%v", d.Iteration, d.Query.Object, d.Query.Relation, d.Query.User, d.Embedded)
+	if d.EmbeddedErr != nil {
+		fmt.Fprintf(&sb, " (%v)", d.EmbeddedErr)
+	}
+	fmt.Fprintf(&sb, " live=%v", d.Live)
+	if d.LiveErr != nil {
+		fmt.Fprintf(&sb, " (%v)", d.LiveErr)
+	}
+	for _, t := range d.Tuples {
+		sb.WriteString("\n  ")
+		sb.WriteString(t.String())
+	}
+	return sb.String()
+}
+
+// Report summarizes a run.
+type Report struct {
+	Iterations    int
+	Queries       int
+	Discrepancies []Discrepancy
+	// Errors are embedded-evaluator failures seen when no live backend is
+	// configured, e.g. exceeded resolution depth.
+	Errors []Discrepancy
+}
+
+// Run fuzzes m according to cfg. It returns an error only when the run
+// itself cannot proceed, e.g. the live store rejects generated tuples;
+// disagreements are collected in the report.
+func Run(ctx context.Context, m *model.Model, cfg Config) (*Report, error) {
+	cfg.defaults()
+	gen := NewGenerator(m, cfg.Seed, cfg.IDsPerType)
+	rep := &Report{}
+	for i := 0; i < cfg.Iterations; i++ {
+		if err := ctx.Err(); err != nil {
+			return rep, err
+		}
+		tuples := gen.Tuples(cfg.Tuples)
+		queries := gen.Queries(cfg.Queries)
+		embedded := eval.New(m, eval.NewTupleStore(tuples...))
+		if cfg.Live != nil {
+			if err := authz.WriteBatched(ctx, cfg.Live, tuples, nil).Err(); err != nil {
+				return rep, fmt.Errorf("fuzz: iteration %d: load live store: %w", i, err)
+			}
+		}
+		for _, q := range queries {
+			d := Discrepancy{Iteration: i, Tuples: tuples, Query: q}
+			d.Embedded, d.EmbeddedErr = embedded.Check(ctx, q)
+			if cfg.Live == nil {
+				if d.EmbeddedErr != nil {
+					rep.Errors = append(rep.Errors, d)
+				}
+				continue
+			}
+			d.Live, d.LiveErr = cfg.Live.Check(ctx, q)
+			if d.Embedded != d.Live || (d.EmbeddedErr == nil) != (d.LiveErr == nil) {
+				rep.Discrepancies = append(rep.Discrepancies, d)
+			}
+		}
+		rep.Iterations++
+		rep.Queries += len(queries)
+		if cfg.Live != nil {
+			if err := authz.WriteBatched(ctx, cfg.Live, nil, tuples).Err(); err != nil {
+				return rep, fmt.Errorf("fuzz: iteration %d: clean live store: %w", i, err)
+			}
+		}
+	}
+	return rep, nil
+}
@@ -0,0 +1,129 @@
+package fuzz
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// Generator produces random tuples and check queries that are valid under a
+// model: every tuple respects the relation's type restrictions, and every
+// query names a relation the object's type defines.
+type Generator struct {
+	m          *model.Model
+	rng        *rand.Rand
+	idsPerType int
+	assignable []slot
+	queryable  []slot
+	userTypes  []string
+}
+
+type slot struct {
+	typ string
+	rel *model.Relation
+}
+
+// NewGenerator returns a generator drawing object IDs from a pool of
+// idsPerType identifiers per type. Small pools make tuples collide on the
+// same objects, which is what exercises inheritance.
+func NewGenerator(m *model.Model, seed int64, idsPerType int) *Generator {
+	if idsPerType <= 0 {
+		idsPerType = 3
+	}
+	g := &Generator{m: m, rng: rand.New(rand.NewSource(seed)), idsPerType: idsPerType}
+	plain := map[string]bool{}
+	for _, t := range m.Types {
+		for _, r := range t.Relations {
+			g.queryable = append(g.queryable, slot{t.Name, r})
+			refs := usableRefs(r)
+			if len(refs) > 0 {
+				g.assignable = append(g.assignable, slot{t.Name, r})
+			}
+			for _, ref := range refs {
+				if ref.Relation == "" && !plain[ref.Type] {
+					plain[ref.Type] = true
+					g.userTypes = append(g.userTypes, ref.Type)
+				}
+			}
+		}
+	}
+	return g
+}
+
+// usableRefs drops conditional restrictions, which the embedded evaluator
+// cannot interpret.
+func usableRefs(r *model.Relation) []model.TypeRef {
+	var refs []model.TypeRef
+	for _, ref := range r.DirectTypes() {
+		if ref.Condition == "" {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// Object returns a random object of typ.
+func (g *Generator) Object(typ string) string {
+	return fmt.Sprintf("%s:%s%d", typ, typ[:1], g.rng.Intn(g.idsPerType))
+}
+
+// Tuple returns one random valid tuple. ok is false when the model has no
+// directly assignable relation.
+func (g *Generator) Tuple() (t authz.Tuple, ok bool) {
+	if len(g.assignable) == 0 {
+		return authz.Tuple{}, false
+	}
+	s := g.assignable[g.rng.Intn(len(g.assignable))]
+	refs := usableRefs(s.rel)
+	ref := refs[g.rng.Intn(len(refs))]
+	user := g.Object(ref.Type)
+	switch {
+	case ref.Wildcard:
+		user = ref.Type + ":*"
+	case ref.Relation != "":
+		user += "#" + ref.Relation
+	}
+	return authz.Tuple{User: user, Relation: s.rel.Name, Object: g.Object(s.typ)}, true
+}
+
+// Tuples returns up to n distinct random tuples.
+func (g *Generator) Tuples(n int) []authz.Tuple {
+	seen := map[authz.Tuple]bool{}
+	var out []authz.Tuple
+	for i := 0; i < n*4 && len(out) < n; i++ {
+		t, ok := g.Tuple()
+		if !ok {
+			break
+		}
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// Query returns a random check whose user is a concrete object of a type
+// that appears in some type restriction.
+func (g *Generator) Query() authz.CheckRequest {
+	s := g.queryable[g.rng.Intn(len(g.queryable))]
+	user := g.m.Types[0].Name
+	if len(g.userTypes) > 0 {
+		user = g.userTypes[g.rng.Intn(len(g.userTypes))]
+	}
+	return authz.CheckRequest{User: g.Object(user), Relation: s.rel.Name, Object: g.Object(s.typ)}
+}
+
+// Queries returns n random checks.
+func (g *Generator) Queries(n int) []authz.CheckRequest {
+	if len(g.queryable) == 0 {
+		return nil
+	}
+	out := make([]authz.CheckRequest, n)
+	for i := range out {
+		out[i] = g.Query()
+	}
+	return out
+}
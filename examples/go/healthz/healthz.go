@@ -0,0 +1,126 @@
+// Package healthz verifies that an application's authorization backend is
+// usable — the server answers, the configured store exists and it has an
+// active model — and exposes the result as an HTTP readiness probe.
+package healthz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+)
+
+// DefaultTimeout bounds a whole Check when the caller's context has no
+// deadline.
+const DefaultTimeout = 2 * time.Second
+
+// Result is the outcome of one probe.
+type Result struct {
+	Name     string        `json:"name"`
+	OK       bool          `json:"ok"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// Report is the outcome of Check.
+type Report struct {
+	OK      bool     `json:"ok"`
+	StoreID string   `json:"store_id,omitempty"`
+	ModelID string   `json:"model_id,omitempty"`
+	Results []Result `json:"results"`
+}
+
+// ErrUnhealthy is returned by Check when any probe fails.
+var ErrUnhealthy = errors.New("healthz: backend unhealthy")
+
+type statusCoder interface{ ResponseStatusCode() int }
+
+// Check probes connectivity and store existence with GetStore, then reads
+// the active model. It returns the report and, when any probe failed, an
+// error wrapping ErrUnhealthy.
+func Check(ctx context.Context, b authz.Backend) (Report, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultTimeout)
+		defer cancel()
+	}
+	rep := Report{OK: true}
+	record := func(name string, start time.Time, err error) bool {
+		r := Result{Name: name, OK: err == nil, Duration: time.Since(start)}
+		if err != nil {
+			r.Error = err.Error()
+			rep.OK = false
+		}
+		rep.Results = append(rep.Results, r)
+		return err == nil
+	}
+
+	start := time.Now()
+	store, err := b.GetStore(ctx)
+	var sc statusCoder
+	switch {
+	case err == nil:
+		record("connectivity", start, nil)
+		record("store", start, nil)
+		rep.StoreID = store.ID
+	case errors.As(err, &sc) && sc.ResponseStatusCode() == http.StatusNotFound:
+		record("connectivity", start, nil)
+		record("store", start, fmt.Errorf("store not found: %w", err))
+	default:
+		record("connectivity", start, err)
+	}
+	if !rep.OK {
+		return rep, ErrUnhealthy
+	}
+
+	start = time.Now()
+	m, err := b.ReadModel(ctx, "")
+	if err == nil && len(m.Types) == 0 {
+		err = errors.New("active model defines no types")
+	}
+	if record("model", start, err) {
+		rep.ModelID = m.ID
+	}
+	if !rep.OK {
+		return rep, ErrUnhealthy
+	}
+	return rep, nil
+}
+
+// Handler serves Check as JSON: 200 when healthy, 503 otherwise. Results are
+// cached for cacheFor so frequent probes do not load the server; zero
+// disables caching.
+func Handler(b authz.Backend, cacheFor time.Duration) http.Handler {
+	return &handler{backend: b, cacheFor: cacheFor}
+}
+
+type handler struct {
+	backend  authz.Backend
+	cacheFor time.Duration
+
+	mu      sync.Mutex
+	last    Report
+	lastErr error
+	at      time.Time
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	if h.cacheFor == 0 || h.at.IsZero() || time.Since(h.at) >= h.cacheFor {
+		h.last, h.lastErr = Check(r.Context(), h.backend)
+		h.at = time.Now()
+	}
+	rep, err := h.last, h.lastErr
+	h.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(rep)
+}
@@ -0,0 +1,185 @@
+// Package hedge cuts Check tail latency by sending a second, identical
+// request when the first is slower than usual and taking whichever answers
+// first:
+//
+//	b := hedge.New(authz.FromSDK(fga), hedge.Config{Budget: 0.05})
+//	az := authz.New(b)
+//
+// The hedge is sent after the observed 95th percentile latency, so roughly
+// one check in twenty is a candidate, and Budget caps the share of checks
+// actually hedged so a slow server is not hit with double the load. Only
+// Check is hedged; every other call goes straight to the backend.
+package hedge
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+)
+
+// Config tunes hedging.
+type Config struct {
+	// Delay is how long the first request runs before the hedge is sent;
+	// zero uses the Percentile of recent latencies.
+	Delay time.Duration
+	// Percentile of recent latencies used as the delay; default 0.95.
+	Percentile float64
+	// MinDelay is a floor for the estimated delay; default 1ms.
+	MinDelay time.Duration
+	// Budget is the share of checks that may be hedged; default 0.05.
+	Budget float64
+	// Window is the number of recent latencies kept; default 1000. No
+	// check is hedged on an estimate until a tenth of it is filled.
+	Window int
+}
+
+// Stats counts hedging activity since New.
+type Stats struct {
+	Checks int64
+	Hedged int64
+	// Won is the number of hedges that answered before the first request.
+	Won int64
+}
+
+// Backend is an authz.Backend whose Check is hedged.
+type Backend struct {
+	authz.Backend
+	cfg Config
+
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+	delay   time.Duration // estimate, refreshed every window/10 samples
+	tokens  float64
+	stats   Stats
+}
+
+// New returns b with hedged checks.
+func New(b authz.Backend, cfg Config) *Backend {
+	if cfg.Percentile <= 0 || cfg.Percentile >= 1 {
+		cfg.Percentile = 0.95
+	}
+	if cfg.MinDelay <= 0 {
+		cfg.MinDelay = time.Millisecond
+	}
+	if cfg.Budget <= 0 {
+		cfg.Budget = 0.05
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = 1000
+	}
+	return &Backend{Backend: b, cfg: cfg, samples: make([]time.Duration, 0, cfg.Window)}
+}
+
+// Stats returns the counters.
+func (h *Backend) Stats() Stats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.stats
+}
+
+type result struct {
+	allowed bool
+	err     error
+	hedge   bool
+}
+
+// Check sends req and, when it is slower than the hedge delay and the
+// budget allows, sends it again. The first answer without an error wins
+// and the other request is cancelled.
+func (h *Backend) Check(ctx context.Context, req authz.CheckRequest) (bool, error) {
+	delay := h.admit()
+	if delay == 0 {
+		return h.timed(ctx, req)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan result, 2)
+	send := func(hedge bool) {
+		allowed, err := h.timed(ctx, req)
+		results <- result{allowed, err, hedge}
+	}
+	go send(false)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	inflight := 1
+	for {
+		select {
+		case <-timer.C:
+			if h.spend() {
+				inflight++
+				go send(true)
+			}
+		case r := <-results:
+			inflight--
+			if r.err == nil || inflight == 0 {
+				if r.hedge && r.err == nil {
+					h.won()
+				}
+				return r.allowed, r.err
+			}
+		}
+	}
+}
+
+// timed calls the backend and records the latency of successful calls.
+func (h *Backend) timed(ctx context.Context, req authz.CheckRequest) (bool, error) {
+	start := time.Now()
+	allowed, err := h.Backend.Check(ctx, req)
+	if err == nil {
+		h.observe(time.Since(start))
+	}
+	return allowed, err
+}
+
+// admit counts a check, earns budget and returns the hedge delay, or zero
+// when there is no estimate yet.
+func (h *Backend) admit() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.stats.Checks++
+	// Cap the savings so an idle period does not allow a burst of hedges.
+	h.tokens = min(h.tokens+h.cfg.Budget, 10)
+	if h.cfg.Delay > 0 {
+		return h.cfg.Delay
+	}
+	return h.delay
+}
+
+func (h *Backend) spend() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.tokens < 1 {
+		return false
+	}
+	h.tokens--
+	h.stats.Hedged++
+	return true
+}
+
+func (h *Backend) won() {
+	h.mu.Lock()
+	h.stats.Won++
+	h.mu.Unlock()
+}
+
+func (h *Backend) observe(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.samples) < h.cfg.Window {
+		h.samples = append(h.samples, d)
+	} else {
+		h.samples[h.next] = d
+	}
+	h.next = (h.next + 1) % h.cfg.Window
+	refresh := max(h.cfg.Window/10, 1)
+	if len(h.samples) >= refresh && h.next%refresh == 0 {
+		sorted := append([]time.Duration(nil), h.samples...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		h.delay = max(sorted[int(float64(len(sorted)-1)*h.cfg.Percentile)], h.cfg.MinDelay)
+	}
+}
@@ -0,0 +1,245 @@
+// Package history reconstructs who was granted what on an object, when,
+// and by whom, from the store's changes feed or an archive of it, joined
+// with the provenance sidecar of package tuples:
+//
+//	h := &history.History{Source: history.Feed{Backend: backend}, Provenance: prov}
+//	tl, err := h.ForObject(ctx, "document:roadmap")
+//	for _, e := range tl.Grants("user:bob", "editor") {
+//		fmt.Println(e.Time, e.Provenance.WrittenBy, e.Provenance.Reason)
+//	}
+//
+// The server may prune old changes; reading the feed's archive (see package
+// archive) with Files keeps the full timeline.
+package history
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/tuples"
+)
+
+// Source yields tuple changes oldest first.
+type Source interface {
+	// Changes returns the changes on objects of objectType, or every
+	// change when objectType is empty.
+	Changes(ctx context.Context, objectType string) ([]authz.Change, error)
+}
+
+// Feed is a Source over the server's changes feed, read from its start.
+type Feed struct {
+	Backend authz.Backend
+}
+
+// Changes implements Source.
+func (f Feed) Changes(ctx context.Context, objectType string) ([]authz.Change, error) {
+	changes, _, err := authz.ReadAllChanges(ctx, f.Backend, objectType, "")
+	return changes, err
+}
+
+// Files is a Source over JSON-lines change logs, one authz.Change per line,
+// as written by packages backup and archive. The changes of all files are
+// sorted by time and exact repeats, which an archive may hold after a
+// crash, are dropped.
+type Files []string
+
+// Glob returns the Files matching pattern, sorted by name.
+func Glob(pattern string) (Files, error) {
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("history: %w", err)
+	}
+	sort.Strings(paths)
+	return Files(paths), nil
+}
+
+// Changes implements Source.
+func (fs Files) Changes(ctx context.Context, objectType string) ([]authz.Change, error) {
+	var all []authz.Change
+	for _, path := range fs {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("history: %w", err)
+		}
+		changes, err := ReadLog(f, objectType)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("history: %s: %w", path, err)
+		}
+		all = append(all, changes...)
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].Timestamp.Before(all[j].Timestamp) })
+	type key struct {
+		c  authz.Change
+		ns int64
+	}
+	seen := map[key]bool{}
+	out := all[:0]
+	for _, c := range all {
+		k := key{c: authz.Change{Tuple: c.Tuple, Operation: c.Operation}, ns: c.Timestamp.UnixNano()}
+		if !seen[k] {
+			seen[k] = true
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+// ReadLog decodes a JSON-lines change log, keeping the changes on objects
+// of objectType, or all of them when it is empty.
+func ReadLog(r io.Reader, objectType string) ([]authz.Change, error) {
+	var out []authz.Change
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	for line := 1; sc.Scan(); line++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var c authz.Change
+		if err := json.Unmarshal(sc.Bytes(), &c); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if objectType == "" || strings.HasPrefix(c.Tuple.Object, objectType+":") {
+			out = append(out, c)
+		}
+	}
+	return out, sc.Err()
+}
+
+// Event is one grant or revocation.
+type Event struct {
+	Time      time.Time
+	Operation authz.Operation
+	User      string
+	Relation  string
+	// Provenance is the sidecar record of a grant still in effect, nil for
+	// revocations, superseded grants and tuples written without a
+	// tuples.Recorder.
+	Provenance *tuples.Record
+}
+
+// Timeline is the history of one object, oldest event first.
+type Timeline struct {
+	Object string
+	Events []Event
+}
+
+// Grants returns the events of user's relation, which is any relation when
+// empty: when it was granted, revoked and granted again.
+func (t *Timeline) Grants(user, relation string) []Event {
+	var out []Event
+	for _, e := range t.Events {
+		if e.User == user && (relation == "" || e.Relation == relation) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// At returns the tuples on the object in effect at time at.
+func (t *Timeline) At(at time.Time) []authz.Tuple {
+	state := map[authz.Tuple]bool{}
+	for _, e := range t.Events {
+		if e.Time.After(at) {
+			break
+		}
+		tu := authz.Tuple{User: e.User, Relation: e.Relation, Object: t.Object}
+		if e.Operation == authz.OpWrite {
+			state[tu] = true
+		} else {
+			delete(state, tu)
+		}
+	}
+	out := make([]authz.Tuple, 0, len(state))
+	for tu := range state {
+		out = append(out, tu)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Relation != out[j].Relation {
+			return out[i].Relation < out[j].Relation
+		}
+		return out[i].User < out[j].User
+	})
+	return out
+}
+
+// WriteText writes one line per event.
+func (t *Timeline) WriteText(w io.Writer) error {
+	for _, e := range t.Events {
+		verb := "granted"
+		if e.Operation == authz.OpDelete {
+			verb = "revoked"
+		}
+		line := fmt.Sprintf("%s %s %s %s", e.Time.UTC().Format(time.RFC3339), verb, e.Relation, e.User)
+		if p := e.Provenance; p != nil {
+			if p.WrittenBy != "" {
+				line += " by " + p.WrittenBy
+			}
+			if p.Impersonator != "" {
+				line += " (impersonated by " + p.Impersonator + ")"
+			}
+			if p.Reason != "" {
+				line += ": " + p.Reason
+			}
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// History answers timeline questions from a Source.
+type History struct {
+	Source Source
+	// Provenance, if set, supplies who made the grants still in effect.
+	Provenance tuples.Store
+}
+
+// ForObject returns the timeline of object, e.g. "document:roadmap".
+func (h *History) ForObject(ctx context.Context, object string) (*Timeline, error) {
+	typ, _, ok := strings.Cut(object, ":")
+	if !ok {
+		return nil, fmt.Errorf("history: object %q must have the form type:id", object)
+	}
+	changes, err := h.Source.Changes(ctx, typ)
+	if err != nil {
+		return nil, fmt.Errorf("history: %w", err)
+	}
+	tl := &Timeline{Object: object}
+	last := map[authz.Tuple]int{} // latest event per tuple key
+	for _, c := range changes {
+		if c.Tuple.Object != object {
+			continue
+		}
+		last[c.Tuple.Key()] = len(tl.Events)
+		tl.Events = append(tl.Events, Event{Time: c.Timestamp, Operation: c.Operation, User: c.Tuple.User, Relation: c.Tuple.Relation})
+	}
+	if h.Provenance == nil || len(last) == 0 {
+		return tl, nil
+	}
+	var current []authz.Tuple
+	for tu, i := range last {
+		if tl.Events[i].Operation == authz.OpWrite {
+			current = append(current, tu)
+		}
+	}
+	recs, err := h.Provenance.Get(ctx, current)
+	if err != nil {
+		return nil, fmt.Errorf("history: provenance: %w", err)
+	}
+	for tu, rec := range recs {
+		rec := rec
+		tl.Events[last[tu]].Provenance = &rec
+	}
+	return tl, nil
+}
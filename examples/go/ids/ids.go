@@ -0,0 +1,193 @@
+// Package ids validates and normalizes OpenFGA identifiers: type names,
+// relation names, object IDs and the composite user and object strings
+// built from them.
+//
+// A Policy runs in one of two modes. Strict rejects anything the server
+// would reject or that violates the configured case policy; Lenient rewrites
+// the value into an acceptable form instead. authz.WithIDPolicy applies a
+// policy to every helper so identifiers are treated the same everywhere.
+package ids
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Server limits on identifier sizes, in bytes.
+const (
+	MaxTypeLength     = 254
+	MaxRelationLength = 50
+	MaxObjectLength   = 256
+	MaxUserLength     = 512
+)
+
+// ErrInvalid is wrapped by every validation error.
+var ErrInvalid = errors.New("ids: invalid identifier")
+
+// Mode selects between rejecting and sanitizing bad input.
+type Mode int
+
+const (
+	Strict Mode = iota
+	Lenient
+)
+
+// Case is a per-type case policy for object IDs.
+type Case int
+
+const (
+	// Preserve leaves IDs as given.
+	Preserve Case = iota
+	// Lower requires (Strict) or converts to (Lenient) lower case, for IDs
+	// such as emails whose upstream source is case-insensitive.
+	Lower
+)
+
+// Policy holds the normalization rules. The zero value is a Strict policy
+// that preserves case.
+type Policy struct {
+	Mode Mode
+	// Case maps a type name to its case policy; types not listed use
+	// DefaultCase.
+	Case        map[string]Case
+	DefaultCase Case
+	// MaxIDLength caps object IDs; zero means the server limit less the type
+	// prefix.
+	MaxIDLength int
+}
+
+func invalid(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalid, fmt.Sprintf(format, args...))
+}
+
+// ValidateType checks a type name.
+func ValidateType(name string) error {
+	return validateName("type", name, MaxTypeLength, ":#@*")
+}
+
+// ValidateRelation checks a relation name.
+func ValidateRelation(name string) error {
+	return validateName("relation", name, MaxRelationLength, ":#@*")
+}
+
+func validateName(kind, name string, max int, forbidden string) error {
+	if name == "" {
+		return invalid("empty %s name", kind)
+	}
+	if len(name) > max {
+		return invalid("%s name %q exceeds %d bytes", kind, name, max)
+	}
+	if i := strings.IndexFunc(name, func(r rune) bool { return unicode.IsSpace(r) || strings.ContainsRune(forbidden, r) }); i >= 0 {
+		return invalid("%s name %q contains %q", kind, name, name[i:i+1])
+	}
+	return nil
+}
+
+// ID validates or sanitizes an object ID of type typ.
+func (p *Policy) ID(typ, id string) (string, error) {
+	if err := ValidateType(typ); err != nil {
+		return "", err
+	}
+	max := p.MaxIDLength
+	if max == 0 {
+		max = MaxObjectLength - len(typ) - 1
+	}
+	if p.Mode == Lenient {
+		return p.sanitize(typ, id, max)
+	}
+	switch {
+	case id == "":
+		return "", invalid("empty %s id", typ)
+	case id == "*":
+		return "", invalid("%s id cannot be the wildcard", typ)
+	case len(id) > max:
+		return "", invalid("%s id %q exceeds %d bytes", typ, id, max)
+	case !utf8.ValidString(id):
+		return "", invalid("%s id %q is not valid UTF-8", typ, id)
+	}
+	if i := strings.IndexFunc(id, forbiddenInID); i >= 0 {
+		return "", invalid("%s id %q contains %q", typ, id, string([]rune(id[i:])[0]))
+	}
+	if p.caseFor(typ) == Lower && strings.ToLower(id) != id {
+		return "", invalid("%s id %q must be lower case", typ, id)
+	}
+	return id, nil
+}
+
+func (p *Policy) sanitize(typ, id string, max int) (string, error) {
+	id = strings.ToValidUTF8(strings.TrimSpace(id), "")
+	id = strings.Map(func(r rune) rune {
+		if forbiddenInID(r) {
+			return '_'
+		}
+		return r
+	}, id)
+	if p.caseFor(typ) == Lower {
+		id = strings.ToLower(id)
+	}
+	for len(id) > max {
+		_, size := utf8.DecodeLastRuneInString(id)
+		id = id[:len(id)-size]
+	}
+	if id == "" || id == "*" {
+		return "", invalid("%s id is empty after sanitizing", typ)
+	}
+	return id, nil
+}
+
+func (p *Policy) caseFor(typ string) Case {
+	if c, ok := p.Case[typ]; ok {
+		return c
+	}
+	return p.DefaultCase
+}
+
+func forbiddenInID(r rune) bool {
+	return r == ':' || r == '#' || unicode.IsSpace(r) || unicode.IsControl(r)
+}
+
+// Object normalizes "type:id".
+func (p *Policy) Object(object string) (string, error) {
+	typ, id, ok := strings.Cut(object, ":")
+	if !ok {
+		return "", invalid("object %q must have the form type:id", object)
+	}
+	id, err := p.ID(typ, id)
+	if err != nil {
+		return "", err
+	}
+	return typ + ":" + id, nil
+}
+
+// User normalizes "type:id", "type:*" or "type:id#relation".
+func (p *Policy) User(user string) (string, error) {
+	obj, rel, isUserset := strings.Cut(user, "#")
+	typ, id, ok := strings.Cut(obj, ":")
+	if !ok {
+		return "", invalid("user %q must have the form type:id", user)
+	}
+	if id == "*" && !isUserset {
+		if err := ValidateType(typ); err != nil {
+			return "", err
+		}
+		return user, nil
+	}
+	id, err := p.ID(typ, id)
+	if err != nil {
+		return "", err
+	}
+	out := typ + ":" + id
+	if isUserset {
+		if err := ValidateRelation(rel); err != nil {
+			return "", err
+		}
+		out += "#" + rel
+	}
+	if len(out) > MaxUserLength {
+		return "", invalid("user %q exceeds %d bytes", out, MaxUserLength)
+	}
+	return out, nil
+}
@@ -0,0 +1,432 @@
+// Package infer proposes a model and tuples from example decisions, one per
+// line, and verifies the proposal on the embedded evaluator. It is
+// experimental: the proposal is a starting point to edit, not a model to
+// deploy.
+//
+//	alice can edit project api because she's admin of organization acme
+//	bob can view project api because he's editor of project api
+//	carol can view project api
+//	dan cannot edit project api
+//
+// An example is "<user> can|cannot <relation> <object> [because <reason>]".
+// Objects are written "type id" or "type:id", users are of type user. A
+// reason names the role that grants the access: "<role> of <object>", or
+// "<type> <role>" and "<role> of <type>" when the table mentions a single
+// object of that type. Words such as "she's", "is" and "the" are ignored.
+//
+// A role on the object itself becomes a relation the example's relation
+// includes; a role on another object becomes a relation of that object's
+// type, granted through a relation linking the two objects, as in "admin
+// from organization". An allowed example without a reason becomes a direct
+// grant. Denied examples shape nothing and are only verified.
+package infer
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/eval"
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// Example is one example decision.
+type Example struct {
+	User     string
+	Relation string
+	Object   string
+	Allowed  bool
+	// Role and On are the reason: Role on object On; both are empty
+	// without one.
+	Role string
+	On   string
+	Pos  model.Pos
+	Text string
+}
+
+// ReadExamples reads and parses an examples file.
+func ReadExamples(path string) ([]*Example, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("infer: %w", err)
+	}
+	defer f.Close()
+	return ParseExamples(path, f)
+}
+
+// ParseExamples parses examples, one per line; blank lines and lines
+// starting with '#' are skipped. file is used only in positions.
+func ParseExamples(file string, r io.Reader) ([]*Example, error) {
+	type pending struct {
+		ex     *Example
+		role   string
+		typ    string // reason object type, when its ID is left out
+		object string
+	}
+	var parsed []pending
+	var errs []error
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		pos := model.Pos{File: file, Line: n, Col: 1}
+		fail := func(format string, args ...any) {
+			errs = append(errs, &model.SyntaxError{Pos: pos, Msg: fmt.Sprintf(format, args...)})
+		}
+		decision, reason, _ := strings.Cut(text, " because ")
+		words := strings.Fields(strings.TrimSuffix(decision, "."))
+		if len(words) < 4 {
+			fail("expected <user> can|cannot <relation> <object>, got %q", text)
+			continue
+		}
+		ex := &Example{Pos: pos, Text: text, User: words[0], Relation: words[2]}
+		if !isName(ex.Relation) {
+			fail("invalid relation name %q", ex.Relation)
+			continue
+		}
+		if !strings.Contains(ex.User, ":") {
+			ex.User = "user:" + ex.User
+		}
+		switch words[1] {
+		case "can":
+			ex.Allowed = true
+		case "cannot", "can't", "can’t":
+		default:
+			fail("expected can or cannot, got %q", words[1])
+			continue
+		}
+		object, rest, ok := objectOf(words[3:])
+		if !ok || len(rest) > 0 {
+			fail("expected an object as \"type id\" or \"type:id\" after %s", ex.Relation)
+			continue
+		}
+		ex.Object = object
+		p := pending{ex: ex}
+		if reason != "" {
+			if !ex.Allowed {
+				fail("a denied example has no reason")
+				continue
+			}
+			words := significant(strings.Fields(strings.TrimSuffix(reason, ".")))
+			switch {
+			case len(words) >= 3 && (words[1] == "of" || words[1] == "on"):
+				p.role = words[0]
+				if obj, rest, ok := objectOf(words[2:]); ok && len(rest) == 0 {
+					p.object = obj
+				} else if len(words) == 3 {
+					p.typ = words[2]
+				} else {
+					fail("cannot read the reason %q", reason)
+					continue
+				}
+			case len(words) == 2:
+				p.typ, p.role = words[0], words[1]
+			case len(words) == 1:
+				p.role, p.object = words[0], ex.Object
+			default:
+				fail("cannot read the reason %q", reason)
+				continue
+			}
+		}
+		if p.role != "" && !isName(p.role) {
+			fail("invalid role name %q", p.role)
+			continue
+		}
+		parsed = append(parsed, p)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("infer: %w", err)
+	}
+	// Fill in the objects reasons name by type only.
+	byType := map[string][]string{}
+	note := func(object string) {
+		typ, _, _ := strings.Cut(object, ":")
+		for _, o := range byType[typ] {
+			if o == object {
+				return
+			}
+		}
+		byType[typ] = append(byType[typ], object)
+	}
+	for _, p := range parsed {
+		note(p.ex.Object)
+		if p.object != "" {
+			note(p.object)
+		}
+	}
+	var out []*Example
+	for _, p := range parsed {
+		p.ex.Role, p.ex.On = p.role, p.object
+		if p.typ != "" {
+			switch objects := byType[p.typ]; len(objects) {
+			case 1:
+				p.ex.On = objects[0]
+			case 0:
+				errs = append(errs, &model.SyntaxError{Pos: p.ex.Pos, Msg: fmt.Sprintf("no %s is mentioned; name it as \"%s <id>\"", p.typ, p.typ)})
+			default:
+				errs = append(errs, &model.SyntaxError{Pos: p.ex.Pos, Msg: fmt.Sprintf("which %s? the examples mention %s", p.typ, strings.Join(objects, ", "))})
+			}
+		}
+		out = append(out, p.ex)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return out, nil
+}
+
+// objectOf reads "type:id" or "type id" from the start of words.
+func objectOf(words []string) (string, []string, bool) {
+	if len(words) == 0 {
+		return "", nil, false
+	}
+	if typ, id, ok := strings.Cut(words[0], ":"); ok {
+		return typ + ":" + id, words[1:], isName(typ) && id != ""
+	}
+	if len(words) < 2 || !isName(words[0]) {
+		return "", nil, false
+	}
+	return words[0] + ":" + words[1], words[2:], true
+}
+
+// filler are the words a reason may contain that carry no meaning here.
+var filler = map[string]bool{
+	"she's": true, "he's": true, "they're": true, "it's": true, "i'm": true, "you're": true,
+	"she": true, "he": true, "they": true, "is": true, "are": true, "am": true,
+	"a": true, "an": true, "the": true,
+}
+
+func significant(words []string) []string {
+	var out []string
+	for _, w := range words {
+		w = strings.ReplaceAll(w, "’", "'")
+		if !filler[strings.ToLower(w)] {
+			out = append(out, strings.TrimSuffix(w, "'s"))
+		}
+	}
+	return out
+}
+
+func isName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c != '_' && c != '-' && !('a' <= c && c <= 'z') && !('A' <= c && c <= 'Z') && !('0' <= c && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// Proposal is an inferred model with the tuples it needs, and the outcome
+// of every example on the evaluator.
+type Proposal struct {
+	Model    *model.Model
+	Tuples   []authz.Tuple
+	Outcomes []Outcome
+}
+
+// Outcome is the verified result of one example.
+type Outcome struct {
+	Example *Example
+	Allowed bool
+	Err     error
+}
+
+// OK reports whether the evaluator agreed with the example.
+func (o Outcome) OK() bool {
+	return o.Err == nil && o.Allowed == o.Example.Allowed
+}
+
+// Failed returns the outcomes that disagree with their example.
+func (p *Proposal) Failed() []Outcome {
+	var out []Outcome
+	for _, o := range p.Outcomes {
+		if !o.OK() {
+			out = append(out, o)
+		}
+	}
+	return out
+}
+
+// Propose infers a model and tuples from examples and checks every example
+// against them. A denied example reported as allowed usually means two
+// examples contradict each other; the proposal is returned regardless.
+func Propose(ctx context.Context, examples []*Example) (*Proposal, error) {
+	b := newBuilder()
+	for _, ex := range examples {
+		typ, _, _ := strings.Cut(ex.Object, ":")
+		b.relation(typ, ex.Relation)
+		switch {
+		case !ex.Allowed:
+		case ex.Role == "":
+			b.grant(typ, ex.Relation, ex.User, ex.Object)
+		case ex.On == ex.Object && ex.Role == ex.Relation:
+			b.grant(typ, ex.Role, ex.User, ex.Object)
+		case ex.On == ex.Object:
+			b.grant(typ, ex.Role, ex.User, ex.Object)
+			b.include(typ, ex.Relation, ex.Role)
+		default:
+			on, _, _ := strings.Cut(ex.On, ":")
+			if on == typ {
+				return nil, &model.SyntaxError{Pos: ex.Pos, Msg: fmt.Sprintf("%s and %s are both of type %s; only roles on other types are inherited", ex.Object, ex.On, typ)}
+			}
+			b.grant(on, ex.Role, ex.User, ex.On)
+			b.link(typ, on, ex.Object, ex.On)
+			b.include(typ, ex.Relation, ex.Role+" from "+on)
+		}
+	}
+	m, err := b.model()
+	if err != nil {
+		return nil, err
+	}
+	p := &Proposal{Model: m, Tuples: b.tuples}
+	e := eval.New(m, eval.NewTupleStore(b.tuples...))
+	for _, ex := range examples {
+		allowed, err := e.Check(ctx, authz.CheckRequest{User: ex.User, Relation: ex.Relation, Object: ex.Object})
+		p.Outcomes = append(p.Outcomes, Outcome{Example: ex, Allowed: allowed, Err: err})
+	}
+	return p, nil
+}
+
+// builder accumulates types and relations in the order examples mention
+// them.
+type builder struct {
+	types  []string
+	rels   map[string][]string
+	direct map[string]bool     // type#relation assignable to users
+	parts  map[string][]string // type#relation → included relations and TTUs
+	links  map[string]bool     // type#linked type
+	tuples []authz.Tuple
+	seen   map[authz.Tuple]bool
+}
+
+func newBuilder() *builder {
+	return &builder{
+		types:  []string{"user"},
+		rels:   map[string][]string{},
+		direct: map[string]bool{},
+		parts:  map[string][]string{},
+		links:  map[string]bool{},
+		seen:   map[authz.Tuple]bool{},
+	}
+}
+
+func (b *builder) typ(name string) {
+	for _, t := range b.types {
+		if t == name {
+			return
+		}
+	}
+	b.types = append(b.types, name)
+}
+
+func (b *builder) relation(typ, rel string) {
+	b.typ(typ)
+	for _, r := range b.rels[typ] {
+		if r == rel {
+			return
+		}
+	}
+	b.rels[typ] = append(b.rels[typ], rel)
+}
+
+func (b *builder) tuple(t authz.Tuple) {
+	if !b.seen[t] {
+		b.seen[t] = true
+		b.tuples = append(b.tuples, t)
+	}
+}
+
+func (b *builder) grant(typ, rel, user, object string) {
+	b.relation(typ, rel)
+	b.direct[typ+"#"+rel] = true
+	b.tuple(authz.Tuple{User: user, Relation: rel, Object: object})
+}
+
+func (b *builder) link(typ, on, object, parent string) {
+	b.relation(typ, on)
+	b.links[typ+"#"+on] = true
+	b.tuple(authz.Tuple{User: parent, Relation: on, Object: object})
+}
+
+func (b *builder) include(typ, rel, part string) {
+	key := typ + "#" + rel
+	for _, p := range b.parts[key] {
+		if p == part {
+			return
+		}
+	}
+	b.parts[key] = append(b.parts[key], part)
+}
+
+func (b *builder) model() (*model.Model, error) {
+	var src strings.Builder
+	src.WriteString("model\n  schema 1.1\n")
+	for _, typ := range b.types {
+		fmt.Fprintf(&src, "\ntype %s\n", typ)
+		if len(b.rels[typ]) == 0 {
+			continue
+		}
+		src.WriteString("  relations\n")
+		for _, rel := range b.rels[typ] {
+			key := typ + "#" + rel
+			var parts []string
+			switch {
+			case b.links[key]:
+				parts = append(parts, "["+rel+"]")
+			case b.direct[key] || len(b.parts[key]) == 0:
+				// A relation only denied still needs a definition.
+				parts = append(parts, "[user]")
+			}
+			parts = append(parts, b.parts[key]...)
+			fmt.Fprintf(&src, "    define %s: %s\n", rel, strings.Join(parts, " or "))
+		}
+	}
+	m, err := model.Parse("inferred", []byte(src.String()))
+	if err != nil {
+		return nil, fmt.Errorf("infer: %w", err)
+	}
+	if err := m.Validate(); err != nil {
+		return nil, fmt.Errorf("infer: %w", err)
+	}
+	return m, nil
+}
+
+// WriteText writes the model, the tuples and the outcome of every example.
+func (p *Proposal) WriteText(w io.Writer) error {
+	fmt.Fprint(w, p.Model)
+	fmt.Fprintln(w, "\n# Tuples")
+	for _, t := range p.Tuples {
+		fmt.Fprintln(w, t)
+	}
+	fmt.Fprintln(w, "\n# Examples")
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, o := range p.Outcomes {
+		status := "ok"
+		switch {
+		case o.Err != nil:
+			status = "error: " + o.Err.Error()
+		case !o.OK():
+			status = fmt.Sprintf("FAIL: got %s", verdict(o.Allowed))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", o.Example.Pos, o.Example.Text, status)
+	}
+	return tw.Flush()
+}
+
+func verdict(allowed bool) string {
+	if allowed {
+		return "allowed"
+	}
+	return "denied"
+}
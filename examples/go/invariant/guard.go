@@ -0,0 +1,128 @@
+package invariant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// ErrViolated is wrapped by the error a Guard returns when it rejects a
+// write.
+var ErrViolated = errors.New("invariant: write would violate invariant")
+
+// ViolationError lists the violations a rejected write would introduce.
+type ViolationError struct {
+	Violations []Violation
+}
+
+func (e *ViolationError) Error() string {
+	parts := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		parts[i] = v.String()
+	}
+	return ErrViolated.Error() + ": " + strings.Join(parts, "; ")
+}
+
+func (e *ViolationError) Unwrap() error { return ErrViolated }
+
+// Scope selects how much existing state a Guard reads before simulating a
+// write.
+type Scope int
+
+const (
+	// ScopeBatch evaluates the mutation on its own, without reads. It catches
+	// batches that are self-contradictory but not conflicts with stored data.
+	ScopeBatch Scope = iota
+	// ScopeObjects reads the stored tuples of every object the batch touches.
+	ScopeObjects
+	// ScopeStore reads the whole store; accurate but only practical for small
+	// stores.
+	ScopeStore
+)
+
+// Guard is an authz.WriteGuard that rejects mutations producing new
+// invariant violations on the objects they touch. Violations that already
+// existed before the write are not reported. Install it with
+// authz.WithWriteGuard and skip it with authz.BypassWriteGuards.
+type Guard struct {
+	Model      *model.Model
+	Backend    authz.Backend
+	Invariants []Invariant
+	Scope      Scope
+}
+
+// GuardWrite implements authz.WriteGuard.
+func (g *Guard) GuardWrite(ctx context.Context, writes, deletes []authz.Tuple) error {
+	current, err := g.read(ctx, writes, deletes)
+	if err != nil {
+		return fmt.Errorf("invariant: guard read: %w", err)
+	}
+	before, err := CheckAll(ctx, NewSnapshot(g.Model, current), g.Invariants)
+	if err != nil {
+		return err
+	}
+	after, err := CheckAll(ctx, NewSnapshot(g.Model, apply(current, writes, deletes)), g.Invariants)
+	if err != nil {
+		return err
+	}
+
+	touched := map[string]bool{}
+	for _, t := range append(append([]authz.Tuple(nil), writes...), deletes...) {
+		touched[t.Object] = true
+	}
+	existing := map[Violation]bool{}
+	for _, v := range before {
+		existing[v] = true
+	}
+	var introduced []Violation
+	for _, v := range after {
+		if touched[v.Object] && !existing[v] {
+			introduced = append(introduced, v)
+		}
+	}
+	if len(introduced) > 0 {
+		return &ViolationError{Violations: introduced}
+	}
+	return nil
+}
+
+func (g *Guard) read(ctx context.Context, writes, deletes []authz.Tuple) ([]authz.Tuple, error) {
+	switch g.Scope {
+	case ScopeStore:
+		return authz.ReadAll(ctx, g.Backend, authz.Tuple{})
+	case ScopeObjects:
+		seen := map[string]bool{}
+		var out []authz.Tuple
+		for _, t := range append(append([]authz.Tuple(nil), writes...), deletes...) {
+			if seen[t.Object] {
+				continue
+			}
+			seen[t.Object] = true
+			ts, err := authz.ReadAll(ctx, g.Backend, authz.Tuple{Object: t.Object})
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, ts...)
+		}
+		return out, nil
+	}
+	return deletes, nil
+}
+
+func apply(current, writes, deletes []authz.Tuple) []authz.Tuple {
+	gone := map[authz.Tuple]bool{}
+	for _, t := range deletes {
+		gone[t] = true
+	}
+	var out []authz.Tuple
+	for _, t := range current {
+		if !gone[t] {
+			out = append(out, t)
+		}
+	}
+	return append(out, writes...)
+}
@@ -0,0 +1,179 @@
+// Package invariant declares properties a store must always satisfy, such
+// as "no user is both viewer and blocked" or "every project links to exactly
+// one organization", and checks them against tuple sets.
+//
+// Invariants are evaluated on a Snapshot: a model plus a set of tuples,
+// resolved with the embedded evaluator. Search looks for tuple combinations
+// the model permits that violate an invariant; Verify checks a live store.
+package invariant
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/eval"
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// Violation is one breach of an invariant.
+type Violation struct {
+	Invariant string
+	Object    string
+	User      string
+	Detail    string
+}
+
+func (v Violation) String() string {
+	s := v.Invariant + ": " + v.Object
+	if v.User != "" {
+		s += " user " + v.User
+	}
+	return s + ": " + v.Detail
+}
+
+// Invariant is a property of a snapshot.
+type Invariant interface {
+	Name() string
+	Check(ctx context.Context, s *Snapshot) ([]Violation, error)
+}
+
+// Snapshot is a model and a tuple set resolved in-process.
+type Snapshot struct {
+	Model *model.Model
+	Store *eval.TupleStore
+	eval  *eval.Evaluator
+}
+
+// NewSnapshot builds a snapshot of tuples under m.
+func NewSnapshot(m *model.Model, tuples []authz.Tuple) *Snapshot {
+	store := eval.NewTupleStore(tuples...)
+	return &Snapshot{Model: m, Store: store, eval: eval.New(m, store)}
+}
+
+// Check resolves a check on the snapshot.
+func (s *Snapshot) Check(ctx context.Context, user, relation, object string) (bool, error) {
+	return s.eval.Check(ctx, authz.CheckRequest{User: user, Relation: relation, Object: object})
+}
+
+// Users returns every concrete user (no usersets or wildcards) that
+// appears in the snapshot, sorted.
+func (s *Snapshot) Users() []string {
+	set := map[string]bool{}
+	for _, t := range s.Store.Tuples() {
+		if !strings.Contains(t.User, "#") && !strings.HasSuffix(t.User, ":*") {
+			set[t.User] = true
+		}
+	}
+	users := make([]string, 0, len(set))
+	for u := range set {
+		users = append(users, u)
+	}
+	sort.Strings(users)
+	return users
+}
+
+// CheckAll evaluates every invariant and returns all violations.
+func CheckAll(ctx context.Context, s *Snapshot, invs []Invariant) ([]Violation, error) {
+	var out []Violation
+	for _, inv := range invs {
+		vs, err := inv.Check(ctx, s)
+		if err != nil {
+			return nil, fmt.Errorf("invariant %s: %w", inv.Name(), err)
+		}
+		out = append(out, vs...)
+	}
+	return out, nil
+}
+
+// Verify reads every tuple from b and checks the invariants against them
+// under m.
+func Verify(ctx context.Context, m *model.Model, b authz.Backend, invs []Invariant) ([]Violation, error) {
+	tuples, err := authz.ReadAll(ctx, b, authz.Tuple{})
+	if err != nil {
+		return nil, fmt.Errorf("invariant: read store: %w", err)
+	}
+	return CheckAll(ctx, NewSnapshot(m, tuples), invs)
+}
+
+// Exclusive requires that no user holds more than one of relations on any
+// object of objectType, e.g. Exclusive("project", "viewer", "blocked").
+func Exclusive(objectType string, relations ...string) Invariant {
+	return &exclusive{objectType: objectType, relations: relations}
+}
+
+type exclusive struct {
+	objectType string
+	relations  []string
+}
+
+func (e *exclusive) Name() string {
+	return fmt.Sprintf("exclusive(%s: %s)", e.objectType, strings.Join(e.relations, ", "))
+}
+
+func (e *exclusive) Check(ctx context.Context, s *Snapshot) ([]Violation, error) {
+	var out []Violation
+	for _, obj := range s.Store.Objects(e.objectType) {
+		for _, user := range s.Users() {
+			var held []string
+			for _, rel := range e.relations {
+				ok, err := s.Check(ctx, user, rel, obj)
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					held = append(held, rel)
+				}
+			}
+			if len(held) > 1 {
+				out = append(out, Violation{Invariant: e.Name(), Object: obj, User: user, Detail: "holds " + strings.Join(held, " and ")})
+			}
+		}
+	}
+	return out, nil
+}
+
+// Cardinality requires every object of objectType to have between min and
+// max direct tuples for relation. A negative max means unbounded. Objects
+// count as present when they appear in any tuple.
+func Cardinality(objectType, relation string, min, max int) Invariant {
+	return &cardinality{objectType: objectType, relation: relation, min: min, max: max}
+}
+
+type cardinality struct {
+	objectType, relation string
+	min, max             int
+}
+
+func (c *cardinality) Name() string {
+	return fmt.Sprintf("cardinality(%s#%s in [%d,%d])", c.objectType, c.relation, c.min, c.max)
+}
+
+func (c *cardinality) Check(ctx context.Context, s *Snapshot) ([]Violation, error) {
+	var out []Violation
+	for _, obj := range s.Store.Objects(c.objectType) {
+		n := len(s.Store.Users(obj, c.relation))
+		if n < c.min || (c.max >= 0 && n > c.max) {
+			out = append(out, Violation{Invariant: c.Name(), Object: obj, Detail: fmt.Sprintf("has %d %s tuples", n, c.relation)})
+		}
+	}
+	return out, nil
+}
+
+// Func adapts a function to an Invariant.
+func Func(name string, fn func(ctx context.Context, s *Snapshot) ([]Violation, error)) Invariant {
+	return &funcInvariant{name: name, fn: fn}
+}
+
+type funcInvariant struct {
+	name string
+	fn   func(context.Context, *Snapshot) ([]Violation, error)
+}
+
+func (f *funcInvariant) Name() string { return f.name }
+
+func (f *funcInvariant) Check(ctx context.Context, s *Snapshot) ([]Violation, error) {
+	return f.fn(ctx, s)
+}
@@ -0,0 +1,85 @@
+package invariant
+
+import (
+	"context"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/fuzz"
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// SearchConfig controls a property search. Zero values select the defaults
+// noted.
+type SearchConfig struct {
+	Seed       int64
+	Runs       int // tuple sets tried, default 200
+	Tuples     int // tuples per set, default 15
+	IDsPerType int // default 2
+}
+
+// Counterexample is a minimal tuple set violating an invariant.
+type Counterexample struct {
+	Invariant  string
+	Tuples     []authz.Tuple
+	Violations []Violation
+}
+
+// Search generates random tuple sets valid under m and returns, for each
+// invariant that can be broken, the first violating set shrunk to a minimal
+// form. An empty result means no violation was found, not that none exists.
+func Search(ctx context.Context, m *model.Model, invs []Invariant, cfg SearchConfig) ([]Counterexample, error) {
+	if cfg.Runs <= 0 {
+		cfg.Runs = 200
+	}
+	if cfg.Tuples <= 0 {
+		cfg.Tuples = 15
+	}
+	if cfg.IDsPerType <= 0 {
+		cfg.IDsPerType = 2
+	}
+	gen := fuzz.NewGenerator(m, cfg.Seed, cfg.IDsPerType)
+	remaining := append([]Invariant(nil), invs...)
+	var found []Counterexample
+	for run := 0; run < cfg.Runs && len(remaining) > 0; run++ {
+		tuples := gen.Tuples(cfg.Tuples)
+		var still []Invariant
+		for _, inv := range remaining {
+			vs, err := inv.Check(ctx, NewSnapshot(m, tuples))
+			if err != nil {
+				return found, err
+			}
+			if len(vs) == 0 {
+				still = append(still, inv)
+				continue
+			}
+			minimal, vs, err := shrink(ctx, m, inv, tuples, vs)
+			if err != nil {
+				return found, err
+			}
+			found = append(found, Counterexample{Invariant: inv.Name(), Tuples: minimal, Violations: vs})
+		}
+		remaining = still
+	}
+	return found, nil
+}
+
+// shrink drops tuples one at a time, keeping each removal that preserves a
+// violation, and repeats until a full pass removes nothing.
+func shrink(ctx context.Context, m *model.Model, inv Invariant, tuples []authz.Tuple, vs []Violation) ([]authz.Tuple, []Violation, error) {
+	for changed := true; changed; {
+		changed = false
+		for i := 0; i < len(tuples); {
+			candidate := append(append([]authz.Tuple(nil), tuples[:i]...), tuples[i+1:]...)
+			cvs, err := inv.Check(ctx, NewSnapshot(m, candidate))
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(cvs) > 0 {
+				tuples, vs, changed = candidate, cvs, true
+				continue
+			}
+			i++
+		}
+	}
+	return tuples, vs, nil
+}
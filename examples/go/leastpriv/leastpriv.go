@@ -0,0 +1,259 @@
+// Package leastpriv correlates what users were granted with the checks they
+// actually passed over a window and recommends revoking grants nobody used
+// and downgrading the rest to the weakest relation that still covers what
+// was used, e.g. editor to viewer:
+//
+//	agg := &decisions.Aggregator{Since: time.Now().Add(-90 * 24 * time.Hour)}
+//	// ... fed by authz.WithDecisionObserver(agg) or agg.ReadLog ...
+//	e := &leastpriv.Engine{Backend: b, Exercised: agg.Allowed()}
+//	recs, err := e.Recommend(ctx)
+//	reqs, err := e.Propose(ctx, gate, "service:leastpriv", recs)
+//
+// Propose stages each recommendation with an approval.Gate, so a human
+// approves every revocation before it is written. Recommendations come
+// from replaying the window's allowed checks on the embedded evaluator
+// with the grant removed or replaced: a grant is only needed when some
+// allowed check fails without it. They are as good as the window is
+// representative, and conditions are not evaluated.
+package leastpriv
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/bogdanticu88/openfga-examples/approval"
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/eval"
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// Kind classifies a Recommendation.
+type Kind string
+
+const (
+	// Revoke recommends deleting a grant no allowed check needed.
+	Revoke Kind = "revoke"
+	// Downgrade recommends replacing a grant with a weaker relation that
+	// would still have allowed every check it was needed for.
+	Downgrade Kind = "downgrade"
+)
+
+// Recommendation is one proposed change to a grant.
+type Recommendation struct {
+	Kind  Kind
+	Tuple authz.Tuple
+	// To is the relation a Downgrade replaces Tuple.Relation with.
+	To string
+	// Needed is the number of distinct allowed checks that fail without
+	// the grant; zero for Revoke.
+	Needed int
+}
+
+// Changes returns the tuples applying r writes and deletes.
+func (r Recommendation) Changes() (writes, deletes []authz.Tuple) {
+	deletes = []authz.Tuple{r.Tuple}
+	if r.Kind == Downgrade {
+		writes = []authz.Tuple{{User: r.Tuple.User, Relation: r.To, Object: r.Tuple.Object}}
+	}
+	return writes, deletes
+}
+
+func (r Recommendation) String() string {
+	if r.Kind == Downgrade {
+		return fmt.Sprintf("downgrade %s to %s: the %d checks it was needed for pass with %s", r.Tuple, r.To, r.Needed, r.To)
+	}
+	return fmt.Sprintf("revoke %s: no allowed check needed it", r.Tuple)
+}
+
+// Engine computes recommendations against a store.
+type Engine struct {
+	Backend authz.Backend
+	// Model defaults to the store's active model.
+	Model *model.Model
+	// Exercised are the allowed checks of the window, keyed by user,
+	// relation and object; see decisions.Aggregator.Allowed. Only the keys
+	// are used.
+	Exercised map[authz.Tuple]int
+	// UserTypes are the user types whose grants are reviewed; default
+	// "user". Usersets and wildcards are never reviewed.
+	UserTypes []string
+	// Relations limits the reviewed grants to these relations, by name
+	// ("editor") or qualified by type ("document#editor"); default all.
+	Relations []string
+}
+
+// Recommend returns a recommendation for every reviewed grant that is not
+// fully needed, sorted by tuple. Each assumes the ones before it are
+// applied: of two redundant grants only the first is revoked, so
+// rejecting one recommendation may make a later one unsafe.
+func (e *Engine) Recommend(ctx context.Context) ([]Recommendation, error) {
+	m := e.Model
+	if m == nil {
+		var err error
+		if m, err = e.Backend.ReadModel(ctx, ""); err != nil {
+			return nil, fmt.Errorf("leastpriv: read model: %w", err)
+		}
+	}
+	all, err := authz.ReadAll(ctx, e.Backend, authz.Tuple{})
+	if err != nil {
+		return nil, fmt.Errorf("leastpriv: read: %w", err)
+	}
+	eval.SortTuples(all)
+	ev := eval.New(m, eval.NewTupleStore(all...))
+	exercised := map[string][]authz.CheckRequest{}
+	for t := range e.Exercised {
+		exercised[t.User] = append(exercised[t.User], authz.CheckRequest{User: t.User, Relation: t.Relation, Object: t.Object})
+	}
+	userTypes := e.UserTypes
+	if len(userTypes) == 0 {
+		userTypes = []string{"user"}
+	}
+	var recs []Recommendation
+	for _, t := range all {
+		userType, id, _ := strings.Cut(t.User, ":")
+		typ, _, _ := strings.Cut(t.Object, ":")
+		if !slices.Contains(userTypes, userType) || strings.Contains(id, "#") || id == "*" ||
+			len(e.Relations) > 0 && !slices.Contains(e.Relations, t.Relation) && !slices.Contains(e.Relations, typ+"#"+t.Relation) {
+			continue
+		}
+		rec, ok, err := e.review(ctx, m, ev, t, exercised[t.User])
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			recs = append(recs, rec)
+		}
+	}
+	return recs, nil
+}
+
+// review replays checks without t, and then with each weaker relation in
+// its place, narrowest first. A recommendation is left applied to the
+// store, so later grants are reviewed as if it were accepted; otherwise t
+// is restored.
+func (e *Engine) review(ctx context.Context, m *model.Model, ev *eval.Evaluator, t authz.Tuple, checks []authz.CheckRequest) (rec Recommendation, ok bool, err error) {
+	store := ev.Store()
+	if err := store.Write(nil, []authz.Tuple{t}); err != nil {
+		return Recommendation{}, false, fmt.Errorf("leastpriv: %w", err)
+	}
+	defer func() {
+		if !ok {
+			store.Write([]authz.Tuple{t}, nil)
+		}
+	}()
+	needed, err := failing(ctx, ev, checks)
+	if err != nil {
+		return Recommendation{}, false, err
+	}
+	if len(needed) == 0 {
+		return Recommendation{Kind: Revoke, Tuple: t}, true, nil
+	}
+	typ, _, _ := strings.Cut(t.Object, ":")
+	for _, rel := range weaker(m, typ, t.Relation) {
+		alt := authz.Tuple{User: t.User, Relation: rel, Object: t.Object, Condition: t.Condition}
+		if m.ValidateTuple(alt.User, alt.Relation, alt.Object, alt.Condition.Name) != nil || store.Has(alt) {
+			continue
+		}
+		if err := store.Write([]authz.Tuple{alt}, nil); err != nil {
+			return Recommendation{}, false, fmt.Errorf("leastpriv: %w", err)
+		}
+		still, err := failing(ctx, ev, needed)
+		if err == nil && len(still) == 0 {
+			return Recommendation{Kind: Downgrade, Tuple: t, To: rel, Needed: len(needed)}, true, nil
+		}
+		store.Write(nil, []authz.Tuple{alt})
+		if err != nil {
+			return Recommendation{}, false, err
+		}
+	}
+	return Recommendation{}, false, nil
+}
+
+// failing returns the checks ev denies.
+func failing(ctx context.Context, ev *eval.Evaluator, checks []authz.CheckRequest) ([]authz.CheckRequest, error) {
+	var out []authz.CheckRequest
+	for _, c := range checks {
+		ok, err := ev.Check(ctx, c)
+		if err != nil {
+			return nil, fmt.Errorf("leastpriv: check %s#%s@%s: %w", c.Object, c.Relation, c.User, err)
+		}
+		if !ok {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+// weaker returns the relations of typ that holders of relation are
+// guaranteed through union branches, those that guarantee the fewest
+// others first.
+func weaker(m *model.Model, typ, relation string) []string {
+	t := m.Type(typ)
+	if t == nil {
+		return nil
+	}
+	// held[x] are the relations guaranteed to holders of x, x included.
+	held := map[string][]string{}
+	for _, r := range t.Relations {
+		for _, s := range stronger(m, typ, r.Name) {
+			held[s] = append(held[s], r.Name)
+		}
+	}
+	var out []string
+	for _, r := range held[relation] {
+		if r != relation {
+			out = append(out, r)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return len(held[out[i]]) < len(held[out[j]]) })
+	return out
+}
+
+// stronger returns the relations of typ whose holders are guaranteed
+// relation, starting with relation itself. Only union branches count: an
+// intersection or difference operand alone does not guarantee access.
+func stronger(m *model.Model, typ, relation string) []string {
+	out := []string{relation}
+	for i := 0; i < len(out); i++ {
+		r := m.Relation(typ, out[i])
+		if r == nil {
+			continue
+		}
+		for _, c := range unionLeaves(r.Rewrite) {
+			if comp, ok := c.(*model.Computed); ok && !slices.Contains(out, comp.Relation) {
+				out = append(out, comp.Relation)
+			}
+		}
+	}
+	return out
+}
+
+func unionLeaves(rw model.Rewrite) []model.Rewrite {
+	if u, ok := rw.(*model.Union); ok {
+		var out []model.Rewrite
+		for _, c := range u.Children {
+			out = append(out, unionLeaves(c)...)
+		}
+		return out
+	}
+	return []model.Rewrite{rw}
+}
+
+// Propose stages each recommendation as its own approval request by
+// requester, so approvers can accept some and reject others. Requests
+// staged before a failure are returned with the error.
+func (e *Engine) Propose(ctx context.Context, gate *approval.Gate, requester string, recs []Recommendation) ([]approval.Request, error) {
+	var out []approval.Request
+	for _, r := range recs {
+		writes, deletes := r.Changes()
+		req, err := gate.Submit(ctx, requester, writes, deletes)
+		if err != nil {
+			return out, fmt.Errorf("leastpriv: %s: %w", r.Tuple, err)
+		}
+		out = append(out, req)
+	}
+	return out, nil
+}
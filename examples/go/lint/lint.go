@@ -0,0 +1,117 @@
+// Package lint reports questionable constructs in authorization models:
+// things the server accepts but that are usually mistakes. Each Rule has a
+// stable ID so findings can be suppressed, counted and annotated in CI.
+package lint
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// Severity ranks findings.
+type Severity int
+
+const (
+	Info Severity = iota
+	Warning
+	Error
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Info:
+		return "info"
+	case Warning:
+		return "warning"
+	}
+	return "error"
+}
+
+// Finding is one reported problem, located at the offending relation.
+type Finding struct {
+	Rule     string
+	Severity Severity
+	Pos      model.Pos
+	Type     string
+	Relation string
+	Message  string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s: %s [%s]", f.Pos, f.Severity, f.Message, f.Rule)
+}
+
+// Rule is one check.
+type Rule struct {
+	ID          string
+	Description string
+	Check       func(m *model.Model, cfg *Config) []Finding
+}
+
+// Config tunes a run. The zero value runs every rule.
+type Config struct {
+	// Public lists relations meant to be public, as "type#relation";
+	// wildcard rules skip them.
+	Public []string
+	// Disable lists rule IDs not to run.
+	Disable []string
+}
+
+func (c *Config) public(typ, rel string) bool {
+	for _, p := range c.Public {
+		if p == typ+"#"+rel {
+			return true
+		}
+	}
+	return false
+}
+
+// Rules is the registry of built-in rules, in reporting order.
+var Rules = []Rule{
+	publicPrivileged,
+	publicRelation,
+	publicInherited,
+}
+
+// Lint runs the enabled rules against m and returns findings sorted by
+// position.
+func Lint(m *model.Model, cfg Config) []Finding {
+	disabled := map[string]bool{}
+	for _, id := range cfg.Disable {
+		disabled[id] = true
+	}
+	var out []Finding
+	for _, r := range Rules {
+		if disabled[r.ID] {
+			continue
+		}
+		for _, f := range r.Check(m, &cfg) {
+			f.Rule = r.ID
+			out = append(out, f)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i].Pos, out[j].Pos
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Col < b.Col
+	})
+	return out
+}
+
+// Max returns the highest severity in findings, or -1 when there are none.
+func Max(findings []Finding) Severity {
+	max := Severity(-1)
+	for _, f := range findings {
+		if f.Severity > max {
+			max = f.Severity
+		}
+	}
+	return max
+}
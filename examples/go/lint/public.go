@@ -0,0 +1,154 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// privileged matches relation names that usually confer more than read
+// access.
+var privileged = regexp.MustCompile(`(?i)(owner|admin|editor|writer|write|edit|manage|delete|create|update|approve|grant|share|member)`)
+
+var publicPrivileged = Rule{
+	ID:          "public-privileged",
+	Description: "a wildcard is assignable to a relation that looks like it grants write or admin access",
+	Check: func(m *model.Model, cfg *Config) []Finding {
+		var out []Finding
+		eachRelation(m, func(t *model.Type, r *model.Relation) {
+			if types := r.WildcardTypes(); len(types) > 0 && privileged.MatchString(r.Name) && !cfg.public(t.Name, r.Name) {
+				out = append(out, Finding{Severity: Warning, Pos: r.Pos, Type: t.Name, Relation: r.Name,
+					Message: fmt.Sprintf("%s#%s accepts %s:*, so anyone could become %s", t.Name, r.Name, types[0], r.Name)})
+			}
+		})
+		return out
+	},
+}
+
+var publicRelation = Rule{
+	ID:          "public-relation",
+	Description: "a wildcard is assignable to a relation not declared public",
+	Check: func(m *model.Model, cfg *Config) []Finding {
+		var out []Finding
+		eachRelation(m, func(t *model.Type, r *model.Relation) {
+			types := r.WildcardTypes()
+			if len(types) == 0 || privileged.MatchString(r.Name) || cfg.public(t.Name, r.Name) {
+				return
+			}
+			out = append(out, Finding{Severity: Info, Pos: r.Pos, Type: t.Name, Relation: r.Name,
+				Message: fmt.Sprintf("%s#%s can be made public to %s:*; list it in Config.Public if intended", t.Name, r.Name, strings.Join(types, ":*, "))})
+		})
+		return out
+	},
+}
+
+var publicInherited = Rule{
+	ID:          "public-inherited",
+	Description: "a relation without wildcards becomes public through another relation",
+	Check: func(m *model.Model, cfg *Config) []Finding {
+		a := &publicity{m: m, memo: map[string]string{}, visiting: map[string]bool{}}
+		var out []Finding
+		eachRelation(m, func(t *model.Type, r *model.Relation) {
+			if len(r.WildcardTypes()) > 0 || cfg.public(t.Name, r.Name) {
+				return
+			}
+			via := a.via(t.Name, r.Name)
+			if via == "" {
+				return
+			}
+			// Inheriting from a declared-public relation is expected unless
+			// the inheriting relation is itself privileged.
+			sev := Info
+			if privileged.MatchString(r.Name) {
+				sev = Warning
+			} else if vt, vr, _ := strings.Cut(via, "#"); cfg.public(vt, vr) {
+				return
+			}
+			out = append(out, Finding{Severity: sev, Pos: r.Pos, Type: t.Name, Relation: r.Name,
+				Message: fmt.Sprintf("%s#%s is public whenever %s is, although it accepts no wildcard", t.Name, r.Name, via)})
+		})
+		return out
+	},
+}
+
+// publicity finds, for each relation, a wildcard-assignable relation it
+// inherits from, if any.
+type publicity struct {
+	m        *model.Model
+	memo     map[string]string
+	visiting map[string]bool
+}
+
+// via returns the type#relation through which typ#rel can include a
+// wildcard, or "".
+func (p *publicity) via(typ, rel string) string {
+	key := typ + "#" + rel
+	if v, ok := p.memo[key]; ok {
+		return v
+	}
+	if p.visiting[key] {
+		return ""
+	}
+	p.visiting[key] = true
+	defer delete(p.visiting, key)
+	r := p.m.Relation(typ, rel)
+	v := ""
+	if r != nil {
+		if len(r.WildcardTypes()) > 0 {
+			v = key
+		} else {
+			v = p.rewrite(typ, r.Rewrite)
+		}
+	}
+	p.memo[key] = v
+	return v
+}
+
+func (p *publicity) rewrite(typ string, rw model.Rewrite) string {
+	switch n := rw.(type) {
+	case *model.Computed:
+		return p.via(typ, n.Relation)
+	case *model.TupleToUserset:
+		ts := p.m.Relation(typ, n.Tupleset)
+		if ts == nil {
+			return ""
+		}
+		for _, ref := range ts.DirectTypes() {
+			if v := p.via(ref.Type, n.Computed); v != "" {
+				return v
+			}
+		}
+	case *model.Union:
+		for _, c := range n.Children {
+			if v := p.rewrite(typ, c); v != "" {
+				return v
+			}
+		}
+	case *model.Intersection:
+		// Public only if every operand can be.
+		v := ""
+		for _, c := range n.Children {
+			cv := p.rewrite(typ, c)
+			if cv == "" {
+				return ""
+			}
+			if v == "" {
+				v = cv
+			}
+		}
+		return v
+	case *model.Difference:
+		return p.rewrite(typ, n.Base)
+	}
+	return ""
+}
+
+func eachRelation(m *model.Model, fn func(*model.Type, *model.Relation)) {
+	for _, t := range m.Types {
+		for _, r := range t.Relations {
+			fn(t, r)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package lint
+
+import (
+	"path/filepath"
+
+	"github.com/bogdanticu88/openfga-examples/sarif"
+)
+
+// Level returns the SARIF level for s.
+func (s Severity) Level() string {
+	switch s {
+	case Info:
+		return sarif.LevelNote
+	case Warning:
+		return sarif.LevelWarning
+	}
+	return sarif.LevelError
+}
+
+// SARIF returns findings as a SARIF log listing every registered rule, so
+// code review tools show each finding inline at the offending relation.
+func SARIF(findings []Finding) *sarif.Log {
+	rules := make([]sarif.Rule, len(Rules))
+	for i, r := range Rules {
+		rules[i] = sarif.Rule{ID: r.ID, ShortDescription: sarif.Message{Text: r.Description}}
+	}
+	log := sarif.New("fgalint", rules)
+	for _, f := range findings {
+		res := sarif.Result{RuleID: f.Rule, Level: f.Severity.Level(), Message: sarif.Message{Text: f.Message}}
+		if f.Pos.File != "" {
+			res.Locations = sarif.At(filepath.ToSlash(f.Pos.File), f.Pos.Line, f.Pos.Col)
+		}
+		log.Add(res)
+	}
+	return log
+}
@@ -0,0 +1,272 @@
+// Package macro adds parameterized relation templates to the model DSL,
+// expanded at build time, so that many similar types share one definition
+// instead of copies that drift apart:
+//
+//	macro standard_resource(parent)
+//	  define parent: [$parent]
+//	  define owner: [user] or owner from parent
+//	  define editor: [user] or owner or editor from parent
+//	  define viewer: [user] or editor or viewer from parent
+//
+//	type document
+//	  relations
+//	    use standard_resource(folder)
+//	    define commenter: [user] or editor
+//
+// A macro's body is the define lines indented under it; $name is replaced
+// with the argument for parameter name. A use line expands in place, and
+// its relations take the use line's position, so validation errors point
+// at the type using the macro. Macros cannot use other macros. Parse
+// handles a single file; package modules expands macros across the
+// fragments of a modular model.
+package macro
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// Macro is a parsed macro definition.
+type Macro struct {
+	Name   string
+	Params []string
+	Pos    model.Pos
+	body   []line
+}
+
+type line struct {
+	text string // "name: rewrite", after the define keyword
+	pos  model.Pos
+}
+
+// Use is one use line.
+type Use struct {
+	Macro string
+	Args  []string
+	Pos   model.Pos
+}
+
+// ParseFile reads and parses a .fga file with macros.
+func ParseFile(path string) (*model.Model, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(path, src)
+}
+
+// Parse parses DSL source with macros and returns the expanded model.
+// Like model.Parse, it does not validate the result.
+func Parse(file string, src []byte) (*model.Model, error) {
+	dsl, macros, uses, err := Split(file, src)
+	if err != nil {
+		return nil, err
+	}
+	m, err := model.Parse(file, dsl)
+	if err != nil {
+		return nil, err
+	}
+	table, err := Table(macros)
+	if err != nil {
+		return nil, err
+	}
+	if err := Expand(m.Types, table, uses); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Split removes the macro definitions and use lines from src, leaving
+// blank lines so positions in the remaining DSL are unchanged, and
+// returns them.
+func Split(file string, src []byte) ([]byte, []*Macro, []*Use, error) {
+	lines := strings.Split(strings.ReplaceAll(string(src), "\r\n", "\n"), "\n")
+	var macros []*Macro
+	var uses []*Use
+	var cur *Macro
+	for i, raw := range lines {
+		text, col := strip(raw)
+		if text == "" {
+			continue
+		}
+		pos := model.Pos{File: file, Line: i + 1, Col: col}
+		if cur != nil {
+			if col > cur.Pos.Col {
+				rest, ok := strings.CutPrefix(text, "define ")
+				if !ok {
+					return nil, nil, nil, &model.SyntaxError{Pos: pos, Msg: "macro bodies hold define lines only"}
+				}
+				cur.body = append(cur.body, line{text: rest, pos: pos})
+				lines[i] = ""
+				continue
+			}
+			cur = nil
+		}
+		word, rest, _ := strings.Cut(text, " ")
+		switch word {
+		case "macro":
+			name, params, err := call(pos, rest)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			for _, p := range params {
+				if !isIdent(p) {
+					return nil, nil, nil, &model.SyntaxError{Pos: pos, Msg: fmt.Sprintf("invalid parameter name %q", p)}
+				}
+			}
+			cur = &Macro{Name: name, Params: params, Pos: pos}
+			macros = append(macros, cur)
+			lines[i] = ""
+		case "use":
+			name, args, err := call(pos, rest)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			uses = append(uses, &Use{Macro: name, Args: args, Pos: pos})
+			lines[i] = ""
+		}
+	}
+	for _, m := range macros {
+		if len(m.body) == 0 {
+			return nil, nil, nil, &model.SyntaxError{Pos: m.Pos, Msg: fmt.Sprintf("macro %s has no define lines", m.Name)}
+		}
+	}
+	return []byte(strings.Join(lines, "\n")), macros, uses, nil
+}
+
+// call parses "name(arg, ...)".
+func call(pos model.Pos, s string) (string, []string, error) {
+	open := strings.Index(s, "(")
+	if open < 0 || !strings.HasSuffix(s, ")") {
+		return "", nil, &model.SyntaxError{Pos: pos, Msg: fmt.Sprintf("expected name(arguments), got %q", s)}
+	}
+	name := strings.TrimSpace(s[:open])
+	if !isIdent(name) {
+		return "", nil, &model.SyntaxError{Pos: pos, Msg: fmt.Sprintf("invalid macro name %q", name)}
+	}
+	var args []string
+	if inner := strings.TrimSpace(s[open+1 : len(s)-1]); inner != "" {
+		for _, a := range strings.Split(inner, ",") {
+			a = strings.TrimSpace(a)
+			if a == "" || strings.ContainsAny(a, "() ") {
+				return "", nil, &model.SyntaxError{Pos: pos, Msg: fmt.Sprintf("invalid argument %q", a)}
+			}
+			args = append(args, a)
+		}
+	}
+	return name, args, nil
+}
+
+// Table indexes macros by name, failing on a name defined twice.
+func Table(macros []*Macro) (map[string]*Macro, error) {
+	table := map[string]*Macro{}
+	var errs []error
+	for _, m := range macros {
+		if prev, ok := table[m.Name]; ok {
+			errs = append(errs, &model.SyntaxError{Pos: m.Pos, Msg: fmt.Sprintf("macro %s already defined at %s", m.Name, prev.Pos)})
+			continue
+		}
+		table[m.Name] = m
+	}
+	return table, errors.Join(errs...)
+}
+
+// Expand adds the relations of each use to the type it appears in: the
+// type in the same file whose definition most closely precedes it. The
+// relations of an expanded type are kept in source order.
+func Expand(types []*model.Type, macros map[string]*Macro, uses []*Use) error {
+	var errs []error
+	expanded := map[*model.Type]bool{}
+	for _, u := range uses {
+		var owner *model.Type
+		for _, t := range types {
+			if t.Pos.File == u.Pos.File && t.Pos.Line < u.Pos.Line && (owner == nil || t.Pos.Line > owner.Pos.Line) {
+				owner = t
+			}
+		}
+		if owner == nil {
+			errs = append(errs, &model.SyntaxError{Pos: u.Pos, Msg: "use outside of a type"})
+			continue
+		}
+		rels, err := u.expand(macros)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		owner.Relations = append(owner.Relations, rels...)
+		expanded[owner] = true
+	}
+	for t := range expanded {
+		sort.SliceStable(t.Relations, func(i, j int) bool { return t.Relations[i].Pos.Line < t.Relations[j].Pos.Line })
+	}
+	return errors.Join(errs...)
+}
+
+func (u *Use) expand(macros map[string]*Macro) ([]*model.Relation, error) {
+	m, ok := macros[u.Macro]
+	if !ok {
+		return nil, &model.SyntaxError{Pos: u.Pos, Msg: fmt.Sprintf("unknown macro %s", u.Macro)}
+	}
+	if len(u.Args) != len(m.Params) {
+		return nil, &model.SyntaxError{Pos: u.Pos, Msg: fmt.Sprintf("macro %s takes %d arguments, got %d", m.Name, len(m.Params), len(u.Args))}
+	}
+	// Longer names first, so $parent_type is not read as $parent.
+	order := make([]int, len(m.Params))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return len(m.Params[order[a]]) > len(m.Params[order[b]]) })
+	var pairs []string
+	for _, i := range order {
+		pairs = append(pairs, "$"+m.Params[i], u.Args[i])
+	}
+	subst := strings.NewReplacer(pairs...)
+	var out []*model.Relation
+	for _, l := range m.body {
+		text := subst.Replace(l.text)
+		if i := strings.Index(text, "$"); i >= 0 {
+			return nil, &model.SyntaxError{Pos: l.pos, Msg: fmt.Sprintf("macro %s: unknown parameter in %q", m.Name, text[i:])}
+		}
+		name, expr, ok := strings.Cut(text, ":")
+		name = strings.TrimSpace(name)
+		if !ok || !isIdent(name) {
+			return nil, &model.SyntaxError{Pos: l.pos, Msg: fmt.Sprintf("macro %s: expected \"define name: rewrite\"", m.Name)}
+		}
+		rw, err := model.ParseRewrite(expr)
+		if err != nil {
+			return nil, &model.SyntaxError{Pos: l.pos, Msg: fmt.Sprintf("macro %s used at %s: %s: %v", m.Name, u.Pos, name, err)}
+		}
+		out = append(out, &model.Relation{Name: name, Rewrite: rw, Pos: u.Pos})
+	}
+	return out, nil
+}
+
+// strip removes a trailing comment and surrounding space, as the model
+// parser does, returning the text and its 1-based column.
+func strip(s string) (string, int) {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t') {
+			s = s[:i]
+			break
+		}
+	}
+	trimmed := strings.TrimLeft(s, " \t")
+	return strings.TrimRight(trimmed, " \t"), len(s) - len(trimmed) + 1
+}
+
+func isIdent(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c != '_' && c != '-' && !('a' <= c && c <= 'z') && !('A' <= c && c <= 'Z') && !('0' <= c && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
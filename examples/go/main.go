@@ -2,13 +2,35 @@ package main
 
 import (
 	"context"
-	"fmt"
-	"log"
+	"log/slog"
+	"os"
 
 	openfga "github.com/openfga/go-sdk"
 	"github.com/openfga/go-sdk/client"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/logging"
+	"github.com/bogdanticu88/openfga-examples/policy"
 )
 
+var logs = logging.New(logging.Config{Handler: slog.NewTextHandler(os.Stderr, nil)})
+
+var logger = logs.For(logging.Client)
+
+// fatal logs msg with err and exits, in place of log.Fatalf.
+func fatal(msg string, err error) {
+	logger.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// policies is the single table mapping service operations to the relation
+// they require. authz.Client.Require resolves operation names through it.
+var policies = policy.Map{
+	"organization.manage": {Relation: "admin", ObjectType: "organization"},
+	"project.view":        {Relation: "viewer", ObjectType: "project"},
+	"project.delete":      {Relation: "owner", ObjectType: "project"},
+}
+
 func main() {
 	ctx := context.Background()
 
@@ -17,7 +39,7 @@ func main() {
 		ApiUrl: "http://localhost:8080",
 	})
 	if err != nil {
-		log.Fatalf("Failed to create OpenFGA client: %v", err)
+		fatal("Failed to create OpenFGA client", err)
 	}
 
 	storeID := createStore(ctx, fgaClient)
@@ -29,6 +51,9 @@ func main() {
 	createRelationships(ctx, fgaClient)
 	checkAccess(ctx, fgaClient)
 	listPermissions(ctx, fgaClient)
+	enforcePolicy(ctx, authz.New(authz.FromSDK(fgaClient),
+		authz.WithPolicy(policies),
+		authz.WithLogger(logger)))
 }
 
 func createStore(ctx context.Context, fgaClient *client.OpenFgaClient) string {
@@ -36,9 +61,9 @@ func createStore(ctx context.Context, fgaClient *client.OpenFgaClient) string {
 		Name: "authorization-store",
 	}).Execute()
 	if err != nil {
-		log.Fatalf("Failed to create store: %v", err)
+		fatal("Failed to create store", err)
 	}
-	fmt.Printf("Created store: %s\n", resp.Id)
+	logger.Info("Created store", "store_id", resp.Id)
 	return resp.Id
 }
 
@@ -112,14 +137,14 @@ func createAuthorizationModel(ctx context.Context, fgaClient *client.OpenFgaClie
 		},
 	}).Execute()
 	if err != nil {
-		log.Fatalf("Failed to write authorization model: %v", err)
+		fatal("Failed to write authorization model", err)
 	}
-	fmt.Printf("Authorization model ID: %s\n", resp.AuthorizationModelId)
+	logger.Info("Wrote authorization model", "model_id", resp.AuthorizationModelId)
 	return resp.AuthorizationModelId
 }
 
 func createRelationships(ctx context.Context, fgaClient *client.OpenFgaClient) {
-	err := fgaClient.WriteTuples(ctx).Body([]client.ClientTupleKey{
+	_, err := fgaClient.WriteTuples(ctx).Body([]client.ClientTupleKey{
 		{
 			User:     "user:alice",
 			Relation: "admin",
@@ -142,9 +167,9 @@ func createRelationships(ctx context.Context, fgaClient *client.OpenFgaClient) {
 		},
 	}).Execute()
 	if err != nil {
-		log.Fatalf("Failed to write relationships: %v", err)
+		fatal("Failed to write relationships", err)
 	}
-	fmt.Println("Relationships created successfully")
+	logger.Info("Relationships created successfully")
 }
 
 func checkAccess(ctx context.Context, fgaClient *client.OpenFgaClient) {
@@ -154,9 +179,9 @@ func checkAccess(ctx context.Context, fgaClient *client.OpenFgaClient) {
 		Object:   "organization:acme",
 	}).Execute()
 	if err != nil {
-		log.Fatalf("Failed to check access: %v", err)
+		fatal("Failed to check access", err)
 	}
-	fmt.Printf("Alice is admin of acme: %v\n", resp.Allowed)
+	logger.Info("Checked access", "user", "user:alice", "relation", "admin", "object", "organization:acme", "allowed", resp.GetAllowed())
 }
 
 func listPermissions(ctx context.Context, fgaClient *client.OpenFgaClient) {
@@ -166,7 +191,15 @@ func listPermissions(ctx context.Context, fgaClient *client.OpenFgaClient) {
 		Type:     "organization",
 	}).Execute()
 	if err != nil {
-		log.Fatalf("Failed to list objects: %v", err)
+		fatal("Failed to list objects", err)
+	}
+	logger.Info("Listed objects", "user", "user:alice", "relation", "admin", "objects", resp.Objects)
+}
+
+func enforcePolicy(ctx context.Context, az *authz.Client) {
+	ctx = authz.WithUser(ctx, "user:alice")
+	if err := az.Require(ctx, "organization.manage", "acme"); err != nil {
+		fatal("Failed to enforce policy", err)
 	}
-	fmt.Printf("Alice can admin: %v\n", resp.Objects)
+	logger.Info("Policy allowed", "user", "user:alice", "operation", "organization.manage", "object", "organization:acme")
 }
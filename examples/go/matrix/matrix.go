@@ -0,0 +1,245 @@
+// Package matrix exports a users × relations permission matrix for every
+// object of a type, for audits and analytics:
+//
+//	object,user,owner,editor,viewer
+//	document:roadmap,user:alice,true,true,true
+//	document:roadmap,user:bob,false,false,true
+//	document:roadmap,user:*,false,false,false
+//
+// Objects are enumerated from the stored tuples and users from one
+// ListUsers call per object and relation, so a row reflects effective
+// access, including access through groups and parents, not only direct
+// assignments. A wildcard row ("user:*") means the relation is public.
+package matrix
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/model"
+	"github.com/bogdanticu88/openfga-examples/parquet"
+)
+
+// Row is one object and user, with whether the user holds each relation in
+// the order of Exporter.Relations.
+type Row struct {
+	Object    string
+	User      string
+	Relations []bool
+}
+
+// Exporter computes matrices from a store.
+type Exporter struct {
+	Backend authz.Backend
+	// Model defaults to the store's active model.
+	Model *model.Model
+	// Relations limits the columns; default every relation of the type.
+	Relations []string
+	// UserTypes are the ListUsers filters; default every type assignable as
+	// a plain user anywhere in the model.
+	UserTypes []string
+	// Rate caps ListUsers calls per second; zero is unlimited.
+	Rate float64
+}
+
+// Columns returns the relation columns exported for objectType.
+func (x *Exporter) Columns(ctx context.Context, objectType string) ([]string, error) {
+	m, err := x.model(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return x.columns(m, objectType)
+}
+
+// Rows calls fn for each row of the matrix of objectType, sorted by object
+// and user. Objects on which nobody holds any of the relations produce no
+// rows.
+func (x *Exporter) Rows(ctx context.Context, objectType string, fn func(Row) error) error {
+	m, err := x.model(ctx)
+	if err != nil {
+		return err
+	}
+	rels, err := x.columns(m, objectType)
+	if err != nil {
+		return err
+	}
+	filters := x.UserTypes
+	if len(filters) == 0 {
+		filters = userTypes(m)
+	}
+	all, err := authz.ReadType(ctx, x.Backend, objectType)
+	if err != nil {
+		return fmt.Errorf("matrix: read: %w", err)
+	}
+	seen := map[string]bool{}
+	var objects []string
+	for _, t := range all {
+		if !seen[t.Object] {
+			seen[t.Object] = true
+			objects = append(objects, t.Object)
+		}
+	}
+	sort.Strings(objects)
+
+	var pace pacer
+	if x.Rate > 0 {
+		pace.every = time.Duration(float64(time.Second) / x.Rate)
+	}
+	for _, object := range objects {
+		has := map[string][]bool{}
+		for i, rel := range rels {
+			if err := pace.wait(ctx); err != nil {
+				return err
+			}
+			users, err := x.Backend.ListUsers(ctx, object, rel, filters)
+			if err != nil {
+				return fmt.Errorf("matrix: list users %s#%s: %w", object, rel, err)
+			}
+			for _, u := range users {
+				if has[u] == nil {
+					has[u] = make([]bool, len(rels))
+				}
+				has[u][i] = true
+			}
+		}
+		users := make([]string, 0, len(has))
+		for u := range has {
+			users = append(users, u)
+		}
+		sort.Strings(users)
+		for _, u := range users {
+			if err := fn(Row{Object: object, User: u, Relations: has[u]}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// CSV writes the matrix of objectType with a header row.
+func (x *Exporter) CSV(ctx context.Context, w io.Writer, objectType string) error {
+	rels, err := x.Columns(ctx, objectType)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	cw.Write(append([]string{"object", "user"}, rels...))
+	err = x.Rows(ctx, objectType, func(r Row) error {
+		rec := []string{r.Object, r.User}
+		for _, b := range r.Relations {
+			rec = append(rec, strconv.FormatBool(b))
+		}
+		return cw.Write(rec)
+	})
+	cw.Flush()
+	if err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+// Parquet writes the matrix of objectType as a Parquet file with string
+// columns object and user and a boolean column per relation.
+func (x *Exporter) Parquet(ctx context.Context, w io.Writer, objectType string) error {
+	rels, err := x.Columns(ctx, objectType)
+	if err != nil {
+		return err
+	}
+	cols := []parquet.Column{{Name: "object", Type: parquet.String}, {Name: "user", Type: parquet.String}}
+	for _, r := range rels {
+		cols = append(cols, parquet.Column{Name: r, Type: parquet.Bool})
+	}
+	pw := parquet.NewWriter(w, cols...)
+	err = x.Rows(ctx, objectType, func(r Row) error {
+		row := []interface{}{r.Object, r.User}
+		for _, b := range r.Relations {
+			row = append(row, b)
+		}
+		return pw.Write(row...)
+	})
+	if err != nil {
+		return err
+	}
+	return pw.Close()
+}
+
+func (x *Exporter) model(ctx context.Context) (*model.Model, error) {
+	if x.Model != nil {
+		return x.Model, nil
+	}
+	m, err := x.Backend.ReadModel(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("matrix: read model: %w", err)
+	}
+	return m, nil
+}
+
+func (x *Exporter) columns(m *model.Model, objectType string) ([]string, error) {
+	t := m.Type(objectType)
+	if t == nil {
+		return nil, fmt.Errorf("matrix: unknown type %q", objectType)
+	}
+	if len(x.Relations) > 0 {
+		for _, r := range x.Relations {
+			if t.Relation(r) == nil {
+				return nil, fmt.Errorf("matrix: unknown relation %s#%s", objectType, r)
+			}
+		}
+		return x.Relations, nil
+	}
+	rels := make([]string, len(t.Relations))
+	for i, r := range t.Relations {
+		rels[i] = r.Name
+	}
+	return rels, nil
+}
+
+// userTypes returns the types assignable as plain users, in model order.
+func userTypes(m *model.Model) []string {
+	plain := map[string]bool{}
+	for _, t := range m.Types {
+		for _, r := range t.Relations {
+			for _, ref := range r.DirectTypes() {
+				if ref.Relation == "" {
+					plain[ref.Type] = true
+				}
+			}
+		}
+	}
+	var out []string
+	for _, t := range m.Types {
+		if plain[t.Name] {
+			out = append(out, t.Name)
+		}
+	}
+	return out
+}
+
+// pacer spaces calls at least every apart.
+type pacer struct {
+	every time.Duration
+	next  time.Time
+}
+
+func (p *pacer) wait(ctx context.Context) error {
+	if p.every == 0 {
+		return nil
+	}
+	if d := time.Until(p.next); d > 0 {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	p.next = time.Now().Add(p.every)
+	return nil
+}
@@ -0,0 +1,278 @@
+// Package casbin converts Casbin RBAC policies into an OpenFGA model and
+// tuples.
+//
+// Policy rules ("p, sub, obj, act") become direct grants of relation act on
+// a resource; role rules ("g, user, role") become role membership, with
+// role-to-role inheritance expressed through usersets; resource grouping
+// ("g2, obj, group") becomes a parent link the group's grants flow through.
+// Domains, deny effects and pattern matching are reported, not guessed.
+package casbin
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/migrate"
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// Options names the generated types. Zero values select the defaults.
+type Options struct {
+	UserType     string // default "user"
+	RoleType     string // default "role"
+	ResourceType string // default "resource"
+	GroupType    string // default "resource_group"
+}
+
+func (o *Options) defaults() {
+	if o.UserType == "" {
+		o.UserType = "user"
+	}
+	if o.RoleType == "" {
+		o.RoleType = "role"
+	}
+	if o.ResourceType == "" {
+		o.ResourceType = "resource"
+	}
+	if o.GroupType == "" {
+		o.GroupType = "resource_group"
+	}
+}
+
+type rule struct {
+	line   int
+	source string
+	fields []string
+}
+
+// Convert reads a Casbin policy file (the CSV adapter format).
+func Convert(r io.Reader, opts Options) (*migrate.Result, error) {
+	opts.defaults()
+	rules, err := readRules(r)
+	if err != nil {
+		return nil, err
+	}
+	res := &migrate.Result{}
+
+	roles := map[string]bool{}
+	groups := map[string]bool{}
+	for _, ru := range rules {
+		switch ru.fields[0] {
+		case "g":
+			if len(ru.fields) >= 3 {
+				roles[ru.fields[2]] = true
+			}
+		case "g2":
+			if len(ru.fields) >= 3 {
+				groups[ru.fields[2]] = true
+			}
+		}
+	}
+
+	actions := map[string]bool{}
+	nestedGroups := false
+	seen := map[authz.Tuple]bool{}
+	add := func(t authz.Tuple) {
+		if !seen[t] {
+			seen[t] = true
+			res.Tuples = append(res.Tuples, t)
+		}
+	}
+	subject := func(name string) (string, error) {
+		if roles[name] {
+			id, err := migrate.ID(opts.RoleType, name)
+			return opts.RoleType + ":" + id + "#assignee", err
+		}
+		id, err := migrate.ID(opts.UserType, name)
+		return opts.UserType + ":" + id, err
+	}
+	object := func(name string) (string, error) {
+		typ := opts.ResourceType
+		if groups[name] {
+			typ = opts.GroupType
+		}
+		id, err := migrate.ID(typ, name)
+		return typ + ":" + id, err
+	}
+
+	for _, ru := range rules {
+		f := ru.fields
+		switch f[0] {
+		case "p":
+			args := f[1:]
+			if n := len(args); n == 4 && (args[3] == "allow" || args[3] == "deny") {
+				if args[3] == "deny" {
+					res.Issuef(ru.line, ru.source, "deny effect has no direct equivalent; model it with \"but not\"")
+					continue
+				}
+				args = args[:3]
+			}
+			if len(args) == 4 {
+				res.Issuef(ru.line, ru.source, "domain %q not mapped; consider a tenant type with tuple-to-userset", args[1])
+				continue
+			}
+			if len(args) != 3 {
+				res.Issuef(ru.line, ru.source, "expected p, sub, obj, act")
+				continue
+			}
+			sub, obj, act := args[0], args[1], args[2]
+			if isPattern(sub) || isPattern(obj) {
+				res.Issuef(ru.line, ru.source, "pattern matching (keyMatch/regex) is not supported; list concrete objects")
+				continue
+			}
+			acts := splitActions(act)
+			if acts == nil {
+				res.Issuef(ru.line, ru.source, "action %q cannot be mapped to relations", act)
+				continue
+			}
+			user, err := subject(sub)
+			if err != nil {
+				res.Issuef(ru.line, ru.source, "subject: %v", err)
+				continue
+			}
+			o, err := object(obj)
+			if err != nil {
+				res.Issuef(ru.line, ru.source, "object: %v", err)
+				continue
+			}
+			for _, a := range acts {
+				actions[a] = true
+				add(authz.Tuple{User: user, Relation: a, Object: o})
+			}
+		case "g":
+			if len(f) == 4 {
+				res.Issuef(ru.line, ru.source, "domain %q not mapped; role membership is treated as global", f[3])
+			} else if len(f) != 3 {
+				res.Issuef(ru.line, ru.source, "expected g, user, role")
+				continue
+			}
+			member, err := subject(f[1])
+			if err != nil {
+				res.Issuef(ru.line, ru.source, "member: %v", err)
+				continue
+			}
+			id, err := migrate.ID(opts.RoleType, f[2])
+			if err != nil {
+				res.Issuef(ru.line, ru.source, "role: %v", err)
+				continue
+			}
+			add(authz.Tuple{User: member, Relation: "assignee", Object: opts.RoleType + ":" + id})
+		case "g2":
+			if len(f) != 3 {
+				res.Issuef(ru.line, ru.source, "expected g2, object, group")
+				continue
+			}
+			child, err := object(f[1])
+			if err != nil {
+				res.Issuef(ru.line, ru.source, "object: %v", err)
+				continue
+			}
+			id, err := migrate.ID(opts.GroupType, f[2])
+			if err != nil {
+				res.Issuef(ru.line, ru.source, "group: %v", err)
+				continue
+			}
+			if groups[f[1]] {
+				nestedGroups = true
+			}
+			add(authz.Tuple{User: opts.GroupType + ":" + id, Relation: "parent", Object: child})
+		default:
+			res.Issuef(ru.line, ru.source, "policy type %q not supported", f[0])
+		}
+	}
+
+	res.Model = buildModel(opts, sortedKeys(actions), len(groups) > 0, nestedGroups)
+	return res, nil
+}
+
+func buildModel(opts Options, actions []string, grouped, nested bool) *model.Model {
+	subjects := []model.TypeRef{{Type: opts.UserType}, {Type: opts.RoleType, Relation: "assignee"}}
+	m := &model.Model{SchemaVersion: "1.1", Types: []*model.Type{
+		{Name: opts.UserType},
+		{Name: opts.RoleType, Relations: []*model.Relation{{
+			Name:    "assignee",
+			Rewrite: &model.Direct{Types: subjects},
+		}}},
+	}}
+	grantable := func(typ string, inherit bool) *model.Type {
+		t := &model.Type{Name: typ}
+		if inherit {
+			t.Relations = append(t.Relations, &model.Relation{Name: "parent", Rewrite: &model.Direct{Types: []model.TypeRef{{Type: opts.GroupType}}}})
+		}
+		for _, a := range actions {
+			var rw model.Rewrite = &model.Direct{Types: append([]model.TypeRef(nil), subjects...)}
+			if inherit {
+				rw = &model.Union{Children: []model.Rewrite{rw, &model.TupleToUserset{Tupleset: "parent", Computed: a}}}
+			}
+			t.Relations = append(t.Relations, &model.Relation{Name: a, Rewrite: rw})
+		}
+		return t
+	}
+	m.Types = append(m.Types, grantable(opts.ResourceType, grouped))
+	if grouped {
+		m.Types = append(m.Types, grantable(opts.GroupType, nested))
+	}
+	return m
+}
+
+func readRules(r io.Reader) ([]rule, error) {
+	var rules []rule
+	sc := bufio.NewScanner(r)
+	line := 0
+	for sc.Scan() {
+		line++
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		cr := csv.NewReader(strings.NewReader(text))
+		cr.TrimLeadingSpace = true
+		fields, err := cr.Read()
+		if err != nil {
+			return nil, fmt.Errorf("casbin: line %d: %w", line, err)
+		}
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		rules = append(rules, rule{line: line, source: text, fields: fields})
+	}
+	return rules, sc.Err()
+}
+
+// splitActions maps an action to relation names. Alternations written as
+// "(read)|(write)" expand to several relations; "*" and other patterns
+// return nil.
+func splitActions(act string) []string {
+	var out []string
+	for _, a := range strings.Split(act, "|") {
+		a = strings.Trim(strings.TrimSpace(a), "()")
+		if a == "" || isPattern(a) {
+			return nil
+		}
+		if n := migrate.Name(a); n != "" {
+			out = append(out, n)
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+func isPattern(s string) bool {
+	return strings.ContainsAny(s, "*?[]{}()^$|\\") || strings.Contains(s, ":")
+}
+
+func sortedKeys(m map[string]bool) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
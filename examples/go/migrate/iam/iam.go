@@ -0,0 +1,260 @@
+// Package iam translates a constrained, IAM-style JSON policy into an
+// OpenFGA model and tuples. It is experimental and meant for prototyping a
+// model from existing policies, not for exact equivalence.
+//
+// The accepted format uses typed names throughout:
+//
+//	{
+//	  "Statement": [{
+//	    "Effect": "Allow",
+//	    "Principal": ["user:alice", "group:eng"],
+//	    "Action": ["document:Read", "document:Edit"],
+//	    "Resource": ["document:roadmap"]
+//	  }]
+//	}
+//
+// Each action becomes a relation on its type ("document#read"), and each
+// principal/resource pair becomes a tuple. Group principals are granted
+// through their membership userset. Deny statements, conditions, wildcard
+// actions and wildcard resources are reported as issues.
+package iam
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/migrate"
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// Policy is the accepted policy document.
+type Policy struct {
+	Version   string      `json:"Version,omitempty"`
+	Statement []Statement `json:"Statement"`
+}
+
+// Statement is one grant. Single strings are accepted where lists are.
+type Statement struct {
+	Sid       string      `json:"Sid,omitempty"`
+	Effect    string      `json:"Effect"`
+	Principal StringList  `json:"Principal"`
+	Action    StringList  `json:"Action"`
+	Resource  StringList  `json:"Resource"`
+	Condition interface{} `json:"Condition,omitempty"`
+}
+
+// StringList decodes from a JSON string or array of strings.
+type StringList []string
+
+func (l *StringList) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*l = StringList{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return fmt.Errorf("iam: expected string or list of strings")
+	}
+	*l = many
+	return nil
+}
+
+// Options configures the translation.
+type Options struct {
+	// GroupTypes maps a principal type to the relation that holds its
+	// members; default {"group": "member"}. Principals of these types are
+	// granted as usersets and get a type with that relation.
+	GroupTypes map[string]string
+	// UserType is the member type of generated group types; default "user".
+	UserType string
+}
+
+// Convert reads a policy document from r.
+func Convert(r io.Reader, opts Options) (*migrate.Result, error) {
+	var p Policy
+	dec := json.NewDecoder(r)
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("iam: %w", err)
+	}
+	return Translate(p, opts), nil
+}
+
+// Translate converts a decoded policy.
+func Translate(p Policy, opts Options) *migrate.Result {
+	if opts.GroupTypes == nil {
+		opts.GroupTypes = map[string]string{"group": "member"}
+	}
+	if opts.UserType == "" {
+		opts.UserType = "user"
+	}
+	res := &migrate.Result{}
+	// relations[type][relation] = set of principal refs
+	relations := map[string]map[string]map[model.TypeRef]bool{}
+	principalTypes := map[string]bool{}
+	seen := map[authz.Tuple]bool{}
+
+	for i, st := range p.Statement {
+		src := st.Sid
+		if src == "" {
+			src = fmt.Sprintf("Statement[%d]", i)
+		}
+		if !strings.EqualFold(st.Effect, "Allow") {
+			res.Issuef(0, src, "effect %q not mapped; model denials with \"but not\"", st.Effect)
+			continue
+		}
+		if st.Condition != nil {
+			res.Issuef(0, src, "condition dropped; the grant is translated unconditionally, add an OpenFGA condition by hand")
+		}
+		var users []string
+		var refs []model.TypeRef
+		for _, pr := range st.Principal {
+			user, ref, err := principal(pr, opts)
+			if err != nil {
+				res.Issuef(0, src, "principal %q: %v", pr, err)
+				continue
+			}
+			users = append(users, user)
+			refs = append(refs, ref)
+			principalTypes[ref.Type] = true
+		}
+		for _, act := range st.Action {
+			typ, name, ok := strings.Cut(act, ":")
+			rel := migrate.Name(name)
+			if !ok || typ == "" || rel == "" || strings.ContainsAny(name, "*?") {
+				res.Issuef(0, src, "action %q must be type:Action without wildcards", act)
+				continue
+			}
+			typ = migrate.Name(typ)
+			if relations[typ] == nil {
+				relations[typ] = map[string]map[model.TypeRef]bool{}
+			}
+			if relations[typ][rel] == nil {
+				relations[typ][rel] = map[model.TypeRef]bool{}
+			}
+			for _, ref := range refs {
+				relations[typ][rel][ref] = true
+			}
+			for _, resource := range st.Resource {
+				rtyp, rid, ok := strings.Cut(resource, ":")
+				if !ok || migrate.Name(rtyp) != typ {
+					continue
+				}
+				id, err := migrate.ID(typ, rid)
+				if err != nil || strings.ContainsAny(rid, "*?") {
+					continue
+				}
+				for _, u := range users {
+					t := authz.Tuple{User: u, Relation: rel, Object: typ + ":" + id}
+					if !seen[t] {
+						seen[t] = true
+						res.Tuples = append(res.Tuples, t)
+					}
+				}
+			}
+		}
+		for _, resource := range st.Resource {
+			rtyp, rid, _ := strings.Cut(resource, ":")
+			if !actionTypeMatches(st.Action, migrate.Name(rtyp)) {
+				res.Issuef(0, src, "resource %q matches no action type in the statement", resource)
+			} else if strings.ContainsAny(rid, "*?") {
+				res.Issuef(0, src, "resource %q: wildcard resources have no tuple equivalent; grant on a parent object instead", resource)
+			} else if _, err := migrate.ID(migrate.Name(rtyp), rid); err != nil {
+				res.Issuef(0, src, "resource %q: %v", resource, err)
+			}
+		}
+	}
+	res.Model = buildModel(relations, principalTypes, opts)
+	return res
+}
+
+func principal(p string, opts Options) (string, model.TypeRef, error) {
+	if p == "*" {
+		return opts.UserType + ":*", model.TypeRef{Type: opts.UserType, Wildcard: true}, nil
+	}
+	typ, id, ok := strings.Cut(p, ":")
+	if !ok || typ == "" {
+		return "", model.TypeRef{}, fmt.Errorf("expected type:id")
+	}
+	typ = migrate.Name(typ)
+	if id == "*" {
+		return typ + ":*", model.TypeRef{Type: typ, Wildcard: true}, nil
+	}
+	id, err := migrate.ID(typ, id)
+	if err != nil {
+		return "", model.TypeRef{}, err
+	}
+	if rel, ok := opts.GroupTypes[typ]; ok {
+		return typ + ":" + id + "#" + rel, model.TypeRef{Type: typ, Relation: rel}, nil
+	}
+	return typ + ":" + id, model.TypeRef{Type: typ}, nil
+}
+
+func actionTypeMatches(actions []string, typ string) bool {
+	for _, a := range actions {
+		if t, _, _ := strings.Cut(a, ":"); migrate.Name(t) == typ {
+			return true
+		}
+	}
+	return false
+}
+
+func buildModel(relations map[string]map[string]map[model.TypeRef]bool, principals map[string]bool, opts Options) *model.Model {
+	m := &model.Model{SchemaVersion: "1.1"}
+	defined := map[string]bool{}
+	addType := func(t *model.Type) {
+		if !defined[t.Name] {
+			defined[t.Name] = true
+			m.Types = append(m.Types, t)
+		}
+	}
+	var groupTypes []string
+	for typ := range principals {
+		if _, ok := opts.GroupTypes[typ]; ok {
+			groupTypes = append(groupTypes, typ)
+		} else if relations[typ] == nil {
+			addType(&model.Type{Name: typ})
+		}
+	}
+	sort.Slice(m.Types, func(i, j int) bool { return m.Types[i].Name < m.Types[j].Name })
+	sort.Strings(groupTypes)
+	if len(groupTypes) > 0 {
+		addType(&model.Type{Name: opts.UserType})
+	}
+	for _, typ := range groupTypes {
+		rel := opts.GroupTypes[typ]
+		addType(&model.Type{Name: typ, Relations: []*model.Relation{{
+			Name:    rel,
+			Rewrite: &model.Direct{Types: []model.TypeRef{{Type: opts.UserType}, {Type: typ, Relation: rel}}},
+		}}})
+	}
+	for _, typ := range sortedKeys(relations) {
+		t := &model.Type{Name: typ}
+		for _, rel := range sortedKeys(relations[typ]) {
+			var refs []model.TypeRef
+			for ref := range relations[typ][rel] {
+				refs = append(refs, ref)
+			}
+			sort.Slice(refs, func(i, j int) bool { return refs[i].String() < refs[j].String() })
+			if len(refs) == 0 {
+				refs = []model.TypeRef{{Type: opts.UserType}}
+			}
+			t.Relations = append(t.Relations, &model.Relation{Name: rel, Rewrite: &model.Direct{Types: refs}})
+		}
+		addType(t)
+	}
+	return m
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
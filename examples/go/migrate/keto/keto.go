@@ -0,0 +1,387 @@
+// Package keto imports Ory Keto relation tuples into OpenFGA. A Mapping
+// translates Keto namespaces and relations to OpenFGA types and relations.
+// Exports are streamed, so arbitrarily large dumps are imported in
+// constant memory.
+//
+// Accepted inputs are the JSON output of "keto relation-tuple get"
+// ({"relation_tuples": [...]}), a JSON array or JSON lines of tuples, and
+// Keto's text form:
+//
+//	files:readme#view@alice
+//	files:readme#view@groups:eng#member
+package keto
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/migrate"
+	"github.com/bogdanticu88/openfga-examples/progress"
+)
+
+// Tuple is a Keto relation tuple. Exactly one of SubjectID and SubjectSet
+// is set.
+type Tuple struct {
+	Namespace  string      `json:"namespace"`
+	Object     string      `json:"object"`
+	Relation   string      `json:"relation"`
+	SubjectID  string      `json:"subject_id,omitempty"`
+	SubjectSet *SubjectSet `json:"subject_set,omitempty"`
+}
+
+// SubjectSet is a Keto subject set: namespace:object#relation.
+type SubjectSet struct {
+	Namespace string `json:"namespace"`
+	Object    string `json:"object"`
+	Relation  string `json:"relation"`
+}
+
+// Namespace maps one Keto namespace.
+type Namespace struct {
+	// Type is the OpenFGA type; default is the namespace name.
+	Type string `json:"type"`
+	// Relations renames relations; unlisted relations keep their name.
+	Relations map[string]string `json:"relations,omitempty"`
+}
+
+// Mapping is the mapping file:
+//
+//	{
+//	  "subject_type": "user",
+//	  "namespaces": {
+//	    "files":  {"type": "document", "relations": {"view": "viewer"}},
+//	    "groups": {"type": "group"}
+//	  }
+//	}
+type Mapping struct {
+	// SubjectType is the type given to plain subject IDs; default "user".
+	SubjectType string               `json:"subject_type"`
+	Namespaces  map[string]Namespace `json:"namespaces"`
+	// Strict rejects tuples in namespaces missing from Namespaces instead
+	// of mapping them by name.
+	Strict bool `json:"strict"`
+}
+
+// LoadMapping reads a mapping file.
+func LoadMapping(path string) (*Mapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m Mapping
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("keto: mapping %s: %w", path, err)
+	}
+	return &m, nil
+}
+
+// Map converts a Keto tuple.
+func (m *Mapping) Map(t Tuple) (authz.Tuple, error) {
+	typ, rel, err := m.relation(t.Namespace, t.Relation)
+	if err != nil {
+		return authz.Tuple{}, err
+	}
+	id, err := migrate.ID(typ, t.Object)
+	if err != nil {
+		return authz.Tuple{}, err
+	}
+	var user string
+	switch {
+	case t.SubjectSet != nil:
+		st, srel, err := m.relation(t.SubjectSet.Namespace, t.SubjectSet.Relation)
+		if err != nil {
+			return authz.Tuple{}, err
+		}
+		sid, err := migrate.ID(st, t.SubjectSet.Object)
+		if err != nil {
+			return authz.Tuple{}, err
+		}
+		user = st + ":" + sid
+		if srel != "" && srel != "..." {
+			user += "#" + srel
+		}
+	case t.SubjectID != "":
+		st := m.SubjectType
+		if st == "" {
+			st = "user"
+		}
+		sid, err := migrate.ID(st, t.SubjectID)
+		if err != nil {
+			return authz.Tuple{}, err
+		}
+		user = st + ":" + sid
+	default:
+		return authz.Tuple{}, fmt.Errorf("tuple has no subject")
+	}
+	return authz.Tuple{User: user, Relation: rel, Object: typ + ":" + id}, nil
+}
+
+func (m *Mapping) relation(namespace, relation string) (string, string, error) {
+	ns, ok := m.Namespaces[namespace]
+	if !ok && m.Strict {
+		return "", "", fmt.Errorf("namespace %q is not mapped", namespace)
+	}
+	typ := ns.Type
+	if typ == "" {
+		typ = migrate.Name(namespace)
+	}
+	if r, ok := ns.Relations[relation]; ok {
+		relation = r
+	}
+	return typ, relation, nil
+}
+
+// Each streams the Keto tuples in r to fn, stopping at the first error fn
+// returns. Lines or entries that cannot be parsed are passed to bad.
+func Each(r io.Reader, fn func(Tuple) error, bad func(line int, src string, err error)) error {
+	br := bufio.NewReaderSize(r, 64*1024)
+	first, err := peekNonSpace(br)
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	switch first {
+	case '[':
+		return eachArray(json.NewDecoder(br), fn)
+	case '{':
+		line, err := br.Peek(min(br.Buffered(), 4096))
+		if err != nil {
+			return err
+		}
+		// JSON lines start with a complete tuple object on the first line;
+		// anything else is the envelope, compact or pretty-printed.
+		if fl := firstLine(line); !json.Valid(fl) || bytes.Contains(fl, []byte(`"relation_tuples"`)) {
+			return eachEnvelope(json.NewDecoder(br), fn)
+		}
+		return eachLine(br, bad, func(s string) (Tuple, error) {
+			var t Tuple
+			err := json.Unmarshal([]byte(s), &t)
+			return t, err
+		}, fn)
+	}
+	return eachLine(br, bad, ParseText, fn)
+}
+
+func peekNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if b != ' ' && b != '\t' && b != '\r' && b != '\n' {
+			return b, br.UnreadByte()
+		}
+	}
+}
+
+func firstLine(b []byte) []byte {
+	if i := bytes.IndexByte(b, '\n'); i >= 0 {
+		return b[:i]
+	}
+	return b
+}
+
+func eachArray(dec *json.Decoder, fn func(Tuple) error) error {
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	for dec.More() {
+		var t Tuple
+		if err := dec.Decode(&t); err != nil {
+			return fmt.Errorf("keto: %w", err)
+		}
+		if err := fn(t); err != nil {
+			return err
+		}
+	}
+	_, err := dec.Token()
+	return err
+}
+
+func eachEnvelope(dec *json.Decoder, fn func(Tuple) error) error {
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if key == "relation_tuples" {
+			if err := eachArray(dec, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func eachLine(br *bufio.Reader, bad func(int, string, error), parse func(string) (Tuple, error), fn func(Tuple) error) error {
+	sc := bufio.NewScanner(br)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for sc.Scan() {
+		line++
+		s := strings.TrimSpace(sc.Text())
+		if s == "" || strings.HasPrefix(s, "//") {
+			continue
+		}
+		t, err := parse(s)
+		if err != nil {
+			if bad != nil {
+				bad(line, s, err)
+			}
+			continue
+		}
+		if err := fn(t); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
+
+// ParseText parses Keto's text form "ns:object#relation@subject", where
+// the subject is an ID or a subject set "ns:object#relation", optionally
+// in parentheses.
+func ParseText(s string) (Tuple, error) {
+	obj, rest, ok := strings.Cut(s, "#")
+	rel, subj, ok2 := strings.Cut(rest, "@")
+	ns, id, ok3 := strings.Cut(obj, ":")
+	if !ok || !ok2 || !ok3 {
+		return Tuple{}, fmt.Errorf("keto: expected namespace:object#relation@subject")
+	}
+	t := Tuple{Namespace: ns, Object: id, Relation: rel}
+	subj = strings.TrimSuffix(strings.TrimPrefix(subj, "("), ")")
+	if sns, sobj, ok := strings.Cut(subj, ":"); ok {
+		sid, srel, _ := strings.Cut(sobj, "#")
+		t.SubjectSet = &SubjectSet{Namespace: sns, Object: sid, Relation: srel}
+	} else {
+		t.SubjectID = subj
+	}
+	return t, nil
+}
+
+// Report summarizes an import.
+type Report struct {
+	Read     int
+	Imported int
+	// Issues lists skipped tuples, up to MaxIssues; Skipped counts all.
+	Issues  []migrate.Issue
+	Skipped int
+	// Writes is the per-tuple outcome of the server writes, nil for a dry
+	// run. The import stops at the first failed batch.
+	Writes *authz.BatchResult
+}
+
+// MaxIssues caps Report.Issues so a systematically broken export does not
+// exhaust memory.
+const MaxIssues = 1000
+
+// Import streams tuples from r, maps them and writes them to b in batches.
+// With dryRun the tuples are mapped but not written.
+func Import(ctx context.Context, r io.Reader, m *Mapping, b authz.Backend, dryRun bool) (*Report, error) {
+	return importTuples(ctx, r, m, b, dryRun, nil)
+}
+
+// ImportTracked is Import reporting to tr, checkpointed with the number of
+// source tuples consumed after each written batch. Restarted on the same
+// export, it skips the tuples an interrupted run already wrote.
+func ImportTracked(ctx context.Context, r io.Reader, m *Mapping, b authz.Backend, tr *progress.Tracker) (*Report, error) {
+	return importTuples(ctx, r, m, b, false, tr)
+}
+
+func importTuples(ctx context.Context, r io.Reader, m *Mapping, b authz.Backend, dryRun bool, tr *progress.Tracker) (*Report, error) {
+	saved, err := tr.Resume(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("keto: %w", err)
+	}
+	resume := 0
+	if saved != "" {
+		if resume, err = strconv.Atoi(saved); err != nil {
+			return nil, fmt.Errorf("keto: checkpoint %q: %w", saved, err)
+		}
+	}
+	rep := &Report{}
+	skip := func(line int, src string, err error) {
+		rep.Skipped++
+		if len(rep.Issues) < MaxIssues {
+			rep.Issues = append(rep.Issues, migrate.Issue{Line: line, Source: src, Reason: err.Error()})
+		}
+	}
+	tr.Skip(resume)
+	reported := 0 // rep.Read at the last checkpoint
+	var batch []authz.Tuple
+	flush := func() error {
+		if len(batch) == 0 || dryRun {
+			rep.Imported += len(batch)
+			batch = batch[:0]
+			return nil
+		}
+		res := authz.WriteBatched(ctx, b, batch, nil)
+		if rep.Writes == nil {
+			rep.Writes = res
+		} else {
+			rep.Writes.Append(res)
+		}
+		if err := res.Err(); err != nil {
+			return fmt.Errorf("keto: write after %d tuples: %w", rep.Imported, err)
+		}
+		rep.Imported += len(batch)
+		if err := tr.Advance(ctx, rep.Read-reported, strconv.Itoa(resume+rep.Read)); err != nil {
+			return fmt.Errorf("keto: %w", err)
+		}
+		reported = rep.Read
+		batch = batch[:0]
+		return nil
+	}
+	skipped := 0
+	err = Each(r, func(kt Tuple) error {
+		if skipped < resume {
+			skipped++
+			return nil
+		}
+		rep.Read++
+		t, err := m.Map(kt)
+		if err != nil {
+			skip(0, fmt.Sprintf("%s:%s#%s", kt.Namespace, kt.Object, kt.Relation), err)
+			return nil
+		}
+		batch = append(batch, t)
+		if len(batch) == authz.MaxWriteTuples {
+			return flush()
+		}
+		return ctx.Err()
+	}, func(line int, src string, err error) {
+		if skipped < resume {
+			skipped++
+			return
+		}
+		rep.Read++
+		skip(line, src, err)
+	})
+	if err != nil {
+		return rep, err
+	}
+	if err := flush(); err != nil {
+		return rep, err
+	}
+	if err := tr.Finish(ctx); err != nil {
+		return rep, fmt.Errorf("keto: %w", err)
+	}
+	return rep, nil
+}
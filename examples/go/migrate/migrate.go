@@ -0,0 +1,103 @@
+// Package migrate holds what the importers under it share: the Result of a
+// conversion, the Issues that need a human, and helpers to turn foreign
+// names into valid OpenFGA identifiers.
+//
+// Importers produce a best-effort model and tuple set and never silently
+// drop input: anything that could not be mapped exactly is an Issue.
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/ids"
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// Issue is a source construct that was skipped or only approximated.
+type Issue struct {
+	// Line is the 1-based source line, or 0 when not line-oriented.
+	Line   int
+	Source string
+	Reason string
+}
+
+func (i Issue) String() string {
+	if i.Line > 0 {
+		return fmt.Sprintf("line %d: %s: %s", i.Line, i.Reason, i.Source)
+	}
+	return fmt.Sprintf("%s: %s", i.Reason, i.Source)
+}
+
+// Result is the outcome of a conversion.
+type Result struct {
+	Model  *model.Model
+	Tuples []authz.Tuple
+	Issues []Issue
+}
+
+// Issuef records an issue.
+func (r *Result) Issuef(line int, source, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, Issue{Line: line, Source: source, Reason: fmt.Sprintf(format, args...)})
+}
+
+// Report writes a human summary of r: counts, then every issue.
+func (r *Result) Report(w io.Writer) error {
+	types := 0
+	if r.Model != nil {
+		types = len(r.Model.Types)
+	}
+	if _, err := fmt.Fprintf(w, "%d types, %d tuples, %d issues\n", types, len(r.Tuples), len(r.Issues)); err != nil {
+		return err
+	}
+	for _, i := range r.Issues {
+		if _, err := fmt.Fprintf(w, "  %s\n", i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Apply validates the model and writes the tuples to b in batches,
+// returning each tuple's outcome; the error is for validation failures,
+// which stop the migration before anything is written. It does not write
+// the model itself, which should be reviewed first.
+func (r *Result) Apply(ctx context.Context, b authz.Backend) (*authz.BatchResult, error) {
+	if err := r.Model.Validate(); err != nil {
+		return nil, fmt.Errorf("migrate: generated model: %w", err)
+	}
+	for _, t := range r.Tuples {
+		if err := r.Model.ValidateTuple(t.User, t.Relation, t.Object, t.Condition.Name); err != nil {
+			return nil, fmt.Errorf("migrate: %w", err)
+		}
+	}
+	return authz.WriteBatched(ctx, b, r.Tuples, nil), nil
+}
+
+var lenient = &ids.Policy{Mode: ids.Lenient}
+
+// ID sanitizes a foreign identifier into an object ID of typ.
+func ID(typ, id string) (string, error) {
+	return lenient.ID(typ, id)
+}
+
+// Name turns a foreign action, role or namespace name into a type or
+// relation name: lower case, with runs of other characters folded to "_".
+func Name(s string) string {
+	var b strings.Builder
+	underscore := false
+	for _, r := range strings.ToLower(strings.TrimSpace(s)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			underscore = false
+		} else if !underscore && b.Len() > 0 {
+			b.WriteByte('_')
+			underscore = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "_")
+}
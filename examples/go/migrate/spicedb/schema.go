@@ -0,0 +1,354 @@
+package spicedb
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type token struct {
+	text string
+	line int
+	off  int
+}
+
+// lex splits a schema into tokens. Newlines are kept as "\n" tokens
+// because they terminate relation and permission statements.
+func lex(src string) ([]token, error) {
+	var toks []token
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			toks = append(toks, token{"\n", line, i})
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated comment", line)
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 4
+		case strings.HasPrefix(src[i:], "->"):
+			toks = append(toks, token{"->", line, i})
+			i += 2
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(src) && src[j] != c && src[j] != '\n' {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(src) || src[j] != c {
+				return nil, fmt.Errorf("line %d: unterminated string", line)
+			}
+			toks = append(toks, token{src[i : j+1], line, i})
+			i = j + 1
+		case isIdent(rune(c)):
+			j := i
+			for j < len(src) && (isIdent(rune(src[j])) || src[j] == '/') {
+				j++
+			}
+			toks = append(toks, token{src[i:j], line, i})
+			i = j
+		default:
+			toks = append(toks, token{string(c), line, i})
+			i++
+		}
+	}
+	return toks, nil
+}
+
+func isIdent(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Schema AST, kept close to SpiceDB's own terms.
+
+type definition struct {
+	name    string
+	line    int
+	members []*member
+}
+
+type member struct {
+	name       string
+	line       int
+	permission bool
+	subjects   []subject // relation only
+	expr       expr      // permission only
+}
+
+type subject struct {
+	typ      string
+	relation string
+	wildcard bool
+	caveat   string
+}
+
+type expr interface{}
+
+type (
+	exprRef   struct{ name string }
+	exprArrow struct{ tupleset, computed string }
+	exprNil   struct{}
+	exprOp    struct {
+		op          string // "+", "&" or "-"
+		left, right expr
+	}
+)
+
+type caveat struct {
+	name   string
+	line   int
+	params [][2]string
+	body   string
+}
+
+type schema struct {
+	defs    []*definition
+	caveats []*caveat
+}
+
+type parser struct {
+	toks []token
+	pos  int
+	src  string
+}
+
+func (p *parser) peek() token {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return token{}
+}
+
+func (p *parser) next() token {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *parser) skipNewlines() {
+	for p.peek().text == "\n" {
+		p.pos++
+	}
+}
+
+func (p *parser) expect(text string) error {
+	if t := p.next(); t.text != text {
+		return fmt.Errorf("line %d: expected %q, found %q", t.line, text, t.text)
+	}
+	return nil
+}
+
+func parseSchema(src string) (*schema, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks, src: src}
+	s := &schema{}
+	for {
+		p.skipNewlines()
+		t := p.next()
+		switch t.text {
+		case "":
+			return s, nil
+		case "definition":
+			d, err := p.definition(t.line)
+			if err != nil {
+				return nil, err
+			}
+			s.defs = append(s.defs, d)
+		case "caveat":
+			c, err := p.caveat(t.line)
+			if err != nil {
+				return nil, err
+			}
+			s.caveats = append(s.caveats, c)
+		default:
+			return nil, fmt.Errorf("line %d: expected definition or caveat, found %q", t.line, t.text)
+		}
+	}
+}
+
+func (p *parser) definition(line int) (*definition, error) {
+	d := &definition{name: p.next().text, line: line}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	for {
+		p.skipNewlines()
+		t := p.next()
+		switch t.text {
+		case "}":
+			return d, nil
+		case "relation":
+			m := &member{name: p.next().text, line: t.line}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			for {
+				s, err := p.subject()
+				if err != nil {
+					return nil, err
+				}
+				m.subjects = append(m.subjects, s)
+				if p.peek().text != "|" {
+					break
+				}
+				p.next()
+			}
+			d.members = append(d.members, m)
+		case "permission":
+			m := &member{name: p.next().text, line: t.line, permission: true}
+			if err := p.expect("="); err != nil {
+				return nil, err
+			}
+			e, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			m.expr = e
+			d.members = append(d.members, m)
+		default:
+			return nil, fmt.Errorf("line %d: expected relation or permission, found %q", t.line, t.text)
+		}
+	}
+}
+
+func (p *parser) subject() (subject, error) {
+	s := subject{typ: p.next().text}
+	switch p.peek().text {
+	case ":":
+		p.next()
+		if err := p.expect("*"); err != nil {
+			return s, err
+		}
+		s.wildcard = true
+	case "#":
+		p.next()
+		s.relation = p.next().text
+	}
+	if p.peek().text == "with" {
+		p.next()
+		s.caveat = p.next().text
+		// "with expiration" and "with caveat and expiration" are traits.
+		for p.peek().text == "and" {
+			p.next()
+			s.caveat += " and " + p.next().text
+		}
+	}
+	if s.typ == "" || s.typ == "\n" {
+		return s, fmt.Errorf("line %d: expected subject type", p.peek().line)
+	}
+	return s, nil
+}
+
+// SpiceDB precedence, loosest first: exclusion (-), intersection (&),
+// union (+), then arrows; "a + b - c" is "(a + b) - c".
+func (p *parser) expression() (expr, error)   { return p.binary("-", p.intersection) }
+func (p *parser) intersection() (expr, error) { return p.binary("&", p.union) }
+func (p *parser) union() (expr, error)        { return p.binary("+", p.primary) }
+
+func (p *parser) binary(op string, operand func() (expr, error)) (expr, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().text == op {
+		p.next()
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = &exprOp{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) primary() (expr, error) {
+	t := p.next()
+	switch t.text {
+	case "(":
+		e, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		return e, p.expect(")")
+	case "nil":
+		return &exprNil{}, nil
+	case "", "\n", ")", "+", "-", "&":
+		return nil, fmt.Errorf("line %d: expected expression, found %q", t.line, t.text)
+	}
+	if p.peek().text == "->" {
+		p.next()
+		return &exprArrow{tupleset: t.text, computed: p.next().text}, nil
+	}
+	if p.peek().text == "." {
+		return nil, fmt.Errorf("line %d: %s.%s: functioned arrows are not supported", t.line, t.text, p.toks[p.pos+1].text)
+	}
+	return &exprRef{name: t.text}, nil
+}
+
+func (p *parser) caveat(line int) (*caveat, error) {
+	c := &caveat{name: p.next().text, line: line}
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	for p.peek().text != ")" {
+		name := p.next().text
+		var typ strings.Builder
+		depth := 0
+		for {
+			t := p.peek()
+			if t.text == "" || (depth == 0 && (t.text == "," || t.text == ")")) {
+				break
+			}
+			switch t.text {
+			case "<":
+				depth++
+			case ">":
+				depth--
+			}
+			typ.WriteString(t.text)
+			p.next()
+		}
+		c.params = append(c.params, [2]string{name, typ.String()})
+		if p.peek().text == "," {
+			p.next()
+		}
+		p.skipNewlines()
+	}
+	p.next()
+	p.skipNewlines()
+	open := p.next()
+	if open.text != "{" {
+		return nil, fmt.Errorf("line %d: expected { after caveat %s", open.line, c.name)
+	}
+	depth := 1
+	for {
+		t := p.next()
+		switch t.text {
+		case "":
+			return nil, fmt.Errorf("line %d: unterminated caveat %s", line, c.name)
+		case "{":
+			depth++
+		case "}":
+			if depth--; depth == 0 {
+				c.body = strings.TrimSpace(p.src[open.off+1 : t.off])
+				return c, nil
+			}
+		}
+	}
+}
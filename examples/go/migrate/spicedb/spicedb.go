@@ -0,0 +1,253 @@
+// Package spicedb migrates a SpiceDB schema and relationship export to the
+// closest OpenFGA model and tuples.
+//
+// Definitions become types, relations and permissions become relations,
+// "+", "&" and "-" become or, and and but not, and arrows become
+// tuple-to-userset. Caveats become conditions with the CEL kept verbatim.
+// Anything without an exact equivalent — expiration traits, functioned
+// arrows, nil, arrows over permissions, caveated relationships — is
+// reported, as are wildcards and caveats, which map but deserve review.
+package spicedb
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/migrate"
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// Convert reads a schema and, when relationships is non-nil, a
+// relationship export with one relationship per line, either as
+// "document:1#reader@user:alice" or in zed's "document:1 reader user:alice"
+// form.
+func Convert(schemaSrc string, relationships io.Reader) (*migrate.Result, error) {
+	s, err := parseSchema(schemaSrc)
+	if err != nil {
+		return nil, fmt.Errorf("spicedb: %w", err)
+	}
+	c := &converter{res: &migrate.Result{Model: &model.Model{SchemaVersion: "1.1"}}, s: s, names: map[string]string{}}
+	c.convertSchema()
+	if relationships != nil {
+		if err := c.convertRelationships(relationships); err != nil {
+			return nil, err
+		}
+	}
+	return c.res, nil
+}
+
+type converter struct {
+	res   *migrate.Result
+	s     *schema
+	names map[string]string // SpiceDB definition name → type name
+}
+
+func (c *converter) typeName(def string) string {
+	if n, ok := c.names[def]; ok {
+		return n
+	}
+	return migrate.Name(def)
+}
+
+func (c *converter) convertSchema() {
+	for _, d := range c.s.defs {
+		name := migrate.Name(d.name)
+		if name != d.name {
+			c.res.Issuef(d.line, d.name, "definition renamed to type %s", name)
+		}
+		c.names[d.name] = name
+	}
+	for _, d := range c.s.defs {
+		t := &model.Type{Name: c.names[d.name]}
+		for _, m := range d.members {
+			where := d.name + "#" + m.name
+			var rw model.Rewrite
+			if m.permission {
+				rw = c.expr(d, where, m.line, m.expr)
+			} else {
+				rw = c.direct(where, m.line, m.subjects)
+			}
+			if rw == nil {
+				c.res.Issuef(m.line, where, "dropped: evaluates to the empty set")
+				continue
+			}
+			t.Relations = append(t.Relations, &model.Relation{Name: m.name, Rewrite: rw})
+		}
+		c.res.Model.Types = append(c.res.Model.Types, t)
+	}
+	for _, cv := range c.s.caveats {
+		cond := &model.Condition{Name: migrate.Name(cv.name), Expression: cv.body}
+		for _, p := range cv.params {
+			cond.Params = append(cond.Params, model.Param{Name: p[0], Type: p[1]})
+		}
+		c.res.Model.Conditions = append(c.res.Model.Conditions, cond)
+		c.res.Issuef(cv.line, cv.name, "caveat converted to condition; check the CEL expression and parameter types")
+	}
+}
+
+func (c *converter) direct(where string, line int, subjects []subject) model.Rewrite {
+	d := &model.Direct{}
+	for _, s := range subjects {
+		ref := model.TypeRef{Type: c.typeName(s.typ), Wildcard: s.wildcard}
+		if s.relation != "" && s.relation != "..." {
+			ref.Relation = s.relation
+		}
+		if s.wildcard {
+			c.res.Issuef(line, where, "%s:* grants public access; confirm it is intended", ref.Type)
+		}
+		for _, trait := range strings.Split(s.caveat, " and ") {
+			switch trait {
+			case "":
+			case "expiration":
+				c.res.Issuef(line, where, "expiration trait on %s not supported; expire grants with a condition or a cleanup job", s.typ)
+			default:
+				ref.Condition = migrate.Name(trait)
+			}
+		}
+		d.Types = append(d.Types, ref)
+	}
+	return d
+}
+
+// expr converts a permission expression; nil means the empty set.
+func (c *converter) expr(d *definition, where string, line int, e expr) model.Rewrite {
+	switch e := e.(type) {
+	case *exprRef:
+		return &model.Computed{Relation: e.name}
+	case *exprNil:
+		return nil
+	case *exprArrow:
+		for _, m := range d.members {
+			if m.name == e.tupleset && m.permission {
+				c.res.Issuef(line, where, "arrow over permission %s; OpenFGA needs a relation as the tupleset", e.tupleset)
+				return nil
+			}
+		}
+		return &model.TupleToUserset{Tupleset: e.tupleset, Computed: e.computed}
+	case *exprOp:
+		left, right := c.expr(d, where, line, e.left), c.expr(d, where, line, e.right)
+		switch e.op {
+		case "+":
+			if left == nil {
+				return right
+			}
+			if right == nil {
+				return left
+			}
+			u := &model.Union{}
+			u.Children = append(operands(left, u), operands(right, u)...)
+			return u
+		case "&":
+			if left == nil || right == nil {
+				return nil
+			}
+			n := &model.Intersection{}
+			n.Children = append(operands(left, n), operands(right, n)...)
+			return n
+		case "-":
+			if left == nil || right == nil {
+				return left
+			}
+			return &model.Difference{Base: left, Subtract: right}
+		}
+	}
+	return nil
+}
+
+// operands returns the children of rw when it is a node of the same kind
+// as like, so chained operators become one n-ary node.
+func operands(rw model.Rewrite, like model.Rewrite) []model.Rewrite {
+	switch n := rw.(type) {
+	case *model.Union:
+		if _, ok := like.(*model.Union); ok {
+			return n.Children
+		}
+	case *model.Intersection:
+		if _, ok := like.(*model.Intersection); ok {
+			return n.Children
+		}
+	}
+	return []model.Rewrite{rw}
+}
+
+func (c *converter) convertRelationships(r io.Reader) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for sc.Scan() {
+		line++
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "//") || strings.HasPrefix(text, "#") {
+			continue
+		}
+		t, caveat, err := c.relationship(text)
+		if err != nil {
+			c.res.Issuef(line, text, "%v", err)
+			continue
+		}
+		if caveat != "" {
+			c.res.Issuef(line, text, "relationship has %s; import it with a tuple condition by hand", caveat)
+			continue
+		}
+		c.res.Tuples = append(c.res.Tuples, t)
+	}
+	return sc.Err()
+}
+
+func (c *converter) relationship(text string) (authz.Tuple, string, error) {
+	var caveat string
+	if i := strings.IndexByte(text, '['); i >= 0 {
+		caveat = strings.TrimSuffix(text[i+1:], "]")
+		if name, _, ok := strings.Cut(caveat, ":"); ok && !strings.HasPrefix(caveat, "expiration") {
+			caveat = name
+		}
+		caveat = "caveat " + caveat
+		text = strings.TrimSpace(text[:i])
+	}
+	var resource, relation, subj string
+	if fields := strings.Fields(text); len(fields) >= 3 {
+		resource, relation, subj = fields[0], fields[1], fields[2]
+		if len(fields) == 4 {
+			subj += "#" + fields[3]
+		}
+	} else {
+		obj, rest, ok := strings.Cut(text, "#")
+		rel, s, ok2 := strings.Cut(rest, "@")
+		if !ok || !ok2 {
+			return authz.Tuple{}, "", fmt.Errorf("expected resource#relation@subject")
+		}
+		resource, relation, subj = obj, rel, s
+	}
+	object, err := c.object(resource)
+	if err != nil {
+		return authz.Tuple{}, "", err
+	}
+	subjObj, subjRel, _ := strings.Cut(subj, "#")
+	user, err := c.object(subjObj)
+	if err != nil {
+		return authz.Tuple{}, "", err
+	}
+	if subjRel != "" && subjRel != "..." {
+		user += "#" + subjRel
+	}
+	return authz.Tuple{User: user, Relation: relation, Object: object}, caveat, nil
+}
+
+func (c *converter) object(s string) (string, error) {
+	typ, id, ok := strings.Cut(s, ":")
+	if !ok {
+		return "", fmt.Errorf("%q is not type:id", s)
+	}
+	name := c.typeName(typ)
+	if id == "*" {
+		return name + ":*", nil
+	}
+	id, err := migrate.ID(name, id)
+	if err != nil {
+		return "", err
+	}
+	return name + ":" + id, nil
+}
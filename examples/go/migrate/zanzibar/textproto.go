@@ -0,0 +1,165 @@
+package zanzibar
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// node is a protobuf text-format field: either a scalar or a message.
+type node struct {
+	name     string
+	value    string
+	children []*node
+	line     int
+}
+
+func (n *node) all(name string) []*node {
+	var out []*node
+	for _, c := range n.children {
+		if c.name == name {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func (n *node) get(name string) *node {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func (n *node) str(name string) string {
+	if c := n.get(name); c != nil {
+		return c.value
+	}
+	return ""
+}
+
+type lexer struct {
+	src  string
+	pos  int
+	line int
+}
+
+func (l *lexer) skip() {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '\n':
+			l.line++
+			l.pos++
+		case c == ' ' || c == '\t' || c == '\r' || c == ',' || c == ';':
+			l.pos++
+		case c == '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// token returns the next token: an identifier, a quoted string (with
+// quotes), or one of "{", "}", ":"; "" at end of input.
+func (l *lexer) token() (string, error) {
+	l.skip()
+	if l.pos >= len(l.src) {
+		return "", nil
+	}
+	start := l.pos
+	switch c := l.src[l.pos]; {
+	case c == '{' || c == '}' || c == ':':
+		l.pos++
+	case c == '"' || c == '\'':
+		l.pos++
+		for l.pos < len(l.src) && l.src[l.pos] != c {
+			if l.src[l.pos] == '\\' {
+				l.pos++
+			}
+			l.pos++
+		}
+		if l.pos >= len(l.src) {
+			return "", fmt.Errorf("line %d: unterminated string", l.line)
+		}
+		l.pos++
+	default:
+		for l.pos < len(l.src) {
+			r := rune(l.src[l.pos])
+			if !(unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_$.-", r)) {
+				break
+			}
+			l.pos++
+		}
+		if l.pos == start {
+			return "", fmt.Errorf("line %d: unexpected %q", l.line, c)
+		}
+	}
+	return l.src[start:l.pos], nil
+}
+
+// parseText parses protobuf text format into a root node.
+func parseText(src string) (*node, error) {
+	l := &lexer{src: src, line: 1}
+	root := &node{}
+	if err := parseFields(l, root, false); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+func parseFields(l *lexer, parent *node, nested bool) error {
+	for {
+		tok, err := l.token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case "":
+			if nested {
+				return fmt.Errorf("line %d: missing }", l.line)
+			}
+			return nil
+		case "}":
+			if !nested {
+				return fmt.Errorf("line %d: unexpected }", l.line)
+			}
+			return nil
+		case "{", ":":
+			return fmt.Errorf("line %d: expected field name, found %q", l.line, tok)
+		}
+		n := &node{name: tok, line: l.line}
+		parent.children = append(parent.children, n)
+		next, err := l.token()
+		if err != nil {
+			return err
+		}
+		if next == ":" {
+			if next, err = l.token(); err != nil {
+				return err
+			}
+			if next != "{" {
+				if strings.HasPrefix(next, "\"") || strings.HasPrefix(next, "'") {
+					if v, err := strconv.Unquote("\"" + next[1:len(next)-1] + "\""); err == nil {
+						next = "\"" + v + "\""
+					}
+					next = next[1 : len(next)-1]
+				}
+				n.value = next
+				continue
+			}
+		}
+		if next != "{" {
+			return fmt.Errorf("line %d: expected { or : after %s", l.line, tok)
+		}
+		if err := parseFields(l, n, true); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,258 @@
+// Package zanzibar converts Zanzibar-style namespace configurations, in
+// protobuf text format, into an OpenFGA model:
+//
+//	name: "doc"
+//	relation { name: "owner" }
+//	relation {
+//	  name: "viewer"
+//	  userset_rewrite {
+//	    union {
+//	      child { _this {} }
+//	      child { computed_userset { relation: "owner" } }
+//	      child { tuple_to_userset {
+//	        tupleset { relation: "parent" }
+//	        computed_userset { object: $TUPLE_USERSET_OBJECT relation: "viewer" }
+//	      } }
+//	    }
+//	  }
+//	}
+//
+// Rewrites map one to one. Zanzibar has no type restrictions, so direct
+// assignments get Options.DefaultSubjects, except tupleset relations, which
+// are restricted to the namespaces that define the computed relation.
+// Every such inference is reported.
+package zanzibar
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/bogdanticu88/openfga-examples/migrate"
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// Options tunes the conversion.
+type Options struct {
+	// DefaultSubjects are the type restrictions given to directly
+	// assignable relations, e.g. "user", "group#member"; default "user".
+	DefaultSubjects []string
+}
+
+// ConvertFiles reads and converts namespace config files.
+func ConvertFiles(opts Options, paths ...string) (*migrate.Result, error) {
+	var srcs []string
+	for _, p := range paths {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		srcs = append(srcs, string(b))
+	}
+	return Convert(opts, srcs...)
+}
+
+// Convert converts namespace configs. A source holds a single config or
+// several wrapped in "namespace { ... }" or "config { ... }" blocks.
+func Convert(opts Options, srcs ...string) (*migrate.Result, error) {
+	if len(opts.DefaultSubjects) == 0 {
+		opts.DefaultSubjects = []string{"user"}
+	}
+	var subjects []model.TypeRef
+	for _, s := range opts.DefaultSubjects {
+		typ, rel, _ := strings.Cut(s, "#")
+		subjects = append(subjects, model.TypeRef{Type: typ, Relation: rel})
+	}
+
+	var namespaces []*node
+	for i, src := range srcs {
+		root, err := parseText(src)
+		if err != nil {
+			return nil, fmt.Errorf("zanzibar: source %d: %w", i+1, err)
+		}
+		if root.get("name") != nil {
+			namespaces = append(namespaces, root)
+		}
+		for _, c := range root.children {
+			if c.name == "namespace" || c.name == "config" {
+				namespaces = append(namespaces, c)
+			}
+		}
+	}
+
+	res := &migrate.Result{Model: &model.Model{SchemaVersion: "1.1"}}
+	c := &converter{res: res}
+	for _, ns := range namespaces {
+		t := &model.Type{Name: ns.str("name")}
+		if t.Name == "" {
+			res.Issuef(ns.line, "namespace", "namespace without a name skipped")
+			continue
+		}
+		for _, rn := range ns.all("relation") {
+			r := &model.Relation{Name: rn.str("name")}
+			if rw := rn.get("userset_rewrite"); rw != nil {
+				r.Rewrite = c.rewrite(t.Name+"#"+r.Name, rw)
+			} else {
+				r.Rewrite = &model.Direct{}
+			}
+			if r.Rewrite == nil {
+				continue
+			}
+			t.Relations = append(t.Relations, r)
+		}
+		res.Model.Types = append(res.Model.Types, t)
+	}
+
+	c.restrict(subjects)
+	for _, ref := range subjects {
+		if res.Model.Type(ref.Type) == nil {
+			res.Model.Types = append([]*model.Type{{Name: ref.Type}}, res.Model.Types...)
+		}
+	}
+	return res, nil
+}
+
+type converter struct {
+	res *migrate.Result
+	// tuplesets maps type#relation to the computed relations looked up
+	// through it.
+	tuplesets map[string][]string
+}
+
+// rewrite converts a userset_rewrite, or any node holding one rewrite.
+func (c *converter) rewrite(where string, n *node) model.Rewrite {
+	for _, k := range n.children {
+		switch k.name {
+		case "_this", "this":
+			return &model.Direct{}
+		case "computed_userset":
+			if obj := k.str("object"); obj != "" && obj != "$TUPLE_USERSET_OBJECT" {
+				c.res.Issuef(k.line, where, "computed_userset object %q not supported", obj)
+				return nil
+			}
+			return &model.Computed{Relation: k.str("relation")}
+		case "tuple_to_userset":
+			ts := k.get("tupleset")
+			cu := k.get("computed_userset")
+			if ts == nil || cu == nil {
+				c.res.Issuef(k.line, where, "tuple_to_userset needs tupleset and computed_userset")
+				return nil
+			}
+			typ, _, _ := strings.Cut(where, "#")
+			if c.tuplesets == nil {
+				c.tuplesets = map[string][]string{}
+			}
+			key := typ + "#" + ts.str("relation")
+			c.tuplesets[key] = append(c.tuplesets[key], cu.str("relation"))
+			return &model.TupleToUserset{Tupleset: ts.str("relation"), Computed: cu.str("relation")}
+		case "userset_rewrite", "child":
+			return c.rewrite(where, k)
+		case "union", "intersection":
+			children := c.children(where, k.all("child"))
+			if children == nil {
+				return nil
+			}
+			if len(children) == 1 {
+				return children[0]
+			}
+			if k.name == "union" {
+				return &model.Union{Children: children}
+			}
+			return &model.Intersection{Children: children}
+		case "exclusion":
+			var base, sub model.Rewrite
+			if b, s := k.get("base"), k.get("subtract"); b != nil && s != nil {
+				base, sub = c.rewrite(where, b), c.rewrite(where, s)
+			} else {
+				children := c.children(where, k.all("child"))
+				if len(children) < 2 {
+					c.res.Issuef(k.line, where, "exclusion needs a base and a subtracted set")
+					return nil
+				}
+				base = children[0]
+				if len(children) == 2 {
+					sub = children[1]
+				} else {
+					sub = &model.Union{Children: children[1:]}
+				}
+			}
+			if base == nil || sub == nil {
+				return nil
+			}
+			return &model.Difference{Base: base, Subtract: sub}
+		}
+	}
+	c.res.Issuef(n.line, where, "unsupported userset rewrite")
+	return nil
+}
+
+func (c *converter) children(where string, nodes []*node) []model.Rewrite {
+	var out []model.Rewrite
+	for _, n := range nodes {
+		rw := c.rewrite(where, n)
+		if rw == nil {
+			return nil
+		}
+		out = append(out, rw)
+	}
+	return out
+}
+
+// restrict fills in the type restrictions of every direct assignment.
+func (c *converter) restrict(subjects []model.TypeRef) {
+	m := c.res.Model
+	defaulted := 0
+	for _, t := range m.Types {
+		for _, r := range t.Relations {
+			var refs []model.TypeRef
+			if computed, ok := c.tuplesets[t.Name+"#"+r.Name]; ok {
+				refs = c.parents(computed)
+				if len(refs) > 0 {
+					names := make([]string, len(refs))
+					for i, ref := range refs {
+						names[i] = ref.Type
+					}
+					c.res.Issuef(0, t.Name+"#"+r.Name, "tupleset restricted to [%s], the types defining %s", strings.Join(names, ", "), strings.Join(computed, ", "))
+				}
+			}
+			inferred := refs != nil
+			if !inferred {
+				refs = subjects
+			}
+			model.Walk(r.Rewrite, func(rw model.Rewrite) {
+				if d, ok := rw.(*model.Direct); ok && len(d.Types) == 0 {
+					d.Types = append([]model.TypeRef(nil), refs...)
+					if !inferred {
+						defaulted++
+					}
+				}
+			})
+		}
+	}
+	if defaulted > 0 {
+		names := make([]string, len(subjects))
+		for i, s := range subjects {
+			names[i] = s.String()
+		}
+		c.res.Issuef(0, "type restrictions", "%d direct assignments defaulted to [%s]; narrow them per relation", defaulted, strings.Join(names, ", "))
+	}
+}
+
+// parents returns the types defining every one of the computed relations.
+func (c *converter) parents(computed []string) []model.TypeRef {
+	var refs []model.TypeRef
+	for _, t := range c.res.Model.Types {
+		ok := true
+		for _, rel := range computed {
+			if t.Relation(rel) == nil {
+				ok = false
+			}
+		}
+		if ok {
+			refs = append(refs, model.TypeRef{Type: t.Name})
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Type < refs[j].Type })
+	return refs
+}
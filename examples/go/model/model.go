@@ -0,0 +1,261 @@
+// Package model is an in-memory representation of OpenFGA authorization
+// models, with a parser for the .fga DSL used under models/.
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Pos is a location in a DSL source file. Line and Col are 1-based.
+type Pos struct {
+	File string
+	Line int
+	Col  int
+}
+
+func (p Pos) String() string {
+	if p.File == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Col)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Col)
+}
+
+// Model is a parsed authorization model.
+type Model struct {
+	// ID is the server-assigned model ID; empty for models parsed from DSL.
+	ID            string
+	SchemaVersion string
+	Types         []*Type
+	Conditions    []*Condition
+}
+
+// Type returns the type definition named name, or nil.
+func (m *Model) Type(name string) *Type {
+	for _, t := range m.Types {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// Relation returns relation rel of type typ, or nil.
+func (m *Model) Relation(typ, rel string) *Relation {
+	if t := m.Type(typ); t != nil {
+		return t.Relation(rel)
+	}
+	return nil
+}
+
+// Condition returns the condition named name, or nil.
+func (m *Model) Condition(name string) *Condition {
+	for _, c := range m.Conditions {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// Type is a type definition such as "type project".
+type Type struct {
+	Name      string
+	Relations []*Relation
+	Pos       Pos
+}
+
+// Relation returns the relation named name, or nil.
+func (t *Type) Relation(name string) *Relation {
+	for _, r := range t.Relations {
+		if r.Name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+// Relation is one "define name: rewrite" line.
+type Relation struct {
+	Name    string
+	Rewrite Rewrite
+	Pos     Pos
+}
+
+// DirectTypes returns the type restrictions of every direct assignment in
+// the relation's rewrite, in source order.
+func (r *Relation) DirectTypes() []TypeRef {
+	var refs []TypeRef
+	Walk(r.Rewrite, func(rw Rewrite) {
+		if d, ok := rw.(*Direct); ok {
+			refs = append(refs, d.Types...)
+		}
+	})
+	return refs
+}
+
+// WildcardTypes returns the types whose wildcard ("user:*") may be
+// assigned to r, i.e. the types r can be made public to.
+func (r *Relation) WildcardTypes() []string {
+	var types []string
+	for _, ref := range r.DirectTypes() {
+		if ref.Wildcard {
+			types = append(types, ref.Type)
+		}
+	}
+	return types
+}
+
+// Assignable reports whether tuples can be written directly to r.
+func (r *Relation) Assignable() bool {
+	return len(r.DirectTypes()) > 0
+}
+
+// TypeRef is one entry of a direct type restriction: "user", "user:*",
+// "group#member" or any of those followed by "with condition".
+type TypeRef struct {
+	Type      string
+	Relation  string
+	Wildcard  bool
+	Condition string
+}
+
+func (t TypeRef) String() string {
+	s := t.Type
+	switch {
+	case t.Wildcard:
+		s += ":*"
+	case t.Relation != "":
+		s += "#" + t.Relation
+	}
+	if t.Condition != "" {
+		s += " with " + t.Condition
+	}
+	return s
+}
+
+// Condition is a named CEL condition. The expression is kept verbatim; the
+// embedded evaluator does not interpret it.
+type Condition struct {
+	Name       string
+	Params     []Param
+	Expression string
+	Pos        Pos
+}
+
+// Param is a typed condition parameter, e.g. "ip: ipaddress".
+type Param struct {
+	Name string
+	Type string
+}
+
+// Rewrite is a relation definition expression. The concrete types are
+// *Direct, *Computed, *TupleToUserset, *Union, *Intersection and
+// *Difference.
+type Rewrite interface {
+	fmt.Stringer
+	rewrite()
+}
+
+// Direct allows tuples whose user matches one of Types.
+type Direct struct {
+	Types []TypeRef
+}
+
+// Computed grants the relation to everyone who has Relation on the same
+// object.
+type Computed struct {
+	Relation string
+}
+
+// TupleToUserset is "Computed from Tupleset": users holding Computed on any
+// object related through Tupleset.
+type TupleToUserset struct {
+	Tupleset string
+	Computed string
+}
+
+// Union is "a or b or ...".
+type Union struct {
+	Children []Rewrite
+}
+
+// Intersection is "a and b and ...".
+type Intersection struct {
+	Children []Rewrite
+}
+
+// Difference is "base but not subtract".
+type Difference struct {
+	Base     Rewrite
+	Subtract Rewrite
+}
+
+func (*Direct) rewrite()         {}
+func (*Computed) rewrite()       {}
+func (*TupleToUserset) rewrite() {}
+func (*Union) rewrite()          {}
+func (*Intersection) rewrite()   {}
+func (*Difference) rewrite()     {}
+
+func (d *Direct) String() string {
+	refs := make([]string, len(d.Types))
+	for i, t := range d.Types {
+		refs[i] = t.String()
+	}
+	return "[" + strings.Join(refs, ", ") + "]"
+}
+
+func (c *Computed) String() string { return c.Relation }
+
+func (t *TupleToUserset) String() string { return t.Computed + " from " + t.Tupleset }
+
+func (u *Union) String() string { return join(u.Children, " or ") }
+
+func (i *Intersection) String() string { return join(i.Children, " and ") }
+
+func (d *Difference) String() string {
+	base := d.Base.String()
+	if _, ok := d.Base.(*Difference); ok {
+		base = "(" + base + ")"
+	}
+	return base + " but not " + operand(d.Subtract)
+}
+
+func join(children []Rewrite, sep string) string {
+	parts := make([]string, len(children))
+	for i, c := range children {
+		parts[i] = operand(c)
+	}
+	return strings.Join(parts, sep)
+}
+
+// operand renders rw, parenthesized when it is itself a compound expression.
+func operand(rw Rewrite) string {
+	switch rw.(type) {
+	case *Union, *Intersection, *Difference:
+		return "(" + rw.String() + ")"
+	}
+	return rw.String()
+}
+
+// Walk calls fn for rw and every expression nested in it, depth first.
+func Walk(rw Rewrite, fn func(Rewrite)) {
+	if rw == nil {
+		return
+	}
+	fn(rw)
+	switch n := rw.(type) {
+	case *Union:
+		for _, c := range n.Children {
+			Walk(c, fn)
+		}
+	case *Intersection:
+		for _, c := range n.Children {
+			Walk(c, fn)
+		}
+	case *Difference:
+		Walk(n.Base, fn)
+		Walk(n.Subtract, fn)
+	}
+}
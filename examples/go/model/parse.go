@@ -0,0 +1,419 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// SyntaxError is a parse failure at a source position.
+type SyntaxError struct {
+	Pos Pos
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return e.Pos.String() + ": " + e.Msg
+}
+
+// ParseFile reads and parses a .fga file.
+func ParseFile(path string) (*Model, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(path, src)
+}
+
+// MustParse parses src and panics on error. It is meant for model fragments
+// embedded in Go code.
+func MustParse(src string) *Model {
+	m, err := Parse("", []byte(src))
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// Parse parses DSL source. file is used only in positions and may be empty.
+// The result is syntactically valid; call Validate for semantic checks.
+func Parse(file string, src []byte) (*Model, error) {
+	p := &parser{file: file, m: &Model{}}
+	lines := strings.Split(strings.ReplaceAll(string(src), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		text, col := stripLine(lines[i])
+		if text == "" {
+			continue
+		}
+		pos := Pos{File: file, Line: i + 1, Col: col}
+		keyword, rest := cutWord(text)
+		var err error
+		switch keyword {
+		case "model":
+			if rest != "" {
+				err = p.errorf(pos, "unexpected %q after model", rest)
+			}
+			p.sawModel = true
+		case "schema":
+			if !p.sawModel {
+				err = p.errorf(pos, "schema must follow model")
+			}
+			p.m.SchemaVersion = rest
+		case "type":
+			err = p.typeDecl(pos, rest)
+		case "relations":
+			if p.typ == nil {
+				err = p.errorf(pos, "relations outside of a type")
+			}
+			p.inRelations = true
+		case "define":
+			err = p.define(pos, rest)
+		case "condition":
+			var consumed int
+			consumed, err = p.condition(pos, lines[i:])
+			i += consumed - 1
+		default:
+			err = p.errorf(pos, "unexpected %q", keyword)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return p.m, nil
+}
+
+type parser struct {
+	file        string
+	m           *Model
+	sawModel    bool
+	typ         *Type
+	inRelations bool
+}
+
+func (p *parser) errorf(pos Pos, format string, args ...interface{}) error {
+	return &SyntaxError{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) typeDecl(pos Pos, name string) error {
+	if !isIdent(name) {
+		return p.errorf(pos, "invalid type name %q", name)
+	}
+	p.typ = &Type{Name: name, Pos: pos}
+	p.inRelations = false
+	p.m.Types = append(p.m.Types, p.typ)
+	return nil
+}
+
+func (p *parser) define(pos Pos, rest string) error {
+	if p.typ == nil || !p.inRelations {
+		return p.errorf(pos, "define outside of a relations block")
+	}
+	colon := strings.Index(rest, ":")
+	if colon < 0 {
+		return p.errorf(pos, "expected ':' after relation name")
+	}
+	name := strings.TrimSpace(rest[:colon])
+	if !isIdent(name) {
+		return p.errorf(pos, "invalid relation name %q", name)
+	}
+	exprCol := pos.Col + len("define ") + colon + 1
+	rw, err := parseRewrite(Pos{File: pos.File, Line: pos.Line, Col: exprCol}, rest[colon+1:])
+	if err != nil {
+		return err
+	}
+	p.typ.Relations = append(p.typ.Relations, &Relation{Name: name, Rewrite: rw, Pos: pos})
+	return nil
+}
+
+// condition parses "condition name(p: type, ...) { expr }", which may span
+// several lines, and returns the number of lines consumed.
+func (p *parser) condition(pos Pos, lines []string) (int, error) {
+	var sb strings.Builder
+	depth, opened := 0, false
+	n := 0
+	for ; n < len(lines); n++ {
+		line := lines[n]
+		for _, r := range line {
+			switch r {
+			case '{':
+				depth++
+				opened = true
+			case '}':
+				depth--
+			}
+		}
+		sb.WriteString(line)
+		sb.WriteString("\n")
+		if opened && depth == 0 {
+			n++
+			break
+		}
+	}
+	if !opened || depth != 0 {
+		return n, p.errorf(pos, "unterminated condition body")
+	}
+	text := strings.TrimSpace(sb.String())
+	text = strings.TrimSpace(strings.TrimPrefix(text, "condition"))
+	open, close := strings.Index(text, "("), strings.Index(text, ")")
+	brace := strings.Index(text, "{")
+	if open < 0 || close < open || brace < close {
+		return n, p.errorf(pos, "expected condition name(params) { expression }")
+	}
+	c := &Condition{Name: strings.TrimSpace(text[:open]), Pos: pos}
+	if !isIdent(c.Name) {
+		return n, p.errorf(pos, "invalid condition name %q", c.Name)
+	}
+	for _, param := range strings.Split(text[open+1:close], ",") {
+		param = strings.TrimSpace(param)
+		if param == "" {
+			continue
+		}
+		name, typ, ok := strings.Cut(param, ":")
+		if !ok {
+			return n, p.errorf(pos, "condition %s: parameter %q needs a type", c.Name, param)
+		}
+		c.Params = append(c.Params, Param{Name: strings.TrimSpace(name), Type: strings.TrimSpace(typ)})
+	}
+	body := text[brace+1:]
+	c.Expression = strings.TrimSpace(body[:strings.LastIndex(body, "}")])
+	p.m.Conditions = append(p.m.Conditions, c)
+	return n, nil
+}
+
+// ParseRewrite parses a relation expression such as
+// "[user] or editor from parent".
+func ParseRewrite(expr string) (Rewrite, error) {
+	return parseRewrite(Pos{Line: 1, Col: 1}, expr)
+}
+
+func parseRewrite(pos Pos, expr string) (Rewrite, error) {
+	toks, err := tokenize(pos, expr)
+	if err != nil {
+		return nil, err
+	}
+	ep := &exprParser{toks: toks, end: Pos{File: pos.File, Line: pos.Line, Col: pos.Col + len(expr)}}
+	rw, err := ep.expr()
+	if err != nil {
+		return nil, err
+	}
+	if t := ep.peek(); t.text != "" {
+		return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("unexpected %q", t.text)}
+	}
+	return rw, nil
+}
+
+type token struct {
+	text string
+	pos  Pos
+}
+
+func tokenize(pos Pos, s string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case strings.IndexByte("[](),:#*", c) >= 0:
+			toks = append(toks, token{text: s[i : i+1], pos: Pos{File: pos.File, Line: pos.Line, Col: pos.Col + i}})
+			i++
+		case isIdentByte(c):
+			j := i
+			for j < len(s) && isIdentByte(s[j]) {
+				j++
+			}
+			toks = append(toks, token{text: s[i:j], pos: Pos{File: pos.File, Line: pos.Line, Col: pos.Col + i}})
+			i = j
+		default:
+			return nil, &SyntaxError{Pos: Pos{File: pos.File, Line: pos.Line, Col: pos.Col + i}, Msg: fmt.Sprintf("unexpected character %q", c)}
+		}
+	}
+	return toks, nil
+}
+
+type exprParser struct {
+	toks []token
+	i    int
+	end  Pos
+}
+
+func (p *exprParser) peek() token {
+	if p.i < len(p.toks) {
+		return p.toks[p.i]
+	}
+	return token{pos: p.end}
+}
+
+func (p *exprParser) next() token {
+	t := p.peek()
+	if p.i < len(p.toks) {
+		p.i++
+	}
+	return t
+}
+
+func (p *exprParser) expect(text string) error {
+	if t := p.next(); t.text != text {
+		return &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("expected %q, found %q", text, t.text)}
+	}
+	return nil
+}
+
+// expr parses terms joined by a single kind of operator, optionally
+// followed by "but not term". Mixing operators requires parentheses.
+func (p *exprParser) expr() (Rewrite, error) {
+	first, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	children := []Rewrite{first}
+	op := ""
+	for t := p.peek(); t.text == "or" || t.text == "and"; t = p.peek() {
+		if op != "" && op != t.text {
+			return nil, &SyntaxError{Pos: t.pos, Msg: "mixing 'or' and 'and' requires parentheses"}
+		}
+		op = p.next().text
+		child, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+	}
+	var rw Rewrite = first
+	switch op {
+	case "or":
+		rw = &Union{Children: children}
+	case "and":
+		rw = &Intersection{Children: children}
+	}
+	if p.peek().text == "but" {
+		p.next()
+		if err := p.expect("not"); err != nil {
+			return nil, err
+		}
+		sub, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		rw = &Difference{Base: rw, Subtract: sub}
+		if t := p.peek(); t.text == "or" || t.text == "and" || t.text == "but" {
+			return nil, &SyntaxError{Pos: t.pos, Msg: "operators after 'but not' require parentheses"}
+		}
+	}
+	return rw, nil
+}
+
+func (p *exprParser) term() (Rewrite, error) {
+	t := p.next()
+	switch {
+	case t.text == "[":
+		return p.direct()
+	case t.text == "(":
+		rw, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		return rw, p.expect(")")
+	case isIdent(t.text) && !isKeyword(t.text):
+		if p.peek().text == "from" {
+			p.next()
+			ts := p.next()
+			if !isIdent(ts.text) || isKeyword(ts.text) {
+				return nil, &SyntaxError{Pos: ts.pos, Msg: fmt.Sprintf("expected tupleset relation after 'from', found %q", ts.text)}
+			}
+			return &TupleToUserset{Tupleset: ts.text, Computed: t.text}, nil
+		}
+		return &Computed{Relation: t.text}, nil
+	case t.text == "":
+		return nil, &SyntaxError{Pos: t.pos, Msg: "unexpected end of expression"}
+	}
+	return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("unexpected %q", t.text)}
+}
+
+func (p *exprParser) direct() (Rewrite, error) {
+	d := &Direct{}
+	for {
+		t := p.next()
+		if !isIdent(t.text) || isKeyword(t.text) {
+			return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("expected type name, found %q", t.text)}
+		}
+		ref := TypeRef{Type: t.text}
+		switch p.peek().text {
+		case ":":
+			p.next()
+			if err := p.expect("*"); err != nil {
+				return nil, err
+			}
+			ref.Wildcard = true
+		case "#":
+			p.next()
+			rel := p.next()
+			if !isIdent(rel.text) {
+				return nil, &SyntaxError{Pos: rel.pos, Msg: fmt.Sprintf("expected relation after '#', found %q", rel.text)}
+			}
+			ref.Relation = rel.text
+		}
+		if p.peek().text == "with" {
+			p.next()
+			cond := p.next()
+			if !isIdent(cond.text) {
+				return nil, &SyntaxError{Pos: cond.pos, Msg: fmt.Sprintf("expected condition name, found %q", cond.text)}
+			}
+			ref.Condition = cond.text
+		}
+		d.Types = append(d.Types, ref)
+		switch t := p.next(); t.text {
+		case ",":
+			continue
+		case "]":
+			return d, nil
+		default:
+			return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("expected ',' or ']', found %q", t.text)}
+		}
+	}
+}
+
+// stripLine removes a trailing comment and surrounding space, returning the
+// remaining text and its 1-based starting column.
+func stripLine(line string) (string, int) {
+	for i := 0; i < len(line); i++ {
+		if line[i] == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t') {
+			line = line[:i]
+			break
+		}
+	}
+	trimmed := strings.TrimLeftFunc(line, unicode.IsSpace)
+	col := len(line) - len(trimmed) + 1
+	return strings.TrimRightFunc(trimmed, unicode.IsSpace), col
+}
+
+func cutWord(s string) (string, string) {
+	word, rest, _ := strings.Cut(s, " ")
+	return word, strings.TrimSpace(rest)
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '-' || c == '.' || c == '/' ||
+		('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+func isIdent(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isIdentByte(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isKeyword(s string) bool {
+	switch s {
+	case "or", "and", "but", "not", "from", "with":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,236 @@
+package model_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+func TestParseRewrite(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"[user]", "[user]"},
+		{"[user, user:*, group#member]", "[user, user:*, group#member]"},
+		{"[user with not_expired, group#member with in_office]", "[user with not_expired, group#member with in_office]"},
+		{"owner", "owner"},
+		{"viewer from parent", "viewer from parent"},
+		{"[user] or owner or viewer from parent", "[user] or owner or viewer from parent"},
+		{"member and allowed", "member and allowed"},
+		{"viewer but not blocked", "viewer but not blocked"},
+		{"([user] or editor) but not blocked", "[user] or editor but not blocked"},
+		{"(a but not b) but not c", "(a but not b) but not c"},
+		{"a or (b and c)", "a or (b and c)"},
+	}
+	for _, tt := range tests {
+		rw, err := model.ParseRewrite(tt.expr)
+		if err != nil {
+			t.Errorf("ParseRewrite(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := rw.String(); got != tt.want {
+			t.Errorf("ParseRewrite(%q) = %q, want %q", tt.expr, got, tt.want)
+		}
+		// The printed form parses back to the same expression.
+		again, err := model.ParseRewrite(tt.want)
+		if err != nil || again.String() != tt.want {
+			t.Errorf("ParseRewrite(%q) = %v, %v; want it to round-trip", tt.want, again, err)
+		}
+	}
+}
+
+func TestParseRewriteShapes(t *testing.T) {
+	rw, err := model.ParseRewrite("[user, group#member] or viewer from parent but not blocked")
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff, ok := rw.(*model.Difference)
+	if !ok {
+		t.Fatalf("got %T, want *model.Difference", rw)
+	}
+	if sub, ok := diff.Subtract.(*model.Computed); !ok || sub.Relation != "blocked" {
+		t.Errorf("Subtract = %#v, want Computed blocked", diff.Subtract)
+	}
+	union, ok := diff.Base.(*model.Union)
+	if !ok || len(union.Children) != 2 {
+		t.Fatalf("Base = %#v, want a two-way union", diff.Base)
+	}
+	d, ok := union.Children[0].(*model.Direct)
+	if !ok || len(d.Types) != 2 || d.Types[1] != (model.TypeRef{Type: "group", Relation: "member"}) {
+		t.Errorf("first child = %#v, want [user, group#member]", union.Children[0])
+	}
+	ttu, ok := union.Children[1].(*model.TupleToUserset)
+	if !ok || ttu.Tupleset != "parent" || ttu.Computed != "viewer" {
+		t.Errorf("second child = %#v, want viewer from parent", union.Children[1])
+	}
+}
+
+func TestParseRewriteErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		col  int
+		msg  string
+	}{
+		{"", 1, "unexpected end of expression"},
+		{"a or b and c", 8, "mixing 'or' and 'and' requires parentheses"},
+		{"a but not b or c", 13, "operators after 'but not' require parentheses"},
+		{"a but b", 7, `expected "not", found "b"`},
+		{"viewer from", 12, "expected tupleset relation after 'from'"},
+		{"viewer from or", 13, "expected tupleset relation after 'from'"},
+		{"[user", 6, `expected ',' or ']', found ""`},
+		{"[]", 2, "expected type name"},
+		{"[user:x]", 7, `expected "*", found "x"`},
+		{"[group#]", 8, "expected relation after '#'"},
+		{"[user with]", 11, "expected condition name"},
+		{"(a or b", 8, `expected ")"`},
+		{"a b", 3, `unexpected "b"`},
+		{"a & b", 3, "unexpected character"},
+	}
+	for _, tt := range tests {
+		_, err := model.ParseRewrite(tt.expr)
+		var se *model.SyntaxError
+		if !errors.As(err, &se) {
+			t.Errorf("ParseRewrite(%q) error = %v, want a *SyntaxError", tt.expr, err)
+			continue
+		}
+		if se.Pos.Col != tt.col || !strings.Contains(se.Msg, tt.msg) {
+			t.Errorf("ParseRewrite(%q) error = col %d %q, want col %d containing %q", tt.expr, se.Pos.Col, se.Msg, tt.col, tt.msg)
+		}
+	}
+}
+
+const docModel = `model
+  schema 1.1
+
+# Users and groups.
+type user
+
+type group
+  relations
+    define member: [user, group#member]
+
+type folder
+  relations
+    define viewer: [user, group#member]
+
+type document
+  relations
+    define parent: [folder]
+    define owner: [user]
+    define blocked: [user]
+    define viewer: [user, user:* with not_expired] or owner or viewer from parent but not blocked # inline comment
+
+condition not_expired(current_time: timestamp, expires_at: timestamp) {
+  current_time < expires_at
+}
+
+condition in_range(
+  ip: ipaddress,
+  cidr: string
+) {
+  ip.in_cidr(cidr)
+}
+`
+
+func TestParse(t *testing.T) {
+	m, err := model.Parse("doc.fga", []byte(docModel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.SchemaVersion != "1.1" {
+		t.Errorf("SchemaVersion = %q, want 1.1", m.SchemaVersion)
+	}
+	if len(m.Types) != 4 {
+		t.Fatalf("got %d types, want 4", len(m.Types))
+	}
+	viewer := m.Relation("document", "viewer")
+	if viewer == nil {
+		t.Fatal("document#viewer not parsed")
+	}
+	if want := "[user, user:* with not_expired] or owner or viewer from parent but not blocked"; viewer.Rewrite.String() != want {
+		t.Errorf("document#viewer = %q, want %q", viewer.Rewrite, want)
+	}
+	if want := (model.Pos{File: "doc.fga", Line: 20, Col: 5}); viewer.Pos != want {
+		t.Errorf("document#viewer at %v, want %v", viewer.Pos, want)
+	}
+	if got := viewer.WildcardTypes(); len(got) != 1 || got[0] != "user" {
+		t.Errorf("WildcardTypes = %v, want [user]", got)
+	}
+	if m.Relation("user", "member") != nil || m.Relation("group", "member") == nil {
+		t.Error("Relation lookup returned the wrong definitions")
+	}
+
+	tests := []struct {
+		name   string
+		params []model.Param
+		expr   string
+		line   int
+	}{
+		{"not_expired", []model.Param{{"current_time", "timestamp"}, {"expires_at", "timestamp"}}, "current_time < expires_at", 22},
+		{"in_range", []model.Param{{"ip", "ipaddress"}, {"cidr", "string"}}, "ip.in_cidr(cidr)", 26},
+	}
+	if len(m.Conditions) != len(tests) {
+		t.Fatalf("got %d conditions, want %d", len(m.Conditions), len(tests))
+	}
+	for _, tt := range tests {
+		c := m.Condition(tt.name)
+		if c == nil {
+			t.Errorf("condition %s not parsed", tt.name)
+			continue
+		}
+		if len(c.Params) != len(tt.params) {
+			t.Errorf("condition %s params = %v, want %v", tt.name, c.Params, tt.params)
+		} else {
+			for i := range c.Params {
+				if c.Params[i] != tt.params[i] {
+					t.Errorf("condition %s param %d = %v, want %v", tt.name, i, c.Params[i], tt.params[i])
+				}
+			}
+		}
+		if c.Expression != tt.expr {
+			t.Errorf("condition %s expression = %q, want %q", tt.name, c.Expression, tt.expr)
+		}
+		if c.Pos.Line != tt.line {
+			t.Errorf("condition %s at line %d, want %d", tt.name, c.Pos.Line, tt.line)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		line int
+		col  int
+		msg  string
+	}{
+		{"schema before model", "schema 1.1\n", 1, 1, "schema must follow model"},
+		{"text after model", "model v2\n", 1, 1, `unexpected "v2" after model`},
+		{"unknown keyword", "model\n  schema 1.1\nentity user\n", 3, 1, `unexpected "entity"`},
+		{"bad type name", "type us#er\n", 1, 1, "invalid type name"},
+		{"relations outside type", "relations\n", 1, 1, "relations outside of a type"},
+		{"define outside relations", "type user\n  define owner: [user]\n", 2, 3, "define outside of a relations block"},
+		{"define without colon", "type doc\n  relations\n    define owner [user]\n", 3, 5, "expected ':' after relation name"},
+		{"bad relation name", "type doc\n  relations\n    define own er: [user]\n", 3, 5, "invalid relation name"},
+		{"expression error column", "type doc\n  relations\n    define viewer: owner or\n", 3, 28, "unexpected end of expression"},
+		{"unterminated condition", "condition c(x: int) {\n  x > 1\n", 1, 1, "unterminated condition body"},
+		{"condition without params", "condition c { true }\n", 1, 1, "expected condition name(params) { expression }"},
+		{"bad condition name", "condition c d(x: int) { x }\n", 1, 1, "invalid condition name"},
+		{"untyped parameter", "condition c(x) { x }\n", 1, 1, `parameter "x" needs a type`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := model.Parse("bad.fga", []byte(tt.src))
+			var se *model.SyntaxError
+			if !errors.As(err, &se) {
+				t.Fatalf("error = %v, want a *SyntaxError", err)
+			}
+			if se.Pos.File != "bad.fga" || se.Pos.Line != tt.line || se.Pos.Col != tt.col || !strings.Contains(se.Msg, tt.msg) {
+				t.Errorf("error = %v, want bad.fga:%d:%d containing %q", se, tt.line, tt.col, tt.msg)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// String renders m in the DSL, keeping types, relations and conditions in
+// their stored order. Parsing the result yields an equivalent model.
+func (m *Model) String() string {
+	var b strings.Builder
+	version := m.SchemaVersion
+	if version == "" {
+		version = "1.1"
+	}
+	fmt.Fprintf(&b, "model\n  schema %s\n", version)
+	for _, t := range m.Types {
+		fmt.Fprintf(&b, "\ntype %s\n", t.Name)
+		if len(t.Relations) == 0 {
+			continue
+		}
+		b.WriteString("  relations\n")
+		for _, r := range t.Relations {
+			fmt.Fprintf(&b, "    define %s: %s\n", r.Name, r.Rewrite)
+		}
+	}
+	for _, c := range m.Conditions {
+		params := make([]string, len(c.Params))
+		for i, p := range c.Params {
+			params[i] = p.Name + ": " + p.Type
+		}
+		fmt.Fprintf(&b, "\ncondition %s(%s) {\n  %s\n}\n", c.Name, strings.Join(params, ", "), strings.TrimSpace(c.Expression))
+	}
+	return b.String()
+}
@@ -0,0 +1,248 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	openfga "github.com/openfga/go-sdk"
+)
+
+// FromSDK converts the API representation of a model, as returned by
+// ReadAuthorizationModel, to a Model. The API stores relations in a map, so
+// relations and conditions come out sorted by name.
+func FromSDK(am *openfga.AuthorizationModel) (*Model, error) {
+	m := &Model{ID: am.Id, SchemaVersion: am.SchemaVersion}
+	for _, td := range am.TypeDefinitions {
+		t := &Type{Name: td.Type}
+		var meta map[string]openfga.RelationMetadata
+		if td.Metadata != nil && td.Metadata.Relations != nil {
+			meta = *td.Metadata.Relations
+		}
+		if td.Relations != nil {
+			names := make([]string, 0, len(*td.Relations))
+			for name := range *td.Relations {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				var refs []TypeRef
+				if md, ok := meta[name]; ok && md.DirectlyRelatedUserTypes != nil {
+					for _, rr := range *md.DirectlyRelatedUserTypes {
+						refs = append(refs, refFromSDK(rr))
+					}
+				}
+				rw, err := rewriteFromSDK((*td.Relations)[name], refs)
+				if err != nil {
+					return nil, fmt.Errorf("model: %s#%s: %w", td.Type, name, err)
+				}
+				t.Relations = append(t.Relations, &Relation{Name: name, Rewrite: rw})
+			}
+		}
+		m.Types = append(m.Types, t)
+	}
+	if am.Conditions != nil {
+		names := make([]string, 0, len(*am.Conditions))
+		for name := range *am.Conditions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			c := (*am.Conditions)[name]
+			cond := &Condition{Name: name, Expression: c.Expression}
+			if c.Parameters != nil {
+				params := make([]string, 0, len(*c.Parameters))
+				for p := range *c.Parameters {
+					params = append(params, p)
+				}
+				sort.Strings(params)
+				for _, p := range params {
+					cond.Params = append(cond.Params, Param{Name: p, Type: paramTypeFromSDK((*c.Parameters)[p])})
+				}
+			}
+			m.Conditions = append(m.Conditions, cond)
+		}
+	}
+	return m, nil
+}
+
+func refFromSDK(rr openfga.RelationReference) TypeRef {
+	ref := TypeRef{Type: rr.Type, Wildcard: rr.Wildcard != nil}
+	if rr.Relation != nil {
+		ref.Relation = *rr.Relation
+	}
+	if rr.Condition != nil {
+		ref.Condition = *rr.Condition
+	}
+	return ref
+}
+
+func rewriteFromSDK(us openfga.Userset, refs []TypeRef) (Rewrite, error) {
+	switch {
+	case us.This != nil:
+		return &Direct{Types: refs}, nil
+	case us.ComputedUserset != nil:
+		return &Computed{Relation: us.ComputedUserset.GetRelation()}, nil
+	case us.TupleToUserset != nil:
+		return &TupleToUserset{
+			Tupleset: us.TupleToUserset.Tupleset.GetRelation(),
+			Computed: us.TupleToUserset.ComputedUserset.GetRelation(),
+		}, nil
+	case us.Union != nil:
+		children, err := childrenFromSDK(us.Union.Child, refs)
+		return &Union{Children: children}, err
+	case us.Intersection != nil:
+		children, err := childrenFromSDK(us.Intersection.Child, refs)
+		return &Intersection{Children: children}, err
+	case us.Difference != nil:
+		base, err := rewriteFromSDK(us.Difference.Base, refs)
+		if err != nil {
+			return nil, err
+		}
+		sub, err := rewriteFromSDK(us.Difference.Subtract, refs)
+		return &Difference{Base: base, Subtract: sub}, err
+	}
+	return nil, fmt.Errorf("empty userset")
+}
+
+func childrenFromSDK(children []openfga.Userset, refs []TypeRef) ([]Rewrite, error) {
+	out := make([]Rewrite, 0, len(children))
+	for _, c := range children {
+		rw, err := rewriteFromSDK(c, refs)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rw)
+	}
+	return out, nil
+}
+
+// paramTypeFromSDK renders TYPE_NAME_MAP with a string generic as
+// "map<string>".
+func paramTypeFromSDK(ref openfga.ConditionParamTypeRef) string {
+	name := strings.ToLower(strings.TrimPrefix(string(ref.TypeName), "TYPE_NAME_"))
+	if ref.GenericTypes == nil || len(*ref.GenericTypes) == 0 {
+		return name
+	}
+	generics := make([]string, len(*ref.GenericTypes))
+	for i, g := range *ref.GenericTypes {
+		generics[i] = paramTypeFromSDK(g)
+	}
+	return name + "<" + strings.Join(generics, ", ") + ">"
+}
+
+// ToSDK converts m to the API representation accepted by
+// WriteAuthorizationModel. Every direct assignment in a relation shares the
+// relation's one list of directly related user types, so a relation with
+// several differently restricted direct assignments comes back from FromSDK
+// with the merged list on each.
+func ToSDK(m *Model) (*openfga.AuthorizationModel, error) {
+	version := m.SchemaVersion
+	if version == "" {
+		version = "1.1"
+	}
+	am := &openfga.AuthorizationModel{Id: m.ID, SchemaVersion: version, TypeDefinitions: []openfga.TypeDefinition{}}
+	for _, t := range m.Types {
+		td := openfga.TypeDefinition{Type: t.Name}
+		if len(t.Relations) > 0 {
+			rels := map[string]openfga.Userset{}
+			meta := map[string]openfga.RelationMetadata{}
+			for _, r := range t.Relations {
+				rels[r.Name] = rewriteToSDK(r.Rewrite)
+				refs := []openfga.RelationReference{}
+				for _, ref := range r.DirectTypes() {
+					refs = append(refs, refToSDK(ref))
+				}
+				meta[r.Name] = openfga.RelationMetadata{DirectlyRelatedUserTypes: &refs}
+			}
+			td.Relations = &rels
+			td.Metadata = &openfga.Metadata{Relations: &meta}
+		}
+		am.TypeDefinitions = append(am.TypeDefinitions, td)
+	}
+	if len(m.Conditions) > 0 {
+		conds := map[string]openfga.Condition{}
+		for _, c := range m.Conditions {
+			params := map[string]openfga.ConditionParamTypeRef{}
+			for _, p := range c.Params {
+				ref, err := paramTypeToSDK(p.Type)
+				if err != nil {
+					return nil, fmt.Errorf("model: condition %s: %w", c.Name, err)
+				}
+				params[p.Name] = ref
+			}
+			conds[c.Name] = openfga.Condition{Name: c.Name, Expression: c.Expression, Parameters: &params}
+		}
+		am.Conditions = &conds
+	}
+	return am, nil
+}
+
+func refToSDK(ref TypeRef) openfga.RelationReference {
+	rr := openfga.RelationReference{Type: ref.Type}
+	if ref.Relation != "" {
+		rel := ref.Relation
+		rr.Relation = &rel
+	}
+	if ref.Wildcard {
+		rr.Wildcard = &map[string]interface{}{}
+	}
+	if ref.Condition != "" {
+		cond := ref.Condition
+		rr.Condition = &cond
+	}
+	return rr
+}
+
+func rewriteToSDK(rw Rewrite) openfga.Userset {
+	str := func(s string) *string { return &s }
+	switch n := rw.(type) {
+	case *Direct:
+		return openfga.Userset{This: &map[string]interface{}{}}
+	case *Computed:
+		return openfga.Userset{ComputedUserset: &openfga.ObjectRelation{Relation: str(n.Relation)}}
+	case *TupleToUserset:
+		return openfga.Userset{TupleToUserset: &openfga.TupleToUserset{
+			Tupleset:        openfga.ObjectRelation{Relation: str(n.Tupleset)},
+			ComputedUserset: openfga.ObjectRelation{Relation: str(n.Computed)},
+		}}
+	case *Union:
+		return openfga.Userset{Union: &openfga.Usersets{Child: childrenToSDK(n.Children)}}
+	case *Intersection:
+		return openfga.Userset{Intersection: &openfga.Usersets{Child: childrenToSDK(n.Children)}}
+	case *Difference:
+		return openfga.Userset{Difference: &openfga.Difference{Base: rewriteToSDK(n.Base), Subtract: rewriteToSDK(n.Subtract)}}
+	}
+	return openfga.Userset{}
+}
+
+func childrenToSDK(children []Rewrite) []openfga.Userset {
+	out := make([]openfga.Userset, len(children))
+	for i, c := range children {
+		out[i] = rewriteToSDK(c)
+	}
+	return out
+}
+
+// paramTypeToSDK parses a parameter type such as "map<string>", the
+// inverse of paramTypeFromSDK.
+func paramTypeToSDK(s string) (openfga.ConditionParamTypeRef, error) {
+	s = strings.TrimSpace(s)
+	name, generic, hasGeneric := strings.Cut(s, "<")
+	tn := openfga.TypeName("TYPE_NAME_" + strings.ToUpper(strings.TrimSpace(name)))
+	if !tn.IsValid() {
+		return openfga.ConditionParamTypeRef{}, fmt.Errorf("unknown parameter type %q", s)
+	}
+	ref := openfga.ConditionParamTypeRef{TypeName: tn}
+	if hasGeneric {
+		if !strings.HasSuffix(generic, ">") {
+			return openfga.ConditionParamTypeRef{}, fmt.Errorf("unterminated parameter type %q", s)
+		}
+		inner, err := paramTypeToSDK(strings.TrimSuffix(generic, ">"))
+		if err != nil {
+			return openfga.ConditionParamTypeRef{}, err
+		}
+		ref.GenericTypes = &[]openfga.ConditionParamTypeRef{inner}
+	}
+	return ref, nil
+}
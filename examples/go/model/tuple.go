@@ -0,0 +1,97 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidTuple is wrapped by every error ValidateTuple returns.
+var ErrInvalidTuple = errors.New("invalid tuple")
+
+// TupleError describes why a tuple cannot be written under the model.
+type TupleError struct {
+	User, Relation, Object string
+	Msg                    string
+}
+
+func (e *TupleError) Error() string {
+	return fmt.Sprintf("%s %s#%s@%s: %s", ErrInvalidTuple, e.Object, e.Relation, e.User, e.Msg)
+}
+
+func (e *TupleError) Unwrap() error { return ErrInvalidTuple }
+
+// ValidateTuple reports whether object#relation@user, bound to the named
+// condition unless condition is empty, could be written: the object's type
+// and relation exist, the relation is directly assignable, and the user's
+// shape matches one of its type restrictions with exactly that condition.
+func (m *Model) ValidateTuple(user, relation, object, condition string) error {
+	fail := func(format string, args ...interface{}) error {
+		return &TupleError{User: user, Relation: relation, Object: object, Msg: fmt.Sprintf(format, args...)}
+	}
+	objType, objID, ok := strings.Cut(object, ":")
+	if !ok || objType == "" || objID == "" {
+		return fail("object must have the form type:id")
+	}
+	if objID == "*" {
+		return fail("object cannot be a wildcard")
+	}
+	t := m.Type(objType)
+	if t == nil {
+		return fail("unknown object type %q", objType)
+	}
+	rel := t.Relation(relation)
+	if rel == nil {
+		return fail("type %s has no relation %q", objType, relation)
+	}
+	refs := rel.DirectTypes()
+	if len(refs) == 0 {
+		return fail("%s#%s is computed and cannot be assigned directly", objType, relation)
+	}
+
+	userObj, userRel, isUserset := strings.Cut(user, "#")
+	userType, userID, ok := strings.Cut(userObj, ":")
+	if !ok || userType == "" || userID == "" || (isUserset && userRel == "") {
+		return fail("user must have the form type:id, type:* or type:id#relation")
+	}
+	wildcard := userID == "*"
+	if wildcard && isUserset {
+		return fail("a wildcard user cannot carry a relation")
+	}
+	if m.Type(userType) == nil {
+		return fail("unknown user type %q", userType)
+	}
+
+	shape := userType
+	switch {
+	case wildcard:
+		shape += ":*"
+	case isUserset:
+		shape += "#" + userRel
+	}
+	var conditional []string
+	matched := false
+	for _, ref := range refs {
+		if ref.Type != userType || ref.Wildcard != wildcard || ref.Relation != userRel {
+			continue
+		}
+		if ref.Condition == condition {
+			return nil
+		}
+		matched = true
+		if ref.Condition != "" {
+			conditional = append(conditional, ref.Condition)
+		}
+	}
+	switch {
+	case matched && condition == "":
+		return fail("requires condition %s", strings.Join(conditional, " or "))
+	case matched:
+		return fail("condition %s is not allowed for user type %s", condition, shape)
+	}
+	allowed := make([]string, len(refs))
+	for i, ref := range refs {
+		allowed[i] = ref.String()
+	}
+	return fail("user type %s is not allowed (allowed: %s)", shape, strings.Join(allowed, ", "))
+}
@@ -0,0 +1,94 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ValidationError is a semantic problem with a model.
+type ValidationError struct {
+	Pos Pos
+	Msg string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Pos.String() + ": " + e.Msg
+}
+
+// Validate checks that every reference in the model resolves: relation
+// names, tupleset relations, type restrictions and conditions. All problems
+// are returned joined.
+func (m *Model) Validate() error {
+	var errs []error
+	fail := func(pos Pos, format string, args ...interface{}) {
+		errs = append(errs, &ValidationError{Pos: pos, Msg: fmt.Sprintf(format, args...)})
+	}
+	if m.SchemaVersion != "" && m.SchemaVersion != "1.1" {
+		fail(Pos{Line: 1, Col: 1}, "unsupported schema version %q", m.SchemaVersion)
+	}
+	seenTypes := map[string]bool{}
+	for _, t := range m.Types {
+		if seenTypes[t.Name] {
+			fail(t.Pos, "duplicate type %s", t.Name)
+		}
+		seenTypes[t.Name] = true
+		seenRels := map[string]bool{}
+		for _, r := range t.Relations {
+			if seenRels[r.Name] {
+				fail(r.Pos, "duplicate relation %s#%s", t.Name, r.Name)
+			}
+			seenRels[r.Name] = true
+		}
+	}
+	seenConds := map[string]bool{}
+	for _, c := range m.Conditions {
+		if seenConds[c.Name] {
+			fail(c.Pos, "duplicate condition %s", c.Name)
+		}
+		seenConds[c.Name] = true
+	}
+
+	for _, t := range m.Types {
+		for _, r := range t.Relations {
+			Walk(r.Rewrite, func(rw Rewrite) {
+				switch n := rw.(type) {
+				case *Direct:
+					for _, ref := range n.Types {
+						switch target := m.Type(ref.Type); {
+						case target == nil:
+							fail(r.Pos, "%s#%s: unknown type %s", t.Name, r.Name, ref.Type)
+						case ref.Relation != "" && target.Relation(ref.Relation) == nil:
+							fail(r.Pos, "%s#%s: unknown relation %s#%s", t.Name, r.Name, ref.Type, ref.Relation)
+						}
+						if ref.Condition != "" && m.Condition(ref.Condition) == nil {
+							fail(r.Pos, "%s#%s: unknown condition %s", t.Name, r.Name, ref.Condition)
+						}
+					}
+				case *Computed:
+					if t.Relation(n.Relation) == nil {
+						fail(r.Pos, "%s#%s: unknown relation %s", t.Name, r.Name, n.Relation)
+					}
+				case *TupleToUserset:
+					ts := t.Relation(n.Tupleset)
+					if ts == nil {
+						fail(r.Pos, "%s#%s: unknown tupleset relation %s", t.Name, r.Name, n.Tupleset)
+						return
+					}
+					found := false
+					for _, ref := range ts.DirectTypes() {
+						if ref.Relation != "" || ref.Wildcard {
+							fail(r.Pos, "%s#%s: tupleset %s may only reference plain types, not %s", t.Name, r.Name, n.Tupleset, ref)
+						}
+						if m.Relation(ref.Type, n.Computed) != nil {
+							found = true
+						}
+					}
+					if !found {
+						fail(r.Pos, "%s#%s: no type related through %s defines %s", t.Name, r.Name, n.Tupleset, n.Computed)
+					}
+				}
+			})
+		}
+	}
+	return errors.Join(errs...)
+}
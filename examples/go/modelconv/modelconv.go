@@ -0,0 +1,249 @@
+// Package modelconv converts authorization models between the DSL and the
+// API's JSON representation, and reports what a conversion cannot carry
+// across: comments and declaration order are DSL-only, module and source
+// metadata are JSON-only.
+package modelconv
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	openfga "github.com/openfga/go-sdk"
+
+	"github.com/bogdanticu88/openfga-examples/model"
+	"github.com/bogdanticu88/openfga-examples/modelfmt"
+)
+
+// Loss is something a conversion dropped or changed. Line is set for DSL
+// sources; Path, a JSON path such as type_definitions[2].metadata.module,
+// for JSON ones.
+type Loss struct {
+	Line int
+	Path string
+	What string
+}
+
+func (l Loss) String() string {
+	switch {
+	case l.Line > 0:
+		return fmt.Sprintf("line %d: %s", l.Line, l.What)
+	case l.Path != "":
+		return l.Path + ": " + l.What
+	}
+	return l.What
+}
+
+// ConvertDSLToJSON converts DSL source to indented API JSON, as accepted by
+// WriteAuthorizationModel. file is used only in error positions.
+func ConvertDSLToJSON(file string, src []byte) ([]byte, []Loss, error) {
+	m, err := model.Parse(file, src)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := m.Validate(); err != nil {
+		return nil, nil, err
+	}
+	am, err := model.ToSDK(m)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := marshal(am)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, dslLosses(m, src), nil
+}
+
+func dslLosses(m *model.Model, src []byte) []Loss {
+	var losses []Loss
+	for i, line := range strings.Split(string(src), "\n") {
+		if _, comment := splitComment(line); comment != "" {
+			losses = append(losses, Loss{Line: i + 1, What: "comment dropped"})
+		}
+	}
+	for _, t := range m.Types {
+		if !sort.SliceIsSorted(t.Relations, func(i, j int) bool { return t.Relations[i].Name < t.Relations[j].Name }) {
+			losses = append(losses, Loss{Line: t.Pos.Line, What: fmt.Sprintf("relations of type %s will read back sorted by name", t.Name)})
+		}
+		for _, r := range t.Relations {
+			if directs(r.Rewrite) > 1 {
+				losses = append(losses, Loss{Line: r.Pos.Line, What: fmt.Sprintf("%s#%s: type restrictions of its direct assignments are merged into one list", t.Name, r.Name)})
+			}
+		}
+	}
+	if !sort.SliceIsSorted(m.Conditions, func(i, j int) bool { return m.Conditions[i].Name < m.Conditions[j].Name }) {
+		losses = append(losses, Loss{What: "conditions will read back sorted by name"})
+	}
+	for _, c := range m.Conditions {
+		if !sort.SliceIsSorted(c.Params, func(i, j int) bool { return c.Params[i].Name < c.Params[j].Name }) {
+			losses = append(losses, Loss{Line: c.Pos.Line, What: fmt.Sprintf("parameters of condition %s will read back sorted by name", c.Name)})
+		}
+	}
+	return losses
+}
+
+func directs(rw model.Rewrite) int {
+	n := 0
+	model.Walk(rw, func(rw model.Rewrite) {
+		if _, ok := rw.(*model.Direct); ok {
+			n++
+		}
+	})
+	return n
+}
+
+func splitComment(line string) (code, comment string) {
+	for i := 0; i < len(line); i++ {
+		if line[i] == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t') {
+			return line[:i], strings.TrimSpace(line[i:])
+		}
+	}
+	return line, ""
+}
+
+// ConvertJSONToDSL converts API JSON, either a bare model or a
+// ReadAuthorizationModel response wrapping one, to canonically formatted
+// DSL. Losses are found by converting the result back and comparing it with
+// the input.
+func ConvertJSONToDSL(src []byte) ([]byte, []Loss, error) {
+	raw, err := unwrap(src)
+	if err != nil {
+		return nil, nil, err
+	}
+	var am openfga.AuthorizationModel
+	if err := json.Unmarshal(raw, &am); err != nil {
+		return nil, nil, fmt.Errorf("modelconv: %w", err)
+	}
+	m, err := model.FromSDK(&am)
+	if err != nil {
+		return nil, nil, err
+	}
+	dsl := m.String()
+	out, err := modelfmt.Format("", []byte(dsl), modelfmt.Options{KeepOrder: true})
+	if err != nil {
+		return nil, nil, fmt.Errorf("modelconv: generated DSL does not parse: %w", err)
+	}
+
+	back, err := model.Parse("", out)
+	if err != nil {
+		return nil, nil, err
+	}
+	backSDK, err := model.ToSDK(back)
+	if err != nil {
+		return nil, nil, err
+	}
+	backJSON, err := marshal(backSDK)
+	if err != nil {
+		return nil, nil, err
+	}
+	var before, after interface{}
+	json.Unmarshal(raw, &before)
+	json.Unmarshal(backJSON, &after)
+	var losses []Loss
+	if am.Id != "" {
+		losses = append(losses, Loss{Path: "id", What: "model ID dropped"})
+	}
+	if b, ok := before.(map[string]interface{}); ok {
+		delete(b, "id")
+	}
+	diff("", normalize(before), normalize(after), &losses)
+	return out, losses, nil
+}
+
+// unwrap returns the model inside {"authorization_model": ...}, or src.
+func unwrap(src []byte) ([]byte, error) {
+	var env map[string]json.RawMessage
+	if err := json.Unmarshal(src, &env); err != nil {
+		return nil, fmt.Errorf("modelconv: %w", err)
+	}
+	if inner, ok := env["authorization_model"]; ok {
+		return inner, nil
+	}
+	return src, nil
+}
+
+func marshal(am *openfga.AuthorizationModel) ([]byte, error) {
+	data, err := json.Marshal(am)
+	if err != nil {
+		return nil, fmt.Errorf("modelconv: %w", err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("modelconv: %w", err)
+	}
+	if doc["id"] == "" {
+		delete(doc, "id")
+	}
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("modelconv: %w", err)
+	}
+	return append(out, '\n'), nil
+}
+
+// normalize drops nulls and empty objects and arrays, which the API uses
+// interchangeably with absent fields.
+func normalize(v interface{}) interface{} {
+	switch n := v.(type) {
+	case map[string]interface{}:
+		out := map[string]interface{}{}
+		for k, c := range n {
+			if c = normalize(c); c != nil {
+				out[k] = c
+			}
+		}
+		if len(out) == 0 {
+			return nil
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, 0, len(n))
+		for _, c := range n {
+			out = append(out, normalize(c))
+		}
+		if len(out) == 0 {
+			return nil
+		}
+		return out
+	}
+	return v
+}
+
+func diff(path string, before, after interface{}, losses *[]Loss) {
+	switch b := before.(type) {
+	case map[string]interface{}:
+		a, _ := after.(map[string]interface{})
+		keys := make([]string, 0, len(b))
+		for k := range b {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			if _, ok := a[k]; !ok {
+				*losses = append(*losses, Loss{Path: p, What: "dropped"})
+				continue
+			}
+			diff(p, b[k], a[k], losses)
+		}
+	case []interface{}:
+		a, _ := after.([]interface{})
+		if len(a) != len(b) {
+			*losses = append(*losses, Loss{Path: path, What: fmt.Sprintf("%d entries became %d", len(b), len(a))})
+			return
+		}
+		for i := range b {
+			diff(fmt.Sprintf("%s[%d]", path, i), b[i], a[i], losses)
+		}
+	default:
+		if !reflect.DeepEqual(before, after) {
+			*losses = append(*losses, Loss{Path: path, What: fmt.Sprintf("%v became %v", before, after)})
+		}
+	}
+}
@@ -0,0 +1,142 @@
+// Package modeldiff compares two versions of a model by behavior: it runs
+// the same checks against the same tuples under both and reports every
+// answer that changes. An empty report means the refactor is invisible to
+// that corpus.
+package modeldiff
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/eval"
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// Change is a query whose outcome differs between the two models. An error
+// under either model, such as a relation the other version does not define,
+// counts as an outcome.
+type Change struct {
+	Query     authz.CheckRequest
+	Before    bool
+	After     bool
+	BeforeErr error
+	AfterErr  error
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s#%s@%s: %s → %s", c.Query.Object, c.Query.Relation, c.Query.User,
+		outcome(c.Before, c.BeforeErr), outcome(c.After, c.AfterErr))
+}
+
+func outcome(allowed bool, err error) string {
+	switch {
+	case err != nil:
+		return "error (" + err.Error() + ")"
+	case allowed:
+		return "allowed"
+	}
+	return "denied"
+}
+
+// Report is the result of Behavioral.
+type Report struct {
+	Queries int
+	Changes []Change
+}
+
+// Granted returns the changes that newly allow access.
+func (r *Report) Granted() []Change {
+	return r.filter(func(c Change) bool { return c.AfterErr == nil && c.After && (c.BeforeErr != nil || !c.Before) })
+}
+
+// Revoked returns the changes that newly deny access.
+func (r *Report) Revoked() []Change {
+	return r.filter(func(c Change) bool { return c.BeforeErr == nil && c.Before && (c.AfterErr != nil || !c.After) })
+}
+
+func (r *Report) filter(keep func(Change) bool) []Change {
+	var out []Change
+	for _, c := range r.Changes {
+		if keep(c) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// WriteText writes one line per change and a summary.
+func (r *Report) WriteText(w io.Writer) error {
+	for _, c := range r.Changes {
+		if _, err := fmt.Fprintln(w, c); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d of %d queries changed (%d granted, %d revoked)\n",
+		len(r.Changes), r.Queries, len(r.Granted()), len(r.Revoked()))
+	return err
+}
+
+// Behavioral evaluates queries against tuples under modelA and modelB and
+// reports the queries whose outcome differs, in query order. Tuples are
+// used as given under both models, even those one of them would reject on
+// write, since that is the data a deployed store would hold.
+func Behavioral(ctx context.Context, modelA, modelB *model.Model, tuples []authz.Tuple, queries []authz.CheckRequest) (*Report, error) {
+	store := eval.NewTupleStore(tuples...)
+	before, after := eval.New(modelA, store), eval.New(modelB, store)
+	rep := &Report{Queries: len(queries)}
+	for _, q := range queries {
+		if err := ctx.Err(); err != nil {
+			return rep, err
+		}
+		c := Change{Query: q}
+		c.Before, c.BeforeErr = before.Check(ctx, q)
+		c.After, c.AfterErr = after.Check(ctx, q)
+		if c.Before != c.After || (c.BeforeErr == nil) != (c.AfterErr == nil) {
+			rep.Changes = append(rep.Changes, c)
+		}
+	}
+	return rep, nil
+}
+
+// Queries derives a corpus from tuples: every concrete user they mention
+// against every relation both models define on every object they mention,
+// sorted by object, relation and user.
+func Queries(modelA, modelB *model.Model, tuples []authz.Tuple) []authz.CheckRequest {
+	users, objects := map[string]bool{}, map[string]bool{}
+	for _, t := range tuples {
+		objects[t.Object] = true
+		if !strings.Contains(t.User, "#") && !strings.HasSuffix(t.User, ":*") {
+			users[t.User] = true
+		}
+	}
+	var out []authz.CheckRequest
+	for _, obj := range sorted(objects) {
+		typ, _, _ := strings.Cut(obj, ":")
+		ta, tb := modelA.Type(typ), modelB.Type(typ)
+		if ta == nil || tb == nil {
+			continue
+		}
+		for _, r := range tb.Relations {
+			if ta.Relation(r.Name) == nil {
+				continue
+			}
+			for _, u := range sorted(users) {
+				out = append(out, authz.CheckRequest{User: u, Relation: r.Name, Object: obj})
+			}
+		}
+	}
+	return out
+}
+
+func sorted(set map[string]bool) []string {
+	out := make([]string, 0, len(set))
+	for s := range set {
+		out = append(out, s)
+	}
+	sort.Strings(out)
+	return out
+}
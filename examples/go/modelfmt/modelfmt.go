@@ -0,0 +1,196 @@
+// Package modelfmt prints authorization models in a canonical style, so
+// that formatting never shows up in review diffs: types, relations and
+// conditions sorted by name, two-space indentation, single spaces around
+// operators and one blank line between blocks. Comments are kept with the
+// line they precede or end.
+package modelfmt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// Options tunes Format.
+type Options struct {
+	// KeepOrder leaves types, relations and conditions in source order and
+	// only normalizes spacing.
+	KeepOrder bool
+}
+
+// Format parses DSL source and returns it in canonical form. file is used
+// only in error positions.
+func Format(file string, src []byte, opts Options) ([]byte, error) {
+	m, err := model.Parse(file, src)
+	if err != nil {
+		return nil, err
+	}
+	if !opts.KeepOrder {
+		m = Canonical(m)
+	}
+	return print(m, scanComments(m, src)), nil
+}
+
+// Canonical returns a copy of m with its types, relations and conditions
+// sorted by name. Rewrites are shared with m.
+func Canonical(m *model.Model) *model.Model {
+	out := *m
+	out.Types = make([]*model.Type, len(m.Types))
+	for i, t := range m.Types {
+		ct := *t
+		ct.Relations = append([]*model.Relation(nil), t.Relations...)
+		sort.SliceStable(ct.Relations, func(a, b int) bool { return ct.Relations[a].Name < ct.Relations[b].Name })
+		out.Types[i] = &ct
+	}
+	sort.SliceStable(out.Types, func(a, b int) bool { return out.Types[a].Name < out.Types[b].Name })
+	out.Conditions = append([]*model.Condition(nil), m.Conditions...)
+	sort.SliceStable(out.Conditions, func(a, b int) bool { return out.Conditions[a].Name < out.Conditions[b].Name })
+	return &out
+}
+
+// comments holds the comments of a source file by the line of the element
+// they belong to. Line 0 is the model header; -1 is the end of the file.
+type comments struct {
+	leading  map[int][]string
+	trailing map[int]string
+}
+
+const endOfFile = -1
+
+func scanComments(m *model.Model, src []byte) comments {
+	elements := map[int]bool{}
+	for _, t := range m.Types {
+		elements[t.Pos.Line] = true
+		for _, r := range t.Relations {
+			elements[r.Pos.Line] = true
+		}
+	}
+	conditions := map[int]bool{}
+	for _, c := range m.Conditions {
+		elements[c.Pos.Line] = true
+		conditions[c.Pos.Line] = true
+	}
+	cs := comments{leading: map[int][]string{}, trailing: map[int]string{}}
+	var pending []string
+	sawHeader := false
+	depth := 0
+	lines := strings.Split(strings.ReplaceAll(string(src), "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		n := i + 1
+		if conditions[n] || depth > 0 {
+			// Condition bodies are kept verbatim, comments included.
+			depth += strings.Count(line, "{") - strings.Count(line, "}")
+			if conditions[n] {
+				cs.leading[n] = pending
+				pending = nil
+			}
+			continue
+		}
+		code, comment := splitComment(line)
+		code = strings.TrimSpace(code)
+		switch {
+		case code == "" && comment != "":
+			pending = append(pending, comment)
+		case code == "":
+		case elements[n]:
+			cs.leading[n] = pending
+			pending = nil
+			if comment != "" {
+				cs.trailing[n] = comment
+			}
+		case !sawHeader && code == "model":
+			sawHeader = true
+			cs.leading[0] = pending
+			pending = nil
+		}
+	}
+	cs.leading[endOfFile] = pending
+	return cs
+}
+
+// splitComment separates a trailing comment, using the parser's rule that
+// '#' starts a comment at the start of a line or after whitespace.
+func splitComment(line string) (code, comment string) {
+	for i := 0; i < len(line); i++ {
+		if line[i] == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t') {
+			return line[:i], strings.TrimSpace(line[i:])
+		}
+	}
+	return line, ""
+}
+
+func print(m *model.Model, cs comments) []byte {
+	var b bytes.Buffer
+	lead := func(line int, indent string) {
+		for _, c := range cs.leading[line] {
+			b.WriteString(indent + c + "\n")
+		}
+	}
+	trail := func(line int) string {
+		if c := cs.trailing[line]; c != "" {
+			return " " + c
+		}
+		return ""
+	}
+	lead(0, "")
+	version := m.SchemaVersion
+	if version == "" {
+		version = "1.1"
+	}
+	fmt.Fprintf(&b, "model\n  schema %s\n", version)
+	for _, t := range m.Types {
+		b.WriteString("\n")
+		lead(t.Pos.Line, "")
+		fmt.Fprintf(&b, "type %s%s\n", t.Name, trail(t.Pos.Line))
+		if len(t.Relations) == 0 {
+			continue
+		}
+		b.WriteString("  relations\n")
+		for _, r := range t.Relations {
+			lead(r.Pos.Line, "    ")
+			fmt.Fprintf(&b, "    define %s: %s%s\n", r.Name, r.Rewrite, trail(r.Pos.Line))
+		}
+	}
+	for _, c := range m.Conditions {
+		b.WriteString("\n")
+		lead(c.Pos.Line, "")
+		params := make([]string, len(c.Params))
+		for i, p := range c.Params {
+			params[i] = p.Name + ": " + p.Type
+		}
+		fmt.Fprintf(&b, "condition %s(%s) {\n  %s\n}\n", c.Name, strings.Join(params, ", "), strings.TrimSpace(c.Expression))
+	}
+	if end := cs.leading[endOfFile]; len(end) > 0 {
+		b.WriteString("\n")
+		lead(endOfFile, "")
+	}
+	return b.Bytes()
+}
+
+// JSON canonicalizes a model in the API's JSON representation: object keys
+// sorted, type definitions sorted by type, two-space indentation. It does
+// not depend on the DSL and keeps fields it does not know.
+func JSON(src []byte) ([]byte, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(src, &doc); err != nil {
+		return nil, fmt.Errorf("modelfmt: %w", err)
+	}
+	if defs, ok := doc["type_definitions"].([]interface{}); ok {
+		sort.SliceStable(defs, func(i, j int) bool { return typeName(defs[i]) < typeName(defs[j]) })
+	}
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("modelfmt: %w", err)
+	}
+	return append(out, '\n'), nil
+}
+
+func typeName(def interface{}) string {
+	m, _ := def.(map[string]interface{})
+	name, _ := m["type"].(string)
+	return name
+}
@@ -0,0 +1,267 @@
+// Package modelschema describes a model's authorization vocabulary — its
+// object types and relations — as a protobuf file or a JSON Schema, so other
+// services can validate permission-related payloads without importing the
+// model itself, or as Go constants, so code in this repository's language
+// refers to relations by name instead of by string.
+//
+//	proto, _ := modelschema.Protobuf(m, "acme.authz.v1")
+//	schema, _ := modelschema.JSONSchema(m, "https://acme.example/authz.schema.json")
+//	src, _ := modelschema.Go(m, "authzmodel")
+package modelschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"go/token"
+	"regexp"
+	"strings"
+
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// JSONSchemaDraft is the $schema written by JSONSchema.
+const JSONSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+var protoPackage = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+// Protobuf returns a proto3 file declaring, in package pkg, an ObjectType
+// enum, one <Type>Relation enum per type with relations, and Object and
+// Relationship messages that use them.
+//
+// Enum numbers follow the order of m. Appending types and relations keeps
+// existing numbers; reordering or removing them in the model is a breaking
+// wire change, as it would be for a hand-written enum.
+func Protobuf(m *model.Model, pkg string) ([]byte, error) {
+	if !protoPackage.MatchString(pkg) {
+		return nil, fmt.Errorf("modelschema: invalid protobuf package %q", pkg)
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by modelschema from authorization model schema %s. DO NOT EDIT.\n\n", m.SchemaVersion)
+	fmt.Fprintf(&b, "syntax = \"proto3\";\n\npackage %s;\n\n", pkg)
+
+	fmt.Fprintln(&b, "// ObjectType is a type of the authorization model.")
+	fmt.Fprintln(&b, "enum ObjectType {")
+	fmt.Fprintln(&b, "  OBJECT_TYPE_UNSPECIFIED = 0;")
+	for i, t := range m.Types {
+		fmt.Fprintf(&b, "  OBJECT_TYPE_%s = %d; // %s\n", upper(t.Name), i+1, t.Name)
+	}
+	fmt.Fprintln(&b, "}")
+
+	for _, t := range m.Types {
+		if len(t.Relations) == 0 {
+			continue
+		}
+		name, prefix := camel(t.Name)+"Relation", upper(t.Name)+"_RELATION_"
+		fmt.Fprintf(&b, "\n// %s is a relation defined on %s.\n", name, t.Name)
+		fmt.Fprintf(&b, "enum %s {\n", name)
+		fmt.Fprintf(&b, "  %sUNSPECIFIED = 0;\n", prefix)
+		for i, r := range t.Relations {
+			fmt.Fprintf(&b, "  %s%s = %d; // %s\n", prefix, upper(r.Name), i+1, r.Name)
+		}
+		fmt.Fprintln(&b, "}")
+	}
+
+	b.WriteString(`
+// Object identifies an object, rendered as "<type>:<id>".
+message Object {
+  ObjectType type = 1;
+  string id = 2;
+}
+
+// Relationship is a tuple: user has relation on object. user_relation is set
+// for usersets ("group:eng#member"); relation names are validated against
+// the <Type>Relation enum of the object's type.
+message Relationship {
+  Object user = 1;
+  string user_relation = 2;
+  string relation = 3;
+  Object object = 4;
+}
+`)
+	return b.Bytes(), nil
+}
+
+// JSONSchema returns a JSON Schema for tuples, {"user", "relation",
+// "object"} objects with an optional "condition", that are valid for m: the
+// object's type defines the relation, the relation is directly assignable
+// and the user matches one of its type restrictions. $defs also holds an
+// object_type enum and a <type>_relation enum per type for reuse by other
+// schemas. id becomes $id when not empty.
+func JSONSchema(m *model.Model, id string) ([]byte, error) {
+	defs := map[string]interface{}{}
+	var typeNames []string
+	var tuples []interface{}
+	for _, t := range m.Types {
+		typeNames = append(typeNames, t.Name)
+		if reservedDef(t.Name) || defs[t.Name] != nil {
+			return nil, fmt.Errorf("modelschema: type %s collides with a generated $defs entry", t.Name)
+		}
+		defs[t.Name] = map[string]interface{}{
+			"type":    "string",
+			"pattern": "^" + regexp.QuoteMeta(t.Name) + ":" + idPattern + "$",
+		}
+		if len(t.Relations) == 0 {
+			continue
+		}
+		var rels []string
+		for _, r := range t.Relations {
+			rels = append(rels, r.Name)
+			if r.Assignable() {
+				tuples = append(tuples, tupleSchema(t, r))
+			}
+		}
+		defs[t.Name+"_relation"] = map[string]interface{}{"enum": rels}
+	}
+	defs["object_type"] = map[string]interface{}{"enum": typeNames}
+	defs["tuple"] = map[string]interface{}{"oneOf": tuples}
+
+	doc := map[string]interface{}{
+		"$schema": JSONSchemaDraft,
+		"title":   "Relationship tuple",
+		"$ref":    "#/$defs/tuple",
+		"$defs":   defs,
+	}
+	if id != "" {
+		doc["$id"] = id
+	}
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("modelschema: %w", err)
+	}
+	return append(out, '\n'), nil
+}
+
+// Go returns the source of a Go package named pkg declaring a string
+// constant per type, Type<Type>, and per relation, <Type><Relation>:
+// TypeProject = "project", ProjectViewer = "viewer".
+func Go(m *model.Model, pkg string) ([]byte, error) {
+	if !token.IsIdentifier(pkg) || token.IsKeyword(pkg) {
+		return nil, fmt.Errorf("modelschema: invalid Go package %q", pkg)
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by modelschema from authorization model schema %s. DO NOT EDIT.\n\n", m.SchemaVersion)
+	fmt.Fprintf(&b, "// Package %s names the types and relations of the authorization model.\n", pkg)
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+
+	declared := map[string]string{}
+	declare := func(ident, what string) error {
+		if prev, ok := declared[ident]; ok {
+			return fmt.Errorf("modelschema: %s and %s both become %s", prev, what, ident)
+		}
+		declared[ident] = what
+		return nil
+	}
+	fmt.Fprintln(&b, "// Object types.")
+	fmt.Fprintln(&b, "const (")
+	for _, t := range m.Types {
+		ident := "Type" + camel(t.Name)
+		if err := declare(ident, "type "+t.Name); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&b, "%s = %q\n", ident, t.Name)
+	}
+	fmt.Fprintln(&b, ")")
+	for _, t := range m.Types {
+		if len(t.Relations) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n// Relations of %s.\n", t.Name)
+		fmt.Fprintln(&b, "const (")
+		for _, r := range t.Relations {
+			ident := camel(t.Name) + camel(r.Name)
+			if err := declare(ident, t.Name+"#"+r.Name); err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(&b, "%s = %q\n", ident, r.Name)
+		}
+		fmt.Fprintln(&b, ")")
+	}
+	out, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("modelschema: %w", err)
+	}
+	return out, nil
+}
+
+// reservedDef reports whether name is one of the $defs keys JSONSchema
+// writes besides the type definitions.
+func reservedDef(name string) bool {
+	return name == "tuple" || name == "object_type" || strings.HasSuffix(name, "_relation")
+}
+
+// idPattern matches an object ID: no whitespace or '#', and not the
+// wildcard.
+const idPattern = `[^\s#*][^\s#]*`
+
+func tupleSchema(t *model.Type, r *model.Relation) map[string]interface{} {
+	var users []interface{}
+	var conditions []string
+	seen := map[string]bool{}
+	for _, ref := range r.DirectTypes() {
+		if ref.Condition != "" && !seen[ref.Condition] {
+			seen[ref.Condition] = true
+			conditions = append(conditions, ref.Condition)
+		}
+		key := ref.Type + "#" + ref.Relation
+		if ref.Wildcard {
+			key = ref.Type + ":*"
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		switch {
+		case ref.Wildcard:
+			users = append(users, map[string]interface{}{"const": ref.Type + ":*"})
+		case ref.Relation != "":
+			users = append(users, map[string]interface{}{
+				"type":    "string",
+				"pattern": "^" + regexp.QuoteMeta(ref.Type) + ":" + idPattern + "#" + regexp.QuoteMeta(ref.Relation) + "$",
+			})
+		default:
+			users = append(users, map[string]interface{}{"$ref": "#/$defs/" + ref.Type})
+		}
+	}
+	props := map[string]interface{}{
+		"user":     map[string]interface{}{"anyOf": users},
+		"relation": map[string]interface{}{"const": r.Name},
+		"object":   map[string]interface{}{"$ref": "#/$defs/" + t.Name},
+	}
+	if len(conditions) > 0 {
+		props["condition"] = map[string]interface{}{
+			"type":     "object",
+			"required": []string{"name"},
+			"properties": map[string]interface{}{
+				"name":    map[string]interface{}{"enum": conditions},
+				"context": map[string]interface{}{"type": "object"},
+			},
+		}
+	}
+	return map[string]interface{}{
+		"title":                t.Name + "#" + r.Name,
+		"type":                 "object",
+		"required":             []string{"user", "relation", "object"},
+		"properties":           props,
+		"additionalProperties": false,
+	}
+}
+
+// upper turns a type or relation name into an enum value suffix.
+func upper(name string) string {
+	return strings.ToUpper(nonIdent.ReplaceAllString(name, "_"))
+}
+
+// camel turns a type name into a message or enum name: audit_log → AuditLog.
+func camel(name string) string {
+	var b strings.Builder
+	for _, part := range nonIdent.Split(name, -1) {
+		if part != "" {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return b.String()
+}
+
+var nonIdent = regexp.MustCompile(`[^A-Za-z0-9]+`)
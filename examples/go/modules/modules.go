@@ -0,0 +1,328 @@
+// Package modules assembles an authorization model split across fragment
+// files, so that teams can own the part of a large model they maintain. A
+// manifest, fga.mod, lists the fragments in the layout OpenFGA's modular
+// models use:
+//
+//	schema: '1.2'
+//	contents:
+//	  - core.fga
+//	  - tracker/projects.fga
+//
+// Each fragment starts with "module <name>" instead of the model header
+// and holds types, conditions and "extend type" blocks that add relations
+// to a type defined in another fragment:
+//
+//	module tracker
+//
+//	extend type organization
+//	  relations
+//	    define can_create_project: admin
+//
+//	type project
+//	  relations
+//	    define organization: [organization]
+//
+// Assemble merges the fragments into one schema 1.1 model and reports
+// every conflict — a type or condition defined twice, a relation defined
+// by two fragments, an extension of an undefined type — with both
+// positions. Positions in the assembled model point into the fragments.
+//
+// Fragments may define and use macros (see package macro); a macro defined
+// in one fragment can be used in any other.
+package modules
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bogdanticu88/openfga-examples/macro"
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// ManifestFile is the conventional manifest name.
+const ManifestFile = "fga.mod"
+
+// ManifestSchema is the manifest schema version Read accepts.
+const ManifestSchema = "1.2"
+
+// Manifest lists the fragments of a modular model.
+type Manifest struct {
+	// Path is the manifest file; fragments are relative to its directory.
+	Path     string
+	Schema   string
+	Contents []string
+}
+
+// ReadManifest reads and parses the manifest at path. Only the schema and
+// contents keys are understood; others are rejected so typos surface.
+func ReadManifest(path string) (*Manifest, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("modules: %w", err)
+	}
+	mf := &Manifest{Path: path}
+	inContents := false
+	for i, line := range strings.Split(string(src), "\n") {
+		if j := strings.Index(line, "#"); j >= 0 {
+			line = line[:j]
+		}
+		text := strings.TrimSpace(line)
+		pos := model.Pos{File: path, Line: i + 1, Col: len(line) - len(strings.TrimLeft(line, " \t")) + 1}
+		switch {
+		case text == "":
+		case strings.HasPrefix(text, "- "):
+			if !inContents {
+				return nil, &model.SyntaxError{Pos: pos, Msg: "list item outside contents"}
+			}
+			mf.Contents = append(mf.Contents, unquote(strings.TrimSpace(text[2:])))
+		default:
+			key, value, ok := strings.Cut(text, ":")
+			if !ok || pos.Col != 1 {
+				return nil, &model.SyntaxError{Pos: pos, Msg: fmt.Sprintf("unexpected %q", text)}
+			}
+			inContents = false
+			switch key {
+			case "schema":
+				mf.Schema = unquote(strings.TrimSpace(value))
+			case "contents":
+				inContents = true
+			default:
+				return nil, &model.SyntaxError{Pos: pos, Msg: fmt.Sprintf("unknown key %q", key)}
+			}
+		}
+	}
+	if mf.Schema != ManifestSchema {
+		return nil, fmt.Errorf("modules: %s: schema %q, want %q", path, mf.Schema, ManifestSchema)
+	}
+	if len(mf.Contents) == 0 {
+		return nil, fmt.Errorf("modules: %s: no contents", path)
+	}
+	return mf, nil
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// Fragments parses every fragment the manifest lists, in order.
+func (mf *Manifest) Fragments() ([]*Fragment, error) {
+	dir := filepath.Dir(mf.Path)
+	out := make([]*Fragment, 0, len(mf.Contents))
+	for _, name := range mf.Contents {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("modules: %w", err)
+		}
+		f, err := ParseFragment(path, src)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, f)
+	}
+	return out, nil
+}
+
+// Fragment is one parsed module file.
+type Fragment struct {
+	// Module is the name from the "module" line.
+	Module string
+	File   string
+	// Types are the types the fragment defines.
+	Types []*model.Type
+	// Extensions add their relations to types defined elsewhere.
+	Extensions []*model.Type
+	Conditions []*model.Condition
+	// Macros and Uses are expanded by Assemble, once the macros of every
+	// fragment are known.
+	Macros []*macro.Macro
+	Uses   []*macro.Use
+}
+
+// ParseFragment parses a module file. file is used only in positions.
+func ParseFragment(file string, src []byte) (*Fragment, error) {
+	// Rewrite the module syntax into plain DSL, keeping line and column
+	// numbers, and let the model parser do the rest.
+	lines := strings.Split(strings.ReplaceAll(string(src), "\r\n", "\n"), "\n")
+	f := &Fragment{File: file}
+	extended := map[int]bool{}
+	for i, line := range lines {
+		text := strings.TrimSpace(line)
+		if j := strings.Index(text, "#"); j >= 0 {
+			text = strings.TrimSpace(text[:j])
+		}
+		col := len(line) - len(strings.TrimLeft(line, " \t")) + 1
+		switch word, rest, _ := strings.Cut(text, " "); word {
+		case "module":
+			if f.Module != "" {
+				return nil, &model.SyntaxError{Pos: model.Pos{File: file, Line: i + 1, Col: col}, Msg: "second module line"}
+			}
+			f.Module = strings.TrimSpace(rest)
+			if f.Module == "" {
+				return nil, &model.SyntaxError{Pos: model.Pos{File: file, Line: i + 1, Col: col}, Msg: "module needs a name"}
+			}
+			lines[i] = ""
+		case "model", "schema":
+			return nil, &model.SyntaxError{Pos: model.Pos{File: file, Line: i + 1, Col: col}, Msg: "fragments start with module, not a model header"}
+		case "extend":
+			if !strings.HasPrefix(strings.TrimSpace(rest), "type ") {
+				return nil, &model.SyntaxError{Pos: model.Pos{File: file, Line: i + 1, Col: col}, Msg: "expected extend type"}
+			}
+			// "extend " becomes spaces so the type keeps its column.
+			at := strings.Index(line, "extend")
+			lines[i] = line[:at] + strings.Repeat(" ", len("extend ")) + strings.TrimLeft(line[at+len("extend"):], " ")
+			extended[i+1] = true
+		}
+	}
+	if f.Module == "" {
+		return nil, &model.SyntaxError{Pos: model.Pos{File: file, Line: 1, Col: 1}, Msg: "missing module line"}
+	}
+	dsl, macros, uses, err := macro.Split(file, []byte(strings.Join(lines, "\n")))
+	if err != nil {
+		return nil, err
+	}
+	f.Macros, f.Uses = macros, uses
+	m, err := model.Parse(file, dsl)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range m.Types {
+		if extended[t.Pos.Line] {
+			f.Extensions = append(f.Extensions, t)
+		} else {
+			f.Types = append(f.Types, t)
+		}
+	}
+	f.Conditions = m.Conditions
+	return f, nil
+}
+
+// Conflict is a definition that clashes with another one, or an extension
+// of a type no fragment defines; Other is then the zero Pos.
+type Conflict struct {
+	Pos   model.Pos
+	Other model.Pos
+	Msg   string
+}
+
+func (c *Conflict) Error() string {
+	if c.Other == (model.Pos{}) {
+		return c.Pos.String() + ": " + c.Msg
+	}
+	return fmt.Sprintf("%s: %s (first defined at %s)", c.Pos, c.Msg, c.Other)
+}
+
+// Assemble merges fragments into one model: types in the order they are
+// defined, each followed by the relations extensions add to it in fragment
+// order. Every conflict is reported, joined into one error; the model is
+// only returned without conflicts. Call Validate on the result for
+// references across fragments.
+func Assemble(fragments []*Fragment) (*model.Model, error) {
+	m := &model.Model{SchemaVersion: "1.1"}
+	var errs []error
+	macros := map[string]*macro.Macro{}
+	for _, f := range fragments {
+		for _, mc := range f.Macros {
+			if prev, ok := macros[mc.Name]; ok {
+				errs = append(errs, &Conflict{Pos: mc.Pos, Other: prev.Pos, Msg: fmt.Sprintf("macro %s defined again", mc.Name)})
+				continue
+			}
+			macros[mc.Name] = mc
+		}
+	}
+	own := make([][]*model.Type, len(fragments))
+	ext := make([][]*model.Type, len(fragments))
+	for i, f := range fragments {
+		// Expand into copies so the fragments can be assembled again.
+		own[i], ext[i] = copyTypes(f.Types), copyTypes(f.Extensions)
+		if err := macro.Expand(append(append([]*model.Type(nil), own[i]...), ext[i]...), macros, f.Uses); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	types := map[string]*model.Type{}
+	for i := range fragments {
+		for _, t := range own[i] {
+			if prev, ok := types[t.Name]; ok {
+				errs = append(errs, &Conflict{Pos: t.Pos, Other: prev.Pos, Msg: fmt.Sprintf("type %s defined again", t.Name)})
+				continue
+			}
+			types[t.Name] = t
+			m.Types = append(m.Types, t)
+		}
+	}
+	conditions := map[string]*model.Condition{}
+	for i, f := range fragments {
+		for _, ext := range ext[i] {
+			t, ok := types[ext.Name]
+			if !ok {
+				errs = append(errs, &Conflict{Pos: ext.Pos, Msg: fmt.Sprintf("extends undefined type %s", ext.Name)})
+				continue
+			}
+			for _, r := range ext.Relations {
+				if prev := t.Relation(r.Name); prev != nil {
+					errs = append(errs, &Conflict{Pos: r.Pos, Other: prev.Pos, Msg: fmt.Sprintf("relation %s#%s defined again", t.Name, r.Name)})
+					continue
+				}
+				t.Relations = append(t.Relations, r)
+			}
+		}
+		for _, c := range f.Conditions {
+			if prev, ok := conditions[c.Name]; ok {
+				errs = append(errs, &Conflict{Pos: c.Pos, Other: prev.Pos, Msg: fmt.Sprintf("condition %s defined again", c.Name)})
+				continue
+			}
+			conditions[c.Name] = c
+			m.Conditions = append(m.Conditions, c)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return m, nil
+}
+
+func copyTypes(types []*model.Type) []*model.Type {
+	out := make([]*model.Type, len(types))
+	for i, t := range types {
+		out[i] = &model.Type{Name: t.Name, Pos: t.Pos, Relations: append([]*model.Relation(nil), t.Relations...)}
+	}
+	return out
+}
+
+// Load reads the manifest at path, assembles its fragments and validates
+// the result.
+func Load(path string) (*model.Model, error) {
+	m, err := ParseFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ParseFile is model.ParseFile for either a .fga file, whose macros it
+// expands, or a manifest, recognised by its .mod extension, whose
+// fragments it assembles. Like model.ParseFile it does not validate the
+// result.
+func ParseFile(path string) (*model.Model, error) {
+	if filepath.Ext(path) != ".mod" {
+		return macro.ParseFile(path)
+	}
+	mf, err := ReadManifest(path)
+	if err != nil {
+		return nil, err
+	}
+	fs, err := mf.Fragments()
+	if err != nil {
+		return nil, err
+	}
+	return Assemble(fs)
+}
@@ -0,0 +1,208 @@
+package modules
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// OwnersFile is the conventional ownership manifest name, kept next to
+// fga.mod.
+const OwnersFile = "fga.owners"
+
+// OwnerRule is one line of an ownership manifest.
+type OwnerRule struct {
+	// Pattern matches "type" or "type#relation", with path.Match globs;
+	// "*" matches everything, including conditions.
+	Pattern string
+	Owners  []string
+	Pos     model.Pos
+}
+
+// Ownership maps the parts of a model to the teams owning them, in the
+// style of CODEOWNERS: each line is a pattern followed by owners, lines
+// starting with # are comments, and the last matching line wins.
+//
+//   - @platform
+//     project                          @tracker
+//     issue                            @tracker
+//     organization#can_create_project  @tracker
+//     space                            @wiki @docs-team
+type Ownership struct {
+	Rules []OwnerRule
+}
+
+// ReadOwnership reads the ownership manifest at path.
+func ReadOwnership(path string) (*Ownership, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("modules: %w", err)
+	}
+	return ParseOwnership(path, src)
+}
+
+// ParseOwnership parses an ownership manifest. file is used only in
+// positions.
+func ParseOwnership(file string, src []byte) (*Ownership, error) {
+	o := &Ownership{}
+	for i, line := range strings.Split(string(src), "\n") {
+		text := strings.TrimSpace(line)
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		pos := model.Pos{File: file, Line: i + 1, Col: strings.Index(line, text) + 1}
+		fields := strings.Fields(text)
+		if len(fields) < 2 {
+			return nil, &model.SyntaxError{Pos: pos, Msg: fmt.Sprintf("%s has no owners", fields[0])}
+		}
+		if _, err := path.Match(fields[0], ""); err != nil {
+			return nil, &model.SyntaxError{Pos: pos, Msg: fmt.Sprintf("invalid pattern %q", fields[0])}
+		}
+		o.Rules = append(o.Rules, OwnerRule{Pattern: fields[0], Owners: fields[1:], Pos: pos})
+	}
+	return o, nil
+}
+
+// Owners returns the owners of typ#relation, or of the whole type when
+// relation is empty; nil when no rule matches. Conditions belong to
+// neither and are matched by "*" only, with an empty typ.
+func (o *Ownership) Owners(typ, relation string) []string {
+	var owners []string
+	for _, r := range o.Rules {
+		if r.Pattern == "*" || typ != "" && o.match(r.Pattern, typ, relation) {
+			owners = r.Owners
+		}
+	}
+	return owners
+}
+
+func (o *Ownership) match(pattern, typ, relation string) bool {
+	pt, pr, qualified := strings.Cut(pattern, "#")
+	if ok, _ := path.Match(pt, typ); !ok {
+		return false
+	}
+	if !qualified {
+		return true
+	}
+	ok, _ := path.Match(pr, relation)
+	return ok && relation != ""
+}
+
+// ChangeKind says how a Change differs from the baseline.
+type ChangeKind string
+
+const (
+	Added   ChangeKind = "added"
+	Removed ChangeKind = "removed"
+	Changed ChangeKind = "changed"
+)
+
+// Change is one type, relation or condition that differs between two
+// models. Relation is empty for a type added or removed as a whole, Type
+// for a condition.
+type Change struct {
+	Kind      ChangeKind
+	Type      string
+	Relation  string
+	Condition string
+	// Pos is in the new model, or in the baseline for removals.
+	Pos model.Pos
+}
+
+func (c Change) String() string {
+	switch {
+	case c.Condition != "":
+		return fmt.Sprintf("condition %s %s", c.Condition, c.Kind)
+	case c.Relation != "":
+		return fmt.Sprintf("relation %s#%s %s", c.Type, c.Relation, c.Kind)
+	}
+	return fmt.Sprintf("type %s %s", c.Type, c.Kind)
+}
+
+// Diff lists the types, relations and conditions next adds, removes or
+// redefines relative to base, sorted by type and relation. A relation is
+// changed when its rewrite prints differently.
+func Diff(base, next *model.Model) []Change {
+	var out []Change
+	for _, nt := range next.Types {
+		bt := base.Type(nt.Name)
+		if bt == nil {
+			out = append(out, Change{Kind: Added, Type: nt.Name, Pos: nt.Pos})
+			continue
+		}
+		for _, nr := range nt.Relations {
+			br := bt.Relation(nr.Name)
+			switch {
+			case br == nil:
+				out = append(out, Change{Kind: Added, Type: nt.Name, Relation: nr.Name, Pos: nr.Pos})
+			case br.Rewrite.String() != nr.Rewrite.String():
+				out = append(out, Change{Kind: Changed, Type: nt.Name, Relation: nr.Name, Pos: nr.Pos})
+			}
+		}
+		for _, br := range bt.Relations {
+			if nt.Relation(br.Name) == nil {
+				out = append(out, Change{Kind: Removed, Type: nt.Name, Relation: br.Name, Pos: br.Pos})
+			}
+		}
+	}
+	for _, bt := range base.Types {
+		if next.Type(bt.Name) == nil {
+			out = append(out, Change{Kind: Removed, Type: bt.Name, Pos: bt.Pos})
+		}
+	}
+	for _, nc := range next.Conditions {
+		bc := base.Condition(nc.Name)
+		switch {
+		case bc == nil:
+			out = append(out, Change{Kind: Added, Condition: nc.Name, Pos: nc.Pos})
+		case bc.Expression != nc.Expression || !slices.Equal(bc.Params, nc.Params):
+			out = append(out, Change{Kind: Changed, Condition: nc.Name, Pos: nc.Pos})
+		}
+	}
+	for _, bc := range base.Conditions {
+		if next.Condition(bc.Name) == nil {
+			out = append(out, Change{Kind: Removed, Condition: bc.Name, Pos: bc.Pos})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.Relation != b.Relation {
+			return a.Relation < b.Relation
+		}
+		return a.Condition < b.Condition
+	})
+	return out
+}
+
+// Violation is a change its author does not own.
+type Violation struct {
+	Change
+	Owners []string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s is owned by %s", v.Pos, v.Change, strings.Join(v.Owners, ", "))
+}
+
+// Check returns the changes none of author's identities, e.g. the
+// author's handle and teams, owns. Changes nothing owns are anyone's to
+// make.
+func (o *Ownership) Check(changes []Change, author []string) []Violation {
+	var out []Violation
+	for _, c := range changes {
+		owners := o.Owners(c.Type, c.Relation)
+		if len(owners) == 0 || slices.ContainsFunc(owners, func(ow string) bool { return slices.Contains(author, ow) }) {
+			continue
+		}
+		out = append(out, Violation{Change: c, Owners: owners})
+	}
+	return out
+}
@@ -0,0 +1,232 @@
+// Package notifications alerts people about authorization events worth a
+// human look — a new admin, an object made public, a model deployed — by
+// watching the changes feed and the active model and sending to Slack,
+// webhook or email sinks.
+//
+//	w := &notifications.Watcher{
+//		Backend:    backend,
+//		Privileged: []string{"admin", "organization#owner"},
+//		Public:     true,
+//		Models:     true,
+//		Sinks:      []notifications.Sink{&notifications.Slack{WebhookURL: url}},
+//		Cursor:     events.FileCursor("/var/lib/app/notify.cursor"),
+//	}
+//	go w.Run(ctx, time.Minute)
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/events"
+)
+
+// Kind classifies an Event.
+type Kind string
+
+const (
+	PrivilegedGranted Kind = "privileged_granted"
+	PublicAccess      Kind = "public_access"
+	ModelDeployed     Kind = "model_deployed"
+)
+
+// Event is one alert.
+type Event struct {
+	Kind Kind   `json:"kind"`
+	Text string `json:"text"`
+	// Tuple is the granted tuple for PrivilegedGranted and PublicAccess.
+	Tuple *authz.Tuple `json:"tuple,omitempty"`
+	// ModelID is the new active model for ModelDeployed.
+	ModelID string    `json:"model_id,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// Sink delivers events.
+type Sink interface {
+	Send(ctx context.Context, ev Event) error
+}
+
+// SinkFunc adapts a function to Sink.
+type SinkFunc func(ctx context.Context, ev Event) error
+
+// Send calls f.
+func (f SinkFunc) Send(ctx context.Context, ev Event) error { return f(ctx, ev) }
+
+// Watcher turns changes and model deploys into events.
+type Watcher struct {
+	Backend authz.Backend
+	Sinks   []Sink
+	// Privileged lists relations whose grants alert, by name ("admin") or
+	// qualified by type ("organization#owner").
+	Privileged []string
+	// Public alerts on every grant to a wildcard user such as user:*.
+	Public bool
+	// Models alerts when the active model changes.
+	Models bool
+	// ObjectType limits change alerts to one type; empty means all types.
+	ObjectType string
+	// Cursor defaults to an in-memory position starting at the end of the
+	// feed, so a fresh watcher does not replay history. A persistent cursor
+	// with no saved position starts at the beginning.
+	Cursor events.Cursor
+	Logger *slog.Logger
+
+	token   string
+	started bool
+	modelID string
+}
+
+// Poll sends the events since the last poll and returns how many were
+// sent. Delivery is at-least-once: the cursor is saved after each page, and
+// a page is retried whole when a sink fails.
+func (w *Watcher) Poll(ctx context.Context) (int, error) {
+	sent := 0
+	if w.Models {
+		n, err := w.pollModel(ctx)
+		sent += n
+		if err != nil {
+			return sent, err
+		}
+	}
+	if len(w.Privileged) == 0 && !w.Public {
+		return sent, nil
+	}
+	token, err := w.load(ctx)
+	if err != nil {
+		return sent, err
+	}
+	for {
+		page, next, err := w.Backend.ReadChanges(ctx, w.ObjectType, token)
+		if err != nil {
+			return sent, fmt.Errorf("notifications: read changes: %w", err)
+		}
+		if w.started || w.Cursor != nil {
+			for _, c := range page {
+				ev, ok := w.classify(c)
+				if !ok {
+					continue
+				}
+				if err := w.send(ctx, ev); err != nil {
+					return sent, err
+				}
+				sent++
+			}
+		}
+		if next != "" && next != token {
+			if err := w.save(ctx, next); err != nil {
+				return sent, err
+			}
+		}
+		if len(page) == 0 || next == "" || next == token {
+			w.started = true
+			return sent, nil
+		}
+		token = next
+	}
+}
+
+func (w *Watcher) pollModel(ctx context.Context) (int, error) {
+	m, err := w.Backend.ReadModel(ctx, "")
+	if err != nil {
+		return 0, fmt.Errorf("notifications: read model: %w", err)
+	}
+	prev := w.modelID
+	if m.ID == prev {
+		return 0, nil
+	}
+	if prev == "" {
+		w.modelID = m.ID
+		return 0, nil
+	}
+	if err := w.Deployed(ctx, m.ID); err != nil {
+		return 0, err
+	}
+	w.modelID = m.ID
+	return 1, nil
+}
+
+// Deployed sends a ModelDeployed event for id. Deploy pipelines can call it
+// directly instead of waiting for Poll to notice.
+func (w *Watcher) Deployed(ctx context.Context, id string) error {
+	return w.send(ctx, Event{Kind: ModelDeployed, ModelID: id, Time: time.Now(), Text: "authorization model " + id + " deployed"})
+}
+
+func (w *Watcher) classify(c authz.Change) (Event, bool) {
+	if c.Operation != authz.OpWrite {
+		return Event{}, false
+	}
+	t := c.Tuple
+	ev := Event{Tuple: &t, Time: c.Timestamp}
+	typ, _, _ := strings.Cut(t.Object, ":")
+	switch {
+	case w.Public && strings.HasSuffix(t.User, ":*"):
+		ev.Kind = PublicAccess
+		ev.Text = fmt.Sprintf("%s is now public: %s granted to everyone (%s)", t.Object, t.Relation, t.User)
+	case slices.Contains(w.Privileged, t.Relation) || slices.Contains(w.Privileged, typ+"#"+t.Relation):
+		ev.Kind = PrivilegedGranted
+		ev.Text = fmt.Sprintf("%s granted %s on %s", t.User, t.Relation, t.Object)
+	default:
+		return Event{}, false
+	}
+	return ev, true
+}
+
+// send delivers ev to every sink; a failing sink does not stop the others.
+func (w *Watcher) send(ctx context.Context, ev Event) error {
+	var errs []error
+	for _, s := range w.Sinks {
+		if err := s.Send(ctx, ev); err != nil {
+			errs = append(errs, fmt.Errorf("notifications: %s: %w", ev.Kind, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Run polls every interval until ctx is done.
+func (w *Watcher) Run(ctx context.Context, interval time.Duration) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		n, err := w.Poll(ctx)
+		if w.Logger != nil {
+			if err != nil {
+				w.Logger.WarnContext(ctx, "notification poll failed", "sent", n, "error", err)
+			} else if n > 0 {
+				w.Logger.DebugContext(ctx, "notifications sent", "count", n)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+func (w *Watcher) load(ctx context.Context) (string, error) {
+	if w.Cursor == nil {
+		return w.token, nil
+	}
+	t, err := w.Cursor.Load(ctx)
+	if err != nil {
+		return "", fmt.Errorf("notifications: load cursor: %w", err)
+	}
+	return t, nil
+}
+
+func (w *Watcher) save(ctx context.Context, token string) error {
+	w.token = token
+	if w.Cursor == nil {
+		return nil
+	}
+	if err := w.Cursor.Save(ctx, token); err != nil {
+		return fmt.Errorf("notifications: save cursor: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"strings"
+)
+
+// Slack posts events to a Slack incoming webhook.
+type Slack struct {
+	WebhookURL string
+	// Client defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// Send implements Sink.
+func (s *Slack) Send(ctx context.Context, ev Event) error {
+	body, err := json.Marshal(map[string]string{"text": ":lock: " + ev.Text})
+	if err != nil {
+		return err
+	}
+	return post(ctx, s.Client, s.WebhookURL, body, nil)
+}
+
+// Webhook posts each event as JSON.
+type Webhook struct {
+	URL string
+	// Header is added to every request, e.g. an Authorization token.
+	Header http.Header
+	// Client defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// Send implements Sink.
+func (h *Webhook) Send(ctx context.Context, ev Event) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	return post(ctx, h.Client, h.URL, body, h.Header)
+}
+
+func post(ctx context.Context, client *http.Client, url string, body []byte, header http.Header) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, vs := range header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("POST %s: %s", url, resp.Status)
+	}
+	return nil
+}
+
+// Email sends each event as a plain-text message over SMTP. The context is
+// not honoured by net/smtp; bound delivery with the server's timeouts.
+type Email struct {
+	// Addr is the SMTP server, host:port.
+	Addr string
+	// Auth may be nil for unauthenticated relays.
+	Auth smtp.Auth
+	From string
+	To   []string
+}
+
+// Send implements Sink.
+func (e *Email) Send(_ context.Context, ev Event) error {
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", e.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.To, ", "))
+	fmt.Fprintf(&msg, "Subject: [authz] %s\r\n", ev.Kind)
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(ev.Text)
+	msg.WriteString("\r\n")
+	return smtp.SendMail(e.Addr, e.Auth, e.From, e.To, []byte(msg.String()))
+}
@@ -0,0 +1,237 @@
+// Package outbox records tuple mutations in an application's transactional
+// outbox table, in the same database transaction as the business change, and
+// relays them to OpenFGA afterwards. Grants therefore never diverge from
+// application data: either both commit or neither does.
+//
+//	tx, _ := db.BeginTx(ctx, nil)
+//	_, _ = tx.ExecContext(ctx, "INSERT INTO projects ...")
+//	_ = ob.Enqueue(ctx, tx, []authz.Tuple{{User: "user:alice", Relation: "owner", Object: "project:api"}}, nil)
+//	_ = tx.Commit()
+//
+// A Relay running alongside the application applies pending rows in order.
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+)
+
+// DefaultTable is the outbox table name used when Outbox.Table is empty.
+const DefaultTable = "fga_outbox"
+
+// Dialect captures the SQL differences between supported databases.
+type Dialect struct {
+	// Placeholder returns the bind parameter for the n-th (1-based) argument.
+	Placeholder func(n int) string
+	// IDColumn is the DDL for an auto-incrementing primary key.
+	IDColumn string
+	// LockClause is appended to the pending-rows query so concurrent relays
+	// take turns: a second relay waits until the first commits its batch,
+	// so rows are never applied out of order. Empty for SQLite, which
+	// serializes writers itself; run a single relay there.
+	LockClause string
+}
+
+var (
+	Postgres = Dialect{
+		Placeholder: func(n int) string { return fmt.Sprintf("$%d", n) },
+		IDColumn:    "BIGSERIAL PRIMARY KEY",
+		LockClause:  " FOR UPDATE",
+	}
+	MySQL = Dialect{
+		Placeholder: func(int) string { return "?" },
+		IDColumn:    "BIGINT AUTO_INCREMENT PRIMARY KEY",
+		LockClause:  " FOR UPDATE",
+	}
+	SQLite = Dialect{
+		Placeholder: func(int) string { return "?" },
+		IDColumn:    "INTEGER PRIMARY KEY AUTOINCREMENT",
+	}
+)
+
+// Execer is satisfied by *sql.Tx, *sql.DB and *sql.Conn.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Outbox describes the outbox table.
+type Outbox struct {
+	Dialect Dialect
+	Table   string
+}
+
+func (o *Outbox) table() string {
+	if o.Table == "" {
+		return DefaultTable
+	}
+	return o.Table
+}
+
+// Schema returns the CREATE TABLE statement for the outbox.
+func (o *Outbox) Schema() string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id %s,
+	payload TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	processed_at TIMESTAMP NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT NULL
+)`, o.table(), o.Dialect.IDColumn)
+}
+
+type payload struct {
+	Writes  []authz.Tuple `json:"writes,omitempty"`
+	Deletes []authz.Tuple `json:"deletes,omitempty"`
+}
+
+// Enqueue inserts a mutation into the outbox through ex, normally the
+// transaction that performs the business change.
+func (o *Outbox) Enqueue(ctx context.Context, ex Execer, writes, deletes []authz.Tuple) error {
+	if len(writes) == 0 && len(deletes) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(payload{Writes: writes, Deletes: deletes})
+	if err != nil {
+		return err
+	}
+	p := o.Dialect.Placeholder
+	q := fmt.Sprintf("INSERT INTO %s (payload, created_at) VALUES (%s, %s)", o.table(), p(1), p(2))
+	if _, err := ex.ExecContext(ctx, q, string(data), time.Now().UTC()); err != nil {
+		return fmt.Errorf("outbox: enqueue: %w", err)
+	}
+	return nil
+}
+
+// Relay applies pending outbox rows to a backend in id order.
+type Relay struct {
+	Outbox  *Outbox
+	DB      *sql.DB
+	Backend authz.Backend
+	// BatchSize is the number of rows claimed per transaction; default 100.
+	BatchSize int
+	// IsDuplicate reports errors meaning the mutation was already applied,
+	// e.g. after a crash between applying a row and marking it. Such rows
+	// are marked processed instead of retried. Defaults to authz.Duplicate.
+	IsDuplicate func(error) bool
+}
+
+// RunOnce claims one batch, applies it and records the outcome. Processing
+// stops at the first failing row so later rows never overtake it. It
+// returns the number of rows applied.
+func (r *Relay) RunOnce(ctx context.Context) (int, error) {
+	batch := r.batchSize()
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	o, p := r.Outbox, r.Outbox.Dialect.Placeholder
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(
+		"SELECT id, payload FROM %s WHERE processed_at IS NULL ORDER BY id LIMIT %d%s",
+		o.table(), batch, o.Dialect.LockClause))
+	if err != nil {
+		return 0, fmt.Errorf("outbox: claim: %w", err)
+	}
+	type row struct {
+		id      int64
+		payload payload
+	}
+	var pending []row
+	for rows.Next() {
+		var rw row
+		var data string
+		if err := rows.Scan(&rw.id, &data); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if err := json.Unmarshal([]byte(data), &rw.payload); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("outbox: row %d: %w", rw.id, err)
+		}
+		pending = append(pending, rw)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	var applyErr error
+	for _, rw := range pending {
+		err := r.Backend.Write(ctx, rw.payload.Writes, rw.payload.Deletes)
+		if err != nil && !r.duplicate(err) {
+			q := fmt.Sprintf("UPDATE %s SET attempts = attempts + 1, last_error = %s WHERE id = %s", o.table(), p(1), p(2))
+			if _, uerr := tx.ExecContext(ctx, q, truncate(err.Error(), 1000), rw.id); uerr != nil {
+				return applied, uerr
+			}
+			applyErr = fmt.Errorf("outbox: apply row %d: %w", rw.id, err)
+			break
+		}
+		q := fmt.Sprintf("UPDATE %s SET processed_at = %s WHERE id = %s", o.table(), p(1), p(2))
+		if _, err := tx.ExecContext(ctx, q, time.Now().UTC(), rw.id); err != nil {
+			return applied, err
+		}
+		applied++
+	}
+	if err := tx.Commit(); err != nil {
+		return applied, err
+	}
+	return applied, applyErr
+}
+
+func (r *Relay) batchSize() int {
+	if r.BatchSize <= 0 {
+		return 100
+	}
+	return r.BatchSize
+}
+
+func (r *Relay) duplicate(err error) bool {
+	if r.IsDuplicate != nil {
+		return r.IsDuplicate(err)
+	}
+	return authz.Duplicate(err)
+}
+
+// Run calls RunOnce every interval, and immediately again while batches come
+// back full, until ctx is done.
+func (r *Relay) Run(ctx context.Context, interval time.Duration) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		n, err := r.RunOnce(ctx)
+		if err == nil && n >= r.batchSize() {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// Purge deletes rows processed before cutoff and returns how many were
+// removed.
+func (r *Relay) Purge(ctx context.Context, cutoff time.Time) (int64, error) {
+	q := fmt.Sprintf("DELETE FROM %s WHERE processed_at IS NOT NULL AND processed_at < %s", r.Outbox.table(), r.Outbox.Dialect.Placeholder(1))
+	res, err := r.DB.ExecContext(ctx, q, cutoff.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
@@ -0,0 +1,204 @@
+// Package overlap reports users granted several relations on one object
+// where one would do — owner, editor and viewer written side by side when
+// the model already derives viewer from editor and editor from owner — and
+// grants that an exclusion cancels, such as viewer next to blocked under
+// "viewer: [user] but not blocked".
+//
+//	findings, err := overlap.Report(ctx, backend)
+//	plan := overlap.Normalize(findings)
+//	// review plan.Redundant, then
+//	err = plan.Apply(ctx, backend, az)
+//
+// Normalize keeps each user's strongest relations and deletes the weaker
+// tuples through compaction.Plan, which verifies access before and after.
+// Conflicts are only reported: whether the grant or the exclusion is the
+// mistake needs a person.
+package overlap
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/compaction"
+	"github.com/bogdanticu88/openfga-examples/eval"
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// Kind classifies a Finding.
+type Kind string
+
+const (
+	// Overlap is a user holding relations directly that others imply.
+	Overlap Kind = "overlap"
+	// Conflict is a user granted a relation and, directly, a relation that
+	// excludes it.
+	Conflict Kind = "conflict"
+)
+
+// Finding is one user on one object.
+type Finding struct {
+	Kind   Kind
+	User   string
+	Object string
+	// Relations are the relations the user holds directly, sorted.
+	Relations []string
+	// Keep are the strongest relations of an Overlap; Redundant the ones
+	// they imply.
+	Keep      []string
+	Redundant []string
+	// Granted and Excluded are the two sides of a Conflict.
+	Granted  string
+	Excluded string
+}
+
+func (f Finding) String() string {
+	if f.Kind == Conflict {
+		return fmt.Sprintf("%s: %s on %s is granted %s but excluded by %s", f.Kind, f.User, f.Object, f.Granted, f.Excluded)
+	}
+	return fmt.Sprintf("%s: %s on %s holds %s; keep %s, the rest is implied", f.Kind, f.User, f.Object,
+		strings.Join(f.Relations, ", "), strings.Join(f.Keep, ", "))
+}
+
+// Report reads the active model and every tuple from b and returns Find's
+// findings.
+func Report(ctx context.Context, b authz.Backend) ([]Finding, error) {
+	m, err := b.ReadModel(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("overlap: read model: %w", err)
+	}
+	all, err := authz.ReadAll(ctx, b, authz.Tuple{})
+	if err != nil {
+		return nil, fmt.Errorf("overlap: read: %w", err)
+	}
+	return Find(m, all), nil
+}
+
+// Find returns the overlaps and conflicts among tuples under m, sorted by
+// object and user.
+func Find(m *model.Model, tuples []authz.Tuple) []Finding {
+	ts := append([]authz.Tuple(nil), tuples...)
+	eval.SortTuples(ts)
+	type key struct{ object, user string }
+	held := map[key][]string{}
+	var keys []key
+	for _, t := range ts {
+		k := key{t.Object, t.User}
+		if held[k] == nil {
+			keys = append(keys, k)
+		}
+		held[k] = append(held[k], t.Relation)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].object != keys[j].object {
+			return keys[i].object < keys[j].object
+		}
+		return keys[i].user < keys[j].user
+	})
+
+	var out []Finding
+	for _, k := range keys {
+		rels := held[k]
+		if len(rels) < 2 {
+			continue
+		}
+		sort.Strings(rels)
+		typ, _, _ := strings.Cut(k.object, ":")
+		var keep, redundant []string
+		for _, r := range rels {
+			implied := false
+			for _, s := range rels {
+				if s != r && slices.Contains(implying(m, typ, r), s) && !slices.Contains(implying(m, typ, s), r) {
+					implied = true
+					break
+				}
+			}
+			if implied {
+				redundant = append(redundant, r)
+			} else {
+				keep = append(keep, r)
+			}
+		}
+		if len(redundant) > 0 {
+			out = append(out, Finding{Kind: Overlap, User: k.user, Object: k.object, Relations: rels, Keep: keep, Redundant: redundant})
+		}
+		for _, r := range rels {
+			for _, x := range excluders(m, typ, r) {
+				if slices.Contains(rels, x) {
+					out = append(out, Finding{Kind: Conflict, User: k.user, Object: k.object, Relations: rels, Granted: r, Excluded: x})
+				}
+			}
+		}
+	}
+	return out
+}
+
+// Normalize returns a plan deleting the redundant tuples of every Overlap
+// finding.
+func Normalize(findings []Finding) *compaction.Plan {
+	plan := &compaction.Plan{}
+	for _, f := range findings {
+		if f.Kind != Overlap {
+			continue
+		}
+		for _, r := range f.Redundant {
+			plan.Redundant = append(plan.Redundant, authz.Tuple{User: f.User, Relation: r, Object: f.Object})
+		}
+		plan.Examined++
+	}
+	eval.SortTuples(plan.Redundant)
+	return plan
+}
+
+// implying returns the relations of typ whose holders are guaranteed
+// relation, through computed relations in union branches.
+func implying(m *model.Model, typ, relation string) []string {
+	out := []string{relation}
+	for i := 0; i < len(out); i++ {
+		r := m.Relation(typ, out[i])
+		if r == nil {
+			continue
+		}
+		for _, c := range unionLeaves(r.Rewrite) {
+			if comp, ok := c.(*model.Computed); ok && !slices.Contains(out, comp.Relation) {
+				out = append(out, comp.Relation)
+			}
+		}
+	}
+	return out
+}
+
+func unionLeaves(rw model.Rewrite) []model.Rewrite {
+	if u, ok := rw.(*model.Union); ok {
+		var out []model.Rewrite
+		for _, c := range u.Children {
+			out = append(out, unionLeaves(c)...)
+		}
+		return out
+	}
+	return []model.Rewrite{rw}
+}
+
+// excluders returns the relations of typ subtracted in relation's rewrite.
+func excluders(m *model.Model, typ, relation string) []string {
+	r := m.Relation(typ, relation)
+	if r == nil {
+		return nil
+	}
+	var out []string
+	model.Walk(r.Rewrite, func(rw model.Rewrite) {
+		d, ok := rw.(*model.Difference)
+		if !ok {
+			return
+		}
+		model.Walk(d.Subtract, func(s model.Rewrite) {
+			if c, ok := s.(*model.Computed); ok && !slices.Contains(out, c.Relation) {
+				out = append(out, c.Relation)
+			}
+		})
+	})
+	return out
+}
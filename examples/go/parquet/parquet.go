@@ -0,0 +1,326 @@
+// Package parquet writes flat tables as Apache Parquet files, enough for
+// loading tuple snapshots and matrices into warehouses such as BigQuery,
+// Snowflake or DuckDB without another dependency:
+//
+//	w := parquet.NewWriter(f, parquet.Column{Name: "user", Type: parquet.String}, parquet.Column{Name: "allowed", Type: parquet.Bool})
+//	w.Write("user:alice", true)
+//	err := w.Close()
+//
+// Every column is required and PLAIN-encoded without compression; rows are
+// buffered and written as one row group per RowGroupRows rows.
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Type is a column type.
+type Type int
+
+const (
+	// String is a UTF-8 byte array.
+	String Type = iota
+	// Int64 is a signed 64-bit integer.
+	Int64
+	// Bool is a boolean.
+	Bool
+	// Timestamp is a UTC instant with microsecond precision.
+	Timestamp
+)
+
+// Column is one column of the schema.
+type Column struct {
+	Name string
+	Type Type
+}
+
+// DefaultRowGroupRows is the row group size when Writer.RowGroupRows is
+// zero.
+const DefaultRowGroupRows = 50000
+
+// CreatedBy is recorded in the file metadata.
+const CreatedBy = "openfga-examples parquet"
+
+// ErrClosed is returned by Write after Close.
+var ErrClosed = errors.New("parquet: writer closed")
+
+// Writer writes one Parquet file. It is not safe for concurrent use.
+type Writer struct {
+	// RowGroupRows is the number of rows buffered before a row group is
+	// written; default DefaultRowGroupRows.
+	RowGroupRows int
+
+	w       io.Writer
+	offset  int64
+	columns []Column
+	values  []bytes.Buffer
+	bits    [][]bool
+	rows    int
+	total   int64
+	groups  []rowGroup
+	started bool
+	closed  bool
+	err     error
+}
+
+type rowGroup struct {
+	rows   int64
+	size   int64
+	chunks []chunk
+}
+
+type chunk struct {
+	offset int64
+	size   int64
+	values int64
+}
+
+// NewWriter returns a writer of a file with the given columns to w.
+func NewWriter(w io.Writer, columns ...Column) *Writer {
+	return &Writer{
+		w:       w,
+		columns: columns,
+		values:  make([]bytes.Buffer, len(columns)),
+		bits:    make([][]bool, len(columns)),
+	}
+}
+
+// Write appends a row. Values must match the columns in number and type:
+// string, int64 (int is accepted), bool or time.Time.
+func (w *Writer) Write(values ...interface{}) error {
+	if w.closed {
+		return ErrClosed
+	}
+	if w.err != nil {
+		return w.err
+	}
+	if len(values) != len(w.columns) {
+		return fmt.Errorf("parquet: row has %d values, want %d", len(values), len(w.columns))
+	}
+	for i, v := range values {
+		if err := w.append(i, v); err != nil {
+			return err
+		}
+	}
+	w.rows++
+	limit := w.RowGroupRows
+	if limit <= 0 {
+		limit = DefaultRowGroupRows
+	}
+	if w.rows >= limit {
+		return w.flush()
+	}
+	return nil
+}
+
+func (w *Writer) append(i int, v interface{}) error {
+	col := w.columns[i]
+	buf := &w.values[i]
+	var b [8]byte
+	switch col.Type {
+	case String:
+		s, ok := v.(string)
+		if !ok {
+			return w.typeError(col, v)
+		}
+		binary.LittleEndian.PutUint32(b[:4], uint32(len(s)))
+		buf.Write(b[:4])
+		buf.WriteString(s)
+	case Int64:
+		var n int64
+		switch x := v.(type) {
+		case int64:
+			n = x
+		case int:
+			n = int64(x)
+		default:
+			return w.typeError(col, v)
+		}
+		binary.LittleEndian.PutUint64(b[:], uint64(n))
+		buf.Write(b[:])
+	case Bool:
+		x, ok := v.(bool)
+		if !ok {
+			return w.typeError(col, v)
+		}
+		w.bits[i] = append(w.bits[i], x)
+	case Timestamp:
+		t, ok := v.(time.Time)
+		if !ok {
+			return w.typeError(col, v)
+		}
+		binary.LittleEndian.PutUint64(b[:], uint64(t.UnixMicro()))
+		buf.Write(b[:])
+	}
+	return nil
+}
+
+func (w *Writer) typeError(col Column, v interface{}) error {
+	return fmt.Errorf("parquet: column %s: unexpected %T", col.Name, v)
+}
+
+// Flush writes the buffered rows as a row group.
+func (w *Writer) Flush() error {
+	if w.closed {
+		return ErrClosed
+	}
+	return w.flush()
+}
+
+func (w *Writer) flush() error {
+	if w.err != nil || w.rows == 0 {
+		return w.err
+	}
+	if !w.started {
+		if w.err = w.emit([]byte("PAR1")); w.err != nil {
+			return w.err
+		}
+		w.started = true
+	}
+	g := rowGroup{rows: int64(w.rows)}
+	for i, col := range w.columns {
+		data := w.values[i].Bytes()
+		if col.Type == Bool {
+			data = packBits(w.bits[i])
+		}
+		var h compact
+		h.begin()
+		h.i32(1, 0) // DATA_PAGE
+		h.i32(2, int32(len(data)))
+		h.i32(3, int32(len(data)))
+		h.structField(5)
+		h.i32(1, int32(w.rows))
+		h.i32(2, 0) // PLAIN
+		h.i32(3, 3) // RLE definition levels, unused for required columns
+		h.i32(4, 3)
+		h.end()
+		h.end()
+		c := chunk{offset: w.offset, size: int64(h.buf.Len() + len(data)), values: int64(w.rows)}
+		if w.err = w.emit(h.buf.Bytes()); w.err != nil {
+			return w.err
+		}
+		if w.err = w.emit(data); w.err != nil {
+			return w.err
+		}
+		g.chunks = append(g.chunks, c)
+		g.size += c.size
+		w.values[i].Reset()
+		w.bits[i] = w.bits[i][:0]
+	}
+	w.groups = append(w.groups, g)
+	w.total += int64(w.rows)
+	w.rows = 0
+	return nil
+}
+
+// Close writes any buffered rows and the file footer. It does not close
+// the underlying writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	if err := w.flush(); err != nil {
+		return err
+	}
+	w.closed = true
+	if !w.started {
+		if w.err = w.emit([]byte("PAR1")); w.err != nil {
+			return w.err
+		}
+	}
+	meta := w.footer()
+	var n [4]byte
+	binary.LittleEndian.PutUint32(n[:], uint32(len(meta)))
+	for _, b := range [][]byte{meta, n[:], []byte("PAR1")} {
+		if w.err = w.emit(b); w.err != nil {
+			return w.err
+		}
+	}
+	return nil
+}
+
+func (w *Writer) footer() []byte {
+	var m compact
+	m.begin()
+	m.i32(1, 1)
+	m.list(2, tStruct, len(w.columns)+1)
+	m.begin()
+	m.str(4, "schema")
+	m.i32(5, int32(len(w.columns)))
+	m.end()
+	for _, col := range w.columns {
+		m.begin()
+		m.i32(1, physical(col.Type))
+		m.i32(3, 0) // REQUIRED
+		m.str(4, col.Name)
+		switch col.Type {
+		case String:
+			m.i32(6, 0) // UTF8
+		case Timestamp:
+			m.i32(6, 10) // TIMESTAMP_MICROS
+		}
+		m.end()
+	}
+	m.i64(3, w.total)
+	m.list(4, tStruct, len(w.groups))
+	for _, g := range w.groups {
+		m.begin()
+		m.list(1, tStruct, len(g.chunks))
+		for i, c := range g.chunks {
+			m.begin()
+			m.i64(2, c.offset)
+			m.structField(3)
+			m.i32(1, physical(w.columns[i].Type))
+			m.list(2, tI32, 1)
+			m.varint(zigzag(0)) // PLAIN
+			m.list(3, tBinary, 1)
+			m.rawStr(w.columns[i].Name)
+			m.i32(4, 0) // UNCOMPRESSED
+			m.i64(5, c.values)
+			m.i64(6, c.size)
+			m.i64(7, c.size)
+			m.i64(9, c.offset)
+			m.end()
+			m.end()
+		}
+		m.i64(2, g.size)
+		m.i64(3, g.rows)
+		m.end()
+	}
+	m.str(6, CreatedBy)
+	m.end()
+	return m.buf.Bytes()
+}
+
+func (w *Writer) emit(b []byte) error {
+	n, err := w.w.Write(b)
+	w.offset += int64(n)
+	return err
+}
+
+func physical(t Type) int32 {
+	switch t {
+	case Bool:
+		return 0
+	case Int64, Timestamp:
+		return 2
+	}
+	return 6 // BYTE_ARRAY
+}
+
+// packBits is the PLAIN encoding of booleans: one bit per value, least
+// significant bit first.
+func packBits(bs []bool) []byte {
+	out := make([]byte, (len(bs)+7)/8)
+	for i, b := range bs {
+		if b {
+			out[i/8] |= 1 << (i % 8)
+		}
+	}
+	return out
+}
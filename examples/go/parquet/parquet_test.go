@@ -0,0 +1,149 @@
+package parquet
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pq "github.com/parquet-go/parquet-go"
+
+	"github.com/bogdanticu88/openfga-examples/fgatest"
+)
+
+var (
+	testColumns = []Column{
+		{Name: "user", Type: String},
+		{Name: "count", Type: Int64},
+		{Name: "allowed", Type: Bool},
+		{Name: "at", Type: Timestamp},
+	}
+	testTime = time.Date(2024, 3, 1, 12, 30, 0, 123456000, time.UTC)
+	testRows = [][]interface{}{
+		{"user:alice", int64(1), true, testTime},
+		{"user:bob", 2, false, testTime.Add(time.Second)},
+		{"", int64(-3), true, time.Unix(0, 0).UTC()},
+		// Non-ASCII and a name long enough to need a multi-byte length.
+		{"user:zoë-" + string(bytes.Repeat([]byte("x"), 200)), int64(1) << 40, true, testTime.Add(-time.Hour)},
+		{"user:eve", int64(0), false, testTime},
+	}
+)
+
+func writeTestFile(t *testing.T, rows [][]interface{}) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := NewWriter(&buf, testColumns...)
+	w.RowGroupRows = 2
+	for _, r := range rows {
+		if err := w.Write(r...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// TestGolden pins the exact bytes written, so encoding changes show up in
+// review. Regenerate with FGATEST_UPDATE=1 after checking TestReadBack.
+func TestGolden(t *testing.T) {
+	got := writeTestFile(t, testRows)
+	path := filepath.Join("testdata", "rows.parquet")
+	if v := os.Getenv(fgatest.UpdateEnv); v != "" && v != "0" {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s; run with %s=1 to accept the change", path, fgatest.UpdateEnv)
+	}
+}
+
+// TestReadBack reads the file with an independent Parquet implementation.
+func TestReadBack(t *testing.T) {
+	data := writeTestFile(t, testRows)
+	f, err := pq.OpenFile(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.NumRows() != int64(len(testRows)) {
+		t.Errorf("NumRows = %d, want %d", f.NumRows(), len(testRows))
+	}
+	if n := len(f.RowGroups()); n != 3 {
+		t.Errorf("got %d row groups, want 3", n)
+	}
+	if got := f.Metadata().CreatedBy; got != CreatedBy {
+		t.Errorf("CreatedBy = %q, want %q", got, CreatedBy)
+	}
+
+	fields := f.Schema().Fields()
+	wantKinds := []pq.Kind{pq.ByteArray, pq.Int64, pq.Boolean, pq.Int64}
+	if len(fields) != len(testColumns) {
+		t.Fatalf("schema has %d fields, want %d", len(fields), len(testColumns))
+	}
+	for i, field := range fields {
+		if field.Name() != testColumns[i].Name || !field.Required() || field.Type().Kind() != wantKinds[i] {
+			t.Errorf("field %d = %s required=%v %v, want %s required %v", i, field.Name(), field.Required(), field.Type().Kind(), testColumns[i].Name, wantKinds[i])
+		}
+	}
+	if lt := fields[0].Type().LogicalType(); lt == nil || lt.UTF8 == nil {
+		t.Errorf("user logical type = %v, want STRING", lt)
+	}
+	if lt := fields[3].Type().LogicalType(); lt == nil || lt.Timestamp == nil || lt.Timestamp.Unit.Micros == nil {
+		t.Errorf("at logical type = %v, want TIMESTAMP(MICROS)", lt)
+	}
+
+	var got []pq.Row
+	for _, g := range f.RowGroups() {
+		rows := g.Rows()
+		buf := make([]pq.Row, g.NumRows())
+		n, err := rows.ReadRows(buf)
+		if int64(n) != g.NumRows() {
+			t.Fatalf("ReadRows = %d, %v; want %d rows", n, err, g.NumRows())
+		}
+		rows.Close()
+		for _, r := range buf[:n] {
+			got = append(got, r.Clone())
+		}
+	}
+	if len(got) != len(testRows) {
+		t.Fatalf("read %d rows, want %d", len(got), len(testRows))
+	}
+	for i, want := range testRows {
+		r := got[i]
+		if s := string(r[0].ByteArray()); s != want[0] {
+			t.Errorf("row %d user = %q, want %q", i, s, want[0])
+		}
+		wantCount, ok := want[1].(int64)
+		if !ok {
+			wantCount = int64(want[1].(int))
+		}
+		if n := r[1].Int64(); n != wantCount {
+			t.Errorf("row %d count = %d, want %d", i, n, wantCount)
+		}
+		if b := r[2].Boolean(); b != want[2] {
+			t.Errorf("row %d allowed = %v, want %v", i, b, want[2])
+		}
+		if at := time.UnixMicro(r[3].Int64()).UTC(); !at.Equal(want[3].(time.Time)) {
+			t.Errorf("row %d at = %v, want %v", i, at, want[3])
+		}
+	}
+}
+
+func TestReadBackEmpty(t *testing.T) {
+	data := writeTestFile(t, nil)
+	f, err := pq.OpenFile(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.NumRows() != 0 || len(f.Schema().Fields()) != len(testColumns) {
+		t.Errorf("empty file: %d rows, %d fields; want 0 rows, %d fields", f.NumRows(), len(f.Schema().Fields()), len(testColumns))
+	}
+}
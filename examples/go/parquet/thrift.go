@@ -0,0 +1,84 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Thrift compact protocol type IDs.
+const (
+	tI32    = 5
+	tI64    = 6
+	tBinary = 8
+	tList   = 9
+	tStruct = 12
+)
+
+// compact encodes the subset of the Thrift compact protocol that Parquet
+// metadata needs. Fields must be written in increasing ID order.
+type compact struct {
+	buf  bytes.Buffer
+	last []int16
+}
+
+func (c *compact) begin() { c.last = append(c.last, 0) }
+
+func (c *compact) end() {
+	c.buf.WriteByte(0)
+	c.last = c.last[:len(c.last)-1]
+}
+
+func (c *compact) field(id int16, typ byte) {
+	top := &c.last[len(c.last)-1]
+	if d := id - *top; d > 0 && d <= 15 {
+		c.buf.WriteByte(byte(d)<<4 | typ)
+	} else {
+		c.buf.WriteByte(typ)
+		c.varint(zigzag(int64(id)))
+	}
+	*top = id
+}
+
+func (c *compact) varint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	c.buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func zigzag(v int64) uint64 { return uint64(v<<1) ^ uint64(v>>63) }
+
+func (c *compact) i32(id int16, v int32) {
+	c.field(id, tI32)
+	c.varint(zigzag(int64(v)))
+}
+
+func (c *compact) i64(id int16, v int64) {
+	c.field(id, tI64)
+	c.varint(zigzag(v))
+}
+
+func (c *compact) str(id int16, s string) {
+	c.field(id, tBinary)
+	c.rawStr(s)
+}
+
+func (c *compact) rawStr(s string) {
+	c.varint(uint64(len(s)))
+	c.buf.WriteString(s)
+}
+
+// list writes a list header; the n elements of type elem follow.
+func (c *compact) list(id int16, elem byte, n int) {
+	c.field(id, tList)
+	if n < 15 {
+		c.buf.WriteByte(byte(n)<<4 | elem)
+		return
+	}
+	c.buf.WriteByte(0xf0 | elem)
+	c.varint(uint64(n))
+}
+
+// structField begins a struct-valued field; close it with end.
+func (c *compact) structField(id int16) {
+	c.field(id, tStruct)
+	c.begin()
+}
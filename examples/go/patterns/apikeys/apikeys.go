@@ -0,0 +1,283 @@
+// Package apikeys supports machine principals. A service, or a user, mints
+// API keys; each key is an api_key object bound to its owner and is granted
+// access like any other user. Requests present the key in a header and are
+// checked as api_key:<id>.
+//
+// Keys have the form "<id>.<secret>". Only a hash of the secret is stored.
+package apikeys
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+)
+
+// Type and relation names used by Fragment.
+const (
+	KeyType       = "api_key"
+	ServiceType   = "service"
+	ownerRelation = "owner"
+)
+
+// DefaultHeader carries the key when no Authorization bearer token is
+// present.
+const DefaultHeader = "X-API-Key"
+
+var (
+	// ErrNoKey is returned when a request carries no API key.
+	ErrNoKey = errors.New("apikeys: no API key in request")
+	// ErrInvalidKey is returned for a malformed, unknown or revoked key.
+	ErrInvalidKey = errors.New("apikeys: invalid API key")
+)
+
+// Fragment returns the DSL for the machine principal types. Resources
+// accept keys by listing api_key in their type restrictions, e.g.
+// "define viewer: [user, api_key]".
+func Fragment(userType string) string {
+	return fmt.Sprintf(`type %[2]s
+  relations
+    define owner: [%[1]s]
+
+type %[3]s
+  relations
+    define owner: [%[1]s, %[2]s]
+`, userType, ServiceType, KeyType)
+}
+
+// Key is a minted key as stored; the secret itself is not kept.
+type Key struct {
+	ID        string
+	Owner     string
+	Label     string
+	Hash      []byte
+	CreatedAt time.Time
+}
+
+// Principal is the user the key is checked as.
+func (k Key) Principal() authz.User { return authz.User{Type: KeyType, ID: k.ID} }
+
+// Store persists keys.
+type Store interface {
+	Put(ctx context.Context, k Key) error
+	// Get returns the key with id, or ErrInvalidKey.
+	Get(ctx context.Context, id string) (Key, error)
+	Delete(ctx context.Context, id string) error
+	List(ctx context.Context) ([]Key, error)
+}
+
+// MemoryStore is a Store for tests and single-process use.
+type MemoryStore struct {
+	mu sync.Mutex
+	m  map[string]Key
+}
+
+func (s *MemoryStore) Put(_ context.Context, k Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.m == nil {
+		s.m = map[string]Key{}
+	}
+	s.m[k.ID] = k
+	return nil
+}
+
+func (s *MemoryStore) Get(_ context.Context, id string) (Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k, ok := s.m[id]
+	if !ok {
+		return Key{}, ErrInvalidKey
+	}
+	return k, nil
+}
+
+func (s *MemoryStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.m, id)
+	return nil
+}
+
+func (s *MemoryStore) List(context.Context) ([]Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Key, 0, len(s.m))
+	for _, k := range s.m {
+		out = append(out, k)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+// Keys mints, resolves and revokes API keys.
+type Keys struct {
+	Client *authz.Client
+	Store  Store
+	// Header is read when there is no bearer token; default DefaultHeader.
+	Header string
+	// Now defaults to time.Now.
+	Now func() time.Time
+}
+
+// Mint creates a key owned by owner, a user or service, and returns the
+// key string to hand out once.
+func (k *Keys) Mint(ctx context.Context, owner authz.User, label string) (string, Key, error) {
+	var id [8]byte
+	var secret [24]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", Key{}, fmt.Errorf("apikeys: %w", err)
+	}
+	if _, err := rand.Read(secret[:]); err != nil {
+		return "", Key{}, fmt.Errorf("apikeys: %w", err)
+	}
+	key := Key{ID: hex.EncodeToString(id[:]), Owner: owner.String(), Label: label, Hash: hash(secret[:]), CreatedAt: k.now()}
+	if err := k.Store.Put(ctx, key); err != nil {
+		return "", Key{}, err
+	}
+	t := authz.NewTuple(owner, ownerRelation, authz.NewObject(KeyType, key.ID))
+	if err := k.Client.Write(ctx, []authz.Tuple{t}, nil); err != nil {
+		k.Store.Delete(ctx, key.ID)
+		return "", Key{}, err
+	}
+	return key.ID + "." + base64.RawURLEncoding.EncodeToString(secret[:]), key, nil
+}
+
+// Resolve verifies a key string and returns the stored key.
+func (k *Keys) Resolve(ctx context.Context, s string) (Key, error) {
+	id, enc, ok := strings.Cut(s, ".")
+	if !ok {
+		return Key{}, ErrInvalidKey
+	}
+	secret, err := base64.RawURLEncoding.DecodeString(enc)
+	if err != nil {
+		return Key{}, ErrInvalidKey
+	}
+	key, err := k.Store.Get(ctx, id)
+	if err != nil {
+		return Key{}, err
+	}
+	if subtle.ConstantTimeCompare(key.Hash, hash(secret)) != 1 {
+		return Key{}, ErrInvalidKey
+	}
+	return key, nil
+}
+
+// FromRequest resolves the key in r's Authorization bearer token or, if
+// there is none in the "<id>.<secret>" form, in the configured header.
+// Other bearer tokens, such as JWTs, are left to other authenticators.
+func (k *Keys) FromRequest(r *http.Request) (Key, error) {
+	s, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || !isKey(s) {
+		header := k.Header
+		if header == "" {
+			header = DefaultHeader
+		}
+		s = r.Header.Get(header)
+	}
+	if s == "" {
+		return Key{}, ErrNoKey
+	}
+	return k.Resolve(r.Context(), s)
+}
+
+// isKey reports whether s has the form of a key minted by Mint.
+func isKey(s string) bool {
+	id, enc, ok := strings.Cut(s, ".")
+	if !ok || len(id) != 16 || len(enc) != 32 {
+		return false
+	}
+	if _, err := hex.DecodeString(id); err != nil {
+		return false
+	}
+	_, err := base64.RawURLEncoding.DecodeString(enc)
+	return err == nil
+}
+
+// Check reports whether the key presented with r has relation on object.
+func (k *Keys) Check(r *http.Request, relation string, object authz.Object) (bool, error) {
+	key, err := k.FromRequest(r)
+	if err != nil {
+		return false, err
+	}
+	return k.Client.CheckRef(r.Context(), key.Principal(), relation, object)
+}
+
+// Middleware resolves the request's key and stores its principal with
+// authz.WithUser, so Client.Require checks as the key. Requests without a
+// key pass through unchanged; requests with an invalid one get 401.
+func (k *Keys) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, err := k.FromRequest(r)
+		switch {
+		case errors.Is(err, ErrNoKey):
+		case err != nil:
+			http.Error(w, "invalid API key", http.StatusUnauthorized)
+			return
+		default:
+			r = r.WithContext(authz.WithUser(r.Context(), key.Principal().String()))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Revoke deletes the key and then every tuple naming it, as user or as
+// object. The key stops resolving first, so it is unusable even if the
+// tuple cleanup fails; call Revoke again to finish it.
+func (k *Keys) Revoke(ctx context.Context, id string) error {
+	if err := k.Store.Delete(ctx, id); err != nil {
+		return err
+	}
+	principal := authz.User{Type: KeyType, ID: id}.String()
+	// The server filters by user only together with an object type, so
+	// read the whole store and filter here.
+	tuples, err := authz.ReadAll(ctx, k.Client.Backend(), authz.Tuple{})
+	if err != nil {
+		return fmt.Errorf("apikeys: %w", err)
+	}
+	var deletes []authz.Tuple
+	for _, t := range tuples {
+		if t.User == principal || t.Object == principal {
+			deletes = append(deletes, t)
+		}
+	}
+	return authz.WriteBatched(ctx, k.Client, nil, deletes).Err()
+}
+
+// Owned returns the keys owned by owner.
+func (k *Keys) Owned(ctx context.Context, owner authz.User) ([]Key, error) {
+	all, err := k.Store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []Key
+	for _, key := range all {
+		if key.Owner == owner.String() {
+			out = append(out, key)
+		}
+	}
+	return out, nil
+}
+
+func (k *Keys) now() time.Time {
+	if k.Now != nil {
+		return k.Now()
+	}
+	return time.Now()
+}
+
+func hash(secret []byte) []byte {
+	sum := sha256.Sum256(secret)
+	return sum[:]
+}
@@ -0,0 +1,351 @@
+// Package delegation lets a user act on behalf of another: A delegates some
+// of their relations to B, on one object or on every object of a type, for
+// a bounded time.
+//
+// Apply adds the relations the pattern needs to a model. For document#editor
+// that is:
+//
+//	type user
+//	  relations
+//	    define document_editor_delegate: [user]
+//	type document
+//	  relations
+//	    define editor_delegate: [user]
+//	    define editor_or_delegate: editor or editor_delegate or document_editor_delegate from editor
+//
+// Applications check editor_or_delegate where delegates should be accepted.
+// Type-wide delegations follow the delegator's own editor tuples, so they
+// end as soon as the delegator loses access. Object delegations are checked
+// when granted and by Revalidate.
+package delegation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// Relation names generated for relation rel of objectType.
+func objectDelegate(rel string) string           { return rel + "_delegate" }
+func typeDelegate(objectType, rel string) string { return objectType + "_" + rel + "_delegate" }
+func effective(rel string) string                { return rel + "_or_delegate" }
+
+// Effective returns the relation to check so delegates of rel are accepted.
+func Effective(rel string) string { return effective(rel) }
+
+// Apply adds delegation relations for rels of objectType to m. Type-wide
+// delegation needs rel to be directly assignable only; for other relations
+// only object delegation is generated and Apply says so in its result.
+func Apply(m *model.Model, userType, objectType string, rels ...string) (objectOnly []string, err error) {
+	ut, ot := m.Type(userType), m.Type(objectType)
+	if ut == nil || ot == nil {
+		return nil, fmt.Errorf("delegation: model lacks type %s or %s", userType, objectType)
+	}
+	direct := &model.Direct{Types: []model.TypeRef{{Type: userType}}}
+	for _, rel := range rels {
+		r := ot.Relation(rel)
+		if r == nil {
+			return nil, fmt.Errorf("delegation: %s#%s is not in the model", objectType, rel)
+		}
+		children := []model.Rewrite{&model.Computed{Relation: rel}, &model.Computed{Relation: objectDelegate(rel)}}
+		if d, ok := r.Rewrite.(*model.Direct); ok && hasPlainType(d, userType) {
+			ut.Relations = append(ut.Relations, &model.Relation{Name: typeDelegate(objectType, rel), Rewrite: direct})
+			children = append(children, &model.TupleToUserset{Tupleset: rel, Computed: typeDelegate(objectType, rel)})
+		} else {
+			objectOnly = append(objectOnly, rel)
+		}
+		ot.Relations = append(ot.Relations,
+			&model.Relation{Name: objectDelegate(rel), Rewrite: direct},
+			&model.Relation{Name: effective(rel), Rewrite: &model.Union{Children: children}})
+	}
+	return objectOnly, m.Validate()
+}
+
+func hasPlainType(d *model.Direct, typ string) bool {
+	for _, ref := range d.Types {
+		if ref.Type == typ && ref.Relation == "" && !ref.Wildcard {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	// ErrNotHeld is returned when the delegator does not hold a relation
+	// they try to delegate.
+	ErrNotHeld = errors.New("delegation: delegator does not hold the relation")
+	// ErrExpiry is returned for a delegation without an expiry, or one
+	// beyond Manager.MaxTTL.
+	ErrExpiry = errors.New("delegation: expiry required within MaxTTL")
+	// ErrNotFound is returned by Revoke for an unknown delegation.
+	ErrNotFound = errors.New("delegation: no such delegation")
+)
+
+// Delegation is one grant from Delegator to Delegate. Object is empty for
+// a type-wide delegation.
+type Delegation struct {
+	ID         string    `json:"id"`
+	Delegator  string    `json:"delegator"`
+	Delegate   string    `json:"delegate"`
+	ObjectType string    `json:"object_type"`
+	Object     string    `json:"object,omitempty"`
+	Relations  []string  `json:"relations"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+func (d Delegation) tuples() []authz.Tuple {
+	var out []authz.Tuple
+	for _, rel := range d.Relations {
+		if d.Object != "" {
+			out = append(out, authz.Tuple{User: d.Delegate, Relation: objectDelegate(rel), Object: d.Object})
+		} else {
+			out = append(out, authz.Tuple{User: d.Delegate, Relation: typeDelegate(d.ObjectType, rel), Object: d.Delegator})
+		}
+	}
+	return out
+}
+
+// Store persists delegations.
+type Store interface {
+	Put(ctx context.Context, d Delegation) error
+	Delete(ctx context.Context, id string) error
+	List(ctx context.Context) ([]Delegation, error)
+}
+
+// MemoryStore is a Store for tests and single-process use.
+type MemoryStore struct {
+	mu sync.Mutex
+	m  map[string]Delegation
+}
+
+func (s *MemoryStore) Put(_ context.Context, d Delegation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.m == nil {
+		s.m = map[string]Delegation{}
+	}
+	s.m[d.ID] = d
+	return nil
+}
+
+func (s *MemoryStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.m, id)
+	return nil
+}
+
+func (s *MemoryStore) List(context.Context) ([]Delegation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Delegation, 0, len(s.m))
+	for _, d := range s.m {
+		out = append(out, d)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+// Manager grants and revokes delegations.
+type Manager struct {
+	Client *authz.Client
+	Store  Store
+	// MaxTTL caps a delegation's lifetime; default 30 days.
+	MaxTTL time.Duration
+	// Now defaults to time.Now.
+	Now func() time.Time
+}
+
+func (m *Manager) now() time.Time {
+	if m.Now != nil {
+		return m.Now()
+	}
+	return time.Now()
+}
+
+// Delegate grants rels on object from delegator to delegate until ttl
+// elapses. The delegator must currently hold every relation.
+func (m *Manager) Delegate(ctx context.Context, delegator, delegate authz.User, object authz.Object, ttl time.Duration, rels ...string) (Delegation, error) {
+	for _, rel := range rels {
+		ok, err := m.Client.CheckRef(ctx, delegator, rel, object)
+		if err != nil {
+			return Delegation{}, err
+		}
+		if !ok {
+			return Delegation{}, fmt.Errorf("%w: %s#%s@%s", ErrNotHeld, object, rel, delegator)
+		}
+	}
+	return m.grant(ctx, Delegation{Delegator: delegator.String(), Delegate: delegate.String(), ObjectType: object.Type, Object: object.String(), Relations: rels}, ttl)
+}
+
+// DelegateType grants rels on every object of objectType on which the
+// delegator holds them, now or later, until ttl elapses.
+func (m *Manager) DelegateType(ctx context.Context, delegator, delegate authz.User, objectType string, ttl time.Duration, rels ...string) (Delegation, error) {
+	return m.grant(ctx, Delegation{Delegator: delegator.String(), Delegate: delegate.String(), ObjectType: objectType, Relations: rels}, ttl)
+}
+
+func (m *Manager) grant(ctx context.Context, d Delegation, ttl time.Duration) (Delegation, error) {
+	max := m.MaxTTL
+	if max <= 0 {
+		max = 30 * 24 * time.Hour
+	}
+	if ttl <= 0 || ttl > max {
+		return Delegation{}, fmt.Errorf("%w: %s (max %s)", ErrExpiry, ttl, max)
+	}
+	d.ID, d.CreatedAt = newID(), m.now()
+	d.ExpiresAt = d.CreatedAt.Add(ttl)
+	var writes []authz.Tuple
+	for _, t := range d.tuples() {
+		existing, _, err := m.Client.Backend().Read(ctx, t, "")
+		if err != nil {
+			return Delegation{}, err
+		}
+		if len(existing) == 0 {
+			writes = append(writes, t)
+		}
+	}
+	if err := m.Store.Put(ctx, d); err != nil {
+		return Delegation{}, err
+	}
+	if len(writes) > 0 {
+		if err := m.Client.Write(ctx, writes, nil); err != nil {
+			m.Store.Delete(ctx, d.ID)
+			return Delegation{}, err
+		}
+	}
+	return d, nil
+}
+
+// Revoke ends a delegation.
+func (m *Manager) Revoke(ctx context.Context, id string) error {
+	all, err := m.Store.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, d := range all {
+		if d.ID == id {
+			return m.end(ctx, d, all)
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrNotFound, id)
+}
+
+// List returns the delegations involving user as delegator or delegate.
+func (m *Manager) List(ctx context.Context, user authz.User) ([]Delegation, error) {
+	all, err := m.Store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []Delegation
+	for _, d := range all {
+		if d.Delegator == user.String() || d.Delegate == user.String() {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+// Sweep ends every expired delegation and returns how many ended.
+func (m *Manager) Sweep(ctx context.Context) (int, error) {
+	return m.endWhere(ctx, func(d Delegation) (bool, error) {
+		return !m.now().Before(d.ExpiresAt), nil
+	})
+}
+
+// Revalidate ends object delegations whose delegator no longer holds a
+// delegated relation. Type-wide delegations need no revalidation.
+func (m *Manager) Revalidate(ctx context.Context) (int, error) {
+	return m.endWhere(ctx, func(d Delegation) (bool, error) {
+		if d.Object == "" {
+			return false, nil
+		}
+		for _, rel := range d.Relations {
+			ok, err := m.Client.Check(ctx, d.Delegator, rel, d.Object)
+			if err != nil || !ok {
+				return err == nil, err
+			}
+		}
+		return false, nil
+	})
+}
+
+func (m *Manager) endWhere(ctx context.Context, stale func(Delegation) (bool, error)) (int, error) {
+	all, err := m.Store.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	live := all
+	for _, d := range all {
+		ok, err := stale(d)
+		if err != nil {
+			return n, err
+		}
+		if !ok {
+			continue
+		}
+		if err := m.end(ctx, d, live); err != nil {
+			return n, err
+		}
+		live = without(live, d.ID)
+		n++
+	}
+	return n, nil
+}
+
+// end deletes d's tuples that no other live delegation still needs, then
+// forgets d.
+func (m *Manager) end(ctx context.Context, d Delegation, all []Delegation) error {
+	needed := map[authz.Tuple]bool{}
+	for _, o := range all {
+		if o.ID != d.ID && m.now().Before(o.ExpiresAt) {
+			for _, t := range o.tuples() {
+				needed[t] = true
+			}
+		}
+	}
+	var deletes []authz.Tuple
+	for _, t := range d.tuples() {
+		if needed[t] {
+			continue
+		}
+		existing, _, err := m.Client.Backend().Read(ctx, t, "")
+		if err != nil {
+			return err
+		}
+		if len(existing) > 0 {
+			deletes = append(deletes, t)
+		}
+	}
+	if len(deletes) > 0 {
+		if err := m.Client.Write(ctx, nil, deletes); err != nil {
+			return err
+		}
+	}
+	return m.Store.Delete(ctx, d.ID)
+}
+
+func without(ds []Delegation, id string) []Delegation {
+	out := make([]Delegation, 0, len(ds))
+	for _, d := range ds {
+		if d.ID != id {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+func newID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
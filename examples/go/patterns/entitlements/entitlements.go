@@ -0,0 +1,185 @@
+// Package entitlements models plan-based feature access: an organization
+// subscribes to a plan, a plan is associated with features, and an
+// organization — and through it its members — has every feature of its
+// plans.
+package entitlements
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+)
+
+// Fragment is the DSL for the pattern. It expects an organization type with
+// a member relation.
+const Fragment = `type plan
+  relations
+    define subscriber: [organization]
+    define subscriber_member: member from subscriber
+
+type feature
+  relations
+    define associated_plan: [plan]
+    define subscriber: subscriber from associated_plan
+    define can_access: subscriber_member from associated_plan
+`
+
+// Type and relation names used by Fragment.
+const (
+	PlanType       = "plan"
+	FeatureType    = "feature"
+	OrgType        = "organization"
+	subscriber     = "subscriber"
+	associatedPlan = "associated_plan"
+	canAccess      = "can_access"
+)
+
+// Entitlements manages subscriptions and plan contents.
+type Entitlements struct {
+	Client *authz.Client
+}
+
+func org(id string) authz.User       { return authz.User{Type: OrgType, ID: id} }
+func plan(id string) authz.Object    { return authz.NewObject(PlanType, id) }
+func feature(id string) authz.Object { return authz.NewObject(FeatureType, id) }
+
+// HasFeature reports whether organization orgID has feature.
+func (e *Entitlements) HasFeature(ctx context.Context, orgID, featureID string) (bool, error) {
+	return e.Client.CheckRef(ctx, org(orgID), subscriber, feature(featureID))
+}
+
+// UserHasFeature reports whether user has feature through an organization
+// they are a member of.
+func (e *Entitlements) UserHasFeature(ctx context.Context, user authz.User, featureID string) (bool, error) {
+	return e.Client.CheckRef(ctx, user, canAccess, feature(featureID))
+}
+
+// Subscribe adds orgID to planID.
+func (e *Entitlements) Subscribe(ctx context.Context, orgID, planID string) error {
+	return e.Client.Write(ctx, []authz.Tuple{authz.NewTuple(org(orgID), subscriber, plan(planID))}, nil)
+}
+
+// Unsubscribe removes orgID from planID.
+func (e *Entitlements) Unsubscribe(ctx context.Context, orgID, planID string) error {
+	return e.Client.Write(ctx, nil, []authz.Tuple{authz.NewTuple(org(orgID), subscriber, plan(planID))})
+}
+
+// ChangePlan moves orgID from one plan to another in a single write, so the
+// organization is never without a plan.
+func (e *Entitlements) ChangePlan(ctx context.Context, orgID, fromPlan, toPlan string) error {
+	return e.Client.Write(ctx,
+		[]authz.Tuple{authz.NewTuple(org(orgID), subscriber, plan(toPlan))},
+		[]authz.Tuple{authz.NewTuple(org(orgID), subscriber, plan(fromPlan))})
+}
+
+// Plans returns the plans orgID subscribes to, sorted.
+func (e *Entitlements) Plans(ctx context.Context, orgID string) ([]string, error) {
+	tuples, err := authz.ReadAll(ctx, e.Client.Backend(), authz.Tuple{User: org(orgID).String(), Relation: subscriber, Object: PlanType + ":"})
+	if err != nil {
+		return nil, fmt.Errorf("entitlements: %w", err)
+	}
+	return objectIDs(tuples), nil
+}
+
+// Subscribers returns the organizations subscribed to planID, sorted.
+func (e *Entitlements) Subscribers(ctx context.Context, planID string) ([]string, error) {
+	tuples, err := authz.ReadAll(ctx, e.Client.Backend(), authz.Tuple{Relation: subscriber, Object: plan(planID).String()})
+	if err != nil {
+		return nil, fmt.Errorf("entitlements: %w", err)
+	}
+	ids := make([]string, 0, len(tuples))
+	for _, t := range tuples {
+		if u, err := t.UserRef(); err == nil && u.Type == OrgType {
+			ids = append(ids, u.ID)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// MigrateSubscribers moves every subscriber of fromPlan to toPlan, e.g. when
+// a plan is retired. Each organization is moved in its own write so a
+// failure leaves every organization on exactly one of the two plans. It
+// returns the number moved.
+func (e *Entitlements) MigrateSubscribers(ctx context.Context, fromPlan, toPlan string) (int, error) {
+	orgs, err := e.Subscribers(ctx, fromPlan)
+	if err != nil {
+		return 0, err
+	}
+	current, err := e.Subscribers(ctx, toPlan)
+	if err != nil {
+		return 0, err
+	}
+	already := map[string]bool{}
+	for _, o := range current {
+		already[o] = true
+	}
+	for i, o := range orgs {
+		var err error
+		if already[o] {
+			err = e.Unsubscribe(ctx, o, fromPlan)
+		} else {
+			err = e.ChangePlan(ctx, o, fromPlan, toPlan)
+		}
+		if err != nil {
+			return i, fmt.Errorf("entitlements: migrate %s: %w", o, err)
+		}
+	}
+	return len(orgs), nil
+}
+
+// Features returns the features associated with planID, sorted.
+func (e *Entitlements) Features(ctx context.Context, planID string) ([]string, error) {
+	tuples, err := authz.ReadAll(ctx, e.Client.Backend(), authz.Tuple{User: plan(planID).String(), Relation: associatedPlan, Object: FeatureType + ":"})
+	if err != nil {
+		return nil, fmt.Errorf("entitlements: %w", err)
+	}
+	return objectIDs(tuples), nil
+}
+
+// SetFeatures makes features the exact feature set of planID, writing only
+// the difference. It returns how many associations were added and removed.
+func (e *Entitlements) SetFeatures(ctx context.Context, planID string, features []string) (added, removed int, err error) {
+	current, err := e.Features(ctx, planID)
+	if err != nil {
+		return 0, 0, err
+	}
+	have := map[string]bool{}
+	for _, f := range current {
+		have[f] = true
+	}
+	want := map[string]bool{}
+	var writes, deletes []authz.Tuple
+	p := plan(planID)
+	for _, f := range features {
+		if want[f] {
+			continue
+		}
+		want[f] = true
+		if !have[f] {
+			writes = append(writes, authz.NewTuple(authz.User{Type: p.Type, ID: p.ID}, associatedPlan, feature(f)))
+		}
+	}
+	for _, f := range current {
+		if !want[f] {
+			deletes = append(deletes, authz.NewTuple(authz.User{Type: p.Type, ID: p.ID}, associatedPlan, feature(f)))
+		}
+	}
+	if err := authz.WriteBatched(ctx, e.Client, writes, deletes).Err(); err != nil {
+		return 0, 0, fmt.Errorf("entitlements: %w", err)
+	}
+	return len(writes), len(deletes), nil
+}
+
+func objectIDs(tuples []authz.Tuple) []string {
+	ids := make([]string, 0, len(tuples))
+	for _, t := range tuples {
+		if o, err := t.ObjectRef(); err == nil {
+			ids = append(ids, o.ID)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,252 @@
+// Package invitations models pending organization invites. An invite is an
+// object linking an organization, an inviter, an invitee and the role to
+// grant; accepting it writes the membership and deletes the invite in one
+// transaction, so an invite is never both pending and accepted.
+package invitations
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+)
+
+// Type and relation names used by Fragment.
+const (
+	InviteType   = "invite"
+	organization = "organization"
+	inviter      = "inviter"
+	invitee      = "invitee"
+	grantPrefix  = "grants_"
+)
+
+var (
+	// ErrNotFound is returned for an invite that does not exist or was
+	// already accepted, declined or revoked.
+	ErrNotFound = errors.New("invitations: no such invite")
+	// ErrNotInvitee is returned when someone other than the invitee tries
+	// to accept or decline.
+	ErrNotInvitee = errors.New("invitations: not the invitee")
+	// ErrUnknownRole is returned for a role Fragment was not generated for.
+	ErrUnknownRole = errors.New("invitations: unknown role")
+)
+
+// Fragment returns the DSL for invites into orgType granting one of roles,
+// each a relation on orgType:
+//
+//	type invite
+//	  relations
+//	    define organization: [organization]
+//	    define inviter: [user]
+//	    define invitee: [user]
+//	    define grants_member: [organization]
+//	    define can_accept: invitee
+func Fragment(orgType, userType string, roles ...string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "type %s\n  relations\n", InviteType)
+	fmt.Fprintf(&sb, "    define %s: [%s]\n", organization, orgType)
+	fmt.Fprintf(&sb, "    define %s: [%s]\n", inviter, userType)
+	fmt.Fprintf(&sb, "    define %s: [%s]\n", invitee, userType)
+	for _, role := range roles {
+		fmt.Fprintf(&sb, "    define %s%s: [%s]\n", grantPrefix, role, orgType)
+	}
+	fmt.Fprintf(&sb, "    define can_accept: %s\n", invitee)
+	return sb.String()
+}
+
+// Invite is a pending invite.
+type Invite struct {
+	ID           string
+	Organization authz.Object
+	Inviter      authz.User
+	Invitee      authz.User
+	Role         string
+}
+
+// Object returns the invite object, invite:<id>.
+func (i Invite) Object() authz.Object { return authz.NewObject(InviteType, i.ID) }
+
+func (i Invite) tuples() []authz.Tuple {
+	o := i.Object()
+	return []authz.Tuple{
+		authz.NewTuple(authz.User{Type: i.Organization.Type, ID: i.Organization.ID}, organization, o),
+		authz.NewTuple(i.Inviter, inviter, o),
+		authz.NewTuple(i.Invitee, invitee, o),
+		authz.NewTuple(authz.User{Type: i.Organization.Type, ID: i.Organization.ID}, grantPrefix+i.Role, o),
+	}
+}
+
+// Invitations manages invites.
+type Invitations struct {
+	Client *authz.Client
+	// Roles lists the roles invites may grant; empty allows any.
+	Roles []string
+	// CanInvite is the relation an inviter needs on the organization, e.g.
+	// "admin"; empty skips the check.
+	CanInvite string
+}
+
+// Create invites invitee into org with role on behalf of from.
+func (inv *Invitations) Create(ctx context.Context, from authz.User, org authz.Object, to authz.User, role string) (Invite, error) {
+	if len(inv.Roles) > 0 && !slices.Contains(inv.Roles, role) {
+		return Invite{}, fmt.Errorf("%w: %s", ErrUnknownRole, role)
+	}
+	if err := inv.authorize(ctx, from, org); err != nil {
+		return Invite{}, err
+	}
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return Invite{}, fmt.Errorf("invitations: %w", err)
+	}
+	i := Invite{ID: hex.EncodeToString(id[:]), Organization: org, Inviter: from, Invitee: to, Role: role}
+	if err := inv.Client.Write(ctx, i.tuples(), nil); err != nil {
+		return Invite{}, err
+	}
+	return i, nil
+}
+
+func (inv *Invitations) authorize(ctx context.Context, user authz.User, org authz.Object) error {
+	if inv.CanInvite == "" {
+		return nil
+	}
+	ok, err := inv.Client.CheckRef(ctx, user, inv.CanInvite, org)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s may not invite to %s", authz.ErrForbidden, user, org)
+	}
+	return nil
+}
+
+// Get returns the pending invite with id.
+func (inv *Invitations) Get(ctx context.Context, id string) (Invite, error) {
+	o := authz.NewObject(InviteType, id)
+	tuples, err := authz.ReadAll(ctx, inv.Client.Backend(), authz.Tuple{Object: o.String()})
+	if err != nil {
+		return Invite{}, fmt.Errorf("invitations: %w", err)
+	}
+	if len(tuples) == 0 {
+		return Invite{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	i := Invite{ID: id}
+	for _, t := range tuples {
+		u, err := t.UserRef()
+		if err != nil {
+			return Invite{}, fmt.Errorf("invitations: %s: %w", t, err)
+		}
+		switch {
+		case t.Relation == organization:
+			i.Organization = u.Object()
+		case t.Relation == inviter:
+			i.Inviter = u
+		case t.Relation == invitee:
+			i.Invitee = u
+		case strings.HasPrefix(t.Relation, grantPrefix):
+			i.Role = strings.TrimPrefix(t.Relation, grantPrefix)
+		}
+	}
+	if i.Organization.Type == "" || i.Invitee.Type == "" || i.Role == "" {
+		return Invite{}, fmt.Errorf("invitations: invite %s is incomplete", id)
+	}
+	return i, nil
+}
+
+// Accept makes user, who must be the invitee, a holder of the invite's
+// role and deletes the invite, in a single write. Accepting into a role
+// the user already holds only deletes the invite.
+func (inv *Invitations) Accept(ctx context.Context, id string, user authz.User) error {
+	i, err := inv.invitee(ctx, id, user)
+	if err != nil {
+		return err
+	}
+	membership := authz.NewTuple(user, i.Role, i.Organization)
+	existing, _, err := inv.Client.Backend().Read(ctx, membership, "")
+	if err != nil {
+		return fmt.Errorf("invitations: %w", err)
+	}
+	var writes []authz.Tuple
+	if len(existing) == 0 {
+		writes = []authz.Tuple{membership}
+	}
+	return inv.Client.Write(ctx, writes, i.tuples())
+}
+
+// Decline deletes the invite on behalf of user, who must be the invitee.
+func (inv *Invitations) Decline(ctx context.Context, id string, user authz.User) error {
+	i, err := inv.invitee(ctx, id, user)
+	if err != nil {
+		return err
+	}
+	return inv.Client.Write(ctx, nil, i.tuples())
+}
+
+// Revoke deletes the invite on behalf of by, who must be its inviter or
+// hold CanInvite on the organization.
+func (inv *Invitations) Revoke(ctx context.Context, id string, by authz.User) error {
+	i, err := inv.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if by != i.Inviter {
+		if inv.CanInvite == "" {
+			return fmt.Errorf("%w: %s did not send invite %s", authz.ErrForbidden, by, id)
+		}
+		if err := inv.authorize(ctx, by, i.Organization); err != nil {
+			return err
+		}
+	}
+	return inv.Client.Write(ctx, nil, i.tuples())
+}
+
+func (inv *Invitations) invitee(ctx context.Context, id string, user authz.User) (Invite, error) {
+	i, err := inv.Get(ctx, id)
+	if err != nil {
+		return Invite{}, err
+	}
+	if i.Invitee != user {
+		return Invite{}, fmt.Errorf("%w: invite %s", ErrNotInvitee, id)
+	}
+	return i, nil
+}
+
+// Pending returns the pending invites into org, sorted by ID.
+func (inv *Invitations) Pending(ctx context.Context, org authz.Object) ([]Invite, error) {
+	orgUser := authz.User{Type: org.Type, ID: org.ID}.String()
+	return inv.list(ctx, authz.Tuple{User: orgUser, Relation: organization, Object: InviteType + ":"})
+}
+
+// For returns the pending invites addressed to user, sorted by ID.
+func (inv *Invitations) For(ctx context.Context, user authz.User) ([]Invite, error) {
+	return inv.list(ctx, authz.Tuple{User: user.String(), Relation: invitee, Object: InviteType + ":"})
+}
+
+func (inv *Invitations) list(ctx context.Context, filter authz.Tuple) ([]Invite, error) {
+	tuples, err := authz.ReadAll(ctx, inv.Client.Backend(), filter)
+	if err != nil {
+		return nil, fmt.Errorf("invitations: %w", err)
+	}
+	var out []Invite
+	for _, t := range tuples {
+		o, err := t.ObjectRef()
+		if err != nil || o.Type != InviteType {
+			continue
+		}
+		i, err := inv.Get(ctx, o.ID)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, i)
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].ID < out[b].ID })
+	return out, nil
+}
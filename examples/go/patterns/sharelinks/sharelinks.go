@@ -0,0 +1,160 @@
+// Package sharelinks implements "anyone with the link" sharing: a link
+// object is granted a relation on a document, and whoever presents the
+// link's token is checked as that link.
+//
+// Tokens are never stored. The link object is named after a hash of its
+// token, so a dump of the store does not yield usable links.
+package sharelinks
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+)
+
+// Defaults for Links.
+const (
+	DefaultType     = "link"
+	DefaultRelation = "viewer"
+)
+
+// ErrBadToken is returned for a token that is not of the form Create
+// produces.
+var ErrBadToken = errors.New("sharelinks: malformed token")
+
+// Fragment returns the DSL for the pattern on typ: the link type and a
+// viewer relation that accepts links.
+//
+//	type link
+//	type document
+//	  relations
+//	    define viewer: [user, link]
+func Fragment(typ, userType string) string {
+	return fmt.Sprintf(`type %[3]s
+
+type %[1]s
+  relations
+    define %[4]s: [%[2]s, %[3]s]
+`, typ, userType, DefaultType, DefaultRelation)
+}
+
+// Link is an active share link. Token is set only on the value returned by
+// Create; afterwards the link is known by its ID alone.
+type Link struct {
+	ID       string
+	Token    string
+	Object   authz.Object
+	Relation string
+}
+
+// Links manages share links.
+type Links struct {
+	Client *authz.Client
+	// Type is the link type; default DefaultType.
+	Type string
+	// Relation is granted to links; default DefaultRelation.
+	Relation string
+}
+
+func (l *Links) typ() string {
+	if l.Type == "" {
+		return DefaultType
+	}
+	return l.Type
+}
+
+func (l *Links) relation() string {
+	if l.Relation == "" {
+		return DefaultRelation
+	}
+	return l.Relation
+}
+
+func (l *Links) user(id string) authz.User { return authz.User{Type: l.typ(), ID: id} }
+
+// ID returns the link ID for token.
+func ID(token string) (string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) != 32 {
+		return "", ErrBadToken
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:16]), nil
+}
+
+// Create mints a link to object and returns it with its token, which the
+// caller hands out and must not log.
+func (l *Links) Create(ctx context.Context, object authz.Object) (Link, error) {
+	var raw [32]byte
+	if _, err := rand.Read(raw[:]); err != nil {
+		return Link{}, fmt.Errorf("sharelinks: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw[:])
+	id, _ := ID(token)
+	link := Link{ID: id, Token: token, Object: object, Relation: l.relation()}
+	if err := l.Client.Write(ctx, []authz.Tuple{authz.NewTuple(l.user(id), link.Relation, object)}, nil); err != nil {
+		return Link{}, err
+	}
+	return link, nil
+}
+
+// Check reports whether token grants relation on object, e.g. the
+// viewer or a relation computed from it. Malformed tokens are denied, not errors.
+func (l *Links) Check(ctx context.Context, token, relation string, object authz.Object) (bool, error) {
+	id, err := ID(token)
+	if err != nil {
+		return false, nil
+	}
+	return l.Client.CheckRef(ctx, l.user(id), relation, object)
+}
+
+// Revoke deletes the link with id from object. Revoking an unknown link is
+// not an error.
+func (l *Links) Revoke(ctx context.Context, id string, object authz.Object) error {
+	t := authz.NewTuple(l.user(id), l.relation(), object)
+	existing, _, err := l.Client.Backend().Read(ctx, t, "")
+	if err != nil {
+		return fmt.Errorf("sharelinks: %w", err)
+	}
+	if len(existing) == 0 {
+		return nil
+	}
+	return l.Client.Write(ctx, nil, []authz.Tuple{t})
+}
+
+// RevokeAll deletes every link to object and returns how many there were,
+// e.g. when the document's owner turns link sharing off.
+func (l *Links) RevokeAll(ctx context.Context, object authz.Object) (int, error) {
+	links, err := l.List(ctx, object)
+	if err != nil {
+		return 0, err
+	}
+	deletes := make([]authz.Tuple, len(links))
+	for i, link := range links {
+		deletes[i] = authz.NewTuple(l.user(link.ID), link.Relation, object)
+	}
+	return len(deletes), authz.WriteBatched(ctx, l.Client, nil, deletes).Err()
+}
+
+// List returns the active links to object, sorted by ID.
+func (l *Links) List(ctx context.Context, object authz.Object) ([]Link, error) {
+	tuples, err := authz.ReadAll(ctx, l.Client.Backend(), authz.Tuple{Relation: l.relation(), Object: object.String()})
+	if err != nil {
+		return nil, fmt.Errorf("sharelinks: %w", err)
+	}
+	var links []Link
+	for _, t := range tuples {
+		if u, err := t.UserRef(); err == nil && u.Type == l.typ() && !u.IsUserset() {
+			links = append(links, Link{ID: u.ID, Object: object, Relation: t.Relation})
+		}
+	}
+	sort.Slice(links, func(i, j int) bool { return links[i].ID < links[j].ID })
+	return links, nil
+}
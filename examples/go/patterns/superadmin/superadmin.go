@@ -0,0 +1,381 @@
+// Package superadmin gives platform operators access to every object
+// through a single system object, and adds an audited break-glass grant
+// that always expires.
+//
+// Every protected type links to the system object and lets its admins in:
+//
+//	type document
+//	  relations
+//	    define system: [system]
+//	    define owner: [user]
+//	    define can_admin: owner or platform_admin from system
+//
+// and every object is created together with its LinkTuple. Break-glass
+// tuples carry the not_expired condition, so checks must send the
+// current_time parameter:
+//
+//	az := authz.New(backend, authz.WithContextProviders(authz.CurrentTime("current_time")))
+package superadmin
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+)
+
+// Fragment is the DSL for the system type. break_glass holders count as
+// platform admins until their grant is revoked or the server finds it
+// expired.
+const Fragment = `type system
+  relations
+    define admin: [user]
+    define break_glass: [user with not_expired]
+    define platform_admin: admin or break_glass
+
+condition not_expired(current_time: timestamp, expires_at: timestamp) {
+  current_time < expires_at
+}
+`
+
+// System is the default system object.
+var System = authz.NewObject("system", "platform")
+
+// LinkTuple returns the tuple connecting object to the system object.
+// Write it with the object's other initial tuples.
+func LinkTuple(object authz.Object) authz.Tuple {
+	return authz.NewTuple(authz.User{Type: System.Type, ID: System.ID}, "system", object)
+}
+
+// AddAdmin makes user a permanent platform admin.
+func AddAdmin(ctx context.Context, c *authz.Client, user authz.User) error {
+	return c.Write(ctx, []authz.Tuple{authz.NewTuple(user, "admin", System)}, nil)
+}
+
+// RemoveAdmin revokes user's permanent platform admin role.
+func RemoveAdmin(ctx context.Context, c *authz.Client, user authz.User) error {
+	return c.Write(ctx, nil, []authz.Tuple{authz.NewTuple(user, "admin", System)})
+}
+
+// IsAdmin reports whether user is a platform admin, permanently or through
+// break-glass. It sends the current time unless ctx already carries one.
+func IsAdmin(ctx context.Context, c *authz.Client, user authz.User) (bool, error) {
+	if _, ok := authz.ConditionContextFrom(ctx)["current_time"]; !ok {
+		ctx = authz.WithConditionContext(ctx, map[string]any{"current_time": time.Now()})
+	}
+	return c.CheckRef(ctx, user, "platform_admin", System)
+}
+
+var (
+	// ErrNoReason is returned for a break-glass request without a reason.
+	ErrNoReason = errors.New("superadmin: break-glass requires a reason")
+	// ErrNoExpiry is returned for a break-glass request without a TTL, or
+	// with one longer than BreakGlass.MaxTTL.
+	ErrNoExpiry = errors.New("superadmin: break-glass requires an expiry within MaxTTL")
+	// ErrNoGrant is returned by Revoke for an unknown grant.
+	ErrNoGrant = errors.New("superadmin: no such grant")
+)
+
+// Grant is one break-glass grant.
+type Grant struct {
+	ID        string    `json:"id"`
+	User      string    `json:"user"`
+	Reason    string    `json:"reason"`
+	GrantedBy string    `json:"granted_by,omitempty"`
+	GrantedAt time.Time `json:"granted_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// GrantStore persists active grants so expiry survives restarts.
+type GrantStore interface {
+	Put(ctx context.Context, g Grant) error
+	Delete(ctx context.Context, id string) error
+	List(ctx context.Context) ([]Grant, error)
+}
+
+// MemoryGrantStore is a GrantStore for tests and single-process use.
+type MemoryGrantStore struct {
+	mu     sync.Mutex
+	grants map[string]Grant
+}
+
+func (s *MemoryGrantStore) Put(_ context.Context, g Grant) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.grants == nil {
+		s.grants = map[string]Grant{}
+	}
+	s.grants[g.ID] = g
+	return nil
+}
+
+func (s *MemoryGrantStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.grants, id)
+	return nil
+}
+
+func (s *MemoryGrantStore) List(context.Context) ([]Grant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Grant, 0, len(s.grants))
+	for _, g := range s.grants {
+		out = append(out, g)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].GrantedAt.Before(out[j].GrantedAt) })
+	return out, nil
+}
+
+// Audit actions.
+const (
+	ActionGranted = "break_glass.granted"
+	ActionRevoked = "break_glass.revoked"
+	ActionExpired = "break_glass.expired"
+	// ActionFailed follows ActionGranted when the grant could not be put
+	// into effect, so the audit trail does not show access that never
+	// existed.
+	ActionFailed = "break_glass.failed"
+)
+
+// AuditEvent records a break-glass state change.
+type AuditEvent struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"`
+	Actor  string    `json:"actor,omitempty"`
+	Grant  Grant     `json:"grant"`
+	// Error is set on ActionFailed events.
+	Error string `json:"error,omitempty"`
+}
+
+// Auditor receives audit events. A failing auditor blocks the grant: no
+// break-glass access without a record of it.
+type Auditor interface {
+	Audit(ctx context.Context, ev AuditEvent) error
+}
+
+// AuditorFunc adapts a function to Auditor.
+type AuditorFunc func(ctx context.Context, ev AuditEvent) error
+
+func (f AuditorFunc) Audit(ctx context.Context, ev AuditEvent) error { return f(ctx, ev) }
+
+// BreakGlass issues time-boxed platform admin grants. The break_glass
+// tuple carries the latest expiry of the user's grants in its not_expired
+// condition, so the server stops honouring it on time; Sweep, which Run
+// calls periodically, only removes expired tuples and records their expiry.
+type BreakGlass struct {
+	Client *authz.Client
+	Store  GrantStore
+	Audit  Auditor
+	// MaxTTL caps a grant's lifetime; default 4h.
+	MaxTTL time.Duration
+	// Now defaults to time.Now.
+	Now func() time.Time
+	// Logger receives Run's sweep results; default slog.Default().
+	Logger *slog.Logger
+}
+
+func (b *BreakGlass) now() time.Time {
+	if b.Now != nil {
+		return b.Now()
+	}
+	return time.Now()
+}
+
+// Grant gives user platform admin access for ttl. The requester is taken
+// from authz.UserFromContext and recorded with the reason.
+func (b *BreakGlass) Grant(ctx context.Context, user authz.User, reason string, ttl time.Duration) (Grant, error) {
+	if reason == "" {
+		return Grant{}, ErrNoReason
+	}
+	max := b.MaxTTL
+	if max <= 0 {
+		max = 4 * time.Hour
+	}
+	if ttl <= 0 || ttl > max {
+		return Grant{}, fmt.Errorf("%w: %s (max %s)", ErrNoExpiry, ttl, max)
+	}
+	actor, _ := authz.UserFromContext(ctx)
+	now := b.now()
+	id, err := newID()
+	if err != nil {
+		return Grant{}, err
+	}
+	g := Grant{ID: id, User: user.String(), Reason: reason, GrantedBy: actor, GrantedAt: now, ExpiresAt: now.Add(ttl)}
+	if err := b.Audit.Audit(ctx, AuditEvent{Time: now, Action: ActionGranted, Actor: actor, Grant: g}); err != nil {
+		return Grant{}, fmt.Errorf("superadmin: audit: %w", err)
+	}
+	if err := b.Store.Put(ctx, g); err != nil {
+		return Grant{}, b.failed(ctx, g, actor, fmt.Errorf("superadmin: store grant: %w", err))
+	}
+	if err := b.setTuple(ctx, user, g.User); err != nil {
+		b.Store.Delete(ctx, g.ID)
+		return Grant{}, b.failed(ctx, g, actor, err)
+	}
+	return g, nil
+}
+
+// failed audits that g did not take effect and returns err, joined with
+// the audit's own error if that fails too.
+func (b *BreakGlass) failed(ctx context.Context, g Grant, actor string, err error) error {
+	ev := AuditEvent{Time: b.now(), Action: ActionFailed, Actor: actor, Grant: g, Error: err.Error()}
+	if aerr := b.Audit.Audit(ctx, ev); aerr != nil {
+		return errors.Join(err, fmt.Errorf("superadmin: audit: %w", aerr))
+	}
+	return err
+}
+
+// setTuple makes the user's break_glass tuple expire with the last of
+// their live grants, or removes it when none is left.
+func (b *BreakGlass) setTuple(ctx context.Context, user authz.User, name string) error {
+	grants, err := b.Store.List(ctx)
+	if err != nil {
+		return err
+	}
+	var until time.Time
+	for _, g := range grants {
+		if g.User == name && b.now().Before(g.ExpiresAt) && g.ExpiresAt.After(until) {
+			until = g.ExpiresAt
+		}
+	}
+	t := authz.NewTuple(user, "break_glass", System)
+	if !until.IsZero() {
+		if t.Condition, err = authz.NewTupleCondition("not_expired", map[string]any{"expires_at": until.UTC().Format(time.RFC3339)}); err != nil {
+			return err
+		}
+	}
+	existing, _, err := b.Client.Backend().Read(ctx, t.Key(), "")
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		if !until.IsZero() && sameExpiry(existing[0].Condition, t.Condition) {
+			return nil
+		}
+		// Guards must never keep emergency access alive. The server keeps
+		// one tuple per key, so moving the expiry replaces the tuple.
+		if err := b.Client.Write(authz.BypassWriteGuards(ctx), nil, []authz.Tuple{t.Key()}); err != nil {
+			return err
+		}
+	}
+	if until.IsZero() {
+		return nil
+	}
+	return b.Client.Write(ctx, []authz.Tuple{t}, nil)
+}
+
+func sameExpiry(a, b authz.TupleCondition) bool {
+	av, err := a.Values()
+	if err != nil || a.Name != b.Name {
+		return false
+	}
+	bv, err := b.Values()
+	return err == nil && av["expires_at"] == bv["expires_at"]
+}
+
+// Revoke ends a grant early.
+func (b *BreakGlass) Revoke(ctx context.Context, id string) error {
+	grants, err := b.Store.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, g := range grants {
+		if g.ID == id {
+			actor, _ := authz.UserFromContext(ctx)
+			return b.end(ctx, g, ActionRevoked, actor)
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrNoGrant, id)
+}
+
+// Active returns the grants that have not expired.
+func (b *BreakGlass) Active(ctx context.Context) ([]Grant, error) {
+	grants, err := b.Store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := b.now()
+	var out []Grant
+	for _, g := range grants {
+		if now.Before(g.ExpiresAt) {
+			out = append(out, g)
+		}
+	}
+	return out, nil
+}
+
+// Sweep removes the tuples of expired grants, which the server already
+// ignores, audits their expiry and returns how many grants it ended.
+func (b *BreakGlass) Sweep(ctx context.Context) (int, error) {
+	grants, err := b.Store.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	now := b.now()
+	n := 0
+	for _, g := range grants {
+		if now.Before(g.ExpiresAt) {
+			continue
+		}
+		if err := b.end(ctx, g, ActionExpired, ""); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Run sweeps every interval until ctx is done. A failed sweep is logged
+// and retried on the next tick.
+func (b *BreakGlass) Run(ctx context.Context, interval time.Duration) error {
+	logger := b.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		n, err := b.Sweep(ctx)
+		if err != nil && ctx.Err() == nil {
+			logger.WarnContext(ctx, "break-glass sweep failed", "ended", n, "error", err)
+		} else if n > 0 {
+			logger.InfoContext(ctx, "expired break-glass grants ended", "ended", n)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+func (b *BreakGlass) end(ctx context.Context, g Grant, action, actor string) error {
+	user, err := authz.ParseUser(g.User)
+	if err != nil {
+		return err
+	}
+	if err := b.Store.Delete(ctx, g.ID); err != nil {
+		return err
+	}
+	// Another live grant for the same user keeps the tuple, expiring with it.
+	if err := b.setTuple(ctx, user, g.User); err != nil {
+		b.Store.Put(ctx, g)
+		return err
+	}
+	return b.Audit.Audit(ctx, AuditEvent{Time: b.now(), Action: action, Actor: actor, Grant: g})
+}
+
+func newID() (string, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("superadmin: grant id: %w", err)
+	}
+	return hex.EncodeToString(b[:]), nil
+}
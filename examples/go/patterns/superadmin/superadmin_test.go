@@ -0,0 +1,70 @@
+package superadmin_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/eval"
+	"github.com/bogdanticu88/openfga-examples/model"
+	"github.com/bogdanticu88/openfga-examples/patterns/superadmin"
+)
+
+func TestGrantPassesModelValidation(t *testing.T) {
+	m, err := model.Parse("superadmin.fga", []byte("model\n  schema 1.1\n\ntype user\n\n"+superadmin.Fragment))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := authz.New(eval.New(m, nil), authz.WithModelValidation(0))
+	b := &superadmin.BreakGlass{
+		Client: c,
+		Store:  &superadmin.MemoryGrantStore{},
+		Audit:  superadmin.AuditorFunc(func(context.Context, superadmin.AuditEvent) error { return nil }),
+	}
+	ctx := context.Background()
+	alice := authz.User{Type: "user", ID: "alice"}
+	if _, err := b.Grant(ctx, alice, "incident 42", time.Hour); err != nil {
+		t.Fatalf("Grant: %v", err)
+	}
+	tuples, err := authz.ReadAll(ctx, c.Backend(), authz.Tuple{Relation: "break_glass"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tuples) != 1 || tuples[0].Condition.Name != "not_expired" {
+		t.Fatalf("break_glass tuples = %v, want one with not_expired", tuples)
+	}
+
+	// An unconditioned write of the same relation is still rejected.
+	err = c.Write(ctx, []authz.Tuple{authz.NewTuple(alice, "break_glass", superadmin.System)}, nil)
+	if err == nil {
+		t.Fatal("unconditioned break_glass write passed validation")
+	}
+}
+
+type failingStore struct{ superadmin.MemoryGrantStore }
+
+func (*failingStore) Put(context.Context, superadmin.Grant) error { return errors.New("disk full") }
+
+func TestGrantAuditsFailure(t *testing.T) {
+	m, err := model.Parse("superadmin.fga", []byte("model\n  schema 1.1\n\ntype user\n\n"+superadmin.Fragment))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions []string
+	b := &superadmin.BreakGlass{
+		Client: authz.New(eval.New(m, nil)),
+		Store:  &failingStore{},
+		Audit: superadmin.AuditorFunc(func(_ context.Context, ev superadmin.AuditEvent) error {
+			actions = append(actions, ev.Action)
+			return nil
+		}),
+	}
+	if _, err := b.Grant(context.Background(), authz.User{Type: "user", ID: "alice"}, "incident 42", time.Hour); err == nil {
+		t.Fatal("Grant succeeded with a failing store")
+	}
+	if len(actions) != 2 || actions[0] != superadmin.ActionGranted || actions[1] != superadmin.ActionFailed {
+		t.Fatalf("audited %v, want granted then failed", actions)
+	}
+}
@@ -0,0 +1,244 @@
+// Package playground serves a small web UI for editing a model, writing
+// sample tuples and running checks, for demos and onboarding:
+//
+//	http.ListenAndServe(":3001", playground.New(playground.Config{Model: m}))
+//
+// Without a Backend it runs on the embedded evaluator and the model can be
+// edited in the browser; with one, checks and tuples go to that backend and
+// the model is read-only. The page talks to a JSON API under /api.
+package playground
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/eval"
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+//go:embed static
+var static embed.FS
+
+// ErrReadOnly is returned when the model is edited on a playground backed by
+// a remote store.
+var ErrReadOnly = errors.New("playground: model is read-only with a remote backend")
+
+// maxBody bounds request bodies.
+const maxBody = 1 << 20
+
+// Config configures a playground.
+type Config struct {
+	// Backend serves checks and tuples; nil uses an embedded evaluator over
+	// Model.
+	Backend authz.Backend
+	// Model is the starting model for the embedded evaluator.
+	Model *model.Model
+	// Tuples seed the embedded evaluator.
+	Tuples []authz.Tuple
+}
+
+// Server is the playground's http.Handler.
+type Server struct {
+	mux *http.ServeMux
+
+	mu      sync.Mutex
+	backend authz.Backend
+	local   *eval.Evaluator // nil with a remote backend
+}
+
+// New returns a playground server.
+func New(cfg Config) *Server {
+	s := &Server{mux: http.NewServeMux(), backend: cfg.Backend}
+	if s.backend == nil {
+		m := cfg.Model
+		if m == nil {
+			m = &model.Model{SchemaVersion: "1.1", Types: []*model.Type{{Name: "user"}}}
+		}
+		s.local = eval.New(m, eval.NewTupleStore(cfg.Tuples...))
+		s.backend = s.local
+	}
+	assets, _ := fs.Sub(static, "static")
+	s.mux.Handle("/", http.FileServer(http.FS(assets)))
+	s.mux.HandleFunc("/api/model", s.handleModel)
+	s.mux.HandleFunc("/api/tuples", s.handleTuples)
+	s.mux.HandleFunc("/api/check", s.handleCheck)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) current() authz.Backend {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.backend
+}
+
+func (s *Server) editable() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.local != nil
+}
+
+type modelResponse struct {
+	DSL      string `json:"dsl"`
+	Editable bool   `json:"editable"`
+}
+
+// handleModel returns the model as DSL on GET and replaces it on PUT, with a
+// text/plain DSL body. Tuples are kept across model changes; those the new
+// model rejects are reported and dropped.
+func (s *Server) handleModel(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		m, err := s.current().ReadModel(r.Context(), "")
+		if err != nil {
+			writeError(w, http.StatusBadGateway, err)
+			return
+		}
+		writeJSON(w, modelResponse{DSL: m.String(), Editable: s.editable()})
+	case http.MethodPut:
+		if !s.editable() {
+			writeError(w, http.StatusConflict, ErrReadOnly)
+			return
+		}
+		src, err := io.ReadAll(io.LimitReader(r.Body, maxBody))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		m, err := model.Parse("model.fga", src)
+		if err == nil {
+			err = m.Validate()
+		}
+		if err != nil {
+			writeError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+		s.mu.Lock()
+		kept, dropped := eval.NewTupleStore(), []string{}
+		for _, t := range s.local.Store().Tuples() {
+			if err := m.ValidateTuple(t.User, t.Relation, t.Object, t.Condition.Name); err != nil {
+				dropped = append(dropped, err.Error())
+				continue
+			}
+			kept.Write([]authz.Tuple{t}, nil)
+		}
+		s.local = eval.New(m, kept)
+		s.backend = s.local
+		s.mu.Unlock()
+		writeJSON(w, map[string]interface{}{"dsl": m.String(), "dropped": dropped})
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	}
+}
+
+// handleTuples lists tuples on GET, optionally filtered by the object,
+// relation and user query parameters, and writes or deletes the JSON array
+// of tuples in the body on POST or DELETE.
+func (s *Server) handleTuples(w http.ResponseWriter, r *http.Request) {
+	b := s.current()
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		filter := authz.Tuple{User: q.Get("user"), Relation: q.Get("relation"), Object: q.Get("object")}
+		typ, _, _ := strings.Cut(filter.Object, ":")
+		all, err := authz.ReadType(r.Context(), b, typ)
+		if err != nil {
+			writeError(w, http.StatusBadGateway, err)
+			return
+		}
+		matched := []authz.Tuple{}
+		for _, t := range all {
+			if filter.Matches(t) {
+				matched = append(matched, t)
+			}
+		}
+		eval.SortTuples(matched)
+		writeJSON(w, matched)
+	case http.MethodPost, http.MethodDelete:
+		var ts []authz.Tuple
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxBody)).Decode(&ts); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		m, err := b.ReadModel(r.Context(), "")
+		if err != nil {
+			writeError(w, http.StatusBadGateway, err)
+			return
+		}
+		var writes, deletes []authz.Tuple
+		if r.Method == http.MethodPost {
+			for _, t := range ts {
+				if err := m.ValidateTuple(t.User, t.Relation, t.Object, t.Condition.Name); err != nil {
+					writeError(w, http.StatusUnprocessableEntity, err)
+					return
+				}
+			}
+			writes = ts
+		} else {
+			deletes = ts
+		}
+		if err := authz.WriteBatched(r.Context(), b, writes, deletes).Err(); err != nil {
+			writeError(w, http.StatusBadGateway, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	}
+}
+
+type checkResult struct {
+	authz.Tuple
+	Allowed bool   `json:"allowed"`
+	Error   string `json:"error,omitempty"`
+}
+
+// handleCheck runs the checks in a POSTed JSON array of {user, relation,
+// object} and returns one result per check, in order.
+func (s *Server) handleCheck(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	var reqs []authz.Tuple
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxBody)).Decode(&reqs); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	b := s.current()
+	results := make([]checkResult, len(reqs))
+	for i, t := range reqs {
+		t = authz.Tuple{User: strings.TrimSpace(t.User), Relation: strings.TrimSpace(t.Relation), Object: strings.TrimSpace(t.Object)}
+		results[i].Tuple = t
+		allowed, err := b.Check(r.Context(), authz.CheckRequest{User: t.User, Relation: t.Relation, Object: t.Object})
+		results[i].Allowed = allowed
+		if err != nil {
+			results[i].Error = err.Error()
+		}
+	}
+	writeJSON(w, results)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,81 @@
+"use strict";
+
+const $ = (sel) => document.querySelector(sel);
+
+async function api(method, path, body, text) {
+  const opts = { method, headers: {} };
+  if (body !== undefined) {
+    opts.body = text ? body : JSON.stringify(body);
+    opts.headers["Content-Type"] = text ? "text/plain" : "application/json";
+  }
+  const resp = await fetch("/api/" + path, opts);
+  if (resp.status === 204) return null;
+  const data = await resp.json();
+  if (!resp.ok) throw new Error(data.error || resp.statusText);
+  return data;
+}
+
+function message(el, text, isError) {
+  el.textContent = text;
+  el.className = isError ? "error" : "";
+}
+
+async function loadModel() {
+  const m = await api("GET", "model");
+  $("#model").value = m.dsl;
+  $("#model").readOnly = !m.editable;
+  $("#save-model").disabled = !m.editable;
+  $("#status").textContent = m.editable ? "embedded evaluator" : "remote store (model is read-only)";
+}
+
+async function loadTuples() {
+  const tuples = await api("GET", "tuples");
+  const body = $("#tuples tbody");
+  body.replaceChildren();
+  for (const t of tuples) {
+    const row = body.insertRow();
+    for (const k of ["user", "relation", "object"]) row.insertCell().textContent = t[k];
+    const del = document.createElement("button");
+    del.textContent = "×";
+    del.onclick = async () => { await api("DELETE", "tuples", [t]); loadTuples(); };
+    row.insertCell().append(del);
+  }
+}
+
+$("#save-model").onclick = async () => {
+  try {
+    const res = await api("PUT", "model", $("#model").value, true);
+    const dropped = res.dropped || [];
+    message($("#model-msg"), dropped.length ? "Applied; dropped tuples:\n" + dropped.join("\n") : "Applied.");
+    loadTuples();
+  } catch (e) {
+    message($("#model-msg"), e.message, true);
+  }
+};
+
+$("#tuple-form").onsubmit = async (ev) => {
+  ev.preventDefault();
+  const f = new FormData(ev.target);
+  try {
+    await api("POST", "tuples", [{ user: f.get("user"), relation: f.get("relation"), object: f.get("object") }]);
+    ev.target.reset();
+    loadTuples();
+  } catch (e) {
+    alert(e.message);
+  }
+};
+
+$("#run-checks").onclick = async () => {
+  const checks = $("#checks").value.split("\n").map((l) => l.trim().split(/\s+/)).filter((f) => f.length === 3)
+    .map(([user, relation, object]) => ({ user, relation, object }));
+  const list = $("#results");
+  list.replaceChildren();
+  for (const r of await api("POST", "check", checks)) {
+    const li = document.createElement("li");
+    li.className = r.error ? "error" : r.allowed ? "allowed" : "denied";
+    li.textContent = `${r.error ? "error" : r.allowed ? "✓" : "✗"} ${r.user} ${r.relation} ${r.object}${r.error ? ": " + r.error : ""}`;
+    list.append(li);
+  }
+};
+
+loadModel().then(loadTuples).catch((e) => message($("#model-msg"), e.message, true));
@@ -0,0 +1,243 @@
+// Package openapi keeps an OpenAPI document in sync with a policy map. It
+// annotates every operation that maps to a policy operation with an x-fga
+// extension and a line in its description, and reports endpoints and rules
+// that do not line up.
+//
+// A service generates its docs from the same table it enforces:
+//
+//	//go:generate go run ./internal/gendocs
+//	out, rep, err := openapi.Annotate(spec, policies, openapi.Options{})
+//
+// An operation maps to the policy operation named by its x-fga-operation
+// field or, failing that, its operationId. The annotation added is
+//
+//	"x-fga": {
+//	  "operation": "project.view",
+//	  "relation": "viewer",
+//	  "object_type": "project",
+//	  "object": "project:{projectId}"
+//	}
+//
+// Only JSON documents are supported. The output is re-encoded, so keys come
+// out sorted.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bogdanticu88/openfga-examples/policy"
+)
+
+// Extension is the key of the annotation added to each operation.
+const Extension = "x-fga"
+
+// OperationField, set on an OpenAPI operation, names its policy operation
+// when the operationId does not.
+const OperationField = "x-fga-operation"
+
+// descriptionPrefix starts the line Annotate adds to descriptions, so
+// re-running it replaces the line instead of adding another.
+const descriptionPrefix = "Authorization: requires "
+
+var methods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+var pathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+// Options tunes Annotate.
+type Options struct {
+	// ObjectParam returns the path parameter carrying the object ID for an
+	// endpoint; default the last parameter in the path. An empty result
+	// leaves the ID out of the annotation.
+	ObjectParam func(method, path string, rule policy.Rule) string
+	// NoDescription leaves descriptions untouched.
+	NoDescription bool
+}
+
+// Annotation is the x-fga value for one endpoint.
+type Annotation struct {
+	Operation  string `json:"operation"`
+	Relation   string `json:"relation"`
+	ObjectType string `json:"object_type"`
+	Object     string `json:"object,omitempty"`
+}
+
+// Endpoint is one method and path of the document.
+type Endpoint struct {
+	Method string
+	Path   string
+}
+
+func (e Endpoint) String() string { return strings.ToUpper(e.Method) + " " + e.Path }
+
+// Report lists what Annotate found.
+type Report struct {
+	// Annotated maps each protected endpoint to its annotation.
+	Annotated map[Endpoint]Annotation
+	// Unmapped are endpoints with no matching policy operation.
+	Unmapped []Endpoint
+	// Unknown are endpoints naming, through x-fga-operation, an operation
+	// the map does not have.
+	Unknown []Endpoint
+	// Unused are policy operations no endpoint maps to.
+	Unused []string
+}
+
+// Annotate returns doc with an x-fga extension on every operation that maps
+// to an entry of m, replacing any previous annotation, and removes stale
+// annotations from operations that no longer map to one.
+func Annotate(doc []byte, m policy.Map, opts Options) ([]byte, *Report, error) {
+	var root map[string]interface{}
+	if err := json.Unmarshal(doc, &root); err != nil {
+		return nil, nil, fmt.Errorf("openapi: %w", err)
+	}
+	rep, err := walkEndpoints(root, m, opts, func(_ Endpoint, op map[string]interface{}, a *Annotation) {
+		if a == nil {
+			delete(op, Extension)
+		} else {
+			op[Extension] = a
+		}
+		if !opts.NoDescription {
+			describe(op, a)
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	out, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return nil, nil, fmt.Errorf("openapi: %w", err)
+	}
+	return append(out, '\n'), rep, nil
+}
+
+// Drift compares the x-fga annotations already in doc with those Annotate
+// would write and returns one message per endpoint that differs, for CI
+// checks that fail when docs and enforcement diverge.
+func Drift(doc []byte, m policy.Map, opts Options) ([]string, error) {
+	var root map[string]interface{}
+	if err := json.Unmarshal(doc, &root); err != nil {
+		return nil, fmt.Errorf("openapi: %w", err)
+	}
+	var drift []string
+	_, err := walkEndpoints(root, m, opts, func(e Endpoint, op map[string]interface{}, want *Annotation) {
+		var have *Annotation
+		if raw, ok := op[Extension]; ok {
+			have = &Annotation{}
+			data, _ := json.Marshal(raw)
+			if err := json.Unmarshal(data, have); err != nil {
+				drift = append(drift, fmt.Sprintf("%s: malformed %s: %v", e, Extension, err))
+				return
+			}
+		}
+		switch {
+		case have == nil && want != nil:
+			drift = append(drift, fmt.Sprintf("%s: missing %s for %s", e, Extension, want.Operation))
+		case have != nil && want == nil:
+			drift = append(drift, fmt.Sprintf("%s: stale %s for %s", e, Extension, have.Operation))
+		case have != nil && !reflect.DeepEqual(*have, *want):
+			drift = append(drift, fmt.Sprintf("%s: %s says %s on %s, policy requires %s on %s",
+				e, Extension, have.Relation, have.ObjectType, want.Relation, want.ObjectType))
+		}
+	})
+	return drift, err
+}
+
+// walkEndpoints calls fn for every operation in the document, in path and
+// method order, with the annotation it should carry or nil.
+func walkEndpoints(root map[string]interface{}, m policy.Map, opts Options, fn func(Endpoint, map[string]interface{}, *Annotation)) (*Report, error) {
+	paths, ok := root["paths"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("openapi: document has no paths object")
+	}
+	objectParam := opts.ObjectParam
+	if objectParam == nil {
+		objectParam = lastParam
+	}
+	names := make([]string, 0, len(paths))
+	for p := range paths {
+		names = append(names, p)
+	}
+	sort.Strings(names)
+
+	rep := &Report{Annotated: map[Endpoint]Annotation{}}
+	used := map[string]bool{}
+	for _, path := range names {
+		item, _ := paths[path].(map[string]interface{})
+		for _, method := range methods {
+			op, ok := item[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			e := Endpoint{Method: method, Path: path}
+			name, explicit := op[OperationField].(string)
+			if !explicit {
+				name, _ = op["operationId"].(string)
+			}
+			rule, ok := m[name]
+			if !ok {
+				if explicit {
+					rep.Unknown = append(rep.Unknown, e)
+				} else {
+					rep.Unmapped = append(rep.Unmapped, e)
+				}
+				fn(e, op, nil)
+				continue
+			}
+			used[name] = true
+			a := Annotation{Operation: name, Relation: rule.Relation, ObjectType: rule.ObjectType}
+			if p := objectParam(method, path, rule); p != "" {
+				a.Object = rule.Object("{" + p + "}")
+			}
+			rep.Annotated[e] = a
+			fn(e, op, &a)
+		}
+	}
+	for _, entry := range m.Entries() {
+		if !used[entry.Operation] {
+			rep.Unused = append(rep.Unused, entry.Operation)
+		}
+	}
+	return rep, nil
+}
+
+func lastParam(_, path string, _ policy.Rule) string {
+	params := pathParam.FindAllStringSubmatch(path, -1)
+	if len(params) == 0 {
+		return ""
+	}
+	return params[len(params)-1][1]
+}
+
+// describe replaces the authorization line of op's description with one for
+// a, or removes it when a is nil.
+func describe(op map[string]interface{}, a *Annotation) {
+	desc, _ := op["description"].(string)
+	var lines []string
+	for _, l := range strings.Split(desc, "\n") {
+		if !strings.HasPrefix(l, descriptionPrefix) {
+			lines = append(lines, l)
+		}
+	}
+	desc = strings.TrimRight(strings.Join(lines, "\n"), "\n")
+	if a != nil {
+		object := a.Object
+		if object == "" {
+			object = "the " + a.ObjectType
+		}
+		line := fmt.Sprintf("%s`%s` on `%s` (policy operation `%s`).", descriptionPrefix, a.Relation, object, a.Operation)
+		if desc != "" {
+			desc += "\n\n"
+		}
+		desc += line
+	}
+	if desc == "" {
+		delete(op, "description")
+	} else {
+		op["description"] = desc
+	}
+}
@@ -0,0 +1,79 @@
+// Package policy declares, in one table, which relation each service
+// operation requires on which object type.
+//
+// Keeping the mapping in a single place lets enforcement helpers accept an
+// operation name instead of a relation, and lets reviewers audit the whole
+// surface of a service at a glance.
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrUnknownOperation is returned when an operation has no rule in the map.
+var ErrUnknownOperation = errors.New("policy: unknown operation")
+
+// Rule is the requirement attached to one operation: the caller must hold
+// Relation on an object of ObjectType.
+type Rule struct {
+	Relation   string
+	ObjectType string
+}
+
+// Object returns the fully qualified object for id, e.g. "project:api".
+func (r Rule) Object(id string) string {
+	return r.ObjectType + ":" + id
+}
+
+// Map is the operation → rule table for a service, e.g.
+//
+//	var Policies = policy.Map{
+//		"project.view":   {Relation: "viewer", ObjectType: "project"},
+//		"project.delete": {Relation: "owner", ObjectType: "project"},
+//	}
+type Map map[string]Rule
+
+// Lookup returns the rule for operation.
+func (m Map) Lookup(operation string) (Rule, error) {
+	rule, ok := m[operation]
+	if !ok {
+		return Rule{}, fmt.Errorf("%w: %q", ErrUnknownOperation, operation)
+	}
+	return rule, nil
+}
+
+// Validate reports every rule that is missing a relation or object type, or
+// whose fields contain characters that can never match an FGA identifier.
+func (m Map) Validate() error {
+	var errs []error
+	for _, e := range m.Entries() {
+		switch {
+		case e.Operation == "":
+			errs = append(errs, errors.New("policy: empty operation name"))
+		case e.Rule.Relation == "" || e.Rule.ObjectType == "":
+			errs = append(errs, fmt.Errorf("policy: %s: relation and object type are required", e.Operation))
+		case strings.ContainsAny(e.Rule.Relation, ":#@ ") || strings.ContainsAny(e.Rule.ObjectType, ":#@ "):
+			errs = append(errs, fmt.Errorf("policy: %s: invalid relation %q or object type %q", e.Operation, e.Rule.Relation, e.Rule.ObjectType))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Entry is one row of the table.
+type Entry struct {
+	Operation string
+	Rule      Rule
+}
+
+// Entries returns the table sorted by operation name, for audits and docs.
+func (m Map) Entries() []Entry {
+	entries := make([]Entry, 0, len(m))
+	for op, rule := range m {
+		entries = append(entries, Entry{Operation: op, Rule: rule})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Operation < entries[j].Operation })
+	return entries
+}
@@ -0,0 +1,222 @@
+// Package privacy answers data-subject requests for the authorization
+// data held about a person: an export of everything that names them, and
+// erasure when they leave.
+//
+//	s := &privacy.Subjects{Backend: backend, Writer: az, Resolver: resolver, Provenance: prov}
+//	exp, err := s.ExportUser(ctx, "user:alice")
+//	json.NewEncoder(w).Encode(exp)
+//
+//	er, err := s.EraseUser(ctx, "user:alice") // offboarding
+//
+// A tuple involves a user when the user is its subject, directly or as a
+// userset of their own object (user:alice#friend), or when it is about the
+// user's object (user:alice as the object of a manager relation). The
+// server's changes feed cannot be edited; erasure removes the user from the
+// store and the provenance sidecar, and archived feeds are retained under
+// the archive's own policy.
+package privacy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/effective"
+	"github.com/bogdanticu88/openfga-examples/eval"
+	"github.com/bogdanticu88/openfga-examples/history"
+	"github.com/bogdanticu88/openfga-examples/logging"
+	"github.com/bogdanticu88/openfga-examples/tuples"
+)
+
+// Export is the machine-readable report of a data-subject access request.
+type Export struct {
+	User        string      `json:"user"`
+	GeneratedAt time.Time   `json:"generated_at"`
+	Store       authz.Store `json:"store"`
+	// Tuples are the stored relationships involving the user, sorted.
+	Tuples []authz.Tuple `json:"tuples"`
+	// Permissions maps each object the user can reach, directly or through
+	// groups and hierarchies, to the relations they hold; nil without a
+	// Resolver.
+	Permissions map[string][]string `json:"permissions,omitempty"`
+	// Incomplete lists the type#relation pairs whose permissions could not
+	// be computed.
+	Incomplete []string `json:"incomplete,omitempty"`
+	// Provenance are the sidecar records of the user's tuples and of the
+	// grants the user made or impersonated.
+	Provenance []tuples.Record `json:"provenance,omitempty"`
+	// History are the changes to the user's tuples still in the feed or
+	// archive; nil without a History source.
+	History []authz.Change `json:"history,omitempty"`
+}
+
+// Erasure summarizes EraseUser.
+type Erasure struct {
+	User string `json:"user"`
+	// Deleted are the tuples removed, sorted.
+	Deleted []authz.Tuple `json:"deleted"`
+	// Scrubbed is the number of provenance records of other tuples whose
+	// WrittenBy or Impersonator named the user.
+	Scrubbed int `json:"scrubbed"`
+}
+
+// Subjects handles data-subject requests against one store.
+type Subjects struct {
+	Backend authz.Backend
+	// Writer applies erasures; default Backend. Set it to an
+	// *authz.Client so write guards see them.
+	Writer authz.TupleWriter
+	// Resolver, if set, supplies derived permissions for exports and has
+	// its cache dropped on erasure.
+	Resolver *effective.Resolver
+	// ObjectTypes limits the derived permissions; default every type of
+	// the resolver's model.
+	ObjectTypes []string
+	// Provenance, if set, is exported and scrubbed.
+	Provenance tuples.Store
+	// History, if set, supplies the user's changes for exports.
+	History history.Source
+	// Redact replaces the user in provenance records of other tuples on
+	// erasure; default logging.RedactUser.
+	Redact func(user string) string
+	// Now defaults to time.Now.
+	Now func() time.Time
+}
+
+// Involves reports whether t names user; see the package documentation.
+func Involves(t authz.Tuple, user string) bool {
+	return t.User == user || strings.HasPrefix(t.User, user+"#") || t.Object == user
+}
+
+// ExportUser collects every relationship and derived permission involving
+// user.
+func (s *Subjects) ExportUser(ctx context.Context, user string) (*Export, error) {
+	exp := &Export{User: user, GeneratedAt: s.now()}
+	store, err := s.Backend.GetStore(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("privacy: get store: %w", err)
+	}
+	exp.Store = store
+	if exp.Tuples, err = s.involving(ctx, user); err != nil {
+		return nil, err
+	}
+
+	if s.Resolver != nil {
+		sum, err := s.Resolver.Permissions(ctx, user, s.ObjectTypes...)
+		if sum == nil {
+			return nil, fmt.Errorf("privacy: permissions: %w", err)
+		}
+		exp.Permissions = sum.Objects
+		for _, f := range sum.Failures {
+			exp.Incomplete = append(exp.Incomplete, f.Type+"#"+f.Relation)
+		}
+	}
+
+	if s.Provenance != nil {
+		recs, err := s.Provenance.List(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("privacy: provenance: %w", err)
+		}
+		for _, r := range recs {
+			if Involves(r.Tuple, user) || r.WrittenBy == user || r.Impersonator == user {
+				exp.Provenance = append(exp.Provenance, r)
+			}
+		}
+		sort.Slice(exp.Provenance, func(i, j int) bool { return exp.Provenance[i].WrittenAt.Before(exp.Provenance[j].WrittenAt) })
+	}
+
+	if s.History != nil {
+		changes, err := s.History.Changes(ctx, "")
+		if err != nil {
+			return nil, fmt.Errorf("privacy: history: %w", err)
+		}
+		for _, c := range changes {
+			if Involves(c.Tuple, user) {
+				exp.History = append(exp.History, c)
+			}
+		}
+	}
+	return exp, nil
+}
+
+// EraseUser deletes every tuple involving user and removes the user from
+// the provenance sidecar: records of the deleted tuples are dropped and
+// records of other tuples have WrittenBy and Impersonator redacted. Batches
+// before a failing one stay deleted; running it again finishes the job.
+func (s *Subjects) EraseUser(ctx context.Context, user string) (*Erasure, error) {
+	er := &Erasure{User: user}
+	matched, err := s.involving(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	w := s.Writer
+	if w == nil {
+		w = s.Backend
+	}
+	if err := authz.WriteBatched(ctx, w, nil, matched).Err(); err != nil {
+		return er, fmt.Errorf("privacy: delete: %w", err)
+	}
+	er.Deleted = matched
+	if s.Resolver != nil {
+		s.Resolver.Invalidate(user)
+	}
+	if s.Provenance == nil {
+		return er, nil
+	}
+	if err := s.Provenance.Delete(ctx, matched); err != nil {
+		return er, fmt.Errorf("privacy: provenance: %w", err)
+	}
+	recs, err := s.Provenance.List(ctx)
+	if err != nil {
+		return er, fmt.Errorf("privacy: provenance: %w", err)
+	}
+	redact := s.Redact
+	if redact == nil {
+		redact = logging.RedactUser
+	}
+	var scrubbed []tuples.Record
+	for _, r := range recs {
+		if r.WrittenBy != user && r.Impersonator != user {
+			continue
+		}
+		if r.WrittenBy == user {
+			r.WrittenBy = redact(user)
+		}
+		if r.Impersonator == user {
+			r.Impersonator = redact(user)
+		}
+		scrubbed = append(scrubbed, r)
+	}
+	if len(scrubbed) > 0 {
+		if err := s.Provenance.Put(ctx, scrubbed); err != nil {
+			return er, fmt.Errorf("privacy: provenance: %w", err)
+		}
+	}
+	er.Scrubbed = len(scrubbed)
+	return er, nil
+}
+
+func (s *Subjects) involving(ctx context.Context, user string) ([]authz.Tuple, error) {
+	all, err := authz.ReadType(ctx, s.Backend, "")
+	if err != nil {
+		return nil, fmt.Errorf("privacy: read: %w", err)
+	}
+	var out []authz.Tuple
+	for _, t := range all {
+		if Involves(t, user) {
+			out = append(out, t)
+		}
+	}
+	eval.SortTuples(out)
+	return out, nil
+}
+
+func (s *Subjects) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
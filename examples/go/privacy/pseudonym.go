@@ -0,0 +1,259 @@
+package privacy
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+)
+
+// PseudonymPrefix starts every pseudonymous ID, which is followed by
+// pseudonymLength base32 characters. IDs of exactly that form are passed
+// through, so tuples read back can be written again unchanged; any other
+// ID, even one starting with the prefix, is hashed.
+const PseudonymPrefix = "ps."
+
+const pseudonymLength = 26
+
+// ErrUnknownPseudonym is returned by PseudonymResolver.Resolve for a
+// pseudonym with no mapping, e.g. one erased with Forget.
+var ErrUnknownPseudonym = errors.New("privacy: unknown pseudonym")
+
+var pseudonymEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// Mapping remembers the real user behind each pseudonym.
+type Mapping interface {
+	Put(ctx context.Context, pseudonym, user string) error
+	Get(ctx context.Context, pseudonym string) (string, bool, error)
+	Delete(ctx context.Context, pseudonym string) error
+}
+
+// MemoryMapping is a Mapping in memory, for tests and single processes.
+type MemoryMapping struct {
+	mu sync.RWMutex
+	m  map[string]string
+}
+
+// Put implements Mapping.
+func (m *MemoryMapping) Put(_ context.Context, pseudonym, user string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.m == nil {
+		m.m = map[string]string{}
+	}
+	m.m[pseudonym] = user
+	return nil
+}
+
+// Get implements Mapping.
+func (m *MemoryMapping) Get(_ context.Context, pseudonym string) (string, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	u, ok := m.m[pseudonym]
+	return u, ok, nil
+}
+
+// Delete implements Mapping.
+func (m *MemoryMapping) Delete(_ context.Context, pseudonym string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.m, pseudonym)
+	return nil
+}
+
+// Pseudonymizer replaces the IDs of user types with keyed hashes, so the
+// authorization store holds no personal identifiers:
+//
+//	p := &privacy.Pseudonymizer{Key: key, Mapping: mapping}
+//	az := authz.New(p.Backend(backend))
+//	az.Check(ctx, "user:alice@example.com", "viewer", "doc:1") // sent as user:ps.3q7h…
+//
+// The same key always yields the same pseudonym, so checks keep working;
+// without the key, pseudonyms cannot be linked to people. Tuples read back
+// carry pseudonyms: compare them with Pseudonym, or reveal them with a
+// PseudonymResolver. To erase a user, pass Pseudonym(user) to
+// Subjects.EraseUser and then Forget them.
+type Pseudonymizer struct {
+	// Key is the HMAC key; keep it out of the authorization store's reach.
+	Key []byte
+	// Types are the pseudonymized types; default "user".
+	Types []string
+	// Mapping, if set, records each pseudonym written so it can be
+	// resolved.
+	Mapping Mapping
+}
+
+// Pseudonym returns s, a user ("user:alice", "user:alice#rel") or object,
+// with its ID pseudonymized when its type is covered. Wildcards, type-only
+// filters such as "user:", other types and pseudonyms are returned
+// unchanged.
+func (p *Pseudonymizer) Pseudonym(s string) string {
+	obj, rel, isUserset := strings.Cut(s, "#")
+	typ, id, ok := strings.Cut(obj, ":")
+	if !ok || id == "" || id == "*" || isPseudonym(id) || !p.covers(typ) {
+		return s
+	}
+	mac := hmac.New(sha256.New, p.Key)
+	mac.Write([]byte(obj))
+	out := typ + ":" + PseudonymPrefix + pseudonymEncoding.EncodeToString(mac.Sum(nil))[:pseudonymLength]
+	if isUserset {
+		out += "#" + rel
+	}
+	return out
+}
+
+// isPseudonym reports whether id has the exact form Pseudonym produces.
+func isPseudonym(id string) bool {
+	enc, ok := strings.CutPrefix(id, PseudonymPrefix)
+	if !ok || len(enc) != pseudonymLength {
+		return false
+	}
+	for _, r := range enc {
+		if (r < 'a' || r > 'z') && (r < '2' || r > '7') {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *Pseudonymizer) covers(typ string) bool {
+	if len(p.Types) == 0 {
+		return typ == "user"
+	}
+	return slices.Contains(p.Types, typ)
+}
+
+// Forget deletes user's mapping, after which its pseudonym can no longer be
+// resolved.
+func (p *Pseudonymizer) Forget(ctx context.Context, user string) error {
+	if p.Mapping == nil {
+		return nil
+	}
+	obj, _, _ := strings.Cut(user, "#")
+	if err := p.Mapping.Delete(ctx, p.Pseudonym(obj)); err != nil {
+		return fmt.Errorf("privacy: forget: %w", err)
+	}
+	return nil
+}
+
+func (p *Pseudonymizer) tuples(ctx context.Context, ts []authz.Tuple) ([]authz.Tuple, error) {
+	out := make([]authz.Tuple, len(ts))
+	for i, t := range ts {
+		out[i] = authz.Tuple{User: p.Pseudonym(t.User), Relation: t.Relation, Object: p.Pseudonym(t.Object), Condition: t.Condition}
+		if err := p.remember(ctx, t.User, out[i].User); err != nil {
+			return nil, err
+		}
+		if err := p.remember(ctx, t.Object, out[i].Object); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func (p *Pseudonymizer) remember(ctx context.Context, real, pseudonym string) error {
+	if p.Mapping == nil || real == pseudonym {
+		return nil
+	}
+	real, _, _ = strings.Cut(real, "#")
+	pseudonym, _, _ = strings.Cut(pseudonym, "#")
+	if err := p.Mapping.Put(ctx, pseudonym, real); err != nil {
+		return fmt.Errorf("privacy: record pseudonym: %w", err)
+	}
+	return nil
+}
+
+// Backend returns b with every user and object of a covered type
+// pseudonymized on the way in. The mapping is recorded before the tuples
+// are written.
+func (p *Pseudonymizer) Backend(b authz.Backend) authz.Backend {
+	return &pseudonymBackend{Backend: b, p: p}
+}
+
+type pseudonymBackend struct {
+	authz.Backend
+	p *Pseudonymizer
+}
+
+func (b *pseudonymBackend) Check(ctx context.Context, req authz.CheckRequest) (bool, error) {
+	req.User, req.Object = b.p.Pseudonym(req.User), b.p.Pseudonym(req.Object)
+	return b.Backend.Check(ctx, req)
+}
+
+func (b *pseudonymBackend) Write(ctx context.Context, writes, deletes []authz.Tuple) error {
+	writes, err := b.p.tuples(ctx, writes)
+	if err != nil {
+		return err
+	}
+	deletes, err = b.p.tuples(ctx, deletes)
+	if err != nil {
+		return err
+	}
+	return b.Backend.Write(ctx, writes, deletes)
+}
+
+func (b *pseudonymBackend) Read(ctx context.Context, filter authz.Tuple, continuationToken string) ([]authz.Tuple, string, error) {
+	filter.User, filter.Object = b.p.Pseudonym(filter.User), b.p.Pseudonym(filter.Object)
+	return b.Backend.Read(ctx, filter, continuationToken)
+}
+
+func (b *pseudonymBackend) ListUsers(ctx context.Context, object, relation string, userFilters []string) ([]string, error) {
+	return b.Backend.ListUsers(ctx, b.p.Pseudonym(object), relation, userFilters)
+}
+
+// ListObjects implements authz.Lister when the wrapped backend does.
+func (b *pseudonymBackend) ListObjects(ctx context.Context, user, relation, objectType string) ([]string, error) {
+	l, ok := b.Backend.(authz.Lister)
+	if !ok {
+		return nil, authz.ErrNoLister
+	}
+	return l.ListObjects(ctx, b.p.Pseudonym(user), relation, objectType)
+}
+
+// PseudonymResolver reveals the users behind pseudonyms to callers holding
+// Relation on Object, e.g. can_reveal on system:pii.
+type PseudonymResolver struct {
+	Mapping Mapping
+	Checker authz.Checker
+	// Relation and Object name the permission required of the user on the
+	// context; see authz.WithUser.
+	Relation string
+	Object   string
+}
+
+// Resolve returns the real user behind pseudonym, keeping any userset
+// relation; values that are not pseudonyms are returned unchanged.
+func (r *PseudonymResolver) Resolve(ctx context.Context, pseudonym string) (string, error) {
+	obj, rel, isUserset := strings.Cut(pseudonym, "#")
+	if _, id, _ := strings.Cut(obj, ":"); !isPseudonym(id) {
+		return pseudonym, nil
+	}
+	caller, ok := authz.UserFromContext(ctx)
+	if !ok {
+		return "", authz.ErrNoUser
+	}
+	allowed, err := r.Checker.Check(ctx, caller, r.Relation, r.Object)
+	if err != nil {
+		return "", err
+	}
+	if !allowed {
+		return "", fmt.Errorf("%w: %s may not resolve pseudonyms", authz.ErrForbidden, caller)
+	}
+	user, ok, err := r.Mapping.Get(ctx, obj)
+	if err != nil {
+		return "", fmt.Errorf("privacy: resolve: %w", err)
+	}
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownPseudonym, obj)
+	}
+	if isUserset {
+		user += "#" + rel
+	}
+	return user, nil
+}
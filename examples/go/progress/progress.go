@@ -0,0 +1,193 @@
+// Package progress is the progress and checkpoint interface shared by long
+// jobs such as imports, migrations and bulk deletes:
+//
+//	tr := &progress.Tracker{
+//		Job:        "import roles.csv",
+//		Reporter:   progress.Log(logger, 10*time.Second),
+//		Checkpoint: events.FileCursor("roles.csv.checkpoint"),
+//	}
+//	rep, err := tuplecsv.Import(ctx, f, b, tuplecsv.Options{Progress: tr})
+//
+// A job loads the checkpoint when it starts and skips the work before it,
+// saves one after each unit of work that is durably done, and clears it on
+// completion, so an interrupted run can simply be started again. A nil
+// *Tracker is valid and does nothing.
+package progress
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/events"
+)
+
+// Progress is a snapshot of a job's position.
+type Progress struct {
+	Job string
+	// Done counts the units completed, including those skipped on resume.
+	Done int
+	// Total is the number of units, zero when unknown, e.g. while
+	// streaming.
+	Total   int
+	Started time.Time
+	// Finished is set on the last report.
+	Finished bool
+}
+
+// Percent returns the share of the job done, 0 to 100, or -1 when the
+// total is unknown.
+func (p Progress) Percent() float64 {
+	if p.Total <= 0 {
+		if p.Finished {
+			return 100
+		}
+		return -1
+	}
+	return 100 * float64(min(p.Done, p.Total)) / float64(p.Total)
+}
+
+func (p Progress) String() string {
+	s := fmt.Sprintf("%s: %d", p.Job, p.Done)
+	if p.Total > 0 {
+		s += fmt.Sprintf("/%d (%.0f%%)", p.Total, p.Percent())
+	}
+	if p.Finished {
+		s += ", done"
+	}
+	return s
+}
+
+// Reporter receives progress updates.
+type Reporter interface {
+	Report(ctx context.Context, p Progress)
+}
+
+// ReporterFunc adapts a function to Reporter.
+type ReporterFunc func(ctx context.Context, p Progress)
+
+// Report calls f.
+func (f ReporterFunc) Report(ctx context.Context, p Progress) { f(ctx, p) }
+
+// Log returns a Reporter logging at info level at most once per interval,
+// and always on completion.
+func Log(l *slog.Logger, interval time.Duration) Reporter {
+	var mu sync.Mutex
+	var last time.Time
+	return ReporterFunc(func(ctx context.Context, p Progress) {
+		mu.Lock()
+		due := p.Finished || time.Since(last) >= interval
+		if due {
+			last = time.Now()
+		}
+		mu.Unlock()
+		if due {
+			l.InfoContext(ctx, "progress", "job", p.Job, "done", p.Done, "total", p.Total, "percent", p.Percent(), "finished", p.Finished)
+		}
+	})
+}
+
+// Tracker reports a job's progress and keeps its checkpoint. Jobs accept a
+// *Tracker; either field may be nil.
+type Tracker struct {
+	Job        string
+	Reporter   Reporter
+	Checkpoint events.Cursor
+
+	mu sync.Mutex
+	p  Progress
+}
+
+// Resume starts tracking and returns the saved checkpoint, empty for a
+// fresh run.
+func (t *Tracker) Resume(ctx context.Context) (string, error) {
+	if t == nil {
+		return "", nil
+	}
+	t.mu.Lock()
+	t.p = Progress{Job: t.Job, Started: time.Now()}
+	t.mu.Unlock()
+	if t.Checkpoint == nil {
+		return "", nil
+	}
+	cp, err := t.Checkpoint.Load(ctx)
+	if err != nil {
+		return "", fmt.Errorf("progress: load checkpoint: %w", err)
+	}
+	return cp, nil
+}
+
+// SetTotal sets the number of units once known.
+func (t *Tracker) SetTotal(total int) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.p.Total = total
+	t.mu.Unlock()
+}
+
+// Skip counts n units done by an earlier run, without reporting.
+func (t *Tracker) Skip(n int) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.p.Done += n
+	t.mu.Unlock()
+}
+
+// Advance counts n more units done, saves checkpoint when it is not empty,
+// and reports. The checkpoint must describe a durable position: resuming
+// from it must not lose work.
+func (t *Tracker) Advance(ctx context.Context, n int, checkpoint string) error {
+	if t == nil {
+		return nil
+	}
+	if checkpoint != "" && t.Checkpoint != nil {
+		if err := t.Checkpoint.Save(ctx, checkpoint); err != nil {
+			return fmt.Errorf("progress: save checkpoint: %w", err)
+		}
+	}
+	t.mu.Lock()
+	t.p.Done += n
+	p := t.p
+	t.mu.Unlock()
+	if t.Reporter != nil {
+		t.Reporter.Report(ctx, p)
+	}
+	return nil
+}
+
+// Finish clears the checkpoint, so the next run starts afresh, and sends
+// the final report.
+func (t *Tracker) Finish(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	if t.Checkpoint != nil {
+		if err := t.Checkpoint.Save(ctx, ""); err != nil {
+			return fmt.Errorf("progress: clear checkpoint: %w", err)
+		}
+	}
+	t.mu.Lock()
+	t.p.Finished = true
+	p := t.p
+	t.mu.Unlock()
+	if t.Reporter != nil {
+		t.Reporter.Report(ctx, p)
+	}
+	return nil
+}
+
+// Progress returns the current position.
+func (t *Tracker) Progress() Progress {
+	if t == nil {
+		return Progress{}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.p
+}
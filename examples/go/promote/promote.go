@@ -0,0 +1,240 @@
+// Package promote moves a validated authorization model, and optionally a
+// curated set of seed tuples, from one environment to another:
+//
+//	staging := promote.Env{Name: "staging", Backend: authz.FromSDK(stg)}
+//	prod := promote.Env{Name: "prod", Backend: authz.FromSDK(prd), Models: promote.SDKModelWriter{Client: prd}}
+//	plan, err := promote.Promote(ctx, staging, prod, promote.Options{
+//		Seeds:   []tuples.Filter{{ObjectType: "system"}, {ObjectType: "role"}},
+//		Confirm: func(p *promote.Plan) bool { p.WriteText(os.Stdout); return ask("apply?") },
+//	})
+//
+// Preview computes the same plan without writing anything, for CI
+// comments and review. Seeds are limited to what the filters select: end
+// user data stays in each environment.
+package promote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/openfga/go-sdk/client"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/eval"
+	"github.com/bogdanticu88/openfga-examples/model"
+	"github.com/bogdanticu88/openfga-examples/textdiff"
+	"github.com/bogdanticu88/openfga-examples/tuples"
+)
+
+// ErrAborted is returned by Promote when Options.Confirm declines.
+var ErrAborted = errors.New("promote: aborted")
+
+// ModelWriter writes an authorization model and returns its ID.
+type ModelWriter interface {
+	WriteModel(ctx context.Context, m *model.Model) (string, error)
+}
+
+// SDKModelWriter is a ModelWriter over the official client.
+type SDKModelWriter struct {
+	Client *client.OpenFgaClient
+}
+
+// WriteModel implements ModelWriter.
+func (w SDKModelWriter) WriteModel(ctx context.Context, m *model.Model) (string, error) {
+	am, err := model.ToSDK(m)
+	if err != nil {
+		return "", err
+	}
+	resp, err := w.Client.WriteAuthorizationModel(ctx).Body(client.ClientWriteAuthorizationModelRequest{
+		SchemaVersion:   am.SchemaVersion,
+		TypeDefinitions: am.TypeDefinitions,
+		Conditions:      am.Conditions,
+	}).Execute()
+	if err != nil {
+		return "", err
+	}
+	return resp.GetAuthorizationModelId(), nil
+}
+
+// Env is one environment's store.
+type Env struct {
+	Name    string
+	Backend authz.Backend
+	// Models writes models; required for the target only.
+	Models ModelWriter
+	// Writer applies seed tuples; default Backend.
+	Writer authz.TupleWriter
+}
+
+// Options tunes a promotion.
+type Options struct {
+	// Seeds select the source tuples to copy; none copies the model only.
+	Seeds []tuples.Filter
+	// Prune deletes target tuples the seed filters select that the source
+	// no longer has, making the seeds an exact copy.
+	Prune bool
+	// Confirm sees the plan before anything is written; returning false
+	// aborts. Nil applies without asking.
+	Confirm func(*Plan) bool
+}
+
+// Plan is what a promotion changes in the target.
+type Plan struct {
+	From, To string
+	// Model is the source's active model; Current the target's, nil when
+	// it has none.
+	Model   *model.Model
+	Current *model.Model
+	// ModelDiff is a line diff of the target's DSL to the source's, empty
+	// when they are the same.
+	ModelDiff string
+	// Removed lists types and relations the target has and the promoted
+	// model drops; checks against them will fail after promotion.
+	Removed []string
+	Writes  []authz.Tuple
+	Deletes []authz.Tuple
+	// ModelID is set by Promote to the ID of the model written, if any.
+	ModelID string
+}
+
+// Empty reports whether the plan changes nothing.
+func (p *Plan) Empty() bool {
+	return p.ModelDiff == "" && len(p.Writes) == 0 && len(p.Deletes) == 0
+}
+
+// WriteText writes a summary of p for review.
+func (p *Plan) WriteText(w io.Writer) error {
+	if p.Empty() {
+		_, err := fmt.Fprintf(w, "%s is up to date with %s\n", p.To, p.From)
+		return err
+	}
+	fmt.Fprintf(w, "promote %s → %s\n", p.From, p.To)
+	if p.ModelDiff != "" {
+		fmt.Fprintf(w, "\nmodel (-%s +%s):\n%s\n", p.To, p.From, p.ModelDiff)
+	}
+	for _, r := range p.Removed {
+		fmt.Fprintf(w, "removed: %s\n", r)
+	}
+	if len(p.Writes)+len(p.Deletes) > 0 {
+		fmt.Fprintf(w, "\nseeds: %d to write, %d to delete\n", len(p.Writes), len(p.Deletes))
+	}
+	for _, t := range p.Writes {
+		fmt.Fprintf(w, "+ %s\n", t)
+	}
+	for _, t := range p.Deletes {
+		fmt.Fprintf(w, "- %s\n", t)
+	}
+	return nil
+}
+
+// Preview returns what promoting from to to would change.
+func Preview(ctx context.Context, from, to Env, opts Options) (*Plan, error) {
+	p := &Plan{From: from.Name, To: to.Name}
+	var err error
+	if p.Model, err = from.Backend.ReadModel(ctx, ""); err != nil {
+		return nil, fmt.Errorf("promote: %s: read model: %w", from.Name, err)
+	}
+	// A store without a model is a valid target; treat the error as none.
+	if cur, err := to.Backend.ReadModel(ctx, ""); err == nil {
+		p.Current = cur
+	}
+	before := ""
+	if p.Current != nil {
+		before = p.Current.String()
+		for _, t := range p.Current.Types {
+			nt := p.Model.Type(t.Name)
+			if nt == nil {
+				p.Removed = append(p.Removed, "type "+t.Name)
+				continue
+			}
+			for _, r := range t.Relations {
+				if nt.Relation(r.Name) == nil {
+					p.Removed = append(p.Removed, t.Name+"#"+r.Name)
+				}
+			}
+		}
+	}
+	if after := p.Model.String(); after != before {
+		p.ModelDiff = textdiff.Lines([]byte(before), []byte(after))
+	}
+
+	if len(opts.Seeds) > 0 {
+		src, err := seeds(ctx, from.Backend, opts.Seeds)
+		if err != nil {
+			return nil, fmt.Errorf("promote: %s: %w", from.Name, err)
+		}
+		dst, err := seeds(ctx, to.Backend, opts.Seeds)
+		if err != nil {
+			return nil, fmt.Errorf("promote: %s: %w", to.Name, err)
+		}
+		for t := range src {
+			if !dst[t] {
+				p.Writes = append(p.Writes, t)
+			}
+		}
+		if opts.Prune {
+			for t := range dst {
+				if !src[t] {
+					p.Deletes = append(p.Deletes, t)
+				}
+			}
+		}
+		eval.SortTuples(p.Writes)
+		eval.SortTuples(p.Deletes)
+	}
+	return p, nil
+}
+
+// Promote previews the promotion, asks Options.Confirm, and applies it:
+// the model first, then the seeds, validated against the new model. The
+// target only serves the new model if its clients do not pin a model ID.
+func Promote(ctx context.Context, from, to Env, opts Options) (*Plan, error) {
+	p, err := Preview(ctx, from, to, opts)
+	if err != nil {
+		return nil, err
+	}
+	if p.Empty() {
+		return p, nil
+	}
+	if opts.Confirm != nil && !opts.Confirm(p) {
+		return p, ErrAborted
+	}
+	for _, t := range p.Writes {
+		if err := p.Model.ValidateTuple(t.User, t.Relation, t.Object, t.Condition.Name); err != nil {
+			return p, fmt.Errorf("promote: seed: %w", err)
+		}
+	}
+	if p.ModelDiff != "" {
+		if to.Models == nil {
+			return p, fmt.Errorf("promote: %s has no model writer", to.Name)
+		}
+		if p.ModelID, err = to.Models.WriteModel(ctx, p.Model); err != nil {
+			return p, fmt.Errorf("promote: %s: write model: %w", to.Name, err)
+		}
+	}
+	w := to.Writer
+	if w == nil {
+		w = to.Backend
+	}
+	if err := authz.WriteBatched(ctx, w, p.Writes, p.Deletes).Err(); err != nil {
+		return p, fmt.Errorf("promote: %s: seeds: %w", to.Name, err)
+	}
+	return p, nil
+}
+
+func seeds(ctx context.Context, b authz.Backend, filters []tuples.Filter) (map[authz.Tuple]bool, error) {
+	all, err := authz.ReadType(ctx, b, "")
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+	out := map[authz.Tuple]bool{}
+	for _, t := range all {
+		if slices.ContainsFunc(filters, func(f tuples.Filter) bool { return f.Match(t) }) {
+			out[t] = true
+		}
+	}
+	return out, nil
+}
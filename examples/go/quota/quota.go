@@ -0,0 +1,141 @@
+// Package quota caps how many relationships a write may create, e.g. "a
+// document has at most 3 owners" or "a user owns at most 10 projects".
+//
+// Guard counts the stored tuples with reads before each write. The check
+// is best-effort: concurrent writers can both pass it and overshoot a limit
+// by the size of their batches. It stops runaway grants, not races.
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+)
+
+// ErrExceeded is wrapped by every LimitError.
+var ErrExceeded = errors.New("quota: limit exceeded")
+
+// Per selects what a Limit counts towards.
+type Per int
+
+const (
+	// PerObject counts the users holding Relation on each object.
+	PerObject Per = iota
+	// PerUser counts the objects of ObjectType on which each user holds
+	// Relation.
+	PerUser
+)
+
+// Limit caps the number of ObjectType#Relation tuples per object or per
+// user.
+type Limit struct {
+	ObjectType string
+	Relation   string
+	Per        Per
+	Max        int
+}
+
+func (l Limit) String() string {
+	per := "object"
+	if l.Per == PerUser {
+		per = "user"
+	}
+	return fmt.Sprintf("at most %d %s#%s per %s", l.Max, l.ObjectType, l.Relation, per)
+}
+
+// MaxPerObject returns the limit "an object of objectType has at most max
+// users with relation".
+func MaxPerObject(objectType, relation string, max int) Limit {
+	return Limit{ObjectType: objectType, Relation: relation, Per: PerObject, Max: max}
+}
+
+// MaxPerUser returns the limit "a user holds relation on at most max
+// objects of objectType".
+func MaxPerUser(objectType, relation string, max int) Limit {
+	return Limit{ObjectType: objectType, Relation: relation, Per: PerUser, Max: max}
+}
+
+func (l Limit) applies(t authz.Tuple) bool {
+	return t.Relation == l.Relation && strings.HasPrefix(t.Object, l.ObjectType+":")
+}
+
+// key is the object or user a tuple counts towards.
+func (l Limit) key(t authz.Tuple) string {
+	if l.Per == PerUser {
+		return t.User
+	}
+	return t.Object
+}
+
+func (l Limit) filter(key string) authz.Tuple {
+	if l.Per == PerUser {
+		return authz.Tuple{User: key, Relation: l.Relation, Object: l.ObjectType + ":"}
+	}
+	return authz.Tuple{Relation: l.Relation, Object: key}
+}
+
+// LimitError reports a write that would take Subject past Limit.
+type LimitError struct {
+	Limit   Limit
+	Subject string
+	Count   int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%v: %s would have %d, %s", ErrExceeded, e.Subject, e.Count, e.Limit)
+}
+
+func (e *LimitError) Unwrap() error { return ErrExceeded }
+
+// Guard is an authz.WriteGuard enforcing Limits. Install it with
+// authz.WithWriteGuard and skip it with authz.BypassWriteGuards.
+type Guard struct {
+	Backend authz.Backend
+	Limits  []Limit
+}
+
+// GuardWrite implements authz.WriteGuard. A write is rejected only when it
+// grows a count past its limit, so subjects already over a lowered limit
+// can still shrink.
+func (g *Guard) GuardWrite(ctx context.Context, writes, deletes []authz.Tuple) error {
+	var errs []error
+	for _, l := range g.Limits {
+		added := map[string][]authz.Tuple{}
+		for _, t := range writes {
+			if l.applies(t) {
+				added[l.key(t)] = append(added[l.key(t)], t)
+			}
+		}
+		for key, ts := range added {
+			stored, err := authz.ReadAll(ctx, g.Backend, l.filter(key))
+			if err != nil {
+				return fmt.Errorf("quota: count %s: %w", key, err)
+			}
+			before := len(stored)
+			after := apply(stored, ts, deletes)
+			if after > l.Max && after > before {
+				errs = append(errs, &LimitError{Limit: l, Subject: key, Count: after})
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// apply returns how many distinct tuple keys remain after writing writes
+// and deleting deletes.
+func apply(stored, writes, deletes []authz.Tuple) int {
+	set := map[authz.Tuple]bool{}
+	for _, t := range stored {
+		set[t.Key()] = true
+	}
+	for _, t := range deletes {
+		delete(set, t.Key())
+	}
+	for _, t := range writes {
+		set[t.Key()] = true
+	}
+	return len(set)
+}
@@ -0,0 +1,353 @@
+// Package region spreads an application's OpenFGA traffic over a primary
+// endpoint and read replicas in other regions:
+//
+//	b := region.New(region.Config{
+//		Primary:  region.Endpoint{Name: "eu-west-1", Backend: authz.FromSDK(eu)},
+//		Replicas: []region.Endpoint{{Name: "us-east-1", Backend: authz.FromSDK(us)}},
+//	})
+//	go b.Run(ctx, 10*time.Second)
+//	az := authz.New(b)
+//
+// Writes always go to the primary. Checks go to the healthy endpoint with
+// the lowest observed latency; other reads prefer the primary. A read that
+// fails on one endpoint with an error authz.Retryable accepts is retried on
+// the next healthy one, so a region outage costs one failed attempt, not an
+// error. Paged reads stay on the endpoint that served their first page,
+// whose name the Backend adds to the continuation tokens it returns.
+// Endpoints are marked down after consecutive failures and back up when a
+// health probe passes; while every endpoint is down, calls try them all.
+package region
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/healthz"
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// Endpoint is one OpenFGA deployment.
+type Endpoint struct {
+	Name    string
+	Backend authz.Backend
+}
+
+// Config configures a Backend.
+type Config struct {
+	Primary  Endpoint
+	Replicas []Endpoint
+	// FailAfter is the number of consecutive failures that mark an endpoint
+	// down; default 3.
+	FailAfter int
+	// Logger receives failover events; nil disables logging.
+	Logger *slog.Logger
+}
+
+// Status is an endpoint's state as seen by the Backend.
+type Status struct {
+	Name    string
+	Primary bool
+	Healthy bool
+	// Latency is a moving average of successful call durations.
+	Latency time.Duration
+	// LastError is the most recent failure, cleared on success.
+	LastError error
+}
+
+// Backend is an authz.Backend routing over several endpoints.
+type Backend struct {
+	cfg Config
+
+	mu        sync.Mutex
+	endpoints []*endpoint // primary first
+}
+
+type endpoint struct {
+	Endpoint
+	primary bool
+	healthy bool
+	fails   int
+	latency time.Duration
+	lastErr error
+}
+
+// New returns a Backend for cfg. Every endpoint starts healthy.
+func New(cfg Config) *Backend {
+	if cfg.FailAfter <= 0 {
+		cfg.FailAfter = 3
+	}
+	b := &Backend{cfg: cfg}
+	b.endpoints = append(b.endpoints, &endpoint{Endpoint: cfg.Primary, primary: true, healthy: true})
+	for _, r := range cfg.Replicas {
+		b.endpoints = append(b.endpoints, &endpoint{Endpoint: r, healthy: true})
+	}
+	return b
+}
+
+// Status returns the state of every endpoint, primary first.
+func (b *Backend) Status() []Status {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := make([]Status, len(b.endpoints))
+	for i, e := range b.endpoints {
+		out[i] = Status{Name: e.Name, Primary: e.primary, Healthy: e.healthy, Latency: e.latency, LastError: e.lastErr}
+	}
+	return out
+}
+
+// Run probes every endpoint with healthz.Check each interval until ctx is
+// done, marking endpoints up or down and refreshing their latency.
+func (b *Backend) Run(ctx context.Context, interval time.Duration) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+			b.Probe(ctx)
+		}
+	}
+}
+
+// Probe checks every endpoint once, concurrently.
+func (b *Backend) Probe(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, e := range b.endpoints {
+		wg.Add(1)
+		go func(e *endpoint) {
+			defer wg.Done()
+			start := time.Now()
+			_, err := healthz.Check(ctx, e.Backend)
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if err != nil {
+				e.lastErr = err
+				if e.healthy {
+					e.healthy = false
+					b.logf(ctx, slog.LevelWarn, "endpoint down", "endpoint", e.Name, "error", err)
+				}
+				return
+			}
+			if !e.healthy {
+				b.logf(ctx, slog.LevelInfo, "endpoint up", "endpoint", e.Name)
+			}
+			e.healthy, e.fails, e.lastErr = true, 0, nil
+			e.observe(time.Since(start))
+		}(e)
+	}
+	wg.Wait()
+}
+
+// Check is served by the fastest healthy endpoint.
+func (b *Backend) Check(ctx context.Context, req authz.CheckRequest) (bool, error) {
+	var allowed bool
+	err := b.do(ctx, "check", b.byLatency(), func(e *endpoint) (err error) {
+		allowed, err = e.Backend.Check(ctx, req)
+		return err
+	})
+	return allowed, err
+}
+
+// Write is served by the primary only, even when it is marked down.
+func (b *Backend) Write(ctx context.Context, writes, deletes []authz.Tuple) error {
+	return b.do(ctx, "write", b.endpoints[:1], func(e *endpoint) error {
+		return e.Backend.Write(ctx, writes, deletes)
+	})
+}
+
+// Read pages through the endpoint that served the first page; see pinned.
+func (b *Backend) Read(ctx context.Context, filter authz.Tuple, continuationToken string) ([]authz.Tuple, string, error) {
+	order, token, err := b.pinned(continuationToken)
+	if err != nil {
+		return nil, "", err
+	}
+	var page []authz.Tuple
+	var next string
+	var served *endpoint
+	err = b.do(ctx, "read", order, func(e *endpoint) (err error) {
+		page, next, err = e.Backend.Read(ctx, filter, token)
+		served = e
+		return err
+	})
+	return page, pin(served, next), err
+}
+
+func (b *Backend) ReadModel(ctx context.Context, id string) (*model.Model, error) {
+	var m *model.Model
+	err := b.do(ctx, "read model", b.healthy(), func(e *endpoint) (err error) {
+		m, err = e.Backend.ReadModel(ctx, id)
+		return err
+	})
+	return m, err
+}
+
+func (b *Backend) GetStore(ctx context.Context) (authz.Store, error) {
+	var s authz.Store
+	err := b.do(ctx, "get store", b.healthy(), func(e *endpoint) (err error) {
+		s, err = e.Backend.GetStore(ctx)
+		return err
+	})
+	return s, err
+}
+
+// ReadChanges pages through the endpoint that served the first page; see
+// pinned.
+func (b *Backend) ReadChanges(ctx context.Context, objectType, continuationToken string) ([]authz.Change, string, error) {
+	order, token, err := b.pinned(continuationToken)
+	if err != nil {
+		return nil, "", err
+	}
+	var changes []authz.Change
+	var next string
+	var served *endpoint
+	err = b.do(ctx, "read changes", order, func(e *endpoint) (err error) {
+		changes, next, err = e.Backend.ReadChanges(ctx, objectType, token)
+		served = e
+		return err
+	})
+	return changes, pin(served, next), err
+}
+
+// ErrForeignToken is returned for a continuation token this Backend did not
+// issue, or one naming an endpoint it no longer has.
+var ErrForeignToken = errors.New("region: continuation token not issued by this backend")
+
+// pin prefixes a continuation token with the escaped name of the endpoint
+// that issued it, so the next page is asked of the same endpoint.
+func pin(e *endpoint, token string) string {
+	if e == nil || token == "" {
+		return token
+	}
+	return url.QueryEscape(e.Name) + ":" + token
+}
+
+// pinned returns the endpoints to try for a page and the token to send
+// them. The first page may fail over; later pages go to the endpoint that
+// issued the token, since replicas cannot resume each other's tokens.
+func (b *Backend) pinned(continuationToken string) ([]*endpoint, string, error) {
+	if continuationToken == "" {
+		return b.healthy(), "", nil
+	}
+	escaped, token, ok := strings.Cut(continuationToken, ":")
+	name, err := url.QueryUnescape(escaped)
+	if !ok || err != nil {
+		return nil, "", ErrForeignToken
+	}
+	for _, e := range b.endpoints {
+		if e.Name == name {
+			return []*endpoint{e}, token, nil
+		}
+	}
+	return nil, "", fmt.Errorf("%w: unknown endpoint %q", ErrForeignToken, name)
+}
+
+func (b *Backend) ListUsers(ctx context.Context, object, relation string, userFilters []string) ([]string, error) {
+	var users []string
+	err := b.do(ctx, "list users", b.byLatency(), func(e *endpoint) (err error) {
+		users, err = e.Backend.ListUsers(ctx, object, relation, userFilters)
+		return err
+	})
+	return users, err
+}
+
+// do calls fn on each endpoint in order until one succeeds. Errors the
+// request itself causes, such as validation failures, are returned without
+// trying further endpoints.
+func (b *Backend) do(ctx context.Context, op string, order []*endpoint, fn func(*endpoint) error) error {
+	var err error
+	for i, e := range order {
+		start := time.Now()
+		if err = fn(e); err == nil {
+			b.succeeded(e, time.Since(start))
+			return nil
+		}
+		if ctx.Err() != nil || !authz.Retryable(err) {
+			return err
+		}
+		b.failed(ctx, e, err)
+		if i < len(order)-1 {
+			b.logf(ctx, slog.LevelInfo, "failing over", "op", op, "from", e.Name, "to", order[i+1].Name, "error", err)
+		}
+	}
+	return fmt.Errorf("region: %s: %w", op, err)
+}
+
+// byLatency returns the healthy endpoints, fastest first. Endpoints without
+// a measurement yet sort first, so they get one.
+func (b *Backend) byLatency() []*endpoint {
+	hs := b.healthy()
+	b.mu.Lock()
+	latency := make(map[*endpoint]time.Duration, len(hs))
+	for _, e := range hs {
+		latency[e] = e.latency
+	}
+	b.mu.Unlock()
+	sort.SliceStable(hs, func(i, j int) bool {
+		li, lj := latency[hs[i]], latency[hs[j]]
+		if li == 0 || lj == 0 {
+			return li == 0 && lj != 0
+		}
+		return li < lj
+	})
+	return hs
+}
+
+// healthy returns the healthy endpoints, primary first. When all are down
+// it returns all of them, so calls still have a chance before the next
+// probe.
+func (b *Backend) healthy() []*endpoint {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var hs []*endpoint
+	for _, e := range b.endpoints {
+		if e.healthy {
+			hs = append(hs, e)
+		}
+	}
+	if len(hs) == 0 {
+		return append(hs, b.endpoints...)
+	}
+	return hs
+}
+
+func (b *Backend) succeeded(e *endpoint, d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	e.fails, e.lastErr = 0, nil
+	e.observe(d)
+}
+
+func (b *Backend) failed(ctx context.Context, e *endpoint, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	e.fails++
+	e.lastErr = err
+	if e.healthy && e.fails >= b.cfg.FailAfter {
+		e.healthy = false
+		b.logf(ctx, slog.LevelWarn, "endpoint down", "endpoint", e.Name, "failures", e.fails, "error", err)
+	}
+}
+
+// observe folds d into the latency average; callers hold b.mu.
+func (e *endpoint) observe(d time.Duration) {
+	if e.latency == 0 {
+		e.latency = d
+		return
+	}
+	e.latency = (e.latency*4 + d) / 5
+}
+
+func (b *Backend) logf(ctx context.Context, level slog.Level, msg string, args ...interface{}) {
+	if b.cfg.Logger != nil {
+		b.cfg.Logger.Log(ctx, level, msg, args...)
+	}
+}
@@ -0,0 +1,102 @@
+package repl
+
+import (
+	"sort"
+	"strings"
+)
+
+// Complete returns the candidates for the last word of line: command names
+// in first position, then types, relations and previously mentioned
+// objects and users according to the command's arguments. Each candidate
+// is the full word, not just the missing suffix.
+func (s *Session) Complete(line string) []string {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || !strings.HasSuffix(line, " ") && len(fields) == 1 {
+		prefix := ""
+		if len(fields) == 1 {
+			prefix = fields[0]
+		}
+		names := make([]string, 0, len(commands))
+		for name := range commands {
+			names = append(names, name)
+		}
+		return filter(names, prefix)
+	}
+	cmd, ok := commands[fields[0]]
+	if !ok {
+		return nil
+	}
+	args, prefix := fields[1:], ""
+	if !strings.HasSuffix(line, " ") {
+		args, prefix = args[:len(args)-1], fields[len(fields)-1]
+	}
+	pos := len(args)
+	if pos >= len(cmd.args) {
+		pos = len(cmd.args) - 1
+		if pos < 0 {
+			return nil
+		}
+	}
+	switch cmd.args[pos] {
+	case "type":
+		return filter(s.typeNames(), prefix)
+	case "relation":
+		return filter(s.relationNames(cmd, args), prefix)
+	default:
+		var words []string
+		for _, t := range s.typeNames() {
+			words = append(words, t+":")
+		}
+		for w := range s.seen {
+			words = append(words, w)
+		}
+		return filter(words, prefix)
+	}
+}
+
+func (s *Session) typeNames() []string {
+	names := make([]string, len(s.Model.Types))
+	for i, t := range s.Model.Types {
+		names[i] = t.Name
+	}
+	return names
+}
+
+// relationNames returns the relations of the object among args, or of every
+// type when no object has been typed yet.
+func (s *Session) relationNames(cmd command, args []string) []string {
+	set := map[string]bool{}
+	for _, t := range s.Model.Types {
+		for _, r := range t.Relations {
+			set[r.Name] = true
+		}
+	}
+	for i, a := range args {
+		if i >= len(cmd.args) || cmd.args[i] != "object" {
+			continue
+		}
+		typ, _, ok := strings.Cut(a, ":")
+		if t := s.Model.Type(typ); ok && t != nil && !strings.Contains(a, "#") {
+			set = map[string]bool{}
+			for _, r := range t.Relations {
+				set[r.Name] = true
+			}
+		}
+	}
+	names := make([]string, 0, len(set))
+	for n := range set {
+		names = append(names, n)
+	}
+	return names
+}
+
+func filter(words []string, prefix string) []string {
+	var out []string
+	for _, w := range words {
+		if strings.HasPrefix(w, prefix) {
+			out = append(out, w)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,120 @@
+package repl
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// expand prints the rewrite of relation on object as a tree, filling direct
+// assignments with the tuples that exist and following computed and
+// tuple-to-userset edges to the objects they reach.
+func (s *Session) expand(ctx context.Context, args []string) error {
+	depth := s.ExpandDepth
+	if depth == 0 {
+		depth = DefaultExpandDepth
+	}
+	e := &expander{s: s, ctx: ctx, maxDepth: depth, visiting: map[string]bool{}}
+	return e.node("", args[1], args[0], 0)
+}
+
+type expander struct {
+	s        *Session
+	ctx      context.Context
+	maxDepth int
+	visiting map[string]bool
+	level    int // nodes being expanded, bounded by maxDepth
+}
+
+func (e *expander) printf(depth int, format string, args ...interface{}) {
+	fmt.Fprintf(e.s.Out, "%s%s\n", strings.Repeat("  ", depth), fmt.Sprintf(format, args...))
+}
+
+// node prints object#relation, after label when not empty, and expands it.
+func (e *expander) node(label, object, relation string, depth int) error {
+	key := object + "#" + relation
+	typ, _, _ := strings.Cut(object, ":")
+	rel := e.s.Model.Relation(typ, relation)
+	switch {
+	case rel == nil:
+		return fmt.Errorf("unknown relation %s#%s", typ, relation)
+	case e.visiting[key]:
+		e.printf(depth, "%s%s (cycle)", label, key)
+		return nil
+	case e.level >= e.maxDepth:
+		e.printf(depth, "%s%s …", label, key)
+		return nil
+	}
+	e.printf(depth, "%s%s", label, key)
+	e.visiting[key] = true
+	e.level++
+	defer func() { delete(e.visiting, key); e.level-- }()
+	return e.rewrite(object, relation, rel.Rewrite, depth+1)
+}
+
+func (e *expander) rewrite(object, relation string, rw model.Rewrite, depth int) error {
+	switch n := rw.(type) {
+	case *model.Direct:
+		ts, err := authz.ReadAll(e.ctx, e.s.Backend, authz.Tuple{Relation: relation, Object: object})
+		if err != nil {
+			return err
+		}
+		users := make([]string, len(ts))
+		for i, t := range ts {
+			users[i] = t.User
+		}
+		if len(users) == 0 {
+			e.printf(depth, "direct: (none)")
+			return nil
+		}
+		e.printf(depth, "direct: %s", strings.Join(users, ", "))
+		for _, u := range users {
+			if obj, rel, ok := strings.Cut(u, "#"); ok {
+				if err := e.node("", obj, rel, depth+1); err != nil {
+					return err
+				}
+			}
+		}
+	case *model.Computed:
+		return e.node(n.Relation+" → ", object, n.Relation, depth)
+	case *model.TupleToUserset:
+		ts, err := authz.ReadAll(e.ctx, e.s.Backend, authz.Tuple{Relation: n.Tupleset, Object: object})
+		if err != nil {
+			return err
+		}
+		e.printf(depth, "%s from %s →", n.Computed, n.Tupleset)
+		if len(ts) == 0 {
+			e.printf(depth+1, "(no %s)", n.Tupleset)
+		}
+		for _, t := range ts {
+			parent, _, _ := strings.Cut(t.User, "#")
+			typ, _, _ := strings.Cut(parent, ":")
+			if e.s.Model.Relation(typ, n.Computed) == nil {
+				continue
+			}
+			if err := e.node("", parent, n.Computed, depth+1); err != nil {
+				return err
+			}
+		}
+	case *model.Union:
+		return e.children("union", object, relation, n.Children, depth)
+	case *model.Intersection:
+		return e.children("intersection", object, relation, n.Children, depth)
+	case *model.Difference:
+		return e.children("but not", object, relation, []model.Rewrite{n.Base, n.Subtract}, depth)
+	}
+	return nil
+}
+
+func (e *expander) children(label, object, relation string, children []model.Rewrite, depth int) error {
+	e.printf(depth, "%s", label)
+	for _, c := range children {
+		if err := e.rewrite(object, relation, c, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
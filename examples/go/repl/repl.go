@@ -0,0 +1,239 @@
+// Package repl is an interactive shell for exploring a model and its tuples:
+//
+//	> check user:alice viewer project:api
+//	allowed
+//	> expand viewer project:api
+//	project:api#viewer
+//	  union
+//	    direct: user:bob
+//	    editor → project:api#editor
+//	      direct: user:alice
+//
+// A Session runs against any authz.Backend; cmd/fgamodel wires it to a
+// terminal with history and tab completion.
+package repl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/eval"
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// ErrQuit is returned by Exec for the quit and exit commands.
+var ErrQuit = errors.New("repl: quit")
+
+// DefaultExpandDepth bounds the tree printed by expand.
+const DefaultExpandDepth = 6
+
+// Session holds the state of one shell: the backend, its model and the
+// command history.
+type Session struct {
+	Backend authz.Backend
+	Model   *model.Model
+	Out     io.Writer
+	// ExpandDepth bounds expand; default DefaultExpandDepth.
+	ExpandDepth int
+
+	history []string
+	seen    map[string]bool // objects and users mentioned so far, for completion
+}
+
+// New returns a session on b, loading its active model.
+func New(ctx context.Context, b authz.Backend, out io.Writer) (*Session, error) {
+	m, err := b.ReadModel(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("repl: read model: %w", err)
+	}
+	return &Session{Backend: b, Model: m, Out: out, seen: map[string]bool{}}, nil
+}
+
+type command struct {
+	usage string
+	help  string
+	// args lists what each argument is, for completion: "user", "relation"
+	// or "object".
+	args []string
+	run  func(s *Session, ctx context.Context, args []string) error
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"check":     {"check <user> <relation> <object>", "check whether user has relation on object", []string{"user", "relation", "object"}, (*Session).check},
+		"expand":    {"expand <relation> <object>", "show how relation on object resolves", []string{"relation", "object"}, (*Session).expand},
+		"tuples":    {"tuples <object|type:> [relation]", "list tuples on an object or every object of a type", []string{"object", "relation"}, (*Session).tuples},
+		"users":     {"users <relation> <object> <filter>...", "list users with relation on object; filters are types or type#relation", []string{"relation", "object", "type"}, (*Session).users},
+		"write":     {"write <user> <relation> <object>", "write a tuple", []string{"user", "relation", "object"}, (*Session).write},
+		"delete":    {"delete <user> <relation> <object>", "delete a tuple", []string{"user", "relation", "object"}, (*Session).delete},
+		"types":     {"types", "list the model's types", nil, (*Session).types},
+		"relations": {"relations <type>", "list a type's relations and their definitions", []string{"type"}, (*Session).relations},
+		"model":     {"model", "print the model", nil, (*Session).printModel},
+		"history":   {"history", "list previous commands", nil, (*Session).printHistory},
+		"help":      {"help", "list commands", nil, (*Session).help},
+		"quit":      {"quit", "leave the shell", nil, func(*Session, context.Context, []string) error { return ErrQuit }},
+		"exit":      {"exit", "leave the shell", nil, func(*Session, context.Context, []string) error { return ErrQuit }},
+	}
+}
+
+// Exec runs one command line. Empty lines and lines starting with '#' are
+// ignored. It returns ErrQuit for quit and exit; other errors are the
+// command's and leave the session usable.
+func (s *Session) Exec(ctx context.Context, line string) error {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+	s.history = append(s.history, line)
+	fields := strings.Fields(line)
+	cmd, ok := commands[fields[0]]
+	if !ok {
+		return fmt.Errorf("unknown command %q; try help", fields[0])
+	}
+	args := fields[1:]
+	if len(args) < countRequired(cmd.usage) {
+		return fmt.Errorf("usage: %s", cmd.usage)
+	}
+	for i, a := range args {
+		if i < len(cmd.args) && (cmd.args[i] == "user" || cmd.args[i] == "object") && strings.Contains(a, ":") && !strings.HasSuffix(a, ":") {
+			s.seen[a] = true
+		}
+	}
+	return cmd.run(s, ctx, args)
+}
+
+// History returns the commands run so far, oldest first.
+func (s *Session) History() []string {
+	return append([]string(nil), s.history...)
+}
+
+func countRequired(usage string) int {
+	return strings.Count(usage, "<") - strings.Count(usage, ">...")
+}
+
+func (s *Session) check(ctx context.Context, args []string) error {
+	allowed, err := s.Backend.Check(ctx, authz.CheckRequest{User: args[0], Relation: args[1], Object: args[2]})
+	if err != nil {
+		return err
+	}
+	if allowed {
+		fmt.Fprintln(s.Out, "allowed")
+	} else {
+		fmt.Fprintln(s.Out, "denied")
+	}
+	return nil
+}
+
+func (s *Session) tuples(ctx context.Context, args []string) error {
+	filter := authz.Tuple{Object: args[0]}
+	if len(args) > 1 {
+		filter.Relation = args[1]
+	}
+	var ts []authz.Tuple
+	var err error
+	if strings.HasSuffix(filter.Object, ":") {
+		var all []authz.Tuple
+		if all, err = authz.ReadType(ctx, s.Backend, strings.TrimSuffix(filter.Object, ":")); err != nil {
+			return err
+		}
+		for _, t := range all {
+			if filter.Matches(t) {
+				ts = append(ts, t)
+			}
+		}
+	} else if ts, err = authz.ReadAll(ctx, s.Backend, filter); err != nil {
+		return err
+	}
+	eval.SortTuples(ts)
+	for _, t := range ts {
+		fmt.Fprintln(s.Out, t)
+	}
+	fmt.Fprintf(s.Out, "(%d tuples)\n", len(ts))
+	return nil
+}
+
+func (s *Session) users(ctx context.Context, args []string) error {
+	users, err := s.Backend.ListUsers(ctx, args[1], args[0], args[2:])
+	if err != nil {
+		return err
+	}
+	sort.Strings(users)
+	for _, u := range users {
+		fmt.Fprintln(s.Out, u)
+	}
+	fmt.Fprintf(s.Out, "(%d users)\n", len(users))
+	return nil
+}
+
+func (s *Session) write(ctx context.Context, args []string) error {
+	t := authz.Tuple{User: args[0], Relation: args[1], Object: args[2]}
+	if err := s.Model.ValidateTuple(t.User, t.Relation, t.Object, t.Condition.Name); err != nil {
+		return err
+	}
+	if err := s.Backend.Write(ctx, []authz.Tuple{t}, nil); err != nil {
+		return err
+	}
+	fmt.Fprintln(s.Out, "wrote", t)
+	return nil
+}
+
+func (s *Session) delete(ctx context.Context, args []string) error {
+	t := authz.Tuple{User: args[0], Relation: args[1], Object: args[2]}
+	if err := s.Backend.Write(ctx, nil, []authz.Tuple{t}); err != nil {
+		return err
+	}
+	fmt.Fprintln(s.Out, "deleted", t)
+	return nil
+}
+
+func (s *Session) types(context.Context, []string) error {
+	for _, t := range s.Model.Types {
+		fmt.Fprintf(s.Out, "%s (%d relations)\n", t.Name, len(t.Relations))
+	}
+	return nil
+}
+
+func (s *Session) relations(_ context.Context, args []string) error {
+	t := s.Model.Type(args[0])
+	if t == nil {
+		return fmt.Errorf("unknown type %q", args[0])
+	}
+	tw := tabwriter.NewWriter(s.Out, 0, 4, 2, ' ', 0)
+	for _, r := range t.Relations {
+		fmt.Fprintf(tw, "%s\t%s\n", r.Name, r.Rewrite)
+	}
+	return tw.Flush()
+}
+
+func (s *Session) printModel(context.Context, []string) error {
+	_, err := io.WriteString(s.Out, s.Model.String())
+	return err
+}
+
+func (s *Session) printHistory(context.Context, []string) error {
+	for i, h := range s.history {
+		fmt.Fprintf(s.Out, "%4d  %s\n", i+1, h)
+	}
+	return nil
+}
+
+func (s *Session) help(context.Context, []string) error {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	tw := tabwriter.NewWriter(s.Out, 0, 4, 2, ' ', 0)
+	for _, name := range names {
+		fmt.Fprintf(tw, "%s\t%s\n", commands[name].usage, commands[name].help)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,231 @@
+// Package review runs periodic access reviews: it lists grants older than a
+// per-relation age so their owners can re-approve or remove them.
+//
+// Tuples carry no timestamps, so a Source supplies when each grant was
+// made: the provenance sidecar when one is kept, or else the server's
+// changes feed.
+package review
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/tuples"
+)
+
+// Policy requires ObjectType#Relation grants to be reviewed once they are
+// older than MaxAge.
+type Policy struct {
+	ObjectType string        `json:"object_type"`
+	Relation   string        `json:"relation"`
+	MaxAge     time.Duration `json:"max_age"`
+}
+
+func (p Policy) String() string {
+	return fmt.Sprintf("%s#%s older than %s", p.ObjectType, p.Relation, p.MaxAge)
+}
+
+func (p Policy) matches(t authz.Tuple) bool {
+	return t.Relation == p.Relation && strings.HasPrefix(t.Object, p.ObjectType+":")
+}
+
+// Source says when tuples were granted.
+type Source interface {
+	// Granted returns the records of the tuples whose grant time is known.
+	Granted(ctx context.Context, objectType string, ts []authz.Tuple) (map[authz.Tuple]tuples.Record, error)
+}
+
+// FromProvenance uses the provenance sidecar, which also supplies who made
+// each grant and why.
+func FromProvenance(s tuples.Store) Source { return provenanceSource{s} }
+
+type provenanceSource struct{ store tuples.Store }
+
+func (p provenanceSource) Granted(ctx context.Context, _ string, ts []authz.Tuple) (map[authz.Tuple]tuples.Record, error) {
+	return p.store.Get(ctx, ts)
+}
+
+// FromChanges replays the server's changes feed, taking the latest write
+// of each tuple. Tuples written before the feed's retention window have no
+// known grant time.
+func FromChanges(b authz.Backend) Source { return changesSource{b} }
+
+type changesSource struct{ backend authz.Backend }
+
+func (c changesSource) Granted(ctx context.Context, objectType string, ts []authz.Tuple) (map[authz.Tuple]tuples.Record, error) {
+	changes, _, err := authz.ReadAllChanges(ctx, c.backend, objectType, "")
+	if err != nil {
+		return nil, fmt.Errorf("review: read changes: %w", err)
+	}
+	latest := map[authz.Tuple]time.Time{}
+	for _, ch := range changes {
+		if ch.Operation == authz.OpWrite {
+			latest[ch.Tuple] = ch.Timestamp
+		} else {
+			delete(latest, ch.Tuple)
+		}
+	}
+	out := map[authz.Tuple]tuples.Record{}
+	for _, t := range ts {
+		if at, ok := latest[t]; ok {
+			out[t] = tuples.Record{Tuple: t, WrittenAt: at}
+		}
+	}
+	return out, nil
+}
+
+// Finding is a grant due for review.
+type Finding struct {
+	Policy Policy        `json:"policy"`
+	Grant  tuples.Record `json:"grant"`
+	Age    time.Duration `json:"age"`
+}
+
+// Report is the outcome of one review.
+type Report struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	// Covered is the number of grants the policies apply to.
+	Covered  int       `json:"covered"`
+	Findings []Finding `json:"findings"`
+	// Unknown lists grants covered by a policy whose grant time the source
+	// does not know; they cannot be shown to be recent.
+	Unknown []authz.Tuple `json:"unknown,omitempty"`
+}
+
+// Empty reports whether r needs no action.
+func (r Report) Empty() bool { return len(r.Findings) == 0 && len(r.Unknown) == 0 }
+
+// WriteText writes r in a form suitable for an email or a ticket.
+func (r Report) WriteText(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "Access review %s: %d grants due, %d of unknown age\n",
+		r.GeneratedAt.UTC().Format(time.RFC3339), len(r.Findings), len(r.Unknown)); err != nil {
+		return err
+	}
+	for _, f := range r.Findings {
+		line := fmt.Sprintf("  %s granted %s ago", f.Grant.Tuple, f.Age.Round(time.Hour))
+		if f.Grant.WrittenBy != "" {
+			line += " by " + f.Grant.WrittenBy
+		}
+		if f.Grant.Reason != "" {
+			line += " (" + f.Grant.Reason + ")"
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	for _, t := range r.Unknown {
+		if _, err := fmt.Fprintf(w, "  %s granted at an unknown time\n", t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Notifier delivers reports, e.g. by email or to a ticket queue.
+type Notifier interface {
+	Notify(ctx context.Context, r Report) error
+}
+
+// NotifierFunc adapts a function to Notifier.
+type NotifierFunc func(ctx context.Context, r Report) error
+
+// Notify calls f.
+func (f NotifierFunc) Notify(ctx context.Context, r Report) error { return f(ctx, r) }
+
+// Reviewer checks the store's grants against Policies.
+type Reviewer struct {
+	Backend  authz.Backend
+	Source   Source
+	Policies []Policy
+	// Notifier receives every non-empty report from Run; nil only logs.
+	Notifier Notifier
+	// Now defaults to time.Now.
+	Now func() time.Time
+	// Logger receives scheduling events; nil disables logging.
+	Logger *slog.Logger
+}
+
+// Review lists the grants due for review, oldest first.
+func (rv *Reviewer) Review(ctx context.Context) (Report, error) {
+	now := rv.now()
+	rep := Report{GeneratedAt: now}
+	all, err := authz.ReadType(ctx, rv.Backend, "")
+	if err != nil {
+		return rep, fmt.Errorf("review: read tuples: %w", err)
+	}
+	for _, p := range rv.Policies {
+		var covered []authz.Tuple
+		for _, t := range all {
+			if p.matches(t) {
+				covered = append(covered, t)
+			}
+		}
+		if len(covered) == 0 {
+			continue
+		}
+		rep.Covered += len(covered)
+		granted, err := rv.Source.Granted(ctx, p.ObjectType, covered)
+		if err != nil {
+			return rep, err
+		}
+		for _, t := range covered {
+			g, ok := granted[t]
+			if !ok {
+				rep.Unknown = append(rep.Unknown, t)
+				continue
+			}
+			if age := now.Sub(g.WrittenAt); age > p.MaxAge {
+				rep.Findings = append(rep.Findings, Finding{Policy: p, Grant: g, Age: age})
+			}
+		}
+	}
+	sort.SliceStable(rep.Findings, func(i, j int) bool { return rep.Findings[i].Age > rep.Findings[j].Age })
+	return rep, nil
+}
+
+// Run reviews every interval until ctx is done, passing non-empty reports
+// to Notifier. Failures are logged and retried at the next interval.
+func (rv *Reviewer) Run(ctx context.Context, interval time.Duration) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+			rep, err := rv.Review(ctx)
+			if err != nil {
+				rv.logf(ctx, slog.LevelWarn, "access review failed", "error", err)
+				continue
+			}
+			if rep.Empty() {
+				continue
+			}
+			rv.logf(ctx, slog.LevelInfo, "access review due", "findings", len(rep.Findings), "unknown", len(rep.Unknown))
+			if rv.Notifier != nil {
+				if err := rv.Notifier.Notify(ctx, rep); err != nil {
+					rv.logf(ctx, slog.LevelWarn, "access review notification failed", "error", err)
+				}
+			}
+		}
+	}
+}
+
+func (rv *Reviewer) now() time.Time {
+	if rv.Now != nil {
+		return rv.Now()
+	}
+	return time.Now()
+}
+
+func (rv *Reviewer) logf(ctx context.Context, level slog.Level, msg string, args ...interface{}) {
+	if rv.Logger != nil {
+		rv.Logger.Log(ctx, level, msg, args...)
+	}
+}
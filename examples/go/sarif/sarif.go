@@ -0,0 +1,119 @@
+// Package sarif writes the subset of SARIF 2.1.0 that code review tools
+// read for inline annotations: rules, results with a level and a message,
+// and file/line locations.
+package sarif
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Version and Schema identify the SARIF dialect written.
+const (
+	Version = "2.1.0"
+	Schema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// Levels of a Result.
+const (
+	LevelNote    = "note"
+	LevelWarning = "warning"
+	LevelError   = "error"
+)
+
+// Log is a SARIF document.
+type Log struct {
+	Version string `json:"version"`
+	Schema  string `json:"$schema"`
+	Runs    []Run  `json:"runs"`
+}
+
+// Run is one tool's invocation.
+type Run struct {
+	Tool    Tool     `json:"tool"`
+	Results []Result `json:"results"`
+}
+
+// Tool describes the analyzer.
+type Tool struct {
+	Driver Driver `json:"driver"`
+}
+
+// Driver names the analyzer and lists its rules.
+type Driver struct {
+	Name           string `json:"name"`
+	InformationURI string `json:"informationUri,omitempty"`
+	Rules          []Rule `json:"rules,omitempty"`
+}
+
+// Rule describes one rule ID results may refer to.
+type Rule struct {
+	ID               string  `json:"id"`
+	ShortDescription Message `json:"shortDescription"`
+}
+
+// Result is one finding.
+type Result struct {
+	RuleID    string     `json:"ruleId"`
+	Level     string     `json:"level"`
+	Message   Message    `json:"message"`
+	Locations []Location `json:"locations,omitempty"`
+}
+
+// Message is SARIF's text wrapper.
+type Message struct {
+	Text string `json:"text"`
+}
+
+// Location points at a file region.
+type Location struct {
+	PhysicalLocation PhysicalLocation `json:"physicalLocation"`
+}
+
+// PhysicalLocation is a file and a region within it.
+type PhysicalLocation struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	Region           *Region          `json:"region,omitempty"`
+}
+
+// ArtifactLocation is a file URI, relative to the repository root when
+// annotating pull requests.
+type ArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// Region is a 1-based line and column.
+type Region struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// New returns a log with a single run for the named tool.
+func New(tool string, rules []Rule) *Log {
+	return &Log{Version: Version, Schema: Schema, Runs: []Run{{
+		Tool:    Tool{Driver: Driver{Name: tool, Rules: rules}},
+		Results: []Result{},
+	}}}
+}
+
+// Add appends r to the log's first run.
+func (l *Log) Add(r Result) {
+	l.Runs[0].Results = append(l.Runs[0].Results, r)
+}
+
+// At returns the location of file at line and col; a zero line omits the
+// region.
+func At(file string, line, col int) []Location {
+	loc := Location{PhysicalLocation: PhysicalLocation{ArtifactLocation: ArtifactLocation{URI: file}}}
+	if line > 0 {
+		loc.PhysicalLocation.Region = &Region{StartLine: line, StartColumn: col}
+	}
+	return []Location{loc}
+}
+
+// Write encodes l as indented JSON.
+func (l *Log) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(l)
+}
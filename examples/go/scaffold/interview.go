@@ -0,0 +1,231 @@
+package scaffold
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Prompter asks one question and returns the answer; an empty answer takes
+// def. fgamodel init reads the answers from the terminal.
+type Prompter interface {
+	Ask(question, def string) (string, error)
+}
+
+// PrompterFunc adapts a function to Prompter.
+type PrompterFunc func(question, def string) (string, error)
+
+// Ask calls f.
+func (f PrompterFunc) Ask(question, def string) (string, error) {
+	return f(question, def)
+}
+
+// Interview builds a Spec from answers to questions about the resources,
+// their roles and hierarchy, sharing with groups and public access. An
+// answer that does not fit is explained on w and the question asked again.
+func Interview(p Prompter, w io.Writer) (*Spec, error) {
+	s := &Spec{}
+	ask := func(question, def string, check func(string) error) (string, error) {
+		for {
+			answer, err := p.Ask(question, def)
+			if err != nil {
+				return "", err
+			}
+			if answer = strings.TrimSpace(answer); answer == "" {
+				answer = def
+			}
+			if err := check(answer); err != nil {
+				fmt.Fprintf(w, "%v\n", err)
+				continue
+			}
+			return answer, nil
+		}
+	}
+	askList := func(question, def string, check func([]string) error) ([]string, error) {
+		var list []string
+		_, err := ask(question, def, func(answer string) error {
+			list = splitList(answer)
+			return check(list)
+		})
+		return list, err
+	}
+	idents := func(list []string) error {
+		for _, name := range list {
+			if !isIdent(name) {
+				return fmt.Errorf("%q is not a valid name: use letters, digits and _", name)
+			}
+		}
+		return nil
+	}
+	ids := func(list []string) error {
+		for _, id := range list {
+			if !isID(id) {
+				return fmt.Errorf("%q is not a valid ID", id)
+			}
+		}
+		return nil
+	}
+
+	names, err := askList("Resources, top of the hierarchy first", "organization, project, document", func(list []string) error {
+		if len(list) == 0 {
+			return fmt.Errorf("name at least one resource")
+		}
+		for _, name := range list {
+			if name == "user" || name == "group" {
+				return fmt.Errorf("%s is generated; pick another name", name)
+			}
+		}
+		return idents(list)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, name := range names {
+		r := &Resource{Name: name}
+		if i > 0 {
+			r.Parent, err = ask(fmt.Sprintf("Parent of %s (one of %s, or none)", name, strings.Join(names[:i], ", ")), names[i-1], func(answer string) error {
+				if answer != "none" && !slices.Contains(names[:i], answer) {
+					return fmt.Errorf("%s is not a resource listed before %s", answer, name)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			if r.Parent == "none" {
+				r.Parent = ""
+			}
+		}
+		def := "owner, editor, viewer"
+		if r.Parent == "" {
+			def = "admin, member"
+		}
+		r.Roles, err = askList(fmt.Sprintf("Roles on %s, strongest first", name), def, func(list []string) error {
+			if len(list) == 0 {
+				return fmt.Errorf("name at least one role")
+			}
+			if slices.Contains(list, r.Parent) {
+				return fmt.Errorf("%s is the parent relation", r.Parent)
+			}
+			return idents(list)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if parent := s.resource(r.Parent); parent != nil {
+			for _, role := range r.Roles {
+				if slices.Contains(parent.Roles, role) {
+					continue
+				}
+				from, err := ask(fmt.Sprintf("Which %s role also grants %s on %s (one of %s, or none)", parent.Name, role, name, strings.Join(parent.Roles, ", ")), "none", func(answer string) error {
+					if answer != "none" && !slices.Contains(parent.Roles, answer) {
+						return fmt.Errorf("%s is not a role of %s", answer, parent.Name)
+					}
+					return nil
+				})
+				if err != nil {
+					return nil, err
+				}
+				if from != "none" {
+					if r.Inherit == nil {
+						r.Inherit = map[string]string{}
+					}
+					r.Inherit[role] = from
+				}
+			}
+		}
+		r.Public, err = askList(fmt.Sprintf("Roles on %s anyone may be given, for public sharing (or none)", name), "none", func(list []string) error {
+			for _, role := range list {
+				if role != "none" && !slices.Contains(r.Roles, role) {
+					return fmt.Errorf("%s is not a role of %s", role, name)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		r.Public = slices.DeleteFunc(r.Public, func(role string) bool { return role == "none" })
+		s.Resources = append(s.Resources, r)
+	}
+	share, err := ask("Share with groups of users (yes/no)", "yes", yesNo)
+	if err != nil {
+		return nil, err
+	}
+	if share[0] == 'y' {
+		if s.Groups, err = askList("Demo groups", "eng", ids); err != nil {
+			return nil, err
+		}
+	}
+	if s.Users, err = askList("Demo users", "alice, bob, carol", ids); err != nil {
+		return nil, err
+	}
+	org, err := ask("Demo organization ID", "acme", func(answer string) error { return ids([]string{answer}) })
+	if err != nil {
+		return nil, err
+	}
+	s.Org = org
+	if s.Package, err = ask("Go package for the constants", "authzmodel", func(answer string) error { return idents([]string{answer}) }); err != nil {
+		return nil, err
+	}
+	if err := s.Check(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func yesNo(answer string) error {
+	switch strings.ToLower(answer) {
+	case "y", "yes", "n", "no":
+		return nil
+	}
+	return fmt.Errorf("answer yes or no")
+}
+
+func splitList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' })
+}
+
+func (s *Spec) resource(name string) *Resource {
+	for _, r := range s.Resources {
+		if r.Name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+// Marshal renders s in the format ParseSpec reads, so an interview can be
+// kept and regenerated with the scaffold command.
+func (s *Spec) Marshal() []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "org: %s\npackage: %s\nusers: [%s]\n", s.Org, s.Package, strings.Join(s.Users, ", "))
+	if len(s.Groups) > 0 {
+		fmt.Fprintf(&b, "groups: [%s]\n", strings.Join(s.Groups, ", "))
+	}
+	b.WriteString("resources:\n")
+	for _, r := range s.Resources {
+		fmt.Fprintf(&b, "  %s:\n", r.Name)
+		if r.Parent != "" {
+			fmt.Fprintf(&b, "    parent: %s\n", r.Parent)
+		}
+		fmt.Fprintf(&b, "    roles: [%s]\n", strings.Join(r.Roles, ", "))
+		if len(r.Inherit) > 0 {
+			var entries []string
+			for role, from := range r.Inherit {
+				entries = append(entries, role+": "+from)
+			}
+			sort.Strings(entries)
+			fmt.Fprintf(&b, "    inherit: {%s}\n", strings.Join(entries, ", "))
+		}
+		if len(r.Public) > 0 {
+			fmt.Fprintf(&b, "    public: [%s]\n", strings.Join(r.Public, ", "))
+		}
+		if len(r.Objects) > 0 {
+			fmt.Fprintf(&b, "    objects: [%s]\n", strings.Join(r.Objects, ", "))
+		}
+	}
+	return []byte(b.String())
+}
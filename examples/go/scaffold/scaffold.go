@@ -0,0 +1,604 @@
+// Package scaffold generates a starting point for a new adopter from a short
+// description of their resources: an authorization model, seed tuples for a
+// demo organization and a Go constants package for the model.
+//
+//	org: acme
+//	users: [alice, bob, carol, dan]
+//	groups: [eng]
+//	resources:
+//	  organization:
+//	    roles: [admin, member]
+//	  project:
+//	    parent: organization
+//	    roles: [owner, editor, viewer]
+//	    inherit: {owner: admin, viewer: member}
+//	    objects: [api, web]
+//	  document:
+//	    parent: project
+//	    roles: [editor, viewer]
+//	    public: [viewer]
+//
+// Roles are listed strongest first and each includes the ones after it. A
+// resource with a parent gets a relation named after the parent type, and
+// each role is also granted by the parent role of the same name, or the one
+// inherit maps it to. Roles listed under public can be granted to everyone
+// ("user:*"). With groups, the model has a group type whose members can be
+// granted any role.
+package scaffold
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/eval"
+	"github.com/bogdanticu88/openfga-examples/model"
+	"github.com/bogdanticu88/openfga-examples/modelschema"
+)
+
+// Spec describes the resources to scaffold.
+type Spec struct {
+	// Org is the ID of the demo organization, used for the root objects;
+	// default acme.
+	Org string
+	// Package names the constants package; default authzmodel.
+	Package string
+	// Users are the demo users the seed tuples grant roles to; default
+	// alice, bob and carol.
+	Users []string
+	// Groups are demo groups; without any the model has no group type.
+	Groups    []string
+	Resources []*Resource
+}
+
+// Resource is one object type of the model.
+type Resource struct {
+	Name string
+	// Parent is the type this one inherits roles from, if any.
+	Parent string
+	// Roles are the assignable relations, strongest first.
+	Roles []string
+	// Inherit maps a role to the parent role that grants it, when the
+	// names differ.
+	Inherit map[string]string
+	// Public lists the roles that may be granted to everyone.
+	Public []string
+	// Objects are the demo object IDs; the default is the org for a
+	// resource without a parent and "<org>-<name>" otherwise.
+	Objects []string
+	Pos     model.Pos
+}
+
+// ReadSpec reads and parses a spec file.
+func ReadSpec(path string) (*Spec, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("scaffold: %w", err)
+	}
+	return ParseSpec(path, src)
+}
+
+// ParseSpec parses the YAML subset shown in the package documentation:
+// nested maps, and lists written inline or as "- item" lines. file is used
+// only in positions. The spec is checked, and defaults are filled in.
+func ParseSpec(file string, src []byte) (*Spec, error) {
+	s := &Spec{}
+	var (
+		res       *Resource
+		resIndent int
+		section   string    // top-level key whose block is being read
+		field     string    // resource key whose block is being read
+		fieldAt   int       // its indentation
+		list      *[]string // list receiving "- item" lines
+		listAt    int       // indentation of the key owning list
+	)
+	for i, line := range strings.Split(strings.ReplaceAll(string(src), "\r\n", "\n"), "\n") {
+		if j := strings.Index(line, " #"); j >= 0 {
+			line = line[:j]
+		}
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		text := strings.TrimSpace(line)
+		if text == "" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		pos := model.Pos{File: file, Line: i + 1, Col: indent + 1}
+		fail := func(format string, args ...any) error {
+			return &model.SyntaxError{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+		}
+		if item, ok := strings.CutPrefix(text, "- "); ok || text == "-" {
+			if list == nil || indent < listAt {
+				return nil, fail("list item outside a list")
+			}
+			*list = append(*list, unquote(strings.TrimSpace(item)))
+			continue
+		}
+		list = nil
+		key, value, ok := strings.Cut(text, ":")
+		if !ok {
+			return nil, fail("expected key: value, got %q", text)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if indent == 0 {
+			section, res = key, nil
+			var err error
+			switch key {
+			case "org":
+				s.Org = unquote(value)
+			case "package":
+				s.Package = unquote(value)
+			case "users":
+				s.Users, err = inlineList(value)
+				list, listAt = &s.Users, 0
+			case "groups":
+				s.Groups, err = inlineList(value)
+				list, listAt = &s.Groups, 0
+			case "resources":
+				if value != "" {
+					return nil, fail("resources holds a map of resources")
+				}
+				resIndent = -1
+			default:
+				return nil, fail("unknown key %q", key)
+			}
+			if err != nil {
+				return nil, fail("%s: %v", key, err)
+			}
+			continue
+		}
+		if section != "resources" {
+			return nil, fail("unexpected indented %q", text)
+		}
+		if resIndent < 0 {
+			resIndent = indent
+		}
+		switch {
+		case indent == resIndent:
+			if value != "" {
+				return nil, fail("resource %s holds a map of settings", key)
+			}
+			res = &Resource{Name: key, Pos: pos}
+			s.Resources = append(s.Resources, res)
+			field = ""
+			continue
+		case indent < resIndent || res == nil:
+			return nil, fail("unexpected %q", text)
+		}
+		if field == "inherit" && indent > fieldAt {
+			res.Inherit[key] = unquote(value)
+			continue
+		}
+		field, fieldAt = key, indent
+		var err error
+		switch key {
+		case "parent":
+			res.Parent = unquote(value)
+		case "roles":
+			res.Roles, err = inlineList(value)
+			list, listAt = &res.Roles, indent
+		case "public":
+			res.Public, err = inlineList(value)
+			list, listAt = &res.Public, indent
+		case "objects":
+			res.Objects, err = inlineList(value)
+			list, listAt = &res.Objects, indent
+		case "inherit":
+			res.Inherit, err = inlineMap(value)
+		default:
+			return nil, fail("unknown resource key %q", key)
+		}
+		if err != nil {
+			return nil, fail("%s: %v", key, err)
+		}
+	}
+	if err := s.Check(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// inlineList parses "[a, b]"; an empty value is an empty list, expected to
+// be followed by "- item" lines.
+func inlineList(value string) ([]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(value, "[") || !strings.HasSuffix(value, "]") {
+		return nil, fmt.Errorf("expected [a, b], got %q", value)
+	}
+	var out []string
+	for _, item := range strings.Split(value[1:len(value)-1], ",") {
+		if item = unquote(strings.TrimSpace(item)); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out, nil
+}
+
+// inlineMap parses "{a: b, c: d}"; an empty value is an empty map, expected
+// to be followed by indented "a: b" lines.
+func inlineMap(value string) (map[string]string, error) {
+	out := map[string]string{}
+	if value == "" {
+		return out, nil
+	}
+	if !strings.HasPrefix(value, "{") || !strings.HasSuffix(value, "}") {
+		return nil, fmt.Errorf("expected {a: b}, got %q", value)
+	}
+	for _, entry := range strings.Split(value[1:len(value)-1], ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("expected a: b, got %q", strings.TrimSpace(entry))
+		}
+		out[unquote(strings.TrimSpace(k))] = unquote(strings.TrimSpace(v))
+	}
+	return out, nil
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// Check fills in the defaults and reports every problem with the spec,
+// joined into one error.
+func (s *Spec) Check() error {
+	if s.Org == "" {
+		s.Org = "acme"
+	}
+	if s.Package == "" {
+		s.Package = "authzmodel"
+	}
+	if len(s.Users) == 0 {
+		s.Users = []string{"alice", "bob", "carol"}
+	}
+	var errs []error
+	fail := func(pos model.Pos, format string, args ...any) {
+		errs = append(errs, &model.SyntaxError{Pos: pos, Msg: fmt.Sprintf(format, args...)})
+	}
+	if len(s.Resources) == 0 {
+		errs = append(errs, errors.New("scaffold: no resources"))
+	}
+	byName := map[string]*Resource{}
+	for _, r := range s.Resources {
+		switch {
+		case !isIdent(r.Name):
+			fail(r.Pos, "invalid resource name %q", r.Name)
+		case r.Name == "user" || r.Name == "group":
+			fail(r.Pos, "resource %s is generated; pick another name", r.Name)
+		case byName[r.Name] != nil:
+			fail(r.Pos, "resource %s defined again", r.Name)
+		}
+		byName[r.Name] = r
+	}
+	for _, r := range s.Resources {
+		if len(r.Roles) == 0 {
+			fail(r.Pos, "resource %s has no roles", r.Name)
+		}
+		roles := map[string]bool{}
+		for _, role := range r.Roles {
+			switch {
+			case !isIdent(role):
+				fail(r.Pos, "%s: invalid role name %q", r.Name, role)
+			case roles[role]:
+				fail(r.Pos, "%s: role %s listed twice", r.Name, role)
+			case role == r.Parent:
+				fail(r.Pos, "%s: role %s clashes with the parent relation", r.Name, role)
+			}
+			roles[role] = true
+		}
+		for _, role := range r.Public {
+			if !roles[role] {
+				fail(r.Pos, "%s: public role %s is not a role", r.Name, role)
+			}
+		}
+		if r.Parent == "" {
+			if len(r.Inherit) > 0 {
+				fail(r.Pos, "%s: inherit without a parent", r.Name)
+			}
+			continue
+		}
+		parent := byName[r.Parent]
+		if parent == nil {
+			fail(r.Pos, "%s: undefined parent %s", r.Name, r.Parent)
+			continue
+		}
+		for child, from := range r.Inherit {
+			if !roles[child] {
+				fail(r.Pos, "%s: inherit: %s is not a role", r.Name, child)
+			}
+			if !slices.Contains(parent.Roles, from) {
+				fail(r.Pos, "%s: inherit: %s is not a role of %s", r.Name, from, parent.Name)
+			}
+		}
+		seen := map[string]bool{r.Name: true}
+		for p := parent; p != nil && p.Parent != ""; p = byName[p.Parent] {
+			if seen[p.Name] {
+				fail(r.Pos, "%s: parents form a cycle", r.Name)
+				break
+			}
+			seen[p.Name] = true
+		}
+	}
+	ids := append(append([]string{s.Org}, s.Users...), s.Groups...)
+	for _, r := range s.Resources {
+		ids = append(ids, r.Objects...)
+	}
+	for _, id := range ids {
+		if !isID(id) {
+			errs = append(errs, fmt.Errorf("scaffold: invalid ID %q", id))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Model builds the authorization model.
+func (s *Spec) Model() (*model.Model, error) {
+	var b strings.Builder
+	b.WriteString("model\n  schema 1.1\n\ntype user\n")
+	if len(s.Groups) > 0 {
+		b.WriteString("\ntype group\n  relations\n    define member: [user]\n")
+	}
+	for _, r := range s.Resources {
+		fmt.Fprintf(&b, "\ntype %s\n  relations\n", r.Name)
+		if r.Parent != "" {
+			fmt.Fprintf(&b, "    define %s: [%s]\n", r.Parent, r.Parent)
+		}
+		for i, role := range r.Roles {
+			refs := []string{"user"}
+			if slices.Contains(r.Public, role) {
+				refs = append(refs, "user:*")
+			}
+			if len(s.Groups) > 0 {
+				refs = append(refs, "group#member")
+			}
+			expr := "[" + strings.Join(refs, ", ") + "]"
+			if i > 0 {
+				expr += " or " + r.Roles[i-1]
+			}
+			if from := s.inherited(r, role); from != "" {
+				expr += " or " + from + " from " + r.Parent
+			}
+			fmt.Fprintf(&b, "    define %s: %s\n", role, expr)
+		}
+	}
+	m, err := model.Parse("scaffold", []byte(b.String()))
+	if err != nil {
+		return nil, fmt.Errorf("scaffold: %w", err)
+	}
+	if err := m.Validate(); err != nil {
+		return nil, fmt.Errorf("scaffold: %w", err)
+	}
+	return m, nil
+}
+
+// inherited returns the parent role granting role on r, or "".
+func (s *Spec) inherited(r *Resource, role string) string {
+	if r.Parent == "" {
+		return ""
+	}
+	if from, ok := r.Inherit[role]; ok {
+		return from
+	}
+	for _, p := range s.Resources {
+		if p.Name == r.Parent && slices.Contains(p.Roles, role) {
+			return role
+		}
+	}
+	return ""
+}
+
+// Objects returns the demo object IDs of r.
+func (s *Spec) Objects(r *Resource) []string {
+	switch {
+	case len(r.Objects) > 0:
+		return r.Objects
+	case r.Parent == "":
+		return []string{s.Org}
+	}
+	return []string{s.Org + "-" + r.Name}
+}
+
+// Tuples returns seed tuples for the demo organization: each object is
+// linked to a parent object, taking the parent's objects in turn, and every
+// role on every object is granted to a user, taking the users in turn. Each
+// group gets members and the weakest role on one object.
+func (s *Spec) Tuples() []authz.Tuple {
+	var out []authz.Tuple
+	next := 0
+	user := func() string {
+		u := "user:" + s.Users[next%len(s.Users)]
+		next++
+		return u
+	}
+	objects := map[string][]string{}
+	for _, r := range s.Resources {
+		objects[r.Name] = s.Objects(r)
+	}
+	for _, r := range s.Resources {
+		for i, id := range objects[r.Name] {
+			object := r.Name + ":" + id
+			if parents := objects[r.Parent]; len(parents) > 0 {
+				out = append(out, authz.Tuple{User: r.Parent + ":" + parents[i%len(parents)], Relation: r.Parent, Object: object})
+			}
+			for _, role := range r.Roles {
+				out = append(out, authz.Tuple{User: user(), Relation: role, Object: object})
+			}
+		}
+	}
+	for i, g := range s.Groups {
+		out = append(out, authz.Tuple{User: user(), Relation: "member", Object: "group:" + g})
+		r := s.Resources[i%len(s.Resources)]
+		weakest := r.Roles[len(r.Roles)-1]
+		out = append(out, authz.Tuple{User: "group:" + g + "#member", Relation: weakest, Object: r.Name + ":" + objects[r.Name][0]})
+	}
+	return out
+}
+
+// Result is the generated scaffold.
+type Result struct {
+	Model  *model.Model
+	Tuples []authz.Tuple
+	// Constants is the source of the constants package; see
+	// modelschema.Go.
+	Constants []byte
+	Package   string
+	// Org names the store in StoreFile.
+	Org string
+}
+
+// Generate builds the model, seed tuples and constants for s, and checks
+// every tuple against the model.
+func Generate(s *Spec) (*Result, error) {
+	m, err := s.Model()
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{Model: m, Tuples: s.Tuples(), Package: s.Package, Org: s.Org}
+	for _, t := range res.Tuples {
+		if err := m.ValidateTuple(t.User, t.Relation, t.Object, t.Condition.Name); err != nil {
+			return nil, fmt.Errorf("scaffold: seed tuple %s: %w", t, err)
+		}
+	}
+	if res.Constants, err = modelschema.Go(m, s.Package); err != nil {
+		return nil, fmt.Errorf("scaffold: %w", err)
+	}
+	return res, nil
+}
+
+// Files returns the generated files by path relative to the output
+// directory: model.fga, relations.txt, model.scenario (see Scenario),
+// store.fga.yaml (see StoreFile) and <package>/<package>.go.
+func (r *Result) Files() map[string][]byte {
+	var b strings.Builder
+	b.WriteString("# Seed tuples generated by scaffold\n# Format: object#relation@user\n\n")
+	for _, t := range r.Tuples {
+		b.WriteString(t.String() + "\n")
+	}
+	return map[string][]byte{
+		"model.fga":      []byte(r.Model.String()),
+		"relations.txt":  []byte(b.String()),
+		"model.scenario": r.Scenario(),
+		"store.fga.yaml": r.StoreFile(),
+		filepath.Join(r.Package, r.Package+".go"): r.Constants,
+	}
+}
+
+// Scenario returns a scenario script, see package scenario, that writes the
+// seed tuples and checks every role on the first object of each type for
+// every demo user and group. The expectations are what the generated model
+// decides, so the script starts green and later edits to the model that
+// change a decision show up as failures.
+func (r *Result) Scenario() []byte {
+	ctx := context.Background()
+	e := eval.New(r.Model, eval.NewTupleStore(r.Tuples...))
+	var users []string
+	seen := map[string]bool{}
+	for _, t := range r.Tuples {
+		if strings.HasPrefix(t.User, "user:") && !seen[t.User] {
+			seen[t.User] = true
+			users = append(users, t.User)
+		}
+	}
+	for _, t := range r.Tuples {
+		if t.Relation == "member" && strings.HasPrefix(t.Object, "group:") && !seen[t.Object+"#member"] {
+			seen[t.Object+"#member"] = true
+			users = append(users, t.Object+"#member")
+		}
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "title Seed data generated by scaffold\nmodel model.fga\n\nsay The tuples of relations.txt.\n")
+	for _, t := range r.Tuples {
+		fmt.Fprintf(&b, "write %s\n", t)
+	}
+	for _, t := range r.Model.Types {
+		object := ""
+		for _, st := range r.Tuples {
+			if strings.HasPrefix(st.Object, t.Name+":") {
+				object = st.Object
+				break
+			}
+		}
+		if object == "" || t.Name == "group" {
+			continue
+		}
+		fmt.Fprintf(&b, "\nsay Roles on %s.\n", object)
+		for _, rel := range t.Relations {
+			if r.Model.Type(rel.Name) != nil {
+				continue // the parent relation
+			}
+			for _, u := range users {
+				allowed, err := e.Check(ctx, authz.CheckRequest{User: u, Relation: rel.Name, Object: object})
+				verdict := "denied"
+				if err == nil && allowed {
+					verdict = "allowed"
+				}
+				fmt.Fprintf(&b, "check %s#%s@%s\nexpect %s\n", object, rel.Name, u, verdict)
+			}
+		}
+	}
+	return []byte(b.String())
+}
+
+// StoreFile returns a store file for the OpenFGA CLI, which creates a
+// store with the model and seed tuples: fga store import --file
+// store.fga.yaml.
+func (r *Result) StoreFile() []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "name: %s\nmodel_file: ./model.fga\ntuples:\n", r.Org)
+	for _, t := range r.Tuples {
+		fmt.Fprintf(&b, "  - user: %s\n    relation: %s\n    object: %s\n", t.User, t.Relation, t.Object)
+	}
+	return []byte(b.String())
+}
+
+// WriteDir writes Files under dir. Existing files are only replaced when
+// overwrite is set.
+func (r *Result) WriteDir(dir string, overwrite bool) error {
+	files := r.Files()
+	if !overwrite {
+		for name := range files {
+			if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				return fmt.Errorf("scaffold: %s already exists", filepath.Join(dir, name))
+			}
+		}
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return fmt.Errorf("scaffold: %w", err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return fmt.Errorf("scaffold: %w", err)
+		}
+	}
+	return nil
+}
+
+func isIdent(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c != '_' && !('a' <= c && c <= 'z') && !('A' <= c && c <= 'Z') && !('0' <= c && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func isID(s string) bool {
+	return s != "" && s != "*" && !strings.ContainsAny(s, " \t#:@")
+}
@@ -0,0 +1,317 @@
+// Package scenario runs scripted access flows: load a model, write and
+// delete tuples, run checks and state what they should return. Scripts make
+// bug reports reproducible and double as living documentation:
+//
+//	title Workspace admins inherit from the tenant
+//	model ../models/saas/model.fga
+//	write tenant:acme#admin@user:alice workspace:w1#tenant@tenant:acme
+//	check workspace:w1#can_manage@user:alice
+//	expect allowed
+//	delete tenant:acme#admin@user:alice
+//	check workspace:w1#can_manage@user:alice
+//	expect denied
+//
+// Steps, one per line; blank lines and lines starting with '#' are skipped:
+//
+//	title <text>          names the scenario
+//	say <text>            narration, printed in reports
+//	model <path>          loads a model; paths are relative to the script
+//	model <<TAG           loads the inline model up to a line holding TAG
+//	write <tuple>...      writes tuples in object#relation@user form
+//	delete <tuple>...     deletes tuples
+//	check <tuple>         runs a check
+//	expect allowed|denied asserts the result of the preceding check
+//	expect error [text]   asserts the preceding step failed, with text in
+//	                      its error when given
+//
+// Tuples already written are kept when a later model step replaces the
+// model, as when a new model version is deployed to a store.
+package scenario
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/eval"
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// ErrFailed is returned by Run when an expectation does not hold or a step
+// fails without an "expect error" after it.
+var ErrFailed = errors.New("scenario: failed")
+
+// Kind is the kind of a step.
+type Kind string
+
+const (
+	Title  Kind = "title"
+	Say    Kind = "say"
+	Model  Kind = "model"
+	Write  Kind = "write"
+	Delete Kind = "delete"
+	Check  Kind = "check"
+	Expect Kind = "expect"
+)
+
+// Step is one line of a script, or the lines of an inline model.
+type Step struct {
+	Kind Kind
+	Line int
+	// Text is the argument text of title, say and expect steps.
+	Text   string
+	Tuples []authz.Tuple
+	Model  *model.Model
+}
+
+// Script is a parsed scenario.
+type Script struct {
+	File  string
+	Title string
+	Steps []Step
+}
+
+// ParseFile reads and parses the script at path.
+func ParseFile(path string) (*Script, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("scenario: %w", err)
+	}
+	return Parse(path, src)
+}
+
+// Parse parses a script. Model paths are resolved against file's directory.
+func Parse(file string, src []byte) (*Script, error) {
+	s := &Script{File: file}
+	fail := func(line int, format string, args ...interface{}) error {
+		return fmt.Errorf("scenario: %s:%d: %s", file, line, fmt.Sprintf(format, args...))
+	}
+	sc := bufio.NewScanner(bytes.NewReader(src))
+	for n := 0; sc.Scan(); {
+		n++
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		word, rest, _ := strings.Cut(text, " ")
+		rest = strings.TrimSpace(rest)
+		step := Step{Kind: Kind(word), Line: n, Text: rest}
+		switch step.Kind {
+		case Title:
+			s.Title = rest
+		case Say:
+		case Model:
+			var m *model.Model
+			var err error
+			if tag, ok := strings.CutPrefix(rest, "<<"); ok {
+				tag = strings.TrimSpace(tag)
+				if tag == "" {
+					return nil, fail(n, "model <<TAG needs a tag")
+				}
+				start := n + 1
+				var body strings.Builder
+				closed := false
+				for sc.Scan() {
+					n++
+					if strings.TrimSpace(sc.Text()) == tag {
+						closed = true
+						break
+					}
+					body.WriteString(sc.Text() + "\n")
+				}
+				if !closed {
+					return nil, fail(start-1, "inline model is not closed by %s", tag)
+				}
+				m, err = model.Parse(fmt.Sprintf("%s:%d", file, start), []byte(body.String()))
+			} else if rest != "" {
+				m, err = model.ParseFile(filepath.Join(filepath.Dir(file), rest))
+			} else {
+				return nil, fail(n, "model needs a path or <<TAG")
+			}
+			if err == nil {
+				err = m.Validate()
+			}
+			if err != nil {
+				return nil, fail(step.Line, "%v", err)
+			}
+			step.Model = m
+		case Write, Delete, Check:
+			for _, f := range strings.Fields(rest) {
+				t, err := authz.ParseTuple(f)
+				if err != nil {
+					return nil, fail(n, "%v", err)
+				}
+				step.Tuples = append(step.Tuples, t)
+			}
+			switch {
+			case len(step.Tuples) == 0:
+				return nil, fail(n, "%s needs a tuple", word)
+			case step.Kind == Check && len(step.Tuples) != 1:
+				return nil, fail(n, "check takes one tuple")
+			}
+		case Expect:
+			verb, _, _ := strings.Cut(rest, " ")
+			if verb != "allowed" && verb != "denied" && verb != "error" {
+				return nil, fail(n, "expect allowed, denied or error, got %q", rest)
+			}
+			if len(s.Steps) == 0 {
+				return nil, fail(n, "expect must follow a step")
+			}
+			if prev := s.Steps[len(s.Steps)-1]; verb != "error" && prev.Kind != Check {
+				return nil, fail(n, "expect %s must follow a check", verb)
+			}
+		default:
+			return nil, fail(n, "unknown step %q", word)
+		}
+		s.Steps = append(s.Steps, step)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("scenario: %w", err)
+	}
+	return s, nil
+}
+
+// Result is the outcome of one step.
+type Result struct {
+	Step Step
+	// Allowed is the answer of a check step.
+	Allowed bool
+	Err     error
+	// Failure explains why an expect step, or a step that errored without
+	// an expect error after it, failed.
+	Failure string
+}
+
+// Report is the outcome of a run.
+type Report struct {
+	Script  *Script
+	Results []Result
+	Failed  int
+}
+
+// Run executes s on an embedded evaluator. It returns the report and
+// ErrFailed when any expectation fails; other errors stop the run.
+func Run(ctx context.Context, s *Script) (*Report, error) {
+	rep := &Report{Script: s}
+	var e *eval.Evaluator
+	store := eval.NewTupleStore()
+	for i, step := range s.Steps {
+		res := Result{Step: step}
+		switch step.Kind {
+		case Model:
+			e = eval.New(step.Model, store)
+		case Write, Delete:
+			if e == nil {
+				return rep, fmt.Errorf("scenario: %s:%d: %s before any model", s.File, step.Line, step.Kind)
+			}
+			if step.Kind == Write {
+				for _, t := range step.Tuples {
+					if res.Err = e.Model().ValidateTuple(t.User, t.Relation, t.Object, t.Condition.Name); res.Err != nil {
+						break
+					}
+				}
+				if res.Err == nil {
+					res.Err = e.Write(ctx, step.Tuples, nil)
+				}
+			} else {
+				res.Err = e.Write(ctx, nil, step.Tuples)
+			}
+		case Check:
+			if e == nil {
+				return rep, fmt.Errorf("scenario: %s:%d: check before any model", s.File, step.Line)
+			}
+			t := step.Tuples[0]
+			res.Allowed, res.Err = e.Check(ctx, authz.CheckRequest{User: t.User, Relation: t.Relation, Object: t.Object})
+		case Expect:
+			res.Failure = expectation(step.Text, rep.Results[len(rep.Results)-1])
+		}
+		if res.Err != nil && !expectsError(s.Steps, i) {
+			res.Failure = res.Err.Error()
+		}
+		if res.Failure != "" {
+			rep.Failed++
+		}
+		rep.Results = append(rep.Results, res)
+	}
+	if rep.Failed > 0 {
+		return rep, ErrFailed
+	}
+	return rep, nil
+}
+
+func expectsError(steps []Step, i int) bool {
+	return i+1 < len(steps) && steps[i+1].Kind == Expect && strings.HasPrefix(steps[i+1].Text, "error")
+}
+
+// expectation returns why want does not hold for prev, or "".
+func expectation(want string, prev Result) string {
+	verb, text, _ := strings.Cut(want, " ")
+	text = strings.TrimSpace(text)
+	switch {
+	case verb == "error" && prev.Err == nil:
+		return "expected an error, the step succeeded"
+	case verb == "error" && !strings.Contains(prev.Err.Error(), text):
+		return fmt.Sprintf("expected an error containing %q, got %v", text, prev.Err)
+	case verb == "error":
+		return ""
+	case prev.Err != nil:
+		return "check failed: " + prev.Err.Error()
+	case prev.Allowed != (verb == "allowed"):
+		return fmt.Sprintf("expected %s, got %s", verb, verdict(prev.Allowed))
+	}
+	return ""
+}
+
+func verdict(allowed bool) string {
+	if allowed {
+		return "allowed"
+	}
+	return "denied"
+}
+
+// WriteText writes the run as a narrative: narration, each action and check
+// with its outcome, and failures marked with the script line.
+func (r *Report) WriteText(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	if r.Script.Title != "" {
+		fmt.Fprintf(bw, "== %s\n", r.Script.Title)
+	}
+	for _, res := range r.Results {
+		st := res.Step
+		switch st.Kind {
+		case Say:
+			fmt.Fprintf(bw, "   %s\n", st.Text)
+		case Model:
+			fmt.Fprintf(bw, "   model with %d types\n", len(st.Model.Types))
+		case Write, Delete:
+			for _, t := range st.Tuples {
+				fmt.Fprintf(bw, "   %s %s\n", st.Kind, t)
+			}
+			if res.Err != nil {
+				fmt.Fprintf(bw, "     → error: %v\n", res.Err)
+			}
+		case Check:
+			outcome := verdict(res.Allowed)
+			if res.Err != nil {
+				outcome = "error: " + res.Err.Error()
+			}
+			fmt.Fprintf(bw, "   check %s → %s\n", st.Tuples[0], outcome)
+		}
+		if res.Failure != "" {
+			fmt.Fprintf(bw, "FAIL %s:%d: %s\n", r.Script.File, st.Line, res.Failure)
+		}
+	}
+	if r.Failed == 0 {
+		fmt.Fprintln(bw, "ok")
+	} else {
+		fmt.Fprintf(bw, "%d failed\n", r.Failed)
+	}
+	return bw.Flush()
+}
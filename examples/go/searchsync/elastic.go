@@ -0,0 +1,64 @@
+package searchsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// HTTPIndex updates documents through the partial-update API shared by
+// Elasticsearch and OpenSearch (POST /<index>/_update/<id>).
+type HTTPIndex struct {
+	// URL is the cluster endpoint, e.g. "https://search:9200".
+	URL   string
+	Index string
+	// Field holds the principals; default "allowed_principals".
+	Field string
+	// Client defaults to http.DefaultClient; set it to add authentication.
+	Client *http.Client
+	// Upsert creates missing documents instead of failing.
+	Upsert bool
+}
+
+func (x *HTTPIndex) SetAllowed(ctx context.Context, docID string, principals []string) error {
+	field := x.Field
+	if field == "" {
+		field = "allowed_principals"
+	}
+	if principals == nil {
+		principals = []string{}
+	}
+	body := map[string]interface{}{"doc": map[string]interface{}{field: principals}}
+	if x.Upsert {
+		body["doc_as_upsert"] = true
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	u := strings.TrimSuffix(x.URL, "/") + "/" + url.PathEscape(x.Index) + "/_update/" + url.PathEscape(docID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	hc := x.Client
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("searchsync: update %s: %s: %s", docID, resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
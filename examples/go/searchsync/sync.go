@@ -0,0 +1,210 @@
+// Package searchsync keeps a per-document "allowed principals" field in a
+// search index in step with OpenFGA, so queries can be pre-filtered by ACL:
+//
+//	{"query": {"bool": {"filter": {"terms": {"allowed_principals": ["user:alice", "group:eng#member", "user:*"]}}}}}
+//
+// A full sync rebuilds the field for given documents from ListUsers; an
+// incremental sync follows the changes feed and recomputes only the
+// documents a change can affect.
+package searchsync
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/events"
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// Index stores the allowed principals of a document.
+type Index interface {
+	SetAllowed(ctx context.Context, docID string, principals []string) error
+}
+
+// Sync maps one object type and relation, e.g. document#viewer, to an
+// index.
+type Sync struct {
+	Backend authz.Backend
+	Index   Index
+	// ObjectType and Relation select what a document's ACL is.
+	ObjectType string
+	Relation   string
+	// UserFilters are passed to ListUsers, e.g. "user", "group#member".
+	UserFilters []string
+	// DocID maps an object ID to a search document ID; default is the ID
+	// part of the object ("document:42" → "42").
+	DocID func(object string) string
+	// Cursor stores the changes-feed position for Incremental; nil keeps
+	// it in memory.
+	Cursor events.Cursor
+	// MaxHops bounds how far Incremental follows indirect changes, e.g.
+	// group membership feeding a folder feeding a document; default 5.
+	MaxHops int
+	Logger  *slog.Logger
+
+	model *model.Model
+	token string
+}
+
+// Document recomputes and stores the ACL of one object.
+func (s *Sync) Document(ctx context.Context, object string) error {
+	users, err := s.Backend.ListUsers(ctx, object, s.Relation, s.UserFilters)
+	if err != nil {
+		return fmt.Errorf("searchsync: list users of %s: %w", object, err)
+	}
+	sort.Strings(users)
+	if err := s.Index.SetAllowed(ctx, s.docID(object), users); err != nil {
+		return fmt.Errorf("searchsync: index %s: %w", object, err)
+	}
+	return nil
+}
+
+// Full recomputes the ACL of every object in objects.
+func (s *Sync) Full(ctx context.Context, objects []string) error {
+	for _, obj := range objects {
+		if err := s.Document(ctx, obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Incremental applies changes since the last run. It returns the number of
+// documents updated.
+func (s *Sync) Incremental(ctx context.Context) (int, error) {
+	token := s.token
+	if s.Cursor != nil {
+		t, err := s.Cursor.Load(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("searchsync: load cursor: %w", err)
+		}
+		token = t
+	}
+	changes, next, err := authz.ReadAllChanges(ctx, s.Backend, "", token)
+	if err != nil {
+		return 0, fmt.Errorf("searchsync: read changes: %w", err)
+	}
+	affected := map[string]bool{}
+	for _, c := range changes {
+		objs, err := s.Affected(ctx, c.Tuple.Object)
+		if err != nil {
+			return 0, err
+		}
+		for _, o := range objs {
+			affected[o] = true
+		}
+	}
+	docs := make([]string, 0, len(affected))
+	for o := range affected {
+		docs = append(docs, o)
+	}
+	sort.Strings(docs)
+	if err := s.Full(ctx, docs); err != nil {
+		return 0, err
+	}
+	if next != token {
+		s.token = next
+		if s.Cursor != nil {
+			if err := s.Cursor.Save(ctx, next); err != nil {
+				return len(docs), fmt.Errorf("searchsync: save cursor: %w", err)
+			}
+		}
+	}
+	if s.Logger != nil && len(docs) > 0 {
+		s.Logger.DebugContext(ctx, "search ACLs updated", "changes", len(changes), "documents", len(docs))
+	}
+	return len(docs), nil
+}
+
+// Affected returns the documents whose ACL may depend on object: object
+// itself when it is a document, and otherwise every document reachable by
+// following tuples that reference object as their user.
+func (s *Sync) Affected(ctx context.Context, object string) ([]string, error) {
+	m, err := s.loadModel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	hops := s.MaxHops
+	if hops <= 0 {
+		hops = 5
+	}
+	seen := map[string]bool{object: true}
+	frontier := []string{object}
+	var docs []string
+	for hop := 0; len(frontier) > 0; hop++ {
+		var next []string
+		for _, obj := range frontier {
+			if strings.HasPrefix(obj, s.ObjectType+":") {
+				docs = append(docs, obj)
+				continue
+			}
+			if hop >= hops {
+				continue
+			}
+			parents, err := s.referencing(ctx, m, obj)
+			if err != nil {
+				return nil, err
+			}
+			for _, p := range parents {
+				if !seen[p] {
+					seen[p] = true
+					next = append(next, p)
+				}
+			}
+		}
+		frontier = next
+	}
+	sort.Strings(docs)
+	return docs, nil
+}
+
+// referencing returns the objects of tuples whose user is obj or one of
+// its usersets, querying only relations whose type restrictions allow it.
+func (s *Sync) referencing(ctx context.Context, m *model.Model, obj string) ([]string, error) {
+	objType, _, _ := strings.Cut(obj, ":")
+	var out []string
+	for _, t := range m.Types {
+		for _, rel := range t.Relations {
+			for _, ref := range rel.DirectTypes() {
+				if ref.Type != objType || ref.Wildcard {
+					continue
+				}
+				user := obj
+				if ref.Relation != "" {
+					user += "#" + ref.Relation
+				}
+				tuples, err := authz.ReadAll(ctx, s.Backend, authz.Tuple{User: user, Relation: rel.Name, Object: t.Name + ":"})
+				if err != nil {
+					return nil, fmt.Errorf("searchsync: read %s: %w", user, err)
+				}
+				for _, tu := range tuples {
+					out = append(out, tu.Object)
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+func (s *Sync) loadModel(ctx context.Context) (*model.Model, error) {
+	if s.model == nil {
+		m, err := s.Backend.ReadModel(ctx, "")
+		if err != nil {
+			return nil, fmt.Errorf("searchsync: read model: %w", err)
+		}
+		s.model = m
+	}
+	return s.model, nil
+}
+
+func (s *Sync) docID(object string) string {
+	if s.DocID != nil {
+		return s.DocID(object)
+	}
+	_, id, _ := strings.Cut(object, ":")
+	return id
+}
@@ -0,0 +1,247 @@
+// Package stats reports how large a store is and how fast it grows, for
+// capacity planning: tuple counts per type and relation, the objects with
+// the most tuples, and writes and deletes per period from the changes feed.
+//
+//	s, err := stats.Collect(ctx, backend, stats.Options{Top: 20, Bucket: 24 * time.Hour})
+//	s.WriteText(os.Stdout)
+//	http.Handle("/metrics", stats.Handler(backend, stats.Options{}, 5*time.Minute))
+//
+// Collect reads every tuple and the changes feed from its start, so it is
+// meant for periodic jobs and scrapes cached for minutes, not for request
+// paths.
+package stats
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+)
+
+// Defaults for zero Options fields.
+const (
+	DefaultTop    = 10
+	DefaultBucket = 24 * time.Hour
+	DefaultWindow = 30 * 24 * time.Hour
+)
+
+// Options tunes Collect.
+type Options struct {
+	// Top is the number of objects in Stats.TopObjects; default DefaultTop.
+	Top int
+	// Bucket is the length of each growth period; default DefaultBucket.
+	Bucket time.Duration
+	// Window is how far back growth is reported; default DefaultWindow.
+	Window time.Duration
+	// Now defaults to time.Now.
+	Now func() time.Time
+}
+
+// Count is the number of tuples of one type and relation.
+type Count struct {
+	Type     string
+	Relation string
+	Tuples   int
+}
+
+// ObjectCount is the number of tuples on one object.
+type ObjectCount struct {
+	Object string
+	Tuples int
+}
+
+// Period is the growth over one bucket. Total is the tuple count at the
+// end of the period, reconstructed from the current total.
+type Period struct {
+	Start   time.Time
+	Writes  int
+	Deletes int
+	Total   int
+}
+
+// Stats is one collection.
+type Stats struct {
+	CollectedAt time.Time
+	Total       int
+	// Relations is sorted by type and relation.
+	Relations []Count
+	// TopObjects is sorted by tuple count, largest first.
+	TopObjects []ObjectCount
+	// Growth covers the window oldest first, one entry per bucket.
+	Growth []Period
+}
+
+// Collect reads the store through b and computes its statistics.
+func Collect(ctx context.Context, b authz.Backend, opts Options) (*Stats, error) {
+	now := time.Now
+	if opts.Now != nil {
+		now = opts.Now
+	}
+	top, bucket, window := opts.Top, opts.Bucket, opts.Window
+	if top <= 0 {
+		top = DefaultTop
+	}
+	if bucket <= 0 {
+		bucket = DefaultBucket
+	}
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	s := &Stats{CollectedAt: now()}
+
+	all, err := authz.ReadAll(ctx, b, authz.Tuple{})
+	if err != nil {
+		return nil, fmt.Errorf("stats: read: %w", err)
+	}
+	s.Total = len(all)
+	relations := map[[2]string]int{}
+	objects := map[string]int{}
+	for _, t := range all {
+		typ, _, _ := strings.Cut(t.Object, ":")
+		relations[[2]string{typ, t.Relation}]++
+		objects[t.Object]++
+	}
+	for k, n := range relations {
+		s.Relations = append(s.Relations, Count{Type: k[0], Relation: k[1], Tuples: n})
+	}
+	sort.Slice(s.Relations, func(i, j int) bool {
+		a, b := s.Relations[i], s.Relations[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Relation < b.Relation
+	})
+	for o, n := range objects {
+		s.TopObjects = append(s.TopObjects, ObjectCount{Object: o, Tuples: n})
+	}
+	sort.Slice(s.TopObjects, func(i, j int) bool {
+		a, b := s.TopObjects[i], s.TopObjects[j]
+		if a.Tuples != b.Tuples {
+			return a.Tuples > b.Tuples
+		}
+		return a.Object < b.Object
+	})
+	if len(s.TopObjects) > top {
+		s.TopObjects = s.TopObjects[:top]
+	}
+
+	changes, _, err := authz.ReadAllChanges(ctx, b, "", "")
+	if err != nil {
+		return nil, fmt.Errorf("stats: read changes: %w", err)
+	}
+	end := s.CollectedAt.Truncate(bucket).Add(bucket)
+	n := int((window + bucket - 1) / bucket)
+	start := end.Add(-time.Duration(n) * bucket)
+	s.Growth = make([]Period, n)
+	for i := range s.Growth {
+		s.Growth[i].Start = start.Add(time.Duration(i) * bucket)
+	}
+	for _, c := range changes {
+		if c.Timestamp.Before(start) || !c.Timestamp.Before(end) {
+			continue
+		}
+		p := &s.Growth[int(c.Timestamp.Sub(start)/bucket)]
+		if c.Operation == authz.OpDelete {
+			p.Deletes++
+		} else {
+			p.Writes++
+		}
+	}
+	total := s.Total
+	for i := n - 1; i >= 0; i-- {
+		s.Growth[i].Total = total
+		total -= s.Growth[i].Writes - s.Growth[i].Deletes
+	}
+	return s, nil
+}
+
+// WriteText writes s as aligned tables.
+func (s *Stats) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "%d tuples at %s\n\nTYPE\tRELATION\tTUPLES\n", s.Total, s.CollectedAt.UTC().Format(time.RFC3339))
+	for _, c := range s.Relations {
+		fmt.Fprintf(tw, "%s\t%s\t%d\n", c.Type, c.Relation, c.Tuples)
+	}
+	fmt.Fprintf(tw, "\nOBJECT\tTUPLES\n")
+	for _, o := range s.TopObjects {
+		fmt.Fprintf(tw, "%s\t%d\n", o.Object, o.Tuples)
+	}
+	fmt.Fprintf(tw, "\nPERIOD\tWRITES\tDELETES\tTOTAL\n")
+	for _, p := range s.Growth {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\n", p.Start.UTC().Format(time.RFC3339), p.Writes, p.Deletes, p.Total)
+	}
+	return tw.Flush()
+}
+
+// WritePrometheus writes s in the Prometheus text exposition format as the
+// gauges fga_tuples, fga_relation_tuples{type,relation},
+// fga_object_tuples{object} for the top objects, and
+// fga_tuple_writes/fga_tuple_deletes for the latest growth period.
+func (s *Stats) WritePrometheus(w io.Writer) error {
+	var b strings.Builder
+	gauge := func(name, help string) { fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name) }
+	gauge("fga_tuples", "Tuples in the store.")
+	fmt.Fprintf(&b, "fga_tuples %d\n", s.Total)
+	gauge("fga_relation_tuples", "Tuples per object type and relation.")
+	for _, c := range s.Relations {
+		fmt.Fprintf(&b, "fga_relation_tuples{type=%s,relation=%s} %d\n", label(c.Type), label(c.Relation), c.Tuples)
+	}
+	gauge("fga_object_tuples", "Tuples on the objects with the most tuples.")
+	for _, o := range s.TopObjects {
+		fmt.Fprintf(&b, "fga_object_tuples{object=%s} %d\n", label(o.Object), o.Tuples)
+	}
+	if len(s.Growth) > 0 {
+		last := s.Growth[len(s.Growth)-1]
+		gauge("fga_tuple_writes", "Tuples written in the current growth period.")
+		fmt.Fprintf(&b, "fga_tuple_writes %d\n", last.Writes)
+		gauge("fga_tuple_deletes", "Tuples deleted in the current growth period.")
+		fmt.Fprintf(&b, "fga_tuple_deletes %d\n", last.Deletes)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func label(v string) string { return `"` + labelEscaper.Replace(v) + `"` }
+
+// Handler serves Collect in the Prometheus text format. Results are cached
+// for cacheFor so scrapes do not reread the store each time; zero disables
+// caching.
+func Handler(b authz.Backend, opts Options, cacheFor time.Duration) http.Handler {
+	return &handler{backend: b, opts: opts, cacheFor: cacheFor}
+}
+
+type handler struct {
+	backend  authz.Backend
+	opts     Options
+	cacheFor time.Duration
+
+	mu   sync.Mutex
+	last *Stats
+	at   time.Time
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	if h.last == nil || h.cacheFor == 0 || time.Since(h.at) >= h.cacheFor {
+		s, err := Collect(r.Context(), h.backend, h.opts)
+		if err != nil {
+			h.mu.Unlock()
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		h.last, h.at = s, time.Now()
+	}
+	s := h.last
+	h.mu.Unlock()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	_ = s.WritePrometheus(w)
+}
@@ -0,0 +1,327 @@
+// Package suggest proposes the smallest grant that gives a user a relation
+// on an object, so access requests are met without over-granting:
+//
+//	e := &suggest.Engine{Backend: b}
+//	ss, _ := e.Suggest(ctx, "user:alice", "viewer", "document:roadmap")
+//	for _, s := range ss {
+//		fmt.Println(s) // e.g. add user:alice to group:pm#member, which has viewer on document:roadmap
+//	}
+//	err := e.Apply(ctx, ss[0])
+//
+// Suggestions are ordered from narrowest to broadest: a direct grant on the
+// object, joining a group that already has access, then a grant on a
+// parent, which also reaches the parent's other children. Within a scope,
+// the requested relation comes before the stronger relations implying it.
+// Type restrictions that require a condition are not suggested, since the
+// engine cannot invent the condition's context.
+package suggest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// ErrIneffective is returned by Apply when the check still fails after the
+// suggested tuples were written; they are deleted again.
+var ErrIneffective = errors.New("suggest: grant did not give access")
+
+// DefaultMaxHops is how many parent links Suggest follows when
+// Engine.MaxHops is zero.
+const DefaultMaxHops = 2
+
+// Scope is how much a suggestion grants beyond the request.
+type Scope int
+
+const (
+	// Object grants a relation on the object itself.
+	Object Scope = iota
+	// Group adds the user to a group, gaining all of the group's access.
+	Group
+	// Parent grants on a parent, reaching its other children too.
+	Parent
+)
+
+func (s Scope) String() string {
+	switch s {
+	case Object:
+		return "object"
+	case Group:
+		return "group"
+	case Parent:
+		return "parent"
+	}
+	return fmt.Sprintf("Scope(%d)", int(s))
+}
+
+// Suggestion is a set of tuples that would give User Relation on Object.
+type Suggestion struct {
+	User     string
+	Relation string
+	Object   string
+	Tuples   []authz.Tuple
+	Scope    Scope
+	Reason   string
+	// via lists the objects between the grant and Object for Parent
+	// suggestions, nearest the grant first; rank is the distance of the
+	// granted relation from the one it implies. Both order suggestions
+	// within a scope.
+	via  []string
+	rank int
+}
+
+func (s Suggestion) String() string { return s.Reason }
+
+// Engine computes suggestions against a store.
+type Engine struct {
+	Backend authz.Backend
+	// Model defaults to the store's active model.
+	Model *model.Model
+	// Writer applies suggestions; default Backend. Set it to an
+	// *authz.Client so write guards see the grant.
+	Writer authz.TupleWriter
+	// MaxHops bounds how many parent links are followed; default
+	// DefaultMaxHops.
+	MaxHops int
+}
+
+// Suggest returns the grants that would give user relation on object,
+// narrowest first. It returns no suggestions when the user already has
+// access.
+func (e *Engine) Suggest(ctx context.Context, user, relation, object string) ([]Suggestion, error) {
+	m := e.Model
+	if m == nil {
+		var err error
+		if m, err = e.Backend.ReadModel(ctx, ""); err != nil {
+			return nil, fmt.Errorf("suggest: read model: %w", err)
+		}
+	}
+	typ, _, _ := strings.Cut(object, ":")
+	if m.Relation(typ, relation) == nil {
+		return nil, fmt.Errorf("suggest: unknown relation %s#%s", typ, relation)
+	}
+	allowed, err := e.Backend.Check(ctx, authz.CheckRequest{User: user, Relation: relation, Object: object})
+	if err != nil {
+		return nil, fmt.Errorf("suggest: %w", err)
+	}
+	if allowed {
+		return nil, nil
+	}
+	hops := e.MaxHops
+	if hops == 0 {
+		hops = DefaultMaxHops
+	}
+	out, err := e.suggest(ctx, m, user, relation, object, hops)
+	if err != nil {
+		return nil, err
+	}
+	for i := range out {
+		s := &out[i]
+		s.User, s.Relation, s.Object = user, relation, object
+		if len(s.via) > 0 {
+			s.Reason += fmt.Sprintf(", inherited by %s", object)
+			if len(s.via) > 1 {
+				s.Reason += " through " + strings.Join(s.via[1:], ", ")
+			}
+			s.Reason += fmt.Sprintf(" (also reaches everything else under %s)", s.via[0])
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Scope != b.Scope {
+			return a.Scope < b.Scope
+		}
+		if len(a.via) != len(b.via) {
+			return len(a.via) < len(b.via)
+		}
+		return a.rank < b.rank
+	})
+	return dedupe(out), nil
+}
+
+func (e *Engine) suggest(ctx context.Context, m *model.Model, user, relation, object string, hops int) ([]Suggestion, error) {
+	typ, _, _ := strings.Cut(object, ":")
+	implying := implies(m, typ, relation)
+	var out []Suggestion
+
+	// A direct grant of the relation, or of the weakest relation implying it.
+	for _, imp := range implying {
+		t := authz.Tuple{User: user, Relation: imp.relation, Object: object}
+		if assignable(m, t) {
+			reason := fmt.Sprintf("grant %s on %s", imp.relation, object)
+			if imp.relation != relation {
+				reason += fmt.Sprintf(" (implies %s)", relation)
+			}
+			out = append(out, Suggestion{Tuples: []authz.Tuple{t}, Scope: Object, Reason: reason, rank: imp.distance})
+		}
+	}
+
+	// Joining a group that already holds one of those relations.
+	onObject, err := authz.ReadAll(ctx, e.Backend, authz.Tuple{Object: object})
+	if err != nil {
+		return nil, fmt.Errorf("suggest: read %s: %w", object, err)
+	}
+	distance := map[string]int{}
+	for _, imp := range implying {
+		distance[imp.relation] = imp.distance
+	}
+	for _, t := range onObject {
+		d, ok := distance[t.Relation]
+		group, member, isUserset := strings.Cut(t.User, "#")
+		if !ok || !isUserset {
+			continue
+		}
+		join := authz.Tuple{User: user, Relation: member, Object: group}
+		if assignable(m, join) {
+			out = append(out, Suggestion{Tuples: []authz.Tuple{join}, Scope: Group, rank: d,
+				Reason: fmt.Sprintf("add %s to %s#%s, which has %s on %s", user, group, member, t.Relation, object)})
+		}
+	}
+
+	// A grant on a parent the relation is inherited from.
+	if hops == 0 {
+		return out, nil
+	}
+	for _, imp := range implying {
+		for _, ttu := range inherited(m, typ, imp.relation) {
+			for _, link := range onObject {
+				if link.Relation != ttu.Tupleset || strings.Contains(link.User, "#") {
+					continue
+				}
+				parentType, _, _ := strings.Cut(link.User, ":")
+				if m.Relation(parentType, ttu.Computed) == nil {
+					continue
+				}
+				inner, err := e.suggest(ctx, m, user, ttu.Computed, link.User, hops-1)
+				if err != nil {
+					return nil, err
+				}
+				for _, s := range inner {
+					if s.Scope == Object {
+						s.Scope = Parent
+						s.via = []string{link.User}
+					} else if s.Scope == Parent {
+						s.via = append(s.via, link.User)
+					}
+					s.rank += imp.distance
+					out = append(out, s)
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+// Apply writes s and checks that it gives the access it was computed for.
+// If it does not, for instance because an exclusion applies, the tuples are
+// deleted and ErrIneffective is returned.
+func (e *Engine) Apply(ctx context.Context, s Suggestion) error {
+	w := e.Writer
+	if w == nil {
+		w = e.Backend
+	}
+	if err := w.Write(ctx, s.Tuples, nil); err != nil {
+		return fmt.Errorf("suggest: apply: %w", err)
+	}
+	allowed, err := e.Backend.Check(ctx, authz.CheckRequest{User: s.User, Relation: s.Relation, Object: s.Object})
+	if err != nil {
+		return fmt.Errorf("suggest: verify: %w", err)
+	}
+	if !allowed {
+		if err := w.Write(ctx, nil, s.Tuples); err != nil {
+			return fmt.Errorf("%w; rollback failed: %v", ErrIneffective, err)
+		}
+		return ErrIneffective
+	}
+	return nil
+}
+
+type implication struct {
+	relation string
+	distance int
+}
+
+// implies returns the relations of typ whose holders are guaranteed
+// relation, nearest first, starting with relation itself. Only union
+// branches count: an intersection or difference operand alone does not
+// guarantee access.
+func implies(m *model.Model, typ, relation string) []implication {
+	out := []implication{{relation, 0}}
+	seen := map[string]bool{relation: true}
+	for i := 0; i < len(out); i++ {
+		r := m.Relation(typ, out[i].relation)
+		if r == nil {
+			continue
+		}
+		for _, c := range unionLeaves(r.Rewrite) {
+			if comp, ok := c.(*model.Computed); ok && !seen[comp.Relation] {
+				seen[comp.Relation] = true
+				out = append(out, implication{comp.Relation, out[i].distance + 1})
+			}
+		}
+	}
+	return out
+}
+
+// inherited returns the tuple-to-userset branches of typ#relation's union.
+func inherited(m *model.Model, typ, relation string) []*model.TupleToUserset {
+	r := m.Relation(typ, relation)
+	if r == nil {
+		return nil
+	}
+	var out []*model.TupleToUserset
+	for _, c := range unionLeaves(r.Rewrite) {
+		if ttu, ok := c.(*model.TupleToUserset); ok {
+			out = append(out, ttu)
+		}
+	}
+	return out
+}
+
+func unionLeaves(rw model.Rewrite) []model.Rewrite {
+	if u, ok := rw.(*model.Union); ok {
+		var out []model.Rewrite
+		for _, c := range u.Children {
+			out = append(out, unionLeaves(c)...)
+		}
+		return out
+	}
+	return []model.Rewrite{rw}
+}
+
+// assignable reports whether t can be written without a condition.
+func assignable(m *model.Model, t authz.Tuple) bool {
+	if m.ValidateTuple(t.User, t.Relation, t.Object, t.Condition.Name) != nil {
+		return false
+	}
+	typ, _, _ := strings.Cut(t.Object, ":")
+	userType, _, _ := strings.Cut(t.User, ":")
+	_, userRel, isUserset := strings.Cut(t.User, "#")
+	for _, ref := range m.Relation(typ, t.Relation).DirectTypes() {
+		if ref.Type == userType && !ref.Wildcard && ref.Condition == "" && (ref.Relation == userRel || !isUserset && ref.Relation == "") {
+			return true
+		}
+	}
+	return false
+}
+
+func dedupe(ss []Suggestion) []Suggestion {
+	seen := map[string]bool{}
+	var out []Suggestion
+	for _, s := range ss {
+		var key strings.Builder
+		for _, t := range s.Tuples {
+			key.WriteString(t.String() + " ")
+		}
+		if !seen[key.String()] {
+			seen[key.String()] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,73 @@
+// Package textdiff renders line diffs for humans: golden-file failures,
+// model promotion previews and similar review output.
+package textdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Lines returns a line diff of a and b: unchanged lines prefixed with two
+// spaces, removed lines with "- " and added lines with "+ ". Unchanged lines
+// more than three away from a change are elided.
+func Lines(a, b []byte) string {
+	x, y := strings.Split(string(a), "\n"), strings.Split(string(b), "\n")
+	// lcs[i][j] is the length of the longest common subsequence of x[i:]
+	// and y[j:].
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	const context = 3
+	var out, same []string
+	changed := false
+	flush := func(last bool) {
+		head, tail := context, context
+		if !changed {
+			head = 0
+		}
+		if last {
+			tail = 0
+		}
+		if len(same) > head+tail {
+			for _, l := range same[:head] {
+				out = append(out, "  "+l)
+			}
+			out = append(out, fmt.Sprintf("  … %d unchanged lines", len(same)-head-tail))
+			same = same[len(same)-tail:]
+		}
+		for _, l := range same {
+			out = append(out, "  "+l)
+		}
+		same = same[:0]
+	}
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			same = append(same, x[i])
+			i, j = i+1, j+1
+		case i < len(x) && (j == len(y) || lcs[i+1][j] >= lcs[i][j+1]):
+			flush(false)
+			out = append(out, "- "+x[i])
+			changed = true
+			i++
+		default:
+			flush(false)
+			out = append(out, "+ "+y[j])
+			changed = true
+			j++
+		}
+	}
+	flush(true)
+	return strings.Join(out, "\n")
+}
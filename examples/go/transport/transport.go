@@ -0,0 +1,92 @@
+// Package transport opens a backend from a configured address, so
+// deployments choose how they reach OpenFGA without code changes:
+//
+//	http://openfga:8080           the HTTP API
+//	unix:///run/openfga/http.sock the HTTP API over a Unix socket, e.g. a sidecar
+//
+//	b, err := transport.Open(ctx, transport.Config{Address: os.Getenv("FGA_ADDRESS"), StoreID: storeID})
+//	if err != nil {
+//		return err
+//	}
+//	defer b.Close()
+//	az := authz.New(b)
+//
+// To evaluate in this process without a server, use package eval.
+package transport
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/openfga/go-sdk/client"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+)
+
+// Config selects a server and store.
+type Config struct {
+	// Address is an http(s) URL or a unix:// socket path.
+	Address string
+	// StoreID is the store to use.
+	StoreID string
+	// ModelID pins the authorization model; default the latest.
+	ModelID string
+	// Timeout bounds each HTTP request; default 10s.
+	Timeout time.Duration
+}
+
+// Backend is an authz.Backend holding connections that Close releases.
+type Backend interface {
+	authz.Backend
+	io.Closer
+}
+
+// Open returns a backend for cfg.Address.
+func Open(ctx context.Context, cfg Config) (Backend, error) {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	switch {
+	case strings.HasPrefix(cfg.Address, "unix://"):
+		path := strings.TrimPrefix(cfg.Address, "unix://")
+		tr := &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", path)
+			},
+		}
+		// The host is never resolved; every request dials the socket.
+		return open("http://localhost", cfg, &http.Client{Transport: tr, Timeout: cfg.Timeout})
+	case strings.HasPrefix(cfg.Address, "http://"), strings.HasPrefix(cfg.Address, "https://"):
+		return open(cfg.Address, cfg, &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone(), Timeout: cfg.Timeout})
+	}
+	return nil, fmt.Errorf("transport: unsupported address %q", cfg.Address)
+}
+
+func open(apiURL string, cfg Config, hc *http.Client) (Backend, error) {
+	fga, err := client.NewSdkClient(&client.ClientConfiguration{
+		ApiUrl:               apiURL,
+		StoreId:              cfg.StoreID,
+		AuthorizationModelId: cfg.ModelID,
+		HTTPClient:           hc,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("transport: %w", err)
+	}
+	return &httpBackend{Backend: authz.FromSDK(fga), client: hc}, nil
+}
+
+type httpBackend struct {
+	authz.Backend
+	client *http.Client
+}
+
+func (b *httpBackend) Close() error {
+	b.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,267 @@
+// Package tuplecsv imports and exports role assignments as CSV with the
+// columns user, relation, object — the layout teams moving off spreadsheets
+// usually already have:
+//
+//	user,relation,object
+//	user:alice,owner,project:api
+//	group:eng#member,viewer,project:api
+//
+// Import validates each row against the store's model, drops duplicates,
+// and reports every rejected row with its reason.
+package tuplecsv
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/eval"
+	"github.com/bogdanticu88/openfga-examples/model"
+	"github.com/bogdanticu88/openfga-examples/progress"
+	"github.com/bogdanticu88/openfga-examples/tuples"
+)
+
+// Header is the column order read and written by this package.
+var Header = []string{"user", "relation", "object"}
+
+// ProvenanceHeader is Header followed by the provenance columns written by
+// ExportProvenance. Import accepts files with either header and ignores the
+// provenance columns.
+var ProvenanceHeader = append(append([]string(nil), Header...), "written_by", "impersonator", "reason", "written_at")
+
+// Reject is a row that was not imported.
+type Reject struct {
+	Line   int
+	Record []string
+	Reason string
+	// Retryable is set for rows the server failed transiently; see
+	// Report.Writes.
+	Retryable bool
+}
+
+// Report summarizes an import.
+type Report struct {
+	Rows       int
+	Imported   int
+	Duplicates int // rows repeated in the file or already in the store
+	Rejects    []Reject
+	// Writes is the per-row outcome of the server writes, nil for a dry
+	// run. Writes.Retry(ctx, authz.RetryTransient) resends the rows that
+	// failed transiently.
+	Writes *authz.BatchResult
+}
+
+// Options tunes Import.
+type Options struct {
+	// Model validates rows; nil reads the store's active model.
+	Model *model.Model
+	// DryRun validates and deduplicates without writing.
+	DryRun bool
+	// Progress counts rows and is checkpointed with the line number after
+	// each written batch; a resumed import skips the lines before it.
+	// Ignored on a dry run.
+	Progress *progress.Tracker
+}
+
+// Import reads assignments from r and writes the new ones to b. A header
+// row is recognised and skipped. Rows that fail validation, or that the
+// server refuses, are reported in Report.Rejects; the error is only for
+// failures that stop the import altogether.
+func Import(ctx context.Context, r io.Reader, b authz.Backend, opts Options) (*Report, error) {
+	m := opts.Model
+	if m == nil {
+		var err error
+		if m, err = b.ReadModel(ctx, ""); err != nil {
+			return nil, fmt.Errorf("tuplecsv: read model: %w", err)
+		}
+	}
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	cr.Comment = '#'
+
+	rep := &Report{}
+	if !opts.DryRun {
+		rep.Writes = &authz.BatchResult{}
+	}
+	resume := 0
+	if !opts.DryRun {
+		saved, err := opts.Progress.Resume(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("tuplecsv: %w", err)
+		}
+		if saved != "" {
+			if resume, err = strconv.Atoi(saved); err != nil {
+				return nil, fmt.Errorf("tuplecsv: checkpoint %q: %w", saved, err)
+			}
+		}
+	}
+	columns := len(Header)
+	seen := map[authz.Tuple]bool{}
+	var pending []authz.Tuple
+	lines := map[authz.Tuple]int{}
+	records := map[authz.Tuple][]string{}
+	rows, last := 0, 0 // rows read since the last flush, line of the last
+	flush := func(line int) error {
+		// A single bad row does not sink its batch; see authz.WriteMany.
+		res := authz.WriteMany(ctx, b, pending, nil)
+		for _, it := range res.Items {
+			if it.Err != nil {
+				rep.Rejects = append(rep.Rejects, Reject{Line: lines[it.Tuple], Record: records[it.Tuple], Reason: it.Err.Error(), Retryable: it.Retryable})
+				continue
+			}
+			rep.Imported++
+		}
+		rep.Writes.Append(res)
+		pending = pending[:0]
+		n := rows
+		rows = 0
+		return opts.Progress.Advance(ctx, n, strconv.Itoa(line))
+	}
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var pe *csv.ParseError
+			if errors.As(err, &pe) {
+				rep.Rejects = append(rep.Rejects, Reject{Line: pe.Line, Reason: pe.Err.Error()})
+				continue
+			}
+			return rep, fmt.Errorf("tuplecsv: %w", err)
+		}
+		line, _ := cr.FieldPos(0)
+		if line == 1 && isHeader(rec, Header) {
+			continue
+		}
+		if line == 1 && isHeader(rec, ProvenanceHeader) {
+			columns = len(ProvenanceHeader)
+			continue
+		}
+		if line <= resume {
+			// Handled by the interrupted run.
+			opts.Progress.Skip(1)
+			continue
+		}
+		last = line
+		rep.Rows++
+		rows++
+		if len(rec) != columns {
+			rep.Rejects = append(rep.Rejects, Reject{Line: line, Record: rec, Reason: fmt.Sprintf("expected %d columns, got %d", columns, len(rec))})
+			continue
+		}
+		t := authz.Tuple{User: strings.TrimSpace(rec[0]), Relation: strings.TrimSpace(rec[1]), Object: strings.TrimSpace(rec[2])}
+		if err := m.ValidateTuple(t.User, t.Relation, t.Object, t.Condition.Name); err != nil {
+			rep.Rejects = append(rep.Rejects, Reject{Line: line, Record: rec, Reason: err.Error()})
+			continue
+		}
+		if seen[t] {
+			rep.Duplicates++
+			continue
+		}
+		seen[t] = true
+		existing, _, err := b.Read(ctx, t, "")
+		if err != nil {
+			return rep, fmt.Errorf("tuplecsv: line %d: %w", line, err)
+		}
+		if len(existing) > 0 {
+			rep.Duplicates++
+			continue
+		}
+		pending = append(pending, t)
+		lines[t], records[t] = line, rec
+		if !opts.DryRun && len(pending) == authz.MaxWriteTuples {
+			if err := flush(line); err != nil {
+				return rep, fmt.Errorf("tuplecsv: %w", err)
+			}
+		}
+	}
+	if opts.DryRun {
+		rep.Imported = len(pending)
+		return rep, nil
+	}
+	if err := flush(last); err != nil {
+		return rep, fmt.Errorf("tuplecsv: %w", err)
+	}
+	if err := opts.Progress.Finish(ctx); err != nil {
+		return rep, fmt.Errorf("tuplecsv: %w", err)
+	}
+	sort.Slice(rep.Rejects, func(i, j int) bool { return rep.Rejects[i].Line < rep.Rejects[j].Line })
+	return rep, nil
+}
+
+// WriteRejects writes rejected rows as CSV with the line number and reason
+// prepended, so the file can be fixed and re-imported.
+func WriteRejects(w io.Writer, rejects []Reject) error {
+	cw := csv.NewWriter(w)
+	cw.Write(append([]string{"line", "reason"}, Header...))
+	for _, r := range rejects {
+		cw.Write(append([]string{fmt.Sprint(r.Line), r.Reason}, r.Record...))
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Export writes the assignments on objects of objectType as CSV with a
+// header, sorted by object, relation and user. An empty objectType exports
+// everything.
+func Export(ctx context.Context, w io.Writer, b authz.Backend, objectType string) error {
+	return export(ctx, w, b, objectType, nil)
+}
+
+// ExportProvenance is Export with the ProvenanceHeader columns filled from
+// s; tuples without a record leave them empty.
+func ExportProvenance(ctx context.Context, w io.Writer, b authz.Backend, objectType string, s tuples.Store) error {
+	return export(ctx, w, b, objectType, s)
+}
+
+func export(ctx context.Context, w io.Writer, b authz.Backend, objectType string, prov tuples.Store) error {
+	matched, err := authz.ReadType(ctx, b, objectType)
+	if err != nil {
+		return fmt.Errorf("tuplecsv: read: %w", err)
+	}
+	eval.SortTuples(matched)
+	cw := csv.NewWriter(w)
+	if prov == nil {
+		cw.Write(Header)
+		for _, t := range matched {
+			cw.Write([]string{t.User, t.Relation, t.Object})
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	recs, err := prov.Get(ctx, matched)
+	if err != nil {
+		return fmt.Errorf("tuplecsv: provenance: %w", err)
+	}
+	cw.Write(ProvenanceHeader)
+	for _, t := range matched {
+		row := []string{t.User, t.Relation, t.Object, "", "", "", ""}
+		if r, ok := recs[t]; ok {
+			row[3], row[4], row[5], row[6] = r.WrittenBy, r.Impersonator, r.Reason, r.WrittenAt.UTC().Format(time.RFC3339)
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func isHeader(rec, header []string) bool {
+	if len(rec) != len(header) {
+		return false
+	}
+	for i, h := range header {
+		if !strings.EqualFold(strings.TrimSpace(rec[i]), h) {
+			return false
+		}
+	}
+	return true
+}
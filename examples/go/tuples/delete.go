@@ -0,0 +1,147 @@
+package tuples
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/progress"
+)
+
+// ErrEmptyFilter is returned by DeleteWhere for a filter that would match
+// every tuple in the store.
+var ErrEmptyFilter = errors.New("tuples: refusing to delete with an empty filter")
+
+// Filter selects tuples by any combination of fields; empty fields match
+// anything.
+type Filter struct {
+	User       string
+	UserPrefix string
+	Relation   string
+	ObjectType string
+	Object     string
+}
+
+// Empty reports whether f matches every tuple.
+func (f Filter) Empty() bool { return f == Filter{} }
+
+// Match reports whether t satisfies f.
+func (f Filter) Match(t authz.Tuple) bool {
+	typ, _, _ := strings.Cut(t.Object, ":")
+	return (f.User == "" || t.User == f.User) &&
+		strings.HasPrefix(t.User, f.UserPrefix) &&
+		(f.Relation == "" || t.Relation == f.Relation) &&
+		(f.ObjectType == "" || typ == f.ObjectType) &&
+		(f.Object == "" || t.Object == f.Object)
+}
+
+// DeleteOptions tunes DeleteWhere.
+type DeleteOptions struct {
+	// DryRun reports the matching tuples without deleting them.
+	DryRun bool
+	// Writer applies the deletes; default the backend. Set it to an
+	// *authz.Client so write guards see them.
+	Writer authz.TupleWriter
+	// BatchSize is the tuples per Write; default authz.MaxWriteTuples.
+	BatchSize int
+	// Progress is advanced after each batch, with the last deleted tuple
+	// as the checkpoint. A run with a saved checkpoint resumes after it,
+	// leaving alone tuples that sort before it, such as grants written
+	// again since the interrupted run.
+	Progress *progress.Tracker
+}
+
+// DeleteResult summarizes DeleteWhere.
+type DeleteResult struct {
+	// Matched are the tuples selected, sorted by object, relation and user;
+	// on a dry run, the tuples that would be deleted.
+	Matched []authz.Tuple
+	Deleted int
+	// Resumed is the number of matching tuples skipped as before the
+	// checkpoint.
+	Resumed int
+}
+
+// DeleteWhere reads the tuples matching f and deletes them in batches, in
+// a stable order so an interrupted run can resume from its checkpoint.
+// Batches before a failing one stay deleted.
+func DeleteWhere(ctx context.Context, b authz.Backend, f Filter, opts DeleteOptions) (*DeleteResult, error) {
+	if f.Empty() {
+		return nil, ErrEmptyFilter
+	}
+	var all []authz.Tuple
+	var err error
+	if f.Object != "" {
+		all, err = authz.ReadAll(ctx, b, authz.Tuple{Object: f.Object, Relation: f.Relation})
+	} else {
+		all, err = authz.ReadType(ctx, b, f.ObjectType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("tuples: read: %w", err)
+	}
+	res := &DeleteResult{}
+	for _, t := range all {
+		if f.Match(t) {
+			res.Matched = append(res.Matched, t)
+		}
+	}
+	sort.Slice(res.Matched, func(i, j int) bool { return less(res.Matched[i], res.Matched[j]) })
+
+	pending := res.Matched
+	if !opts.DryRun {
+		saved, err := opts.Progress.Resume(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("tuples: %w", err)
+		}
+		if saved != "" {
+			last, err := authz.ParseTuple(saved)
+			if err != nil {
+				return nil, fmt.Errorf("tuples: checkpoint: %w", err)
+			}
+			n := sort.Search(len(pending), func(i int) bool { return less(last, pending[i]) })
+			res.Resumed, pending = n, pending[n:]
+		}
+		opts.Progress.SetTotal(len(res.Matched))
+		opts.Progress.Skip(res.Resumed)
+	}
+	if opts.DryRun {
+		res.Matched = pending
+		return res, nil
+	}
+	w := opts.Writer
+	if w == nil {
+		w = b
+	}
+	size := opts.BatchSize
+	if size <= 0 || size > authz.MaxWriteTuples {
+		size = authz.MaxWriteTuples
+	}
+	for len(pending) > 0 {
+		batch := pending[:min(size, len(pending))]
+		if err := w.Write(ctx, nil, batch); err != nil {
+			return res, fmt.Errorf("tuples: delete after %d: %w", res.Deleted, err)
+		}
+		res.Deleted += len(batch)
+		pending = pending[len(batch):]
+		if err := opts.Progress.Advance(ctx, len(batch), batch[len(batch)-1].String()); err != nil {
+			return res, fmt.Errorf("tuples: %w", err)
+		}
+	}
+	if err := opts.Progress.Finish(ctx); err != nil {
+		return res, fmt.Errorf("tuples: %w", err)
+	}
+	return res, nil
+}
+
+func less(a, b authz.Tuple) bool {
+	if a.Object != b.Object {
+		return a.Object < b.Object
+	}
+	if a.Relation != b.Relation {
+		return a.Relation < b.Relation
+	}
+	return a.User < b.User
+}
@@ -0,0 +1,122 @@
+// Package tuples holds helpers that operate on stored tuples as data.
+//
+// OpenFGA tuples carry no metadata, so Recorder keeps a provenance sidecar:
+// for every tuple written through it, who wrote it, why and when.
+//
+//	prov := tuples.NewSQLStore(db, outbox.Postgres, "")
+//	az := authz.New(&tuples.Recorder{Backend: backend, Store: prov})
+//	ctx = tuples.WithReason(authz.WithUser(ctx, "user:alice"), "JIRA-123")
+//	_ = az.Write(ctx, grants, nil)
+//	rec, ok, _ := tuples.Provenance(ctx, prov, grants[0])
+package tuples
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+)
+
+// Record is the provenance of one stored tuple.
+type Record struct {
+	Tuple authz.Tuple `json:"tuple"`
+	// WrittenBy is the user carried by the writing context, if any.
+	WrittenBy string `json:"written_by,omitempty"`
+	// Impersonator is the admin acting as WrittenBy, if any.
+	Impersonator string    `json:"impersonator,omitempty"`
+	Reason       string    `json:"reason,omitempty"`
+	WrittenAt    time.Time `json:"written_at"`
+}
+
+// Store persists provenance records, one per tuple key (see authz.Tuple.Key):
+// writes of the same tuple with and without a condition share a record.
+type Store interface {
+	// Put records recs, replacing earlier records of the same tuples.
+	Put(ctx context.Context, recs []Record) error
+	// Get returns the records of the tuples that have one.
+	Get(ctx context.Context, ts []authz.Tuple) (map[authz.Tuple]Record, error)
+	Delete(ctx context.Context, ts []authz.Tuple) error
+	// List returns every record.
+	List(ctx context.Context) ([]Record, error)
+}
+
+type reasonKey struct{}
+
+// WithReason returns a context whose writes are recorded with reason, e.g.
+// a ticket ID.
+func WithReason(ctx context.Context, reason string) context.Context {
+	return context.WithValue(ctx, reasonKey{}, reason)
+}
+
+// Reason returns the reason stored by WithReason.
+func Reason(ctx context.Context) (string, bool) {
+	r, ok := ctx.Value(reasonKey{}).(string)
+	return r, ok && r != ""
+}
+
+// Provenance returns the record of t, if s has one.
+func Provenance(ctx context.Context, s Store, t authz.Tuple) (Record, bool, error) {
+	recs, err := s.Get(ctx, []authz.Tuple{t})
+	if err != nil {
+		return Record{}, false, err
+	}
+	rec, ok := recs[t]
+	return rec, ok, nil
+}
+
+// Recorder is an authz.Backend that records the provenance of every
+// successful write in Store and forgets deleted tuples.
+//
+// The sidecar is updated after the tuples are committed and is not part of
+// the transaction. A sidecar failure is logged, not returned, since the
+// write itself succeeded and retrying it would fail.
+type Recorder struct {
+	authz.Backend
+	Store Store
+	// Now defaults to time.Now.
+	Now func() time.Time
+	// Logger receives sidecar failures; nil disables logging.
+	Logger *slog.Logger
+}
+
+// Write implements authz.Backend.
+func (r *Recorder) Write(ctx context.Context, writes, deletes []authz.Tuple) error {
+	if err := r.Backend.Write(ctx, writes, deletes); err != nil {
+		return err
+	}
+	if len(deletes) > 0 {
+		if err := r.Store.Delete(ctx, deletes); err != nil {
+			r.logf(ctx, "provenance delete failed", "deletes", len(deletes), "error", err)
+		}
+	}
+	if len(writes) == 0 {
+		return nil
+	}
+	rec := Record{WrittenAt: r.now()}
+	rec.WrittenBy, _ = authz.UserFromContext(ctx)
+	rec.Impersonator, _ = authz.Impersonator(ctx)
+	rec.Reason, _ = Reason(ctx)
+	recs := make([]Record, len(writes))
+	for i, t := range writes {
+		recs[i] = rec
+		recs[i].Tuple = t
+	}
+	if err := r.Store.Put(ctx, recs); err != nil {
+		r.logf(ctx, "provenance record failed", "writes", len(writes), "error", err)
+	}
+	return nil
+}
+
+func (r *Recorder) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return time.Now()
+}
+
+func (r *Recorder) logf(ctx context.Context, msg string, args ...interface{}) {
+	if r.Logger != nil {
+		r.Logger.WarnContext(ctx, msg, args...)
+	}
+}
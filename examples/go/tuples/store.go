@@ -0,0 +1,279 @@
+package tuples
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/outbox"
+)
+
+// MemoryStore is a Store for tests and single-process use.
+type MemoryStore struct {
+	mu sync.Mutex
+	m  map[authz.Tuple]Record
+}
+
+func (s *MemoryStore) Put(_ context.Context, recs []Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.m == nil {
+		s.m = map[authz.Tuple]Record{}
+	}
+	for _, r := range recs {
+		s.m[r.Tuple.Key()] = r
+	}
+	return nil
+}
+
+func (s *MemoryStore) Get(_ context.Context, ts []authz.Tuple) (map[authz.Tuple]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := map[authz.Tuple]Record{}
+	for _, t := range ts {
+		if r, ok := s.m[t.Key()]; ok {
+			out[t] = r
+		}
+	}
+	return out, nil
+}
+
+func (s *MemoryStore) Delete(_ context.Context, ts []authz.Tuple) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range ts {
+		delete(s.m, t.Key())
+	}
+	return nil
+}
+
+func (s *MemoryStore) List(context.Context) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Record, 0, len(s.m))
+	for _, r := range s.m {
+		out = append(out, r)
+	}
+	sortRecords(out)
+	return out, nil
+}
+
+func sortRecords(recs []Record) {
+	sort.Slice(recs, func(i, j int) bool { return recs[i].Tuple.String() < recs[j].Tuple.String() })
+}
+
+// DefaultTable is the provenance table name used when none is given.
+const DefaultTable = "fga_provenance"
+
+// SQLStore keeps records in a database table, one row per tuple.
+type SQLStore struct {
+	db      *sql.DB
+	dialect outbox.Dialect
+	table   string
+}
+
+// NewSQLStore returns a store over table in db, DefaultTable when empty.
+// Create the table with Schema.
+func NewSQLStore(db *sql.DB, dialect outbox.Dialect, table string) *SQLStore {
+	if table == "" {
+		table = DefaultTable
+	}
+	return &SQLStore{db: db, dialect: dialect, table: table}
+}
+
+// Schema returns the DDL for the store's table.
+func (s *SQLStore) Schema() string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+  tuple_key VARCHAR(512) PRIMARY KEY,
+  written_by VARCHAR(256) NOT NULL,
+  impersonator VARCHAR(256) NOT NULL,
+  reason TEXT NOT NULL,
+  written_at TIMESTAMP NOT NULL
+)`, s.table)
+}
+
+func (s *SQLStore) ph(n int) string { return s.dialect.Placeholder(n) }
+
+// Put implements Store, replacing existing rows within one transaction.
+func (s *SQLStore) Put(ctx context.Context, recs []Record) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("tuples: %w", err)
+	}
+	defer tx.Rollback()
+	del := fmt.Sprintf("DELETE FROM %s WHERE tuple_key = %s", s.table, s.ph(1))
+	ins := fmt.Sprintf("INSERT INTO %s (tuple_key, written_by, impersonator, reason, written_at) VALUES (%s, %s, %s, %s, %s)",
+		s.table, s.ph(1), s.ph(2), s.ph(3), s.ph(4), s.ph(5))
+	for _, r := range recs {
+		key := r.Tuple.Key().String()
+		if _, err := tx.ExecContext(ctx, del, key); err != nil {
+			return fmt.Errorf("tuples: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, ins, key, r.WrittenBy, r.Impersonator, r.Reason, r.WrittenAt.UTC()); err != nil {
+			return fmt.Errorf("tuples: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("tuples: %w", err)
+	}
+	return nil
+}
+
+// Get implements Store.
+func (s *SQLStore) Get(ctx context.Context, ts []authz.Tuple) (map[authz.Tuple]Record, error) {
+	q := fmt.Sprintf("SELECT tuple_key, written_by, impersonator, reason, written_at FROM %s WHERE tuple_key = %s", s.table, s.ph(1))
+	out := map[authz.Tuple]Record{}
+	for _, t := range ts {
+		rows, err := s.db.QueryContext(ctx, q, t.Key().String())
+		if err != nil {
+			return nil, fmt.Errorf("tuples: %w", err)
+		}
+		recs, err := scanRecords(rows)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range recs {
+			out[t] = r
+		}
+	}
+	return out, nil
+}
+
+// Delete implements Store.
+func (s *SQLStore) Delete(ctx context.Context, ts []authz.Tuple) error {
+	q := fmt.Sprintf("DELETE FROM %s WHERE tuple_key = %s", s.table, s.ph(1))
+	for _, t := range ts {
+		if _, err := s.db.ExecContext(ctx, q, t.Key().String()); err != nil {
+			return fmt.Errorf("tuples: %w", err)
+		}
+	}
+	return nil
+}
+
+// List implements Store.
+func (s *SQLStore) List(ctx context.Context) ([]Record, error) {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf("SELECT tuple_key, written_by, impersonator, reason, written_at FROM %s ORDER BY tuple_key", s.table))
+	if err != nil {
+		return nil, fmt.Errorf("tuples: %w", err)
+	}
+	return scanRecords(rows)
+}
+
+func scanRecords(rows *sql.Rows) ([]Record, error) {
+	defer rows.Close()
+	var out []Record
+	for rows.Next() {
+		var key string
+		var r Record
+		if err := rows.Scan(&key, &r.WrittenBy, &r.Impersonator, &r.Reason, &r.WrittenAt); err != nil {
+			return nil, fmt.Errorf("tuples: %w", err)
+		}
+		t, err := authz.ParseTuple(key)
+		if err != nil {
+			return nil, fmt.Errorf("tuples: %w", err)
+		}
+		r.Tuple = t
+		out = append(out, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("tuples: %w", err)
+	}
+	return out, nil
+}
+
+// HashClient is the subset of a Redis client used by HashStore. Adapting
+// go-redis takes a few lines per method.
+type HashClient interface {
+	HSet(ctx context.Context, key, field, value string) error
+	HGet(ctx context.Context, key, field string) (value string, ok bool, err error)
+	HDel(ctx context.Context, key string, fields ...string) error
+	HGetAll(ctx context.Context, key string) (map[string]string, error)
+}
+
+// DefaultHashKey is the Redis hash used when HashStore.Key is empty.
+const DefaultHashKey = "fga:provenance"
+
+// HashStore keeps records as JSON in one Redis hash, keyed by tuple.
+type HashStore struct {
+	Client HashClient
+	// Key is the hash name; default DefaultHashKey.
+	Key string
+}
+
+func (s *HashStore) key() string {
+	if s.Key == "" {
+		return DefaultHashKey
+	}
+	return s.Key
+}
+
+// Put implements Store.
+func (s *HashStore) Put(ctx context.Context, recs []Record) error {
+	for _, r := range recs {
+		data, err := json.Marshal(r)
+		if err != nil {
+			return fmt.Errorf("tuples: %w", err)
+		}
+		if err := s.Client.HSet(ctx, s.key(), r.Tuple.Key().String(), string(data)); err != nil {
+			return fmt.Errorf("tuples: %w", err)
+		}
+	}
+	return nil
+}
+
+// Get implements Store.
+func (s *HashStore) Get(ctx context.Context, ts []authz.Tuple) (map[authz.Tuple]Record, error) {
+	out := map[authz.Tuple]Record{}
+	for _, t := range ts {
+		v, ok, err := s.Client.HGet(ctx, s.key(), t.Key().String())
+		if err != nil {
+			return nil, fmt.Errorf("tuples: %w", err)
+		}
+		if !ok {
+			continue
+		}
+		var r Record
+		if err := json.Unmarshal([]byte(v), &r); err != nil {
+			return nil, fmt.Errorf("tuples: %s: %w", t, err)
+		}
+		out[t] = r
+	}
+	return out, nil
+}
+
+// Delete implements Store.
+func (s *HashStore) Delete(ctx context.Context, ts []authz.Tuple) error {
+	if len(ts) == 0 {
+		return nil
+	}
+	fields := make([]string, len(ts))
+	for i, t := range ts {
+		fields[i] = t.Key().String()
+	}
+	if err := s.Client.HDel(ctx, s.key(), fields...); err != nil {
+		return fmt.Errorf("tuples: %w", err)
+	}
+	return nil
+}
+
+// List implements Store.
+func (s *HashStore) List(ctx context.Context) ([]Record, error) {
+	all, err := s.Client.HGetAll(ctx, s.key())
+	if err != nil {
+		return nil, fmt.Errorf("tuples: %w", err)
+	}
+	out := make([]Record, 0, len(all))
+	for field, v := range all {
+		var r Record
+		if err := json.Unmarshal([]byte(v), &r); err != nil {
+			return nil, fmt.Errorf("tuples: %s: %w", field, err)
+		}
+		out = append(out, r)
+	}
+	sortRecords(out)
+	return out, nil
+}
@@ -0,0 +1,183 @@
+// Package unused finds model cleanup candidates by combining the model
+// with what the store holds and what applications check:
+//
+//	var u unused.Usage
+//	az := authz.New(backend, authz.WithDecisionObserver(&u))
+//	// ... after a representative window, e.g. a release cycle:
+//	findings, err := unused.Report(ctx, backend, u.Checks())
+//
+// A relation is live when it is checked, or when evaluating a checked
+// relation can consult it, through a computed relation, a tupleset or a
+// userset restriction. Relations that are neither live nor hold tuples are
+// reported as unused; relations whose tuples nothing live ever reads are
+// reported as unread. Both are only as good as the window the usage was
+// collected over: a relation checked by a yearly job looks unused in June.
+package unused
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+	"github.com/bogdanticu88/openfga-examples/model"
+)
+
+// Kind classifies a Finding.
+type Kind string
+
+const (
+	// Unused relations hold no tuples and are never evaluated.
+	Unused Kind = "unused"
+	// Unread relations hold tuples that no evaluated relation consults.
+	Unread Kind = "unread"
+)
+
+// Finding is one cleanup candidate.
+type Finding struct {
+	Kind     Kind
+	Pos      model.Pos
+	Type     string
+	Relation string
+	Tuples   int
+	Message  string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s: %s", f.Pos, f.Kind, f.Message)
+}
+
+// Usage counts checks per "type#relation". It is an
+// authz.DecisionObserver; the zero value is ready to use and safe for
+// concurrent use.
+type Usage struct {
+	mu     sync.Mutex
+	since  time.Time
+	checks map[string]int
+}
+
+// ObserveDecision implements authz.DecisionObserver.
+func (u *Usage) ObserveDecision(_ context.Context, d authz.Decision) {
+	typ, _, _ := strings.Cut(d.Object, ":")
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.checks == nil {
+		u.checks = map[string]int{}
+		u.since = d.Time
+	}
+	u.checks[typ+"#"+d.Relation]++
+}
+
+// Checks returns a copy of the counts.
+func (u *Usage) Checks() map[string]int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	out := make(map[string]int, len(u.checks))
+	for k, v := range u.checks {
+		out[k] = v
+	}
+	return out
+}
+
+// Since returns the time of the first observed decision.
+func (u *Usage) Since() time.Time {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.since
+}
+
+// Report reads the active model and every tuple from b and returns
+// Detect's findings for checks.
+func Report(ctx context.Context, b authz.Backend, checks map[string]int) ([]Finding, error) {
+	m, err := b.ReadModel(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("unused: read model: %w", err)
+	}
+	all, err := authz.ReadAll(ctx, b, authz.Tuple{})
+	if err != nil {
+		return nil, fmt.Errorf("unused: read: %w", err)
+	}
+	return Detect(m, all, checks), nil
+}
+
+// Detect returns the unused and unread relations of m given the stored
+// tuples and the check counts per "type#relation", in model order.
+func Detect(m *model.Model, tuples []authz.Tuple, checks map[string]int) []Finding {
+	counts := map[string]int{}
+	for _, t := range tuples {
+		typ, _, _ := strings.Cut(t.Object, ":")
+		counts[typ+"#"+t.Relation]++
+	}
+	live := map[string]bool{}
+	var queue []string
+	for key, n := range checks {
+		if n > 0 && !live[key] {
+			live[key] = true
+			queue = append(queue, key)
+		}
+	}
+	for len(queue) > 0 {
+		key := queue[0]
+		queue = queue[1:]
+		typ, rel, _ := strings.Cut(key, "#")
+		for _, dep := range dependencies(m, typ, rel) {
+			if !live[dep] {
+				live[dep] = true
+				queue = append(queue, dep)
+			}
+		}
+	}
+
+	var out []Finding
+	for _, t := range m.Types {
+		for _, r := range t.Relations {
+			key := t.Name + "#" + r.Name
+			if live[key] {
+				continue
+			}
+			f := Finding{Pos: r.Pos, Type: t.Name, Relation: r.Name, Tuples: counts[key]}
+			if f.Tuples == 0 {
+				f.Kind = Unused
+				f.Message = fmt.Sprintf("%s has no tuples and is never evaluated", key)
+			} else {
+				f.Kind = Unread
+				f.Message = fmt.Sprintf("%s holds %d tuples but is never evaluated", key, f.Tuples)
+			}
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// dependencies returns the relations evaluating typ#rel can consult.
+func dependencies(m *model.Model, typ, rel string) []string {
+	r := m.Relation(typ, rel)
+	if r == nil {
+		return nil
+	}
+	var deps []string
+	model.Walk(r.Rewrite, func(rw model.Rewrite) {
+		switch n := rw.(type) {
+		case *model.Computed:
+			deps = append(deps, typ+"#"+n.Relation)
+		case *model.TupleToUserset:
+			deps = append(deps, typ+"#"+n.Tupleset)
+			if ts := m.Relation(typ, n.Tupleset); ts != nil {
+				for _, ref := range ts.DirectTypes() {
+					if m.Relation(ref.Type, n.Computed) != nil {
+						deps = append(deps, ref.Type+"#"+n.Computed)
+					}
+				}
+			}
+		case *model.Direct:
+			for _, ref := range n.Types {
+				if ref.Relation != "" {
+					deps = append(deps, ref.Type+"#"+ref.Relation)
+				}
+			}
+		}
+	})
+	return deps
+}
@@ -0,0 +1,243 @@
+// Package writequeue keeps tuple writes from being lost while the
+// authorization server is unavailable. Writer decorates an authz.Backend:
+// writes that fail because the server is down are appended to a durable
+// Queue and replayed in order once it recovers.
+//
+// FileQueue is a local, fsync'd implementation. SQLite- or Redis-backed
+// queues plug in by implementing Queue.
+package writequeue
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+)
+
+// Entry is one queued Write call.
+type Entry struct {
+	Seq        uint64        `json:"seq"`
+	Writes     []authz.Tuple `json:"writes,omitempty"`
+	Deletes    []authz.Tuple `json:"deletes,omitempty"`
+	EnqueuedAt time.Time     `json:"enqueued_at"`
+}
+
+// Queue is a FIFO of entries. Append assigns Seq; Peek returns the oldest
+// unacknowledged entry; Ack removes it.
+type Queue interface {
+	Append(ctx context.Context, e Entry) (uint64, error)
+	Peek(ctx context.Context) (Entry, bool, error)
+	Ack(ctx context.Context, seq uint64) error
+	Len(ctx context.Context) (int, error)
+}
+
+// ErrOutOfOrder is returned by Ack for an entry that is not the oldest.
+var ErrOutOfOrder = errors.New("writequeue: ack out of order")
+
+// MemoryQueue is a non-durable Queue for tests.
+type MemoryQueue struct {
+	mu      sync.Mutex
+	entries []Entry
+	next    uint64
+}
+
+func (q *MemoryQueue) Append(ctx context.Context, e Entry) (uint64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.next++
+	e.Seq = q.next
+	q.entries = append(q.entries, e)
+	return e.Seq, nil
+}
+
+func (q *MemoryQueue) Peek(ctx context.Context) (Entry, bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.entries) == 0 {
+		return Entry{}, false, nil
+	}
+	return q.entries[0], true, nil
+}
+
+func (q *MemoryQueue) Ack(ctx context.Context, seq uint64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.entries) == 0 || q.entries[0].Seq != seq {
+		return ErrOutOfOrder
+	}
+	q.entries = q.entries[1:]
+	return nil
+}
+
+func (q *MemoryQueue) Len(ctx context.Context) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.entries), nil
+}
+
+// FileQueue persists entries as JSON lines in dir/queue.log and the last
+// acknowledged sequence number in dir/queue.ack. Every append and ack is
+// fsync'd. The log is truncated once every entry has been acknowledged.
+type FileQueue struct {
+	dir string
+
+	mu      sync.Mutex
+	pending []Entry
+	next    uint64
+}
+
+// OpenFileQueue opens or creates the queue in dir.
+func OpenFileQueue(dir string) (*FileQueue, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	q := &FileQueue{dir: dir}
+	acked, err := q.readAck()
+	if err != nil {
+		return nil, err
+	}
+	q.next = acked
+	f, err := os.Open(q.logPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return q, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	var good int64
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			break
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		var e Entry
+		if err == io.EOF || json.Unmarshal(line, &e) != nil {
+			// A torn final line from a crash mid-append is cut off, so
+			// later appends start on a line of their own.
+			if err := os.Truncate(q.logPath(), good); err != nil {
+				return nil, err
+			}
+			break
+		}
+		good += int64(len(line))
+		if e.Seq > q.next {
+			q.next = e.Seq
+		}
+		if e.Seq > acked {
+			q.pending = append(q.pending, e)
+		}
+	}
+	return q, nil
+}
+
+func (q *FileQueue) logPath() string { return filepath.Join(q.dir, "queue.log") }
+func (q *FileQueue) ackPath() string { return filepath.Join(q.dir, "queue.ack") }
+
+func (q *FileQueue) readAck() (uint64, error) {
+	data, err := os.ReadFile(q.ackPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
+
+func (q *FileQueue) Append(ctx context.Context, e Entry) (uint64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	e.Seq = q.next + 1
+	line, err := json.Marshal(e)
+	if err != nil {
+		return 0, err
+	}
+	f, err := os.OpenFile(q.logPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return 0, err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return 0, err
+	}
+	if err := f.Close(); err != nil {
+		return 0, err
+	}
+	q.next = e.Seq
+	q.pending = append(q.pending, e)
+	return e.Seq, nil
+}
+
+func (q *FileQueue) Peek(ctx context.Context) (Entry, bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.pending) == 0 {
+		return Entry{}, false, nil
+	}
+	return q.pending[0], true, nil
+}
+
+func (q *FileQueue) Ack(ctx context.Context, seq uint64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.pending) == 0 || q.pending[0].Seq != seq {
+		return ErrOutOfOrder
+	}
+	if err := writeFileSync(q.ackPath(), []byte(strconv.FormatUint(seq, 10))); err != nil {
+		return err
+	}
+	q.pending = q.pending[1:]
+	if len(q.pending) == 0 {
+		return os.Truncate(q.logPath(), 0)
+	}
+	return nil
+}
+
+func (q *FileQueue) Len(ctx context.Context) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending), nil
+}
+
+// writeFileSync replaces path atomically via a synced temporary file.
+func writeFileSync(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("writequeue: %w", err)
+	}
+	return nil
+}
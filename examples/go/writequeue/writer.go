@@ -0,0 +1,118 @@
+package writequeue
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/bogdanticu88/openfga-examples/authz"
+)
+
+// Writer is an authz.Backend whose Write falls back to a queue while the
+// backend is unavailable. Once anything is queued, later writes are queued
+// behind it so mutations reach the server in the order they were made.
+type Writer struct {
+	authz.Backend
+	Queue Queue
+	// IsUnavailable decides whether an error means "server down, queue and
+	// retry" rather than "request rejected". Defaults to Unavailable.
+	IsUnavailable func(error) bool
+	// OnRejected is called when a queued entry is refused by the server for
+	// a reason other than unavailability, e.g. a tuple that already exists.
+	// The entry is then dropped so the queue keeps moving. When nil, entries
+	// refused as duplicates (see authz.Duplicate) count as applied, since
+	// they were most likely applied before a timeout, and other refused
+	// entries are logged at error level and dropped.
+	OnRejected func(Entry, error)
+	// Logger receives queueing and flush events; nil disables logging.
+	Logger *slog.Logger
+}
+
+// Write implements authz.Backend. It returns nil when the mutation was
+// queued.
+func (w *Writer) Write(ctx context.Context, writes, deletes []authz.Tuple) error {
+	n, err := w.Queue.Len(ctx)
+	if err != nil {
+		return fmt.Errorf("writequeue: %w", err)
+	}
+	if n == 0 {
+		err := w.Backend.Write(ctx, writes, deletes)
+		if err == nil || !w.unavailable(err) {
+			return err
+		}
+		w.logf(ctx, slog.LevelWarn, "backend unavailable, queueing write", "error", err)
+	}
+	seq, err := w.Queue.Append(ctx, Entry{Writes: writes, Deletes: deletes, EnqueuedAt: time.Now()})
+	if err != nil {
+		return fmt.Errorf("writequeue: enqueue: %w", err)
+	}
+	w.logf(ctx, slog.LevelDebug, "write queued", "seq", seq)
+	return nil
+}
+
+// Flush replays queued entries in order until the queue is empty or the
+// backend is still unavailable. It returns the number of entries applied.
+func (w *Writer) Flush(ctx context.Context) (int, error) {
+	applied := 0
+	for {
+		e, ok, err := w.Queue.Peek(ctx)
+		if err != nil || !ok {
+			return applied, err
+		}
+		if err := w.Backend.Write(ctx, e.Writes, e.Deletes); err != nil {
+			switch {
+			case w.unavailable(err):
+				return applied, fmt.Errorf("writequeue: flush seq %d: %w", e.Seq, err)
+			case w.OnRejected != nil:
+				w.OnRejected(e, err)
+			case authz.Duplicate(err):
+				applied++
+			default:
+				w.logf(ctx, slog.LevelError, "queued write rejected, dropping", "seq", e.Seq, "error", err)
+			}
+		} else {
+			applied++
+		}
+		if err := w.Queue.Ack(ctx, e.Seq); err != nil {
+			return applied, err
+		}
+	}
+}
+
+// Run flushes every interval until ctx is done.
+func (w *Writer) Run(ctx context.Context, interval time.Duration) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+			if n, err := w.Flush(ctx); err != nil {
+				w.logf(ctx, slog.LevelDebug, "flush incomplete", "applied", n, "error", err)
+			} else if n > 0 {
+				w.logf(ctx, slog.LevelInfo, "queue flushed", "applied", n)
+			}
+		}
+	}
+}
+
+func (w *Writer) unavailable(err error) bool {
+	if w.IsUnavailable != nil {
+		return w.IsUnavailable(err)
+	}
+	return Unavailable(err)
+}
+
+func (w *Writer) logf(ctx context.Context, level slog.Level, msg string, args ...interface{}) {
+	if w.Logger != nil {
+		w.Logger.Log(ctx, level, msg, args...)
+	}
+}
+
+// Unavailable reports whether err looks like the server being down or
+// overloaded: network failures, timeouts, 429 and 5xx responses.
+func Unavailable(err error) bool {
+	return authz.Retryable(err)
+}
@@ -0,0 +1,5 @@
+schema: '1.2'
+contents:
+  - core.fga
+  - tracker/projects.fga
+  - wiki/spaces.fga